@@ -13,6 +13,10 @@ import (
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/config"
 	"github.com/observer/teatime/internal/database"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/encryption"
+	"github.com/observer/teatime/internal/linkpreview"
+	"github.com/observer/teatime/internal/media"
 	"github.com/observer/teatime/internal/pubsub"
 	"github.com/observer/teatime/internal/server"
 	"github.com/observer/teatime/internal/storage"
@@ -39,7 +43,7 @@ func main() {
 	defer cancel()
 
 	// Connect to database
-	db, err := database.New(ctx, cfg.DatabaseURL)
+	db, err := database.New(ctx, cfg.DatabaseURL, cfg.DBStatementTimeout)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
@@ -55,9 +59,27 @@ func main() {
 	// Initialize repositories
 	userRepo := database.NewUserRepository(db)
 	convRepo := database.NewConversationRepository(db)
+	convRepo.SetSearchAccessRecheck(cfg.SearchAccessRecheck)
 	callRepo := database.NewCallRepository(db)
 	attachmentRepo := database.NewAttachmentRepository(db.Pool)
 
+	// Opt-in encryption at rest for message bodies and attachment filenames
+	if cfg.MessageEncryptionKey != "" {
+		key, err := encryption.ParseKey(cfg.MessageEncryptionKey)
+		if err != nil {
+			slog.Error("invalid MESSAGE_ENCRYPTION_KEY", "error", err)
+			os.Exit(1)
+		}
+		fieldCipher, err := encryption.NewFieldCipher(key)
+		if err != nil {
+			slog.Error("failed to create field cipher", "error", err)
+			os.Exit(1)
+		}
+		convRepo.SetFieldCipher(fieldCipher)
+		attachmentRepo.SetFieldCipher(fieldCipher)
+		slog.Info("message encryption at rest enabled; full-text search is disabled")
+	}
+
 	// Initialize token service (use a default key for dev if not set)
 	jwtKey := cfg.JWTSigningKey
 	if jwtKey == "" {
@@ -75,23 +97,27 @@ func main() {
 		slog.Error("failed to create token service", "error", err)
 		os.Exit(1)
 	}
+	tokenService.SetIssuerAudience(cfg.JWTIssuer, cfg.JWTAudience)
 
 	// Initialize auth service
 	authService := auth.NewService(userRepo, tokenService)
 
-	// Initialize OAuth service (optional - only if configured)
-	var oauthService *auth.OAuthService
+	// Initialize OAuth providers (optional - only those configured)
 	var oauthHandler *api.OAuthHandlers
 	if cfg.OAuthEnabled {
-		oauthService = auth.NewOAuthService(
-			cfg.GoogleClientID,
-			cfg.GoogleClientSecret,
-			cfg.GoogleRedirectURL,
-		)
-		oauthHandler = api.NewOAuthHandlers(oauthService, authService, userRepo, cfg.AppBaseURL)
-		slog.Info("Google OAuth enabled", "redirect_url", cfg.GoogleRedirectURL)
+		providers := make(map[string]*auth.OAuthService, len(cfg.OAuthProviders))
+		for name, p := range cfg.OAuthProviders {
+			oauthService, err := auth.NewOAuthService(name, p.ClientID, p.ClientSecret, p.RedirectURL)
+			if err != nil {
+				slog.Error("failed to configure OAuth provider", "provider", name, "error", err)
+				os.Exit(1)
+			}
+			providers[name] = oauthService
+			slog.Info("OAuth provider enabled", "provider", name, "redirect_url", p.RedirectURL)
+		}
+		oauthHandler = api.NewOAuthHandlers(providers, authService, userRepo, cfg.AppBaseURL)
 	} else {
-		slog.Info("Google OAuth not configured - OAuth login disabled")
+		slog.Info("OAuth not configured - OAuth login disabled")
 	}
 
 	// Initialize R2 storage (optional - skip if not configured)
@@ -103,7 +129,23 @@ func main() {
 			slog.Error("failed to initialize R2 storage", "error", err)
 			os.Exit(1)
 		}
-		uploadHandler = api.NewUploadHandler(attachmentRepo, convRepo, r2Storage, cfg.MaxUploadBytes, cfg.R2Bucket)
+		retryPolicy := storage.DefaultRetryPolicy
+		retryPolicy.MaxAttempts = cfg.R2MaxAttempts
+		retryPolicy.BaseDelay = cfg.R2RetryBaseDelay
+		r2Storage.SetRetryPolicy(retryPolicy)
+		uploadHandler = api.NewUploadHandler(attachmentRepo, convRepo, r2Storage, cfg.MaxUploadBytes, cfg.StorageQuotaBytes, cfg.R2Bucket)
+		thumbnailSizes, err := media.ParseThumbnailSizes(cfg.ThumbnailSizes)
+		if err != nil {
+			slog.Error("invalid THUMBNAIL_SIZES", "error", err)
+			os.Exit(1)
+		}
+		images := media.NewImageProcessor(attachmentRepo, r2Storage, logger)
+		images.SetAutoOrient(cfg.ImageAutoOrient)
+		images.SetThumbnailSizes(thumbnailSizes)
+		uploadHandler.SetImageProcessor(images)
+		go images.Run(context.Background())
+		uploadHandler.SetAllowedMimeTypes(cfg.UploadAllowedMimeTypes)
+		uploadHandler.SetDownloadURLTTL(cfg.AttachmentURLTTL)
 		slog.Info("R2 storage initialized", "bucket", cfg.R2Bucket)
 	} else {
 		slog.Warn("R2 storage not configured - file uploads disabled")
@@ -140,23 +182,91 @@ func main() {
 		TURNURLs:     cfg.ICETURNURLs,
 		TURNUsername: cfg.TURNUsername,
 		TURNPassword: cfg.TURNPassword,
+		RingTimeout:  cfg.CallRingTimeout,
+		IdleTimeout:  cfg.CallIdleTimeout,
+
+		TURNSecret:        cfg.TURNSecret,
+		TURNCredentialTTL: cfg.TURNCredentialTTL,
 	}
 	webrtcManager := webrtc.NewManager(webrtcConfig, ps, logger)
+	// Swap in a region-aware provider here to send users nearer TURN servers
+	webrtcManager.SetICEServerProvider(webrtcConfig)
 	callHandler := webrtc.NewCallHandler(webrtcManager, convRepo, callRepo, ps, logger)
 
 	// Initialize SFU for group calls
 	sfuConfig := &webrtc.SFUConfig{
-		ICEServers: webrtcConfig.GetPionICEServers(),
+		ICEServers:        webrtcConfig.GetPionICEServers(),
+		ICEServersFor:     webrtcConfig.PionICEServersFor,
+		MaxParticipants:   cfg.SFUMaxParticipants,
+		EnableAudioLevels: cfg.SFUAudioLevels,
+		RecordingDir:      cfg.SFURecordingDir,
+		EnableSimulcast:   cfg.SFUSimulcast,
+		MaxForwardedVideo: cfg.SFUMaxVideo,
 	}
 	sfu := webrtc.NewSFU(sfuConfig, ps, logger)
 	sfuHandler := webrtc.NewSFUHandler(sfu, webrtcManager, convRepo, callRepo, ps, logger)
+	callHandler.SetAutoAcceptLookup(userRepo)
+	sfuHandler.SetAutoAcceptLookup(userRepo)
+	callHandler.SetCallLocators(webrtcManager, sfu)
+	sfuHandler.SetCallLocators(webrtcManager, sfu)
+	apiCallHandler.SetParticipantCounters(webrtcManager, sfu)
+	convHandler.SetCallEvictors(callHandler, sfuHandler)
+	convHandler.SetAttachments(attachmentRepo)
+	convHandler.SetMessageFilter(domain.NewMessageFilter(domain.FilterMode(cfg.TextFilterMode), cfg.TextBlocklist))
+	convHandler.SetPinLimit(cfg.MaxPinnedMessages)
+	convHandler.SetTitleLimit(cfg.MaxTitleLength)
+	convHandler.SetMessageLimit(cfg.MaxMessageLength)
+	convHandler.SetGroupSizeLimit(cfg.MaxGroupMembers)
+	convHandler.SetEditWindow(cfg.MessageEditWindow)
+	convHandler.SetAutoCreateDMs(cfg.AutoCreateDMs)
+	convHandler.SetSearchLimits(cfg.SearchMaxQueryLength, cfg.SearchMaxTerms)
 
 	// Initialize WebSocket hub and handler
 	wsHub := websocket.NewHub(authService, convRepo, userRepo, attachmentRepo, ps, logger)
 	wsHub.SetCallHandler(callHandler)
 	wsHub.SetSFUHandler(sfuHandler)
+	wsHub.SetMessageLimit(cfg.MaxMessageLength)
+	wsHub.SetFloodLimits(cfg.FloodMaxMessages, cfg.FloodWindow, cfg.FloodCooldown)
+	wsHub.SetCallReconnectGrace(cfg.CallReconnectGrace)
+	wsHub.SetAdmissionLimit(cfg.WSMaxPendingAuths, cfg.WSStormRetryAfter)
+	wsHub.SetAuthTimeout(cfg.WSAuthTimeout)
+	wsHub.SetLastSeenHeartbeat(cfg.LastSeenHeartbeat)
+	wsHub.SetHeartbeat(cfg.WSPingInterval, cfg.WSPongWait)
+	wsHub.SetClientRateLimits(cfg.WSRateMessages, cfg.WSRateSignals, cfg.WSRateWindow)
+	wsHub.SetCompression(cfg.WSCompression, cfg.WSCompressionLevel, cfg.WSCompressionMinSize)
+	wsHub.SetReplayBuffer(cfg.WSReplayBufferSize, cfg.WSReplayMaxAge)
+	if r2Storage != nil {
+		wsHub.SetAttachmentURLSigner(r2Storage)
+	}
+	userHandler.SetPresenceProvider(wsHub)
+
+	// Notify @mentioned users wherever they are
+	mentions := websocket.NewMentions(userRepo, convRepo, broadcaster, logger)
+	wsHub.SetMentions(mentions)
+	convHandler.SetMentions(mentions)
+	convHandler.SetFloodChecker(wsHub)
+
+	// Unfurl links in new messages in the background
+	if cfg.LinkPreviewsEnabled {
+		fetcher := linkpreview.NewFetcher(cfg.LinkPreviewTimeout, cfg.LinkPreviewHostRate)
+		previews := websocket.NewLinkPreviewWorker(convRepo, fetcher, broadcaster, logger)
+		wsHub.SetLinkPreviews(previews)
+		convHandler.SetLinkPreviews(previews)
+		go previews.Run(context.Background())
+	}
+
 	go wsHub.Run(context.Background())
+	go webrtcManager.RunReaper(context.Background(), webrtc.EndCallLog(callRepo, logger))
+	go websocket.NewPinSweeper(convRepo, broadcaster, cfg.PinSweepInterval, logger).Run(context.Background())
+	go websocket.NewMessageSweeper(convRepo, broadcaster, cfg.MessageSweepInterval, logger).Run(context.Background())
+	dispatcher := websocket.NewScheduledDispatcher(convRepo, broadcaster, cfg.ScheduledDispatchInterval, logger)
+	go dispatcher.Run(context.Background())
+	if r2Storage != nil {
+		go media.NewObjectReaper(attachmentRepo, r2Storage, cfg.ObjectReapInterval, logger).Run(context.Background())
+	}
 	wsHandler := websocket.NewHandler(wsHub, logger)
+	adminHandler := api.NewAdminHandler(wsHub, webrtcManager, sfu, logger)
+	adminHandler.SetCallRecorder(sfuHandler)
 
 	// Determine static files directory (relative to working dir in dev, configurable in prod)
 	staticDir := "../frontend"
@@ -179,6 +289,7 @@ func main() {
 		CallHandler:    apiCallHandler,
 		UploadHandler:  uploadHandler,
 		OAuthHandler:   oauthHandler,
+		AdminHandler:   adminHandler,
 		WSHandler:      wsHandler,
 		StaticDir:      staticDir,
 		Logger:         logger,
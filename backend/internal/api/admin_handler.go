@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/auth"
+	"github.com/observer/teatime/internal/webrtc"
+	"github.com/observer/teatime/internal/websocket"
+)
+
+// AdminHandler exposes live server state for operators
+type AdminHandler struct {
+	hub      *websocket.Hub
+	calls    *webrtc.Manager
+	sfu      *webrtc.SFU
+	recorder CallRecorder
+	logger   *slog.Logger
+}
+
+// CallRecorder starts and stops recording SFU calls
+type CallRecorder interface {
+	StartRecording(ctx context.Context, roomID, by uuid.UUID) (string, error)
+	StopRecording(ctx context.Context, roomID, by uuid.UUID) (string, error)
+}
+
+func NewAdminHandler(hub *websocket.Hub, calls *webrtc.Manager, sfu *webrtc.SFU, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		hub:    hub,
+		calls:  calls,
+		sfu:    sfu,
+		logger: logger,
+	}
+}
+
+// SetCallRecorder enables the call recording endpoints
+func (h *AdminHandler) SetCallRecorder(recorder CallRecorder) {
+	h.recorder = recorder
+}
+
+// GetWSStats godoc
+//
+//	@Summary		WebSocket hub stats
+//	@Description	Live connection and room subscription counts (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			top	query		int	false	"Entries in per-user/per-room breakdowns (default 10, max 100)"
+//	@Success		200	{object}	websocket.HubStats
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/admin/ws/stats [get]
+func (h *AdminHandler) GetWSStats(w http.ResponseWriter, r *http.Request) {
+	top := 10
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		if n, err := strconv.Atoi(topStr); err == nil && n >= 0 && n <= 100 {
+			top = n
+		}
+	}
+
+	writeJSON(w, http.StatusOK, h.hub.Stats(top))
+}
+
+// GetCallStats godoc
+//
+//	@Summary		Call stats
+//	@Description	Active P2P and SFU rooms and participant counts (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	object{p2p=webrtc.CallStats,sfu=webrtc.CallStats}
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/admin/calls/stats [get]
+func (h *AdminHandler) GetCallStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"p2p": h.calls.Stats(),
+		"sfu": h.sfu.Stats(),
+	})
+}
+
+// GetCallRoomStats godoc
+//
+//	@Summary		Call quality
+//	@Description	Packet loss, jitter and round-trip time per participant in an SFU room, from RTCP receiver reports (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			roomId	path		string	true	"Room ID"
+//	@Success		200		{object}	webrtc.RoomStats
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string	"No active SFU room"
+//	@Router			/admin/calls/{roomId}/stats [get]
+func (h *AdminHandler) GetCallRoomStats(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(r.PathValue("roomId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid room ID")
+		return
+	}
+
+	stats, ok := h.sfu.GetRoomStats(roomID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// StartCallRecording godoc
+//
+//	@Summary		Start recording a call
+//	@Description	Record each participant's audio and video in an SFU call to disk. The conversation is sent call.recording_started. (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			roomId	path		string	true	"Room ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string	"No active SFU call"
+//	@Failure		409		{object}	map[string]string	"Already recording"
+//	@Failure		501		{object}	map[string]string	"Recording not enabled"
+//	@Router			/calls/{roomId}/record/start [post]
+func (h *AdminHandler) StartCallRecording(w http.ResponseWriter, r *http.Request) {
+	h.toggleRecording(w, r, true)
+}
+
+// StopCallRecording godoc
+//
+//	@Summary		Stop recording a call
+//	@Description	Stop recording an SFU call and finalize its files. The conversation is sent call.recording_stopped. (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			roomId	path		string	true	"Room ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string	"No active SFU call"
+//	@Failure		409		{object}	map[string]string	"Not recording"
+//	@Router			/calls/{roomId}/record/stop [post]
+func (h *AdminHandler) StopCallRecording(w http.ResponseWriter, r *http.Request) {
+	h.toggleRecording(w, r, false)
+}
+
+func (h *AdminHandler) toggleRecording(w http.ResponseWriter, r *http.Request, start bool) {
+	adminID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	roomID, err := uuid.Parse(r.PathValue("roomId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid room ID")
+		return
+	}
+
+	if h.recorder == nil {
+		writeError(w, http.StatusNotImplemented, webrtc.ErrRecordingDisabled.Error())
+		return
+	}
+
+	var path string
+	if start {
+		path, err = h.recorder.StartRecording(r.Context(), roomID, adminID)
+	} else {
+		path, err = h.recorder.StopRecording(r.Context(), roomID, adminID)
+	}
+	switch {
+	case errors.Is(err, webrtc.ErrRecordingDisabled):
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	case errors.Is(err, webrtc.ErrSFURoomNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, webrtc.ErrAlreadyRecording), errors.Is(err, webrtc.ErrNotRecording):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("failed to toggle call recording", "error", err, "room_id", roomID, "start", start)
+		writeError(w, http.StatusInternalServerError, "failed to update recording")
+		return
+	}
+
+	status := "recording"
+	if !start {
+		status = "stopped"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": status, "path": path})
+}
+
+// ClearThrottle godoc
+//
+//	@Summary		Clear flood mute
+//	@Description	Lift an automatic flood mute from a user before the cooldown ends (admin only)
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"User ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string	"User is not muted"
+//	@Router			/admin/users/{id}/throttle [delete]
+func (h *AdminHandler) ClearThrottle(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if !h.hub.ClearThrottle(userID, adminID) {
+		writeError(w, http.StatusNotFound, "user is not muted")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "throttle cleared"})
+}
@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/domain"
 )
@@ -42,7 +44,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.auth.Register(r.Context(), input)
+	user, tokens, err := h.auth.Register(sessionContext(r), input)
 	if err != nil {
 		h.handleAuthError(w, err)
 		return
@@ -77,7 +79,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.auth.Login(r.Context(), input)
+	user, tokens, err := h.auth.Login(sessionContext(r), input)
 	if err != nil {
 		h.handleAuthError(w, err)
 		return
@@ -110,7 +112,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.auth.Refresh(r.Context(), cookie.Value)
+	user, tokens, err := h.auth.Refresh(sessionContext(r), cookie.Value)
 	if err != nil {
 		h.handleAuthError(w, err)
 		return
@@ -155,6 +157,132 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
 }
 
+// ChangePassword godoc
+//
+//	@Summary		Change password
+//	@Description	Change the logged-in user's password. The current password is required, the new one must meet the registration rules, and every other session is logged out.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		auth.ChangePasswordInput	true	"Current and new password"
+//	@Success		200		{object}	map[string]interface{}	"Password changed; new tokens issued"
+//	@Failure		400		{object}	map[string]string	"Invalid input"
+//	@Failure		401		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string	"Current password is incorrect"
+//	@Router			/auth/change-password [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input auth.ChangePasswordInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, tokens, err := h.auth.ChangePassword(sessionContext(r), userID, input)
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		// Not 401: the session itself is fine, only the password was wrong
+		writeError(w, http.StatusForbidden, "current password is incorrect")
+		return
+	}
+	if err != nil {
+		h.handleAuthError(w, err)
+		return
+	}
+
+	// Replace the revoked refresh token with the new one
+	h.setRefreshTokenCookie(w, tokens.RefreshToken)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user":         user.ToPublic(),
+		"access_token": tokens.AccessToken,
+		"expires_at":   tokens.ExpiresAt,
+	})
+}
+
+// ListSessions godoc
+//
+//	@Summary		List active sessions
+//	@Description	List the devices the user is logged in on, newest first. The session the request's refresh cookie belongs to is flagged as current_session_id.
+//	@Tags			auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	object{sessions=[]domain.RefreshToken,count=int,current_session_id=string}
+//	@Failure		401	{object}	map[string]string
+//	@Router			/auth/sessions [get]
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessions, err := h.auth.ListSessions(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("list sessions failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []domain.RefreshToken{}
+	}
+
+	resp := map[string]interface{}{
+		"sessions": sessions,
+		"count":    len(sessions),
+	}
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		if id, ok := h.auth.SessionID(r.Context(), cookie.Value); ok {
+			resp["current_session_id"] = id
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeSession godoc
+//
+//	@Summary		Revoke a session
+//	@Description	Log out one of the user's sessions. Its access token stays valid until it expires.
+//	@Tags			auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Session ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid session ID")
+		return
+	}
+
+	if err := h.auth.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Error("revoke session failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "session revoked"})
+}
+
 // Me godoc
 //
 //	@Summary		Get authenticated user
@@ -182,6 +310,12 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// sessionContext records the requesting client for any session issued
+// while handling r
+func sessionContext(r *http.Request) context.Context {
+	return auth.WithSessionInfo(r.Context(), auth.SessionInfoFromRequest(r))
+}
+
 func (h *AuthHandler) setRefreshTokenCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
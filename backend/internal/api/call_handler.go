@@ -10,12 +10,14 @@ import (
 	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/database"
+	"github.com/observer/teatime/internal/webrtc"
 )
 
 // CallHandler handles call-related HTTP endpoints
 type CallHandler struct {
 	callRepo *database.CallRepository
 	convRepo *database.ConversationRepository
+	counters []webrtc.ParticipantCounter
 	logger   *slog.Logger
 }
 
@@ -76,6 +78,72 @@ func (h *CallHandler) GetCallHistory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SetParticipantCounters sets the sources of live participant counts for active calls
+func (h *CallHandler) SetParticipantCounters(counters ...webrtc.ParticipantCounter) {
+	h.counters = counters
+}
+
+// GetActiveCalls godoc
+// @Summary List conversations with an active call
+// @Tags calls
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit (default 50)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} map[string]interface{}
+// @Router /calls/active [get]
+func (h *CallHandler) GetActiveCalls(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	calls, err := h.callRepo.GetActiveCallsForUser(r.Context(), userID, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to get active calls", "error", err, "user_id", userID)
+		writeError(w, http.StatusInternalServerError, "Failed to get active calls")
+		return
+	}
+
+	if calls == nil {
+		calls = []database.CallLog{}
+	}
+
+	if len(calls) > 0 && len(h.counters) > 0 {
+		roomIDs := make([]uuid.UUID, len(calls))
+		for i, call := range calls {
+			roomIDs[i] = call.ConversationID
+		}
+		for _, counter := range h.counters {
+			counts := counter.ParticipantCounts(roomIDs)
+			for i := range calls {
+				calls[i].LiveParticipants += counts[calls[i].ConversationID]
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"calls":  calls,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
 // GetCall godoc
 // @Summary Get a specific call
 // @Tags calls
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -13,35 +14,147 @@ import (
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/database"
 	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/webrtc"
 	"github.com/observer/teatime/internal/websocket"
 )
 
 // ConversationHandler handles conversation and message endpoints
 type ConversationHandler struct {
-	convs       *database.ConversationRepository
-	users       *database.UserRepository
-	broadcaster websocket.RoomBroadcaster
-	logger      *slog.Logger
+	convs         *database.ConversationRepository
+	users         *database.UserRepository
+	attachments   *database.AttachmentRepository
+	broadcaster   websocket.RoomBroadcaster
+	linkPreviews  *websocket.LinkPreviewWorker
+	mentions      *websocket.Mentions
+	flood         websocket.FloodChecker
+	evictors      []webrtc.CallEvictor
+	filter        *domain.MessageFilter
+	maxPins       int
+	maxTitleLen   int
+	maxMessageLen int
+	maxMembers    int
+	editWindow    time.Duration
+	autoCreateDMs bool
+	maxSearchLen  int
+	maxSearchTerm int
+	logger        *slog.Logger
 }
 
 func NewConversationHandler(convs *database.ConversationRepository, users *database.UserRepository, broadcaster websocket.RoomBroadcaster, logger *slog.Logger) *ConversationHandler {
 	return &ConversationHandler{
-		convs:       convs,
-		users:       users,
-		broadcaster: broadcaster,
-		logger:      logger,
+		convs:         convs,
+		users:         users,
+		broadcaster:   broadcaster,
+		maxPins:       domain.DefaultMaxPins,
+		maxTitleLen:   domain.MaxTitleLength,
+		maxMessageLen: domain.DefaultMaxMessageLength,
+		maxMembers:    domain.DefaultMaxGroupMembers,
+		editWindow:    domain.DefaultMessageEditWindow,
+		autoCreateDMs: true,
+		maxSearchLen:  domain.DefaultMaxSearchQueryLength,
+		maxSearchTerm: domain.DefaultMaxSearchTerms,
+		logger:        logger,
 	}
 }
 
+// SetFloodChecker counts REST sends against the sender's flood limit
+func (h *ConversationHandler) SetFloodChecker(flood websocket.FloodChecker) {
+	h.flood = flood
+}
+
+// checkFlood records a send, writing 429 and returning false if the user is
+// muted for flooding
+func (h *ConversationHandler) checkFlood(w http.ResponseWriter, userID uuid.UUID) bool {
+	if h.flood == nil || h.flood.CheckFlood(userID) {
+		return true
+	}
+	writeError(w, http.StatusTooManyRequests, "throttled")
+	return false
+}
+
+// SetCallEvictors sets the call handlers used to drop banned users from live calls
+func (h *ConversationHandler) SetCallEvictors(evictors ...webrtc.CallEvictor) {
+	h.evictors = evictors
+}
+
+// SetMessageFilter sets the filter applied to group titles
+func (h *ConversationHandler) SetMessageFilter(f *domain.MessageFilter) {
+	h.filter = f
+}
+
+// SetPinLimit sets how many messages a conversation may pin; 0 disables the limit
+func (h *ConversationHandler) SetPinLimit(max int) {
+	h.maxPins = max
+}
+
+// SetTitleLimit sets the longest group title allowed, in visible characters;
+// 0 keeps the default
+func (h *ConversationHandler) SetTitleLimit(max int) {
+	if max > 0 {
+		h.maxTitleLen = max
+	}
+}
+
+// SetMessageLimit sets the longest message body allowed, in bytes; 0 keeps
+// the default
+func (h *ConversationHandler) SetMessageLimit(max int) {
+	if max > 0 {
+		h.maxMessageLen = max
+	}
+}
+
+// SetGroupSizeLimit sets how many members a group may be created with; 0
+// keeps the default
+func (h *ConversationHandler) SetGroupSizeLimit(max int) {
+	if max > 0 {
+		h.maxMembers = max
+	}
+}
+
+// SetEditWindow sets how long after sending a message may be edited; 0 disables the limit
+func (h *ConversationHandler) SetEditWindow(window time.Duration) {
+	h.editWindow = window
+}
+
+// SetAutoCreateDMs sets whether messaging a user by username starts a DM
+// with them if there isn't one yet
+func (h *ConversationHandler) SetAutoCreateDMs(enabled bool) {
+	h.autoCreateDMs = enabled
+}
+
+// SetSearchLimits sets the longest search query accepted, in characters, and
+// how many of its terms are searched for; 0 disables a limit
+func (h *ConversationHandler) SetSearchLimits(maxLength, maxTerms int) {
+	h.maxSearchLen = maxLength
+	h.maxSearchTerm = maxTerms
+}
+
+// SetAttachments sets the repository used to attach uploads to messages sent
+// over REST; without it attachment_id is rejected
+func (h *ConversationHandler) SetAttachments(attachments *database.AttachmentRepository) {
+	h.attachments = attachments
+}
+
+// SetLinkPreviews queues links in messages sent over REST for preview cards
+func (h *ConversationHandler) SetLinkPreviews(w *websocket.LinkPreviewWorker) {
+	h.linkPreviews = w
+}
+
+// SetMentions records @mentions in messages sent over REST and notifies
+// those mentioned
+func (h *ConversationHandler) SetMentions(m *websocket.Mentions) {
+	h.mentions = m
+}
+
 // CreateConversation godoc
 //
 //	@Summary		Create conversation
-//	@Description	Create a new direct message or group conversation
+//	@Description	Create a new direct message or group conversation. Groups can be created with members' roles and their settings in one step.
 //	@Tags			conversations
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			request	body		object{type=string,title=string,member_ids=[]string}	true	"Conversation details"
+//	@Param			request	body		object{type=string,title=string,member_ids=[]string,members=[]object{user_id=string,role=string},read_only=bool,disappearing_ttl=int,allow_member_adds=bool,default_member_role=string}	true	"Conversation details"
 //	@Success		201	{object}	domain.Conversation
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
@@ -57,6 +170,13 @@ func (h *ConversationHandler) CreateConversation(w http.ResponseWriter, r *http.
 		Type      string   `json:"type"`       // "dm" or "group"
 		Title     string   `json:"title"`      // for groups only
 		MemberIDs []string `json:"member_ids"` // UUIDs of other members
+
+		// Groups only: members with explicit roles, and initial settings
+		Members []struct {
+			UserID string `json:"user_id"`
+			Role   string `json:"role"`
+		} `json:"members"`
+		domain.GroupSettings
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -112,26 +232,6 @@ func (h *ConversationHandler) CreateConversation(w http.ResponseWriter, r *http.
 		}
 	}
 
-	// Group validation
-	if convType == domain.ConversationTypeGroup {
-		if len(memberIDs) < 2 {
-			writeError(w, http.StatusBadRequest, "group must have at least 2 members")
-			return
-		}
-		if len(memberIDs) > 100 {
-			writeError(w, http.StatusBadRequest, "group cannot exceed 100 members")
-			return
-		}
-		if input.Title == "" {
-			writeError(w, http.StatusBadRequest, "group title is required")
-			return
-		}
-		if len(input.Title) > 100 {
-			writeError(w, http.StatusBadRequest, "title too long (max 100)")
-			return
-		}
-	}
-
 	// Create conversation
 	conv := &domain.Conversation{
 		ID:        uuid.New(),
@@ -142,7 +242,50 @@ func (h *ConversationHandler) CreateConversation(w http.ResponseWriter, r *http.
 		UpdatedAt: time.Now(),
 	}
 
-	if err := h.convs.Create(r.Context(), conv, memberIDs); err != nil {
+	if convType == domain.ConversationTypeGroup {
+		title, err := h.filter.Clean(input.Title, h.maxTitleLen)
+		if err != nil {
+			writeTitleError(w, err, h.maxTitleLen)
+			return
+		}
+		conv.Title = title
+		conv.DefaultMemberRole = domain.MemberRoleMember
+		conv.AllowMemberAdds = true
+
+		requested := make([]domain.ConversationMember, 0, len(memberIDs)+len(input.Members))
+		for _, id := range memberIDs {
+			requested = append(requested, domain.ConversationMember{UserID: id})
+		}
+		for _, m := range input.Members {
+			id, err := uuid.Parse(m.UserID)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid member ID: "+m.UserID)
+				return
+			}
+			requested = append(requested, domain.ConversationMember{UserID: id, Role: domain.MemberRole(m.Role)})
+		}
+
+		if err := createGroup(r.Context(), h.convs, conv, input.GroupSettings, requested, h.maxMembers); err != nil {
+			switch {
+			case errors.Is(err, domain.ErrGroupTooLarge):
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("group cannot exceed %d members", h.maxMembers))
+			case errors.Is(err, domain.ErrInvalidMessageTTL), errors.Is(err, domain.ErrInvalidRole),
+				errors.Is(err, domain.ErrGroupTooSmall):
+				writeError(w, http.StatusBadRequest, err.Error())
+			default:
+				h.logger.Error("create group failed", "error", err)
+				writeError(w, http.StatusInternalServerError, "failed to create conversation")
+			}
+			return
+		}
+	} else if err := h.convs.Create(r.Context(), conv, memberIDs); err != nil {
+		if errors.Is(err, domain.ErrDMExists) {
+			// Created concurrently since the check above
+			if existing, err := h.convs.FindDMBetween(r.Context(), memberIDs[0], memberIDs[1]); err == nil && existing != nil {
+				writeJSON(w, http.StatusOK, existing)
+				return
+			}
+		}
 		h.logger.Error("create conversation failed", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to create conversation")
 		return
@@ -222,7 +365,7 @@ func (h *ConversationHandler) ListConversations(w http.ResponseWriter, r *http.R
 // GetConversation godoc
 //
 //	@Summary		Get conversation details
-//	@Description	Get details of a specific conversation including members
+//	@Description	Get details of a specific conversation including its first 20 members to join and member_count. Page through the rest with GET /conversations/{id}/members.
 //	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
@@ -256,7 +399,7 @@ func (h *ConversationHandler) GetConversation(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	conv, err := h.convs.GetByID(r.Context(), convID)
+	conv, err := h.convs.GetByIDPreview(r.Context(), convID)
 	if err != nil {
 		if errors.Is(err, domain.ErrConversationNotFound) {
 			writeError(w, http.StatusNotFound, "conversation not found")
@@ -277,9 +420,212 @@ func (h *ConversationHandler) GetConversation(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	// Per-member counts are group insights, visible to admins only. The
+	// caller may not be among the previewed members, so ask for their role.
+	isAdmin := false
+	if conv.Type == domain.ConversationTypeGroup {
+		role, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+		isAdmin = err == nil && role == domain.MemberRoleAdmin
+	}
+	stats, err := h.convs.GetStats(r.Context(), convID, isAdmin)
+	if err == nil {
+		conv.Stats = stats
+	} else {
+		h.logger.Warn("failed to fetch conversation stats", "error", err)
+	}
+
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// GetMembers godoc
+//
+//	@Summary		List conversation members
+//	@Description	Page through a conversation's members in join order. Pass a page's next_before as before to get the next one.
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			limit	query		int	false	"Page size (default 50, max 100)"
+//	@Param			before	query		string	false	"next_before from the previous page"
+//	@Success		200	{object}	object{members=[]domain.ConversationMember,count=int,has_more=bool,next_before=string}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/members [get]
+func (h *ConversationHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	cursor, err := domain.ParseMemberCursor(r.URL.Query().Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	members, hasMore, err := h.convs.GetMembers(r.Context(), convID, limit, cursor)
+	if err != nil {
+		h.logger.Error("get members failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get members")
+		return
+	}
+	if members == nil {
+		members = []domain.ConversationMember{}
+	}
+
+	resp := map[string]interface{}{
+		"members":  members,
+		"count":    len(members),
+		"has_more": hasMore,
+	}
+	if hasMore {
+		resp["next_before"] = domain.NextMemberCursor(members).String()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetConversationInsights godoc
+//
+//	@Summary		Get conversation insights
+//	@Description	Get message, attachment and member counts, attachment storage, active days and top senders for a conversation. Admins only.
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	domain.ConversationInsights
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/insights [get]
+func (h *ConversationHandler) GetConversationInsights(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	role, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return
+	}
+	if role != domain.MemberRoleAdmin {
+		writeError(w, http.StatusForbidden, "only admins can view insights")
+		return
+	}
+
+	insights, err := h.convs.GetInsights(r.Context(), convID)
+	if err != nil {
+		h.logger.Error("get conversation insights failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get insights")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, insights)
+}
+
+// GetSavedConversation godoc
+//
+//	@Summary		Get saved messages conversation
+//	@Description	Get the authenticated user's "Saved Messages" self-conversation, creating it on first access
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		401	{object}	map[string]string
+//	@Router			/conversations/saved [get]
+func (h *ConversationHandler) GetSavedConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	conv, err := h.convs.GetOrCreateSavedConversation(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("get saved conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get saved conversation")
+		return
+	}
+
 	writeJSON(w, http.StatusOK, conv)
 }
 
+// GetSharedConversations godoc
+//
+//	@Summary		Get groups shared with a user
+//	@Description	List the unarchived groups you and another user are both in, for their profile. DMs are never included.
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			username	path		string	true	"Username"
+//	@Success		200	{object}	object{conversations=[]domain.Conversation,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/users/{username}/shared [get]
+func (h *ConversationHandler) GetSharedConversations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	other, err := h.users.GetByUsername(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if other.ID == userID {
+		writeError(w, http.StatusBadRequest, "cannot list groups shared with yourself")
+		return
+	}
+
+	// Always scoped to the caller, so nobody can see which groups two other users share
+	convs, err := h.convs.GetSharedConversations(r.Context(), userID, other.ID)
+	if err != nil {
+		h.logger.Error("get shared conversations failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get shared conversations")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"conversations": convs,
+		"count":         len(convs),
+	})
+}
+
 // AddMember godoc
 //
 //	@Summary		Add member to conversation
@@ -289,10 +635,11 @@ func (h *ConversationHandler) GetConversation(w http.ResponseWriter, r *http.Req
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			id	path		string	true	"Conversation ID"
-//	@Param			request	body		object{user_id=string}	true	"User to add"
+//	@Param			request	body		object{user_id=string,role=string}	true	"User to add (role defaults to the group's default member role)"
 //	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
 //	@Router			/conversations/{id}/members [post]
 func (h *ConversationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
@@ -309,6 +656,7 @@ func (h *ConversationHandler) AddMember(w http.ResponseWriter, r *http.Request)
 
 	var input struct {
 		UserID string `json:"user_id"`
+		Role   string `json:"role"` // optional, "member" or "admin"
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -321,9 +669,9 @@ func (h *ConversationHandler) AddMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check caller is member
-	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
-	if err != nil || !isMember {
+	// Check caller is member and get their role
+	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
 		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
@@ -339,8 +687,26 @@ func (h *ConversationHandler) AddMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Resolve the new member's role, bounded by the caller's own
+	role, err := domain.ResolveInviteRole(callerRole, conv.DefaultMemberRole, domain.MemberRole(strings.ToLower(input.Role)), conv.AllowMemberAdds)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidRole):
+			writeError(w, http.StatusBadRequest, "role must be 'member', 'moderator' or 'admin'")
+		case errors.Is(err, domain.ErrRoleEscalation):
+			writeError(w, http.StatusForbidden, "only admins can add admins")
+		default:
+			writeError(w, http.StatusForbidden, "only admins can add members to this group")
+		}
+		return
+	}
+
 	// Add member
-	if err := h.convs.AddMember(r.Context(), convID, newMemberID, domain.MemberRoleMember); err != nil {
+	if err := h.convs.AddMember(r.Context(), convID, newMemberID, role); err != nil {
+		if errors.Is(err, domain.ErrBanned) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		h.logger.Error("add member failed", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to add member")
 		return
@@ -349,7 +715,7 @@ func (h *ConversationHandler) AddMember(w http.ResponseWriter, r *http.Request)
 	// Get new member's username for broadcast
 	newMember, err := h.users.GetByID(r.Context(), newMemberID)
 	if err == nil && h.broadcaster != nil {
-		if err := h.broadcaster.BroadcastMemberJoined(r.Context(), convID, newMemberID, newMember.Username, string(domain.MemberRoleMember), userID); err != nil {
+		if err := h.broadcaster.BroadcastMemberJoined(r.Context(), convID, newMemberID, newMember.Username, string(role), userID); err != nil {
 			h.logger.Error("failed to broadcast member joined", "error", err)
 		}
 	}
@@ -428,21 +794,21 @@ func (h *ConversationHandler) RemoveMember(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, map[string]string{"status": "member removed"})
 }
 
-// UpdateConversation godoc
+// LeaveConversation godoc
 //
-//	@Summary		Update conversation
-//	@Description	Update conversation title or settings
+//	@Summary		Leave group
+//	@Description	Remove yourself from a group. If you were its last admin, the oldest remaining member is promoted to admin. DMs can't be left.
 //	@Tags			conversations
-//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			id	path		string	true	"Conversation ID"
-//	@Param			request	body		object{title=string}	true	"Update details"
-//	@Success		200	{object}	domain.Conversation
+//	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id} [patch]
-func (h *ConversationHandler) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/leave [post]
+func (h *ConversationHandler) LeaveConversation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
@@ -455,86 +821,2480 @@ func (h *ConversationHandler) UpdateConversation(w http.ResponseWriter, r *http.
 		return
 	}
 
-	var input struct {
-		Title string `json:"title"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-
-	// Validate title
-	if input.Title == "" {
-		writeError(w, http.StatusBadRequest, "title is required")
-		return
-	}
-	if len(input.Title) > 100 {
-		writeError(w, http.StatusBadRequest, "title too long (max 100)")
-		return
-	}
-
-	// Check caller is admin
-	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	conv, err := h.convs.GetByID(r.Context(), convID)
 	if err != nil {
-		if errors.Is(err, domain.ErrNotMember) {
-			writeError(w, http.StatusForbidden, "not a member of this conversation")
+		if errors.Is(err, domain.ErrConversationNotFound) {
+			writeError(w, http.StatusNotFound, "conversation not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to check membership")
-		return
-	}
-
-	if callerRole != domain.MemberRoleAdmin {
-		writeError(w, http.StatusForbidden, "only admins can rename the group")
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
 		return
 	}
 
-	if err := h.convs.UpdateTitle(r.Context(), convID, input.Title); err != nil {
-		if errors.Is(err, domain.ErrConversationNotFound) {
-			writeError(w, http.StatusNotFound, "conversation not found")
-			return
+	promoted, err := leaveGroup(r.Context(), h.convs, conv, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotGroup):
+			writeError(w, http.StatusBadRequest, "only groups can be left")
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+		default:
+			h.logger.Error("leave conversation failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to leave conversation")
 		}
-		h.logger.Error("update conversation failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to update conversation")
 		return
 	}
 
-	// Broadcast the title update
 	if h.broadcaster != nil {
-		if err := h.broadcaster.BroadcastRoomUpdated(r.Context(), convID, input.Title, userID); err != nil {
-			h.logger.Error("failed to broadcast room updated", "error", err)
+		username := ""
+		for _, m := range conv.Members {
+			if m.UserID == userID && m.User != nil {
+				username = m.User.Username
+			}
+		}
+		if err := h.broadcaster.BroadcastMemberLeft(r.Context(), convID, userID, username, userID); err != nil {
+			h.logger.Error("failed to broadcast member left", "error", err)
+		}
+		if promoted != uuid.Nil {
+			if err := h.broadcaster.BroadcastMemberRoleChanged(r.Context(), convID, promoted, string(domain.MemberRoleAdmin), uuid.Nil); err != nil {
+				h.logger.Error("failed to broadcast member role changed", "error", err)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "left conversation"})
+}
+
+// UpdateMemberRole godoc
+//
+//	@Summary		Change member role
+//	@Description	Promote or demote a group member (admins only). The last admin can't be demoted.
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			userId	path		string	true	"User ID"
+//	@Param			request	body		object{role=string}	true	"New role: member, moderator or admin"
+//	@Success		200	{object}	domain.ConversationMember
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		409	{object}	map[string]string	"Last admin"
+//	@Router			/conversations/{id}/members/{userId}/role [patch]
+func (h *ConversationHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	targetUserID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var input struct {
+		Role domain.MemberRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || !input.Role.IsValid() {
+		writeError(w, http.StatusBadRequest, "role must be 'member', 'moderator' or 'admin'")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		if errors.Is(err, domain.ErrConversationNotFound) {
+			writeError(w, http.StatusNotFound, "conversation not found")
+			return
+		}
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return
+	}
+
+	if err := conv.ChangeMemberRole(userID, targetUserID, input.Role); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotGroup), errors.Is(err, domain.ErrInvalidRole):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrCannotRemoveAdmin):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusForbidden, "both users must be members of this conversation")
+		default:
+			writeError(w, http.StatusForbidden, err.Error())
+		}
+		return
+	}
+
+	if err := h.convs.UpdateMemberRole(r.Context(), convID, targetUserID, input.Role); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrCannotRemoveAdmin):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusNotFound, "member not found")
+		default:
+			h.logger.Error("update member role failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update member role")
+		}
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMemberRoleChanged(r.Context(), convID, targetUserID, string(input.Role), userID); err != nil {
+			h.logger.Error("failed to broadcast member role changed", "error", err)
+		}
+	}
+
+	for _, m := range conv.Members {
+		if m.UserID == targetUserID {
+			writeJSON(w, http.StatusOK, m)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "role updated"})
+}
+
+// SetMemberNickname godoc
+//
+//	@Summary		Set member nickname
+//	@Description	Set a nickname shown in place of a member's display name in this group only. Members can set their own; admins can set anyone's. An empty nickname clears it.
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			userId	path		string	true	"User ID"
+//	@Param			request	body		object{nickname=string}	true	"Nickname, or empty to clear"
+//	@Success		200	{object}	domain.ConversationMember
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/members/{userId}/nickname [put]
+func (h *ConversationHandler) SetMemberNickname(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	targetUserID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var input struct {
+		Nickname string `json:"nickname"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	nickname := ""
+	if strings.TrimSpace(input.Nickname) != "" {
+		nickname, err = h.filter.Clean(input.Nickname, domain.MaxNicknameLength)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		if errors.Is(err, domain.ErrConversationNotFound) {
+			writeError(w, http.StatusNotFound, "conversation not found")
+			return
+		}
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return
+	}
+
+	if err := conv.CanSetNickname(userID, targetUserID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotGroup):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusForbidden, "both users must be members of this conversation")
+		default:
+			writeError(w, http.StatusForbidden, "only admins can set other members' nicknames")
+		}
+		return
+	}
+
+	if nickname == "" {
+		err = h.convs.ClearNickname(r.Context(), convID, targetUserID)
+	} else {
+		err = h.convs.SetNickname(r.Context(), convID, targetUserID, userID, nickname)
+	}
+	if err != nil {
+		h.logger.Error("set nickname failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to set nickname")
+		return
+	}
+
+	// Re-read the member so a cleared nickname falls back to their own name
+	conv, err = h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return
+	}
+	for _, m := range conv.Members {
+		if m.UserID == targetUserID {
+			writeJSON(w, http.StatusOK, m)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "member not found")
+}
+
+// TransferOwnership godoc
+//
+//	@Summary		Transfer group ownership
+//	@Description	Hand ownership of a group to another member, who becomes an admin. Only the current owner may transfer (or an admin if the owner has left).
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{user_id=string}	true	"New owner"
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/transfer [post]
+func (h *ConversationHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.UserID == uuid.Nil {
+		writeError(w, http.StatusBadRequest, "user_id required")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		if errors.Is(err, domain.ErrConversationNotFound) {
+			writeError(w, http.StatusNotFound, "conversation not found")
+			return
+		}
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return
+	}
+
+	if err := conv.TransferOwnership(userID, input.UserID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotGroup), errors.Is(err, domain.ErrInvalidTransfer):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusForbidden, "both users must be members of this conversation")
+		default:
+			writeError(w, http.StatusForbidden, err.Error())
+		}
+		return
+	}
+
+	if err := h.convs.TransferOwnership(r.Context(), convID, userID, input.UserID); err != nil {
+		if errors.Is(err, domain.ErrNotOwner) || errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusConflict, "ownership changed, please retry")
+			return
+		}
+		h.logger.Error("transfer ownership failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to transfer ownership")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastOwnerChanged(r.Context(), convID, userID, input.UserID); err != nil {
+			h.logger.Error("failed to broadcast owner changed", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// BanMember godoc
+//
+//	@Summary		Ban user from conversation
+//	@Description	Remove a user from a group (if present), end their call participation, and block them from rejoining (admins only)
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			userId	path		string	true	"User ID to ban"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/bans/{userId} [post]
+func (h *ConversationHandler) BanMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	targetUserID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	conv, ok := h.authorizeBan(w, r, convID, userID, targetUserID)
+	if !ok {
+		return
+	}
+
+	wasMember, err := h.convs.BanFromConversation(r.Context(), convID, targetUserID, userID)
+	if err != nil {
+		h.logger.Error("ban member failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to ban user")
+		return
+	}
+
+	for _, evictor := range h.evictors {
+		evictor.EvictFromCall(r.Context(), convID, targetUserID)
+	}
+
+	// member_left also takes the target's open connections out of the room
+	if wasMember && h.broadcaster != nil {
+		targetUsername := ""
+		for _, m := range conv.Members {
+			if m.UserID == targetUserID && m.User != nil {
+				targetUsername = m.User.Username
+			}
+		}
+		if err := h.broadcaster.BroadcastMemberLeft(r.Context(), convID, targetUserID, targetUsername, userID); err != nil {
+			h.logger.Error("failed to broadcast member left", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "user banned"})
+}
+
+// UnbanMember godoc
+//
+//	@Summary		Unban user from conversation
+//	@Description	Lift a ban so the user can be added back to the group (admins only). Does not re-add them.
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			userId	path		string	true	"User ID to unban"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/bans/{userId} [delete]
+func (h *ConversationHandler) UnbanMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	targetUserID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if _, ok := h.authorizeBan(w, r, convID, userID, targetUserID); !ok {
+		return
+	}
+
+	if err := h.convs.UnbanFromConversation(r.Context(), convID, targetUserID); err != nil {
+		h.logger.Error("unban member failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unban user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "user unbanned"})
+}
+
+// writeTitleError maps a MessageFilter error on a group title to a response
+func writeTitleError(w http.ResponseWriter, err error, maxLen int) {
+	switch {
+	case errors.Is(err, domain.ErrEmptyText):
+		writeError(w, http.StatusBadRequest, "group title is required")
+	case errors.Is(err, domain.ErrTextTooLong):
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("title too long (max %d)", maxLen))
+	default:
+		writeError(w, http.StatusBadRequest, "title contains blocked words")
+	}
+}
+
+// searchQuery reads and cleans the q parameter, writing a 400 if it's unusable
+func (h *ConversationHandler) searchQuery(w http.ResponseWriter, r *http.Request) (string, bool) {
+	raw := r.URL.Query().Get("q")
+	if strings.TrimSpace(raw) == "" {
+		writeError(w, http.StatusBadRequest, "search query is required")
+		return "", false
+	}
+
+	query, err := domain.CleanSearchQuery(raw, h.maxSearchLen, h.maxSearchTerm)
+	switch {
+	case errors.Is(err, domain.ErrSearchQueryTooLong):
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("search query too long (max %d characters)", h.maxSearchLen))
+		return "", false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("search query too short (min %d characters)", domain.MinSearchQueryLength))
+		return "", false
+	}
+	return query, true
+}
+
+// searchPage reads the before_rank/before_created_at/before_id cursor and
+// limit for a search, writing a 400 if the cursor is malformed
+func searchPage(w http.ResponseWriter, r *http.Request) (*domain.SearchCursor, int, bool) {
+	q := r.URL.Query()
+	cursor, err := domain.ParseSearchCursor(q.Get("before_rank"), q.Get("before_created_at"), q.Get("before_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, 0, false
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	return cursor, limit, true
+}
+
+// searchFilter reads the from_user, after and before search filters, writing
+// a 400 if a date is malformed or the username is unknown
+func (h *ConversationHandler) searchFilter(w http.ResponseWriter, r *http.Request) (domain.SearchFilter, bool) {
+	var filter domain.SearchFilter
+	after, err := domain.ParseSearchDate(r.URL.Query().Get("after"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return filter, false
+	}
+	before, err := domain.ParseSearchDate(r.URL.Query().Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return filter, false
+	}
+	filter.After, filter.Before = after, before
+	if err := filter.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return filter, false
+	}
+
+	from := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("from_user")), "@")
+	if from == "" {
+		return filter, true
+	}
+	if id, err := uuid.Parse(from); err == nil {
+		filter.FromUserID = &id
+		return filter, true
+	}
+	user, err := h.users.GetByUsername(r.Context(), from)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			writeError(w, http.StatusBadRequest, "unknown user in from_user")
+			return filter, false
+		}
+		h.logger.Error("search sender lookup failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to search messages")
+		return filter, false
+	}
+	filter.FromUserID = &user.ID
+	return filter, true
+}
+
+// searchResponse builds a page of search results, echoing the cursor it was
+// requested with
+func searchResponse(messages []domain.Message, query string, cursor *domain.SearchCursor, hasMore bool) map[string]interface{} {
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+	resp := map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+		"query":    query,
+		"has_more": hasMore,
+	}
+	if cursor != nil {
+		resp["before_rank"] = cursor.BeforeRank
+		resp["before_created_at"] = cursor.BeforeCreatedAt
+		resp["before_id"] = cursor.BeforeID
+	}
+	return resp
+}
+
+// authorizeBan loads the conversation and checks the caller may ban or unban
+// the target, writing the error response if not
+func (h *ConversationHandler) authorizeBan(w http.ResponseWriter, r *http.Request, convID, userID, targetUserID uuid.UUID) (*domain.Conversation, bool) {
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		if errors.Is(err, domain.ErrConversationNotFound) {
+			writeError(w, http.StatusNotFound, "conversation not found")
+			return nil, false
+		}
+		h.logger.Error("get conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return nil, false
+	}
+
+	if err := conv.CanBan(userID, targetUserID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotGroup), errors.Is(err, domain.ErrInvalidBan):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, domain.ErrNotMember):
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+		default:
+			writeError(w, http.StatusForbidden, err.Error())
+		}
+		return nil, false
+	}
+
+	return conv, true
+}
+
+// UpdateConversation godoc
+//
+//	@Summary		Update conversation
+//	@Description	Update a group's title or read-only (announcement) mode, in which only admins may post (admins only)
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{title=string,read_only=bool}	true	"Fields to change"
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id} [patch]
+func (h *ConversationHandler) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		Title    *string `json:"title"`
+		ReadOnly *bool   `json:"read_only"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Title == nil && input.ReadOnly == nil {
+		writeError(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+
+	// Validate title
+	if input.Title != nil {
+		title, err := h.filter.Clean(*input.Title, h.maxTitleLen)
+		if err != nil {
+			writeTitleError(w, err, h.maxTitleLen)
+			return
+		}
+		input.Title = &title
+	}
+
+	// Check caller is admin
+	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return
+	}
+
+	if callerRole != domain.MemberRoleAdmin {
+		writeError(w, http.StatusForbidden, "only admins can update the group")
+		return
+	}
+
+	if input.Title != nil {
+		if err := h.convs.UpdateTitle(r.Context(), convID, *input.Title); err != nil {
+			if errors.Is(err, domain.ErrConversationNotFound) {
+				writeError(w, http.StatusNotFound, "conversation not found")
+				return
+			}
+			h.logger.Error("update conversation failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update conversation")
+			return
+		}
+
+		// Broadcast the title update
+		if h.broadcaster != nil {
+			if err := h.broadcaster.BroadcastRoomUpdated(r.Context(), convID, *input.Title, userID); err != nil {
+				h.logger.Error("failed to broadcast room updated", "error", err)
+			}
+		}
+	}
+
+	if input.ReadOnly != nil {
+		if err := h.convs.SetReadOnly(r.Context(), convID, *input.ReadOnly); err != nil {
+			if errors.Is(err, domain.ErrConversationNotFound) {
+				writeError(w, http.StatusNotFound, "conversation not found")
+				return
+			}
+			h.logger.Error("set read-only failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update conversation")
+			return
+		}
+
+		if h.broadcaster != nil {
+			if err := h.broadcaster.BroadcastReadOnlyChanged(r.Context(), convID, *input.ReadOnly, userID); err != nil {
+				h.logger.Error("failed to broadcast read-only change", "error", err)
+			}
 		}
 	}
 
 	// Fetch updated conversation
 	conv, err := h.convs.GetByID(r.Context(), convID)
 	if err != nil {
-		h.logger.Error("fetch conversation failed", "error", err)
-		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+		h.logger.Error("fetch conversation failed", "error", err)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// UpdateSearchLanguage godoc
+//
+//	@Summary		Set conversation search language
+//	@Description	Choose the text-search language used to index and search this conversation's messages (admins only in groups). Existing messages are re-indexed.
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{language=string}	true	"Postgres text-search config, e.g. 'french' or 'simple'"
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/language [put]
+func (h *ConversationHandler) UpdateSearchLanguage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	lang, err := domain.ParseSearchLanguage(input.Language)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	if conv.Type == domain.ConversationTypeGroup && callerRole != domain.MemberRoleAdmin {
+		writeError(w, http.StatusForbidden, "only admins can change group settings")
+		return
+	}
+
+	if err := h.convs.UpdateSearchLanguage(r.Context(), convID, lang); err != nil {
+		h.logger.Error("update search language failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update search language")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastRoomUpdated(r.Context(), convID, conv.Title, userID); err != nil {
+			h.logger.Error("failed to broadcast room updated", "error", err)
+		}
+	}
+
+	conv.SearchLanguage = lang
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// UpdateMemberSettings godoc
+//
+//	@Summary		Update group member settings
+//	@Description	Set the default role for invited members and whether non-admins may add members (admins only)
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{default_member_role=string,allow_member_adds=bool}	true	"Settings to change"
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/settings [patch]
+func (h *ConversationHandler) UpdateMemberSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		DefaultMemberRole *string `json:"default_member_role"`
+		AllowMemberAdds   *bool   `json:"allow_member_adds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Check caller is admin
+	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return
+	}
+	if callerRole != domain.MemberRoleAdmin {
+		writeError(w, http.StatusForbidden, "only admins can change group settings")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	if conv.Type != domain.ConversationTypeGroup {
+		writeError(w, http.StatusBadRequest, "settings only apply to groups")
+		return
+	}
+
+	// Merge with current values
+	defaultRole := conv.DefaultMemberRole
+	if input.DefaultMemberRole != nil {
+		defaultRole = domain.MemberRole(strings.ToLower(*input.DefaultMemberRole))
+		if !defaultRole.IsValid() {
+			writeError(w, http.StatusBadRequest, "default_member_role must be 'member', 'moderator' or 'admin'")
+			return
+		}
+	}
+	allowMemberAdds := conv.AllowMemberAdds
+	if input.AllowMemberAdds != nil {
+		allowMemberAdds = *input.AllowMemberAdds
+	}
+
+	if err := h.convs.UpdateMemberSettings(r.Context(), convID, defaultRole, allowMemberAdds); err != nil {
+		h.logger.Error("update member settings failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update settings")
+		return
+	}
+
+	// Let connected members refresh the conversation
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastRoomUpdated(r.Context(), convID, conv.Title, userID); err != nil {
+			h.logger.Error("failed to broadcast room updated", "error", err)
+		}
+	}
+
+	conv.DefaultMemberRole = defaultRole
+	conv.AllowMemberAdds = allowMemberAdds
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// SetDisappearingMessages godoc
+//
+//	@Summary		Set disappearing messages
+//	@Description	Set how long new messages in the conversation live before they're deleted; 0 turns disappearing messages off. Admins only in groups; either member in a DM.
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{ttl_seconds=int}	true	"Message lifetime in seconds (max 90 days)"
+//	@Success		200	{object}	domain.Conversation
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/disappearing [patch]
+func (h *ConversationHandler) SetDisappearingMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		TTLSeconds int `json:"ttl_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := domain.ValidateMessageTTL(input.TTLSeconds); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	callerRole, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	if conv.Type == domain.ConversationTypeGroup && callerRole != domain.MemberRoleAdmin {
+		writeError(w, http.StatusForbidden, "only admins can change disappearing messages")
+		return
+	}
+
+	if err := h.convs.SetMessageTTL(r.Context(), convID, input.TTLSeconds); err != nil {
+		h.logger.Error("set message ttl failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update conversation")
+		return
+	}
+
+	// Let connected members pick up the new lifetime
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageTTLChanged(r.Context(), convID, input.TTLSeconds, userID); err != nil {
+			h.logger.Error("failed to broadcast message ttl change", "error", err)
+		}
+	}
+
+	conv.MessageTTLSeconds = input.TTLSeconds
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// ============================================================================
+// Messages
+// ============================================================================
+
+// GetMessages godoc
+//
+//	@Summary		Get messages
+//	@Description	Get messages from a conversation with pagination
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			before	query		string	false	"Cursor for pagination: created_at of the oldest message loaded"
+//	@Param			before_seq	query		int	false	"seq of that message, so messages sent in the same instant aren't skipped"
+//	@Param			limit	query		int	false	"Number of messages (default 50)"
+//	@Success		200	{object}	object{messages=[]domain.Message,has_more=bool}
+//	@Failure		401	{object}	map[string]string
+//	@Router			/conversations/{id}/messages [get]
+func (h *ConversationHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	// Check membership
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	// Parse pagination
+	var before *time.Time
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		t, err := time.Parse(time.RFC3339, beforeStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'before' timestamp")
+			return
+		}
+		before = &t
+	}
+	var beforeSeq *int64
+	if seqStr := r.URL.Query().Get("before_seq"); seqStr != "" {
+		seq, err := strconv.ParseInt(seqStr, 10, 64)
+		if err != nil || before == nil {
+			writeError(w, http.StatusBadRequest, "before_seq must be an integer and given with before")
+			return
+		}
+		beforeSeq = &seq
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	messages, err := h.convs.GetMessages(r.Context(), convID, userID, before, beforeSeq, limit)
+	if err != nil {
+		h.logger.Error("get messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get messages")
+		return
+	}
+
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+
+	// Populate receipt statuses for messages sent by the current user
+	if len(messages) > 0 {
+		// Collect message IDs sent by this user
+		var ownMsgIDs []uuid.UUID
+		for _, msg := range messages {
+			if msg.SenderID != nil && *msg.SenderID == userID {
+				ownMsgIDs = append(ownMsgIDs, msg.ID)
+			}
+		}
+
+		// Get receipt statuses in bulk
+		if len(ownMsgIDs) > 0 {
+			statuses, err := h.convs.GetMessageReceiptStatuses(r.Context(), ownMsgIDs)
+			if err != nil {
+				h.logger.Warn("failed to get receipt statuses", "error", err)
+			} else {
+				// Apply statuses to messages
+				for i := range messages {
+					if messages[i].SenderID != nil && *messages[i].SenderID == userID {
+						messages[i].SetReceiptStatus(statuses[messages[i].ID])
+					}
+				}
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
+
+// SendMessage godoc
+//
+//	@Summary		Send message
+//	@Description	Send a new message to a conversation, optionally as a reply quoting an earlier message in it. Attachments (attachment_ids, or the older single attachment_id; up to 10) must be the sender's own finished uploads to the conversation; the message carries each one's kind and, for voice notes, duration_ms.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{body_text=string,attachment_id=string,attachment_ids=[]string,reply_to_id=string}	true	"Message content"
+//	@Success		201	{object}	domain.Message
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		429	{object}	map[string]string	"Muted for sending too fast"
+//	@Router			/conversations/{id}/messages [post]
+func (h *ConversationHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		BodyText      string      `json:"body_text"`
+		AttachmentID  *uuid.UUID  `json:"attachment_id"`
+		AttachmentIDs []uuid.UUID `json:"attachment_ids"`
+		ReplyToID     *uuid.UUID  `json:"reply_to_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Validate message; a voice note or file may be sent without text
+	input.BodyText = strings.TrimSpace(input.BodyText)
+	attachmentIDs, err := domain.MessageAttachmentIDs(input.AttachmentID, input.AttachmentIDs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.BodyText == "" && len(attachmentIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "message cannot be empty")
+		return
+	}
+	if len(input.BodyText) > h.maxMessageLen {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("message too long (max %d chars)", h.maxMessageLen))
+		return
+	}
+	if !h.checkFlood(w, userID) {
+		return
+	}
+
+	// Check membership, and that the caller may post if the conversation is read-only
+	role, readOnly, err := h.convs.GetPostingRole(r.Context(), convID, userID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		writeError(w, http.StatusForbidden, "read_only")
+		return
+	}
+
+	// A block in either direction closes an existing DM
+	if err := checkDMBlock(r.Context(), h.convs, convID, userID); err != nil {
+		if errors.Is(err, domain.ErrUserBlocked) {
+			writeError(w, http.StatusForbidden, "blocked")
+			return
+		}
+		h.logger.Error("check block failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to send message")
+		return
+	}
+
+	// Create message
+	msg := &domain.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       &userID,
+		BodyText:       input.BodyText,
+		ReplyToID:      input.ReplyToID,
+		CreatedAt:      time.Now(),
+	}
+
+	if len(attachmentIDs) > 0 {
+		if h.attachments == nil {
+			writeError(w, http.StatusBadRequest, "attachments are not enabled")
+			return
+		}
+		attachments := make([]*domain.Attachment, 0, len(attachmentIDs))
+		for _, id := range attachmentIDs {
+			att, _ := h.attachments.GetAttachmentByID(r.Context(), id.String())
+			if err := domain.CheckMessageAttachment(att, convID.String(), userID.String()); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			attachments = append(attachments, att)
+		}
+		msg.SetAttachments(attachments)
+	}
+
+	if input.ReplyToID != nil {
+		target, err := h.convs.GetMessageByID(r.Context(), *input.ReplyToID)
+		if err == nil {
+			err = domain.CheckReplyTarget(target, convID, userID)
+		}
+		if errors.Is(err, domain.ErrMessageNotFound) || errors.Is(err, domain.ErrInvalidReply) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			h.logger.Error("get reply target failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to send message")
+			return
+		}
+		msg.ReplyTo = target.ReplyPreview()
+	}
+
+	if err := h.convs.CreateMessage(r.Context(), msg); err != nil {
+		h.logger.Error("create message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to send message")
+		return
+	}
+
+	h.linkPreviews.Enqueue(msg)
+
+	// The draft has been sent
+	if err := h.convs.DeleteDraft(r.Context(), convID, userID); err != nil {
+		h.logger.Error("clear draft failed", "error", err)
+	}
+
+	// Get sender info
+	user, _ := h.users.GetByID(r.Context(), userID)
+	senderUsername := ""
+	if user != nil {
+		pub := user.ToPublic()
+		msg.Sender = &pub
+		senderUsername = user.Username
+	}
+	h.mentions.Record(r.Context(), msg, senderUsername)
+
+	writeJSON(w, http.StatusCreated, msg)
+}
+
+// ScheduleMessage godoc
+//
+//	@Summary		Schedule a message
+//	@Description	Compose a message now and have it sent to the conversation at send_at, which must be in the future and within 30 days
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{body_text=string,send_at=string}	true	"Message content and RFC 3339 send time"
+//	@Success		201	{object}	domain.ScheduledMessage
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		409	{object}	map[string]string	"Too many messages already scheduled"
+//	@Router			/conversations/{id}/messages/schedule [post]
+func (h *ConversationHandler) ScheduleMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		BodyText string    `json:"body_text"`
+		SendAt   time.Time `json:"send_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	input.BodyText = strings.TrimSpace(input.BodyText)
+	if input.BodyText == "" {
+		writeError(w, http.StatusBadRequest, "message cannot be empty")
+		return
+	}
+	if len(input.BodyText) > h.maxMessageLen {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("message too long (max %d chars)", h.maxMessageLen))
+		return
+	}
+	if err := domain.ValidateSendAt(input.SendAt, time.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The same rules as sending now; the dispatcher checks them again at send_at
+	role, readOnly, err := h.convs.GetPostingRole(r.Context(), convID, userID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		writeError(w, http.StatusForbidden, "read_only")
+		return
+	}
+	if err := checkDMBlock(r.Context(), h.convs, convID, userID); err != nil {
+		if errors.Is(err, domain.ErrUserBlocked) {
+			writeError(w, http.StatusForbidden, "blocked")
+			return
+		}
+		h.logger.Error("check block failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to schedule message")
+		return
+	}
+
+	scheduled := &domain.ScheduledMessage{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       userID,
+		BodyText:       input.BodyText,
+		SendAt:         input.SendAt.UTC(),
+	}
+	if err := h.convs.ScheduleMessage(r.Context(), scheduled); err != nil {
+		if errors.Is(err, domain.ErrTooManyScheduledMessages) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("too many scheduled messages (max %d)", domain.MaxPendingScheduledMessages))
+			return
+		}
+		h.logger.Error("schedule message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to schedule message")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, scheduled)
+}
+
+// ListScheduledMessages godoc
+//
+//	@Summary		List scheduled messages
+//	@Description	List the caller's messages waiting to be sent to a conversation, soonest first
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	object{scheduled=[]domain.ScheduledMessage,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/scheduled [get]
+func (h *ConversationHandler) ListScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	scheduled, err := h.convs.ListScheduledMessages(r.Context(), convID, userID)
+	if err != nil {
+		h.logger.Error("list scheduled messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list scheduled messages")
+		return
+	}
+	if scheduled == nil {
+		scheduled = []domain.ScheduledMessage{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scheduled": scheduled,
+		"count":     len(scheduled),
+	})
+}
+
+// CancelScheduledMessage godoc
+//
+//	@Summary		Cancel a scheduled message
+//	@Description	Delete one of the caller's scheduled messages before it is sent
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Scheduled message ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/scheduled/{id} [delete]
+func (h *ConversationHandler) CancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid scheduled message ID")
+		return
+	}
+
+	if err := h.convs.CancelScheduledMessage(r.Context(), id, userID); err != nil {
+		if errors.Is(err, domain.ErrScheduledMessageNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Error("cancel scheduled message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to cancel scheduled message")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "scheduled message cancelled"})
+}
+
+// SendDirectMessage godoc
+//
+//	@Summary		Message a user directly
+//	@Description	Send a message to a user by username, starting a DM with them if there isn't one yet (unless DM auto-creation is disabled)
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			username	path		string	true	"Recipient's username"
+//	@Param			request	body		object{body_text=string}	true	"Message content"
+//	@Success		201	{object}	object{conversation=domain.Conversation,message=domain.Message,created=bool}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		429	{object}	map[string]string	"Muted for sending too fast"
+//	@Router			/users/{username}/messages [post]
+func (h *ConversationHandler) SendDirectMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input struct {
+		BodyText string `json:"body_text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	input.BodyText = strings.TrimSpace(input.BodyText)
+	if input.BodyText == "" {
+		writeError(w, http.StatusBadRequest, "message cannot be empty")
+		return
+	}
+	if len(input.BodyText) > h.maxMessageLen {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("message too long (max %d chars)", h.maxMessageLen))
+		return
+	}
+	if !h.checkFlood(w, userID) {
+		return
+	}
+
+	recipient, err := h.users.GetByUsername(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if recipient.ID == userID {
+		writeError(w, http.StatusBadRequest, "cannot message yourself")
+		return
+	}
+
+	msg := &domain.Message{
+		ID:        uuid.New(),
+		SenderID:  &userID,
+		BodyText:  input.BodyText,
+		CreatedAt: time.Now(),
+	}
+	convID, created, err := sendDirectMessage(r.Context(), h.convs, msg, recipient.ID, h.autoCreateDMs)
+	switch {
+	case errors.Is(err, domain.ErrUserBlocked):
+		writeError(w, http.StatusForbidden, "cannot message this user")
+		return
+	case errors.Is(err, domain.ErrConversationNotFound):
+		writeError(w, http.StatusNotFound, "no conversation with this user")
+		return
+	case err != nil:
+		h.logger.Error("send direct message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to send message")
+		return
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		h.logger.Error("fetch conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get conversation")
+		return
+	}
+	pubRecipient := recipient.ToPublic()
+	conv.OtherUser = &pubRecipient
+
+	username := ""
+	if user, _ := h.users.GetByID(r.Context(), userID); user != nil {
+		pub := user.ToPublic()
+		msg.Sender = &pub
+		username = pub.Username
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageBatch(r.Context(), convID, []domain.Message{*msg}, username); err != nil {
+			h.logger.Warn("failed to broadcast direct message", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"conversation": conv,
+		"message":      msg,
+		"created":      created,
+	})
+}
+
+// ForwardMessages godoc
+//
+//	@Summary		Forward messages
+//	@Description	Forward a selection of messages to another conversation, preserving their original order. The batch is all-or-nothing.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		object{message_ids=[]string,target_conversation_id=string}	true	"Messages to forward (max 50)"
+//	@Success		201	{object}	object{messages=[]domain.Message,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		429	{object}	map[string]string	"Muted for sending too fast"
+//	@Router			/messages/forward [post]
+func (h *ConversationHandler) ForwardMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input struct {
+		MessageIDs           []uuid.UUID `json:"message_ids"`
+		TargetConversationID uuid.UUID   `json:"target_conversation_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.TargetConversationID == uuid.Nil {
+		writeError(w, http.StatusBadRequest, "target_conversation_id required")
+		return
+	}
+	if len(input.MessageIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "message_ids required")
+		return
+	}
+	if len(input.MessageIDs) > domain.MaxForwardBatch {
+		writeError(w, http.StatusBadRequest, "too many messages (max 50)")
+		return
+	}
+	// A forwarded batch counts as one send
+	if !h.checkFlood(w, userID) {
+		return
+	}
+
+	// Check membership of the target, and that the caller may post there
+	role, readOnly, err := h.convs.GetPostingRole(r.Context(), input.TargetConversationID, userID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "not a member of target conversation")
+		return
+	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		writeError(w, http.StatusForbidden, "read_only")
+		return
+	}
+
+	sources, err := h.convs.GetMessagesByIDs(r.Context(), input.MessageIDs)
+	if err != nil {
+		h.logger.Error("get forward sources failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to forward messages")
+		return
+	}
+
+	// Whispers the caller wasn't part of are treated as missing
+	for id, m := range sources {
+		if !m.VisibleToUser(userID) {
+			delete(sources, id)
+		}
+	}
+
+	// Check membership of every source conversation
+	sourceConvs := make([]uuid.UUID, 0, len(sources))
+	for _, m := range sources {
+		sourceConvs = append(sourceConvs, m.ConversationID)
+	}
+	accessible, err := h.convs.GetMemberConversations(r.Context(), userID, sourceConvs)
+	if err != nil {
+		h.logger.Error("check source membership failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to forward messages")
+		return
+	}
+
+	forwarded, inaccessible, err := domain.BuildForwardBatch(input.MessageIDs, sources, accessible, input.TargetConversationID, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrSourceNotFound) {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"error":       err.Error(),
+				"message_ids": inaccessible,
+			})
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.convs.CreateMessages(r.Context(), forwarded); err != nil {
+		h.logger.Error("forward messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to forward messages")
+		return
+	}
+
+	// Get sender info
+	user, _ := h.users.GetByID(r.Context(), userID)
+	username := ""
+	if user != nil {
+		pub := user.ToPublic()
+		username = pub.Username
+		for i := range forwarded {
+			forwarded[i].Sender = &pub
+		}
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageBatch(r.Context(), input.TargetConversationID, forwarded, username); err != nil {
+			h.logger.Error("failed to broadcast forwarded messages", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"messages": forwarded,
+		"count":    len(forwarded),
+	})
+}
+
+// ============================================================================
+// Blocking
+// ============================================================================
+
+// BlockUser godoc
+//
+//	@Summary		Block user
+//	@Description	Block a user from contacting you
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			username	path		string	true	"Username to block"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/blocks/{username} [post]
+func (h *ConversationHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	username := r.PathValue("username")
+	targetUser, err := h.users.GetByUsername(r.Context(), username)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if targetUser.ID == userID {
+		writeError(w, http.StatusBadRequest, "cannot block yourself")
+		return
+	}
+
+	if err := h.convs.Block(r.Context(), userID, targetUser.ID); err != nil {
+		h.logger.Error("block user failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to block user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "user blocked"})
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblock user
+//	@Description	Remove a user from your block list
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			username	path		string	true	"Username to unblock"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/blocks/{username} [delete]
+func (h *ConversationHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	username := r.PathValue("username")
+	targetUser, err := h.users.GetByUsername(r.Context(), username)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := h.convs.Unblock(r.Context(), userID, targetUser.ID); err != nil {
+		h.logger.Error("unblock user failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unblock user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "user unblocked"})
+}
+
+// ============================================================================
+// Starred Messages
+// ============================================================================
+
+// StarMessage godoc
+//
+//	@Summary		Star message
+//	@Description	Add a message to your starred list
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Message ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/messages/{id}/star [post]
+func (h *ConversationHandler) StarMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	messageID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	// Get the message to check membership
+	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		h.logger.Error("get message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get message")
+		return
+	}
+	if !msg.VisibleToUser(userID) {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	// Check membership
+	isMember, err := h.convs.IsMember(r.Context(), msg.ConversationID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	if err := h.convs.StarMessage(r.Context(), userID, messageID); err != nil {
+		h.logger.Error("star message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to star message")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.NotifyMessageStarred(r.Context(), userID, messageID, &msg.ConversationID, true); err != nil {
+			h.logger.Error("failed to notify star", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "message starred"})
+}
+
+// UnstarMessage godoc
+//
+//	@Summary		Unstar message
+//	@Description	Remove a message from your starred list
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Message ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/messages/{id}/star [delete]
+func (h *ConversationHandler) UnstarMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	messageID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	if err := h.convs.UnstarMessage(r.Context(), userID, messageID); err != nil {
+		h.logger.Error("unstar message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unstar message")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.NotifyMessageStarred(r.Context(), userID, messageID, nil, false); err != nil {
+			h.logger.Error("failed to notify unstar", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "message unstarred"})
+}
+
+// EditMessage godoc
+//
+//	@Summary		Edit message
+//	@Description	Replace the text of a message you sent, within the edit window (24h by default). The previous text is kept in the message's edit history.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		string						true	"Message ID"
+//	@Param			request	body		object{body_text=string}	true	"New message text"
+//	@Success		200		{object}	domain.Message
+//	@Failure		400		{object}	map[string]string
+//	@Failure		401		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string	"Not the sender, the edit window has closed, or the conversation is read-only"
+//	@Failure		404		{object}	map[string]string
+//	@Router			/messages/{id} [patch]
+func (h *ConversationHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	messageID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	var input struct {
+		BodyText string `json:"body_text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	input.BodyText = strings.TrimSpace(input.BodyText)
+	if input.BodyText == "" {
+		writeError(w, http.StatusBadRequest, "message cannot be empty")
+		return
+	}
+	if len(input.BodyText) > h.maxMessageLen {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("message too long (max %d chars)", h.maxMessageLen))
+		return
+	}
+
+	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		h.logger.Error("get message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get message")
+		return
+	}
+
+	if err := msg.CanEdit(userID, h.editWindow, time.Now()); err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	// A sender who has since left, or who may no longer post in a read-only
+	// conversation, can no longer change what the room sees
+	role, readOnly, err := h.convs.GetPostingRole(r.Context(), msg.ConversationID, userID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		writeError(w, http.StatusForbidden, "read_only")
+		return
+	}
+
+	// Nothing changed: don't record an empty edit
+	if input.BodyText == msg.BodyText {
+		writeJSON(w, http.StatusOK, msg)
+		return
+	}
+
+	editedAt, err := h.convs.EditMessage(r.Context(), messageID, input.BodyText)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		h.logger.Error("edit message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to edit message")
+		return
+	}
+	msg.BodyText = input.BodyText
+	msg.EditedAt = &editedAt
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageEdited(r.Context(), msg, userID); err != nil {
+			h.logger.Error("failed to broadcast message edit", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// DeleteMessage godoc
+//
+//	@Summary		Delete message
+//	@Description	Delete a message you sent (only the sender or group admin can delete)
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Message ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/messages/{id} [delete]
+func (h *ConversationHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	messageID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	// Get message to check ownership and conversation
+	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		h.logger.Error("get message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get message")
+		return
+	}
+
+	// Check permissions: sender can delete their own message, or admins and moderators can delete any in the group
+	role, convType, _ := h.convs.GetModerationContext(r.Context(), msg.ConversationID, userID)
+	if !msg.DeletableBy(userID, role, convType) {
+		writeError(w, http.StatusForbidden, "you can only delete your own messages")
+		return
+	}
+
+	// Delete the message
+	if err := h.convs.DeleteMessage(r.Context(), messageID); err != nil {
+		h.logger.Error("delete message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete message")
+		return
+	}
+
+	// Broadcast deletion to room
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageDeleted(r.Context(), msg, userID); err != nil {
+			h.logger.Error("failed to broadcast message deletion", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "message deleted"})
+}
+
+// DeleteMessages godoc
+//
+//	@Summary		Delete messages in bulk
+//	@Description	Delete up to 100 messages at once, or clear history sent before a time. Each message follows the single-delete rules: senders can delete their own, and in groups admins and moderators any. When clearing history, members only clear their own messages. Nothing is deleted unless every listed message may be.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		string									true	"Conversation ID"
+//	@Param			request	body		object{message_ids=[]string,before=string}	true	"Message IDs, or an RFC 3339 time to clear history before"
+//	@Success		200	{object}	object{message_ids=[]string,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/messages/delete [post]
+func (h *ConversationHandler) DeleteMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		MessageIDs []uuid.UUID `json:"message_ids"`
+		Before     *time.Time  `json:"before"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := domain.CheckDeleteBatch(input.MessageIDs, input.Before); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, convType, err := h.convs.GetModerationContext(r.Context(), convID, userID)
+	if errors.Is(err, domain.ErrNotMember) {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+	if err != nil {
+		h.logger.Error("get moderation context failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete messages")
+		return
+	}
+
+	var deleted []domain.Message
+	if input.Before != nil {
+		// Members clear only their own history; moderators clear everyone's
+		var senderID *uuid.UUID
+		if !domain.CanModerateMessagesIn(convType, role) {
+			senderID = &userID
+		}
+		deleted, err = h.convs.DeleteMessagesBefore(r.Context(), convID, *input.Before, senderID)
+	} else {
+		var msgs map[uuid.UUID]domain.Message
+		msgs, err = h.convs.GetMessagesByIDs(r.Context(), input.MessageIDs)
+		if err != nil {
+			h.logger.Error("get messages failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to delete messages")
+			return
+		}
+		for _, id := range input.MessageIDs {
+			msg, found := msgs[id]
+			if !found || msg.ConversationID != convID {
+				writeError(w, http.StatusNotFound, "message not found: "+id.String())
+				return
+			}
+			if !msg.DeletableBy(userID, role, convType) {
+				writeError(w, http.StatusForbidden, "you can only delete your own messages")
+				return
+			}
+		}
+		deleted, err = h.convs.DeleteMessages(r.Context(), convID, input.MessageIDs)
+	}
+	if err != nil {
+		h.logger.Error("delete messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete messages")
+		return
+	}
+
+	if h.broadcaster != nil && len(deleted) > 0 {
+		if err := h.broadcaster.BroadcastMessagesDeleted(r.Context(), convID, deleted, userID); err != nil {
+			h.logger.Error("failed to broadcast message deletion", "error", err)
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(deleted))
+	for _, m := range deleted {
+		ids = append(ids, m.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message_ids": ids,
+		"count":       len(ids),
+	})
+}
+
+// PinMessage godoc
+//
+//	@Summary		Pin message
+//	@Description	Pin a message for everyone in the conversation, optionally for a limited time (admins and moderators only in groups). New pins go to the top of the pinned list. Whispers can't be pinned.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			messageId	path		string	true	"Message ID"
+//	@Param			request	body		object{duration_seconds=int}	false	"Pin lifetime; omit or 0 to pin indefinitely"
+//	@Success		200	{object}	domain.PinnedMessage
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		409	{object}	map[string]string	"Pin limit reached"
+//	@Router			/conversations/{id}/messages/{messageId}/pin [post]
+func (h *ConversationHandler) PinMessage(w http.ResponseWriter, r *http.Request) {
+	userID, convID, messageID, ok := h.authorizePin(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		DurationSeconds int64 `json:"duration_seconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	expiresAt, err := domain.PinExpiry(time.Now(), input.DurationSeconds)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pin, err := h.convs.PinMessage(r.Context(), convID, messageID, userID, expiresAt, h.maxPins)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		if errors.Is(err, domain.ErrTooManyPins) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrCannotPinWhisper) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("pin message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to pin message")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessagePinned(r.Context(), pin, userID); err != nil {
+			h.logger.Error("failed to broadcast message pinned", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, pin)
+}
+
+// UnpinMessage godoc
+//
+//	@Summary		Unpin message
+//	@Description	Remove a pinned message (admins and moderators only in groups)
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			messageId	path		string	true	"Message ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/messages/{messageId}/pin [delete]
+func (h *ConversationHandler) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	userID, convID, messageID, ok := h.authorizePin(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := h.convs.UnpinMessage(r.Context(), convID, messageID)
+	if err != nil {
+		h.logger.Error("unpin message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unpin message")
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "message is not pinned")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastMessageUnpinned(r.Context(), convID, messageID, &userID); err != nil {
+			h.logger.Error("failed to broadcast message unpinned", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "message unpinned"})
+}
+
+// GetPinnedMessages godoc
+//
+//	@Summary		Get pinned messages
+//	@Description	List a conversation's pinned messages in their manual order, excluding expired pins
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	object{pins=[]domain.PinnedMessage,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/pinned [get]
+//	@Router			/conversations/{id}/pins [get]
+func (h *ConversationHandler) GetPinnedMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	pins, err := h.convs.GetPinnedMessages(r.Context(), convID, userID)
+	if err != nil {
+		h.logger.Error("get pinned messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get pinned messages")
+		return
+	}
+
+	if pins == nil {
+		pins = []domain.PinnedMessage{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pins":  pins,
+		"count": len(pins),
+	})
+}
+
+// ReorderPins godoc
+//
+//	@Summary		Reorder pinned messages
+//	@Description	Set the manual order of a conversation's pins (admins and moderators only in groups). The list must contain every active pin exactly once.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Param			request	body		object{message_ids=[]string}	true	"Pinned message IDs, top first"
+//	@Success		200	{object}	object{pins=[]domain.PinnedMessage,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/pinned/order [put]
+func (h *ConversationHandler) ReorderPins(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	var input struct {
+		MessageIDs []uuid.UUID `json:"message_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if !h.checkCanPin(w, r, convID, userID) {
+		return
+	}
+
+	if err := h.convs.ReorderPins(r.Context(), convID, input.MessageIDs); err != nil {
+		if errors.Is(err, domain.ErrInvalidPinOrder) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("reorder pins failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to reorder pins")
+		return
+	}
+
+	if h.broadcaster != nil {
+		if err := h.broadcaster.BroadcastPinsReordered(r.Context(), convID, input.MessageIDs, userID); err != nil {
+			h.logger.Error("failed to broadcast pins reordered", "error", err)
+		}
+	}
+
+	pins, err := h.convs.GetPinnedMessages(r.Context(), convID, userID)
+	if err != nil {
+		h.logger.Error("get pinned messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get pinned messages")
+		return
+	}
+
+	if pins == nil {
+		pins = []domain.PinnedMessage{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pins":  pins,
+		"count": len(pins),
+	})
+}
+
+// authorizePin parses the pin route and checks the caller may pin in the
+// conversation, writing the error response if not
+func (h *ConversationHandler) authorizePin(w http.ResponseWriter, r *http.Request) (userID, convID, messageID uuid.UUID, ok bool) {
+	userID, authed := auth.GetUserID(r.Context())
+	if !authed {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	messageID, err = uuid.Parse(r.PathValue("messageId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	if !h.checkCanPin(w, r, convID, userID) {
+		return
+	}
+
+	return userID, convID, messageID, true
+}
+
+// checkCanPin checks the user may manage pins in the conversation, writing
+// the error response if not
+func (h *ConversationHandler) checkCanPin(w http.ResponseWriter, r *http.Request, convID, userID uuid.UUID) bool {
+	role, err := h.convs.GetMemberRole(r.Context(), convID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotMember) {
+			writeError(w, http.StatusForbidden, "not a member of this conversation")
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
+		return false
+	}
+
+	conv, err := h.convs.GetByID(r.Context(), convID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return false
+	}
+
+	if !domain.CanPin(conv.Type, role) {
+		writeError(w, http.StatusForbidden, "only admins and moderators can pin messages in this group")
+		return false
+	}
+	return true
+}
+
+// GetStarredMessages godoc
+//
+//	@Summary		Get starred messages
+//	@Description	Retrieve all messages you've starred
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			limit	query		int	false	"Result limit (default 50)"
+//	@Success		200	{object}	object{messages=[]domain.Message,count=int}
+//	@Failure		401	{object}	map[string]string
+//	@Router			/messages/starred [get]
+func (h *ConversationHandler) GetStarredMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, conv)
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	messages, err := h.convs.GetStarredMessages(r.Context(), userID, limit)
+	if err != nil {
+		h.logger.Error("get starred messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get starred messages")
+		return
+	}
+
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
+
+// GetMentions godoc
+//
+//	@Summary		Get mentions
+//	@Description	Retrieve recent messages that @mentioned you, newest first, with the conversation each was sent in. Conversations you've left are not included.
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			before	query		string	false	"Only mentions before this RFC 3339 time, for paging"
+//	@Param			limit	query		int	false	"Result limit (default 50, max 100)"
+//	@Success		200	{object}	object{mentions=[]domain.Mention,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/mentions [get]
+func (h *ConversationHandler) GetMentions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	var before *time.Time
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		t, err := time.Parse(time.RFC3339, beforeStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'before' timestamp")
+			return
+		}
+		before = &t
+	}
+
+	mentions, err := h.convs.GetMentions(r.Context(), userID, before, limit)
+	if err != nil {
+		h.logger.Error("get mentions failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get mentions")
+		return
+	}
+
+	if mentions == nil {
+		mentions = []domain.Mention{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mentions": mentions,
+		"count":    len(mentions),
+	})
 }
 
 // ============================================================================
-// Messages
+// Message Search
 // ============================================================================
 
-// GetMessages godoc
+// SearchMessages godoc
 //
-//	@Summary		Get messages
-//	@Description	Get messages from a conversation with pagination
+//	@Summary		Search messages in conversation
+//	@Description	Full-text search within a specific conversation
 //	@Tags			messages
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			id	path		string	true	"Conversation ID"
-//	@Param			before	query		string	false	"Cursor for pagination"
-//	@Param			limit	query		int	false	"Number of messages (default 50)"
-//	@Success		200	{object}	object{messages=[]domain.Message,has_more=bool}
+//	@Param			q	query		string	true	"Search query"
+//	@Param			limit	query		int	false	"Result limit (default 50)"
+//	@Param			before_rank	query		number	false	"Rank of the last hit on the previous page"
+//	@Param			before_created_at	query		string	false	"created_at of the last hit on the previous page"
+//	@Param			before_id	query		string	false	"ID of the last hit on the previous page"
+//	@Param			from_user	query		string	false	"Only messages from this sender (username or user ID)"
+//	@Param			after	query		string	false	"Only messages sent at or after this date (YYYY-MM-DD or RFC 3339)"
+//	@Param			before	query		string	false	"Only messages sent before this date (YYYY-MM-DD or RFC 3339)"
+//	@Success		200	{object}	object{messages=[]domain.Message,count=int,query=string,has_more=bool}
+//	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id}/messages [get]
-func (h *ConversationHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+//	@Failure		501	{object}	map[string]string	"Search disabled by message encryption"
+//	@Router			/conversations/{id}/messages/search [get]
+func (h *ConversationHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
@@ -554,453 +3314,469 @@ func (h *ConversationHandler) GetMessages(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Parse pagination
-	var before *time.Time
-	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
-		t, err := time.Parse(time.RFC3339, beforeStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid 'before' timestamp")
-			return
-		}
-		before = &t
+	query, ok := h.searchQuery(w, r)
+	if !ok {
+		return
 	}
 
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	filter, ok := h.searchFilter(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, ok := searchPage(w, r)
+	if !ok {
+		return
 	}
 
-	messages, err := h.convs.GetMessages(r.Context(), convID, before, limit)
+	messages, hasMore, err := h.convs.SearchMessages(r.Context(), convID, userID, query, filter, cursor, limit)
+	if errors.Is(err, domain.ErrSearchDisabled) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
 	if err != nil {
-		h.logger.Error("get messages failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to get messages")
+		h.logger.Error("search messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to search messages")
 		return
 	}
 
-	if messages == nil {
-		messages = []domain.Message{}
+	writeJSON(w, http.StatusOK, searchResponse(messages, query, cursor, hasMore))
+}
+
+// SearchAllMessages godoc
+//
+//	@Summary		Search all messages
+//	@Description	Full-text search across all conversations you're in
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			q	query		string	true	"Search query"
+//	@Param			limit	query		int	false	"Result limit (default 50)"
+//	@Param			before_rank	query		number	false	"Rank of the last hit on the previous page"
+//	@Param			before_created_at	query		string	false	"created_at of the last hit on the previous page"
+//	@Param			before_id	query		string	false	"ID of the last hit on the previous page"
+//	@Param			from_user	query		string	false	"Only messages from this sender (username or user ID)"
+//	@Param			after	query		string	false	"Only messages sent at or after this date (YYYY-MM-DD or RFC 3339)"
+//	@Param			before	query		string	false	"Only messages sent before this date (YYYY-MM-DD or RFC 3339)"
+//	@Success		200	{object}	object{messages=[]domain.Message,count=int,query=string,has_more=bool}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		501	{object}	map[string]string	"Search disabled by message encryption"
+//	@Router			/messages/search [get]
+func (h *ConversationHandler) SearchAllMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
 	}
 
-	// Populate receipt statuses for messages sent by the current user
-	if len(messages) > 0 {
-		// Collect message IDs sent by this user
-		var ownMsgIDs []uuid.UUID
-		for _, msg := range messages {
-			if msg.SenderID != nil && *msg.SenderID == userID {
-				ownMsgIDs = append(ownMsgIDs, msg.ID)
-			}
-		}
+	query, ok := h.searchQuery(w, r)
+	if !ok {
+		return
+	}
 
-		// Get receipt statuses in bulk
-		if len(ownMsgIDs) > 0 {
-			statuses, err := h.convs.GetMessageReceiptStatuses(r.Context(), ownMsgIDs)
-			if err != nil {
-				h.logger.Warn("failed to get receipt statuses", "error", err)
-			} else {
-				// Apply statuses to messages
-				for i := range messages {
-					if messages[i].SenderID != nil && *messages[i].SenderID == userID {
-						if status, ok := statuses[messages[i].ID]; ok {
-							messages[i].ReceiptStatus = status
-						} else {
-							messages[i].ReceiptStatus = "sent"
-						}
-					}
-				}
-			}
-		}
+	filter, ok := h.searchFilter(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, ok := searchPage(w, r)
+	if !ok {
+		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-	})
+	messages, hasMore, err := h.convs.SearchAllMessages(r.Context(), userID, query, filter, cursor, limit)
+	if errors.Is(err, domain.ErrSearchDisabled) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("search all messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to search messages")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, searchResponse(messages, query, cursor, hasMore))
 }
 
-// SendMessage godoc
+// SearchStarredMessages godoc
 //
-//	@Summary		Send message
-//	@Description	Send a new message to a conversation
+//	@Summary		Search starred messages
+//	@Description	Full-text search over the messages you've starred, in conversations you can still read. Each hit carries an HTML snippet with matches wrapped in <mark>.
 //	@Tags			messages
-//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			id	path		string	true	"Conversation ID"
-//	@Param			request	body		object{body_text=string,attachment_id=string}	true	"Message content"
-//	@Success		201	{object}	domain.Message
+//	@Param			q	query		string	true	"Search query"
+//	@Param			limit	query		int	false	"Result limit (default 50)"
+//	@Success		200	{object}	object{messages=[]domain.Message,count=int,query=string}
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id}/messages [post]
-func (h *ConversationHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+//	@Failure		501	{object}	map[string]string	"Search disabled by message encryption"
+//	@Router			/messages/starred/search [get]
+func (h *ConversationHandler) SearchStarredMessages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	convID, err := uuid.Parse(r.PathValue("id"))
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid conversation ID")
-		return
-	}
-
-	var input struct {
-		BodyText string `json:"body_text"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	query, ok := h.searchQuery(w, r)
+	if !ok {
 		return
 	}
 
-	// Validate message
-	input.BodyText = strings.TrimSpace(input.BodyText)
-	if input.BodyText == "" {
-		writeError(w, http.StatusBadRequest, "message cannot be empty")
-		return
-	}
-	if len(input.BodyText) > 10000 {
-		writeError(w, http.StatusBadRequest, "message too long (max 10000 chars)")
-		return
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
 	}
 
-	// Check membership
-	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
-	if err != nil || !isMember {
-		writeError(w, http.StatusForbidden, "not a member of this conversation")
+	messages, err := h.convs.SearchStarredMessages(r.Context(), userID, query, limit)
+	if errors.Is(err, domain.ErrSearchDisabled) {
+		writeError(w, http.StatusNotImplemented, err.Error())
 		return
 	}
-
-	// Create message
-	msg := &domain.Message{
-		ID:             uuid.New(),
-		ConversationID: convID,
-		SenderID:       &userID,
-		BodyText:       input.BodyText,
-		CreatedAt:      time.Now(),
-	}
-
-	if err := h.convs.CreateMessage(r.Context(), msg); err != nil {
-		h.logger.Error("create message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to send message")
+	if err != nil {
+		h.logger.Error("search starred messages failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to search messages")
 		return
 	}
 
-	// Get sender info
-	user, _ := h.users.GetByID(r.Context(), userID)
-	if user != nil {
-		pub := user.ToPublic()
-		msg.Sender = &pub
+	if messages == nil {
+		messages = []domain.Message{}
 	}
 
-	writeJSON(w, http.StatusCreated, msg)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messages": messages,
+		"count":    len(messages),
+		"query":    query,
+	})
 }
 
 // ============================================================================
-// Blocking
+// Archive
 // ============================================================================
 
-// BlockUser godoc
+// ArchiveConversation godoc
 //
-//	@Summary		Block user
-//	@Description	Block a user from contacting you
-//	@Tags			users
+//	@Summary		Archive conversation
+//	@Description	Move a conversation to the archive
+//	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			username	path		string	true	"Username to block"
+//	@Param			id	path		string	true	"Conversation ID"
 //	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/blocks/{username} [post]
-func (h *ConversationHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+//	@Router			/conversations/{id}/archive [post]
+func (h *ConversationHandler) ArchiveConversation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	username := r.PathValue("username")
-	targetUser, err := h.users.GetByUsername(r.Context(), username)
+	convID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
 		return
 	}
 
-	if targetUser.ID == userID {
-		writeError(w, http.StatusBadRequest, "cannot block yourself")
+	// Check membership
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	if err := h.convs.Block(r.Context(), userID, targetUser.ID); err != nil {
-		h.logger.Error("block user failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to block user")
+	if err := h.convs.ArchiveConversation(r.Context(), convID); err != nil {
+		h.logger.Error("archive conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to archive conversation")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "user blocked"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "conversation archived"})
 }
 
-// UnblockUser godoc
+// UnarchiveConversation godoc
 //
-//	@Summary		Unblock user
-//	@Description	Remove a user from your block list
-//	@Tags			users
+//	@Summary		Unarchive conversation
+//	@Description	Restore a conversation from the archive
+//	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			username	path		string	true	"Username to unblock"
+//	@Param			id	path		string	true	"Conversation ID"
 //	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/blocks/{username} [delete]
-func (h *ConversationHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+//	@Router			/conversations/{id}/unarchive [post]
+func (h *ConversationHandler) UnarchiveConversation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	username := r.PathValue("username")
-	targetUser, err := h.users.GetByUsername(r.Context(), username)
+	convID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
 		return
 	}
 
-	if err := h.convs.Unblock(r.Context(), userID, targetUser.ID); err != nil {
-		h.logger.Error("unblock user failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to unblock user")
+	// Check membership
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "user unblocked"})
-}
+	if err := h.convs.UnarchiveConversation(r.Context(), convID); err != nil {
+		h.logger.Error("unarchive conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unarchive conversation")
+		return
+	}
 
-// ============================================================================
-// Starred Messages
-// ============================================================================
+	writeJSON(w, http.StatusOK, map[string]string{"status": "conversation unarchived"})
+}
 
-// StarMessage godoc
+// BatchConversations godoc
 //
-//	@Summary		Star message
-//	@Description	Add a message to your starred list
-//	@Tags			messages
+//	@Summary		Apply conversation actions in bulk
+//	@Description	Archive, unarchive, mute, unmute, pin, unpin or mark read several conversations in one request. Ops that pass validation run in a single transaction; each op gets its own result.
+//	@Tags			conversations
+//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			id	path		string	true	"Message ID"
-//	@Success		200	{object}	map[string]string
+//	@Param			request	body		object{operations=[]domain.ConversationOp}	true	"Operations to apply"
+//	@Success		200	{object}	object{results=[]domain.ConversationOpResult,count=int}
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/messages/{id}/star [post]
-func (h *ConversationHandler) StarMessage(w http.ResponseWriter, r *http.Request) {
+//	@Router			/conversations/batch [post]
+func (h *ConversationHandler) BatchConversations(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	messageID, err := uuid.Parse(r.PathValue("id"))
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid message ID")
+	var input struct {
+		Operations []domain.ConversationOp `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-
-	// Get the message to check membership
-	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
-	if err != nil {
-		if errors.Is(err, domain.ErrMessageNotFound) {
-			writeError(w, http.StatusNotFound, "message not found")
-			return
-		}
-		h.logger.Error("get message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to get message")
+	if len(input.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, "no operations given")
 		return
 	}
-
-	// Check membership
-	isMember, err := h.convs.IsMember(r.Context(), msg.ConversationID, userID)
-	if err != nil || !isMember {
-		writeError(w, http.StatusForbidden, "not a member of this conversation")
+	if len(input.Operations) > domain.MaxConversationBatch {
+		writeError(w, http.StatusBadRequest, "too many operations")
 		return
 	}
 
-	if err := h.convs.StarMessage(r.Context(), userID, messageID); err != nil {
-		h.logger.Error("star message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to star message")
+	convIDs := make([]uuid.UUID, len(input.Operations))
+	for i, op := range input.Operations {
+		convIDs[i] = op.ConversationID
+	}
+	memberOf, err := h.convs.GetMemberConversations(r.Context(), userID, convIDs)
+	if err != nil {
+		h.logger.Error("batch membership check failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to check membership")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "message starred"})
+	apply, results := domain.PlanConversationBatch(input.Operations, memberOf)
+	if len(apply) > 0 {
+		applyErr := h.convs.ApplyConversationBatch(r.Context(), userID, apply)
+		if applyErr != nil {
+			h.logger.Error("apply conversation batch failed", "error", applyErr)
+		}
+		for i := range results {
+			if results[i].Error != "" {
+				continue
+			}
+			if applyErr != nil {
+				results[i].Error = "failed to apply operation"
+			} else {
+				results[i].OK = true
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
 }
 
-// UnstarMessage godoc
+// SetConversationFolder godoc
 //
-//	@Summary		Unstar message
-//	@Description	Remove a message from your starred list
-//	@Tags			messages
+//	@Summary		File conversation into a folder
+//	@Description	Put a conversation into one of the caller's folders, replacing any earlier folder. Folders are private to the caller.
+//	@Tags			conversations
+//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			id	path		string	true	"Message ID"
-//	@Success		200	{object}	map[string]string
+//	@Param			id		path		string				true	"Conversation ID"
+//	@Param			request	body		object{folder=string}	true	"Folder name"
+//	@Success		200	{object}	domain.ConversationFolder
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/messages/{id}/star [delete]
-func (h *ConversationHandler) UnstarMessage(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/folder [put]
+func (h *ConversationHandler) SetConversationFolder(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	messageID, err := uuid.Parse(r.PathValue("id"))
+	convID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid message ID")
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
 		return
 	}
 
-	if err := h.convs.UnstarMessage(r.Context(), userID, messageID); err != nil {
-		h.logger.Error("unstar message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to unstar message")
+	var input struct {
+		Folder string `json:"folder"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	folder, err := domain.CleanFolderName(input.Folder)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "message unstarred"})
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	if err := h.convs.SetConversationFolder(r.Context(), convID, userID, folder); err != nil {
+		h.logger.Error("set conversation folder failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to set folder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain.ConversationFolder{ConversationID: convID, Folder: folder})
 }
 
-// DeleteMessage godoc
+// ClearConversationFolder godoc
 //
-//	@Summary		Delete message
-//	@Description	Delete a message you sent (only the sender or group admin can delete)
-//	@Tags			messages
+//	@Summary		Remove conversation from its folder
+//	@Description	Take a conversation out of the caller's folders
+//	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			id	path		string	true	"Message ID"
+//	@Param			id	path		string	true	"Conversation ID"
 //	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
 //	@Failure		403	{object}	map[string]string
-//	@Failure		404	{object}	map[string]string
-//	@Router			/messages/{id} [delete]
-func (h *ConversationHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+//	@Router			/conversations/{id}/folder [delete]
+func (h *ConversationHandler) ClearConversationFolder(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	messageID, err := uuid.Parse(r.PathValue("id"))
-	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid message ID")
-		return
-	}
-
-	// Get message to check ownership and conversation
-	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
+	convID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		if errors.Is(err, domain.ErrMessageNotFound) {
-			writeError(w, http.StatusNotFound, "message not found")
-			return
-		}
-		h.logger.Error("get message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to get message")
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
 		return
 	}
 
-	// Check permissions: sender can delete their own message, or admin can delete any in the group
-	canDelete := false
-	if msg.SenderID != nil && *msg.SenderID == userID {
-		canDelete = true
-	} else {
-		// Check if user is admin of the conversation
-		role, err := h.convs.GetMemberRole(r.Context(), msg.ConversationID, userID)
-		if err == nil && role == domain.MemberRoleAdmin {
-			canDelete = true
-		}
-	}
-
-	if !canDelete {
-		writeError(w, http.StatusForbidden, "you can only delete your own messages")
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	// Delete the message
-	if err := h.convs.DeleteMessage(r.Context(), messageID); err != nil {
-		h.logger.Error("delete message failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to delete message")
+	if err := h.convs.ClearConversationFolder(r.Context(), convID, userID); err != nil {
+		h.logger.Error("clear conversation folder failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to clear folder")
 		return
 	}
 
-	// Broadcast deletion to room
-	if h.broadcaster != nil {
-		if err := h.broadcaster.BroadcastMessageDeleted(r.Context(), messageID, msg.ConversationID, userID); err != nil {
-			h.logger.Error("failed to broadcast message deletion", "error", err)
-		}
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "message deleted"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "folder cleared"})
 }
 
-// GetStarredMessages godoc
+// SaveDraft godoc
 //
-//	@Summary		Get starred messages
-//	@Description	Retrieve all messages you've starred
-//	@Tags			messages
+//	@Summary		Save draft
+//	@Description	Store the caller's unsent message in a conversation so it follows them across devices, replacing any earlier draft. The draft is cleared when the caller sends a message.
+//	@Tags			conversations
+//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			limit	query		int	false	"Result limit (default 50)"
-//	@Success		200	{object}	object{messages=[]domain.Message,count=int}
+//	@Param			id		path		string				true	"Conversation ID"
+//	@Param			request	body		object{body=string}	true	"Draft text"
+//	@Success		200	{object}	domain.Draft
+//	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/messages/starred [get]
-func (h *ConversationHandler) GetStarredMessages(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/draft [put]
+func (h *ConversationHandler) SaveDraft(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
 	}
 
-	messages, err := h.convs.GetStarredMessages(r.Context(), userID, limit)
-	if err != nil {
-		h.logger.Error("get starred messages failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to get starred messages")
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := domain.CheckDraft(input.Body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if messages == nil {
-		messages = []domain.Message{}
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-	})
-}
+	draft := &domain.Draft{ConversationID: convID, Body: input.Body}
+	if err := h.convs.SaveDraft(r.Context(), draft, userID); err != nil {
+		h.logger.Error("save draft failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save draft")
+		return
+	}
 
-// ============================================================================
-// Message Search
-// ============================================================================
+	writeJSON(w, http.StatusOK, draft)
+}
 
-// SearchMessages godoc
+// GetDraft godoc
 //
-//	@Summary		Search messages in conversation
-//	@Description	Full-text search within a specific conversation
-//	@Tags			messages
+//	@Summary		Get draft
+//	@Description	Get the caller's unsent draft in a conversation
+//	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
 //	@Param			id	path		string	true	"Conversation ID"
-//	@Param			q	query		string	true	"Search query"
-//	@Param			limit	query		int	false	"Result limit (default 20)"
-//	@Success		200	{object}	object{messages=[]domain.Message,count=int,query=string}
+//	@Success		200	{object}	domain.Draft
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id}/messages/search [get]
-func (h *ConversationHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/conversations/{id}/draft [get]
+func (h *ConversationHandler) GetDraft(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
@@ -1013,112 +3789,83 @@ func (h *ConversationHandler) SearchMessages(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Check membership
 	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
 	if err != nil || !isMember {
 		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		writeError(w, http.StatusBadRequest, "search query is required")
+	draft, err := h.convs.GetDraft(r.Context(), convID, userID)
+	if errors.Is(err, domain.ErrDraftNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
-
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	messages, err := h.convs.SearchMessages(r.Context(), convID, query, limit)
 	if err != nil {
-		h.logger.Error("search messages failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to search messages")
+		h.logger.Error("get draft failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get draft")
 		return
 	}
 
-	if messages == nil {
-		messages = []domain.Message{}
-	}
-
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-		"query":    query,
-	})
+	writeJSON(w, http.StatusOK, draft)
 }
 
-// SearchAllMessages godoc
+// DeleteDraft godoc
 //
-//	@Summary		Search all messages
-//	@Description	Full-text search across all conversations you're in
-//	@Tags			messages
+//	@Summary		Delete draft
+//	@Description	Discard the caller's unsent draft in a conversation
+//	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			q	query		string	true	"Search query"
-//	@Param			limit	query		int	false	"Result limit (default 50)"
-//	@Success		200	{object}	object{messages=[]domain.Message,count=int,query=string}
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/messages/search [get]
-func (h *ConversationHandler) SearchAllMessages(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/draft [delete]
+func (h *ConversationHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		writeError(w, http.StatusBadRequest, "search query is required")
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
 		return
 	}
 
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	messages, err := h.convs.SearchAllMessages(r.Context(), userID, query, limit)
-	if err != nil {
-		h.logger.Error("search all messages failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to search messages")
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	if messages == nil {
-		messages = []domain.Message{}
+	if err := h.convs.DeleteDraft(r.Context(), convID, userID); err != nil {
+		h.logger.Error("delete draft failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete draft")
+		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-		"query":    query,
-	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "draft deleted"})
 }
 
-// ============================================================================
-// Archive
-// ============================================================================
-
-// ArchiveConversation godoc
+// MuteConversation godoc
 //
-//	@Summary		Archive conversation
-//	@Description	Move a conversation to the archive
+//	@Summary		Mute conversation
+//	@Description	Silence notifications from a conversation for the caller without leaving it, for a while or indefinitely
 //	@Tags			conversations
+//	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			id	path		string	true	"Conversation ID"
-//	@Success		200	{object}	map[string]string
+//	@Param			id		path		string						true	"Conversation ID"
+//	@Param			request	body		object{duration_seconds=int}	false	"Mute length; omit or 0 to mute indefinitely"
+//	@Success		200	{object}	domain.ConversationMute
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id}/archive [post]
-func (h *ConversationHandler) ArchiveConversation(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/mute [post]
+func (h *ConversationHandler) MuteConversation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
@@ -1131,26 +3878,41 @@ func (h *ConversationHandler) ArchiveConversation(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Check membership
+	var input struct {
+		DurationSeconds int64 `json:"duration_seconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	until, err := domain.MuteExpiry(time.Now(), input.DurationSeconds)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
 	if err != nil || !isMember {
 		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	if err := h.convs.ArchiveConversation(r.Context(), convID); err != nil {
-		h.logger.Error("archive conversation failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to archive conversation")
+	if err := h.convs.MuteConversation(r.Context(), convID, userID, until); err != nil {
+		h.logger.Error("mute conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to mute conversation")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "conversation archived"})
+	writeJSON(w, http.StatusOK, domain.ConversationMute{ConversationID: convID, MutedUntil: until})
 }
 
-// UnarchiveConversation godoc
+// UnmuteConversation godoc
 //
-//	@Summary		Unarchive conversation
-//	@Description	Restore a conversation from the archive
+//	@Summary		Unmute conversation
+//	@Description	Lift the caller's mute on a conversation
 //	@Tags			conversations
 //	@Produce		json
 //	@Security		BearerAuth
@@ -1158,8 +3920,9 @@ func (h *ConversationHandler) ArchiveConversation(w http.ResponseWriter, r *http
 //	@Success		200	{object}	map[string]string
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
-//	@Router			/conversations/{id}/unarchive [post]
-func (h *ConversationHandler) UnarchiveConversation(w http.ResponseWriter, r *http.Request) {
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/mute [delete]
+func (h *ConversationHandler) UnmuteConversation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserID(r.Context())
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
@@ -1172,20 +3935,46 @@ func (h *ConversationHandler) UnarchiveConversation(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Check membership
 	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
 	if err != nil || !isMember {
 		writeError(w, http.StatusForbidden, "not a member of this conversation")
 		return
 	}
 
-	if err := h.convs.UnarchiveConversation(r.Context(), convID); err != nil {
-		h.logger.Error("unarchive conversation failed", "error", err)
-		writeError(w, http.StatusInternalServerError, "failed to unarchive conversation")
+	if err := h.convs.UnmuteConversation(r.Context(), convID, userID); err != nil {
+		h.logger.Error("unmute conversation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to unmute conversation")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "conversation unarchived"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "unmuted"})
+}
+
+// Sync godoc
+//
+//	@Summary		Sync conversation layout
+//	@Description	Return the caller's pinned, muted and archived conversations and folders in one call, so a reconnecting client can restore its layout
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	domain.SyncState
+//	@Failure		401	{object}	map[string]string
+//	@Router			/sync [get]
+func (h *ConversationHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	state, err := h.convs.GetSyncState(r.Context(), userID, time.Now())
+	if err != nil {
+		h.logger.Error("get sync state failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to sync")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
 }
 
 // ============================================================================
@@ -1249,6 +4038,114 @@ func (h *ConversationHandler) MarkConversationRead(w http.ResponseWriter, r *htt
 	writeJSON(w, http.StatusOK, map[string]string{"status": "marked as read"})
 }
 
+// GetUnreadCount godoc
+//
+//	@Summary		Get unread count
+//	@Description	Get the caller's unread count and last-read cursor for one conversation, counted the same way as the conversation list
+//	@Tags			conversations
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	domain.UnreadState
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Router			/conversations/{id}/unread [get]
+func (h *ConversationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	convID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation ID")
+		return
+	}
+
+	isMember, err := h.convs.IsMember(r.Context(), convID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	state, err := h.convs.GetUnreadState(r.Context(), convID, userID)
+	if err != nil {
+		h.logger.Error("get unread count failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get unread count")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+// MarkUnreadFrom godoc
+//
+//	@Summary		Mark unread from a message
+//	@Description	Move the caller's read cursor to just before a message, so it and everything after it show as unread
+//	@Tags			messages
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string	true	"Message ID"
+//	@Success		200	{object}	domain.UnreadState
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/messages/{id}/unread [post]
+func (h *ConversationHandler) MarkUnreadFrom(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	messageID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid message ID")
+		return
+	}
+
+	msg, err := h.convs.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			writeError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		h.logger.Error("get message failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get message")
+		return
+	}
+	if !msg.VisibleToUser(userID) {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	// The cursor is moved in the message's own conversation
+	isMember, err := h.convs.IsMember(r.Context(), msg.ConversationID, userID)
+	if err != nil || !isMember {
+		writeError(w, http.StatusForbidden, "not a member of this conversation")
+		return
+	}
+
+	state, err := h.convs.MarkUnreadFrom(r.Context(), msg, userID)
+	if err != nil {
+		h.logger.Error("mark unread failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to mark unread")
+		return
+	}
+
+	// Keep the user's other devices' badges in step
+	if h.broadcaster != nil {
+		if err := h.broadcaster.NotifyConversationRead(r.Context(), userID, state); err != nil {
+			h.logger.Error("failed to notify conversation read", "error", err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
 // MarkAllConversationsRead godoc
 //
 //	@Summary		Mark all conversations as read
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// directMessageStore is the part of ConversationRepository used to message a
+// user by username
+type directMessageStore interface {
+	FindDMBetween(ctx context.Context, user1, user2 uuid.UUID) (*domain.Conversation, error)
+	IsBlocked(ctx context.Context, user1, user2 uuid.UUID) (bool, error)
+	CreateMessage(ctx context.Context, msg *domain.Message) error
+	CreateDMWithMessage(ctx context.Context, conv *domain.Conversation, memberIDs []uuid.UUID, msg *domain.Message) error
+}
+
+// sendDirectMessage posts msg from its sender to the DM with recipientID. If
+// the two have no DM yet and create is set, the DM is created together with
+// the message; otherwise domain.ErrConversationNotFound is returned. Either
+// side having blocked the other fails with domain.ErrUserBlocked. It returns
+// the DM's ID and whether it was created.
+func sendDirectMessage(ctx context.Context, store directMessageStore, msg *domain.Message, recipientID uuid.UUID, create bool) (uuid.UUID, bool, error) {
+	senderID := *msg.SenderID
+
+	blocked, err := store.IsBlocked(ctx, senderID, recipientID)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if blocked {
+		return uuid.Nil, false, domain.ErrUserBlocked
+	}
+
+	existing, err := store.FindDMBetween(ctx, senderID, recipientID)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if existing != nil {
+		return postToDM(ctx, store, msg, existing.ID)
+	}
+
+	if !create {
+		return uuid.Nil, false, domain.ErrConversationNotFound
+	}
+
+	now := time.Now()
+	conv := &domain.Conversation{
+		ID:        uuid.New(),
+		Type:      domain.ConversationTypeDM,
+		CreatedBy: &senderID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	msg.ConversationID = conv.ID
+	err = store.CreateDMWithMessage(ctx, conv, []uuid.UUID{senderID, recipientID}, msg)
+	if errors.Is(err, domain.ErrDMExists) {
+		// Someone else's first message created the DM since we looked
+		existing, err := store.FindDMBetween(ctx, senderID, recipientID)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if existing == nil {
+			return uuid.Nil, false, domain.ErrConversationNotFound
+		}
+		return postToDM(ctx, store, msg, existing.ID)
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return conv.ID, true, nil
+}
+
+// postToDM posts msg to the existing DM convID
+func postToDM(ctx context.Context, store directMessageStore, msg *domain.Message, convID uuid.UUID) (uuid.UUID, bool, error) {
+	msg.ConversationID = convID
+	if err := store.CreateMessage(ctx, msg); err != nil {
+		return uuid.Nil, false, err
+	}
+	return convID, false, nil
+}
+
+// dmBlockStore is the part of ConversationRepository used to check blocks
+// before posting to an existing conversation
+type dmBlockStore interface {
+	GetDMPartner(ctx context.Context, convID, userID uuid.UUID) (uuid.UUID, error)
+	IsBlocked(ctx context.Context, user1, user2 uuid.UUID) (bool, error)
+}
+
+// checkDMBlock fails with domain.ErrUserBlocked if convID is a DM whose
+// members have blocked each other, in either direction. Groups always pass.
+func checkDMBlock(ctx context.Context, store dmBlockStore, convID, senderID uuid.UUID) error {
+	partnerID, err := store.GetDMPartner(ctx, convID, senderID)
+	if err != nil || partnerID == uuid.Nil {
+		return err
+	}
+	blocked, err := store.IsBlocked(ctx, senderID, partnerID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return domain.ErrUserBlocked
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDMStore mimics the conversations, members, messages and blocks tables
+type memoryDMStore struct {
+	members  map[uuid.UUID][]uuid.UUID
+	messages []domain.Message
+	blocks   map[[2]uuid.UUID]bool
+
+	staleFinds int // FindDMBetween misses this many times, like a read racing a create
+}
+
+func newMemoryDMStore() *memoryDMStore {
+	return &memoryDMStore{
+		members: make(map[uuid.UUID][]uuid.UUID),
+		blocks:  make(map[[2]uuid.UUID]bool),
+	}
+}
+
+func (s *memoryDMStore) FindDMBetween(_ context.Context, user1, user2 uuid.UUID) (*domain.Conversation, error) {
+	if s.staleFinds > 0 {
+		s.staleFinds--
+		return nil, nil
+	}
+	for id, m := range s.members {
+		if len(m) == 2 && (m[0] == user1 && m[1] == user2 || m[0] == user2 && m[1] == user1) {
+			return &domain.Conversation{ID: id, Type: domain.ConversationTypeDM}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryDMStore) IsBlocked(_ context.Context, user1, user2 uuid.UUID) (bool, error) {
+	return s.blocks[[2]uuid.UUID{user1, user2}] || s.blocks[[2]uuid.UUID{user2, user1}], nil
+}
+
+func (s *memoryDMStore) CreateMessage(_ context.Context, msg *domain.Message) error {
+	if _, ok := s.members[msg.ConversationID]; !ok {
+		return domain.ErrConversationNotFound
+	}
+	s.messages = append(s.messages, *msg)
+	return nil
+}
+
+func (s *memoryDMStore) CreateDMWithMessage(_ context.Context, conv *domain.Conversation, memberIDs []uuid.UUID, msg *domain.Message) error {
+	for _, m := range s.members {
+		if len(m) == 2 && len(memberIDs) == 2 && (m[0] == memberIDs[0] && m[1] == memberIDs[1] || m[0] == memberIDs[1] && m[1] == memberIDs[0]) {
+			return domain.ErrDMExists
+		}
+	}
+	s.members[conv.ID] = memberIDs
+	s.messages = append(s.messages, *msg)
+	return nil
+}
+
+func newDirectMessage(senderID uuid.UUID, body string) *domain.Message {
+	return &domain.Message{ID: uuid.New(), SenderID: &senderID, BodyText: body, CreatedAt: time.Now()}
+}
+
+func TestSendDirectMessage_CreatesDMWithFirstMessage(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+
+	msg := newDirectMessage(alice, "hi bob")
+	convID, created, err := sendDirectMessage(context.Background(), store, msg, bob, true)
+	require.NoError(t, err)
+
+	assert.True(t, created)
+	assert.ElementsMatch(t, []uuid.UUID{alice, bob}, store.members[convID])
+	require.Len(t, store.messages, 1)
+	assert.Equal(t, convID, store.messages[0].ConversationID)
+	assert.Equal(t, "hi bob", store.messages[0].BodyText)
+}
+
+func TestSendDirectMessage_AppendsToExistingDM(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+	existing := uuid.New()
+	store.members[existing] = []uuid.UUID{bob, alice}
+
+	convID, created, err := sendDirectMessage(context.Background(), store, newDirectMessage(alice, "again"), bob, true)
+	require.NoError(t, err)
+
+	assert.False(t, created)
+	assert.Equal(t, existing, convID)
+	assert.Len(t, store.members, 1, "no second DM is created")
+	require.Len(t, store.messages, 1)
+	assert.Equal(t, existing, store.messages[0].ConversationID)
+}
+
+func TestSendDirectMessage_PostsToDMCreatedConcurrently(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+	existing := uuid.New()
+	store.members[existing] = []uuid.UUID{bob, alice}
+	store.staleFinds = 1 // bob's first message landed after alice looked
+
+	convID, created, err := sendDirectMessage(context.Background(), store, newDirectMessage(alice, "hi bob"), bob, true)
+	require.NoError(t, err)
+
+	assert.False(t, created)
+	assert.Equal(t, existing, convID)
+	assert.Len(t, store.members, 1, "no second DM is created")
+	require.Len(t, store.messages, 1)
+	assert.Equal(t, existing, store.messages[0].ConversationID)
+}
+
+func TestSendDirectMessage_RejectsBlockedUsers(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+	store.blocks[[2]uuid.UUID{bob, alice}] = true
+
+	_, _, err := sendDirectMessage(context.Background(), store, newDirectMessage(alice, "hi"), bob, true)
+	assert.ErrorIs(t, err, domain.ErrUserBlocked)
+
+	// A block also stops messages in a DM that already exists
+	store.members[uuid.New()] = []uuid.UUID{alice, bob}
+	_, _, err = sendDirectMessage(context.Background(), store, newDirectMessage(alice, "hi"), bob, true)
+	assert.ErrorIs(t, err, domain.ErrUserBlocked)
+	assert.Empty(t, store.messages)
+}
+
+func TestSendDirectMessage_WithoutAutoCreate(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+
+	_, _, err := sendDirectMessage(context.Background(), store, newDirectMessage(alice, "hi"), bob, false)
+	assert.ErrorIs(t, err, domain.ErrConversationNotFound)
+	assert.Empty(t, store.members)
+}
+
+func (s *memoryDMStore) GetDMPartner(_ context.Context, convID, userID uuid.UUID) (uuid.UUID, error) {
+	for _, id := range s.members[convID] {
+		if id != userID {
+			return id, nil
+		}
+	}
+	return uuid.Nil, nil
+}
+
+func TestCheckDMBlock_BlockClosesPreviouslyOpenDM(t *testing.T) {
+	store := newMemoryDMStore()
+	alice, bob := uuid.New(), uuid.New()
+	ctx := context.Background()
+
+	convID, _, err := sendDirectMessage(ctx, store, newDirectMessage(alice, "hi bob"), bob, true)
+	require.NoError(t, err)
+	require.NoError(t, checkDMBlock(ctx, store, convID, alice), "open DM accepts messages")
+
+	store.blocks[[2]uuid.UUID{bob, alice}] = true
+	assert.ErrorIs(t, checkDMBlock(ctx, store, convID, alice), domain.ErrUserBlocked, "the blocked sender can't post")
+	assert.ErrorIs(t, checkDMBlock(ctx, store, convID, bob), domain.ErrUserBlocked, "nor can the blocker, in either direction")
+}
+
+func TestCheckDMBlock_IgnoresNonDMs(t *testing.T) {
+	store := newMemoryDMStore()
+	alice := uuid.New()
+
+	assert.NoError(t, checkDMBlock(context.Background(), store, uuid.New(), alice))
+}
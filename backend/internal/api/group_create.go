@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// groupStore is the part of ConversationRepository used to create a group
+type groupStore interface {
+	CreateGroup(ctx context.Context, conv *domain.Conversation, members []domain.ConversationMember) error
+}
+
+// createGroup applies settings to a new group and stores it together with
+// its members. Invalid settings or roles, or more than maxMembers members,
+// fail before anything is stored.
+func createGroup(ctx context.Context, store groupStore, conv *domain.Conversation, settings domain.GroupSettings, requested []domain.ConversationMember, maxMembers int) error {
+	if err := settings.Apply(conv); err != nil {
+		return err
+	}
+	members, err := domain.GroupMembers(*conv.CreatedBy, requested, conv.DefaultMemberRole, maxMembers)
+	if err != nil {
+		return err
+	}
+	return store.CreateGroup(ctx, conv, members)
+}
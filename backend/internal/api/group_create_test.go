@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryGroupStore keeps each created group with its members, as one write
+type memoryGroupStore struct {
+	groups  map[uuid.UUID]domain.Conversation
+	members map[uuid.UUID][]domain.ConversationMember
+}
+
+func newMemoryGroupStore() *memoryGroupStore {
+	return &memoryGroupStore{
+		groups:  make(map[uuid.UUID]domain.Conversation),
+		members: make(map[uuid.UUID][]domain.ConversationMember),
+	}
+}
+
+func (s *memoryGroupStore) CreateGroup(_ context.Context, conv *domain.Conversation, members []domain.ConversationMember) error {
+	s.groups[conv.ID] = *conv
+	s.members[conv.ID] = members
+	return nil
+}
+
+func newGroup(creatorID uuid.UUID) *domain.Conversation {
+	return &domain.Conversation{
+		ID:                uuid.New(),
+		Type:              domain.ConversationTypeGroup,
+		Title:             "book club",
+		CreatedBy:         &creatorID,
+		CreatedAt:         time.Now(),
+		DefaultMemberRole: domain.MemberRoleMember,
+		AllowMemberAdds:   true,
+	}
+}
+
+func TestCreateGroup_PersistsSettingsAndRolesTogether(t *testing.T) {
+	store := newMemoryGroupStore()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+
+	readOnly, allowAdds, ttl := true, false, 3600
+	settings := domain.GroupSettings{ReadOnly: &readOnly, AllowMemberAdds: &allowAdds, DisappearingTTL: &ttl}
+	conv := newGroup(alice)
+
+	err := createGroup(context.Background(), store, conv, settings, []domain.ConversationMember{
+		{UserID: bob, Role: domain.MemberRoleAdmin},
+		{UserID: carol},
+		{UserID: alice, Role: domain.MemberRoleMember}, // the creator stays admin
+	}, domain.DefaultMaxGroupMembers)
+	require.NoError(t, err)
+
+	saved := store.groups[conv.ID]
+	assert.True(t, saved.ReadOnly)
+	assert.False(t, saved.AllowMemberAdds)
+	assert.Equal(t, 3600, saved.MessageTTLSeconds)
+	assert.Equal(t, []domain.ConversationMember{
+		{UserID: alice, Role: domain.MemberRoleAdmin},
+		{UserID: bob, Role: domain.MemberRoleAdmin},
+		{UserID: carol, Role: domain.MemberRoleMember},
+	}, store.members[conv.ID])
+}
+
+func TestCreateGroup_InvalidSettingsStoreNothing(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	ttl := -1
+	badRole := domain.MemberRole("owner")
+
+	tests := []struct {
+		name     string
+		settings domain.GroupSettings
+		members  []domain.ConversationMember
+		want     error
+	}{
+		{"negative ttl", domain.GroupSettings{DisappearingTTL: &ttl}, []domain.ConversationMember{{UserID: bob}}, domain.ErrInvalidMessageTTL},
+		{"unknown default role", domain.GroupSettings{DefaultMemberRole: &badRole}, []domain.ConversationMember{{UserID: bob}}, domain.ErrInvalidRole},
+		{"unknown member role", domain.GroupSettings{}, []domain.ConversationMember{{UserID: bob, Role: badRole}}, domain.ErrInvalidRole},
+		{"creator only", domain.GroupSettings{}, []domain.ConversationMember{{UserID: alice}}, domain.ErrGroupTooSmall},
+		{"over the size limit", domain.GroupSettings{}, []domain.ConversationMember{{UserID: bob}, {UserID: uuid.New()}}, domain.ErrGroupTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryGroupStore()
+			err := createGroup(context.Background(), store, newGroup(alice), tt.settings, tt.members, 2)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, store.groups)
+		})
+	}
+}
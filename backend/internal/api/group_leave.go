@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// leaveStore is the part of ConversationRepository used to leave a group
+type leaveStore interface {
+	LeaveGroup(ctx context.Context, convID, userID uuid.UUID) (uuid.UUID, error)
+}
+
+// leaveGroup removes userID from a group. If they were its last admin, the
+// oldest remaining member is promoted so the group keeps one; their ID is
+// returned, or uuid.Nil if nobody was promoted. Members must be populated.
+func leaveGroup(ctx context.Context, store leaveStore, conv *domain.Conversation, userID uuid.UUID) (uuid.UUID, error) {
+	if conv.Type != domain.ConversationTypeGroup || conv.IsSaved {
+		return uuid.Nil, domain.ErrNotGroup
+	}
+
+	isMember := false
+	for _, m := range conv.Members {
+		if m.UserID == userID {
+			isMember = true
+		}
+	}
+	if !isMember {
+		return uuid.Nil, domain.ErrNotMember
+	}
+
+	// Removal and promotion happen in one transaction, so the group is never
+	// seen without an admin
+	return store.LeaveGroup(ctx, conv.ID, userID)
+}
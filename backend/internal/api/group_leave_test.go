@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLeaveStore mirrors the repository's removal and promotion rules
+type memoryLeaveStore struct {
+	members []domain.ConversationMember
+}
+
+func (s *memoryLeaveStore) LeaveGroup(_ context.Context, _, userID uuid.UUID) (uuid.UUID, error) {
+	var role domain.MemberRole
+	for i, m := range s.members {
+		if m.UserID == userID {
+			role = m.Role
+			s.members = append(s.members[:i], s.members[i+1:]...)
+			break
+		}
+	}
+	if role == "" {
+		return uuid.Nil, domain.ErrNotMember
+	}
+	if role != domain.MemberRoleAdmin || len(s.members) == 0 {
+		return uuid.Nil, nil
+	}
+	for _, m := range s.members {
+		if m.Role == domain.MemberRoleAdmin {
+			return uuid.Nil, nil
+		}
+	}
+	sort.Slice(s.members, func(i, j int) bool { return s.members[i].JoinedAt.Before(s.members[j].JoinedAt) })
+	s.members[0].Role = domain.MemberRoleAdmin
+	return s.members[0].UserID, nil
+}
+
+func (s *memoryLeaveStore) roleOf(userID uuid.UUID) domain.MemberRole {
+	for _, m := range s.members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
+
+// leaveFixture builds a group whose members joined in the given order
+func leaveFixture(roles ...domain.MemberRole) (*domain.Conversation, *memoryLeaveStore, []uuid.UUID) {
+	conv := newGroup(uuid.New())
+	store := &memoryLeaveStore{}
+	ids := make([]uuid.UUID, len(roles))
+	joined := time.Now().Add(-time.Hour)
+	for i, role := range roles {
+		ids[i] = uuid.New()
+		m := domain.ConversationMember{ConversationID: conv.ID, UserID: ids[i], Role: role, JoinedAt: joined.Add(time.Duration(i) * time.Minute)}
+		conv.Members = append(conv.Members, m)
+		store.members = append(store.members, m)
+	}
+	return conv, store, ids
+}
+
+func TestLeaveGroup_LastAdminPromotesOldestMember(t *testing.T) {
+	conv, store, ids := leaveFixture(domain.MemberRoleAdmin, domain.MemberRoleMember, domain.MemberRoleModerator)
+
+	promoted, err := leaveGroup(context.Background(), store, conv, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, ids[1], promoted, "the longest-standing member takes over")
+	assert.Equal(t, domain.MemberRoleAdmin, store.roleOf(ids[1]))
+	assert.Equal(t, domain.MemberRoleModerator, store.roleOf(ids[2]))
+	assert.Empty(t, store.roleOf(ids[0]))
+}
+
+func TestLeaveGroup_NoPromotionWhileAdminsRemain(t *testing.T) {
+	conv, store, ids := leaveFixture(domain.MemberRoleAdmin, domain.MemberRoleMember, domain.MemberRoleAdmin)
+
+	promoted, err := leaveGroup(context.Background(), store, conv, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, promoted)
+	assert.Equal(t, domain.MemberRoleMember, store.roleOf(ids[1]))
+}
+
+func TestLeaveGroup_MemberLeavingNeverPromotes(t *testing.T) {
+	conv, store, ids := leaveFixture(domain.MemberRoleAdmin, domain.MemberRoleMember)
+
+	promoted, err := leaveGroup(context.Background(), store, conv, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, promoted)
+	assert.Len(t, store.members, 1)
+}
+
+func TestLeaveGroup_Rejections(t *testing.T) {
+	conv, store, ids := leaveFixture(domain.MemberRoleAdmin, domain.MemberRoleMember)
+
+	_, err := leaveGroup(context.Background(), store, conv, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrNotMember)
+
+	dm := &domain.Conversation{ID: uuid.New(), Type: domain.ConversationTypeDM, Members: conv.Members}
+	_, err = leaveGroup(context.Background(), store, dm, ids[1])
+	assert.ErrorIs(t, err, domain.ErrNotGroup, "DMs can't be left")
+	assert.Len(t, store.members, 2, "rejected leaves must not remove anyone")
+}
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
@@ -17,60 +18,92 @@ import (
 	"github.com/observer/teatime/internal/domain"
 )
 
-// OAuthHandlers handles OAuth-related API endpoints
+// OAuthHandlers handles OAuth-related API endpoints for every configured
+// identity provider
 type OAuthHandlers struct {
-	oauthService *auth.OAuthService
-	authService  *auth.Service
-	userRepo     *database.UserRepository
-	appBaseURL   string
-	logger       *slog.Logger
+	providers   map[string]*auth.OAuthService
+	authService *auth.Service
+	userRepo    *database.UserRepository
+	appBaseURL  string
+	logger      *slog.Logger
 }
 
-// NewOAuthHandlers creates a new OAuth handlers instance
+// NewOAuthHandlers creates a new OAuth handlers instance; providers are
+// keyed by name, which is also their route (/auth/{name})
 func NewOAuthHandlers(
-	oauthService *auth.OAuthService,
+	providers map[string]*auth.OAuthService,
 	authService *auth.Service,
 	userRepo *database.UserRepository,
 	appBaseURL string,
 ) *OAuthHandlers {
 	return &OAuthHandlers{
-		oauthService: oauthService,
-		authService:  authService,
-		userRepo:     userRepo,
-		appBaseURL:   appBaseURL,
-		logger:       slog.Default().With("component", "oauth-handlers"),
+		providers:   providers,
+		authService: authService,
+		userRepo:    userRepo,
+		appBaseURL:  appBaseURL,
+		logger:      slog.Default().With("component", "oauth-handlers"),
 	}
 }
 
-// HandleGoogleAuth initiates the Google OAuth flow
-func (h *OAuthHandlers) HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
-	authURL, state, err := h.oauthService.GetAuthURL()
-	if err != nil {
-		h.logger.Error("failed to generate auth URL", "error", err)
-		h.redirectWithError(w, r, "Failed to initiate login")
-		return
+// Providers returns the names of the configured providers, sorted
+func (h *OAuthHandlers) Providers() []string {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+// HandleAuth returns a handler that starts the named provider's OAuth flow
+func (h *OAuthHandlers) HandleAuth(provider string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oauthService, ok := h.providers[provider]
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown OAuth provider")
+			return
+		}
+
+		authURL, state, err := oauthService.GetAuthURL()
+		if err != nil {
+			h.logger.Error("failed to generate auth URL", "provider", provider, "error", err)
+			h.redirectWithError(w, r, "Failed to initiate login")
+			return
+		}
 
-	h.logger.Info("redirecting to Google OAuth", "state", state[:8]+"...")
+		h.logger.Info("redirecting to OAuth provider", "provider", provider, "state", state[:8]+"...")
 
-	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+		http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+	}
 }
 
-// HandleGoogleCallback handles the OAuth callback from Google
-func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
+// HandleCallback returns a handler for the named provider's OAuth callback
+func (h *OAuthHandlers) HandleCallback(provider string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oauthService, ok := h.providers[provider]
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown OAuth provider")
+			return
+		}
+		h.handleCallback(w, r, oauthService)
+	}
+}
+
+func (h *OAuthHandlers) handleCallback(w http.ResponseWriter, r *http.Request, oauthService *auth.OAuthService) {
 	ctx := r.Context()
+	provider := oauthService.Provider()
 
-	// Check for error from Google
+	// Check for error from the provider
 	if errParam := r.URL.Query().Get("error"); errParam != "" {
-		h.logger.Warn("OAuth error from Google", "error", errParam)
+		h.logger.Warn("OAuth error from provider", "provider", provider, "error", errParam)
 		h.redirectWithError(w, r, "Authentication cancelled")
 		return
 	}
 
 	// Validate state parameter (CSRF protection)
 	state := r.URL.Query().Get("state")
-	if !h.oauthService.ValidateState(state) {
-		h.logger.Warn("invalid OAuth state", "state", state[:8]+"...")
+	if !oauthService.ValidateState(state) {
+		h.logger.Warn("invalid OAuth state", "provider", provider)
 		h.redirectWithError(w, r, "Invalid authentication state")
 		return
 	}
@@ -84,22 +117,22 @@ func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Exchange code for user info
-	googleUser, err := h.oauthService.ExchangeCode(ctx, code)
+	oauthUser, err := oauthService.ExchangeCode(ctx, code)
 	if err != nil {
-		h.logger.Error("failed to exchange code", "error", err)
-		h.redirectWithError(w, r, "Failed to authenticate with Google")
+		h.logger.Error("failed to exchange code", "provider", provider, "error", err)
+		h.redirectWithError(w, r, "Failed to authenticate with "+providerTitle(provider))
 		return
 	}
 
 	// Check if email is verified
-	if !googleUser.VerifiedEmail {
-		h.logger.Warn("unverified Google email", "email", googleUser.Email)
-		h.redirectWithError(w, r, "Please verify your Google email first")
+	if oauthUser.Email == "" || !oauthUser.EmailVerified {
+		h.logger.Warn("unverified OAuth email", "provider", provider, "email", oauthUser.Email)
+		h.redirectWithError(w, r, "Please verify your "+providerTitle(provider)+" email first")
 		return
 	}
 
 	// Try to find existing user by OAuth identity
-	user, err := h.userRepo.GetUserByOAuthProvider(ctx, "google", googleUser.ID)
+	user, err := h.userRepo.GetUserByOAuthProvider(ctx, provider, oauthUser.ID)
 	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
 		h.logger.Error("failed to lookup OAuth user", "error", err)
 		h.redirectWithError(w, r, "Database error")
@@ -110,7 +143,7 @@ func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Requ
 
 	if user == nil {
 		// No OAuth identity found, check if user exists by email
-		user, err = h.userRepo.GetByEmail(ctx, googleUser.Email)
+		user, err = h.userRepo.GetByEmail(ctx, oauthUser.Email)
 		if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
 			h.logger.Error("failed to lookup user by email", "error", err)
 			h.redirectWithError(w, r, "Database error")
@@ -119,28 +152,28 @@ func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Requ
 
 		if user != nil {
 			// User exists with this email, link the OAuth identity
-			h.logger.Info("linking OAuth to existing user", "user_id", user.ID, "email", googleUser.Email)
-			if err := h.userRepo.CreateOAuthIdentity(ctx, user.ID, "google", googleUser.ID); err != nil {
+			h.logger.Info("linking OAuth to existing user", "provider", provider, "user_id", user.ID, "email", oauthUser.Email)
+			if err := h.userRepo.CreateOAuthIdentity(ctx, user.ID, provider, oauthUser.ID); err != nil {
 				h.logger.Error("failed to link OAuth identity", "error", err)
 				h.redirectWithError(w, r, "Failed to link account")
 				return
 			}
 		} else {
 			// New user - create account
-			h.logger.Info("creating new OAuth user", "email", googleUser.Email, "name", googleUser.Name)
+			h.logger.Info("creating new OAuth user", "provider", provider, "email", oauthUser.Email, "name", oauthUser.Name)
 
 			// Generate a temporary username (user will be prompted to change it)
-			tempUsername := h.generateTempUsername(googleUser.Name)
+			tempUsername := h.generateTempUsername(oauthUser.Name)
 
 			user = &domain.User{
 				ID:          uuid.New(),
 				Username:    tempUsername,
-				Email:       googleUser.Email,
-				DisplayName: googleUser.Name,
-				AvatarURL:   googleUser.Picture,
+				Email:       oauthUser.Email,
+				DisplayName: oauthUser.Name,
+				AvatarURL:   oauthUser.AvatarURL,
 			}
 
-			if err := h.userRepo.CreateUserWithOAuth(ctx, user, "google", googleUser.ID); err != nil {
+			if err := h.userRepo.CreateUserWithOAuth(ctx, user, provider, oauthUser.ID); err != nil {
 				h.logger.Error("failed to create OAuth user", "error", err)
 				h.redirectWithError(w, r, "Failed to create account")
 				return
@@ -166,7 +199,7 @@ func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Store refresh token
-	if _, err := h.userRepo.CreateRefreshToken(ctx, user.ID, refreshToken, expiresAt); err != nil {
+	if _, err := h.userRepo.CreateRefreshToken(ctx, user.ID, refreshToken, expiresAt, auth.SessionInfoFromRequest(r)); err != nil {
 		h.logger.Error("failed to store refresh token", "error", err)
 		h.redirectWithError(w, r, "Failed to create session")
 		return
@@ -195,6 +228,18 @@ func (h *OAuthHandlers) HandleGoogleCallback(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
 }
 
+// providerTitle capitalizes a provider name for messages shown to users
+func providerTitle(provider string) string {
+	switch provider {
+	case "github":
+		return "GitHub"
+	case "":
+		return provider
+	default:
+		return strings.ToUpper(provider[:1]) + provider[1:]
+	}
+}
+
 // redirectWithError redirects to the frontend with an error message
 func (h *OAuthHandlers) redirectWithError(w http.ResponseWriter, r *http.Request, message string) {
 	redirectURL := fmt.Sprintf("%s/#oauth_error=%s", h.appBaseURL, message)
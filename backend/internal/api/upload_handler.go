@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -12,6 +14,7 @@ import (
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/database"
 	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/media"
 	"github.com/observer/teatime/internal/storage"
 )
 
@@ -20,15 +23,22 @@ type UploadHandler struct {
 	conversationRepo *database.ConversationRepository
 	r2Storage        *storage.R2Storage
 	maxUploadBytes   int64
+	storageQuota     int64
+	images           *media.ImageProcessor
 	allowedMimeTypes []string
+	downloadURLTTL   time.Duration
 	r2Bucket         string
 }
 
+// DefaultDownloadURLTTL is how long /attachments/{id}/download links stay valid
+const DefaultDownloadURLTTL = 15 * time.Minute
+
 func NewUploadHandler(
 	attachmentRepo *database.AttachmentRepository,
 	conversationRepo *database.ConversationRepository,
 	r2Storage *storage.R2Storage,
 	maxUploadBytes int64,
+	storageQuota int64,
 	r2Bucket string,
 ) *UploadHandler {
 	return &UploadHandler{
@@ -36,17 +46,35 @@ func NewUploadHandler(
 		conversationRepo: conversationRepo,
 		r2Storage:        r2Storage,
 		maxUploadBytes:   maxUploadBytes,
+		storageQuota:     storageQuota,
 		r2Bucket:         r2Bucket,
-		allowedMimeTypes: []string{
-			"image/", "video/", "audio/",
-			"application/pdf",
-			"application/msword",
-			"application/vnd.openxmlformats-officedocument",
-			"text/plain",
-		},
+		allowedMimeTypes: media.DefaultAllowedMimeTypes,
+		downloadURLTTL:   DefaultDownloadURLTTL,
 	}
 }
 
+// SetDownloadURLTTL sets how long signed download URLs stay valid; zero
+// keeps the default
+func (h *UploadHandler) SetDownloadURLTTL(ttl time.Duration) {
+	if ttl > 0 {
+		h.downloadURLTTL = ttl
+	}
+}
+
+// SetAllowedMimeTypes replaces the upload type allowlist. Entries ending in
+// "/" or "." match as prefixes. An empty list keeps the default.
+func (h *UploadHandler) SetAllowedMimeTypes(types []string) {
+	if len(types) > 0 {
+		h.allowedMimeTypes = types
+	}
+}
+
+// SetImageProcessor sets the worker that straightens and thumbnails image
+// uploads once they complete
+func (h *UploadHandler) SetImageProcessor(p *media.ImageProcessor) {
+	h.images = p
+}
+
 // InitUpload godoc
 //
 //	@Summary		Initialize file upload
@@ -60,6 +88,8 @@ func NewUploadHandler(
 //	@Failure		400		{object}	map[string]string	"Invalid input"
 //	@Failure		403		{object}	map[string]string	"Not a member of conversation"
 //	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Failure		413		{object}	map[string]string	"Storage quota exceeded"
+//	@Failure		415		{object}	map[string]string	"File type not allowed"
 //	@Router			/uploads/init [post]
 func (h *UploadHandler) InitUpload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -87,9 +117,16 @@ func (h *UploadHandler) InitUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check mime type
-	if !h.isMimeTypeAllowed(req.MimeType) {
-		http.Error(w, "file type not allowed", http.StatusBadRequest)
+	// Check mime type; the content itself is checked once it's uploaded
+	if !media.MimeTypeAllowed(req.MimeType, h.allowedMimeTypes) {
+		http.Error(w, "file type not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Voice clips may say how long they are
+	kind := domain.ClassifyAttachment(req.MimeType)
+	if err := domain.ValidateDuration(kind, req.DurationMs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -127,9 +164,16 @@ func (h *UploadHandler) InitUpload(w http.ResponseWriter, r *http.Request) {
 		SizeBytes:      req.SizeBytes,
 		Status:         domain.AttachmentStatusUploading,
 		CreatedAt:      time.Now(),
+		Kind:           kind,
+		DurationMs:     req.DurationMs,
 	}
 
-	if err := h.attachmentRepo.CreateAttachment(ctx, attachment); err != nil {
+	// The quota is checked and charged together, so parallel uploads can't overrun it
+	if err := h.attachmentRepo.CreateAttachmentWithinQuota(ctx, attachment, h.storageQuota); err != nil {
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			http.Error(w, "quota_exceeded", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "failed to create attachment record", http.StatusInternalServerError)
 		return
 	}
@@ -168,6 +212,7 @@ func (h *UploadHandler) InitUpload(w http.ResponseWriter, r *http.Request) {
 //	@Failure		400		{object}	map[string]string	"Invalid input"
 //	@Failure		403		{object}	map[string]string	"Not authorized"
 //	@Failure		404		{object}	map[string]string	"Attachment not found"
+//	@Failure		415		{object}	map[string]string	"File content doesn't match its type, or the type isn't allowed"
 //	@Router			/uploads/complete [post]
 func (h *UploadHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -213,12 +258,36 @@ func (h *UploadHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Don't trust the client's Content-Type: sniff what was actually stored
+	head, err := h.r2Storage.GetObjectHead(ctx, attachment.ObjectKey, media.SniffLen)
+	if err != nil {
+		http.Error(w, "failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+	if err := media.CheckContent(attachment.MimeType, head, h.allowedMimeTypes); err != nil {
+		h.discardUpload(ctx, attachment)
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Fill in a voice clip's length when the client didn't send one. Best
+	// effort: most formats don't record it up front.
+	if attachment.Kind == domain.AttachmentKindVoice && attachment.DurationMs == nil {
+		if durationMs, ok := media.ProbeDuration(attachment.MimeType, head); ok && domain.ValidateDuration(attachment.Kind, &durationMs) == nil {
+			_ = h.attachmentRepo.SetAttachmentDuration(ctx, attachment.ID, durationMs)
+		}
+	}
+
 	// Mark as ready
 	if err := h.attachmentRepo.MarkAttachmentReady(ctx, req.AttachmentID, req.SHA256); err != nil {
 		http.Error(w, "failed to mark attachment ready", http.StatusInternalServerError)
 		return
 	}
 
+	// Straighten sideways phone photos and pre-render the sizes clients show
+	// in grids and previews, off the request path
+	h.images.Enqueue(attachment)
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
 		"status":        "completed",
@@ -288,21 +357,140 @@ func (h *UploadHandler) GetAttachmentURL(w http.ResponseWriter, r *http.Request)
 		MimeType:     attachment.MimeType,
 		SizeBytes:    attachment.SizeBytes,
 		DownloadURL:  downloadURL,
+		Kind:         attachment.Kind,
+		DurationMs:   attachment.DurationMs,
+		Thumbnails:   media.ThumbnailURLs(ctx, h.r2Storage, attachment.ObjectKey, attachment.ThumbnailSizes, 1*time.Hour),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
-// Helper functions
+// DownloadAttachment godoc
+//
+//	@Summary		Download an attachment
+//	@Description	Redirect to a short-lived signed URL for an attachment. The caller must be a member of its conversation and able to see the message it was sent with (or be its uploader).
+//	@Tags			attachments
+//	@Security		BearerAuth
+//	@Param			id	path	string	true	"Attachment ID"
+//	@Success		302	"Redirect to the signed download URL"
+//	@Failure		403	{object}	map[string]string	"Not authorized"
+//	@Failure		404	{object}	map[string]string	"Attachment not found"
+//	@Router			/attachments/{id}/download [get]
+func (h *UploadHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	attachment, err := h.attachmentRepo.GetAttachmentByID(ctx, r.PathValue("id"))
+	if err != nil || attachment.Status != domain.AttachmentStatusReady {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+
+	allowed, err := h.attachmentRepo.CanDownloadAttachment(ctx, attachment.ID, userID)
+	if err != nil {
+		http.Error(w, "failed to verify access", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "not authorized", http.StatusForbidden)
+		return
+	}
+
+	downloadURL, err := h.r2Storage.GeneratePresignedGetURL(ctx, attachment.ObjectKey, h.downloadURLTTL)
+	if err != nil {
+		http.Error(w, "failed to generate download URL", http.StatusInternalServerError)
+		return
+	}
+
+	// The signed URL expires, so the redirect must not be cached past it
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.Redirect(w, r, downloadURL, http.StatusFound)
+}
+
+// DeleteAttachment godoc
+//
+//	@Summary		Delete an attachment
+//	@Description	Delete an uploaded attachment and free its storage quota (uploader only)
+//	@Tags			attachments
+//	@Security		BearerAuth
+//	@Param			id	path	string	true	"Attachment ID"
+//	@Success		204	"Attachment deleted"
+//	@Failure		403	{object}	map[string]string	"Not authorized"
+//	@Failure		404	{object}	map[string]string	"Attachment not found"
+//	@Failure		409	{object}	map[string]string	"Attachment is used by a message"
+//	@Router			/attachments/{id} [delete]
+func (h *UploadHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	attachment, err := h.attachmentRepo.GetAttachmentByID(ctx, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+
+	if attachment.UploaderID != userID.String() {
+		http.Error(w, "not authorized", http.StatusForbidden)
+		return
+	}
 
-func (h *UploadHandler) isMimeTypeAllowed(mimeType string) bool {
-	for _, allowed := range h.allowedMimeTypes {
-		if strings.HasPrefix(mimeType, allowed) {
-			return true
+	// Removing the row releases its bytes from the uploader's quota and
+	// queues the stored objects for the object reaper
+	if err := h.attachmentRepo.DeleteAttachment(ctx, attachment.ID); err != nil {
+		if errors.Is(err, domain.ErrAttachmentInUse) {
+			http.Error(w, "attachment is used by a message; delete the message instead", http.StatusConflict)
+			return
 		}
+		http.Error(w, "failed to delete attachment", http.StatusInternalServerError)
+		return
 	}
-	return false
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStorageUsage godoc
+//
+//	@Summary		Get storage usage
+//	@Description	Get the current user's attachment storage used and remaining quota
+//	@Tags			uploads
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	domain.StorageUsage	"Storage usage"
+//	@Failure		401	{object}	map[string]string	"Unauthorized"
+//	@Router			/users/me/storage [get]
+func (h *UploadHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	used, err := h.attachmentRepo.GetStorageUsed(ctx, userID)
+	if err != nil {
+		http.Error(w, "failed to get storage usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(domain.NewStorageUsage(used, h.storageQuota))
+}
+
+// Helper functions
+
+// discardUpload removes a rejected upload's record, releasing its quota; the
+// object reaper removes the object
+func (h *UploadHandler) discardUpload(ctx context.Context, attachment *domain.Attachment) {
+	_ = h.attachmentRepo.DeleteAttachment(ctx, attachment.ID)
 }
 
 func (h *UploadHandler) generateObjectKey(conversationID, attachmentID, filename string) string {
@@ -2,18 +2,24 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/database"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/websocket"
 )
 
 // UserHandler handles user-related endpoints
 type UserHandler struct {
-	users  *database.UserRepository
-	logger *slog.Logger
+	users    *database.UserRepository
+	presence websocket.PresenceProvider
+	logger   *slog.Logger
 }
 
 func NewUserHandler(users *database.UserRepository, logger *slog.Logger) *UserHandler {
@@ -23,6 +29,11 @@ func NewUserHandler(users *database.UserRepository, logger *slog.Logger) *UserHa
 	}
 }
 
+// SetPresenceProvider sets the source of live connection state for presence queries
+func (h *UserHandler) SetPresenceProvider(p websocket.PresenceProvider) {
+	h.presence = p
+}
+
 // Search godoc
 //
 //	@Summary		Search users
@@ -125,8 +136,8 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate
-	if len(input.DisplayName) > 100 {
-		writeError(w, http.StatusBadRequest, "display name too long (max 100)")
+	if err := domain.CheckTextLength(input.DisplayName, domain.MaxDisplayNameLength); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("display name too long (max %d)", domain.MaxDisplayNameLength))
 		return
 	}
 	if len(input.AvatarURL) > 500 {
@@ -183,12 +194,12 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 // UpdatePreferences godoc
 //
 //	@Summary		Update preferences
-//	@Description	Update privacy preferences (online status, read receipts)
+//	@Description	Update privacy preferences (online status, read receipts), whether calls from contacts auto-answer, and push notification sound and message previews
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
-//	@Param			request	body		object{show_online_status=bool,read_receipts_enabled=bool}	true	"Preferences"
+//	@Param			request	body		object{show_online_status=bool,read_receipts_enabled=bool,auto_accept_calls=bool,notification_sound=string,message_previews=bool}	true	"Preferences (auto_accept_calls, notification_sound and message_previews are left unchanged if omitted)"
 //	@Success		200	{object}	interface{}
 //	@Failure		400	{object}	map[string]string
 //	@Failure		401	{object}	map[string]string
@@ -201,19 +212,54 @@ func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request)
 	}
 
 	var input struct {
-		ShowOnlineStatus    bool `json:"show_online_status"`
-		ReadReceiptsEnabled bool `json:"read_receipts_enabled"`
+		ShowOnlineStatus    bool    `json:"show_online_status"`
+		ReadReceiptsEnabled bool    `json:"read_receipts_enabled"`
+		AutoAcceptCalls     *bool   `json:"auto_accept_calls"`
+		NotificationSound   *string `json:"notification_sound"`
+		MessagePreviews     *bool   `json:"message_previews"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	if input.NotificationSound != nil {
+		if err := domain.ValidateNotificationSound(*input.NotificationSound); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	if err := h.users.UpdatePreferences(r.Context(), userID, input.ShowOnlineStatus, input.ReadReceiptsEnabled); err != nil {
 		h.logger.Error("update preferences failed", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to update preferences")
 		return
 	}
+	if input.AutoAcceptCalls != nil {
+		if err := h.users.SetAutoAcceptCalls(r.Context(), userID, *input.AutoAcceptCalls); err != nil {
+			h.logger.Error("update auto-accept failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update preferences")
+			return
+		}
+	}
+	if input.NotificationSound != nil || input.MessagePreviews != nil {
+		current, err := h.users.GetByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		sound, previews := current.NotificationSound, current.MessagePreviews
+		if input.NotificationSound != nil {
+			sound = *input.NotificationSound
+		}
+		if input.MessagePreviews != nil {
+			previews = *input.MessagePreviews
+		}
+		if err := h.users.SetNotificationPreferences(r.Context(), userID, sound, previews); err != nil {
+			h.logger.Error("update notification preferences failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to update preferences")
+			return
+		}
+	}
 
 	// Return updated user
 	user, err := h.users.GetByID(r.Context(), userID)
@@ -253,3 +299,196 @@ func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"message": "account deleted successfully"})
 }
 
+// QueryPresence godoc
+//
+//	@Summary		Query presence
+//	@Description	Get online status and last seen for a batch of users, honoring each user's privacy settings
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		object{user_ids=[]string}	true	"User IDs (max 100)"
+//	@Success		200	{object}	object{presence=[]domain.UserPresence,count=int}
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/presence/query [post]
+func (h *UserHandler) QueryPresence(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.GetUserID(r.Context()); !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input struct {
+		UserIDs []uuid.UUID `json:"user_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(input.UserIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "user_ids required")
+		return
+	}
+	if len(input.UserIDs) > domain.MaxPresenceQueryUsers {
+		writeError(w, http.StatusBadRequest, "too many user_ids (max 100)")
+		return
+	}
+
+	users, err := h.users.GetByIDs(r.Context(), input.UserIDs)
+	if err != nil {
+		h.logger.Error("query presence failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to query presence")
+		return
+	}
+
+	online := map[uuid.UUID]bool{}
+	if h.presence != nil {
+		online = h.presence.OnlineStatus(input.UserIDs)
+	}
+
+	presence := make([]domain.UserPresence, len(users))
+	for i, u := range users {
+		presence[i] = u.Presence(online[u.ID])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"presence": presence,
+		"count":    len(presence),
+	})
+}
+
+// GetPresence godoc
+//
+//	@Summary		Get user presence
+//	@Description	Get whether a user is online and when they were last seen, honoring their privacy settings
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			username	path		string	true	"Username"
+//	@Success		200	{object}	domain.UserPresence
+//	@Failure		401	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/users/{username}/presence [get]
+func (h *UserHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.GetUserID(r.Context()); !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	user, err := h.users.GetByUsername(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	online := h.presence != nil && h.presence.IsOnline(user.ID)
+	writeJSON(w, http.StatusOK, user.Presence(online))
+}
+
+// ListContacts godoc
+//
+//	@Summary		List contacts
+//	@Description	List your contacts. Calls from contacts auto-answer if auto_accept_calls is on.
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	object{contacts=[]domain.PublicUser,count=int}
+//	@Failure		401	{object}	map[string]string
+//	@Router			/users/me/contacts [get]
+func (h *UserHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	contacts, err := h.users.GetContacts(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("list contacts failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list contacts")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"contacts": contacts,
+		"count":    len(contacts),
+	})
+}
+
+// AddContact godoc
+//
+//	@Summary		Add contact
+//	@Description	Add a user to your contacts
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/users/me/contacts/{userId} [post]
+func (h *UserHandler) AddContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	contactID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if _, err := h.users.GetByID(r.Context(), contactID); err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := h.users.AddContact(r.Context(), userID, contactID); err != nil {
+		if errors.Is(err, domain.ErrSelfContact) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("add contact failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to add contact")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "contact added"})
+}
+
+// RemoveContact godoc
+//
+//	@Summary		Remove contact
+//	@Description	Remove a user from your contacts
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	map[string]string
+//	@Failure		401	{object}	map[string]string
+//	@Router			/users/me/contacts/{userId} [delete]
+func (h *UserHandler) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	contactID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if err := h.users.RemoveContact(r.Context(), userID, contactID); err != nil {
+		h.logger.Error("remove contact failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to remove contact")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "contact removed"})
+}
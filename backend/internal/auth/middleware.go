@@ -68,6 +68,28 @@ func OptionalMiddleware(authService *Service) func(http.Handler) http.Handler {
 	}
 }
 
+// AdminMiddleware restricts a route to the configured operator accounts.
+// It must run after Middleware so the user ID is in the context.
+func AdminMiddleware(adminUserIDs []string) func(http.Handler) http.Handler {
+	admins := make(map[uuid.UUID]bool, len(adminUserIDs))
+	for _, s := range adminUserIDs {
+		if id, err := uuid.Parse(s); err == nil {
+			admins[id] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r.Context())
+			if !ok || !admins[userID] {
+				http.Error(w, `{"error":"admin access required"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(ctx context.Context) (uuid.UUID, bool) {
 	id, ok := ctx.Value(UserIDKey).(uuid.UUID)
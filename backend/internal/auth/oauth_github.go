@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// githubUser is the profile returned by GitHub's /user endpoint
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// githubEmail is one entry from GitHub's /user/emails endpoint
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func fetchGitHubUser(_ context.Context, client *http.Client) (*OAuthUser, error) {
+	var user githubUser
+	if err := getJSON(client, "https://api.github.com/user", &user); err != nil {
+		return nil, err
+	}
+	// The profile email is optional and may be unverified, so ask for the
+	// account's email addresses instead
+	var emails []githubEmail
+	if err := getJSON(client, "https://api.github.com/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+	email, verified := primaryGitHubEmail(emails)
+	return &OAuthUser{
+		ID:            strconv.FormatInt(user.ID, 10),
+		Email:         email,
+		EmailVerified: verified,
+		Name:          name,
+		AvatarURL:     user.AvatarURL,
+	}, nil
+}
+
+// primaryGitHubEmail picks the account's primary email, falling back to any
+// verified one, and reports whether GitHub has verified it
+func primaryGitHubEmail(emails []githubEmail) (string, bool) {
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, true
+		}
+	}
+	for _, e := range emails {
+		if e.Verified {
+			return e.Email, true
+		}
+	}
+	for _, e := range emails {
+		if e.Primary {
+			return e.Email, false
+		}
+	}
+	return "", false
+}
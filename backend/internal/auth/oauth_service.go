@@ -13,56 +13,90 @@ import (
 	"time"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
 )
 
-// GoogleUser represents user info returned from Google OAuth
-type GoogleUser struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	VerifiedEmail bool   `json:"verified_email"`
-	Name          string `json:"name"`
-	GivenName     string `json:"given_name"`
-	FamilyName    string `json:"family_name"`
-	Picture       string `json:"picture"`
+// OAuthUser is the account a provider vouches for, in provider-neutral form
+type OAuthUser struct {
+	ID            string // provider's stable user ID
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+}
+
+// oauthProvider describes how to sign in with one identity provider
+type oauthProvider struct {
+	endpoint  oauth2.Endpoint
+	scopes    []string
+	fetchUser func(ctx context.Context, client *http.Client) (*OAuthUser, error)
+}
+
+// oauthProviders lists the identity providers that can be configured, by name
+var oauthProviders = map[string]oauthProvider{
+	"google": {
+		endpoint: google.Endpoint,
+		scopes: []string{
+			"https://www.googleapis.com/auth/userinfo.email",
+			"https://www.googleapis.com/auth/userinfo.profile",
+		},
+		fetchUser: fetchGoogleUser,
+	},
+	"github": {
+		endpoint:  github.Endpoint,
+		scopes:    []string{"read:user", "user:email"},
+		fetchUser: fetchGitHubUser,
+	},
 }
 
-// OAuthService handles Google OAuth flow
+// OAuthService handles the OAuth flow for one identity provider
 type OAuthService struct {
-	config *oauth2.Config
-	logger *slog.Logger
+	provider string
+	config   *oauth2.Config
+	fetch    func(ctx context.Context, client *http.Client) (*OAuthUser, error)
+	logger   *slog.Logger
 
 	// State token store (in-memory for now, expires after 10 minutes)
 	states   map[string]time.Time
 	statesMu sync.Mutex
 }
 
-// NewOAuthService creates a new OAuth service
-func NewOAuthService(clientID, clientSecret, redirectURL string) *OAuthService {
+// NewOAuthService creates an OAuth service for the named provider ("google"
+// or "github")
+func NewOAuthService(provider, clientID, clientSecret, redirectURL string) (*OAuthService, error) {
+	p, ok := oauthProviders[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown OAuth provider %q", provider)
+	}
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		RedirectURL:  redirectURL,
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+		Scopes:       p.scopes,
+		Endpoint:     p.endpoint,
 	}
 
 	svc := &OAuthService{
-		config: config,
-		logger: slog.Default().With("component", "oauth"),
-		states: make(map[string]time.Time),
+		provider: provider,
+		config:   config,
+		fetch:    p.fetchUser,
+		logger:   slog.Default().With("component", "oauth", "provider", provider),
+		states:   make(map[string]time.Time),
 	}
 
 	// Start cleanup goroutine
 	go svc.cleanupExpiredStates()
 
-	return svc
+	return svc, nil
 }
 
-// GetAuthURL generates the Google OAuth authorization URL
+// Provider returns the name of the identity provider
+func (s *OAuthService) Provider() string {
+	return s.provider
+}
+
+// GetAuthURL generates the provider's authorization URL
 func (s *OAuthService) GetAuthURL() (string, string, error) {
 	state, err := s.generateState()
 	if err != nil {
@@ -90,8 +124,8 @@ func (s *OAuthService) ValidateState(state string) bool {
 	return time.Now().Before(expiresAt)
 }
 
-// ExchangeCode exchanges the authorization code for Google user info
-func (s *OAuthService) ExchangeCode(ctx context.Context, code string) (*GoogleUser, error) {
+// ExchangeCode exchanges the authorization code for the provider's user info
+func (s *OAuthService) ExchangeCode(ctx context.Context, code string) (*OAuthUser, error) {
 	// Exchange code for token
 	token, err := s.config.Exchange(ctx, code)
 	if err != nil {
@@ -100,33 +134,63 @@ func (s *OAuthService) ExchangeCode(ctx context.Context, code string) (*GoogleUs
 	}
 
 	// Fetch user info using the access token
-	client := s.config.Client(ctx, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	user, err := s.fetch(ctx, s.config.Client(ctx, token))
 	if err != nil {
 		s.logger.Error("failed to fetch user info", "error", err)
-		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+		return nil, err
+	}
+
+	s.logger.Info("successfully fetched OAuth user info",
+		"provider_user_id", user.ID,
+		"email", user.Email,
+		"name", user.Name,
+	)
+
+	return user, nil
+}
+
+// getJSON fetches url with an OAuth-authorized client and decodes the JSON body into v
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user info: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		s.logger.Error("user info request failed", "status", resp.StatusCode, "body", string(body))
-		return nil, fmt.Errorf("user info request failed: %s", resp.Status)
+		return fmt.Errorf("user info request failed: %s: %s", resp.Status, body)
 	}
 
-	var user GoogleUser
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		s.logger.Error("failed to decode user info", "error", err)
-		return nil, fmt.Errorf("failed to decode user info: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode user info: %w", err)
 	}
+	return nil
+}
 
-	s.logger.Info("successfully fetched Google user info",
-		"google_id", user.ID,
-		"email", user.Email,
-		"name", user.Name,
-	)
+// googleUser represents user info returned from Google OAuth
+type googleUser struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Picture       string `json:"picture"`
+}
 
-	return &user, nil
+func fetchGoogleUser(_ context.Context, client *http.Client) (*OAuthUser, error) {
+	var user googleUser
+	if err := getJSON(client, "https://www.googleapis.com/oauth2/v2/userinfo", &user); err != nil {
+		return nil, err
+	}
+	return &OAuthUser{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.VerifiedEmail,
+		Name:          user.Name,
+		AvatarURL:     user.Picture,
+	}, nil
 }
 
 // generateState creates a cryptographically secure random state string
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOAuthService_Providers(t *testing.T) {
+	for _, name := range []string{"google", "github"} {
+		svc, err := NewOAuthService(name, "id", "secret", "http://localhost/auth/"+name+"/callback")
+		require.NoError(t, err)
+		assert.Equal(t, name, svc.Provider())
+	}
+
+	_, err := NewOAuthService("myspace", "id", "secret", "")
+	assert.Error(t, err, "unknown providers are a configuration error")
+}
+
+func TestPrimaryGitHubEmail(t *testing.T) {
+	email, verified := primaryGitHubEmail([]githubEmail{
+		{Email: "old@example.com", Verified: true},
+		{Email: "main@example.com", Primary: true, Verified: true},
+	})
+	assert.Equal(t, "main@example.com", email)
+	assert.True(t, verified)
+
+	email, verified = primaryGitHubEmail([]githubEmail{
+		{Email: "main@example.com", Primary: true},
+		{Email: "work@example.com", Verified: true},
+	})
+	assert.Equal(t, "work@example.com", email, "a verified address beats an unverified primary")
+	assert.True(t, verified)
+
+	email, verified = primaryGitHubEmail([]githubEmail{{Email: "main@example.com", Primary: true}})
+	assert.Equal(t, "main@example.com", email)
+	assert.False(t, verified, "unverified addresses can't be used to sign in")
+}
@@ -21,13 +21,16 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
 	EmailExists(ctx context.Context, email string) (bool, error)
 	UsernameExists(ctx context.Context, username string) (bool, error)
 
-	CreateRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) (uuid.UUID, error)
+	CreateRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, info domain.SessionInfo) (uuid.UUID, error)
 	GetRefreshToken(ctx context.Context, token string) (*domain.RefreshToken, error)
 	RevokeRefreshToken(ctx context.Context, tokenID uuid.UUID) error
 	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
+	ListActiveRefreshTokens(ctx context.Context, userID uuid.UUID) ([]domain.RefreshToken, error)
+	RevokeUserRefreshToken(ctx context.Context, userID, tokenID uuid.UUID) error
 }
 
 // Service handles authentication logic
@@ -154,6 +157,56 @@ func (s *Service) Login(ctx context.Context, input LoginInput) (*domain.User, *T
 	return user, tokens, nil
 }
 
+// ChangePasswordInput for a logged-in user changing their password
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword replaces a user's password after checking the current one.
+// Every existing session is revoked and a fresh token pair is returned so the
+// caller stays logged in.
+func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, input ChangePasswordInput) (*domain.User, *TokenPair, error) {
+	if err := validatePassword(input.NewPassword); err != nil {
+		return nil, nil, err
+	}
+
+	// Accounts without a password (OAuth sign-ins) can't change one
+	hash, err := s.users.GetPasswordHash(ctx, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("get password: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(input.CurrentPassword)); err != nil {
+		return nil, nil, domain.ErrInvalidCredentials
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hash password: %w", err)
+	}
+	if err := s.users.UpdatePasswordHash(ctx, userID, string(newHash)); err != nil {
+		return nil, nil, fmt.Errorf("update password: %w", err)
+	}
+
+	if err := s.users.RevokeAllUserTokens(ctx, userID); err != nil {
+		return nil, nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get user: %w", err)
+	}
+	tokens, err := s.generateTokenPair(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
 // Refresh generates new tokens using a refresh token
 func (s *Service) Refresh(ctx context.Context, refreshToken string) (*domain.User, *TokenPair, error) {
 	// Get stored token
@@ -205,6 +258,25 @@ func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
 	return s.users.RevokeAllUserTokens(ctx, userID)
 }
 
+// ListSessions returns the user's active sessions (unrevoked refresh tokens)
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.RefreshToken, error) {
+	return s.users.ListActiveRefreshTokens(ctx, userID)
+}
+
+// RevokeSession logs out one of the user's sessions
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return s.users.RevokeUserRefreshToken(ctx, userID, sessionID)
+}
+
+// SessionID returns the session a refresh token belongs to, if it's still valid
+func (s *Service) SessionID(ctx context.Context, refreshToken string) (uuid.UUID, bool) {
+	storedToken, err := s.users.GetRefreshToken(ctx, refreshToken)
+	if err != nil || !storedToken.IsValid() {
+		return uuid.Nil, false
+	}
+	return storedToken.ID, true
+}
+
 // ValidateToken validates an access token and returns claims
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	return s.tokens.ValidateAccessToken(tokenString)
@@ -224,8 +296,8 @@ func (s *Service) generateTokenPair(ctx context.Context, user *domain.User) (*To
 		return nil, fmt.Errorf("generate refresh token: %w", err)
 	}
 
-	// Store refresh token
-	_, err = s.users.CreateRefreshToken(ctx, user.ID, refreshToken, refreshExpiresAt)
+	// Store refresh token, noting the client it was issued to
+	_, err = s.users.CreateRefreshToken(ctx, user.ID, refreshToken, refreshExpiresAt, sessionInfo(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("store refresh token: %w", err)
 	}
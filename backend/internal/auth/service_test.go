@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// memoryUserRepo keeps users, password hashes and refresh tokens in memory
+type memoryUserRepo struct {
+	mu     sync.Mutex
+	users  map[uuid.UUID]*domain.User
+	hashes map[uuid.UUID]string
+	tokens map[string]*domain.RefreshToken
+}
+
+func newMemoryUserRepo() *memoryUserRepo {
+	return &memoryUserRepo{
+		users:  make(map[uuid.UUID]*domain.User),
+		hashes: make(map[uuid.UUID]string),
+		tokens: make(map[string]*domain.RefreshToken),
+	}
+}
+
+func (r *memoryUserRepo) Create(_ context.Context, user *domain.User, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.ID] = user
+	r.hashes[user.ID] = passwordHash
+	return nil
+}
+
+func (r *memoryUserRepo) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *memoryUserRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *memoryUserRepo) GetPasswordHash(_ context.Context, userID uuid.UUID) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.hashes[userID]; ok {
+		return h, nil
+	}
+	return "", domain.ErrUserNotFound
+}
+
+func (r *memoryUserRepo) UpdatePasswordHash(_ context.Context, userID uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hashes[userID]; !ok {
+		return domain.ErrUserNotFound
+	}
+	r.hashes[userID] = passwordHash
+	return nil
+}
+
+func (r *memoryUserRepo) EmailExists(ctx context.Context, email string) (bool, error) {
+	_, err := r.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (r *memoryUserRepo) UsernameExists(_ context.Context, username string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUserRepo) CreateRefreshToken(_ context.Context, userID uuid.UUID, token string, expiresAt time.Time, info domain.SessionInfo) (uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := uuid.New()
+	r.tokens[token] = &domain.RefreshToken{ID: id, UserID: userID, ExpiresAt: expiresAt, CreatedAt: time.Now(), UserAgent: info.UserAgent, IP: info.IP}
+	return id, nil
+}
+
+func (r *memoryUserRepo) GetRefreshToken(_ context.Context, token string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rt, ok := r.tokens[token]; ok {
+		return rt, nil
+	}
+	return nil, domain.ErrTokenInvalid
+}
+
+func (r *memoryUserRepo) RevokeRefreshToken(_ context.Context, tokenID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, rt := range r.tokens {
+		if rt.ID == tokenID {
+			rt.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepo) RevokeAllUserTokens(_ context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, rt := range r.tokens {
+		if rt.UserID == userID && rt.RevokedAt == nil {
+			rt.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepo) ListActiveRefreshTokens(_ context.Context, userID uuid.UUID) ([]domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []domain.RefreshToken
+	for _, rt := range r.tokens {
+		if rt.UserID == userID && rt.IsValid() {
+			active = append(active, *rt)
+		}
+	}
+	return active, nil
+}
+
+func (r *memoryUserRepo) RevokeUserRefreshToken(_ context.Context, userID, tokenID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rt := range r.tokens {
+		if rt.ID == tokenID && rt.UserID == userID && rt.RevokedAt == nil {
+			now := time.Now()
+			rt.RevokedAt = &now
+			return nil
+		}
+	}
+	return domain.ErrSessionNotFound
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryUserRepo()
+	svc := NewService(repo, newTestTokenService(t, "", ""))
+
+	user, first, err := svc.Register(ctx, RegisterInput{Email: "alice@example.com", Username: "alice", Password: "Original1"})
+	require.NoError(t, err)
+	_, second, err := svc.Login(ctx, LoginInput{Email: "alice@example.com", Password: "Original1"})
+	require.NoError(t, err)
+
+	_, _, err = svc.ChangePassword(ctx, user.ID, ChangePasswordInput{CurrentPassword: "Wrong1234", NewPassword: "Updated22"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials, "the current password must match")
+
+	_, _, err = svc.ChangePassword(ctx, user.ID, ChangePasswordInput{CurrentPassword: "Original1", NewPassword: "weak"})
+	assert.Error(t, err, "the new password must meet the registration rules")
+
+	_, fresh, err := svc.ChangePassword(ctx, user.ID, ChangePasswordInput{CurrentPassword: "Original1", NewPassword: "Updated22"})
+	require.NoError(t, err)
+
+	hash, err := repo.GetPasswordHash(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("Updated22")))
+
+	for _, old := range []*TokenPair{first, second} {
+		_, _, err := svc.Refresh(ctx, old.RefreshToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked, "existing sessions are logged out")
+	}
+	_, _, err = svc.Refresh(ctx, fresh.RefreshToken)
+	assert.NoError(t, err, "the caller's new session stays valid")
+
+	_, _, err = svc.Login(ctx, LoginInput{Email: "alice@example.com", Password: "Updated22"})
+	assert.NoError(t, err)
+}
+
+func TestService_ChangePassword_NoPassword(t *testing.T) {
+	svc := NewService(newMemoryUserRepo(), newTestTokenService(t, "", ""))
+	_, _, err := svc.ChangePassword(context.Background(), uuid.New(), ChangePasswordInput{CurrentPassword: "Anything1", NewPassword: "Updated22"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials, "accounts without a password can't change it")
+}
+
+func TestService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(newMemoryUserRepo(), newTestTokenService(t, "", ""))
+
+	laptop := WithSessionInfo(ctx, domain.SessionInfo{UserAgent: "Firefox", IP: "203.0.113.7"})
+	user, first, err := svc.Register(laptop, RegisterInput{Email: "alice@example.com", Username: "alice", Password: "Original1"})
+	require.NoError(t, err)
+	phone := WithSessionInfo(ctx, domain.SessionInfo{UserAgent: "TeaTime iOS", IP: "198.51.100.2"})
+	_, second, err := svc.Login(phone, LoginInput{Email: "alice@example.com", Password: "Original1"})
+	require.NoError(t, err)
+
+	sessions, err := svc.ListSessions(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	agents := []string{sessions[0].UserAgent, sessions[1].UserAgent}
+	assert.ElementsMatch(t, []string{"Firefox", "TeaTime iOS"}, agents, "sessions record the client they were issued to")
+
+	phoneID, ok := svc.SessionID(ctx, second.RefreshToken)
+	require.True(t, ok)
+	assert.ErrorIs(t, svc.RevokeSession(ctx, uuid.New(), phoneID), domain.ErrSessionNotFound, "users can't revoke each other's sessions")
+	require.NoError(t, svc.RevokeSession(ctx, user.ID, phoneID))
+	assert.ErrorIs(t, svc.RevokeSession(ctx, user.ID, phoneID), domain.ErrSessionNotFound)
+
+	_, _, err = svc.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+	_, ok = svc.SessionID(ctx, second.RefreshToken)
+	assert.False(t, ok)
+
+	sessions, err = svc.ListSessions(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	firstID, _ := svc.SessionID(ctx, first.RefreshToken)
+	assert.Equal(t, firstID, sessions[0].ID, "other sessions are untouched")
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+type sessionInfoKey struct{}
+
+// WithSessionInfo attaches the client a new session is for, so tokens issued
+// under ctx record it
+func WithSessionInfo(ctx context.Context, info domain.SessionInfo) context.Context {
+	return context.WithValue(ctx, sessionInfoKey{}, info)
+}
+
+// sessionInfo returns the client attached with WithSessionInfo, if any
+func sessionInfo(ctx context.Context) domain.SessionInfo {
+	info, _ := ctx.Value(sessionInfoKey{}).(domain.SessionInfo)
+	return info
+}
+
+// SessionInfoFromRequest describes the client making r by its user agent
+// and remote address
+func SessionInfoFromRequest(r *http.Request) domain.SessionInfo {
+	userAgent := r.UserAgent()
+	if len(userAgent) > domain.MaxUserAgentLength {
+		userAgent = userAgent[:domain.MaxUserAgentLength]
+	}
+	// Drops a rune cut in half above, and any bytes Postgres would refuse as text
+	userAgent = strings.ToValidUTF8(userAgent, "")
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return domain.SessionInfo{UserAgent: userAgent, IP: ip}
+}
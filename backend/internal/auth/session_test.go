@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+func TestSessionInfoFromRequest_TruncatesUserAgentOnRuneBoundary(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/auth/login", nil)
+	r.RemoteAddr = "203.0.113.7:4242"
+	// One byte short of the cap, then a two-byte rune straddling it
+	r.Header.Set("User-Agent", strings.Repeat("a", domain.MaxUserAgentLength-1)+"é and more")
+
+	info := SessionInfoFromRequest(r)
+	assert.True(t, utf8.ValidString(info.UserAgent))
+	assert.Equal(t, strings.Repeat("a", domain.MaxUserAgentLength-1), info.UserAgent)
+	assert.Equal(t, "203.0.113.7", info.IP)
+}
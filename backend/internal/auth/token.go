@@ -19,6 +19,9 @@ const (
 	TokenTypeRefresh TokenType = "refresh"
 )
 
+// DefaultIssuer is the iss claim used when no issuer is configured
+const DefaultIssuer = "teatime"
+
 // Claims represents the JWT claims
 type Claims struct {
 	jwt.RegisteredClaims
@@ -32,6 +35,8 @@ type TokenService struct {
 	signingKey      []byte
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	issuer          string
+	audience        string // empty means aud is neither set nor checked
 }
 
 // NewTokenService creates a new token service
@@ -43,9 +48,20 @@ func NewTokenService(signingKey string) (*TokenService, error) {
 		signingKey:      []byte(signingKey),
 		accessTokenTTL:  10 * 24 * time.Hour, // 10 days (increased to reduce frequent re-logins)
 		refreshTokenTTL: 30 * 24 * time.Hour, // 30 days
+		issuer:          DefaultIssuer,
 	}, nil
 }
 
+// SetIssuerAudience configures the iss and aud claims stamped on new access
+// tokens and required when validating them. An empty issuer keeps the default;
+// an empty audience disables the aud check.
+func (s *TokenService) SetIssuerAudience(issuer, audience string) {
+	if issuer != "" {
+		s.issuer = issuer
+	}
+	s.audience = audience
+}
+
 // GenerateAccessToken creates a short-lived access token
 func (s *TokenService) GenerateAccessToken(userID uuid.UUID, username string) (string, time.Time, error) {
 	now := time.Now()
@@ -57,13 +73,17 @@ func (s *TokenService) GenerateAccessToken(userID uuid.UUID, username string) (s
 			Subject:   userID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			Issuer:    "teatime",
+			Issuer:    s.issuer,
 		},
 		UserID:   userID,
 		Username: username,
 		Type:     TokenTypeAccess,
 	}
 
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signed, err := token.SignedString(s.signingKey)
 	if err != nil {
@@ -89,12 +109,17 @@ func (s *TokenService) GenerateRefreshToken() (string, time.Time, error) {
 
 // ValidateAccessToken parses and validates an access token
 func (s *TokenService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithIssuer(s.issuer)}
+	if s.audience != "" {
+		opts = append(opts, jwt.WithAudience(s.audience))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return s.signingKey, nil
-	})
+	}, opts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "test-signing-key-at-least-32-characters"
+
+func newTestTokenService(t *testing.T, issuer, audience string) *TokenService {
+	t.Helper()
+	svc, err := NewTokenService(testSigningKey)
+	require.NoError(t, err)
+	svc.SetIssuerAudience(issuer, audience)
+	return svc
+}
+
+func TestTokenService_DefaultIssuer(t *testing.T) {
+	svc := newTestTokenService(t, "", "")
+	userID := uuid.New()
+
+	token, _, err := svc.GenerateAccessToken(userID, "alice")
+	require.NoError(t, err)
+
+	claims, err := svc.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultIssuer, claims.Issuer)
+	assert.Empty(t, claims.Audience)
+	assert.Equal(t, userID, claims.UserID)
+}
+
+func TestTokenService_AcceptsMatchingIssuerAudience(t *testing.T) {
+	svc := newTestTokenService(t, "teatime-auth", "teatime-api")
+
+	token, _, err := svc.GenerateAccessToken(uuid.New(), "alice")
+	require.NoError(t, err)
+
+	claims, err := svc.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "teatime-auth", claims.Issuer)
+	assert.Contains(t, claims.Audience, "teatime-api")
+}
+
+func TestTokenService_RejectsIssuerMismatch(t *testing.T) {
+	minter := newTestTokenService(t, "other-service", "teatime-api")
+	validator := newTestTokenService(t, "teatime-auth", "teatime-api")
+
+	token, _, err := minter.GenerateAccessToken(uuid.New(), "alice")
+	require.NoError(t, err)
+
+	_, err = validator.ValidateAccessToken(token)
+	assert.Error(t, err)
+}
+
+func TestTokenService_RejectsAudienceMismatch(t *testing.T) {
+	minter := newTestTokenService(t, "teatime-auth", "other-api")
+	validator := newTestTokenService(t, "teatime-auth", "teatime-api")
+
+	token, _, err := minter.GenerateAccessToken(uuid.New(), "alice")
+	require.NoError(t, err)
+
+	_, err = validator.ValidateAccessToken(token)
+	assert.Error(t, err)
+}
+
+func TestTokenService_RejectsMissingAudience(t *testing.T) {
+	minter := newTestTokenService(t, "teatime-auth", "")
+	validator := newTestTokenService(t, "teatime-auth", "teatime-api")
+
+	token, _, err := minter.GenerateAccessToken(uuid.New(), "alice")
+	require.NoError(t, err)
+
+	_, err = validator.ValidateAccessToken(token)
+	assert.Error(t, err)
+}
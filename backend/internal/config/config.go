@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all application configuration.
@@ -14,12 +16,14 @@ type Config struct {
 	Env        string // "development" or "production"
 
 	// Database
-	DatabaseURL string
+	DatabaseURL        string
+	DBStatementTimeout time.Duration // server-side statement_timeout, 0 disables
 
 	// Auth (will be populated later)
-	JWTSigningKey  string
-	GitHubClientID string
-	GitHubSecret   string
+	JWTSigningKey string
+	JWTIssuer     string   // iss claim stamped on and required of access tokens
+	JWTAudience   string   // aud claim; empty disables the audience check
+	AdminUserIDs  []string // user IDs allowed to reach /admin endpoints
 
 	// URLs
 	AppBaseURL string
@@ -33,6 +37,10 @@ type Config struct {
 	ICETURNURLs  []string
 	TURNUsername string
 	TURNPassword string
+	// Shared secret for time-limited TURN credentials, replacing the static
+	// pair when set
+	TURNSecret        string
+	TURNCredentialTTL time.Duration
 
 	// R2 / File Storage
 	R2AccountID       string
@@ -40,17 +48,131 @@ type Config struct {
 	R2SecretAccessKey string
 	R2Bucket          string
 	R2Endpoint        string
+	R2MaxAttempts     int           // tries per object operation, 1 disables retries
+	R2RetryBaseDelay  time.Duration // first retry backoff, doubled per attempt
 	MaxUploadBytes    int64
+	StorageQuotaBytes int64 // per-user attachment quota, 0 disables
+	ImageAutoOrient   bool  // apply EXIF orientation to uploaded JPEGs
+
+	// Upload MIME type allowlist; entries ending in "/" or "." match as
+	// prefixes (e.g. "image/"). Empty uses media.DefaultAllowedMimeTypes.
+	UploadAllowedMimeTypes []string
+
+	// How long signed attachment download URLs stay valid
+	AttachmentURLTTL time.Duration
+
+	// Thumbnail variants generated for image uploads, by longest edge in
+	// pixels (e.g. "96,400,1080"). Empty disables thumbnails.
+	ThumbnailSizes []string
+
+	// Flood detection (messages summed across conversations)
+	FloodMaxMessages int           // max sends per window, 0 disables
+	FloodWindow      time.Duration // sliding window for counting sends
+	FloodCooldown    time.Duration // how long a flooding user stays muted
+
+	// Calls
+	CallReconnectGrace time.Duration // how long a dropped connection stays in its calls, 0 disables
+	CallRingTimeout    time.Duration // how long a call rings unanswered before it's marked missed
+	CallIdleTimeout    time.Duration // how long a disconnected, silent participant stays in a 1:1 call
+	SFUMaxParticipants int           // participants allowed in one group call
+	SFUAudioLevels     bool          // announce the active speaker in group calls
+	SFURecordingDir    string        // where admins' group call recordings go, empty disables recording
+	SFUSimulcast       bool          // forward each group call subscriber the simulcast layer their bandwidth allows
+	SFUMaxVideo        int           // forward only the latest speakers' video in big group calls, 0 forwards all
+
+	// Reconnect-storm shedding for WebSocket upgrades
+	WSMaxPendingAuths int           // connections allowed to be mid-auth at once, 0 disables
+	WSStormRetryAfter time.Duration // base retry delay sent to shed clients, jittered up to 2x
+	WSAuthTimeout     time.Duration // unauthenticated connections are closed after this, 0 disables
+
+	// How often connected users' last seen time is refreshed, 0 only records disconnects
+	LastSeenHeartbeat time.Duration
+
+	// WebSocket liveness: ping interval, and silence after which a connection is dropped
+	WSPingInterval time.Duration
+	WSPongWait     time.Duration
+
+	// Per-connection WebSocket event limits; events over them are dropped
+	WSRateMessages int // chat messages and typing events per window, 0 disables
+	WSRateSignals  int // call signaling events per window, 0 disables
+	WSRateWindow   time.Duration
+
+	// WebSocket permessage-deflate: whether to offer it, the deflate level
+	// (-2 to 9) and the smallest frame worth compressing, in bytes
+	WSCompression        bool
+	WSCompressionLevel   int
+	WSCompressionMinSize int
+
+	// Missed-event replay for reconnecting clients: recent room events kept
+	// per conversation (0 disables) and for how long
+	WSReplayBufferSize int
+	WSReplayMaxAge     time.Duration
+
+	// Text filtering for group titles, nicknames and announcements
+	TextFilterMode string   // "reject" or "mask"
+	TextBlocklist  []string // case-insensitive whole-word terms
+	MaxTitleLength int      // longest group title, in visible characters; 0 keeps the default
+
+	// Message and group size limits; 0 keeps the defaults
+	MaxMessageLength int // longest message body, in bytes
+	MaxGroupMembers  int // most members a group may be created with
+
+	// Message pins: how often expired pins are swept, and how many a
+	// conversation may hold (0 disables the limit)
+	PinSweepInterval  time.Duration
+	MaxPinnedMessages int
+
+	// How often expired disappearing messages are deleted
+	MessageSweepInterval time.Duration
+
+	// How often due scheduled messages are sent
+	ScheduledDispatchInterval time.Duration
+
+	// How often deleted attachments' objects are removed from R2
+	ObjectReapInterval time.Duration
+
+	// Link previews: whether links in new messages are unfurled, how long a
+	// page fetch may take, and how many fetches a minute any one host gets.
+	// Off by default, as unfurling fetches whatever URLs users post.
+	LinkPreviewsEnabled bool
+	LinkPreviewTimeout  time.Duration
+	LinkPreviewHostRate int
+
+	// How long after sending a message its sender may edit it, 0 disables the limit
+	MessageEditWindow time.Duration
+
+	// Whether messaging a user by username starts a DM if none exists yet
+	AutoCreateDMs bool
+
+	// Base64 AES-256 key for encrypting message bodies and attachment
+	// filenames at rest. Empty leaves them in plaintext; setting it
+	// disables full-text search.
+	MessageEncryptionKey string
+
+	// Re-check membership and bans on cross-conversation search results
+	// after the query, so a just-left conversation can't leak a hit
+	SearchAccessRecheck bool
+
+	// Longest search query accepted, in characters, and how many of its
+	// terms are searched for; 0 disables a limit
+	SearchMaxQueryLength int
+	SearchMaxTerms       int
 
 	// Redis (for PubSub horizontal scaling)
 	RedisURL   string // e.g., "redis://localhost:6379"
 	PubSubType string // "memory" or "redis"
 
-	// Google OAuth
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleRedirectURL  string // OAuth callback URL
-	OAuthEnabled       bool   // Feature flag for OAuth
+	// OAuth sign-in providers keyed by name ("google", "github"); only
+	// providers with a client ID and secret are present
+	OAuthProviders map[string]OAuthProvider
+	OAuthEnabled   bool // Feature flag for OAuth, set when any provider is configured
+}
+
+// OAuthProvider holds one identity provider's OAuth client settings
+type OAuthProvider struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // OAuth callback URL
 }
 
 // Load reads configuration from environment variables.
@@ -64,17 +186,22 @@ func Load() (*Config, error) {
 		APIBaseURL:  getEnvOrDefault("API_BASE_URL", "http://localhost:8080"),
 	}
 
+	cfg.DBStatementTimeout = getDurationEnv("DB_STATEMENT_TIMEOUT", 30*time.Second)
+
 	// These are optional in Stage 0, required later
 	cfg.JWTSigningKey = os.Getenv("JWT_SIGNING_KEY")
-	cfg.GitHubClientID = os.Getenv("GITHUB_CLIENT_ID")
-	cfg.GitHubSecret = os.Getenv("GITHUB_CLIENT_SECRET")
+	cfg.JWTIssuer = getEnvOrDefault("JWT_ISSUER", "teatime")
+	cfg.JWTAudience = os.Getenv("JWT_AUDIENCE")
 	cfg.StaticDir = os.Getenv("STATIC_DIR")
+	cfg.AdminUserIDs = splitEnv("ADMIN_USER_IDS", "")
 
 	// WebRTC / TURN configuration
 	cfg.ICESTUNURLs = splitEnv("ICE_STUN_URLS", "stun:stun.l.google.com:19302")
 	cfg.ICETURNURLs = splitEnv("ICE_TURN_URLS", "")
 	cfg.TURNUsername = os.Getenv("TURN_USERNAME")
 	cfg.TURNPassword = os.Getenv("TURN_PASSWORD")
+	cfg.TURNSecret = os.Getenv("TURN_SECRET")
+	cfg.TURNCredentialTTL = getDurationEnv("TURN_CREDENTIAL_TTL", 12*time.Hour)
 
 	// R2 / File Storage configuration
 	cfg.R2AccountID = os.Getenv("R2_ACCOUNT_ID")
@@ -82,17 +209,81 @@ func Load() (*Config, error) {
 	cfg.R2SecretAccessKey = os.Getenv("R2_SECRET_ACCESS_KEY")
 	cfg.R2Bucket = os.Getenv("R2_BUCKET")
 	cfg.R2Endpoint = getEnvOrDefault("R2_ENDPOINT", fmt.Sprintf("https://%s.r2.cloudflarestorage.com", cfg.R2AccountID))
-	cfg.MaxUploadBytes = 100 * 1024 * 1024 // 100MB default
+	cfg.R2MaxAttempts = int(getInt64Env("R2_MAX_ATTEMPTS", 3))
+	cfg.R2RetryBaseDelay = getDurationEnv("R2_RETRY_BASE_DELAY", 100*time.Millisecond)
+	cfg.MaxUploadBytes = 100 * 1024 * 1024                                     // 100MB default
+	cfg.StorageQuotaBytes = getInt64Env("STORAGE_QUOTA_BYTES", 1024*1024*1024) // 1GB default
+	cfg.ImageAutoOrient = getBoolEnv("IMAGE_AUTO_ORIENT", true)
+	cfg.UploadAllowedMimeTypes = splitEnv("UPLOAD_ALLOWED_MIME_TYPES", "")
+	cfg.AttachmentURLTTL = getDurationEnv("ATTACHMENT_URL_TTL", 15*time.Minute)
+	cfg.ThumbnailSizes = splitEnv("THUMBNAIL_SIZES", "96,400,1080")
+
+	// Flood detection
+	cfg.FloodMaxMessages = int(getInt64Env("FLOOD_MAX_MESSAGES", 30))
+	cfg.FloodWindow = getDurationEnv("FLOOD_WINDOW", 10*time.Second)
+	cfg.FloodCooldown = getDurationEnv("FLOOD_COOLDOWN", time.Minute)
+
+	// Calls
+	cfg.CallReconnectGrace = getDurationEnv("CALL_RECONNECT_GRACE", 10*time.Second)
+	cfg.CallRingTimeout = getDurationEnv("CALL_RING_TIMEOUT", 45*time.Second)
+	cfg.CallIdleTimeout = getDurationEnv("CALL_IDLE_TIMEOUT", 5*time.Minute)
+	cfg.SFUMaxParticipants = int(getInt64Env("SFU_MAX_PARTICIPANTS", 12))
+	cfg.SFUAudioLevels = getBoolEnv("SFU_AUDIO_LEVELS", true)
+	cfg.SFURecordingDir = os.Getenv("SFU_RECORDING_DIR")
+	cfg.SFUSimulcast = getBoolEnv("SFU_SIMULCAST", false)
+	cfg.SFUMaxVideo = int(getInt64Env("SFU_MAX_FORWARDED_VIDEO", 0))
+
+	// Reconnect storms
+	cfg.WSMaxPendingAuths = int(getInt64Env("WS_MAX_PENDING_AUTHS", 200))
+	cfg.WSStormRetryAfter = getDurationEnv("WS_STORM_RETRY_AFTER", 5*time.Second)
+	cfg.WSAuthTimeout = getDurationEnv("WS_AUTH_TIMEOUT", 10*time.Second)
+	cfg.LastSeenHeartbeat = getDurationEnv("LAST_SEEN_HEARTBEAT", 5*time.Minute)
+	cfg.WSPingInterval = getDurationEnv("WS_PING_INTERVAL", 30*time.Second)
+	cfg.WSPongWait = getDurationEnv("WS_PONG_WAIT", 60*time.Second)
+	cfg.WSRateMessages = int(getInt64Env("WS_RATE_MESSAGES", 20))
+	cfg.WSRateSignals = int(getInt64Env("WS_RATE_SIGNALS", 100))
+	cfg.WSRateWindow = getDurationEnv("WS_RATE_WINDOW", 10*time.Second)
+	cfg.WSCompression = getBoolEnv("WS_COMPRESSION", true)
+	cfg.WSCompressionLevel = int(getInt64Env("WS_COMPRESSION_LEVEL", 1))
+	cfg.WSCompressionMinSize = int(getInt64Env("WS_COMPRESSION_MIN_SIZE", 512))
+	cfg.WSReplayBufferSize = int(getInt64Env("WS_REPLAY_BUFFER_SIZE", 200))
+	cfg.WSReplayMaxAge = getDurationEnv("WS_REPLAY_MAX_AGE", 2*time.Minute)
+
+	// Text filtering
+	cfg.TextFilterMode = getEnvOrDefault("TEXT_FILTER_MODE", "reject")
+	cfg.TextBlocklist = splitEnv("TEXT_BLOCKLIST", "")
+	cfg.MaxTitleLength = int(getInt64Env("MAX_TITLE_LENGTH", 100))
+	cfg.MaxMessageLength = int(getInt64Env("MAX_MESSAGE_LENGTH", 10000))
+	cfg.MaxGroupMembers = int(getInt64Env("MAX_GROUP_MEMBERS", 100))
+
+	cfg.PinSweepInterval = getDurationEnv("PIN_SWEEP_INTERVAL", time.Minute)
+	cfg.MessageSweepInterval = getDurationEnv("MESSAGE_SWEEP_INTERVAL", 30*time.Second)
+	cfg.ScheduledDispatchInterval = getDurationEnv("SCHEDULED_DISPATCH_INTERVAL", 10*time.Second)
+	cfg.ObjectReapInterval = getDurationEnv("OBJECT_REAP_INTERVAL", time.Minute)
+	cfg.LinkPreviewsEnabled = getBoolEnv("LINK_PREVIEWS_ENABLED", false)
+	cfg.LinkPreviewTimeout = getDurationEnv("LINK_PREVIEW_TIMEOUT", 5*time.Second)
+	cfg.LinkPreviewHostRate = int(getInt64Env("LINK_PREVIEW_HOST_RATE", 10))
+	cfg.MaxPinnedMessages = int(getInt64Env("MAX_PINNED_MESSAGES", 50))
+	cfg.MessageEditWindow = getDurationEnv("MESSAGE_EDIT_WINDOW", 24*time.Hour)
+	cfg.AutoCreateDMs = getBoolEnv("AUTO_CREATE_DMS", true)
+	cfg.MessageEncryptionKey = os.Getenv("MESSAGE_ENCRYPTION_KEY")
+	cfg.SearchAccessRecheck = getBoolEnv("SEARCH_ACCESS_RECHECK", true)
+	cfg.SearchMaxQueryLength = int(getInt64Env("SEARCH_MAX_QUERY_LENGTH", 200))
+	cfg.SearchMaxTerms = int(getInt64Env("SEARCH_MAX_TERMS", 10))
 
 	// Redis / PubSub configuration
 	cfg.RedisURL = os.Getenv("REDIS_URL")
 	cfg.PubSubType = getEnvOrDefault("PUBSUB_TYPE", "memory") // "memory" or "redis"
 
-	// Google OAuth configuration
-	cfg.GoogleClientID = os.Getenv("GOOGLE_CLIENT_ID")
-	cfg.GoogleClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
-	cfg.GoogleRedirectURL = getEnvOrDefault("GOOGLE_REDIRECT_URL", cfg.APIBaseURL+"/auth/google/callback")
-	cfg.OAuthEnabled = cfg.GoogleClientID != "" && cfg.GoogleClientSecret != ""
+	// OAuth providers, each read from <NAME>_CLIENT_ID, <NAME>_CLIENT_SECRET
+	// and <NAME>_REDIRECT_URL
+	cfg.OAuthProviders = make(map[string]OAuthProvider)
+	for _, name := range []string{"google", "github"} {
+		if p, ok := loadOAuthProvider(name, cfg.APIBaseURL); ok {
+			cfg.OAuthProviders[name] = p
+		}
+	}
+	cfg.OAuthEnabled = len(cfg.OAuthProviders) > 0
 
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -112,6 +303,18 @@ func (c *Config) IsDevelopment() bool {
 	return c.Env == "development"
 }
 
+// loadOAuthProvider reads a provider's client settings, reporting false if
+// it isn't configured
+func loadOAuthProvider(name, apiBaseURL string) (OAuthProvider, bool) {
+	prefix := strings.ToUpper(name) + "_"
+	p := OAuthProvider{
+		ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  getEnvOrDefault(prefix+"REDIRECT_URL", apiBaseURL+"/auth/"+name+"/callback"),
+	}
+	return p, p.ClientID != "" && p.ClientSecret != ""
+}
+
 func getEnvOrDefault(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -119,6 +322,45 @@ func getEnvOrDefault(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getBoolEnv parses a boolean env var, falling back to the default
+func getBoolEnv(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return defaultVal
+	}
+	return b
+}
+
+// getInt64Env parses an integer env var, falling back to the default
+func getInt64Env(key string, defaultVal int64) int64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || n < 0 {
+		return defaultVal
+	}
+	return n
+}
+
+// getDurationEnv parses a duration env var (e.g. "30s"), falling back to the default
+func getDurationEnv(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		return defaultVal
+	}
+	return d
+}
+
 // splitEnv splits a comma-separated env var into a slice
 func splitEnv(key, defaultVal string) []string {
 	val := os.Getenv(key)
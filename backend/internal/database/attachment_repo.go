@@ -5,27 +5,39 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/encryption"
 )
 
 type AttachmentRepository struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	cipher *encryption.FieldCipher // nil stores filenames in plaintext
 }
 
 func NewAttachmentRepository(pool *pgxpool.Pool) *AttachmentRepository {
 	return &AttachmentRepository{pool: pool}
 }
 
+// SetFieldCipher enables encryption of attachment filenames at rest
+func (r *AttachmentRepository) SetFieldCipher(c *encryption.FieldCipher) {
+	r.cipher = c
+}
+
 // CreateAttachment creates a new attachment record in uploading status
 func (r *AttachmentRepository) CreateAttachment(ctx context.Context, att *domain.Attachment) error {
 	query := `
-		INSERT INTO attachments (id, uploader_id, conversation_id, bucket, object_key, filename, mime_type, size_bytes, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO attachments (id, uploader_id, conversation_id, bucket, object_key, filename, mime_type, size_bytes, status, created_at, kind, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := r.pool.Exec(ctx, query,
+	filename, err := r.cipher.Encrypt(att.Filename)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt filename: %w", err)
+	}
+	_, err = r.pool.Exec(ctx, query,
 		att.ID, att.UploaderID, att.ConversationID, att.Bucket, att.ObjectKey,
-		att.Filename, att.MimeType, att.SizeBytes, att.Status, att.CreatedAt,
+		filename, att.MimeType, att.SizeBytes, att.Status, att.CreatedAt, att.Kind, att.DurationMs,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create attachment: %w", err)
@@ -33,10 +45,49 @@ func (r *AttachmentRepository) CreateAttachment(ctx context.Context, att *domain
 	return nil
 }
 
+// CreateAttachmentWithinQuota creates an attachment record like
+// CreateAttachment, unless it would take the uploader past quota bytes
+// (domain.ErrQuotaExceeded). The uploader's row is locked for the check, so
+// concurrent uploads can't race past the quota. A quota of 0 or less is
+// unlimited.
+func (r *AttachmentRepository) CreateAttachmentWithinQuota(ctx context.Context, att *domain.Attachment, quota int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var used int64
+	err = tx.QueryRow(ctx, `SELECT storage_used_bytes FROM users WHERE id = $1 FOR UPDATE`, att.UploaderID).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to check storage used: %w", err)
+	}
+	if !domain.QuotaAllows(used, att.SizeBytes, quota) {
+		return domain.ErrQuotaExceeded
+	}
+
+	filename, err := r.cipher.Encrypt(att.Filename)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt filename: %w", err)
+	}
+	// The storage trigger charges the bytes under the lock taken above
+	_, err = tx.Exec(ctx, `
+		INSERT INTO attachments (id, uploader_id, conversation_id, bucket, object_key, filename, mime_type, size_bytes, status, created_at, kind, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		att.ID, att.UploaderID, att.ConversationID, att.Bucket, att.ObjectKey,
+		filename, att.MimeType, att.SizeBytes, att.Status, att.CreatedAt, att.Kind, att.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
 // GetAttachmentByID retrieves an attachment by ID
 func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id string) (*domain.Attachment, error) {
 	query := `
-		SELECT id::text, uploader_id::text, conversation_id::text, bucket, object_key, filename, mime_type, size_bytes, sha256, status, created_at, completed_at
+		SELECT id::text, uploader_id::text, conversation_id::text, bucket, object_key, filename, mime_type, size_bytes, sha256, status, created_at, completed_at, thumbnail_sizes, kind, duration_ms
 		FROM attachments
 		WHERE id = $1
 	`
@@ -44,16 +95,46 @@ func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id string)
 	fmt.Printf("DEBUG: Querying attachment with ID: %s\n", id)
 	err := r.pool.QueryRow(ctx, query, id).Scan(
 		&att.ID, &att.UploaderID, &att.ConversationID, &att.Bucket, &att.ObjectKey,
-		&att.Filename, &att.MimeType, &att.SizeBytes, &att.SHA256, &att.Status, &att.CreatedAt, &att.CompletedAt,
+		&att.Filename, &att.MimeType, &att.SizeBytes, &att.SHA256, &att.Status, &att.CreatedAt, &att.CompletedAt, &att.ThumbnailSizes,
+		&att.Kind, &att.DurationMs,
 	)
 	if err != nil {
 		fmt.Printf("DEBUG: Query error: %v\n", err)
 		return nil, fmt.Errorf("failed to get attachment: %w", err)
 	}
+	if att.Filename, err = r.cipher.Decrypt(att.Filename); err != nil {
+		return nil, fmt.Errorf("failed to decrypt filename: %w", err)
+	}
 	fmt.Printf("DEBUG: Found attachment: %s\n", att.ID)
 	return &att, nil
 }
 
+// CanDownloadAttachment reports whether a user may download an attachment.
+// They must be a member of its conversation and, unless they uploaded it,
+// it must be attached to a message they can see (whispers only reach their
+// audience).
+func (r *AttachmentRepository) CanDownloadAttachment(ctx context.Context, id string, userID uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM attachments a
+			JOIN conversation_members cm ON cm.conversation_id = a.conversation_id AND cm.user_id = $2
+			WHERE a.id = $1 AND (
+				a.uploader_id = $2 OR EXISTS (
+					SELECT 1 FROM message_attachments ma
+					JOIN messages m ON m.id = ma.message_id
+					WHERE ma.attachment_id = a.id AND m.conversation_id = a.conversation_id
+					  AND (m.visible_to IS NULL OR $2 = ANY(m.visible_to))
+				)
+			)
+		)
+	`, id, userID).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("failed to check attachment access: %w", err)
+	}
+	return ok, nil
+}
+
 // MarkAttachmentReady marks an attachment as ready after successful upload
 func (r *AttachmentRepository) MarkAttachmentReady(ctx context.Context, id string, sha256 string) error {
 	now := time.Now()
@@ -69,6 +150,68 @@ func (r *AttachmentRepository) MarkAttachmentReady(ctx context.Context, id strin
 	return nil
 }
 
+// SetAttachmentContent records an attachment's size and checksum after its
+// stored object was rewritten, moving the uploader's quota by the difference
+func (r *AttachmentRepository) SetAttachmentContent(ctx context.Context, id string, sizeBytes int64, sha256 string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var uploaderID uuid.UUID
+	var oldSize int64
+	var status domain.AttachmentStatus
+	err = tx.QueryRow(ctx, `SELECT uploader_id, size_bytes, status FROM attachments WHERE id = $1 FOR UPDATE`, id).Scan(&uploaderID, &oldSize, &status)
+	if err != nil {
+		return fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE attachments SET size_bytes = $1, sha256 = $2 WHERE id = $3`, sizeBytes, sha256, id)
+	if err != nil {
+		return fmt.Errorf("failed to set attachment content: %w", err)
+	}
+	// The storage trigger only charges inserts, so charge the change here
+	if status != domain.AttachmentStatusError && sizeBytes != oldSize {
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET storage_used_bytes = GREATEST(storage_used_bytes + $1, 0)
+			WHERE id = $2
+		`, sizeBytes-oldSize, uploaderID)
+		if err != nil {
+			return fmt.Errorf("failed to update storage used: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// SetThumbnailSizes records which thumbnail variants exist for an attachment
+func (r *AttachmentRepository) SetThumbnailSizes(ctx context.Context, id string, sizes []int) error {
+	query := `
+		UPDATE attachments
+		SET thumbnail_sizes = $1
+		WHERE id = $2
+	`
+	_, err := r.pool.Exec(ctx, query, sizes, id)
+	if err != nil {
+		return fmt.Errorf("failed to set thumbnail sizes: %w", err)
+	}
+	return nil
+}
+
+// SetAttachmentDuration records a voice clip's length, in milliseconds
+func (r *AttachmentRepository) SetAttachmentDuration(ctx context.Context, id string, durationMs int64) error {
+	query := `
+		UPDATE attachments
+		SET duration_ms = $1
+		WHERE id = $2
+	`
+	_, err := r.pool.Exec(ctx, query, durationMs, id)
+	if err != nil {
+		return fmt.Errorf("failed to set attachment duration: %w", err)
+	}
+	return nil
+}
+
 // MarkAttachmentError marks an attachment as error
 func (r *AttachmentRepository) MarkAttachmentError(ctx context.Context, id string) error {
 	query := `
@@ -86,7 +229,7 @@ func (r *AttachmentRepository) MarkAttachmentError(ctx context.Context, id strin
 // GetAttachmentsByConversation retrieves all attachments for a conversation
 func (r *AttachmentRepository) GetAttachmentsByConversation(ctx context.Context, conversationID string) ([]*domain.Attachment, error) {
 	query := `
-		SELECT id, uploader_id, conversation_id, bucket, object_key, filename, mime_type, size_bytes, sha256, status, created_at, completed_at
+		SELECT id, uploader_id, conversation_id, bucket, object_key, filename, mime_type, size_bytes, sha256, status, created_at, completed_at, thumbnail_sizes, kind, duration_ms
 		FROM attachments
 		WHERE conversation_id = $1 AND status = $2
 		ORDER BY created_at DESC
@@ -102,23 +245,85 @@ func (r *AttachmentRepository) GetAttachmentsByConversation(ctx context.Context,
 		var att domain.Attachment
 		err := rows.Scan(
 			&att.ID, &att.UploaderID, &att.ConversationID, &att.Bucket, &att.ObjectKey,
-			&att.Filename, &att.MimeType, &att.SizeBytes, &att.SHA256, &att.Status, &att.CreatedAt, &att.CompletedAt,
+			&att.Filename, &att.MimeType, &att.SizeBytes, &att.SHA256, &att.Status, &att.CreatedAt, &att.CompletedAt, &att.ThumbnailSizes,
+			&att.Kind, &att.DurationMs,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan attachment: %w", err)
 		}
+		if att.Filename, err = r.cipher.Decrypt(att.Filename); err != nil {
+			return nil, fmt.Errorf("failed to decrypt filename: %w", err)
+		}
 		attachments = append(attachments, &att)
 	}
 
 	return attachments, nil
 }
 
-// DeleteAttachment deletes an attachment record
+// DeleteAttachment deletes an attachment record, queueing its stored objects
+// for the object reaper. Attachments a message still uses are kept and
+// domain.ErrAttachmentInUse returned; deleting the message frees them.
 func (r *AttachmentRepository) DeleteAttachment(ctx context.Context, id string) error {
-	query := `DELETE FROM attachments WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
+	var inUse bool
+	err := r.pool.QueryRow(ctx, `
+		WITH deleted AS (
+			DELETE FROM attachments a
+			WHERE a.id = $1
+			AND NOT EXISTS (SELECT 1 FROM messages WHERE attachment_id = a.id)
+			AND NOT EXISTS (SELECT 1 FROM message_attachments WHERE attachment_id = a.id)
+			RETURNING a.id
+		)
+		SELECT NOT EXISTS (SELECT 1 FROM deleted) AND EXISTS (SELECT 1 FROM attachments WHERE id = $1)
+	`, id).Scan(&inUse)
 	if err != nil {
 		return fmt.Errorf("failed to delete attachment: %w", err)
 	}
+	if inUse {
+		return domain.ErrAttachmentInUse
+	}
 	return nil
 }
+
+// PendingObjectDeletions returns up to limit stored objects of deleted
+// attachments, oldest first
+func (r *AttachmentRepository) PendingObjectDeletions(ctx context.Context, limit int) ([]domain.DeletedObject, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT object_key, thumbnail_sizes FROM storage_deletions
+		ORDER BY created_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list object deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var objects []domain.DeletedObject
+	for rows.Next() {
+		var obj domain.DeletedObject
+		if err := rows.Scan(&obj.ObjectKey, &obj.ThumbnailSizes); err != nil {
+			return nil, fmt.Errorf("failed to scan object deletion: %w", err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, rows.Err()
+}
+
+// ForgetObjectDeletions drops objects removed from storage from the queue
+func (r *AttachmentRepository) ForgetObjectDeletions(ctx context.Context, objectKeys []string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM storage_deletions WHERE object_key = ANY($1)`, objectKeys)
+	if err != nil {
+		return fmt.Errorf("failed to forget object deletions: %w", err)
+	}
+	return nil
+}
+
+// GetStorageUsed returns the total attachment bytes charged to a user.
+// The counter is maintained by a trigger on the attachments table.
+func (r *AttachmentRepository) GetStorageUsed(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var used int64
+	err := r.pool.QueryRow(ctx, `SELECT storage_used_bytes FROM users WHERE id = $1`, userID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get storage used: %w", err)
+	}
+	return used, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAttachment describes an upload of size bytes by uploader
+func newTestAttachment(uploader, convID uuid.UUID, size int64) *domain.Attachment {
+	id := uuid.New().String()
+	return &domain.Attachment{
+		ID:             id,
+		UploaderID:     uploader.String(),
+		ConversationID: convID.String(),
+		Bucket:         "test",
+		ObjectKey:      "test/" + id,
+		Filename:       "file.bin",
+		MimeType:       "application/octet-stream",
+		SizeBytes:      size,
+		Status:         domain.AttachmentStatusUploading,
+		CreatedAt:      time.Now(),
+		Kind:           domain.ClassifyAttachment("application/octet-stream"),
+	}
+}
+
+func TestCreateAttachmentWithinQuota_StopsAtQuota(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAttachmentRepository(db.Pool)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, userID)
+
+	require.NoError(t, repo.CreateAttachmentWithinQuota(ctx, newTestAttachment(userID, convID, 60), 100))
+	require.NoError(t, repo.CreateAttachmentWithinQuota(ctx, newTestAttachment(userID, convID, 40), 100))
+	err := repo.CreateAttachmentWithinQuota(ctx, newTestAttachment(userID, convID, 1), 100)
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	used, err := repo.GetStorageUsed(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), used)
+}
+
+func TestDeleteAttachment_RefusesWhileAMessageUsesIt(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAttachmentRepository(db.Pool)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, userID)
+
+	att := newTestAttachment(userID, convID, 10)
+	require.NoError(t, repo.CreateAttachment(ctx, att))
+	attID := uuid.MustParse(att.ID)
+	msg := &domain.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       &userID,
+		AttachmentID:   &attID,
+		AttachmentIDs:  []uuid.UUID{attID},
+		CreatedAt:      time.Now(),
+	}
+	require.NoError(t, convs.CreateMessage(ctx, msg))
+
+	assert.ErrorIs(t, repo.DeleteAttachment(ctx, att.ID), domain.ErrAttachmentInUse)
+	_, err := repo.GetAttachmentByID(ctx, att.ID)
+	require.NoError(t, err)
+
+	// Deleting the message frees the attachment and queues its object
+	require.NoError(t, convs.DeleteMessage(ctx, msg.ID))
+	pending, err := repo.PendingObjectDeletions(ctx, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, pending, domain.DeletedObject{ObjectKey: att.ObjectKey, ThumbnailSizes: []int{}})
+	require.NoError(t, repo.ForgetObjectDeletions(ctx, []string{att.ObjectKey}))
+}
+
+func TestDeleteAttachment_QueuesObjectForReaper(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAttachmentRepository(db.Pool)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, userID)
+
+	att := newTestAttachment(userID, convID, 10)
+	require.NoError(t, repo.CreateAttachment(ctx, att))
+	require.NoError(t, repo.DeleteAttachment(ctx, att.ID))
+
+	pending, err := repo.PendingObjectDeletions(ctx, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, pending, domain.DeletedObject{ObjectKey: att.ObjectKey, ThumbnailSizes: []int{}})
+	require.NoError(t, repo.ForgetObjectDeletions(ctx, []string{att.ObjectKey}))
+
+	used, err := repo.GetStorageUsed(ctx, userID)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
+
+func TestSetAttachmentContent_MovesQuotaBySizeChange(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAttachmentRepository(db.Pool)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, userID)
+
+	att := newTestAttachment(userID, convID, 1000)
+	require.NoError(t, repo.CreateAttachment(ctx, att))
+	require.NoError(t, repo.SetAttachmentContent(ctx, att.ID, 800, "abc123"))
+
+	used, err := repo.GetStorageUsed(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), used)
+
+	stored, err := repo.GetAttachmentByID(ctx, att.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), stored.SizeBytes)
+	require.NotNil(t, stored.SHA256)
+	assert.Equal(t, "abc123", *stored.SHA256)
+
+	// Deleting releases exactly what's charged now
+	require.NoError(t, repo.DeleteAttachment(ctx, att.ID))
+	_, _ = db.Pool.Exec(ctx, `DELETE FROM storage_deletions WHERE object_key = $1`, att.ObjectKey)
+	used, err = repo.GetStorageUsed(ctx, userID)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
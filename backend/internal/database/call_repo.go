@@ -40,6 +40,8 @@ type CallLog struct {
 	EndedAt         *time.Time `json:"ended_at,omitempty"`
 	DurationSeconds int        `json:"duration_seconds"`
 	CreatedAt       time.Time  `json:"created_at"`
+	RecordingPath   *string    `json:"-"` // server-side directory, only for operators
+	Recorded        bool       `json:"recorded,omitempty"`
 
 	// Populated from joins
 	InitiatorUsername string            `json:"initiator_username,omitempty"`
@@ -47,6 +49,9 @@ type CallLog struct {
 	ConversationType  string            `json:"conversation_type,omitempty"`
 	OtherUser         *UserSummary      `json:"other_user,omitempty"` // For DMs
 	Participants      []CallParticipant `json:"participants,omitempty"`
+
+	// Populated from live call state
+	LiveParticipants int `json:"live_participants,omitempty"`
 }
 
 // CallParticipant represents a user who joined a call
@@ -130,6 +135,13 @@ func (r *CallRepository) EndCall(ctx context.Context, callID uuid.UUID) error {
 	return err
 }
 
+// SetRecordingPath records where the call's recording is being written
+func (r *CallRepository) SetRecordingPath(ctx context.Context, callID uuid.UUID, path string) error {
+	query := `UPDATE call_logs SET recording_path = $2 WHERE id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, callID, path)
+	return err
+}
+
 // AddParticipant adds a participant to a call
 func (r *CallRepository) AddParticipant(ctx context.Context, callID, userID uuid.UUID) error {
 	query := `
@@ -153,7 +165,7 @@ func (r *CallRepository) GetCallLog(ctx context.Context, callID uuid.UUID) (*Cal
 	query := `
 		SELECT 
 			cl.id, cl.conversation_id, cl.initiator_id, cl.call_type, cl.status,
-			cl.started_at, cl.ended_at, cl.duration_seconds, cl.created_at,
+			cl.started_at, cl.ended_at, cl.duration_seconds, cl.created_at, cl.recording_path,
 			u.username as initiator_username,
 			c.title as conversation_title, c.type as conversation_type
 		FROM call_logs cl
@@ -168,7 +180,7 @@ func (r *CallRepository) GetCallLog(ctx context.Context, callID uuid.UUID) (*Cal
 
 	err := r.db.Pool.QueryRow(ctx, query, callID).Scan(
 		&call.ID, &call.ConversationID, &call.InitiatorID, &call.CallType, &call.Status,
-		&startedAt, &endedAt, &call.DurationSeconds, &call.CreatedAt,
+		&startedAt, &endedAt, &call.DurationSeconds, &call.CreatedAt, &call.RecordingPath,
 		&call.InitiatorUsername, &convTitle, &call.ConversationType,
 	)
 	if err != nil {
@@ -187,6 +199,7 @@ func (r *CallRepository) GetCallLog(ctx context.Context, callID uuid.UUID) (*Cal
 	if convTitle.Valid {
 		call.ConversationTitle = convTitle.String
 	}
+	call.Recorded = call.RecordingPath != nil
 
 	return &call, nil
 }
@@ -226,6 +239,63 @@ func (r *CallRepository) GetActiveCallForConversation(ctx context.Context, conve
 	return &call, nil
 }
 
+// GetActiveCallsForUser returns the most recent active/ringing call in each
+// conversation the user is a member of, newest first
+func (r *CallRepository) GetActiveCallsForUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]CallLog, error) {
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (cl.conversation_id)
+				cl.id, cl.conversation_id, cl.initiator_id, cl.call_type, cl.status,
+				cl.started_at, cl.ended_at, cl.duration_seconds, cl.created_at,
+				u.username as initiator_username,
+				c.title as conversation_title, c.type as conversation_type
+			FROM call_logs cl
+			JOIN conversation_members cm ON cm.conversation_id = cl.conversation_id AND cm.user_id = $1
+			JOIN users u ON u.id = cl.initiator_id
+			JOIN conversations c ON c.id = cl.conversation_id
+			WHERE cl.status IN ('ringing', 'active')
+			ORDER BY cl.conversation_id, cl.created_at DESC
+		) sub
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []CallLog
+	for rows.Next() {
+		var call CallLog
+		var startedAt, endedAt sql.NullTime
+		var convTitle sql.NullString
+
+		if err := rows.Scan(
+			&call.ID, &call.ConversationID, &call.InitiatorID, &call.CallType, &call.Status,
+			&startedAt, &endedAt, &call.DurationSeconds, &call.CreatedAt,
+			&call.InitiatorUsername, &convTitle, &call.ConversationType,
+		); err != nil {
+			return nil, err
+		}
+
+		if startedAt.Valid {
+			call.StartedAt = &startedAt.Time
+		}
+		if endedAt.Valid {
+			call.EndedAt = &endedAt.Time
+		}
+		if convTitle.Valid {
+			call.ConversationTitle = convTitle.String
+		}
+
+		calls = append(calls, call)
+	}
+
+	return calls, rows.Err()
+}
+
 // GetUserCallHistory retrieves call history for a user
 func (r *CallRepository) GetUserCallHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]CallLog, error) {
 	query := `
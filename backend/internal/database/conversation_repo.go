@@ -8,15 +8,40 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/encryption"
 )
 
 // ConversationRepository handles conversation and message data access
 type ConversationRepository struct {
-	db *DB
+	db            *DB
+	cipher        *encryption.FieldCipher // nil stores message bodies in plaintext
+	searchRecheck bool                    // re-verify access on cross-conversation search hits
 }
 
 func NewConversationRepository(db *DB) *ConversationRepository {
-	return &ConversationRepository{db: db}
+	return &ConversationRepository{db: db, searchRecheck: true}
+}
+
+// SetSearchAccessRecheck controls whether SearchAllMessages re-verifies
+// membership after the query. It is on by default.
+func (r *ConversationRepository) SetSearchAccessRecheck(enabled bool) {
+	r.searchRecheck = enabled
+}
+
+// SetFieldCipher enables encryption of message bodies at rest. Rows written
+// before it was enabled are still read back as plaintext.
+func (r *ConversationRepository) SetFieldCipher(c *encryption.FieldCipher) {
+	r.cipher = c
+}
+
+// openBody decrypts a message body read from the database
+func (r *ConversationRepository) openBody(m *domain.Message) error {
+	body, err := r.cipher.Decrypt(m.BodyText)
+	if err != nil {
+		return err
+	}
+	m.BodyText = body
+	return nil
 }
 
 // Create creates a new conversation with initial members
@@ -27,6 +52,12 @@ func (r *ConversationRepository) Create(ctx context.Context, conv *domain.Conver
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	if conv.Type == domain.ConversationTypeDM {
+		if err := claimDMPair(ctx, tx, memberIDs); err != nil {
+			return err
+		}
+	}
+
 	// Insert conversation
 	_, err = tx.Exec(ctx, `
 		INSERT INTO conversations (id, type, title, created_by)
@@ -54,19 +85,92 @@ func (r *ConversationRepository) Create(ctx context.Context, conv *domain.Conver
 	return tx.Commit(ctx)
 }
 
+// CreateGroup creates a group with its settings and members in one
+// transaction, so a group is never seen half set up
+func (r *ConversationRepository) CreateGroup(ctx context.Context, conv *domain.Conversation, members []domain.ConversationMember) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO conversations (id, type, title, created_by, default_member_role, allow_member_adds, read_only, message_ttl_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0))
+	`, conv.ID, conv.Type, conv.Title, conv.CreatedBy,
+		conv.DefaultMemberRole, conv.AllowMemberAdds, conv.ReadOnly, conv.MessageTTLSeconds)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO conversation_members (conversation_id, user_id, role)
+			VALUES ($1, $2, $3)
+		`, conv.ID, m.UserID, m.Role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CreateDMWithMessage creates a DM between memberIDs and posts its first
+// message in one transaction, so a DM never exists without the message that
+// started it. Returns domain.ErrDMExists if the two already share a DM.
+func (r *ConversationRepository) CreateDMWithMessage(ctx context.Context, conv *domain.Conversation, memberIDs []uuid.UUID, msg *domain.Message) error {
+	body, err := r.cipher.Encrypt(msg.BodyText)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := claimDMPair(ctx, tx, memberIDs); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO conversations (id, type, title, created_by)
+		VALUES ($1, $2, $3, $4)
+	`, conv.ID, conv.Type, conv.Title, conv.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range memberIDs {
+		role := domain.MemberRoleMember
+		if conv.CreatedBy != nil && *conv.CreatedBy == userID {
+			role = domain.MemberRoleAdmin
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO conversation_members (conversation_id, user_id, role)
+			VALUES ($1, $2, $3)
+		`, conv.ID, userID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, body_text, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, msg.ID, conv.ID, msg.SenderID, body, msg.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 // GetByID retrieves a conversation with its members
 func (r *ConversationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Conversation, error) {
-	conv := &domain.Conversation{}
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT id, type, title, created_by, created_at, updated_at
-		FROM conversations WHERE id = $1
-	`, id).Scan(
-		&conv.ID, &conv.Type, &conv.Title,
-		&conv.CreatedBy, &conv.CreatedAt, &conv.UpdatedAt,
-	)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, domain.ErrConversationNotFound
-	}
+	conv, err := r.getConversation(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -74,31 +178,115 @@ func (r *ConversationRepository) GetByID(ctx context.Context, id uuid.UUID) (*do
 	// Fetch members with user info
 	rows, err := r.db.Pool.Query(ctx, `
 		SELECT cm.conversation_id, cm.user_id, cm.role, cm.joined_at,
-		       u.id, u.username, u.display_name, u.avatar_url
+		       u.id, u.username, u.display_name, u.avatar_url, COALESCE(n.nickname, '')
 		FROM conversation_members cm
 		JOIN users u ON u.id = cm.user_id
+		LEFT JOIN conversation_nicknames n ON n.conversation_id = cm.conversation_id AND n.target_user_id = cm.user_id
 		WHERE cm.conversation_id = $1
 	`, id)
 	if err != nil {
 		return nil, err
 	}
+	conv.Members, err = scanMembers(rows)
+	if err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// GetByIDPreview is GetByID for display: it loads only the first
+// domain.MemberPreviewSize members to join, with MemberCount giving the total. The
+// rest are paged through with GetMembers.
+func (r *ConversationRepository) GetByIDPreview(ctx context.Context, id uuid.UUID) (*domain.Conversation, error) {
+	conv, err := r.getConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM conversation_members WHERE conversation_id = $1
+	`, id).Scan(&conv.MemberCount); err != nil {
+		return nil, err
+	}
+	conv.Members, _, err = r.GetMembers(ctx, id, domain.MemberPreviewSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// GetMembers returns up to limit members in join order, starting after
+// cursor (nil for the first page), and whether there are more
+func (r *ConversationRepository) GetMembers(ctx context.Context, convID uuid.UUID, limit int, cursor *domain.MemberCursor) ([]domain.ConversationMember, bool, error) {
+	var afterJoinedAt *time.Time
+	var afterUserID *uuid.UUID
+	if cursor != nil {
+		afterJoinedAt, afterUserID = &cursor.JoinedAt, &cursor.UserID
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT cm.conversation_id, cm.user_id, cm.role, cm.joined_at,
+		       u.id, u.username, u.display_name, u.avatar_url, COALESCE(n.nickname, '')
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		LEFT JOIN conversation_nicknames n ON n.conversation_id = cm.conversation_id AND n.target_user_id = cm.user_id
+		WHERE cm.conversation_id = $1
+		  AND ($3::timestamptz IS NULL OR (cm.joined_at, cm.user_id) > ($3::timestamptz, $4::uuid))
+		ORDER BY cm.joined_at, cm.user_id
+		LIMIT $2
+	`, convID, limit+1, afterJoinedAt, afterUserID)
+	if err != nil {
+		return nil, false, err
+	}
+	members, err := scanMembers(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	members, hasMore := domain.PageMembers(members, limit)
+	return members, hasMore, nil
+}
+
+// scanMembers reads conversation members with their user info and nickname,
+// closing rows
+func scanMembers(rows pgx.Rows) ([]domain.ConversationMember, error) {
 	defer rows.Close()
 
+	var members []domain.ConversationMember
 	for rows.Next() {
 		var m domain.ConversationMember
 		var user domain.PublicUser
+		var nickname string
 		err := rows.Scan(
 			&m.ConversationID, &m.UserID, &m.Role, &m.JoinedAt,
-			&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL,
+			&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &nickname,
 		)
 		if err != nil {
 			return nil, err
 		}
 		m.User = &user
-		conv.Members = append(conv.Members, m)
+		m.SetNickname(nickname)
+		members = append(members, m)
 	}
+	return members, rows.Err()
+}
 
-	return conv, rows.Err()
+// getConversation loads a conversation's own row, without members
+func (r *ConversationRepository) getConversation(ctx context.Context, id uuid.UUID) (*domain.Conversation, error) {
+	conv := &domain.Conversation{}
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT id, type, title, created_by, created_at, updated_at, saved_for IS NOT NULL,
+		       default_member_role, allow_member_adds, read_only, COALESCE(message_ttl_seconds, 0), search_language::text
+		FROM conversations WHERE id = $1
+	`, id).Scan(
+		&conv.ID, &conv.Type, &conv.Title,
+		&conv.CreatedBy, &conv.CreatedAt, &conv.UpdatedAt, &conv.IsSaved,
+		&conv.DefaultMemberRole, &conv.AllowMemberAdds, &conv.ReadOnly, &conv.MessageTTLSeconds, &conv.SearchLanguage,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conv, nil
 }
 
 // GetUserConversations returns all conversations for a user
@@ -130,6 +318,45 @@ func (r *ConversationRepository) GetUserConversations(ctx context.Context, userI
 	return conversations, rows.Err()
 }
 
+// GetSharedConversations returns the unarchived groups both users belong to,
+// most recently active first
+func (r *ConversationRepository) GetSharedConversations(ctx context.Context, userA, userB uuid.UUID) ([]domain.Conversation, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT c.id, c.type, c.title, c.created_by, c.created_at, c.updated_at
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id
+		WHERE cm.user_id = $1 AND c.type = 'group' AND c.archived_at IS NULL
+		ORDER BY c.updated_at DESC
+	`, userA)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mine []domain.Conversation
+	var ids []uuid.UUID
+	for rows.Next() {
+		var c domain.Conversation
+		if err := rows.Scan(&c.ID, &c.Type, &c.Title, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		mine = append(mine, c)
+		ids = append(ids, c.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(mine) == 0 {
+		return []domain.Conversation{}, nil
+	}
+
+	theirs, err := r.GetMemberConversations(ctx, userB, ids)
+	if err != nil {
+		return nil, err
+	}
+	return domain.SharedGroups(mine, theirs), nil
+}
+
 // IsMember checks if a user is a member of a conversation
 func (r *ConversationRepository) IsMember(ctx context.Context, convID, userID uuid.UUID) (bool, error) {
 	var exists bool
@@ -142,16 +369,84 @@ func (r *ConversationRepository) IsMember(ctx context.Context, convID, userID uu
 	return exists, err
 }
 
-// AddMember adds a user to a conversation
+// AddMember adds a user to a conversation. Returns domain.ErrBanned if the
+// user is banned from it.
 func (r *ConversationRepository) AddMember(ctx context.Context, convID, userID uuid.UUID, role domain.MemberRole) error {
-	_, err := r.db.Pool.Exec(ctx, `
+	result, err := r.db.Pool.Exec(ctx, `
 		INSERT INTO conversation_members (conversation_id, user_id, role)
-		VALUES ($1, $2, $3)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (
+			SELECT 1 FROM conversation_bans
+			WHERE conversation_id = $1 AND user_id = $2
+		)
 		ON CONFLICT DO NOTHING
 	`, convID, userID, role)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		// Either already a member or banned; only the latter is an error
+		banned, err := r.IsBanned(ctx, convID, userID)
+		if err != nil {
+			return err
+		}
+		if banned {
+			return domain.ErrBanned
+		}
+	}
+	return nil
+}
+
+// BanFromConversation bans a user from a conversation and removes their
+// membership. Returns whether the user was a member.
+func (r *ConversationRepository) BanFromConversation(ctx context.Context, convID, userID, bannedBy uuid.UUID) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO conversation_bans (conversation_id, user_id, banned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, convID, userID, bannedBy)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM conversation_members
+		WHERE conversation_id = $1 AND user_id = $2
+	`, convID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, tx.Commit(ctx)
+}
+
+// UnbanFromConversation lifts a ban. It does not re-add the user.
+func (r *ConversationRepository) UnbanFromConversation(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM conversation_bans
+		WHERE conversation_id = $1 AND user_id = $2
+	`, convID, userID)
 	return err
 }
 
+// IsBanned checks if a user is banned from a conversation
+func (r *ConversationRepository) IsBanned(ctx context.Context, convID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM conversation_bans
+			WHERE conversation_id = $1 AND user_id = $2
+		)
+	`, convID, userID).Scan(&exists)
+	return exists, err
+}
+
 // RemoveMember removes a user from a conversation
 func (r *ConversationRepository) RemoveMember(ctx context.Context, convID, userID uuid.UUID) error {
 	_, err := r.db.Pool.Exec(ctx, `
@@ -161,6 +456,73 @@ func (r *ConversationRepository) RemoveMember(ctx context.Context, convID, userI
 	return err
 }
 
+// LeaveGroup removes userID from a conversation and, if they were an admin
+// and none is left, promotes the longest-standing member in the same
+// transaction. Returns the promoted member's ID (uuid.Nil if nobody was
+// promoted), or domain.ErrNotMember if userID wasn't a member.
+func (r *ConversationRepository) LeaveGroup(ctx context.Context, convID, userID uuid.UUID) (uuid.UUID, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Serialise leaves so two admins leaving at once both see the other go
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM conversations WHERE id = $1 FOR UPDATE`, convID); err != nil {
+		return uuid.Nil, err
+	}
+
+	var role domain.MemberRole
+	err = tx.QueryRow(ctx, `
+		DELETE FROM conversation_members
+		WHERE conversation_id = $1 AND user_id = $2
+		RETURNING role
+	`, convID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, domain.ErrNotMember
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	promoted := uuid.Nil
+	if role == domain.MemberRoleAdmin {
+		if promoted, err = promoteOldestMember(ctx, tx, convID); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	return promoted, nil
+}
+
+// promoteOldestMember makes the longest-standing member an admin if the
+// conversation has none left, returning their ID (uuid.Nil if nobody was
+// promoted)
+func promoteOldestMember(ctx context.Context, tx pgx.Tx, convID uuid.UUID) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		UPDATE conversation_members SET role = 'admin'
+		WHERE conversation_id = $1
+		AND user_id = (
+			SELECT user_id FROM conversation_members
+			WHERE conversation_id = $1
+			ORDER BY joined_at, user_id
+			LIMIT 1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM conversation_members
+			WHERE conversation_id = $1 AND role = 'admin'
+		)
+		RETURNING user_id
+	`, convID).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return userID, err
+}
+
 // GetMemberRole returns a user's role in a conversation (returns error if not a member)
 func (r *ConversationRepository) GetMemberRole(ctx context.Context, convID, userID uuid.UUID) (domain.MemberRole, error) {
 	var role domain.MemberRole
@@ -174,41 +536,279 @@ func (r *ConversationRepository) GetMemberRole(ctx context.Context, convID, user
 	return role, err
 }
 
-// UpdateTitle updates a group conversation's title
-func (r *ConversationRepository) UpdateTitle(ctx context.Context, convID uuid.UUID, title string) error {
-	result, err := r.db.Pool.Exec(ctx, `
-		UPDATE conversations 
-		SET title = $2, updated_at = NOW()
-		WHERE id = $1 AND type = 'group'
-	`, convID, title)
+// GetModerationContext returns a user's role in a conversation and the
+// conversation's type, which together decide whether they may moderate it.
+// Returns domain.ErrNotMember if they aren't a member.
+func (r *ConversationRepository) GetModerationContext(ctx context.Context, convID, userID uuid.UUID) (domain.MemberRole, domain.ConversationType, error) {
+	var role domain.MemberRole
+	var convType domain.ConversationType
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT cm.role, c.type
+		FROM conversation_members cm
+		JOIN conversations c ON c.id = cm.conversation_id
+		WHERE cm.conversation_id = $1 AND cm.user_id = $2
+	`, convID, userID).Scan(&role, &convType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", domain.ErrNotMember
+	}
+	return role, convType, err
+}
+
+// UpdateMemberRole sets a member's role. Demoting an admin is conditional on
+// another admin remaining; it locks the conversation like LeaveGroup, so
+// concurrent demotions and leaves can't leave the group without one.
+func (r *ConversationRepository) UpdateMemberRole(ctx context.Context, convID, userID uuid.UUID, role domain.MemberRole) error {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return domain.ErrConversationNotFound
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM conversations WHERE id = $1 FOR UPDATE`, convID); err != nil {
+		return err
 	}
-	return nil
-}
 
-// GetMemberCount returns the number of members in a conversation
-func (r *ConversationRepository) GetMemberCount(ctx context.Context, convID uuid.UUID) (int, error) {
-	var count int
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT COUNT(*) FROM conversation_members WHERE conversation_id = $1
-	`, convID).Scan(&count)
+	result, err := tx.Exec(ctx, `
+		UPDATE conversation_members cm SET role = $3
+		WHERE cm.conversation_id = $1 AND cm.user_id = $2
+		AND (
+			$3 = 'admin'
+			OR cm.role <> 'admin'
+			OR EXISTS (
+				SELECT 1 FROM conversation_members
+				WHERE conversation_id = $1 AND user_id <> $2 AND role = 'admin'
+			)
+		)
+	`, convID, userID, role)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		var isMember bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id = $1 AND user_id = $2)
+		`, convID, userID).Scan(&isMember); err != nil {
+			return err
+		}
+		if !isMember {
+			return domain.ErrNotMember
+		}
+		return domain.ErrCannotRemoveAdmin
+	}
+	return tx.Commit(ctx)
+}
+
+// SetNickname shows nickname in place of the target's display name in a
+// conversation, replacing any earlier nickname
+func (r *ConversationRepository) SetNickname(ctx context.Context, convID, targetID, setBy uuid.UUID, nickname string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO conversation_nicknames (conversation_id, target_user_id, nickname, set_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (conversation_id, target_user_id)
+		DO UPDATE SET nickname = EXCLUDED.nickname, set_by = EXCLUDED.set_by, updated_at = EXCLUDED.updated_at
+	`, convID, targetID, nickname, setBy)
+	return err
+}
+
+// ClearNickname removes the target's nickname in a conversation, if any
+func (r *ConversationRepository) ClearNickname(ctx context.Context, convID, targetID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM conversation_nicknames
+		WHERE conversation_id = $1 AND target_user_id = $2
+	`, convID, targetID)
+	return err
+}
+
+// UpdateTitle updates a group conversation's title
+func (r *ConversationRepository) UpdateTitle(ctx context.Context, convID uuid.UUID, title string) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE conversations 
+		SET title = $2, updated_at = NOW()
+		WHERE id = $1 AND type = 'group'
+	`, convID, title)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrConversationNotFound
+	}
+	return nil
+}
+
+// UpdateMemberSettings updates a group's default invite role and whether non-admins may add members
+func (r *ConversationRepository) UpdateMemberSettings(ctx context.Context, convID uuid.UUID, defaultRole domain.MemberRole, allowMemberAdds bool) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE conversations 
+		SET default_member_role = $2, allow_member_adds = $3, updated_at = NOW()
+		WHERE id = $1 AND type = 'group'
+	`, convID, defaultRole, allowMemberAdds)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrConversationNotFound
+	}
+	return nil
+}
+
+// SetReadOnly turns a group's announcement mode on or off
+func (r *ConversationRepository) SetReadOnly(ctx context.Context, convID uuid.UUID, readOnly bool) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE conversations
+		SET read_only = $2, updated_at = NOW()
+		WHERE id = $1 AND type = 'group'
+	`, convID, readOnly)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrConversationNotFound
+	}
+	return nil
+}
+
+// GetPostingRole returns a member's role along with whether the conversation
+// is read-only, for deciding if they may send (returns error if not a member)
+func (r *ConversationRepository) GetPostingRole(ctx context.Context, convID, userID uuid.UUID) (domain.MemberRole, bool, error) {
+	var role domain.MemberRole
+	var readOnly bool
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT cm.role, c.read_only
+		FROM conversation_members cm
+		JOIN conversations c ON c.id = cm.conversation_id
+		WHERE cm.conversation_id = $1 AND cm.user_id = $2
+	`, convID, userID).Scan(&role, &readOnly)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, domain.ErrNotMember
+	}
+	return role, readOnly, err
+}
+
+// UpdateSearchLanguage sets a conversation's text-search config and re-indexes
+// its messages with it
+func (r *ConversationRepository) UpdateSearchLanguage(ctx context.Context, convID uuid.UUID, lang string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE conversations SET search_language = $2::regconfig, updated_at = NOW()
+		WHERE id = $1
+	`, convID, lang)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrConversationNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE messages SET search_vector = to_tsvector($2::regconfig, COALESCE(body_text, ''))
+		WHERE conversation_id = $1
+	`, convID, lang)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// TransferOwnership makes toUserID the group's owner and promotes them to admin.
+// The update is conditional on fromUserID still being the owner (or the owner
+// having left), so concurrent transfers can't both succeed.
+func (r *ConversationRepository) TransferOwnership(ctx context.Context, convID, fromUserID, toUserID uuid.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE conversations c
+		SET created_by = $3, updated_at = NOW()
+		WHERE c.id = $1 AND c.type = 'group'
+		AND (
+			c.created_by = $2
+			OR c.created_by IS NULL
+			OR NOT EXISTS (
+				SELECT 1 FROM conversation_members
+				WHERE conversation_id = c.id AND user_id = c.created_by
+			)
+		)
+	`, convID, fromUserID, toUserID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotOwner
+	}
+
+	result, err = tx.Exec(ctx, `
+		UPDATE conversation_members SET role = 'admin'
+		WHERE conversation_id = $1 AND user_id = $2
+	`, convID, toUserID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotMember
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetMemberCount returns the number of members in a conversation
+func (r *ConversationRepository) GetMemberCount(ctx context.Context, convID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM conversation_members WHERE conversation_id = $1
+	`, convID).Scan(&count)
 	return count, err
 }
 
+// findDMQuery selects the DM between users $1 and $2
+const findDMQuery = `
+	SELECT c.id FROM conversations c
+	WHERE c.type = 'dm'
+	AND EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id = c.id AND user_id = $1)
+	AND EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id = c.id AND user_id = $2)
+	AND (SELECT COUNT(*) FROM conversation_members WHERE conversation_id = c.id) = 2
+	LIMIT 1
+`
+
+// claimDMPair locks the pair of users in a new DM until tx ends and fails
+// with domain.ErrDMExists if they already share one, so two concurrent first
+// messages can't each create a DM
+func claimDMPair(ctx context.Context, tx pgx.Tx, memberIDs []uuid.UUID) error {
+	if len(memberIDs) != 2 {
+		return nil
+	}
+	user1, user2 := memberIDs[0], memberIDs[1]
+	if user2.String() < user1.String() {
+		user1, user2 = user2, user1
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`,
+		"dm:"+user1.String()+":"+user2.String()); err != nil {
+		return err
+	}
+
+	var convID uuid.UUID
+	err := tx.QueryRow(ctx, findDMQuery, user1, user2).Scan(&convID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return domain.ErrDMExists
+}
+
 // FindDMBetween finds an existing DM conversation between two users
 func (r *ConversationRepository) FindDMBetween(ctx context.Context, user1, user2 uuid.UUID) (*domain.Conversation, error) {
 	var convID uuid.UUID
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT c.id FROM conversations c
-		WHERE c.type = 'dm'
-		AND EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id = c.id AND user_id = $1)
-		AND EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id = c.id AND user_id = $2)
-		AND (SELECT COUNT(*) FROM conversation_members WHERE conversation_id = c.id) = 2
-	`, user1, user2).Scan(&convID)
+	err := r.db.Pool.QueryRow(ctx, findDMQuery, user1, user2).Scan(&convID)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil // No DM exists, not an error
@@ -219,54 +819,977 @@ func (r *ConversationRepository) FindDMBetween(ctx context.Context, user1, user2
 	return r.GetByID(ctx, convID)
 }
 
+// GetOrCreateSavedConversation returns the user's "Saved Messages" conversation,
+// provisioning it on first access. The unique index on saved_for guarantees a
+// single saved conversation per user even under concurrent calls.
+func (r *ConversationRepository) GetOrCreateSavedConversation(ctx context.Context, userID uuid.UUID) (*domain.Conversation, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var convID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO conversations (id, type, title, created_by, saved_for)
+		VALUES ($1, 'dm', '', $2, $2)
+		ON CONFLICT (saved_for) WHERE saved_for IS NOT NULL DO NOTHING
+		RETURNING id
+	`, uuid.New(), userID).Scan(&convID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already provisioned
+		err = tx.QueryRow(ctx, `
+			SELECT id FROM conversations WHERE saved_for = $1
+		`, userID).Scan(&convID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO conversation_members (conversation_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, convID, userID, domain.MemberRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, convID)
+}
+
 // ============================================================================
 // Message Operations
 // ============================================================================
 
+// messageExpirySQL computes a new message's expires_at from its
+// conversation's disappearing-message TTL, given the conversation joined as c
+// and the placeholder holding the message's created_at
+func messageExpirySQL(createdAt string) string {
+	return `CASE WHEN c.message_ttl_seconds > 0
+		THEN ` + createdAt + `::timestamptz + make_interval(secs => c.message_ttl_seconds) END`
+}
+
 // CreateMessage creates a new message
 func (r *ConversationRepository) CreateMessage(ctx context.Context, msg *domain.Message) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		INSERT INTO messages (id, conversation_id, sender_id, body_text, attachment_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, msg.ID, msg.ConversationID, msg.SenderID, msg.BodyText, msg.AttachmentID, msg.CreatedAt)
+	body, err := r.cipher.Encrypt(msg.BodyText)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := insertMessage(ctx, tx, msg, body); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// Update conversation's updated_at
+	_, _ = r.db.Pool.Exec(ctx, `
+		UPDATE conversations SET updated_at = NOW() WHERE id = $1
+	`, msg.ConversationID)
+	return nil
+}
+
+// insertMessage inserts msg with its already-encrypted body and links its
+// attachments
+func insertMessage(ctx context.Context, tx pgx.Tx, msg *domain.Message, body string) error {
+	// expires_at follows the conversation's disappearing-message setting
+	err := tx.QueryRow(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, body_text, attachment_id, visible_to, reply_to_id, created_at, expires_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, `+messageExpirySQL("$8")+`
+		FROM conversations c WHERE c.id = $2
+		RETURNING expires_at, seq
+	`, msg.ID, msg.ConversationID, msg.SenderID, body, msg.AttachmentID, msg.VisibleTo, msg.ReplyToID, msg.CreatedAt).Scan(&msg.ExpiresAt, &msg.Seq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrConversationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return insertMessageAttachments(ctx, tx, msg)
+}
 
-	if err == nil {
-		// Update conversation's updated_at
-		_, _ = r.db.Pool.Exec(ctx, `
-			UPDATE conversations SET updated_at = NOW() WHERE id = $1
-		`, msg.ConversationID)
+// insertMessageAttachments links a new message to its attachments, in order
+func insertMessageAttachments(ctx context.Context, tx pgx.Tx, msg *domain.Message) error {
+	if len(msg.AttachmentIDs) == 0 {
+		return nil
 	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO message_attachments (message_id, attachment_id, position)
+		SELECT $1, a.id, a.ord - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS a(id, ord)
+	`, msg.ID, msg.AttachmentIDs)
 	return err
 }
 
-// GetMessages retrieves messages with cursor pagination (before timestamp)
-func (r *ConversationRepository) GetMessages(ctx context.Context, convID uuid.UUID, before *time.Time, limit int) ([]domain.Message, error) {
-	var rows pgx.Rows
-	var err error
+// CreateMessages inserts a batch of messages atomically, in slice order
+func (r *ConversationRepository) CreateMessages(ctx context.Context, msgs []domain.Message) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	if before != nil {
-		rows, err = r.db.Pool.Query(ctx, `
-			SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.attachment_id, m.created_at,
-			       u.id, u.username, u.display_name, u.avatar_url
-			FROM messages m
-			LEFT JOIN users u ON u.id = m.sender_id
-			WHERE m.conversation_id = $1 AND m.created_at < $2
-			ORDER BY m.created_at DESC
-			LIMIT $3
-		`, convID, before, limit)
-	} else {
-		rows, err = r.db.Pool.Query(ctx, `
-			SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.attachment_id, m.created_at,
-			       u.id, u.username, u.display_name, u.avatar_url
-			FROM messages m
-			LEFT JOIN users u ON u.id = m.sender_id
-			WHERE m.conversation_id = $1
-			ORDER BY m.created_at DESC
-			LIMIT $2
-		`, convID, limit)
+	convIDs := make(map[uuid.UUID]bool)
+	for i := range msgs {
+		msg := &msgs[i]
+		body, err := r.cipher.Encrypt(msg.BodyText)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO messages (id, conversation_id, sender_id, body_text, attachment_id, forwarded_from, created_at, expires_at)
+			SELECT $1, $2, $3, $4, $5, $6, $7, `+messageExpirySQL("$7")+`
+			FROM conversations c WHERE c.id = $2
+			RETURNING expires_at, seq
+		`, msg.ID, msg.ConversationID, msg.SenderID, body, msg.AttachmentID, msg.ForwardedFrom, msg.CreatedAt).Scan(&msg.ExpiresAt, &msg.Seq)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrConversationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := insertMessageAttachments(ctx, tx, msg); err != nil {
+			return err
+		}
+		convIDs[msg.ConversationID] = true
+	}
+
+	for convID := range convIDs {
+		_, err := tx.Exec(ctx, `UPDATE conversations SET updated_at = NOW() WHERE id = $1`, convID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetMessagesByIDs returns the requested messages keyed by ID; unknown IDs are skipped
+func (r *ConversationRepository) GetMessagesByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.Message, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.attachment_id, m.visible_to, m.created_at,
+		       ARRAY(SELECT ma.attachment_id FROM message_attachments ma WHERE ma.message_id = m.id ORDER BY ma.position)
+		FROM messages m WHERE m.id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make(map[uuid.UUID]domain.Message, len(ids))
+	for rows.Next() {
+		var m domain.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.BodyText, &m.AttachmentID, &m.VisibleTo, &m.CreatedAt, &m.AttachmentIDs); err != nil {
+			return nil, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, err
+		}
+		messages[m.ID] = m
+	}
+	return messages, rows.Err()
+}
+
+// PinMessage pins a message in its conversation, replacing any existing pin
+// (and its expiry). New pins go to the top of the manual order; re-pinning
+// keeps the message's place. Returns domain.ErrMessageNotFound if the
+// message is not in the conversation, domain.ErrCannotPinWhisper if only
+// some members can see it, or domain.ErrTooManyPins if the conversation
+// already has maxPins active pins (0 disables the limit).
+func (r *ConversationRepository) PinMessage(ctx context.Context, convID, messageID, pinnedBy uuid.UUID, expiresAt *time.Time, maxPins int) (*domain.PinnedMessage, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Lock the conversation so concurrent pins can't overshoot the limit
+	_, err = tx.Exec(ctx, `SELECT 1 FROM conversations WHERE id = $1 FOR UPDATE`, convID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pins are shown to everyone, so a whisper can't be pinned
+	var whisper bool
+	err = tx.QueryRow(ctx, `
+		SELECT visible_to IS NOT NULL FROM messages WHERE id = $2 AND conversation_id = $1
+	`, convID, messageID).Scan(&whisper)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if whisper {
+		return nil, domain.ErrCannotPinWhisper
+	}
+
+	var active int
+	var alreadyPinned bool
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(BOOL_OR(message_id = $2), false)
+		FROM pinned_messages
+		WHERE conversation_id = $1
+		AND (expires_at IS NULL OR expires_at > NOW())
+	`, convID, messageID).Scan(&active, &alreadyPinned)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.CheckPinLimit(active, alreadyPinned, maxPins); err != nil {
+		return nil, err
+	}
+
+	pin := domain.PinnedMessage{ConversationID: convID, MessageID: messageID}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO pinned_messages (conversation_id, message_id, pinned_by, expires_at, position)
+		SELECT conversation_id, id, $3, $4,
+		       COALESCE((SELECT MIN(position) FROM pinned_messages WHERE conversation_id = $1), 1) - 1
+		FROM messages WHERE id = $2 AND conversation_id = $1
+		ON CONFLICT (conversation_id, message_id)
+		DO UPDATE SET pinned_by = EXCLUDED.pinned_by, pinned_at = NOW(), expires_at = EXCLUDED.expires_at
+		RETURNING pinned_by, pinned_at, expires_at, position
+	`, convID, messageID, pinnedBy, expiresAt).Scan(&pin.PinnedBy, &pin.PinnedAt, &pin.ExpiresAt, &pin.Position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pin, tx.Commit(ctx)
+}
+
+// ReorderPins sets the manual order of a conversation's pins. orderedIDs must
+// list every active pin exactly once, or domain.ErrInvalidPinOrder is returned.
+func (r *ConversationRepository) ReorderPins(ctx context.Context, convID uuid.UUID, orderedIDs []uuid.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT message_id FROM pinned_messages
+		WHERE conversation_id = $1
+		AND (expires_at IS NULL OR expires_at > NOW())
+		FOR UPDATE
+	`, convID)
+	if err != nil {
+		return err
+	}
+	var pinned []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		pinned = append(pinned, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := domain.ValidatePinOrder(pinned, orderedIDs); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE pinned_messages pm SET position = o.ord
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(message_id, ord)
+		WHERE pm.conversation_id = $1 AND pm.message_id = o.message_id
+	`, convID, orderedIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UnpinMessage removes a pin. Returns whether the message was pinned.
+func (r *ConversationRepository) UnpinMessage(ctx context.Context, convID, messageID uuid.UUID) (bool, error) {
+	result, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM pinned_messages WHERE conversation_id = $1 AND message_id = $2
+	`, convID, messageID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetPinnedMessages returns a conversation's unexpired pins with their
+// messages, in manual order, leaving out whispers the viewer isn't part of
+func (r *ConversationRepository) GetPinnedMessages(ctx context.Context, convID, viewerID uuid.UUID) ([]domain.PinnedMessage, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT pm.conversation_id, pm.message_id, pm.pinned_by, pm.pinned_at, pm.expires_at, pm.position,
+		       m.sender_id, m.body_text, m.attachment_id, m.forwarded_from, m.created_at
+		FROM pinned_messages pm
+		JOIN messages m ON m.id = pm.message_id
+		WHERE pm.conversation_id = $1
+		AND (pm.expires_at IS NULL OR pm.expires_at > NOW())
+		AND (m.visible_to IS NULL OR $2 = ANY(m.visible_to))
+		ORDER BY pm.position, pm.pinned_at DESC
+	`, convID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []domain.PinnedMessage
+	for rows.Next() {
+		var p domain.PinnedMessage
+		var m domain.Message
+		if err := rows.Scan(
+			&p.ConversationID, &p.MessageID, &p.PinnedBy, &p.PinnedAt, &p.ExpiresAt, &p.Position,
+			&m.SenderID, &m.BodyText, &m.AttachmentID, &m.ForwardedFrom, &m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, err
+		}
+		m.ID = p.MessageID
+		m.ConversationID = p.ConversationID
+		p.Message = &m
+		pins = append(pins, p)
+	}
+	return pins, rows.Err()
+}
+
+// UnpinExpired deletes every pin that lapsed at or before now and returns them
+func (r *ConversationRepository) UnpinExpired(ctx context.Context, now time.Time) ([]domain.PinnedMessage, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		DELETE FROM pinned_messages
+		WHERE expires_at IS NOT NULL AND expires_at <= $1
+		RETURNING conversation_id, message_id, pinned_by, pinned_at, expires_at
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []domain.PinnedMessage
+	for rows.Next() {
+		var p domain.PinnedMessage
+		if err := rows.Scan(&p.ConversationID, &p.MessageID, &p.PinnedBy, &p.PinnedAt, &p.ExpiresAt); err != nil {
+			return nil, err
+		}
+		pins = append(pins, p)
+	}
+	return pins, rows.Err()
+}
+
+// GetMembersIn returns which of the given users belong to the conversation
+func (r *ConversationRepository) GetMembersIn(ctx context.Context, convID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT user_id FROM conversation_members
+		WHERE conversation_id = $1 AND user_id = ANY($2)
+	`, convID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	member := make(map[uuid.UUID]bool, len(userIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		member[id] = true
+	}
+	return member, rows.Err()
+}
+
+// GetMemberConversations returns which of the given conversations the user belongs to
+func (r *ConversationRepository) GetMemberConversations(ctx context.Context, userID uuid.UUID, convIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT conversation_id FROM conversation_members
+		WHERE user_id = $1 AND conversation_id = ANY($2)
+	`, userID, convIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	member := make(map[uuid.UUID]bool, len(convIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		member[id] = true
+	}
+	return member, rows.Err()
+}
+
+// GetMessages retrieves messages with cursor pagination (before timestamp,
+// and optionally the seq of the message at that timestamp, for messages sent
+// in the same instant), leaving out whispers the viewer isn't part of
+func (r *ConversationRepository) GetMessages(ctx context.Context, convID, viewerID uuid.UUID, before *time.Time, beforeSeq *int64, limit int) ([]domain.Message, error) {
+	var rows pgx.Rows
+	var err error
+
+	if before != nil {
+		rows, err = r.db.Pool.Query(ctx, `
+			SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.attachment_id, m.forwarded_from, m.visible_to, m.created_at, m.seq, m.edited_at, m.expires_at,
+			       u.id, u.username, u.display_name, u.avatar_url,
+			       m.reply_to_id, rm.sender_id, rm.body_text, rm.attachment_id, rm.visible_to, rm.created_at,
+			       ru.username, ru.display_name, ru.avatar_url
+			FROM messages m
+			LEFT JOIN users u ON u.id = m.sender_id
+			LEFT JOIN messages rm ON rm.id = m.reply_to_id
+			LEFT JOIN users ru ON ru.id = rm.sender_id
+			WHERE m.conversation_id = $1
+			  AND (m.created_at < $2 OR (m.created_at = $2 AND m.seq < $5::bigint))
+			  AND (m.visible_to IS NULL OR $4 = ANY(m.visible_to))
+			  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+			ORDER BY m.created_at DESC, m.seq DESC
+			LIMIT $3
+		`, convID, before, limit, viewerID, beforeSeq)
+	} else {
+		rows, err = r.db.Pool.Query(ctx, `
+			SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.attachment_id, m.forwarded_from, m.visible_to, m.created_at, m.seq, m.edited_at, m.expires_at,
+			       u.id, u.username, u.display_name, u.avatar_url,
+			       m.reply_to_id, rm.sender_id, rm.body_text, rm.attachment_id, rm.visible_to, rm.created_at,
+			       ru.username, ru.display_name, ru.avatar_url
+			FROM messages m
+			LEFT JOIN users u ON u.id = m.sender_id
+			LEFT JOIN messages rm ON rm.id = m.reply_to_id
+			LEFT JOIN users ru ON ru.id = rm.sender_id
+			WHERE m.conversation_id = $1
+			  AND (m.visible_to IS NULL OR $3 = ANY(m.visible_to))
+			  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+			ORDER BY m.created_at DESC, m.seq DESC
+			LIMIT $2
+		`, convID, limit, viewerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []domain.Message
+	for rows.Next() {
+		var m domain.Message
+		var senderID *uuid.UUID
+		var userID *uuid.UUID
+		var username, displayName, avatarURL *string
+		var reply domain.Message
+		var replyBody *string
+		var replyCreatedAt *time.Time
+		var replyUsername, replyDisplayName, replyAvatarURL *string
+
+		err := rows.Scan(
+			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.AttachmentID, &m.ForwardedFrom, &m.VisibleTo, &m.CreatedAt, &m.Seq, &m.EditedAt, &m.ExpiresAt,
+			&userID, &username, &displayName, &avatarURL,
+			&m.ReplyToID, &reply.SenderID, &replyBody, &reply.AttachmentID, &reply.VisibleTo, &replyCreatedAt,
+			&replyUsername, &replyDisplayName, &replyAvatarURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, err
+		}
+		// Quote the replied-to message unless it's gone or a whisper the
+		// viewer wasn't part of
+		if m.ReplyToID != nil && replyBody != nil && reply.VisibleToUser(viewerID) {
+			reply.ID = *m.ReplyToID
+			reply.ConversationID = m.ConversationID
+			reply.BodyText = *replyBody
+			reply.CreatedAt = *replyCreatedAt
+			if err := r.openBody(&reply); err != nil {
+				return nil, err
+			}
+			if reply.SenderID != nil && replyUsername != nil {
+				reply.Sender = &domain.PublicUser{
+					ID:          *reply.SenderID,
+					Username:    *replyUsername,
+					DisplayName: stringValue(replyDisplayName),
+					AvatarURL:   stringValue(replyAvatarURL),
+				}
+			}
+			m.ReplyTo = reply.ReplyPreview()
+		}
+		m.SenderID = senderID
+		if userID != nil {
+			m.Sender = &domain.PublicUser{
+				ID:          *userID,
+				Username:    *username,
+				DisplayName: stringValue(displayName),
+				AvatarURL:   stringValue(avatarURL),
+			}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := r.loadMessageAttachments(ctx, messages); err != nil {
+		return nil, err
+	}
+	if err := r.loadLinkPreviews(ctx, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// loadMessageAttachments fills in each message's attachments, in order, with
+// one query for the whole page
+func (r *ConversationRepository) loadMessageAttachments(ctx context.Context, messages []domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT ma.message_id, a.id::text, a.uploader_id::text, a.conversation_id::text, a.bucket, a.object_key, a.filename,
+		       a.mime_type, a.size_bytes, a.sha256, a.status, a.created_at, a.completed_at, a.thumbnail_sizes, a.kind, a.duration_ms
+		FROM message_attachments ma
+		JOIN attachments a ON a.id = ma.attachment_id
+		WHERE ma.message_id = ANY($1)
+		ORDER BY ma.message_id, ma.position
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byMessage := make(map[uuid.UUID][]*domain.Attachment)
+	for rows.Next() {
+		var messageID uuid.UUID
+		var att domain.Attachment
+		err := rows.Scan(
+			&messageID, &att.ID, &att.UploaderID, &att.ConversationID, &att.Bucket, &att.ObjectKey, &att.Filename,
+			&att.MimeType, &att.SizeBytes, &att.SHA256, &att.Status, &att.CreatedAt, &att.CompletedAt, &att.ThumbnailSizes, &att.Kind, &att.DurationMs,
+		)
+		if err != nil {
+			return err
+		}
+		if att.Filename, err = r.cipher.Decrypt(att.Filename); err != nil {
+			return err
+		}
+		byMessage[messageID] = append(byMessage[messageID], &att)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range messages {
+		if atts := byMessage[messages[i].ID]; len(atts) > 0 {
+			messages[i].SetAttachments(atts)
+		}
+	}
+	return nil
+}
+
+// SaveLinkPreview stores the preview card for a message, replacing any
+// earlier one. It returns ErrMessageNotFound if the message was deleted in
+// the meantime.
+func (r *ConversationRepository) SaveLinkPreview(ctx context.Context, p *domain.LinkPreview) error {
+	fields := []string{p.URL, p.Title, p.Description, p.ImageURL}
+	for i, f := range fields {
+		sealed, err := r.cipher.Encrypt(f)
+		if err != nil {
+			return err
+		}
+		fields[i] = sealed
+	}
+	result, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO message_link_previews (message_id, url, title, description, image_url, fetched_at)
+		SELECT m.id, $2, $3, $4, $5, $6 FROM messages m WHERE m.id = $1
+		ON CONFLICT (message_id) DO UPDATE
+		SET url = EXCLUDED.url, title = EXCLUDED.title, description = EXCLUDED.description,
+		    image_url = EXCLUDED.image_url, fetched_at = EXCLUDED.fetched_at
+	`, p.MessageID, fields[0], fields[1], fields[2], fields[3], p.FetchedAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrMessageNotFound
+	}
+	return nil
+}
+
+// loadLinkPreviews fills in the link preview of each message that has one
+func (r *ConversationRepository) loadLinkPreviews(ctx context.Context, messages []domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	index := make(map[uuid.UUID]int, len(messages))
+	ids := make([]uuid.UUID, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+		index[messages[i].ID] = i
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT message_id, url, title, description, image_url, fetched_at
+		FROM message_link_previews
+		WHERE message_id = ANY($1)
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p domain.LinkPreview
+		if err := rows.Scan(&p.MessageID, &p.URL, &p.Title, &p.Description, &p.ImageURL, &p.FetchedAt); err != nil {
+			return err
+		}
+		for _, f := range []*string{&p.URL, &p.Title, &p.Description, &p.ImageURL} {
+			if *f, err = r.cipher.Decrypt(*f); err != nil {
+				return err
+			}
+		}
+		m := &messages[index[p.MessageID]]
+		p.ConversationID = m.ConversationID
+		m.LinkPreview = &p
+	}
+	return rows.Err()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ============================================================================
+// Block Operations
+// ============================================================================
+
+// Block creates a block relationship
+func (r *ConversationRepository) Block(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, blockerID, blockedID)
+	return err
+}
+
+// Unblock removes a block relationship
+func (r *ConversationRepository) Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
+	`, blockerID, blockedID)
+	return err
+}
+
+// IsBlocked checks if user1 has blocked user2 OR user2 has blocked user1
+func (r *ConversationRepository) IsBlocked(ctx context.Context, user1, user2 uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM blocks 
+			WHERE (blocker_id = $1 AND blocked_id = $2)
+			   OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`, user1, user2).Scan(&exists)
+	return exists, err
+}
+
+// GetDMPartner returns the other member of a DM, or uuid.Nil if the
+// conversation isn't a DM (or is the user's saved messages)
+func (r *ConversationRepository) GetDMPartner(ctx context.Context, convID, userID uuid.UUID) (uuid.UUID, error) {
+	var partnerID uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT cm.user_id
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id
+		WHERE c.id = $1 AND c.type = 'dm' AND cm.user_id != $2
+		LIMIT 1
+	`, convID, userID).Scan(&partnerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return partnerID, err
+}
+
+// ============================================================================
+// Starred Messages
+// ============================================================================
+
+// StarMessage adds a message to user's starred list
+func (r *ConversationRepository) StarMessage(ctx context.Context, userID, messageID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO starred_messages (user_id, message_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, messageID)
+	return err
+}
+
+// UnstarMessage removes a message from user's starred list
+func (r *ConversationRepository) UnstarMessage(ctx context.Context, userID, messageID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM starred_messages WHERE user_id = $1 AND message_id = $2
+	`, userID, messageID)
+	return err
+}
+
+// GetStarredMessages returns all starred messages for a user
+func (r *ConversationRepository) GetStarredMessages(ctx context.Context, userID uuid.UUID, limit int) ([]domain.Message, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
+		       u.id, u.username, u.display_name, u.avatar_url
+		FROM starred_messages sm
+		JOIN messages m ON m.id = sm.message_id
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE sm.user_id = $1
+		ORDER BY sm.starred_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []domain.Message
+	for rows.Next() {
+		var m domain.Message
+		var senderID *uuid.UUID
+		var userIDPtr *uuid.UUID
+		var username, displayName, avatarURL *string
+
+		err := rows.Scan(
+			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
+			&userIDPtr, &username, &displayName, &avatarURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, err
+		}
+		m.SenderID = senderID
+		if userIDPtr != nil {
+			m.Sender = &domain.PublicUser{
+				ID:          *userIDPtr,
+				Username:    *username,
+				DisplayName: stringValue(displayName),
+				AvatarURL:   stringValue(avatarURL),
+			}
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// IsMessageStarred checks if a message is starred by a user
+func (r *ConversationRepository) IsMessageStarred(ctx context.Context, userID, messageID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM starred_messages WHERE user_id = $1 AND message_id = $2)
+	`, userID, messageID).Scan(&exists)
+	return exists, err
+}
+
+// ============================================================================
+// Mentions
+// ============================================================================
+
+// CreateMentions records that msg mentioned each of userIDs
+func (r *ConversationRepository) CreateMentions(ctx context.Context, msg *domain.Message, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO message_mentions (message_id, user_id, conversation_id, created_at)
+		SELECT $1, u, $2, $3 FROM unnest($4::uuid[]) AS u
+		ON CONFLICT DO NOTHING
+	`, msg.ID, msg.ConversationID, msg.CreatedAt, userIDs)
+	return err
+}
+
+// GetMentions returns the messages that mentioned a user, newest first and
+// before the cursor if one is given. Conversations they've since left and
+// expired messages are left out.
+func (r *ConversationRepository) GetMentions(ctx context.Context, userID uuid.UUID, before *time.Time, limit int) ([]domain.Mention, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.visible_to, m.created_at, m.edited_at,
+		       u.id, u.username, u.display_name, u.avatar_url,
+		       c.type, COALESCE(c.title, '')
+		FROM message_mentions mm
+		JOIN messages m ON m.id = mm.message_id
+		JOIN conversations c ON c.id = mm.conversation_id
+		JOIN conversation_members cm ON cm.conversation_id = mm.conversation_id AND cm.user_id = mm.user_id
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE mm.user_id = $1
+		  AND ($2::timestamptz IS NULL OR mm.created_at < $2)
+		  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+		ORDER BY mm.created_at DESC
+		LIMIT $3
+	`, userID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []domain.Mention
+	for rows.Next() {
+		var mention domain.Mention
+		m := &mention.Message
+		var senderUserID *uuid.UUID
+		var username, displayName, avatarURL *string
+
+		err := rows.Scan(
+			&m.ID, &m.ConversationID, &m.SenderID, &m.BodyText, &m.VisibleTo, &m.CreatedAt, &m.EditedAt,
+			&senderUserID, &username, &displayName, &avatarURL,
+			&mention.ConversationType, &mention.ConversationTitle,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openBody(m); err != nil {
+			return nil, err
+		}
+		if senderUserID != nil {
+			m.Sender = &domain.PublicUser{
+				ID:          *senderUserID,
+				Username:    *username,
+				DisplayName: stringValue(displayName),
+				AvatarURL:   stringValue(avatarURL),
+			}
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, rows.Err()
+}
+
+// ============================================================================
+// Message Search
+// ============================================================================
+
+// searchCursorArgs returns the before_rank, before_created_at and before_id
+// query arguments for a search cursor; all are NULL on the first page
+func searchCursorArgs(cursor *domain.SearchCursor) (*float64, *time.Time, *uuid.UUID) {
+	if cursor == nil {
+		return nil, nil, nil
+	}
+	return &cursor.BeforeRank, &cursor.BeforeCreatedAt, &cursor.BeforeID
+}
+
+// SearchMessages performs full-text search on messages within a conversation,
+// narrowed by filter, returning up to limit hits after cursor (nil for the
+// first page) and whether more follow
+func (r *ConversationRepository) SearchMessages(ctx context.Context, convID, viewerID uuid.UUID, query string, filter domain.SearchFilter, cursor *domain.SearchCursor, limit int) ([]domain.Message, bool, error) {
+	// The search index only ever sees ciphertext when bodies are encrypted
+	if r.cipher.Enabled() {
+		return nil, false, domain.ErrSearchDisabled
+	}
+
+	beforeRank, beforeCreatedAt, beforeID := searchCursorArgs(cursor)
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
+		       u.id, u.username, u.display_name, u.avatar_url,
+		       ts_rank(m.search_vector, plainto_tsquery(c.search_language, $2)) as rank
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE m.conversation_id = $1 
+		  AND m.search_vector @@ plainto_tsquery(c.search_language, $2)
+		  AND (m.visible_to IS NULL OR $4 = ANY(m.visible_to))
+		  AND ($5::real IS NULL OR (ts_rank(m.search_vector, plainto_tsquery(c.search_language, $2)), m.created_at, m.id) < ($5::real, $6::timestamptz, $7::uuid))
+		  AND ($8::uuid IS NULL OR m.sender_id = $8)
+		  AND ($9::timestamptz IS NULL OR m.created_at >= $9)
+		  AND ($10::timestamptz IS NULL OR m.created_at < $10)
+		ORDER BY rank DESC, m.created_at DESC, m.id DESC
+		LIMIT $3
+	`, convID, query, limit+1, viewerID, beforeRank, beforeCreatedAt, beforeID, filter.FromUserID, filter.After, filter.Before)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var messages []domain.Message
+	for rows.Next() {
+		var m domain.Message
+		var senderID *uuid.UUID
+		var userIDPtr *uuid.UUID
+		var username, displayName, avatarURL *string
+
+		err := rows.Scan(
+			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
+			&userIDPtr, &username, &displayName, &avatarURL,
+			&m.Rank,
+		)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, false, err
+		}
+		m.SenderID = senderID
+		if userIDPtr != nil {
+			m.Sender = &domain.PublicUser{
+				ID:          *userIDPtr,
+				Username:    *username,
+				DisplayName: stringValue(displayName),
+				AvatarURL:   stringValue(avatarURL),
+			}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	messages, hasMore := domain.PageSearchHits(messages, limit)
+	return messages, hasMore, nil
+}
+
+// SearchAllMessages searches across all conversations the user is a member
+// of, filtering and paging like SearchMessages
+func (r *ConversationRepository) SearchAllMessages(ctx context.Context, userID uuid.UUID, query string, filter domain.SearchFilter, cursor *domain.SearchCursor, limit int) ([]domain.Message, bool, error) {
+	// The search index only ever sees ciphertext when bodies are encrypted
+	if r.cipher.Enabled() {
+		return nil, false, domain.ErrSearchDisabled
 	}
+
+	beforeRank, beforeCreatedAt, beforeID := searchCursorArgs(cursor)
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
+		       u.id, u.username, u.display_name, u.avatar_url,
+		       ts_rank(m.search_vector, plainto_tsquery(c.search_language, $2)) as rank
+		FROM messages m
+		LEFT JOIN users u ON u.id = m.sender_id
+		JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = $1
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.search_vector @@ plainto_tsquery(c.search_language, $2)
+		  AND (m.visible_to IS NULL OR $1 = ANY(m.visible_to))
+		  AND NOT EXISTS (
+		      SELECT 1 FROM conversation_bans b
+		      WHERE b.conversation_id = m.conversation_id AND b.user_id = $1
+		  )
+		  AND ($4::real IS NULL OR (ts_rank(m.search_vector, plainto_tsquery(c.search_language, $2)), m.created_at, m.id) < ($4::real, $5::timestamptz, $6::uuid))
+		  AND ($7::uuid IS NULL OR m.sender_id = $7)
+		  AND ($8::timestamptz IS NULL OR m.created_at >= $8)
+		  AND ($9::timestamptz IS NULL OR m.created_at < $9)
+		ORDER BY rank DESC, m.created_at DESC, m.id DESC
+		LIMIT $3
+	`, userID, query, limit+1, beforeRank, beforeCreatedAt, beforeID, filter.FromUserID, filter.After, filter.Before)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
@@ -274,20 +1797,24 @@ func (r *ConversationRepository) GetMessages(ctx context.Context, convID uuid.UU
 	for rows.Next() {
 		var m domain.Message
 		var senderID *uuid.UUID
-		var userID *uuid.UUID
+		var userIDPtr *uuid.UUID
 		var username, displayName, avatarURL *string
 
 		err := rows.Scan(
-			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.AttachmentID, &m.CreatedAt,
-			&userID, &username, &displayName, &avatarURL,
+			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
+			&userIDPtr, &username, &displayName, &avatarURL,
+			&m.Rank,
 		)
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+		if err := r.openBody(&m); err != nil {
+			return nil, false, err
 		}
 		m.SenderID = senderID
-		if userID != nil {
+		if userIDPtr != nil {
 			m.Sender = &domain.PublicUser{
-				ID:          *userID,
+				ID:          *userIDPtr,
 				Username:    *username,
 				DisplayName: stringValue(displayName),
 				AvatarURL:   stringValue(avatarURL),
@@ -295,85 +1822,61 @@ func (r *ConversationRepository) GetMessages(ctx context.Context, convID uuid.UU
 		}
 		messages = append(messages, m)
 	}
-	return messages, rows.Err()
-}
-
-func stringValue(s *string) string {
-	if s == nil {
-		return ""
+	if err := rows.Err(); err != nil {
+		return nil, false, err
 	}
-	return *s
-}
-
-// ============================================================================
-// Block Operations
-// ============================================================================
-
-// Block creates a block relationship
-func (r *ConversationRepository) Block(ctx context.Context, blockerID, blockedID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		INSERT INTO blocks (blocker_id, blocked_id)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`, blockerID, blockedID)
-	return err
-}
-
-// Unblock removes a block relationship
-func (r *ConversationRepository) Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
-	`, blockerID, blockedID)
-	return err
-}
-
-// IsBlocked checks if user1 has blocked user2 OR user2 has blocked user1
-func (r *ConversationRepository) IsBlocked(ctx context.Context, user1, user2 uuid.UUID) (bool, error) {
-	var exists bool
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT EXISTS(
-			SELECT 1 FROM blocks 
-			WHERE (blocker_id = $1 AND blocked_id = $2)
-			   OR (blocker_id = $2 AND blocked_id = $1)
-		)
-	`, user1, user2).Scan(&exists)
-	return exists, err
-}
+	rows.Close()
 
-// ============================================================================
-// Starred Messages
-// ============================================================================
+	// Page before filtering so the cursor stays on the query's own ordering
+	messages, hasMore := domain.PageSearchHits(messages, limit)
+	if !r.searchRecheck || len(messages) == 0 {
+		return messages, hasMore, nil
+	}
 
-// StarMessage adds a message to user's starred list
-func (r *ConversationRepository) StarMessage(ctx context.Context, userID, messageID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		INSERT INTO starred_messages (user_id, message_id)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`, userID, messageID)
-	return err
+	// Membership may have changed while the search ran; only return hits
+	// from conversations the user can still read
+	convIDs := make([]uuid.UUID, 0, len(messages))
+	for _, m := range messages {
+		convIDs = append(convIDs, m.ConversationID)
+	}
+	accessible, err := r.getAccessibleConversations(ctx, userID, convIDs)
+	if err != nil {
+		return nil, false, err
+	}
+	return domain.FilterAccessibleMessages(messages, accessible), hasMore, nil
 }
 
-// UnstarMessage removes a message from user's starred list
-func (r *ConversationRepository) UnstarMessage(ctx context.Context, userID, messageID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		DELETE FROM starred_messages WHERE user_id = $1 AND message_id = $2
-	`, userID, messageID)
-	return err
-}
+// SearchStarredMessages searches the user's starred messages, returning each
+// hit with a highlighted snippet. Stars on messages from conversations the
+// user can no longer read are skipped.
+func (r *ConversationRepository) SearchStarredMessages(ctx context.Context, userID uuid.UUID, query string, limit int) ([]domain.Message, error) {
+	// The search index only ever sees ciphertext when bodies are encrypted
+	if r.cipher.Enabled() {
+		return nil, domain.ErrSearchDisabled
+	}
 
-// GetStarredMessages returns all starred messages for a user
-func (r *ConversationRepository) GetStarredMessages(ctx context.Context, userID uuid.UUID, limit int) ([]domain.Message, error) {
+	headlineOpts := "StartSel=" + domain.SnippetStartSel + ", StopSel=" + domain.SnippetStopSel +
+		", MaxFragments=2, MaxWords=20, MinWords=5"
 	rows, err := r.db.Pool.Query(ctx, `
 		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
-		       u.id, u.username, u.display_name, u.avatar_url
+		       u.id, u.username, u.display_name, u.avatar_url,
+		       ts_headline(c.search_language, m.body_text, plainto_tsquery(c.search_language, $2), $4),
+		       ts_rank(m.search_vector, plainto_tsquery(c.search_language, $2)) as rank
 		FROM starred_messages sm
 		JOIN messages m ON m.id = sm.message_id
+		JOIN conversations c ON c.id = m.conversation_id
+		JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = $1
 		LEFT JOIN users u ON u.id = m.sender_id
 		WHERE sm.user_id = $1
-		ORDER BY sm.starred_at DESC
-		LIMIT $2
-	`, userID, limit)
+		  AND m.search_vector @@ plainto_tsquery(c.search_language, $2)
+		  AND (m.visible_to IS NULL OR $1 = ANY(m.visible_to))
+		  AND NOT EXISTS (
+		      SELECT 1 FROM conversation_bans b
+		      WHERE b.conversation_id = m.conversation_id AND b.user_id = $1
+		  )
+		ORDER BY rank DESC, sm.starred_at DESC
+		LIMIT $3
+	`, userID, query, limit, headlineOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -385,15 +1888,19 @@ func (r *ConversationRepository) GetStarredMessages(ctx context.Context, userID
 		var senderID *uuid.UUID
 		var userIDPtr *uuid.UUID
 		var username, displayName, avatarURL *string
+		var headline string
+		var rank float64
 
 		err := rows.Scan(
 			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
 			&userIDPtr, &username, &displayName, &avatarURL,
+			&headline, &rank,
 		)
 		if err != nil {
 			return nil, err
 		}
 		m.SenderID = senderID
+		m.Snippet = domain.RenderSnippet(headline)
 		if userIDPtr != nil {
 			m.Sender = &domain.PublicUser{
 				ID:          *userIDPtr,
@@ -407,177 +1914,385 @@ func (r *ConversationRepository) GetStarredMessages(ctx context.Context, userID
 	return messages, rows.Err()
 }
 
-// IsMessageStarred checks if a message is starred by a user
-func (r *ConversationRepository) IsMessageStarred(ctx context.Context, userID, messageID uuid.UUID) (bool, error) {
-	var exists bool
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM starred_messages WHERE user_id = $1 AND message_id = $2)
-	`, userID, messageID).Scan(&exists)
-	return exists, err
+// getAccessibleConversations returns which of the given conversations the
+// user is a member of and not banned from
+func (r *ConversationRepository) getAccessibleConversations(ctx context.Context, userID uuid.UUID, convIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT cm.conversation_id FROM conversation_members cm
+		WHERE cm.user_id = $1 AND cm.conversation_id = ANY($2)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM conversation_bans b
+		      WHERE b.conversation_id = cm.conversation_id AND b.user_id = cm.user_id
+		  )
+	`, userID, convIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accessible := make(map[uuid.UUID]bool, len(convIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accessible[id] = true
+	}
+	return accessible, rows.Err()
 }
 
 // ============================================================================
-// Message Search
+// Archive Conversations
 // ============================================================================
 
-// SearchMessages performs full-text search on messages within a conversation
-func (r *ConversationRepository) SearchMessages(ctx context.Context, convID uuid.UUID, query string, limit int) ([]domain.Message, error) {
+// ArchiveConversation marks a conversation as archived for a user
+func (r *ConversationRepository) ArchiveConversation(ctx context.Context, convID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, archiveConversationSQL, convID)
+	return err
+}
+
+// UnarchiveConversation unarchives a conversation
+func (r *ConversationRepository) UnarchiveConversation(ctx context.Context, convID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, unarchiveConversationSQL, convID)
+	return err
+}
+
+// GetArchivedConversations returns all archived conversations for a user
+func (r *ConversationRepository) GetArchivedConversations(ctx context.Context, userID uuid.UUID) ([]domain.Conversation, error) {
 	rows, err := r.db.Pool.Query(ctx, `
-		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
-		       u.id, u.username, u.display_name, u.avatar_url,
-		       ts_rank(m.search_vector, plainto_tsquery('english', $2)) as rank
-		FROM messages m
-		LEFT JOIN users u ON u.id = m.sender_id
-		WHERE m.conversation_id = $1 
-		  AND m.search_vector @@ plainto_tsquery('english', $2)
-		ORDER BY rank DESC, m.created_at DESC
-		LIMIT $3
-	`, convID, query, limit)
+		SELECT c.id, c.type, c.title, c.created_by, c.created_at, c.updated_at, c.archived_at
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id
+		WHERE cm.user_id = $1 AND c.archived_at IS NOT NULL
+		ORDER BY c.archived_at DESC
+	`, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []domain.Message
+	var conversations []domain.Conversation
 	for rows.Next() {
-		var m domain.Message
-		var senderID *uuid.UUID
-		var userIDPtr *uuid.UUID
-		var username, displayName, avatarURL *string
-		var rank float64
-
+		var c domain.Conversation
 		err := rows.Scan(
-			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
-			&userIDPtr, &username, &displayName, &avatarURL,
-			&rank,
+			&c.ID, &c.Type, &c.Title,
+			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		m.SenderID = senderID
-		if userIDPtr != nil {
-			m.Sender = &domain.PublicUser{
-				ID:          *userIDPtr,
-				Username:    *username,
-				DisplayName: stringValue(displayName),
-				AvatarURL:   stringValue(avatarURL),
-			}
+		conversations = append(conversations, c)
+	}
+	return conversations, rows.Err()
+}
+
+// ============================================================================
+// Conversation Mutes / Pins
+// ============================================================================
+
+// MuteConversation silences a conversation for a user until the given time,
+// or indefinitely if until is nil
+func (r *ConversationRepository) MuteConversation(ctx context.Context, convID, userID uuid.UUID, until *time.Time) error {
+	_, err := r.db.Pool.Exec(ctx, muteConversationSQL, convID, userID, until)
+	return err
+}
+
+// UnmuteConversation lifts a user's mute on a conversation
+func (r *ConversationRepository) UnmuteConversation(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, unmuteConversationSQL, convID, userID)
+	return err
+}
+
+// PinConversation keeps a conversation at the top of a user's list
+func (r *ConversationRepository) PinConversation(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, pinConversationSQL, convID, userID)
+	return err
+}
+
+// UnpinConversation removes a conversation from a user's pinned list
+func (r *ConversationRepository) UnpinConversation(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, unpinConversationSQL, convID, userID)
+	return err
+}
+
+// ============================================================================
+// Conversation Folders
+// ============================================================================
+
+// SetConversationFolder files a conversation into one of the user's folders,
+// moving it out of any folder it was in
+func (r *ConversationRepository) SetConversationFolder(ctx context.Context, convID, userID uuid.UUID, folder string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO conversation_folders (user_id, conversation_id, folder)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, conversation_id)
+		DO UPDATE SET folder = EXCLUDED.folder
+	`, userID, convID, folder)
+	return err
+}
+
+// ClearConversationFolder takes a conversation out of the user's folders
+func (r *ConversationRepository) ClearConversationFolder(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM conversation_folders
+		WHERE user_id = $1 AND conversation_id = $2
+	`, userID, convID)
+	return err
+}
+
+// ============================================================================
+// Drafts
+// ============================================================================
+
+// SaveDraft stores a user's unsent draft in a conversation, replacing any
+// earlier one
+func (r *ConversationRepository) SaveDraft(ctx context.Context, draft *domain.Draft, userID uuid.UUID) error {
+	body, err := r.cipher.Encrypt(draft.Body)
+	if err != nil {
+		return err
+	}
+	return r.db.Pool.QueryRow(ctx, `
+		INSERT INTO conversation_drafts (conversation_id, user_id, body, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (conversation_id, user_id)
+		DO UPDATE SET body = EXCLUDED.body, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, draft.ConversationID, userID, body).Scan(&draft.UpdatedAt)
+}
+
+// GetDraft returns a user's draft in a conversation, or ErrDraftNotFound
+func (r *ConversationRepository) GetDraft(ctx context.Context, convID, userID uuid.UUID) (*domain.Draft, error) {
+	draft := &domain.Draft{ConversationID: convID}
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT body, updated_at FROM conversation_drafts
+		WHERE conversation_id = $1 AND user_id = $2
+	`, convID, userID).Scan(&draft.Body, &draft.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if draft.Body, err = r.cipher.Decrypt(draft.Body); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// DeleteDraft discards a user's draft in a conversation, if there is one
+func (r *ConversationRepository) DeleteDraft(ctx context.Context, convID, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM conversation_drafts
+		WHERE conversation_id = $1 AND user_id = $2
+	`, convID, userID)
+	return err
+}
+
+// ============================================================================
+// Sync
+// ============================================================================
+
+// GetSyncState returns the user's conversation layout: pins, mutes, archived
+// conversations and folders. Only conversations the user is still a member of
+// are included.
+func (r *ConversationRepository) GetSyncState(ctx context.Context, userID uuid.UUID, now time.Time) (*domain.SyncState, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pinned, err := collectConversationIDs(ctx, tx, `
+		SELECT pc.conversation_id
+		FROM pinned_conversations pc
+		JOIN conversation_members cm ON cm.conversation_id = pc.conversation_id AND cm.user_id = pc.user_id
+		WHERE pc.user_id = $1
+		ORDER BY pc.pinned_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archived, err := collectConversationIDs(ctx, tx, `
+		SELECT c.id
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id
+		WHERE cm.user_id = $1 AND c.archived_at IS NOT NULL
+		ORDER BY c.archived_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT ms.conversation_id, ms.muted_until
+		FROM conversation_mute_settings ms
+		JOIN conversation_members cm ON cm.conversation_id = ms.conversation_id AND cm.user_id = ms.user_id
+		WHERE ms.user_id = $1
+		ORDER BY ms.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var mutes []domain.ConversationMute
+	for rows.Next() {
+		var m domain.ConversationMute
+		if err := rows.Scan(&m.ConversationID, &m.MutedUntil); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		mutes = append(mutes, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT cf.conversation_id, cf.folder
+		FROM conversation_folders cf
+		JOIN conversation_members cm ON cm.conversation_id = cf.conversation_id AND cm.user_id = cf.user_id
+		WHERE cf.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var folders []domain.ConversationFolder
+	for rows.Next() {
+		var f domain.ConversationFolder
+		if err := rows.Scan(&f.ConversationID, &f.Folder); err != nil {
+			rows.Close()
+			return nil, err
 		}
-		messages = append(messages, m)
+		folders = append(folders, f)
 	}
-	return messages, rows.Err()
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	state := domain.BuildSyncState(pinned, archived, mutes, folders, now)
+	return &state, nil
 }
 
-// SearchAllMessages searches across all conversations the user is a member of
-func (r *ConversationRepository) SearchAllMessages(ctx context.Context, userID uuid.UUID, query string, limit int) ([]domain.Message, error) {
-	rows, err := r.db.Pool.Query(ctx, `
-		SELECT m.id, m.conversation_id, m.sender_id, m.body_text, m.created_at,
-		       u.id, u.username, u.display_name, u.avatar_url,
-		       ts_rank(m.search_vector, plainto_tsquery('english', $2)) as rank
-		FROM messages m
-		LEFT JOIN users u ON u.id = m.sender_id
-		JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = $1
-		WHERE m.search_vector @@ plainto_tsquery('english', $2)
-		ORDER BY rank DESC, m.created_at DESC
-		LIMIT $3
-	`, userID, query, limit)
+// collectConversationIDs runs a per-user query whose only column is a conversation ID
+func collectConversationIDs(ctx context.Context, tx pgx.Tx, query string, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []domain.Message
+	var ids []uuid.UUID
 	for rows.Next() {
-		var m domain.Message
-		var senderID *uuid.UUID
-		var userIDPtr *uuid.UUID
-		var username, displayName, avatarURL *string
-		var rank float64
-
-		err := rows.Scan(
-			&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt,
-			&userIDPtr, &username, &displayName, &avatarURL,
-			&rank,
-		)
-		if err != nil {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		m.SenderID = senderID
-		if userIDPtr != nil {
-			m.Sender = &domain.PublicUser{
-				ID:          *userIDPtr,
-				Username:    *username,
-				DisplayName: stringValue(displayName),
-				AvatarURL:   stringValue(avatarURL),
-			}
-		}
-		messages = append(messages, m)
+		ids = append(ids, id)
 	}
-	return messages, rows.Err()
+	return ids, rows.Err()
 }
 
 // ============================================================================
-// Archive Conversations
+// Batch Operations
 // ============================================================================
 
-// ArchiveConversation marks a conversation as archived for a user
-func (r *ConversationRepository) ArchiveConversation(ctx context.Context, convID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		UPDATE conversations SET archived_at = NOW() WHERE id = $1
-	`, convID)
-	return err
-}
-
-// UnarchiveConversation unarchives a conversation
-func (r *ConversationRepository) UnarchiveConversation(ctx context.Context, convID uuid.UUID) error {
-	_, err := r.db.Pool.Exec(ctx, `
-		UPDATE conversations SET archived_at = NULL WHERE id = $1
-	`, convID)
-	return err
-}
-
-// GetArchivedConversations returns all archived conversations for a user
-func (r *ConversationRepository) GetArchivedConversations(ctx context.Context, userID uuid.UUID) ([]domain.Conversation, error) {
-	rows, err := r.db.Pool.Query(ctx, `
-		SELECT c.id, c.type, c.title, c.created_by, c.created_at, c.updated_at, c.archived_at
-		FROM conversations c
-		JOIN conversation_members cm ON cm.conversation_id = c.id
-		WHERE cm.user_id = $1 AND c.archived_at IS NOT NULL
-		ORDER BY c.archived_at DESC
-	`, userID)
+// ApplyConversationBatch runs validated batch ops for a user in a single
+// transaction, so either every op lands or none do. Read ops mark the whole
+// conversation read.
+func (r *ConversationRepository) ApplyConversationBatch(ctx context.Context, userID uuid.UUID, ops []domain.ConversationOp) error {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	var conversations []domain.Conversation
-	for rows.Next() {
-		var c domain.Conversation
-		err := rows.Scan(
-			&c.ID, &c.Type, &c.Title,
-			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt,
-		)
+	for _, op := range ops {
+		switch op.Action {
+		case domain.ConversationActionArchive:
+			_, err = tx.Exec(ctx, archiveConversationSQL, op.ConversationID)
+		case domain.ConversationActionUnarchive:
+			_, err = tx.Exec(ctx, unarchiveConversationSQL, op.ConversationID)
+		case domain.ConversationActionMute:
+			_, err = tx.Exec(ctx, muteConversationSQL, op.ConversationID, userID, nil)
+		case domain.ConversationActionUnmute:
+			_, err = tx.Exec(ctx, unmuteConversationSQL, op.ConversationID, userID)
+		case domain.ConversationActionPin:
+			_, err = tx.Exec(ctx, pinConversationSQL, op.ConversationID, userID)
+		case domain.ConversationActionUnpin:
+			_, err = tx.Exec(ctx, unpinConversationSQL, op.ConversationID, userID)
+		case domain.ConversationActionRead:
+			_, err = tx.Exec(ctx, markConversationReadSQL, op.ConversationID, userID, nil)
+		default:
+			err = domain.ErrInvalidAction
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
-		conversations = append(conversations, c)
 	}
-	return conversations, rows.Err()
+
+	return tx.Commit(ctx)
 }
 
+// Shared by the single-conversation methods and ApplyConversationBatch
+const (
+	archiveConversationSQL   = `UPDATE conversations SET archived_at = NOW() WHERE id = $1`
+	unarchiveConversationSQL = `UPDATE conversations SET archived_at = NULL WHERE id = $1`
+
+	muteConversationSQL = `
+		INSERT INTO conversation_mute_settings (conversation_id, user_id, muted_until)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (conversation_id, user_id)
+		DO UPDATE SET muted_until = EXCLUDED.muted_until`
+	unmuteConversationSQL = `
+		DELETE FROM conversation_mute_settings
+		WHERE conversation_id = $1 AND user_id = $2`
+
+	pinConversationSQL = `
+		INSERT INTO pinned_conversations (conversation_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+	unpinConversationSQL = `
+		DELETE FROM pinned_conversations
+		WHERE conversation_id = $1 AND user_id = $2`
+
+	markConversationReadSQL = `
+		INSERT INTO conversation_read_status (conversation_id, user_id, last_read_at, last_read_message_id)
+		VALUES ($1, $2, NOW(), $3)
+		ON CONFLICT (conversation_id, user_id)
+		DO UPDATE SET last_read_at = NOW(), last_read_message_id = EXCLUDED.last_read_message_id`
+)
+
 // ============================================================================
 // Read Status / Unread Tracking
 // ============================================================================
 
 // MarkConversationRead updates the read status for a user in a conversation
 func (r *ConversationRepository) MarkConversationRead(ctx context.Context, convID, userID uuid.UUID, messageID *uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, markConversationReadSQL, convID, userID, messageID)
+	return err
+}
+
+// MarkUnreadFrom moves the user's read cursor to just before the message, so
+// it and everything after it count as unread. Returns the resulting state.
+func (r *ConversationRepository) MarkUnreadFrom(ctx context.Context, msg *domain.Message, userID uuid.UUID) (*domain.UnreadState, error) {
+	cursor := domain.UnreadCursorBefore(msg.CreatedAt)
 	_, err := r.db.Pool.Exec(ctx, `
 		INSERT INTO conversation_read_status (conversation_id, user_id, last_read_at, last_read_message_id)
-		VALUES ($1, $2, NOW(), $3)
+		VALUES ($1, $2, $3, (
+			SELECT id FROM messages
+			WHERE conversation_id = $1 AND created_at <= $3
+			ORDER BY created_at DESC, seq DESC
+			LIMIT 1
+		))
 		ON CONFLICT (conversation_id, user_id)
-		DO UPDATE SET last_read_at = NOW(), last_read_message_id = EXCLUDED.last_read_message_id
-	`, convID, userID, messageID)
-	return err
+		DO UPDATE SET last_read_at = EXCLUDED.last_read_at, last_read_message_id = EXCLUDED.last_read_message_id
+	`, msg.ConversationID, userID, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetUnreadState(ctx, msg.ConversationID, userID)
 }
 
 // MarkAllConversationsRead marks all conversations as read for a user
@@ -593,21 +2308,38 @@ func (r *ConversationRepository) MarkAllConversationsRead(ctx context.Context, u
 	return err
 }
 
+// unreadMessageSQL is the predicate for a message counting as unread, given
+// the user as $1 and their read status joined as rs. It's shared by the
+// conversation list and the single-conversation count so badges agree.
+const unreadMessageSQL = `m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz)
+			  AND m.sender_id != $1
+			  AND (m.visible_to IS NULL OR $1 = ANY(m.visible_to))`
+
 // GetUnreadCount returns the unread message count for a user in a conversation
 func (r *ConversationRepository) GetUnreadCount(ctx context.Context, convID, userID uuid.UUID) (int, error) {
-	var count int
+	state, err := r.GetUnreadState(ctx, convID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return state.UnreadCount, nil
+}
+
+// GetUnreadState returns a user's unread count and last-read cursor for one
+// conversation, counted the same way as GetUserConversationsWithDetails
+func (r *ConversationRepository) GetUnreadState(ctx context.Context, convID, userID uuid.UUID) (*domain.UnreadState, error) {
+	state := &domain.UnreadState{ConversationID: convID}
 	err := r.db.Pool.QueryRow(ctx, `
-		SELECT COUNT(*)
-		FROM messages m
-		WHERE m.conversation_id = $1
-		  AND m.created_at > COALESCE(
-		      (SELECT last_read_at FROM conversation_read_status 
-		       WHERE conversation_id = $1 AND user_id = $2),
-		      '1970-01-01'::timestamptz
-		  )
-		  AND m.sender_id != $2
-	`, convID, userID).Scan(&count)
-	return count, err
+		SELECT rs.last_read_at, rs.last_read_message_id,
+		       (SELECT COUNT(*) FROM messages m
+		        WHERE m.conversation_id = $2
+		          AND `+unreadMessageSQL+`)
+		FROM (SELECT 1) AS one
+		LEFT JOIN conversation_read_status rs ON rs.conversation_id = $2 AND rs.user_id = $1
+	`, userID, convID).Scan(&state.LastReadAt, &state.LastReadMessageID, &state.UnreadCount)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
 }
 
 // GetUserConversationsWithDetails returns all conversations for a user with unread counts and last message
@@ -617,7 +2349,8 @@ func (r *ConversationRepository) GetUserConversationsWithDetails(ctx context.Con
 			SELECT DISTINCT ON (conversation_id)
 				conversation_id, id, sender_id, body_text, created_at
 			FROM messages
-			ORDER BY conversation_id, created_at DESC
+			WHERE visible_to IS NULL OR $1 = ANY(visible_to)
+			ORDER BY conversation_id, created_at DESC, seq DESC
 		),
 		unread_counts AS (
 			SELECT 
@@ -625,8 +2358,7 @@ func (r *ConversationRepository) GetUserConversationsWithDetails(ctx context.Con
 				COUNT(*) as unread_count
 			FROM messages m
 			LEFT JOIN conversation_read_status rs ON rs.conversation_id = m.conversation_id AND rs.user_id = $1
-			WHERE m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz)
-			  AND m.sender_id != $1
+			WHERE `+unreadMessageSQL+`
 			GROUP BY m.conversation_id
 		),
 		member_counts AS (
@@ -636,110 +2368,372 @@ func (r *ConversationRepository) GetUserConversationsWithDetails(ctx context.Con
 		)
 		SELECT 
 			c.id, c.type, c.title, c.created_by, c.created_at, c.updated_at, c.archived_at,
+			c.saved_for IS NOT NULL, c.default_member_role, c.allow_member_adds, c.read_only,
 			COALESCE(uc.unread_count, 0) as unread_count,
 			COALESCE(mc.member_count, 0) as member_count,
-			lm.id, lm.sender_id, lm.body_text, lm.created_at
+			ms.user_id IS NOT NULL AND (ms.muted_until IS NULL OR ms.muted_until > NOW()) as muted,
+			lm.id, lm.sender_id, lm.body_text, lm.created_at,
+			d.body
 		FROM conversations c
 		JOIN conversation_members cm ON cm.conversation_id = c.id
 		LEFT JOIN last_messages lm ON lm.conversation_id = c.id
 		LEFT JOIN unread_counts uc ON uc.conversation_id = c.id
 		LEFT JOIN member_counts mc ON mc.conversation_id = c.id
+		LEFT JOIN conversation_mute_settings ms ON ms.conversation_id = c.id AND ms.user_id = $1
+		LEFT JOIN conversation_drafts d ON d.conversation_id = c.id AND d.user_id = $1
 		WHERE cm.user_id = $1 AND c.archived_at IS NULL
 		ORDER BY COALESCE(lm.created_at, c.created_at) DESC
 	`, userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer rows.Close()
+
+	var conversations []domain.Conversation
+	for rows.Next() {
+		var c domain.Conversation
+		var lastMsgID, lastMsgSenderID *uuid.UUID
+		var lastMsgBody *string
+		var lastMsgCreatedAt *time.Time
+
+		err := rows.Scan(
+			&c.ID, &c.Type, &c.Title,
+			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt,
+			&c.IsSaved, &c.DefaultMemberRole, &c.AllowMemberAdds, &c.ReadOnly,
+			&c.UnreadCount, &c.MemberCount, &c.Muted,
+			&lastMsgID, &lastMsgSenderID, &lastMsgBody, &lastMsgCreatedAt,
+			&c.Draft,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if c.Draft != nil {
+			draft, err := r.cipher.Decrypt(*c.Draft)
+			if err != nil {
+				return nil, err
+			}
+			c.Draft = &draft
+		}
+
+		// Populate last message if exists
+		if lastMsgID != nil {
+			c.LastMessage = &domain.Message{
+				ID:             *lastMsgID,
+				ConversationID: c.ID,
+				SenderID:       lastMsgSenderID,
+				BodyText:       stringValue(lastMsgBody),
+				CreatedAt:      *lastMsgCreatedAt,
+			}
+			if err := r.openBody(c.LastMessage); err != nil {
+				return nil, err
+			}
+		}
+
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// For DM conversations, fetch the other users in one query
+	var dmIDs []uuid.UUID
+	for _, c := range conversations {
+		if c.Type == domain.ConversationTypeDM && !c.IsSaved {
+			dmIDs = append(dmIDs, c.ID)
+		}
+	}
+	if len(dmIDs) > 0 {
+		others, err := r.getOtherDMUsers(ctx, dmIDs, userID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range conversations {
+			conversations[i].OtherUser = others[conversations[i].ID]
+		}
+	}
+
+	return conversations, nil
+}
+
+// getOtherDMUsers returns the other user of each given DM conversation, keyed
+// by conversation ID. Last seen follows the same rule as GetOtherDMUser.
+func (r *ConversationRepository) getOtherDMUsers(ctx context.Context, convIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]*domain.PublicUser, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT DISTINCT ON (cm.conversation_id)
+		       cm.conversation_id, u.id, u.username, u.display_name, u.avatar_url,
+		       CASE WHEN u.show_online_status THEN u.last_seen_at END
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.conversation_id = ANY($1) AND cm.user_id != $2
+		ORDER BY cm.conversation_id, cm.joined_at
+	`, convIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	others := make(map[uuid.UUID]*domain.PublicUser, len(convIDs))
+	for rows.Next() {
+		var convID uuid.UUID
+		var user domain.PublicUser
+		if err := rows.Scan(&convID, &user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.LastSeenAt); err != nil {
+			return nil, err
+		}
+		others[convID] = &user
+	}
+	return others, rows.Err()
+}
+
+// GetOtherDMUser returns the other user in a DM conversation. Their last seen
+// time is only included if they share their online status.
+func (r *ConversationRepository) GetOtherDMUser(ctx context.Context, convID, userID uuid.UUID) (*domain.PublicUser, error) {
+	var user domain.PublicUser
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT u.id, u.username, u.display_name, u.avatar_url,
+		       CASE WHEN u.show_online_status THEN u.last_seen_at END
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.conversation_id = $1 AND cm.user_id != $2
+		LIMIT 1
+	`, convID, userID).Scan(&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetMessageByID returns a message by ID
+func (r *ConversationRepository) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*domain.Message, error) {
+	var m domain.Message
+	var senderID *uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT id, conversation_id, sender_id, body_text, attachment_id, visible_to, reply_to_id, created_at, edited_at
+		FROM messages WHERE id = $1
+	`, messageID).Scan(&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.AttachmentID, &m.VisibleTo, &m.ReplyToID, &m.CreatedAt, &m.EditedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openBody(&m); err != nil {
+		return nil, err
+	}
+	m.SenderID = senderID
+	return &m, nil
+}
+
+// EditMessage replaces a message's body and stamps edited_at, keeping the
+// body it replaced in message_edits. Returns when the edit was made, or
+// domain.ErrMessageNotFound.
+func (r *ConversationRepository) EditMessage(ctx context.Context, messageID uuid.UUID, newBody string) (time.Time, error) {
+	body, err := r.cipher.Encrypt(newBody)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Previous bodies are kept exactly as stored, so history stays
+	// encrypted whenever the message was
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT body_text FROM messages WHERE id = $1 FOR UPDATE`, messageID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO message_edits (message_id, previous_body)
+		VALUES ($1, $2)
+	`, messageID, previous)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var editedAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE messages SET body_text = $2, edited_at = NOW()
+		WHERE id = $1
+		RETURNING edited_at
+	`, messageID, body).Scan(&editedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return time.Time{}, err
+	}
+	return editedAt, nil
+}
+
+// DeleteMessage deletes a message by ID (soft delete by setting deleted_at if needed, or hard delete for MVP)
+func (r *ConversationRepository) DeleteMessage(ctx context.Context, messageID uuid.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The RETURNING subquery sees the join rows as they were before the cascade
+	var attachmentIDs []uuid.UUID
+	err = tx.QueryRow(ctx, `
+		DELETE FROM messages m WHERE m.id = $1
+		RETURNING ARRAY(SELECT ma.attachment_id FROM message_attachments ma WHERE ma.message_id = m.id)
+	`, messageID).Scan(&attachmentIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := deleteOrphanAttachments(ctx, tx, attachmentIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteMessages deletes the given messages of a conversation in one
+// transaction, returning those that were deleted. IDs from other
+// conversations are skipped.
+func (r *ConversationRepository) DeleteMessages(ctx context.Context, convID uuid.UUID, ids []uuid.UUID) ([]domain.Message, error) {
+	return r.deleteMessagesWhere(ctx, `m.conversation_id = $1 AND m.id = ANY($2)`, convID, ids)
+}
+
+// DeleteMessagesBefore clears a conversation's history sent before the given
+// time, only senderID's messages if it's set, returning those deleted
+func (r *ConversationRepository) DeleteMessagesBefore(ctx context.Context, convID uuid.UUID, before time.Time, senderID *uuid.UUID) ([]domain.Message, error) {
+	return r.deleteMessagesWhere(ctx, `m.conversation_id = $1 AND m.created_at < $2 AND ($3::uuid IS NULL OR m.sender_id = $3)`, convID, before, senderID)
+}
+
+// deleteMessagesWhere deletes the messages matching where, along with any
+// attachments left unreferenced. It returns them with only their ID,
+// conversation and whisper audience set: enough to tell who saw them.
+func (r *ConversationRepository) deleteMessagesWhere(ctx context.Context, where string, args ...interface{}) ([]domain.Message, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	var conversations []domain.Conversation
-	for rows.Next() {
-		var c domain.Conversation
-		var lastMsgID, lastMsgSenderID *uuid.UUID
-		var lastMsgBody *string
-		var lastMsgCreatedAt *time.Time
+	rows, err := tx.Query(ctx, `
+		DELETE FROM messages m WHERE `+where+`
+		RETURNING m.id, m.conversation_id, m.visible_to, ARRAY(SELECT ma.attachment_id FROM message_attachments ma WHERE ma.message_id = m.id)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
 
-		err := rows.Scan(
-			&c.ID, &c.Type, &c.Title,
-			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt,
-			&c.UnreadCount, &c.MemberCount,
-			&lastMsgID, &lastMsgSenderID, &lastMsgBody, &lastMsgCreatedAt,
-		)
-		if err != nil {
+	var deleted []domain.Message
+	var attachmentIDs []uuid.UUID
+	for rows.Next() {
+		var m domain.Message
+		var attachments []uuid.UUID
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.VisibleTo, &attachments); err != nil {
+			rows.Close()
 			return nil, err
 		}
-
-		// Populate last message if exists
-		if lastMsgID != nil {
-			c.LastMessage = &domain.Message{
-				ID:             *lastMsgID,
-				ConversationID: c.ID,
-				SenderID:       lastMsgSenderID,
-				BodyText:       stringValue(lastMsgBody),
-				CreatedAt:      *lastMsgCreatedAt,
-			}
-		}
-
-		conversations = append(conversations, c)
+		deleted = append(deleted, m)
+		attachmentIDs = append(attachmentIDs, attachments...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// For DM conversations, fetch the other user
-	for i := range conversations {
-		if conversations[i].Type == domain.ConversationTypeDM {
-			otherUser, err := r.GetOtherDMUser(ctx, conversations[i].ID, userID)
-			if err == nil && otherUser != nil {
-				conversations[i].OtherUser = otherUser
-			}
-		}
+	if err := deleteOrphanAttachments(ctx, tx, attachmentIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
+	return deleted, nil
+}
 
-	return conversations, rows.Err()
+// deleteOrphanAttachments drops the given attachments once no message
+// references them, so their bytes are released from the uploader's quota and
+// their stored objects queued for the object reaper
+func deleteOrphanAttachments(ctx context.Context, tx pgx.Tx, attachmentIDs []uuid.UUID) error {
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		DELETE FROM attachments a
+		WHERE a.id = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM messages WHERE attachment_id = a.id)
+		AND NOT EXISTS (SELECT 1 FROM message_attachments WHERE attachment_id = a.id)
+	`, attachmentIDs)
+	return err
 }
 
-// GetOtherDMUser returns the other user in a DM conversation
-func (r *ConversationRepository) GetOtherDMUser(ctx context.Context, convID, userID uuid.UUID) (*domain.PublicUser, error) {
-	var user domain.PublicUser
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT u.id, u.username, u.display_name, u.avatar_url
-		FROM conversation_members cm
-		JOIN users u ON u.id = cm.user_id
-		WHERE cm.conversation_id = $1 AND cm.user_id != $2
-		LIMIT 1
-	`, convID, userID).Scan(&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL)
+// SetMessageTTL sets how long new messages in a conversation live, in
+// seconds; 0 turns disappearing messages off. Existing messages keep the
+// expiry they were sent with.
+func (r *ConversationRepository) SetMessageTTL(ctx context.Context, convID uuid.UUID, seconds int) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE conversations
+		SET message_ttl_seconds = NULLIF($2, 0), updated_at = NOW()
+		WHERE id = $1
+	`, convID, seconds)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &user, nil
+	if result.RowsAffected() == 0 {
+		return domain.ErrConversationNotFound
+	}
+	return nil
 }
 
-// GetMessageByID returns a message by ID
-func (r *ConversationRepository) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*domain.Message, error) {
-	var m domain.Message
-	var senderID *uuid.UUID
-	err := r.db.Pool.QueryRow(ctx, `
-		SELECT id, conversation_id, sender_id, body_text, created_at
-		FROM messages WHERE id = $1
-	`, messageID).Scan(&m.ID, &m.ConversationID, &senderID, &m.BodyText, &m.CreatedAt)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, domain.ErrMessageNotFound
-	}
+// DeleteExpiredMessages deletes every disappearing message whose expiry is at
+// or before now and returns them. Attachments left unreferenced are dropped,
+// as in DeleteMessage.
+func (r *ConversationRepository) DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.Message, error) {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	m.SenderID = senderID
-	return &m, nil
-}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-// DeleteMessage deletes a message by ID (soft delete by setting deleted_at if needed, or hard delete for MVP)
-func (r *ConversationRepository) DeleteMessage(ctx context.Context, messageID uuid.UUID) error {
-	result, err := r.db.Pool.Exec(ctx, `DELETE FROM messages WHERE id = $1`, messageID)
+	rows, err := tx.Query(ctx, `
+		DELETE FROM messages m
+		WHERE m.expires_at IS NOT NULL AND m.expires_at <= $1
+		RETURNING m.id, m.conversation_id, m.sender_id, m.attachment_id, m.visible_to, m.created_at, m.expires_at,
+		          ARRAY(SELECT ma.attachment_id FROM message_attachments ma WHERE ma.message_id = m.id ORDER BY ma.position)
+	`, now)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if result.RowsAffected() == 0 {
-		return domain.ErrMessageNotFound
+
+	var expired []domain.Message
+	var attachmentIDs []uuid.UUID
+	for rows.Next() {
+		var m domain.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.AttachmentID, &m.VisibleTo, &m.CreatedAt, &m.ExpiresAt, &m.AttachmentIDs); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		attachmentIDs = append(attachmentIDs, m.AttachmentIDs...)
+		expired = append(expired, m)
 	}
-	return nil
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := deleteOrphanAttachments(ctx, tx, attachmentIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return expired, nil
 }
 
 // =============================================================================
@@ -842,6 +2836,49 @@ func (r *ConversationRepository) MarkConversationMessagesRead(ctx context.Contex
 	return messageIDs, rows.Err()
 }
 
+// MarkMessagesReadUpTo marks every message in the conversation sent by others,
+// up to and including upToID, as read in one statement. Returns only the
+// message IDs that were newly marked.
+func (r *ConversationRepository) MarkMessagesReadUpTo(ctx context.Context, conversationID, userID, upToID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		WITH cursor AS (
+			SELECT created_at FROM messages WHERE id = $3 AND conversation_id = $1
+		),
+		messages_to_mark AS (
+			SELECT m.id FROM messages m
+			JOIN cursor c ON m.created_at <= c.created_at
+			LEFT JOIN message_receipts mr ON mr.message_id = m.id AND mr.user_id = $2
+			WHERE m.conversation_id = $1
+			  AND m.sender_id IS DISTINCT FROM $2
+			  AND mr.read_at IS NULL
+		),
+		inserted AS (
+			INSERT INTO message_receipts (message_id, user_id, delivered_at, read_at)
+			SELECT id, $2, NOW(), NOW() FROM messages_to_mark
+			ON CONFLICT (message_id, user_id)
+			DO UPDATE SET
+				delivered_at = COALESCE(message_receipts.delivered_at, NOW()),
+				read_at = COALESCE(message_receipts.read_at, NOW())
+			RETURNING message_id
+		)
+		SELECT message_id FROM inserted
+	`, conversationID, userID, upToID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messageIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		messageIDs = append(messageIDs, id)
+	}
+	return messageIDs, rows.Err()
+}
+
 // GetMessageReceipts retrieves all receipts for a message
 func (r *ConversationRepository) GetMessageReceipts(ctx context.Context, messageID uuid.UUID) ([]domain.MessageReceipt, error) {
 	rows, err := r.db.Pool.Query(ctx, `
@@ -889,41 +2926,290 @@ func (r *ConversationRepository) GetMessageReceiptStatus(ctx context.Context, me
 	return "sent", nil
 }
 
-// GetMessageReceiptStatuses returns receipt status for multiple messages at once
-func (r *ConversationRepository) GetMessageReceiptStatuses(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+// GetMessageReceiptStatuses returns, for multiple messages at once, how many
+// recipients each has been delivered to and read by out of how many can see
+// it: the whisper audience, or the conversation's current members, less the
+// sender. A read receipt counts as delivered too.
+func (r *ConversationRepository) GetMessageReceiptStatuses(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]domain.ReceiptStatus, error) {
 	if len(messageIDs) == 0 {
-		return make(map[uuid.UUID]string), nil
+		return make(map[uuid.UUID]domain.ReceiptStatus), nil
 	}
 
 	rows, err := r.db.Pool.Query(ctx, `
-		SELECT 
-			m.id,
-			COUNT(mr.delivered_at) as delivered_count,
-			COUNT(mr.read_at) as read_count
-		FROM unnest($1::uuid[]) AS m(id)
-		LEFT JOIN message_receipts mr ON mr.message_id = m.id
-		GROUP BY m.id
+		SELECT
+			msg.id,
+			COUNT(COALESCE(mr.delivered_at, mr.read_at)) as delivered_count,
+			COUNT(mr.read_at) as read_count,
+			CASE WHEN msg.visible_to IS NOT NULL
+				THEN cardinality(msg.visible_to) - 1
+				ELSE (SELECT COUNT(*) FROM conversation_members cm
+				      WHERE cm.conversation_id = msg.conversation_id
+				        AND cm.user_id IS DISTINCT FROM msg.sender_id)
+			END as recipient_count
+		FROM messages msg
+		LEFT JOIN message_receipts mr ON mr.message_id = msg.id AND mr.user_id IS DISTINCT FROM msg.sender_id
+		WHERE msg.id = ANY($1)
+		GROUP BY msg.id
 	`, messageIDs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	result := make(map[uuid.UUID]string)
+	result := make(map[uuid.UUID]domain.ReceiptStatus)
 	for rows.Next() {
 		var id uuid.UUID
-		var deliveredCount, readCount int
-		if err := rows.Scan(&id, &deliveredCount, &readCount); err != nil {
+		var s domain.ReceiptStatus
+		if err := rows.Scan(&id, &s.Delivered, &s.Read, &s.Recipients); err != nil {
 			return nil, err
 		}
+		// Receipts from members who have since left still count
+		s.Recipients = max(s.Recipients, s.Delivered)
+		result[id] = s
+	}
+	return result, rows.Err()
+}
+
+// GetStats returns the counters maintained by the messages stats trigger.
+// Per-member counts are only loaded when includeMembers is set.
+func (r *ConversationRepository) GetStats(ctx context.Context, convID uuid.UUID, includeMembers bool) (*domain.ConversationStats, error) {
+	stats := &domain.ConversationStats{}
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT message_count, attachment_count
+		FROM conversation_stats WHERE conversation_id = $1
+	`, convID).Scan(&stats.MessageCount, &stats.AttachmentCount)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if !includeMembers {
+		return stats, nil
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT user_id, message_count
+		FROM conversation_member_stats
+		WHERE conversation_id = $1 AND message_count > 0
+	`, convID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		if readCount > 0 {
-			result[id] = "read"
-		} else if deliveredCount > 0 {
-			result[id] = "delivered"
-		} else {
-			result[id] = "sent"
+	stats.MemberMessageCounts = make(map[uuid.UUID]int64)
+	for rows.Next() {
+		var userID uuid.UUID
+		var count int64
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
 		}
+		stats.MemberMessageCounts[userID] = count
 	}
-	return result, rows.Err()
+	return stats, rows.Err()
+}
+
+// GetInsights returns size and activity figures for a conversation. Message,
+// attachment and per-sender counts come from the stats counters; attachment
+// bytes and active days are computed from the attachments and messages indexes.
+func (r *ConversationRepository) GetInsights(ctx context.Context, convID uuid.UUID) (*domain.ConversationInsights, error) {
+	stats, err := r.GetStats(ctx, convID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberCount, activeDays int
+	var attachmentBytes int64
+	err = r.db.Pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM conversation_members WHERE conversation_id = $1),
+			(SELECT COALESCE(SUM(size_bytes), 0) FROM attachments WHERE conversation_id = $1 AND status = 'ready'),
+			(SELECT COUNT(DISTINCT date_trunc('day', created_at)) FROM messages WHERE conversation_id = $1)
+	`, convID).Scan(&memberCount, &attachmentBytes, &activeDays)
+	if err != nil {
+		return nil, err
+	}
+
+	insights := domain.NewConversationInsights(convID, stats, memberCount, attachmentBytes, activeDays)
+	if len(insights.TopSenders) == 0 {
+		return insights, nil
+	}
+
+	ids := make([]uuid.UUID, len(insights.TopSenders))
+	for i, s := range insights.TopSenders {
+		ids[i] = s.UserID
+	}
+	rows, err := r.db.Pool.Query(ctx, `SELECT id, username FROM users WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usernames := make(map[uuid.UUID]string, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		usernames[id] = username
+	}
+	for i := range insights.TopSenders {
+		insights.TopSenders[i].Username = usernames[insights.TopSenders[i].UserID]
+	}
+	return insights, rows.Err()
+}
+
+// ============================================================================
+// Scheduled Messages
+// ============================================================================
+
+// ScheduleMessage stores a message to be sent at s.SendAt. Returns
+// domain.ErrTooManyScheduledMessages if the sender already has
+// domain.MaxPendingScheduledMessages waiting.
+func (r *ConversationRepository) ScheduleMessage(ctx context.Context, s *domain.ScheduledMessage) error {
+	body, err := r.cipher.Encrypt(s.BodyText)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Lock the sender so concurrent requests can't both slip under the cap
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, s.SenderID); err != nil {
+		return err
+	}
+	var pending int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM scheduled_messages WHERE sender_id = $1
+	`, s.SenderID).Scan(&pending); err != nil {
+		return err
+	}
+	if pending >= domain.MaxPendingScheduledMessages {
+		return domain.ErrTooManyScheduledMessages
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO scheduled_messages (id, conversation_id, sender_id, body_text, send_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, s.ID, s.ConversationID, s.SenderID, body, s.SendAt).Scan(&s.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListScheduledMessages returns a user's pending messages in a conversation,
+// soonest first
+func (r *ConversationRepository) ListScheduledMessages(ctx context.Context, convID, senderID uuid.UUID) ([]domain.ScheduledMessage, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT s.id, s.conversation_id, s.sender_id, u.username, s.body_text, s.send_at, s.created_at
+		FROM scheduled_messages s
+		JOIN users u ON u.id = s.sender_id
+		WHERE s.conversation_id = $1 AND s.sender_id = $2
+		ORDER BY s.send_at, s.id
+	`, convID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanScheduledMessages(rows)
+}
+
+// CancelScheduledMessage deletes a pending message; only its sender may
+func (r *ConversationRepository) CancelScheduledMessage(ctx context.Context, id, senderID uuid.UUID) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM scheduled_messages WHERE id = $1 AND sender_id = $2
+	`, id, senderID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrScheduledMessageNotFound
+	}
+	return nil
+}
+
+// scheduledClaimLease is how long a claimed scheduled message is left to its
+// dispatcher before another may claim it again
+const scheduledClaimLease = 5 * time.Minute
+
+// ClaimDueScheduledMessages leases and returns every scheduled message due at
+// or before now. Messages stay stored until SendScheduledMessage posts them,
+// so one whose post fails is claimed again once its lease runs out. Rows
+// locked or leased by another instance are skipped, so each message is
+// claimed once at a time.
+func (r *ConversationRepository) ClaimDueScheduledMessages(ctx context.Context, now time.Time) ([]domain.ScheduledMessage, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM scheduled_messages
+			WHERE send_at <= $1 AND (claimed_until IS NULL OR claimed_until <= $1)
+			ORDER BY send_at
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE scheduled_messages s SET claimed_until = $2
+		FROM due, users u
+		WHERE s.id = due.id AND u.id = s.sender_id
+		RETURNING s.id, s.conversation_id, s.sender_id, u.username, s.body_text, s.send_at, s.created_at
+	`, now, now.Add(scheduledClaimLease))
+	if err != nil {
+		return nil, err
+	}
+	return r.scanScheduledMessages(rows)
+}
+
+// SendScheduledMessage posts msg and removes the scheduled message it came
+// from in one transaction, so a failed post leaves it scheduled. Returns
+// domain.ErrScheduledMessageNotFound without posting if it was cancelled or
+// sent in the meantime.
+func (r *ConversationRepository) SendScheduledMessage(ctx context.Context, scheduledID uuid.UUID, msg *domain.Message) error {
+	body, err := r.cipher.Encrypt(msg.BodyText)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, `DELETE FROM scheduled_messages WHERE id = $1`, scheduledID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrScheduledMessageNotFound
+	}
+	if err := insertMessage(ctx, tx, msg, body); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE conversations SET updated_at = NOW() WHERE id = $1
+	`, msg.ConversationID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *ConversationRepository) scanScheduledMessages(rows pgx.Rows) ([]domain.ScheduledMessage, error) {
+	defer rows.Close()
+
+	var scheduled []domain.ScheduledMessage
+	for rows.Next() {
+		var s domain.ScheduledMessage
+		if err := rows.Scan(&s.ID, &s.ConversationID, &s.SenderID, &s.SenderUsername, &s.BodyText, &s.SendAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		body, err := r.cipher.Decrypt(s.BodyText)
+		if err != nil {
+			return nil, err
+		}
+		s.BodyText = body
+		scheduled = append(scheduled, s)
+	}
+	return scheduled, rows.Err()
 }
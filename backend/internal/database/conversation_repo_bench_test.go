@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// queryCounter is a pgx tracer that counts the queries sent to Postgres
+type queryCounter struct {
+	n atomic.Int64
+}
+
+func (c *queryCounter) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	c.n.Add(1)
+	return ctx
+}
+
+func (c *queryCounter) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// openBenchDB connects to TEST_DATABASE_URL with a query counter attached,
+// skipping the benchmark when no database is configured
+func openBenchDB(b *testing.B) (*DB, *queryCounter) {
+	b.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	counter := &queryCounter{}
+	config.ConnConfig.Tracer = counter
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	db := &DB{Pool: pool}
+	b.Cleanup(db.Close)
+
+	if err := EnsureSchema(ctx, db, "../../migrations"); err != nil {
+		b.Fatal(err)
+	}
+	return db, counter
+}
+
+// seedDMs creates a user with dmCount DMs, each with a different partner
+func seedDMs(b *testing.B, db *DB, dmCount int) uuid.UUID {
+	b.Helper()
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	convs := NewConversationRepository(db)
+
+	newUser := func() uuid.UUID {
+		id := uuid.New()
+		name := "bench_" + id.String()[:8]
+		user := &domain.User{ID: id, Username: name, Email: name + "@example.com"}
+		if err := users.Create(ctx, user, "x"); err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		return id
+	}
+
+	me := newUser()
+	for i := 0; i < dmCount; i++ {
+		conv := &domain.Conversation{ID: uuid.New(), Type: domain.ConversationTypeDM}
+		if err := convs.Create(ctx, conv, []uuid.UUID{me, newUser()}); err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM conversations WHERE id = $1`, conv.ID) })
+	}
+	return me
+}
+
+// BenchmarkGetUserConversationsWithDetails_DMs checks that listing stays at a
+// constant number of queries however many DMs the user has
+func BenchmarkGetUserConversationsWithDetails_DMs(b *testing.B) {
+	db, counter := openBenchDB(b)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+
+	for _, dmCount := range []int{10, 100} {
+		b.Run(fmt.Sprintf("dms=%d", dmCount), func(b *testing.B) {
+			userID := seedDMs(b, db, dmCount)
+
+			counter.n.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				list, err := convs.GetUserConversationsWithDetails(ctx, userID)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(list) != dmCount || list[0].OtherUser == nil {
+					b.Fatalf("expected %d DMs with their other users, got %d", dmCount, len(list))
+				}
+			}
+			b.StopTimer()
+
+			perOp := float64(counter.n.Load()) / float64(b.N)
+			b.ReportMetric(perOp, "queries/op")
+			if perOp > 2 {
+				b.Fatalf("listing ran %.0f queries per call; DM partners should be fetched in one batch", perOp)
+			}
+		})
+	}
+}
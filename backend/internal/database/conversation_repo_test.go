@@ -0,0 +1,438 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrCreateSavedConversation_CreatedOnceThenReused(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+
+	first, err := convs.GetOrCreateSavedConversation(ctx, userID)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM conversations WHERE id = $1`, first.ID) })
+	assert.True(t, first.IsSaved)
+	require.Len(t, first.Members, 1)
+	assert.Equal(t, userID, first.Members[0].UserID)
+
+	second, err := convs.GetOrCreateSavedConversation(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	var count int
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM conversations WHERE saved_for = $1`, userID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestGetStats_CountersFollowSendAndDelete(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	attachments := NewAttachmentRepository(db.Pool)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob)
+
+	att := newTestAttachment(alice, convID, 10)
+	require.NoError(t, attachments.CreateAttachment(ctx, att))
+	attID := uuid.MustParse(att.ID)
+	send := func(sender uuid.UUID, attachmentID *uuid.UUID) uuid.UUID {
+		msg := &domain.Message{
+			ID:             uuid.New(),
+			ConversationID: convID,
+			SenderID:       &sender,
+			BodyText:       "hello",
+			AttachmentID:   attachmentID,
+			CreatedAt:      time.Now(),
+		}
+		require.NoError(t, convs.CreateMessage(ctx, msg))
+		return msg.ID
+	}
+	withAttachment := send(alice, &attID)
+	send(alice, nil)
+	fromBob := send(bob, nil)
+
+	stats, err := convs.GetStats(ctx, convID, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.MessageCount)
+	assert.Equal(t, int64(1), stats.AttachmentCount)
+	assert.Equal(t, map[uuid.UUID]int64{alice: 2, bob: 1}, stats.MemberMessageCounts)
+
+	require.NoError(t, convs.DeleteMessage(ctx, withAttachment))
+	require.NoError(t, convs.DeleteMessage(ctx, fromBob))
+	_, _ = db.Pool.Exec(ctx, `DELETE FROM storage_deletions WHERE object_key = $1`, att.ObjectKey)
+
+	stats, err = convs.GetStats(ctx, convID, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.MessageCount)
+	assert.Equal(t, int64(0), stats.AttachmentCount)
+	assert.Equal(t, map[uuid.UUID]int64{alice: 1}, stats.MemberMessageCounts)
+}
+
+func TestGetMessages_SeqOrdersMessagesSentTogether(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, userID)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	batch := make([]domain.Message, 3)
+	for i := range batch {
+		batch[i] = domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &userID, BodyText: "msg", CreatedAt: now}
+	}
+	require.NoError(t, convs.CreateMessages(ctx, batch))
+	assert.Less(t, batch[0].Seq, batch[1].Seq)
+	assert.Less(t, batch[1].Seq, batch[2].Seq)
+
+	// Newest first, in insertion order despite the shared timestamp
+	page, err := convs.GetMessages(ctx, convID, userID, nil, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, batch[2].ID, page[0].ID)
+	assert.Equal(t, batch[1].ID, page[1].ID)
+
+	// Paging from the middle of the batch doesn't skip the rest of it
+	oldest := page[len(page)-1]
+	page, err = convs.GetMessages(ctx, convID, userID, &oldest.CreatedAt, &oldest.Seq, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, batch[0].ID, page[0].ID)
+}
+
+func TestGetUnreadState_MatchesConversationList(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	carol := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob, carol)
+
+	// Stamp messages well clear of the database clock, which sets the read cursor
+	before, after := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	send := func(sender uuid.UUID, visibleTo []uuid.UUID, at time.Time) {
+		msg := &domain.Message{
+			ID:             uuid.New(),
+			ConversationID: convID,
+			SenderID:       &sender,
+			BodyText:       "hello",
+			VisibleTo:      visibleTo,
+			CreatedAt:      at,
+		}
+		require.NoError(t, convs.CreateMessage(ctx, msg))
+	}
+	send(bob, nil, before)
+	send(bob, nil, before)
+	require.NoError(t, convs.MarkConversationRead(ctx, convID, alice, nil))
+
+	send(bob, nil, after)
+	send(carol, nil, after)
+	send(alice, nil, after)                       // own messages never count
+	send(bob, []uuid.UUID{bob, carol}, after)     // nor whispers alice can't see
+	send(carol, []uuid.UUID{carol, alice}, after) // but whispers to her do
+
+	state, err := convs.GetUnreadState(ctx, convID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 3, state.UnreadCount)
+	assert.NotNil(t, state.LastReadAt)
+
+	list, err := convs.GetUserConversationsWithDetails(ctx, alice)
+	require.NoError(t, err)
+	var found bool
+	for _, c := range list {
+		if c.ID == convID {
+			found = true
+			assert.Equal(t, state.UnreadCount, c.UnreadCount)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestPinMessage_RejectsWhispersAndPinsHideThem(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	carol := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob, carol)
+
+	whisper := &domain.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       &alice,
+		BodyText:       "just between us",
+		VisibleTo:      []uuid.UUID{alice, bob},
+		CreatedAt:      time.Now(),
+	}
+	require.NoError(t, convs.CreateMessage(ctx, whisper))
+
+	_, err := convs.PinMessage(ctx, convID, whisper.ID, alice, nil, 0)
+	assert.ErrorIs(t, err, domain.ErrCannotPinWhisper)
+
+	// A pin left over from before whispers were refused stays hidden from
+	// members outside the whisper
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO pinned_messages (conversation_id, message_id, pinned_by, position) VALUES ($1, $2, $3, 0)
+	`, convID, whisper.ID, alice)
+	require.NoError(t, err)
+
+	pins, err := convs.GetPinnedMessages(ctx, convID, carol)
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+
+	pins, err = convs.GetPinnedMessages(ctx, convID, bob)
+	require.NoError(t, err)
+	assert.Len(t, pins, 1)
+}
+
+func TestUpdateTitle_StoresTitlesLongInCodePoints(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice)
+
+	// 100 visible characters, each a family emoji of several code points
+	title := strings.Repeat("👨‍👩‍👧", domain.MaxTitleLength)
+	require.NoError(t, domain.CheckTextLength(title, domain.MaxTitleLength))
+	require.NoError(t, convs.UpdateTitle(ctx, convID, title))
+
+	conv, err := convs.GetByID(ctx, convID)
+	require.NoError(t, err)
+	assert.Equal(t, title, conv.Title)
+}
+
+func TestSearchStarredMessages_OnlyReturnsCallersStars(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob)
+
+	mine := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &bob, BodyText: "the teapot is on", CreatedAt: time.Now()}
+	theirs := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &alice, BodyText: "another teapot please", CreatedAt: time.Now()}
+	unstarred := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &alice, BodyText: "teapot spotted", CreatedAt: time.Now()}
+	for _, m := range []*domain.Message{mine, theirs, unstarred} {
+		require.NoError(t, convs.CreateMessage(ctx, m))
+	}
+	require.NoError(t, convs.StarMessage(ctx, alice, mine.ID))
+	require.NoError(t, convs.StarMessage(ctx, bob, theirs.ID))
+
+	hits, err := convs.SearchStarredMessages(ctx, alice, "teapot", 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 1, "another member's stars and unstarred matches are left out")
+	assert.Equal(t, mine.ID, hits[0].ID)
+}
+
+func TestCreateDMWithMessage_ConcurrentFirstMessagesShareOneDM(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+
+	errs := make(chan error, 2)
+	for _, pair := range [][2]uuid.UUID{{alice, bob}, {bob, alice}} {
+		sender := pair[0]
+		conv := &domain.Conversation{ID: uuid.New(), Type: domain.ConversationTypeDM, CreatedBy: &sender}
+		t.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM conversations WHERE id = $1`, conv.ID) })
+		msg := &domain.Message{ID: uuid.New(), ConversationID: conv.ID, SenderID: &sender, BodyText: "hi", CreatedAt: time.Now()}
+		members := pair[:]
+		go func() { errs <- convs.CreateDMWithMessage(ctx, conv, members, msg) }()
+	}
+
+	var created, existed int
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, domain.ErrDMExists):
+			existed++
+		default:
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 1, existed)
+}
+
+func TestLeaveGroup_LastAdminLeavingPromotesInSameTransaction(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	carol := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob, carol)
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE conversation_members SET role = CASE WHEN user_id = $2 THEN 'admin' ELSE 'member' END,
+		       joined_at = NOW() - CASE WHEN user_id = $3 THEN interval '2 hours' ELSE interval '1 hour' END
+		WHERE conversation_id = $1
+	`, convID, alice, bob)
+	require.NoError(t, err)
+
+	promoted, err := convs.LeaveGroup(ctx, convID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, bob, promoted, "the longest-standing member takes over")
+
+	role, err := convs.GetMemberRole(ctx, convID, bob)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MemberRoleAdmin, role)
+
+	_, err = convs.LeaveGroup(ctx, convID, alice)
+	assert.ErrorIs(t, err, domain.ErrNotMember)
+
+	promoted, err = convs.LeaveGroup(ctx, convID, carol)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, promoted, "members leaving never promote")
+}
+
+func TestSearchMessages_CursorSurvivesDeletedHit(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice)
+
+	// Identical bodies rank the same, so they come newest first
+	sent := time.Now().Add(-time.Hour)
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		msg := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &alice, BodyText: "kettle on", CreatedAt: sent.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, convs.CreateMessage(ctx, msg))
+		ids = append(ids, msg.ID)
+	}
+
+	first, hasMore, err := convs.SearchMessages(ctx, convID, alice, "kettle", domain.SearchFilter{}, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	assert.True(t, hasMore)
+	assert.Equal(t, ids[2], first[0].ID)
+
+	// The hit the cursor names is deleted before the next page is fetched
+	require.NoError(t, convs.DeleteMessage(ctx, first[0].ID))
+
+	cursor := &domain.SearchCursor{BeforeRank: first[0].Rank, BeforeCreatedAt: first[0].CreatedAt, BeforeID: first[0].ID}
+	rest, hasMore, err := convs.SearchMessages(ctx, convID, alice, "kettle", domain.SearchFilter{}, cursor, 10)
+	require.NoError(t, err)
+	assert.False(t, hasMore)
+	require.Len(t, rest, 2, "equal-rank hits after the deleted one aren't skipped")
+	assert.Equal(t, []uuid.UUID{ids[1], ids[0]}, []uuid.UUID{rest[0].ID, rest[1].ID})
+}
+
+func TestScheduledMessages_StayStoredUntilSentAndAreCapped(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice)
+
+	now := time.Now()
+	s := &domain.ScheduledMessage{ID: uuid.New(), ConversationID: convID, SenderID: alice, BodyText: "later", SendAt: now.Add(time.Minute)}
+	require.NoError(t, convs.ScheduleMessage(ctx, s))
+
+	due, err := convs.ClaimDueScheduledMessages(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	due, err = convs.ClaimDueScheduledMessages(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, due, "a leased message isn't claimed twice")
+
+	pending, err := convs.ListScheduledMessages(ctx, convID, alice)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "claiming doesn't delete the message")
+
+	msg := s.ToMessage(now)
+	require.NoError(t, convs.SendScheduledMessage(ctx, s.ID, msg))
+	assert.ErrorIs(t, convs.SendScheduledMessage(ctx, s.ID, s.ToMessage(now)), domain.ErrScheduledMessageNotFound, "sent once")
+	pending, err = convs.ListScheduledMessages(ctx, convID, alice)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO scheduled_messages (id, conversation_id, sender_id, body_text, send_at)
+		SELECT gen_random_uuid(), $1, $2, 'filler', NOW() + interval '1 day' FROM generate_series(1, $3)
+	`, convID, alice, domain.MaxPendingScheduledMessages)
+	require.NoError(t, err)
+	over := &domain.ScheduledMessage{ID: uuid.New(), ConversationID: convID, SenderID: alice, BodyText: "one more", SendAt: now.Add(time.Hour)}
+	assert.ErrorIs(t, convs.ScheduleMessage(ctx, over), domain.ErrTooManyScheduledMessages)
+}
+
+func TestGetMembers_PagesInJoinOrderPastDepartedMember(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice, bob, carol := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob, carol)
+
+	joined := time.Now().Add(-time.Hour)
+	for i, id := range []uuid.UUID{alice, bob, carol} {
+		_, err := db.Pool.Exec(ctx, `UPDATE conversation_members SET joined_at = $3 WHERE conversation_id = $1 AND user_id = $2`,
+			convID, id, joined.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	first, hasMore, err := convs.GetMembers(ctx, convID, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.True(t, hasMore)
+	assert.Equal(t, []uuid.UUID{alice, bob}, []uuid.UUID{first[0].UserID, first[1].UserID}, "earliest to join come first")
+
+	// The member the cursor names leaves before the next page is fetched
+	require.NoError(t, convs.RemoveMember(ctx, convID, bob))
+
+	cursor := domain.NextMemberCursor(first)
+	rest, hasMore, err := convs.GetMembers(ctx, convID, 2, &cursor)
+	require.NoError(t, err)
+	assert.False(t, hasMore)
+	require.Len(t, rest, 1)
+	assert.Equal(t, carol, rest[0].UserID)
+}
+
+func TestUpdateMemberRole_ConcurrentDemotionsKeepAnAdmin(t *testing.T) {
+	db := openTestDB(t)
+	convs := NewConversationRepository(db)
+	ctx := context.Background()
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	convID := createTestConversation(t, db, domain.ConversationTypeGroup, alice, bob)
+	_, err := db.Pool.Exec(ctx, `UPDATE conversation_members SET role = 'admin' WHERE conversation_id = $1`, convID)
+	require.NoError(t, err)
+
+	errs := make(chan error, 2)
+	for _, id := range []uuid.UUID{alice, bob} {
+		go func() { errs <- convs.UpdateMemberRole(ctx, convID, id, domain.MemberRoleMember) }()
+	}
+
+	var demoted, refused int
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			demoted++
+		case errors.Is(err, domain.ErrCannotRemoveAdmin):
+			refused++
+		default:
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, 1, demoted)
+	assert.Equal(t, 1, refused, "the second demotion sees the first and keeps the last admin")
+
+	assert.ErrorIs(t, convs.UpdateMemberRole(ctx, convID, uuid.New(), domain.MemberRoleMember), domain.ErrNotMember)
+}
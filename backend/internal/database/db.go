@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -35,8 +36,11 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
-// New creates a new database connection pool
-func New(ctx context.Context, databaseURL string) (*DB, error) {
+// New creates a new database connection pool.
+// statementTimeout is applied server-side to every session so runaway queries
+// are aborted by Postgres even if the caller's context has no deadline; zero disables it.
+// EnsureSchema lifts it for migrations.
+func New(ctx context.Context, databaseURL string, statementTimeout time.Duration) (*DB, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse database URL: %w", err)
@@ -49,6 +53,10 @@ func New(ctx context.Context, databaseURL string) (*DB, error) {
 	config.MaxConnIdleTime = 30 * time.Minute
 	config.HealthCheckPeriod = time.Minute
 
+	if statementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	}
+
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("create connection pool: %w", err)
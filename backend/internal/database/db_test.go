@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB connects to TEST_DATABASE_URL and applies the migrations,
+// skipping the test when no database is configured
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &DB{Pool: pool}
+	t.Cleanup(db.Close)
+
+	if err := EnsureSchema(ctx, db, "../../migrations"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createTestUser adds a user that is deleted again when the test ends
+func createTestUser(t *testing.T, db *DB) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	id := uuid.New()
+	name := "test_" + id.String()[:8]
+	user := &domain.User{ID: id, Username: name, Email: name + "@example.com"}
+	if err := NewUserRepository(db).Create(ctx, user, "x"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
+
+// createTestConversation creates a conversation between members, deleted
+// again when the test ends
+func createTestConversation(t *testing.T, db *DB, convType domain.ConversationType, members ...uuid.UUID) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	conv := &domain.Conversation{ID: uuid.New(), Type: convType, Title: "test"}
+	if convType == domain.ConversationTypeDM {
+		conv.Title = ""
+	}
+	if err := NewConversationRepository(db).Create(ctx, conv, members); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = db.Pool.Exec(ctx, `DELETE FROM conversations WHERE id = $1`, conv.ID) })
+	return conv.ID
+}
+
+func TestQuery_CancelledContextAbortsQuery(t *testing.T) {
+	db := openTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := db.Pool.Exec(ctx, `SELECT pg_sleep(10)`)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "cancelling should abort the running query")
+}
+
+func TestNew_StatementTimeoutAbortsQuery(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := New(context.Background(), url, 50*time.Millisecond)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	_, err = db.Pool.Exec(context.Background(), `SELECT pg_sleep(10)`)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "57014", pgErr.Code, "query_canceled")
+}
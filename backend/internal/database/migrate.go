@@ -99,6 +99,15 @@ func EnsureSchema(ctx context.Context, db *DB, migrationsDir string) error {
 			return fmt.Errorf("begin transaction: %w", err)
 		}
 
+		// Migrations backfilling large tables can outlast the pool's
+		// statement_timeout, which is meant for queries at runtime
+		if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				slog.Error("rollback failed", "error", rbErr)
+			}
+			return fmt.Errorf("disable statement timeout for %s: %w", file, err)
+		}
+
 		if _, err := tx.Exec(ctx, string(content)); err != nil {
 			if rbErr := tx.Rollback(ctx); rbErr != nil {
 				slog.Error("rollback failed", "error", rbErr)
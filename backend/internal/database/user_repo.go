@@ -55,13 +55,15 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 	user := &domain.User{}
 	err := r.db.Pool.QueryRow(ctx, `
 		SELECT id, username, email, display_name, avatar_url, 
-		       show_online_status, read_receipts_enabled, last_seen_at,
+		       show_online_status, read_receipts_enabled, auto_accept_calls, last_seen_at,
+		       notification_sound, message_previews,
 		       created_at, updated_at
 		FROM users WHERE id = $1
 	`, id).Scan(
 		&user.ID, &user.Username, &user.Email,
 		&user.DisplayName, &user.AvatarURL,
-		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.LastSeenAt,
+		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.AutoAcceptCalls, &user.LastSeenAt,
+		&user.NotificationSound, &user.MessagePreviews,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -75,13 +77,15 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	user := &domain.User{}
 	err := r.db.Pool.QueryRow(ctx, `
 		SELECT id, username, email, display_name, avatar_url,
-		       show_online_status, read_receipts_enabled, last_seen_at,
+		       show_online_status, read_receipts_enabled, auto_accept_calls, last_seen_at,
+		       notification_sound, message_previews,
 		       created_at, updated_at
 		FROM users WHERE email = $1
 	`, email).Scan(
 		&user.ID, &user.Username, &user.Email,
 		&user.DisplayName, &user.AvatarURL,
-		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.LastSeenAt,
+		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.AutoAcceptCalls, &user.LastSeenAt,
+		&user.NotificationSound, &user.MessagePreviews,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -95,13 +99,15 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*d
 	user := &domain.User{}
 	err := r.db.Pool.QueryRow(ctx, `
 		SELECT id, username, email, display_name, avatar_url,
-		       show_online_status, read_receipts_enabled, last_seen_at,
+		       show_online_status, read_receipts_enabled, auto_accept_calls, last_seen_at,
+		       notification_sound, message_previews,
 		       created_at, updated_at
 		FROM users WHERE username = $1
 	`, username).Scan(
 		&user.ID, &user.Username, &user.Email,
 		&user.DisplayName, &user.AvatarURL,
-		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.LastSeenAt,
+		&user.ShowOnlineStatus, &user.ReadReceiptsEnabled, &user.AutoAcceptCalls, &user.LastSeenAt,
+		&user.NotificationSound, &user.MessagePreviews,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -122,6 +128,20 @@ func (r *UserRepository) GetPasswordHash(ctx context.Context, userID uuid.UUID)
 	return hash, err
 }
 
+// UpdatePasswordHash replaces a user's password hash
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE credentials SET password_hash = $2 WHERE user_id = $1
+	`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
 // EmailExists checks if email is already registered
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
 	var exists bool
@@ -170,6 +190,72 @@ func (r *UserRepository) SearchByUsername(ctx context.Context, query string, lim
 	return users, rows.Err()
 }
 
+// GetByIDs fetches users by ID; unknown IDs are skipped
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, username, email, display_name, avatar_url,
+		       show_online_status, read_receipts_enabled, auto_accept_calls, last_seen_at,
+		       notification_sound, message_previews,
+		       created_at, updated_at
+		FROM users
+		WHERE id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []domain.User
+	for rows.Next() {
+		var u domain.User
+		err := rows.Scan(
+			&u.ID, &u.Username, &u.Email,
+			&u.DisplayName, &u.AvatarURL,
+			&u.ShowOnlineStatus, &u.ReadReceiptsEnabled, &u.AutoAcceptCalls, &u.LastSeenAt,
+			&u.NotificationSound, &u.MessagePreviews,
+			&u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetByUsernames fetches users by username; unknown names are skipped
+func (r *UserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]domain.User, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, username, email, display_name, avatar_url,
+		       show_online_status, read_receipts_enabled, auto_accept_calls, last_seen_at,
+		       notification_sound, message_previews,
+		       created_at, updated_at
+		FROM users
+		WHERE username = ANY($1)
+	`, usernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []domain.User
+	for rows.Next() {
+		var u domain.User
+		err := rows.Scan(
+			&u.ID, &u.Username, &u.Email,
+			&u.DisplayName, &u.AvatarURL,
+			&u.ShowOnlineStatus, &u.ReadReceiptsEnabled, &u.AutoAcceptCalls, &u.LastSeenAt,
+			&u.NotificationSound, &u.MessagePreviews,
+			&u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
 // Update updates user profile fields
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	_, err := r.db.Pool.Exec(ctx, `
@@ -190,15 +276,16 @@ func hashToken(token string) string {
 	return hex.EncodeToString(h[:])
 }
 
-// CreateRefreshToken stores a new refresh token (hashed)
-func (r *UserRepository) CreateRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) (uuid.UUID, error) {
+// CreateRefreshToken stores a new refresh token (hashed) along with the
+// client it was issued to
+func (r *UserRepository) CreateRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, info domain.SessionInfo) (uuid.UUID, error) {
 	id := uuid.New()
 	tokenHash := hashToken(token)
 
 	_, err := r.db.Pool.Exec(ctx, `
-		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at)
-		VALUES ($1, $2, $3, $4)
-	`, id, userID, tokenHash, expiresAt)
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, id, userID, tokenHash, expiresAt, info.UserAgent, info.IP)
 
 	return id, err
 }
@@ -209,11 +296,12 @@ func (r *UserRepository) GetRefreshToken(ctx context.Context, token string) (*do
 	rt := &domain.RefreshToken{}
 
 	err := r.db.Pool.QueryRow(ctx, `
-		SELECT id, user_id, token_hash, expires_at, created_at, revoked_at
+		SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip
 		FROM refresh_tokens WHERE token_hash = $1
 	`, tokenHash).Scan(
 		&rt.ID, &rt.UserID, &rt.TokenHash,
 		&rt.ExpiresAt, &rt.CreatedAt, &rt.RevokedAt,
+		&rt.UserAgent, &rt.IP,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, domain.ErrTokenInvalid
@@ -238,6 +326,46 @@ func (r *UserRepository) RevokeAllUserTokens(ctx context.Context, userID uuid.UU
 	return err
 }
 
+// ListActiveRefreshTokens returns a user's unrevoked, unexpired refresh
+// tokens, newest first
+func (r *UserRepository) ListActiveRefreshTokens(ctx context.Context, userID uuid.UUID) ([]domain.RefreshToken, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, user_id, expires_at, created_at, user_agent, ip
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []domain.RefreshToken
+	for rows.Next() {
+		var rt domain.RefreshToken
+		if err := rows.Scan(&rt.ID, &rt.UserID, &rt.ExpiresAt, &rt.CreatedAt, &rt.UserAgent, &rt.IP); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, rt)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeUserRefreshToken revokes one of a user's active refresh tokens
+func (r *UserRepository) RevokeUserRefreshToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
 // ============================================================================
 // OAuth Identity Operations
 // ============================================================================
@@ -328,11 +456,101 @@ func (r *UserRepository) UpdatePreferences(ctx context.Context, userID uuid.UUID
 	return err
 }
 
-// UpdateLastSeen updates the user's last seen timestamp
-func (r *UserRepository) UpdateLastSeen(ctx context.Context, userID uuid.UUID) error {
+// SetAutoAcceptCalls turns auto-answering of calls from contacts on or off
+func (r *UserRepository) SetAutoAcceptCalls(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE users SET auto_accept_calls = $2, updated_at = NOW() WHERE id = $1
+	`, userID, enabled)
+	return err
+}
+
+// SetNotificationPreferences sets the user's push notification sound and
+// whether pushes show message text
+func (r *UserRepository) SetNotificationPreferences(ctx context.Context, userID uuid.UUID, sound string, previews bool) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE users SET notification_sound = $2, message_previews = $3, updated_at = NOW() WHERE id = $1
+	`, userID, sound, previews)
+	return err
+}
+
+// AddContact adds contactID to the user's contacts. Adding an existing
+// contact is a no-op.
+func (r *UserRepository) AddContact(ctx context.Context, userID, contactID uuid.UUID) error {
+	if userID == contactID {
+		return domain.ErrSelfContact
+	}
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO user_contacts (user_id, contact_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, contactID)
+	return err
+}
+
+// RemoveContact removes contactID from the user's contacts
+func (r *UserRepository) RemoveContact(ctx context.Context, userID, contactID uuid.UUID) error {
 	_, err := r.db.Pool.Exec(ctx, `
-		UPDATE users SET last_seen_at = NOW() WHERE id = $1
+		DELETE FROM user_contacts WHERE user_id = $1 AND contact_id = $2
+	`, userID, contactID)
+	return err
+}
+
+// GetContacts returns the user's contacts, most recently added first
+func (r *UserRepository) GetContacts(ctx context.Context, userID uuid.UUID) ([]domain.PublicUser, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT u.id, u.username, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, '')
+		FROM user_contacts uc
+		JOIN users u ON u.id = uc.contact_id
+		WHERE uc.user_id = $1
+		ORDER BY uc.created_at DESC
 	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []domain.PublicUser{}
+	for rows.Next() {
+		var u domain.PublicUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.DisplayName, &u.AvatarURL); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, u)
+	}
+	return contacts, rows.Err()
+}
+
+// GetCallAutoAccept returns each callee's auto-answer setting as it applies
+// to callerID. Unknown callees are omitted.
+func (r *UserRepository) GetCallAutoAccept(ctx context.Context, callerID uuid.UUID, calleeIDs []uuid.UUID) (map[uuid.UUID]domain.CallAutoAccept, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT u.id, u.auto_accept_calls,
+		       EXISTS(SELECT 1 FROM user_contacts uc WHERE uc.user_id = u.id AND uc.contact_id = $1)
+		FROM users u
+		WHERE u.id = ANY($2)
+	`, callerID, calleeIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[uuid.UUID]domain.CallAutoAccept, len(calleeIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var a domain.CallAutoAccept
+		if err := rows.Scan(&id, &a.Enabled, &a.CallerIsContact); err != nil {
+			return nil, err
+		}
+		settings[id] = a
+	}
+	return settings, rows.Err()
+}
+
+// TouchLastSeen sets each user's last seen timestamp to now
+func (r *UserRepository) TouchLastSeen(ctx context.Context, userIDs ...uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE users SET last_seen_at = NOW() WHERE id = ANY($1)
+	`, userIDs)
 	return err
 }
 
@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // AttachmentStatus represents the upload status
 type AttachmentStatus string
@@ -11,6 +16,42 @@ const (
 	AttachmentStatusError     AttachmentStatus = "error"
 )
 
+// AttachmentKind tells clients how to render an attachment
+type AttachmentKind string
+
+const (
+	AttachmentKindVoice AttachmentKind = "voice" // audio clip, shown with a player
+	AttachmentKindImage AttachmentKind = "image"
+	AttachmentKindFile  AttachmentKind = "file"
+)
+
+// MaxVoiceDurationMs caps the duration a voice attachment may claim
+const MaxVoiceDurationMs int64 = 60 * 60 * 1000
+
+// ClassifyAttachment picks an attachment's kind from its MIME type
+func ClassifyAttachment(mimeType string) AttachmentKind {
+	t := strings.ToLower(strings.TrimSpace(mimeType))
+	switch {
+	case strings.HasPrefix(t, "audio/"):
+		return AttachmentKindVoice
+	case strings.HasPrefix(t, "image/"):
+		return AttachmentKindImage
+	}
+	return AttachmentKindFile
+}
+
+// ValidateDuration checks a clip duration for an attachment of the given
+// kind. Only voice attachments carry one; nil means unknown.
+func ValidateDuration(kind AttachmentKind, durationMs *int64) error {
+	if durationMs == nil {
+		return nil
+	}
+	if kind != AttachmentKindVoice || *durationMs <= 0 || *durationMs > MaxVoiceDurationMs {
+		return ErrInvalidDuration
+	}
+	return nil
+}
+
 // Attachment represents a file uploaded to R2
 type Attachment struct {
 	ID             string           `json:"id"`
@@ -25,6 +66,69 @@ type Attachment struct {
 	Status         AttachmentStatus `json:"status"`
 	CreatedAt      time.Time        `json:"created_at"`
 	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+	ThumbnailSizes []int            `json:"thumbnail_sizes,omitempty"` // generated variants, by longest edge
+	Kind           AttachmentKind   `json:"kind"`
+	DurationMs     *int64           `json:"duration_ms,omitempty"` // voice clips only; nil if unknown
+}
+
+// CheckMessageAttachment checks that a sender may attach att to a message in
+// convID: it must be their own finished upload to that conversation
+func CheckMessageAttachment(att *Attachment, convID, senderID string) error {
+	if att == nil || att.Status != AttachmentStatusReady || att.ConversationID != convID || att.UploaderID != senderID {
+		return ErrAttachmentNotReady
+	}
+	return nil
+}
+
+// MaxAttachmentsPerMessage caps how many files one message may carry
+const MaxAttachmentsPerMessage = 10
+
+// MessageAttachmentIDs merges the legacy single attachment ID with a list of
+// them, dropping duplicates and keeping order
+func MessageAttachmentIDs(single *uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	if single != nil {
+		ids = append([]uuid.UUID{*single}, ids...)
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	var merged []uuid.UUID
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	if len(merged) > MaxAttachmentsPerMessage {
+		return nil, ErrTooManyAttachments
+	}
+	return merged, nil
+}
+
+// SetAttachmentIDs sets a message's attachments, filling in the single
+// AttachmentID older clients read when there's exactly one
+func (m *Message) SetAttachmentIDs(ids []uuid.UUID) {
+	m.AttachmentIDs = ids
+	m.AttachmentID = nil
+	if len(ids) == 1 {
+		id := ids[0]
+		m.AttachmentID = &id
+	}
+}
+
+// SetAttachments sets a message's loaded attachments, and their IDs, in the
+// same way as SetAttachmentIDs
+func (m *Message) SetAttachments(atts []*Attachment) {
+	ids := make([]uuid.UUID, 0, len(atts))
+	for _, att := range atts {
+		if id, err := uuid.Parse(att.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	m.SetAttachmentIDs(ids)
+	m.Attachments = atts
+	m.Attachment = nil
+	if len(atts) == 1 {
+		m.Attachment = atts[0]
+	}
 }
 
 // UploadInitRequest is the request to initialize an upload
@@ -33,6 +137,7 @@ type UploadInitRequest struct {
 	Filename       string `json:"filename"`
 	MimeType       string `json:"mime_type"`
 	SizeBytes      int64  `json:"size_bytes"`
+	DurationMs     *int64 `json:"duration_ms,omitempty"` // clip length for audio; probed on completion if omitted
 }
 
 // UploadInitResponse is the response from upload init
@@ -56,4 +161,43 @@ type AttachmentDownloadResponse struct {
 	MimeType     string `json:"mime_type"`
 	SizeBytes    int64  `json:"size_bytes"`
 	DownloadURL  string `json:"download_url"`
+
+	Kind       AttachmentKind `json:"kind"`
+	DurationMs *int64         `json:"duration_ms,omitempty"`
+
+	// Thumbnails maps each generated variant's size (longest edge, in
+	// pixels) to its download URL. Empty for non-images.
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+}
+
+// StorageUsage reports a user's attachment storage against their quota
+type StorageUsage struct {
+	UsedBytes      int64 `json:"used_bytes"`
+	QuotaBytes     int64 `json:"quota_bytes"`               // 0 means unlimited
+	AvailableBytes int64 `json:"available_bytes,omitempty"` // omitted when unlimited
+}
+
+// NewStorageUsage builds a usage report, clamping available space at zero
+func NewStorageUsage(used, quota int64) StorageUsage {
+	usage := StorageUsage{UsedBytes: used, QuotaBytes: quota}
+	if quota > 0 && used < quota {
+		usage.AvailableBytes = quota - used
+	}
+	return usage
+}
+
+// DeletedObject is a deleted attachment's stored object, with its thumbnail
+// variants, waiting to be removed from storage
+type DeletedObject struct {
+	ObjectKey      string
+	ThumbnailSizes []int
+}
+
+// QuotaAllows reports whether uploading size more bytes stays within quota.
+// A quota of zero or less disables enforcement.
+func QuotaAllows(used, size, quota int64) bool {
+	if quota <= 0 {
+		return true
+	}
+	return used+size <= quota
 }
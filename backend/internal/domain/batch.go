@@ -0,0 +1,66 @@
+package domain
+
+import "github.com/google/uuid"
+
+// MaxConversationBatch caps how many operations one batch request may carry
+const MaxConversationBatch = 50
+
+// ConversationAction is a per-conversation action that can be batched
+type ConversationAction string
+
+const (
+	ConversationActionArchive   ConversationAction = "archive"
+	ConversationActionUnarchive ConversationAction = "unarchive"
+	ConversationActionMute      ConversationAction = "mute"
+	ConversationActionUnmute    ConversationAction = "unmute"
+	ConversationActionPin       ConversationAction = "pin"
+	ConversationActionUnpin     ConversationAction = "unpin"
+	ConversationActionRead      ConversationAction = "read"
+)
+
+// IsValid checks if the action is one the batch endpoint understands
+func (a ConversationAction) IsValid() bool {
+	switch a {
+	case ConversationActionArchive, ConversationActionUnarchive,
+		ConversationActionMute, ConversationActionUnmute,
+		ConversationActionPin, ConversationActionUnpin,
+		ConversationActionRead:
+		return true
+	}
+	return false
+}
+
+// ConversationOp is one operation in a batch request
+type ConversationOp struct {
+	ConversationID uuid.UUID          `json:"conversation_id"`
+	Action         ConversationAction `json:"action"`
+}
+
+// ConversationOpResult reports the outcome of one batched operation
+type ConversationOpResult struct {
+	ConversationID uuid.UUID          `json:"conversation_id"`
+	Action         ConversationAction `json:"action"`
+	OK             bool               `json:"ok"`
+	Error          string             `json:"error,omitempty"`
+}
+
+// PlanConversationBatch validates each op against the conversations the user
+// belongs to. It returns the ops that may be applied, in request order, and a
+// result per input op with failures already filled in. Callers mark the
+// applied ops' results once they've run.
+func PlanConversationBatch(ops []ConversationOp, memberOf map[uuid.UUID]bool) ([]ConversationOp, []ConversationOpResult) {
+	apply := make([]ConversationOp, 0, len(ops))
+	results := make([]ConversationOpResult, len(ops))
+	for i, op := range ops {
+		results[i] = ConversationOpResult{ConversationID: op.ConversationID, Action: op.Action}
+		switch {
+		case !op.Action.IsValid():
+			results[i].Error = ErrInvalidAction.Error()
+		case !memberOf[op.ConversationID]:
+			results[i].Error = ErrNotMember.Error()
+		default:
+			apply = append(apply, op)
+		}
+	}
+	return apply, results
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,10 +17,66 @@ const (
 type MemberRole string
 
 const (
-	MemberRoleMember MemberRole = "member"
-	MemberRoleAdmin  MemberRole = "admin"
+	MemberRoleMember    MemberRole = "member"
+	MemberRoleModerator MemberRole = "moderator" // may delete others' messages, but not rename or kick
+	MemberRoleAdmin     MemberRole = "admin"
 )
 
+// IsValid reports whether r is a known member role
+func (r MemberRole) IsValid() bool {
+	return r == MemberRoleMember || r == MemberRoleModerator || r == MemberRoleAdmin
+}
+
+// CanModerateMessages reports whether r may delete other members' messages
+func (r MemberRole) CanModerateMessages() bool {
+	return r == MemberRoleAdmin || r == MemberRoleModerator
+}
+
+// CanModerateMessagesIn reports whether a member with role may delete other
+// members' messages in a conversation of convType. Only groups have
+// moderators: a DM's creator is stored as admin but moderates nothing.
+func CanModerateMessagesIn(convType ConversationType, role MemberRole) bool {
+	return convType == ConversationTypeGroup && role.CanModerateMessages()
+}
+
+// ResolveInviteRole determines the role a newly added member receives.
+// An empty requested role falls back to the group's default. The result is
+// bounded by the adder's own role: admins may add members with any role, while
+// everyone else may only add plain members, and only when the group allows it.
+func ResolveInviteRole(adderRole, defaultRole, requested MemberRole, allowMemberAdds bool) (MemberRole, error) {
+	if requested != "" && !requested.IsValid() {
+		return "", ErrInvalidRole
+	}
+
+	if adderRole == MemberRoleAdmin {
+		if requested != "" {
+			return requested, nil
+		}
+		if defaultRole.IsValid() {
+			return defaultRole, nil
+		}
+		return MemberRoleMember, nil
+	}
+
+	if !allowMemberAdds {
+		return "", ErrMemberAddsDisabled
+	}
+	if requested != "" && requested != MemberRoleMember {
+		return "", ErrRoleEscalation
+	}
+	// A member can never grant more than their own role, even via the default
+	return MemberRoleMember, nil
+}
+
+// CanPost checks whether a member with role may send messages. In read-only
+// (announcement) mode only admins can; everyone else can still read.
+func CanPost(role MemberRole, readOnly bool) error {
+	if readOnly && role != MemberRoleAdmin {
+		return ErrReadOnly
+	}
+	return nil
+}
+
 // Conversation represents a chat (DM or group)
 type Conversation struct {
 	ID         uuid.UUID        `json:"id"`
@@ -29,6 +86,19 @@ type Conversation struct {
 	CreatedAt  time.Time        `json:"created_at"`
 	UpdatedAt  time.Time        `json:"updated_at"`
 	ArchivedAt *time.Time       `json:"archived_at,omitempty"`
+	IsSaved    bool             `json:"is_saved,omitempty"` // "Saved Messages" self-conversation
+
+	// Group settings
+	DefaultMemberRole MemberRole `json:"default_member_role,omitempty"` // role given to invited members
+	AllowMemberAdds   bool       `json:"allow_member_adds"`             // whether non-admins may add members
+	ReadOnly          bool       `json:"read_only"`                     // whether only admins may post
+
+	// Disappearing messages: new messages are deleted this many seconds
+	// after they're sent. 0 keeps them forever.
+	MessageTTLSeconds int `json:"message_ttl_seconds,omitempty"`
+
+	// Postgres text-search config used to index and search messages
+	SearchLanguage string `json:"search_language,omitempty"`
 
 	// Populated on fetch
 	Members     []ConversationMember `json:"members,omitempty"`
@@ -36,6 +106,157 @@ type Conversation struct {
 	LastMessage *Message             `json:"last_message,omitempty"`
 	OtherUser   *PublicUser          `json:"other_user,omitempty"` // For DMs
 	MemberCount int                  `json:"member_count,omitempty"`
+	Muted       bool                 `json:"muted,omitempty"` // caller has notifications muted
+	Draft       *string              `json:"draft,omitempty"` // caller's unsent draft
+	Stats       *ConversationStats   `json:"stats,omitempty"`
+}
+
+// ConversationStats holds incrementally maintained message counters
+type ConversationStats struct {
+	MessageCount    int64 `json:"message_count"`
+	AttachmentCount int64 `json:"attachment_count"`
+
+	// Messages sent per member; only populated for group admins
+	MemberMessageCounts map[uuid.UUID]int64 `json:"member_message_counts,omitempty"`
+}
+
+// member returns the membership entry for a user, or nil if they aren't a member
+func (c *Conversation) member(userID uuid.UUID) *ConversationMember {
+	for i := range c.Members {
+		if c.Members[i].UserID == userID {
+			return &c.Members[i]
+		}
+	}
+	return nil
+}
+
+// TransferOwnership hands group ownership (created_by) from one member to
+// another, promoting the new owner to admin. The previous owner keeps their
+// admin role. Only the current owner may transfer; if the owner has left or
+// been deleted, any remaining admin may claim the transfer on their behalf.
+// Members must be populated.
+func (c *Conversation) TransferOwnership(fromUserID, toUserID uuid.UUID) error {
+	if c.Type != ConversationTypeGroup {
+		return ErrNotGroup
+	}
+	if fromUserID == toUserID {
+		return ErrInvalidTransfer
+	}
+
+	from := c.member(fromUserID)
+	if from == nil {
+		return ErrNotMember
+	}
+
+	ownerPresent := c.CreatedBy != nil && c.member(*c.CreatedBy) != nil
+	isOwner := c.CreatedBy != nil && *c.CreatedBy == fromUserID
+	if !isOwner && (ownerPresent || from.Role != MemberRoleAdmin) {
+		return ErrNotOwner
+	}
+
+	to := c.member(toUserID)
+	if to == nil {
+		return ErrNotMember
+	}
+
+	to.Role = MemberRoleAdmin
+	owner := toUserID
+	c.CreatedBy = &owner
+	return nil
+}
+
+// CanBan reports whether actorID may ban targetID from the group. Only admins
+// can ban, and neither themselves nor the owner. The target need not be a
+// current member, so a ban can pre-empt a rejoin. Members must be populated.
+func (c *Conversation) CanBan(actorID, targetID uuid.UUID) error {
+	if c.Type != ConversationTypeGroup {
+		return ErrNotGroup
+	}
+
+	actor := c.member(actorID)
+	if actor == nil {
+		return ErrNotMember
+	}
+	if actor.Role != MemberRoleAdmin {
+		return ErrNotAdmin
+	}
+
+	if actorID == targetID || (c.CreatedBy != nil && *c.CreatedBy == targetID) {
+		return ErrInvalidBan
+	}
+	return nil
+}
+
+// ChangeMemberRole sets targetID's role on actorID's behalf. Only admins can
+// change roles, and the group's last admin can't be demoted, so it is never
+// left without one. Members must be populated.
+func (c *Conversation) ChangeMemberRole(actorID, targetID uuid.UUID, role MemberRole) error {
+	if c.Type != ConversationTypeGroup {
+		return ErrNotGroup
+	}
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+
+	actor := c.member(actorID)
+	if actor == nil {
+		return ErrNotMember
+	}
+	if actor.Role != MemberRoleAdmin {
+		return ErrNotAdmin
+	}
+
+	target := c.member(targetID)
+	if target == nil {
+		return ErrNotMember
+	}
+
+	if target.Role == MemberRoleAdmin && role != MemberRoleAdmin {
+		admins := 0
+		for _, m := range c.Members {
+			if m.Role == MemberRoleAdmin {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return ErrCannotRemoveAdmin
+		}
+	}
+
+	target.Role = role
+	return nil
+}
+
+// CanSetNickname reports whether actorID may set targetID's nickname in the
+// group. Members can nickname themselves; admins can nickname anyone. Members
+// must be populated.
+func (c *Conversation) CanSetNickname(actorID, targetID uuid.UUID) error {
+	if c.Type != ConversationTypeGroup {
+		return ErrNotGroup
+	}
+
+	actor := c.member(actorID)
+	if actor == nil || c.member(targetID) == nil {
+		return ErrNotMember
+	}
+	if actorID != targetID && actor.Role != MemberRoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// SharedGroups returns the viewer's conversations that the other user is
+// also in. Only unarchived groups count: DMs and saved messages are never
+// revealed on a profile.
+func SharedGroups(mine []Conversation, theirs map[uuid.UUID]bool) []Conversation {
+	shared := make([]Conversation, 0)
+	for _, c := range mine {
+		if !theirs[c.ID] || c.Type != ConversationTypeGroup || c.IsSaved || c.ArchivedAt != nil {
+			continue
+		}
+		shared = append(shared, c)
+	}
+	return shared
 }
 
 // ConversationMember represents a user's membership in a conversation
@@ -46,22 +267,186 @@ type ConversationMember struct {
 	JoinedAt       time.Time  `json:"joined_at"`
 
 	// Populated on fetch
-	User *PublicUser `json:"user,omitempty"`
+	User     *PublicUser `json:"user,omitempty"`
+	Nickname string      `json:"nickname,omitempty"` // shown as the user's display name in this conversation
+}
+
+// MaxNicknameLength is the maximum length of a per-conversation nickname, in characters
+const MaxNicknameLength = 32
+
+// SetNickname shows nickname as the member's display name in this
+// conversation. An empty nickname leaves the display name alone.
+func (m *ConversationMember) SetNickname(nickname string) {
+	m.Nickname = nickname
+	if nickname != "" && m.User != nil {
+		m.User.DisplayName = nickname
+	}
 }
 
 // Message represents a chat message
 type Message struct {
-	ID             uuid.UUID  `json:"id"`
-	ConversationID uuid.UUID  `json:"conversation_id"`
-	SenderID       *uuid.UUID `json:"sender_id,omitempty"` // nil if sender deleted
-	BodyText       string     `json:"body_text"`
-	AttachmentID   *uuid.UUID `json:"attachment_id,omitempty"` // Link to attachment
-	CreatedAt      time.Time  `json:"created_at"`
+	ID             uuid.UUID   `json:"id"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       *uuid.UUID  `json:"sender_id,omitempty"` // nil if sender deleted
+	BodyText       string      `json:"body_text"`
+	AttachmentID   *uuid.UUID  `json:"attachment_id,omitempty"`  // Set when the message has exactly one attachment
+	AttachmentIDs  []uuid.UUID `json:"attachment_ids,omitempty"` // Every attachment, in display order
+	ForwardedFrom  *uuid.UUID  `json:"forwarded_from,omitempty"` // Original message if forwarded
+	ReplyToID      *uuid.UUID  `json:"reply_to_id,omitempty"`    // Message this one replies to
+	VisibleTo      []uuid.UUID `json:"visible_to,omitempty"`     // Whisper audience, sender included; nil means everyone
+	CreatedAt      time.Time   `json:"created_at"`
+	Seq            int64       `json:"seq,omitempty"`        // Insertion order; breaks created_at ties
+	EditedAt       *time.Time  `json:"edited_at,omitempty"`  // nil if never edited
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"` // set in conversations with disappearing messages
 
 	// Populated on fetch
-	Sender        *PublicUser `json:"sender,omitempty"`
-	Attachment    *Attachment `json:"attachment,omitempty"`
-	ReceiptStatus string      `json:"receipt_status,omitempty"` // "sent", "delivered", "read"
+	Sender         *PublicUser    `json:"sender,omitempty"`
+	Attachment     *Attachment    `json:"attachment,omitempty"` // Set when there's exactly one, as for AttachmentID
+	Attachments    []*Attachment  `json:"attachments,omitempty"`
+	LinkPreview    *LinkPreview   `json:"link_preview,omitempty"`
+	ReplyTo        *Message       `json:"reply_to,omitempty"`       // Quote of ReplyToID, see ReplyPreview
+	Snippet        string         `json:"snippet,omitempty"`        // Search hit excerpt, HTML with matches in <mark>
+	Rank           float64        `json:"rank,omitempty"`           // Search hit relevance, for paging with before_rank
+	ReceiptStatus  *ReceiptStatus `json:"receipts,omitempty"`       // Sender's own messages only, see SetReceiptStatus
+	ReceiptSummary string         `json:"receipt_status,omitempty"` // "sent", "delivered", "read", for older clients
+}
+
+// MaxMessageTTL caps how long disappearing messages may live
+const MaxMessageTTL = 90 * 24 * time.Hour
+
+// ValidateMessageTTL checks a conversation's disappearing-message lifetime;
+// 0 turns disappearing messages off
+func ValidateMessageTTL(seconds int) error {
+	if seconds < 0 || seconds > int(MaxMessageTTL/time.Second) {
+		return ErrInvalidMessageTTL
+	}
+	return nil
+}
+
+// Expired reports whether a disappearing message has outlived its TTL
+func (m *Message) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// DefaultMaxMessageLength is the longest message body accepted, in bytes,
+// unless configured otherwise
+const DefaultMaxMessageLength = 10000
+
+// MaxReplyPreviewLength is how many characters of a replied-to message are
+// quoted with the reply
+const MaxReplyPreviewLength = 100
+
+// ReplyPreview returns the lightweight copy of m quoted with replies to it:
+// who sent it and the start of its body
+func (m *Message) ReplyPreview() *Message {
+	return &Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Sender:         m.Sender,
+		BodyText:       TruncateText(m.BodyText, MaxReplyPreviewLength),
+		AttachmentID:   m.AttachmentID,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// CheckReplyTarget checks that a user in convID may reply to target. Replies
+// must stay in the target's conversation, and a whisper the user can't see is
+// treated as missing.
+func CheckReplyTarget(target *Message, convID, userID uuid.UUID) error {
+	if !target.VisibleToUser(userID) {
+		return ErrMessageNotFound
+	}
+	if target.ConversationID != convID {
+		return ErrInvalidReply
+	}
+	return nil
+}
+
+// DefaultMessageEditWindow is how long after sending a message may be
+// edited when no window is configured
+const DefaultMessageEditWindow = 24 * time.Hour
+
+// CanEdit reports whether the user may edit the message at now. Only the
+// sender may, and only within window of sending; a window of 0 never closes.
+func (m *Message) CanEdit(userID uuid.UUID, window time.Duration, now time.Time) error {
+	if m.SenderID == nil || *m.SenderID != userID {
+		return ErrNotMessageSender
+	}
+	if window > 0 && now.Sub(m.CreatedAt) > window {
+		return ErrEditWindowClosed
+	}
+	return nil
+}
+
+// DeletableBy reports whether userID, whose role in the conversation is
+// role, may delete the message: senders can delete their own, and in groups
+// admins and moderators can delete any. role is empty for non-members.
+func (m *Message) DeletableBy(userID uuid.UUID, role MemberRole, convType ConversationType) bool {
+	if m.SenderID != nil && *m.SenderID == userID {
+		return true
+	}
+	return CanModerateMessagesIn(convType, role)
+}
+
+// MaxDeleteBatch caps how many messages one batch delete may name
+const MaxDeleteBatch = 100
+
+// CheckDeleteBatch validates a batch delete, which names either up to
+// MaxDeleteBatch message IDs or a time to clear history before, not both
+func CheckDeleteBatch(ids []uuid.UUID, before *time.Time) error {
+	if (len(ids) == 0) == (before == nil) || len(ids) > MaxDeleteBatch {
+		return ErrInvalidDeleteBatch
+	}
+	return nil
+}
+
+// IsWhisper reports whether the message is restricted to some members
+func (m *Message) IsWhisper() bool {
+	return m.VisibleTo != nil
+}
+
+// VisibleToUser reports whether the user may see the message
+func (m *Message) VisibleToUser(userID uuid.UUID) bool {
+	if m.VisibleTo == nil {
+		return true
+	}
+	for _, id := range m.VisibleTo {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxWhisperRecipients caps how many members one whisper may address
+const MaxWhisperRecipients = 20
+
+// WhisperAudience validates whisper recipients against the conversation's
+// members and returns the visibility list: the recipients, deduplicated, with
+// the sender first. Returns ErrInvalidRecipients if the list is empty, too
+// long, only names the sender, or names someone outside the conversation.
+func WhisperAudience(senderID uuid.UUID, recipientIDs []uuid.UUID, members map[uuid.UUID]bool) ([]uuid.UUID, error) {
+	if len(recipientIDs) == 0 || len(recipientIDs) > MaxWhisperRecipients {
+		return nil, ErrInvalidRecipients
+	}
+
+	audience := []uuid.UUID{senderID}
+	seen := map[uuid.UUID]bool{senderID: true}
+	for _, id := range recipientIDs {
+		if !members[id] {
+			return nil, ErrInvalidRecipients
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		audience = append(audience, id)
+	}
+	if len(audience) == 1 {
+		return nil, ErrInvalidRecipients
+	}
+	return audience, nil
 }
 
 // MessageReceipt tracks delivered/read status per user
@@ -72,6 +457,34 @@ type MessageReceipt struct {
 	ReadAt      *time.Time `json:"read_at,omitempty"`
 }
 
+// ReceiptStatus aggregates a message's receipts, so a group message can
+// show "read by N of M"
+type ReceiptStatus struct {
+	Delivered  int `json:"delivered"`  // recipients it has reached, including those who read it
+	Read       int `json:"read"`       // recipients who have read it
+	Recipients int `json:"recipients"` // members other than the sender who can see it
+}
+
+// Summary is the single status older clients show: "read" once anyone has
+// read the message, "delivered" once anyone has received it, else "sent"
+func (s ReceiptStatus) Summary() string {
+	switch {
+	case s.Read > 0:
+		return "read"
+	case s.Delivered > 0:
+		return "delivered"
+	default:
+		return "sent"
+	}
+}
+
+// SetReceiptStatus sets the message's receipt counts along with the
+// summary string derived from them
+func (m *Message) SetReceiptStatus(s ReceiptStatus) {
+	m.ReceiptStatus = &s
+	m.ReceiptSummary = s.Summary()
+}
+
 // StarredMessage represents a message starred by a user
 type StarredMessage struct {
 	UserID    uuid.UUID `json:"user_id"`
@@ -97,3 +510,78 @@ type ReadStatus struct {
 	LastReadAt        time.Time  `json:"last_read_at"`
 	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
 }
+
+// UnreadState is a user's unread badge and read cursor for one conversation
+type UnreadState struct {
+	ConversationID    uuid.UUID  `json:"conversation_id"`
+	UnreadCount       int        `json:"unread_count"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"` // nil if never read
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
+}
+
+// UnreadCursorBefore returns the read cursor that leaves a message sent at
+// createdAt, and everything after it, unread. Postgres keeps microseconds, so
+// the cursor sits one microsecond earlier.
+func UnreadCursorBefore(createdAt time.Time) time.Time {
+	return createdAt.Add(-time.Microsecond)
+}
+
+// MaxForwardBatch caps how many messages can be forwarded in one request
+const MaxForwardBatch = 50
+
+// BuildForwardBatch prepares copies of the selected messages for the target
+// conversation. The batch is all-or-nothing: if any selected message is
+// missing or lives in a conversation the sender can't access, nothing is
+// forwarded and the offending IDs are returned with ErrSourceNotFound.
+// Copies keep the original chronological order. They share one timestamp;
+// inserting them in slice order gives them increasing seq, which keeps them
+// in order in the target.
+func BuildForwardBatch(messageIDs []uuid.UUID, sources map[uuid.UUID]Message, accessible map[uuid.UUID]bool, targetID, senderID uuid.UUID, now time.Time) ([]Message, []uuid.UUID, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil, ErrEmptyBatch
+	}
+	if len(messageIDs) > MaxForwardBatch {
+		return nil, nil, ErrBatchTooLarge
+	}
+
+	seen := make(map[uuid.UUID]bool, len(messageIDs))
+	selected := make([]Message, 0, len(messageIDs))
+	var inaccessible []uuid.UUID
+	for _, id := range messageIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		src, ok := sources[id]
+		if !ok || !accessible[src.ConversationID] {
+			inaccessible = append(inaccessible, id)
+			continue
+		}
+		selected = append(selected, src)
+	}
+	if len(inaccessible) > 0 {
+		return nil, inaccessible, ErrSourceNotFound
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].CreatedAt.Before(selected[j].CreatedAt)
+	})
+
+	forwarded := make([]Message, len(selected))
+	for i, src := range selected {
+		srcID := src.ID
+		sender := senderID
+		forwarded[i] = Message{
+			ID:             uuid.New(),
+			ConversationID: targetID,
+			SenderID:       &sender,
+			BodyText:       src.BodyText,
+			AttachmentID:   src.AttachmentID,
+			AttachmentIDs:  src.AttachmentIDs,
+			ForwardedFrom:  &srcID,
+			CreatedAt:      now,
+		}
+	}
+	return forwarded, nil, nil
+}
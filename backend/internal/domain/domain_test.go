@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -157,5 +159,1244 @@ func TestConversationType_Values(t *testing.T) {
 
 func TestMemberRole_Values(t *testing.T) {
 	assert.Equal(t, MemberRole("member"), MemberRoleMember)
+	assert.Equal(t, MemberRole("moderator"), MemberRoleModerator)
 	assert.Equal(t, MemberRole("admin"), MemberRoleAdmin)
 }
+
+func TestMemberRole_CanModerateMessages(t *testing.T) {
+	assert.True(t, MemberRoleAdmin.CanModerateMessages())
+	assert.True(t, MemberRoleModerator.CanModerateMessages())
+	assert.False(t, MemberRoleMember.CanModerateMessages())
+}
+
+// =============================================================================
+// Invite Role Tests
+// =============================================================================
+
+func TestResolveInviteRole_AdminUsesGroupDefault(t *testing.T) {
+	role, err := ResolveInviteRole(MemberRoleAdmin, MemberRoleAdmin, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleAdmin, role)
+}
+
+func TestResolveInviteRole_AdminDefaultsToMemberWhenUnset(t *testing.T) {
+	role, err := ResolveInviteRole(MemberRoleAdmin, "", "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleMember, role)
+}
+
+func TestResolveInviteRole_AdminCanRequestEitherRole(t *testing.T) {
+	role, err := ResolveInviteRole(MemberRoleAdmin, MemberRoleMember, MemberRoleAdmin, true)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleAdmin, role)
+
+	role, err = ResolveInviteRole(MemberRoleAdmin, MemberRoleAdmin, MemberRoleMember, true)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleMember, role)
+}
+
+func TestResolveInviteRole_MemberCannotEscalate(t *testing.T) {
+	_, err := ResolveInviteRole(MemberRoleMember, MemberRoleMember, MemberRoleAdmin, true)
+	assert.ErrorIs(t, err, ErrRoleEscalation)
+
+	_, err = ResolveInviteRole(MemberRoleModerator, MemberRoleMember, MemberRoleModerator, true)
+	assert.ErrorIs(t, err, ErrRoleEscalation, "only admins hand out moderator")
+}
+
+func TestResolveInviteRole_MemberDefaultCappedAtMember(t *testing.T) {
+	role, err := ResolveInviteRole(MemberRoleMember, MemberRoleAdmin, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleMember, role, "admin default must not let a member grant admin")
+}
+
+func TestResolveInviteRole_MemberAddsDisabled(t *testing.T) {
+	_, err := ResolveInviteRole(MemberRoleMember, MemberRoleMember, "", false)
+	assert.ErrorIs(t, err, ErrMemberAddsDisabled)
+}
+
+func TestResolveInviteRole_AdminIgnoresMemberAddsSetting(t *testing.T) {
+	role, err := ResolveInviteRole(MemberRoleAdmin, MemberRoleMember, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, MemberRoleMember, role)
+}
+
+func TestResolveInviteRole_InvalidRole(t *testing.T) {
+	_, err := ResolveInviteRole(MemberRoleAdmin, MemberRoleMember, MemberRole("owner"), true)
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+// =============================================================================
+// Storage Quota Tests
+// =============================================================================
+
+func TestQuotaAllows_ExactlyAtQuota(t *testing.T) {
+	assert.True(t, QuotaAllows(900, 100, 1000), "filling the quota exactly should be allowed")
+}
+
+func TestQuotaAllows_OneByteOver(t *testing.T) {
+	assert.False(t, QuotaAllows(900, 101, 1000))
+}
+
+func TestQuotaAllows_Unlimited(t *testing.T) {
+	assert.True(t, QuotaAllows(1<<40, 1<<40, 0))
+}
+
+func TestQuotaAllows_ReleasedSpaceIsReusable(t *testing.T) {
+	used := int64(1000)
+	assert.False(t, QuotaAllows(used, 1, 1000))
+
+	// Deleting a 200-byte attachment frees quota for the next upload
+	used -= 200
+	assert.True(t, QuotaAllows(used, 200, 1000))
+}
+
+func TestNewStorageUsage(t *testing.T) {
+	usage := NewStorageUsage(300, 1000)
+	assert.Equal(t, int64(300), usage.UsedBytes)
+	assert.Equal(t, int64(700), usage.AvailableBytes)
+
+	over := NewStorageUsage(1200, 1000)
+	assert.Equal(t, int64(0), over.AvailableBytes)
+
+	unlimited := NewStorageUsage(1200, 0)
+	assert.Equal(t, int64(0), unlimited.QuotaBytes)
+	assert.Equal(t, int64(0), unlimited.AvailableBytes)
+}
+
+// =============================================================================
+// Presence Tests
+// =============================================================================
+
+func TestUser_Presence_MixedOnlineOffline(t *testing.T) {
+	lastSeen := time.Now().Add(-time.Hour)
+	online := &User{ID: uuid.New(), ShowOnlineStatus: true}
+	offline := &User{ID: uuid.New(), ShowOnlineStatus: true, LastSeenAt: &lastSeen}
+
+	p := online.Presence(true)
+	assert.Equal(t, PresenceOnline, p.Status)
+	assert.True(t, p.Online)
+
+	p = offline.Presence(false)
+	assert.Equal(t, PresenceOffline, p.Status)
+	assert.False(t, p.Online)
+	if assert.NotNil(t, p.LastSeenAt) {
+		assert.Equal(t, lastSeen, *p.LastSeenAt)
+	}
+}
+
+func TestUser_Presence_PrivacySuppressed(t *testing.T) {
+	lastSeen := time.Now()
+	u := &User{ID: uuid.New(), ShowOnlineStatus: false, LastSeenAt: &lastSeen}
+
+	p := u.Presence(true)
+	assert.Equal(t, u.ID, p.UserID)
+	assert.Equal(t, PresenceHidden, p.Status)
+	assert.False(t, p.Online, "hidden users must not appear online")
+	assert.Nil(t, p.LastSeenAt, "hidden users must not expose last seen")
+}
+
+// =============================================================================
+// Forward Batch Tests
+// =============================================================================
+
+func TestBuildForwardBatch_PreservesChronologicalOrder(t *testing.T) {
+	srcConv, target, sender := uuid.New(), uuid.New(), uuid.New()
+	base := time.Now().Add(-time.Hour)
+	first := Message{ID: uuid.New(), ConversationID: srcConv, BodyText: "first", CreatedAt: base}
+	second := Message{ID: uuid.New(), ConversationID: srcConv, BodyText: "second", CreatedAt: base.Add(time.Minute)}
+	third := Message{ID: uuid.New(), ConversationID: srcConv, BodyText: "third", CreatedAt: base.Add(2 * time.Minute)}
+
+	sources := map[uuid.UUID]Message{first.ID: first, second.ID: second, third.ID: third}
+	accessible := map[uuid.UUID]bool{srcConv: true}
+
+	// Selection order from the client is arbitrary
+	out, inaccessible, err := BuildForwardBatch([]uuid.UUID{third.ID, first.ID, second.ID}, sources, accessible, target, sender, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, inaccessible)
+	if assert.Len(t, out, 3) {
+		assert.Equal(t, "first", out[0].BodyText)
+		assert.Equal(t, "second", out[1].BodyText)
+		assert.Equal(t, "third", out[2].BodyText)
+
+		for _, m := range out {
+			assert.Equal(t, target, m.ConversationID)
+			assert.Equal(t, sender, *m.SenderID)
+			assert.NotNil(t, m.ForwardedFrom)
+			assert.Equal(t, out[0].CreatedAt, m.CreatedAt, "copies are sent together; seq orders them")
+		}
+		assert.Equal(t, first.ID, *out[0].ForwardedFrom)
+	}
+}
+
+func TestBuildForwardBatch_InaccessibleSourceRejectsWholeBatch(t *testing.T) {
+	allowed, forbidden := uuid.New(), uuid.New()
+	ok := Message{ID: uuid.New(), ConversationID: allowed, CreatedAt: time.Now()}
+	hidden := Message{ID: uuid.New(), ConversationID: forbidden, CreatedAt: time.Now()}
+	missing := uuid.New()
+
+	sources := map[uuid.UUID]Message{ok.ID: ok, hidden.ID: hidden}
+	accessible := map[uuid.UUID]bool{allowed: true}
+
+	out, inaccessible, err := BuildForwardBatch([]uuid.UUID{ok.ID, hidden.ID, missing}, sources, accessible, uuid.New(), uuid.New(), time.Now())
+	assert.ErrorIs(t, err, ErrSourceNotFound)
+	assert.Nil(t, out, "nothing should be forwarded when any source fails")
+	assert.ElementsMatch(t, []uuid.UUID{hidden.ID, missing}, inaccessible)
+}
+
+func TestBuildForwardBatch_Limits(t *testing.T) {
+	_, _, err := BuildForwardBatch(nil, nil, nil, uuid.New(), uuid.New(), time.Now())
+	assert.ErrorIs(t, err, ErrEmptyBatch)
+
+	ids := make([]uuid.UUID, MaxForwardBatch+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	_, _, err = BuildForwardBatch(ids, nil, nil, uuid.New(), uuid.New(), time.Now())
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestBuildForwardBatch_DeduplicatesSelection(t *testing.T) {
+	conv := uuid.New()
+	m := Message{ID: uuid.New(), ConversationID: conv, CreatedAt: time.Now()}
+
+	out, _, err := BuildForwardBatch([]uuid.UUID{m.ID, m.ID}, map[uuid.UUID]Message{m.ID: m}, map[uuid.UUID]bool{conv: true}, uuid.New(), uuid.New(), time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, out, 1)
+}
+
+// =============================================================================
+// Ownership Transfer Tests
+// =============================================================================
+
+func newOwnedGroup(owner uuid.UUID, members map[uuid.UUID]MemberRole) *Conversation {
+	conv := &Conversation{ID: uuid.New(), Type: ConversationTypeGroup, CreatedBy: &owner}
+	for id, role := range members {
+		conv.Members = append(conv.Members, ConversationMember{ConversationID: conv.ID, UserID: id, Role: role})
+	}
+	return conv
+}
+
+func roleOf(conv *Conversation, userID uuid.UUID) MemberRole {
+	if m := conv.member(userID); m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func TestConversation_TransferOwnership_ByOwner(t *testing.T) {
+	owner, target := uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{owner: MemberRoleAdmin, target: MemberRoleMember})
+
+	assert.NoError(t, conv.TransferOwnership(owner, target))
+	assert.Equal(t, target, *conv.CreatedBy)
+	assert.Equal(t, MemberRoleAdmin, roleOf(conv, target), "new owner is promoted to admin")
+	assert.Equal(t, MemberRoleAdmin, roleOf(conv, owner), "previous owner keeps admin")
+}
+
+func TestConversation_TransferOwnership_NonOwnerRejected(t *testing.T) {
+	owner, admin, member := uuid.New(), uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{
+		owner: MemberRoleAdmin, admin: MemberRoleAdmin, member: MemberRoleMember,
+	})
+
+	assert.ErrorIs(t, conv.TransferOwnership(admin, member), ErrNotOwner, "admins can't take over while the owner is present")
+	assert.ErrorIs(t, conv.TransferOwnership(member, admin), ErrNotOwner)
+	assert.Equal(t, owner, *conv.CreatedBy)
+	assert.Equal(t, MemberRoleMember, roleOf(conv, member), "failed transfer must not change roles")
+}
+
+func TestConversation_TransferOwnership_AdminWhenOwnerGone(t *testing.T) {
+	departed, admin, member := uuid.New(), uuid.New(), uuid.New()
+	conv := newOwnedGroup(departed, map[uuid.UUID]MemberRole{admin: MemberRoleAdmin, member: MemberRoleMember})
+
+	assert.ErrorIs(t, conv.TransferOwnership(member, admin), ErrNotOwner, "plain members still can't transfer")
+	assert.NoError(t, conv.TransferOwnership(admin, member))
+	assert.Equal(t, member, *conv.CreatedBy)
+	assert.Equal(t, MemberRoleAdmin, roleOf(conv, member))
+}
+
+func TestConversation_TransferOwnership_Validation(t *testing.T) {
+	owner, outsider := uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{owner: MemberRoleAdmin})
+
+	assert.ErrorIs(t, conv.TransferOwnership(owner, owner), ErrInvalidTransfer)
+	assert.ErrorIs(t, conv.TransferOwnership(owner, outsider), ErrNotMember)
+	assert.ErrorIs(t, conv.TransferOwnership(outsider, owner), ErrNotMember)
+
+	dm := &Conversation{Type: ConversationTypeDM, CreatedBy: &owner}
+	assert.ErrorIs(t, dm.TransferOwnership(owner, outsider), ErrNotGroup)
+}
+
+// =============================================================================
+// Member Role Change Tests
+// =============================================================================
+
+func TestConversation_ChangeMemberRole_ByAdmin(t *testing.T) {
+	owner, member := uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{owner: MemberRoleAdmin, member: MemberRoleMember})
+
+	assert.NoError(t, conv.ChangeMemberRole(owner, member, MemberRoleModerator))
+	assert.Equal(t, MemberRoleModerator, roleOf(conv, member))
+
+	assert.NoError(t, conv.ChangeMemberRole(owner, member, MemberRoleAdmin))
+	assert.Equal(t, MemberRoleAdmin, roleOf(conv, member))
+}
+
+func TestConversation_ChangeMemberRole_OnlyAdmins(t *testing.T) {
+	owner, mod, member, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{
+		owner: MemberRoleAdmin, mod: MemberRoleModerator, member: MemberRoleMember,
+	})
+
+	assert.ErrorIs(t, conv.ChangeMemberRole(mod, member, MemberRoleModerator), ErrNotAdmin, "moderators can't change roles")
+	assert.ErrorIs(t, conv.ChangeMemberRole(member, member, MemberRoleAdmin), ErrNotAdmin)
+	assert.ErrorIs(t, conv.ChangeMemberRole(outsider, member, MemberRoleAdmin), ErrNotMember)
+	assert.ErrorIs(t, conv.ChangeMemberRole(owner, outsider, MemberRoleAdmin), ErrNotMember)
+	assert.ErrorIs(t, conv.ChangeMemberRole(owner, member, MemberRole("owner")), ErrInvalidRole)
+	assert.Equal(t, MemberRoleMember, roleOf(conv, member), "failed changes must not touch roles")
+}
+
+func TestConversation_ChangeMemberRole_LastAdmin(t *testing.T) {
+	owner, member := uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{owner: MemberRoleAdmin, member: MemberRoleMember})
+
+	assert.ErrorIs(t, conv.ChangeMemberRole(owner, owner, MemberRoleMember), ErrCannotRemoveAdmin)
+	assert.NoError(t, conv.ChangeMemberRole(owner, owner, MemberRoleAdmin), "re-affirming admin is a no-op")
+
+	assert.NoError(t, conv.ChangeMemberRole(owner, member, MemberRoleAdmin))
+	assert.NoError(t, conv.ChangeMemberRole(owner, owner, MemberRoleModerator), "another admin remains")
+	assert.Equal(t, MemberRoleModerator, roleOf(conv, owner))
+
+	dm := &Conversation{Type: ConversationTypeDM}
+	assert.ErrorIs(t, dm.ChangeMemberRole(owner, member, MemberRoleAdmin), ErrNotGroup)
+}
+
+// =============================================================================
+// Conversation Ban Tests
+// =============================================================================
+
+func TestConversation_CanBan(t *testing.T) {
+	owner, admin, member, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{
+		owner: MemberRoleAdmin, admin: MemberRoleAdmin, member: MemberRoleMember,
+	})
+
+	assert.NoError(t, conv.CanBan(admin, member))
+	assert.NoError(t, conv.CanBan(owner, admin))
+	assert.NoError(t, conv.CanBan(admin, outsider), "non-members can be banned pre-emptively")
+
+	assert.ErrorIs(t, conv.CanBan(member, admin), ErrNotAdmin)
+	assert.ErrorIs(t, conv.CanBan(outsider, member), ErrNotMember)
+	assert.ErrorIs(t, conv.CanBan(admin, admin), ErrInvalidBan)
+	assert.ErrorIs(t, conv.CanBan(admin, owner), ErrInvalidBan, "the owner can't be banned")
+
+	dm := &Conversation{Type: ConversationTypeDM}
+	assert.ErrorIs(t, dm.CanBan(admin, member), ErrNotGroup)
+}
+
+func TestMessage_DeletableBy(t *testing.T) {
+	sender, other := uuid.New(), uuid.New()
+	msg := &Message{SenderID: &sender}
+
+	assert.True(t, msg.DeletableBy(sender, MemberRoleMember, ConversationTypeGroup))
+	assert.True(t, msg.DeletableBy(sender, "", ""), "senders can delete after leaving")
+	assert.True(t, msg.DeletableBy(other, MemberRoleModerator, ConversationTypeGroup))
+	assert.True(t, msg.DeletableBy(other, MemberRoleAdmin, ConversationTypeGroup))
+	assert.False(t, msg.DeletableBy(other, MemberRoleMember, ConversationTypeGroup))
+
+	orphan := &Message{}
+	assert.False(t, orphan.DeletableBy(other, MemberRoleMember, ConversationTypeGroup))
+	assert.True(t, orphan.DeletableBy(other, MemberRoleAdmin, ConversationTypeGroup))
+}
+
+func TestMessage_DeletableBy_DMCreatorCantDeletePartnersMessages(t *testing.T) {
+	creator, partner := uuid.New(), uuid.New()
+	msg := &Message{SenderID: &partner}
+
+	// The DM's creator is stored as admin, which mustn't make them a moderator
+	assert.False(t, msg.DeletableBy(creator, MemberRoleAdmin, ConversationTypeDM))
+	assert.True(t, msg.DeletableBy(partner, MemberRoleMember, ConversationTypeDM))
+	assert.False(t, CanModerateMessagesIn(ConversationTypeDM, MemberRoleAdmin))
+	assert.True(t, CanModerateMessagesIn(ConversationTypeGroup, MemberRoleModerator))
+}
+
+func TestCheckDeleteBatch(t *testing.T) {
+	before := time.Now()
+	ids := []uuid.UUID{uuid.New()}
+
+	assert.NoError(t, CheckDeleteBatch(ids, nil))
+	assert.NoError(t, CheckDeleteBatch(nil, &before))
+	assert.ErrorIs(t, CheckDeleteBatch(nil, nil), ErrInvalidDeleteBatch)
+	assert.ErrorIs(t, CheckDeleteBatch(ids, &before), ErrInvalidDeleteBatch)
+	assert.ErrorIs(t, CheckDeleteBatch(make([]uuid.UUID, MaxDeleteBatch+1), nil), ErrInvalidDeleteBatch)
+}
+
+func TestConversation_CanSetNickname(t *testing.T) {
+	owner, admin, member, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	conv := newOwnedGroup(owner, map[uuid.UUID]MemberRole{
+		owner: MemberRoleAdmin, admin: MemberRoleAdmin, member: MemberRoleMember,
+	})
+
+	assert.NoError(t, conv.CanSetNickname(member, member))
+	assert.NoError(t, conv.CanSetNickname(admin, member))
+	assert.NoError(t, conv.CanSetNickname(admin, owner))
+
+	assert.ErrorIs(t, conv.CanSetNickname(member, admin), ErrNotAdmin)
+	assert.ErrorIs(t, conv.CanSetNickname(outsider, outsider), ErrNotMember)
+	assert.ErrorIs(t, conv.CanSetNickname(admin, outsider), ErrNotMember)
+
+	dm := &Conversation{Type: ConversationTypeDM}
+	assert.ErrorIs(t, dm.CanSetNickname(member, member), ErrNotGroup)
+}
+
+func TestConversationMember_SetNickname(t *testing.T) {
+	m := ConversationMember{User: &PublicUser{Username: "alice", DisplayName: "Alice"}}
+
+	m.SetNickname("")
+	assert.Equal(t, "Alice", m.User.DisplayName)
+
+	m.SetNickname("Al")
+	assert.Equal(t, "Al", m.Nickname)
+	assert.Equal(t, "Al", m.User.DisplayName)
+}
+
+// =============================================================================
+// Message Filter Tests
+// =============================================================================
+
+func TestMessageFilter_CleanTitlePasses(t *testing.T) {
+	f := NewMessageFilter(FilterModeReject, []string{"darn"})
+
+	title, err := f.Clean("  Weekend Plans  ", MaxTitleLength)
+	assert.NoError(t, err)
+	assert.Equal(t, "Weekend Plans", title)
+
+	title, err = f.Clean("Darnell's Birthday", MaxTitleLength)
+	assert.NoError(t, err, "blocked terms only match whole words")
+	assert.Equal(t, "Darnell's Birthday", title)
+}
+
+func TestMessageFilter_RejectMode(t *testing.T) {
+	f := NewMessageFilter(FilterModeReject, []string{"darn", "heck"})
+
+	_, err := f.Clean("Darn Good Team", MaxTitleLength)
+	assert.ErrorIs(t, err, ErrBlockedText)
+	_, err = f.Clean("what the HECK", MaxTitleLength)
+	assert.ErrorIs(t, err, ErrBlockedText)
+}
+
+func TestMessageFilter_MaskMode(t *testing.T) {
+	f := NewMessageFilter(FilterModeMask, []string{"darn"})
+
+	title, err := f.Clean("Darn good team, darn", MaxTitleLength)
+	assert.NoError(t, err)
+	assert.Equal(t, "**** good team, ****", title)
+}
+
+func TestMessageFilter_Length(t *testing.T) {
+	var f *MessageFilter // nil filter still validates length
+
+	_, err := f.Clean("   ", MaxTitleLength)
+	assert.ErrorIs(t, err, ErrEmptyText)
+
+	_, err = f.Clean(strings.Repeat("a", MaxTitleLength+1), MaxTitleLength)
+	assert.ErrorIs(t, err, ErrTextTooLong)
+
+	title, err := f.Clean(strings.Repeat("é", MaxTitleLength), MaxTitleLength)
+	assert.NoError(t, err, "length is counted in characters, not bytes")
+	assert.Equal(t, MaxTitleLength, len([]rune(title)))
+}
+
+func TestMessageFilter_LengthCountsGraphemes(t *testing.T) {
+	var f *MessageFilter
+
+	// 4-byte emoji: 400 bytes, but 100 visible characters
+	title, err := f.Clean(strings.Repeat("😀", MaxTitleLength), MaxTitleLength)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxTitleLength, TextLength(title))
+
+	// A family emoji is 7 code points joined by ZWJs, still one character
+	_, err = f.Clean(strings.Repeat("👨‍👩‍👧‍👦", MaxTitleLength), MaxTitleLength)
+	assert.NoError(t, err)
+	_, err = f.Clean(strings.Repeat("👨‍👩‍👧‍👦", MaxTitleLength+1), MaxTitleLength)
+	assert.ErrorIs(t, err, ErrTextTooLong)
+}
+
+func TestTextLength(t *testing.T) {
+	assert.Equal(t, 0, TextLength(""))
+	assert.Equal(t, 5, TextLength("hello"))
+	assert.Equal(t, 1, TextLength("😀"), "4-byte emoji")
+	assert.Equal(t, 1, TextLength("👍🏽"), "emoji with skin tone modifier")
+	assert.Equal(t, 1, TextLength("🇯🇵"), "flag made of two regional indicators")
+	assert.Equal(t, 1, TextLength("👨‍👩‍👧‍👦"), "ZWJ family sequence")
+	assert.Equal(t, 4, TextLength("cafe\u0301"), "combining accent joins its letter")
+	assert.Equal(t, 5, TextLength("Tea🍵!"))
+}
+
+func TestCheckTextLength(t *testing.T) {
+	assert.NoError(t, CheckTextLength("🇯🇵🇫🇷", 2))
+	assert.ErrorIs(t, CheckTextLength("🇯🇵🇫🇷🇩🇪", 2), ErrTextTooLong)
+	assert.NoError(t, CheckTextLength(strings.Repeat("👍🏽", MaxDisplayNameLength), MaxDisplayNameLength))
+}
+
+// =============================================================================
+// Pin Tests
+// =============================================================================
+
+func TestPinExpiry(t *testing.T) {
+	now := time.Now()
+
+	expiresAt, err := PinExpiry(now, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, expiresAt, "zero duration pins indefinitely")
+
+	expiresAt, err = PinExpiry(now, 3600)
+	assert.NoError(t, err)
+	if assert.NotNil(t, expiresAt) {
+		assert.Equal(t, now.Add(time.Hour), *expiresAt)
+	}
+
+	_, err = PinExpiry(now, -1)
+	assert.ErrorIs(t, err, ErrInvalidPinDuration)
+	_, err = PinExpiry(now, int64(MaxPinDuration/time.Second)+1)
+	assert.ErrorIs(t, err, ErrInvalidPinDuration)
+	_, err = PinExpiry(now, math.MaxInt64/1000)
+	assert.ErrorIs(t, err, ErrInvalidPinDuration, "seconds that overflow a Duration are still refused")
+}
+
+func TestPinnedMessage_Expired(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+	pin := PinnedMessage{ExpiresAt: &expiresAt}
+
+	assert.False(t, pin.Expired(now))
+	assert.True(t, pin.Expired(expiresAt), "a pin lapses at its expiry instant")
+	assert.False(t, (&PinnedMessage{}).Expired(now.Add(MaxPinDuration)), "pins without expiry never lapse")
+}
+
+func TestCanPin(t *testing.T) {
+	assert.True(t, CanPin(ConversationTypeDM, MemberRoleMember), "anyone can pin in a DM")
+	assert.True(t, CanPin(ConversationTypeGroup, MemberRoleAdmin))
+	assert.True(t, CanPin(ConversationTypeGroup, MemberRoleModerator), "moderators can pin in groups")
+	assert.False(t, CanPin(ConversationTypeGroup, MemberRoleMember))
+}
+
+func TestCheckPinLimit(t *testing.T) {
+	assert.NoError(t, CheckPinLimit(2, false, 3))
+	assert.ErrorIs(t, CheckPinLimit(3, false, 3), ErrTooManyPins, "a full conversation can't take another pin")
+	assert.ErrorIs(t, CheckPinLimit(5, false, 3), ErrTooManyPins, "lowering the limit blocks new pins")
+	assert.NoError(t, CheckPinLimit(3, true, 3), "re-pinning to change expiry doesn't add a pin")
+	assert.NoError(t, CheckPinLimit(1000, false, 0), "0 disables the limit")
+}
+
+func TestValidatePinOrder(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	pinned := []uuid.UUID{a, b, c}
+
+	assert.NoError(t, ValidatePinOrder(pinned, []uuid.UUID{c, a, b}))
+	assert.NoError(t, ValidatePinOrder(nil, nil), "nothing pinned, nothing to order")
+
+	assert.ErrorIs(t, ValidatePinOrder(pinned, []uuid.UUID{c, a}), ErrInvalidPinOrder, "missing pin")
+	assert.ErrorIs(t, ValidatePinOrder(pinned, []uuid.UUID{c, a, b, uuid.New()}), ErrInvalidPinOrder, "extra pin")
+	assert.ErrorIs(t, ValidatePinOrder(pinned, []uuid.UUID{c, a, uuid.New()}), ErrInvalidPinOrder, "unknown message")
+	assert.ErrorIs(t, ValidatePinOrder(pinned, []uuid.UUID{c, c, a}), ErrInvalidPinOrder, "duplicate")
+}
+
+// =============================================================================
+// Search Language Tests
+// =============================================================================
+
+func TestParseSearchLanguage(t *testing.T) {
+	lang, err := ParseSearchLanguage("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSearchLanguage, lang, "empty selects the default")
+
+	lang, err = ParseSearchLanguage(" French ")
+	assert.NoError(t, err)
+	assert.Equal(t, "french", lang, "a non-English conversation searches with its own config")
+
+	lang, err = ParseSearchLanguage("simple")
+	assert.NoError(t, err)
+	assert.Equal(t, "simple", lang)
+
+	_, err = ParseSearchLanguage("klingon")
+	assert.ErrorIs(t, err, ErrInvalidSearchLanguage)
+	_, err = ParseSearchLanguage("english'); DROP TABLE messages; --")
+	assert.ErrorIs(t, err, ErrInvalidSearchLanguage, "only known configs reach SQL")
+}
+
+func TestRenderSnippet(t *testing.T) {
+	raw := "use " + SnippetStartSel + "tea" + SnippetStopSel + " <b>now</b> & later"
+	assert.Equal(t, "use <mark>tea</mark> &lt;b&gt;now&lt;/b&gt; &amp; later", RenderSnippet(raw), "body markup is escaped, matches are marked")
+	assert.Equal(t, "", RenderSnippet(""))
+}
+
+func TestCleanSearchQuery(t *testing.T) {
+	q, err := CleanSearchQuery("  green\x00 \x1btea\t time ", 200, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "green tea time", q, "control characters are stripped and whitespace collapsed")
+
+	q, err = CleanSearchQuery("a b c d e f", 200, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b c", q, "terms past the cap are dropped")
+
+	q, err = CleanSearchQuery("chá", 3, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "chá", q, "length counts characters, not bytes")
+}
+
+func TestCleanSearchQuery_RejectsOverLength(t *testing.T) {
+	_, err := CleanSearchQuery(strings.Repeat("tea ", 1<<18), 200, 10)
+	assert.ErrorIs(t, err, ErrSearchQueryTooLong)
+
+	_, err = CleanSearchQuery(strings.Repeat("x", 201), 200, 0)
+	assert.ErrorIs(t, err, ErrSearchQueryTooLong)
+
+	_, err = CleanSearchQuery(strings.Repeat("x", 201), 0, 0)
+	assert.NoError(t, err, "0 disables the limit")
+}
+
+func TestCleanSearchQuery_RejectsTooShort(t *testing.T) {
+	for _, q := range []string{"", "   ", "a", " a ", "\x00a\x01"} {
+		_, err := CleanSearchQuery(q, 200, 10)
+		assert.ErrorIs(t, err, ErrSearchQueryTooShort, "%q", q)
+	}
+}
+
+func TestFilterAccessibleMessages_DropsJustLeftConversation(t *testing.T) {
+	stillIn, justLeft, banned := uuid.New(), uuid.New(), uuid.New()
+	hits := []Message{
+		{ID: uuid.New(), ConversationID: justLeft, BodyText: "tea at 5"},
+		{ID: uuid.New(), ConversationID: stillIn, BodyText: "tea?"},
+		{ID: uuid.New(), ConversationID: banned, BodyText: "more tea"},
+		{ID: uuid.New(), ConversationID: stillIn, BodyText: "green tea"},
+	}
+
+	// Membership as re-read after the query: the user has since left one
+	// conversation and been banned from another
+	accessible := map[uuid.UUID]bool{stillIn: true}
+
+	kept := FilterAccessibleMessages(hits, accessible)
+	assert.Len(t, kept, 2)
+	assert.Equal(t, hits[1].ID, kept[0].ID, "rank order is preserved")
+	assert.Equal(t, hits[3].ID, kept[1].ID)
+	for _, m := range kept {
+		assert.NotEqual(t, justLeft, m.ConversationID, "just-left conversation doesn't leak")
+		assert.NotEqual(t, banned, m.ConversationID)
+	}
+
+	assert.NotNil(t, FilterAccessibleMessages(hits, nil), "encodes as an empty list, not null")
+}
+
+func TestParseSearchCursor(t *testing.T) {
+	id := uuid.New()
+
+	at := "2025-03-14T09:30:00.123456Z"
+
+	cursor, err := ParseSearchCursor("", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor, "no cursor means the first page")
+
+	cursor, err = ParseSearchCursor("0.0607927", at, id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchCursor{BeforeRank: 0.0607927, BeforeCreatedAt: time.Date(2025, 3, 14, 9, 30, 0, 123456000, time.UTC), BeforeID: id}, cursor)
+
+	for _, tc := range [][3]string{{"0.5", at, ""}, {"", at, id.String()}, {"0.5", "", id.String()}, {"high", at, id.String()}, {"0.5", "yesterday", id.String()}, {"0.5", at, "nope"}} {
+		_, err := ParseSearchCursor(tc[0], tc[1], tc[2])
+		assert.ErrorIs(t, err, ErrInvalidSearchCursor, "rank=%q created_at=%q id=%q", tc[0], tc[1], tc[2])
+	}
+}
+
+func TestParseSearchDate(t *testing.T) {
+	got, err := ParseSearchDate("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = ParseSearchDate("2025-03-14")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), *got, "a date means the start of that day")
+
+	got, err = ParseSearchDate("2025-03-14T09:30:00+02:00")
+	assert.NoError(t, err)
+	assert.True(t, got.Equal(time.Date(2025, 3, 14, 7, 30, 0, 0, time.UTC)))
+
+	for _, bad := range []string{"yesterday", "14/03/2025", "2025-13-01"} {
+		_, err := ParseSearchDate(bad)
+		assert.ErrorIs(t, err, ErrInvalidSearchDate, bad)
+	}
+}
+
+func TestSearchFilter_Validate(t *testing.T) {
+	early := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.AddDate(0, 1, 0)
+
+	assert.NoError(t, SearchFilter{}.Validate())
+	assert.NoError(t, SearchFilter{After: &early}.Validate())
+	assert.NoError(t, SearchFilter{After: &early, Before: &late}.Validate())
+	assert.ErrorIs(t, SearchFilter{After: &late, Before: &early}.Validate(), ErrInvalidSearchDate)
+	assert.ErrorIs(t, SearchFilter{After: &early, Before: &early}.Validate(), ErrInvalidSearchDate, "an empty range matches nothing")
+}
+
+func TestPageSearchHits(t *testing.T) {
+	hits := []Message{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+
+	page, more := PageSearchHits(hits, 2)
+	assert.True(t, more)
+	assert.Equal(t, hits[:2], page)
+
+	page, more = PageSearchHits(hits, 3)
+	assert.False(t, more, "exactly limit hits leaves nothing for a next page")
+	assert.Len(t, page, 3)
+}
+
+func TestPageMembers(t *testing.T) {
+	members := []ConversationMember{{UserID: uuid.New()}, {UserID: uuid.New()}, {UserID: uuid.New()}}
+
+	page, more := PageMembers(members, 2)
+	assert.True(t, more)
+	assert.Equal(t, members[:2], page)
+
+	page, more = PageMembers(members, 3)
+	assert.False(t, more)
+	assert.Len(t, page, 3)
+}
+
+func TestParseMemberCursor(t *testing.T) {
+	id := uuid.New()
+
+	cursor, err := ParseMemberCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor, "no cursor means the first page")
+
+	joined := time.Date(2025, 3, 14, 9, 30, 0, 123456000, time.FixedZone("CET", 3600))
+	next := NextMemberCursor([]ConversationMember{{UserID: uuid.New()}, {UserID: id, JoinedAt: joined}})
+	cursor, err = ParseMemberCursor(next.String())
+	assert.NoError(t, err)
+	assert.True(t, cursor.JoinedAt.Equal(joined))
+	assert.Equal(t, id, cursor.UserID)
+
+	for _, bad := range []string{id.String(), "2025-03-14T09:30:00Z", "2025-03-14_" + id.String(), "2025-03-14T09:30:00Z_nope"} {
+		_, err := ParseMemberCursor(bad)
+		assert.ErrorIs(t, err, ErrInvalidMemberCursor, bad)
+	}
+}
+
+// =============================================================================
+// Conversation Batch Tests
+// =============================================================================
+
+func TestPlanConversationBatch_MixedBatch(t *testing.T) {
+	mine, other := uuid.New(), uuid.New()
+	memberOf := map[uuid.UUID]bool{mine: true}
+
+	ops := []ConversationOp{
+		{ConversationID: mine, Action: ConversationActionArchive},
+		{ConversationID: other, Action: ConversationActionMute},
+		{ConversationID: mine, Action: "explode"},
+		{ConversationID: mine, Action: ConversationActionRead},
+	}
+
+	apply, results := PlanConversationBatch(ops, memberOf)
+
+	assert.Equal(t, []ConversationOp{ops[0], ops[3]}, apply, "only valid ops run, in request order")
+	assert.Len(t, results, len(ops), "one result per op")
+
+	for i, res := range results {
+		assert.Equal(t, ops[i].ConversationID, res.ConversationID)
+		assert.Equal(t, ops[i].Action, res.Action)
+		assert.False(t, res.OK, "nothing is OK until applied")
+	}
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, ErrNotMember.Error(), results[1].Error, "membership is checked per op")
+	assert.Equal(t, ErrInvalidAction.Error(), results[2].Error)
+	assert.Empty(t, results[3].Error)
+}
+
+func TestConversationAction_IsValid(t *testing.T) {
+	for _, a := range []ConversationAction{
+		ConversationActionArchive, ConversationActionUnarchive,
+		ConversationActionMute, ConversationActionUnmute,
+		ConversationActionPin, ConversationActionUnpin,
+		ConversationActionRead,
+	} {
+		assert.True(t, a.IsValid(), a)
+	}
+	assert.False(t, ConversationAction("").IsValid())
+	assert.False(t, ConversationAction("ARCHIVE").IsValid())
+}
+
+// =============================================================================
+// Shared Conversation Tests
+// =============================================================================
+
+func TestSharedGroups(t *testing.T) {
+	archivedAt := time.Now()
+	sharedGroup := Conversation{ID: uuid.New(), Type: ConversationTypeGroup, Title: "book club"}
+	myGroup := Conversation{ID: uuid.New(), Type: ConversationTypeGroup, Title: "family"}
+	sharedDM := Conversation{ID: uuid.New(), Type: ConversationTypeDM}
+	sharedArchived := Conversation{ID: uuid.New(), Type: ConversationTypeGroup, ArchivedAt: &archivedAt}
+
+	mine := []Conversation{sharedGroup, myGroup, sharedDM, sharedArchived}
+	theirs := map[uuid.UUID]bool{sharedGroup.ID: true, sharedDM.ID: true, sharedArchived.ID: true}
+
+	shared := SharedGroups(mine, theirs)
+	assert.Len(t, shared, 1)
+	assert.Equal(t, sharedGroup.ID, shared[0].ID, "groups both users are in are returned")
+
+	assert.Empty(t, SharedGroups(mine, map[uuid.UUID]bool{myGroup.ID: false}), "groups they're not in aren't")
+	assert.NotNil(t, SharedGroups(nil, nil), "encodes as an empty list, not null")
+}
+
+// =============================================================================
+// Sync State Tests
+// =============================================================================
+
+func TestBuildSyncState_ReturnsPinsMutesAndFolders(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	lapsed := now.Add(-time.Minute)
+
+	pinned, archived := uuid.New(), uuid.New()
+	mutedForever, mutedForAnHour, mutedUntilLapsed := uuid.New(), uuid.New(), uuid.New()
+	work1, work2, family := uuid.New(), uuid.New(), uuid.New()
+
+	state := BuildSyncState(
+		[]uuid.UUID{pinned},
+		[]uuid.UUID{archived},
+		[]ConversationMute{
+			{ConversationID: mutedForever},
+			{ConversationID: mutedForAnHour, MutedUntil: &later},
+			{ConversationID: mutedUntilLapsed, MutedUntil: &lapsed},
+		},
+		[]ConversationFolder{
+			{ConversationID: work1, Folder: "Work"},
+			{ConversationID: family, Folder: "Family"},
+			{ConversationID: work2, Folder: "Work"},
+		},
+		now,
+	)
+
+	assert.Equal(t, []uuid.UUID{pinned}, state.PinnedConversations)
+	assert.Equal(t, []uuid.UUID{archived}, state.ArchivedConversations)
+
+	assert.Len(t, state.MutedConversations, 2, "lapsed mutes are dropped")
+	assert.Equal(t, mutedForever, state.MutedConversations[0].ConversationID)
+	assert.Nil(t, state.MutedConversations[0].MutedUntil)
+	assert.Equal(t, mutedForAnHour, state.MutedConversations[1].ConversationID)
+
+	assert.Len(t, state.Folders, 2)
+	assert.ElementsMatch(t, []uuid.UUID{work1, work2}, state.Folders["Work"])
+	assert.Equal(t, []uuid.UUID{family}, state.Folders["Family"])
+	assert.True(t, now.Equal(state.SyncedAt))
+}
+
+func TestBuildSyncState_EmptyIsNotNull(t *testing.T) {
+	state := BuildSyncState(nil, nil, nil, nil, time.Now())
+
+	assert.NotNil(t, state.PinnedConversations)
+	assert.NotNil(t, state.MutedConversations)
+	assert.NotNil(t, state.ArchivedConversations)
+	assert.NotNil(t, state.Folders, "clients replace their layout wholesale")
+}
+
+func TestMuteExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	until, err := MuteExpiry(now, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, until, "zero mutes indefinitely")
+
+	until, err = MuteExpiry(now, 8*3600)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(8*time.Hour), *until)
+
+	_, err = MuteExpiry(now, -60)
+	assert.ErrorIs(t, err, ErrInvalidMuteDuration)
+	_, err = MuteExpiry(now, int64(MaxMuteDuration/time.Second)+1)
+	assert.ErrorIs(t, err, ErrInvalidMuteDuration)
+	_, err = MuteExpiry(now, math.MaxInt64/1000)
+	assert.ErrorIs(t, err, ErrInvalidMuteDuration, "seconds that overflow a Duration are still refused")
+}
+
+func TestCleanFolderName(t *testing.T) {
+	name, err := CleanFolderName("  Work  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", name)
+
+	_, err = CleanFolderName("   ")
+	assert.ErrorIs(t, err, ErrEmptyText)
+	_, err = CleanFolderName(strings.Repeat("f", MaxFolderNameLength+1))
+	assert.ErrorIs(t, err, ErrTextTooLong)
+}
+
+func TestCheckDraft(t *testing.T) {
+	assert.NoError(t, CheckDraft("  half-typed "))
+	assert.ErrorIs(t, CheckDraft(" \n "), ErrEmptyText)
+	assert.ErrorIs(t, CheckDraft(strings.Repeat("d", MaxDraftLength+1)), ErrTextTooLong)
+}
+
+// =============================================================================
+// Unread Cursor Tests
+// =============================================================================
+
+func TestUnreadCursorBefore_CountReflectsNewCursor(t *testing.T) {
+	me, them := uuid.New(), uuid.New()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	messages := []Message{
+		{SenderID: &them, CreatedAt: base},
+		{SenderID: &them, CreatedAt: base.Add(time.Microsecond)},
+		{SenderID: &me, CreatedAt: base.Add(2 * time.Microsecond)},
+		{SenderID: &them, CreatedAt: base.Add(3 * time.Microsecond)},
+	}
+
+	// Mirrors the unread predicate: after the cursor and not sent by me
+	countUnread := func(cursor time.Time) int {
+		n := 0
+		for _, m := range messages {
+			if m.CreatedAt.After(cursor) && *m.SenderID != me {
+				n++
+			}
+		}
+		return n
+	}
+
+	assert.Equal(t, 2, countUnread(UnreadCursorBefore(messages[1].CreatedAt)), "the chosen message and later ones are unread")
+	assert.Equal(t, 3, countUnread(UnreadCursorBefore(messages[0].CreatedAt)), "marking from the first message unreads everything")
+	assert.Equal(t, 1, countUnread(UnreadCursorBefore(messages[3].CreatedAt)), "a neighbour one microsecond earlier stays read")
+}
+
+// =============================================================================
+// Whisper Tests
+// =============================================================================
+
+func TestWhisperAudience(t *testing.T) {
+	sender, alice, bob, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	members := map[uuid.UUID]bool{sender: true, alice: true, bob: true}
+
+	audience, err := WhisperAudience(sender, []uuid.UUID{alice, alice, sender}, members)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{sender, alice}, audience, "sender first, duplicates dropped")
+
+	_, err = WhisperAudience(sender, []uuid.UUID{alice, outsider}, members)
+	assert.ErrorIs(t, err, ErrInvalidRecipients, "can't whisper to someone outside the group")
+	_, err = WhisperAudience(sender, []uuid.UUID{sender}, members)
+	assert.ErrorIs(t, err, ErrInvalidRecipients, "a whisper needs someone besides the sender")
+	_, err = WhisperAudience(sender, nil, members)
+	assert.ErrorIs(t, err, ErrInvalidRecipients)
+}
+
+func TestMessage_SetReceiptStatus(t *testing.T) {
+	tests := []struct {
+		status ReceiptStatus
+		want   string
+	}{
+		{ReceiptStatus{Recipients: 4}, "sent"},
+		{ReceiptStatus{Delivered: 2, Recipients: 4}, "delivered"},
+		{ReceiptStatus{Delivered: 3, Read: 1, Recipients: 4}, "read"},
+	}
+	for _, tt := range tests {
+		var m Message
+		m.SetReceiptStatus(tt.status)
+		assert.Equal(t, tt.want, m.ReceiptSummary)
+		assert.Equal(t, tt.status, *m.ReceiptStatus)
+	}
+}
+
+func TestMessage_VisibleToUser(t *testing.T) {
+	sender, recipient, bystander := uuid.New(), uuid.New(), uuid.New()
+
+	whisper := Message{SenderID: &sender, VisibleTo: []uuid.UUID{sender, recipient}}
+	assert.True(t, whisper.IsWhisper())
+	assert.True(t, whisper.VisibleToUser(sender))
+	assert.True(t, whisper.VisibleToUser(recipient))
+	assert.False(t, whisper.VisibleToUser(bystander), "non-recipients don't see the whisper")
+
+	public := Message{SenderID: &sender}
+	assert.False(t, public.IsWhisper())
+	assert.True(t, public.VisibleToUser(bystander))
+}
+
+func TestMessage_CanEdit(t *testing.T) {
+	sender, other := uuid.New(), uuid.New()
+	sentAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	msg := Message{SenderID: &sender, CreatedAt: sentAt}
+
+	assert.NoError(t, msg.CanEdit(sender, DefaultMessageEditWindow, sentAt.Add(time.Hour)))
+	assert.NoError(t, msg.CanEdit(sender, DefaultMessageEditWindow, sentAt.Add(DefaultMessageEditWindow)), "the window is inclusive")
+	assert.ErrorIs(t, msg.CanEdit(sender, DefaultMessageEditWindow, sentAt.Add(DefaultMessageEditWindow+time.Second)), ErrEditWindowClosed)
+	assert.NoError(t, msg.CanEdit(sender, 0, sentAt.Add(365*24*time.Hour)), "a zero window never closes")
+
+	assert.ErrorIs(t, msg.CanEdit(other, DefaultMessageEditWindow, sentAt), ErrNotMessageSender)
+
+	orphaned := Message{CreatedAt: sentAt}
+	assert.ErrorIs(t, orphaned.CanEdit(sender, DefaultMessageEditWindow, sentAt), ErrNotMessageSender, "messages from deleted users can't be edited")
+}
+
+func TestCanPost(t *testing.T) {
+	assert.NoError(t, CanPost(MemberRoleMember, false))
+	assert.NoError(t, CanPost(MemberRoleAdmin, false))
+
+	assert.ErrorIs(t, CanPost(MemberRoleMember, true), ErrReadOnly, "members can't post in read-only mode")
+	assert.NoError(t, CanPost(MemberRoleAdmin, true), "admins still can")
+}
+
+func TestValidateMessageTTL(t *testing.T) {
+	assert.NoError(t, ValidateMessageTTL(0), "0 turns disappearing messages off")
+	assert.NoError(t, ValidateMessageTTL(86400))
+	assert.NoError(t, ValidateMessageTTL(int(MaxMessageTTL/time.Second)))
+	assert.ErrorIs(t, ValidateMessageTTL(-1), ErrInvalidMessageTTL)
+	assert.ErrorIs(t, ValidateMessageTTL(int(MaxMessageTTL/time.Second)+1), ErrInvalidMessageTTL)
+	assert.ErrorIs(t, ValidateMessageTTL(math.MaxInt64/1000), ErrInvalidMessageTTL, "no overflow into a valid duration")
+}
+
+func TestMessage_Expired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Minute)
+	msg := Message{ExpiresAt: &expiresAt}
+
+	assert.False(t, msg.Expired(now))
+	assert.True(t, msg.Expired(expiresAt), "a message expires exactly at its expiry")
+	assert.False(t, (&Message{}).Expired(now.Add(1000*time.Hour)), "messages without expiry never expire")
+}
+
+func TestCheckReplyTarget(t *testing.T) {
+	sender, recipient, bystander := uuid.New(), uuid.New(), uuid.New()
+	convID := uuid.New()
+
+	target := &Message{ConversationID: convID, SenderID: &sender}
+	assert.NoError(t, CheckReplyTarget(target, convID, bystander))
+	assert.ErrorIs(t, CheckReplyTarget(target, uuid.New(), bystander), ErrInvalidReply, "replies can't cross conversations")
+
+	whisper := &Message{ConversationID: convID, SenderID: &sender, VisibleTo: []uuid.UUID{sender, recipient}}
+	assert.NoError(t, CheckReplyTarget(whisper, convID, recipient))
+	assert.ErrorIs(t, CheckReplyTarget(whisper, convID, bystander), ErrMessageNotFound, "an unseen whisper can't be replied to")
+}
+
+func TestMessage_ReplyPreview(t *testing.T) {
+	sender := uuid.New()
+	msg := &Message{ID: uuid.New(), SenderID: &sender, BodyText: strings.Repeat("a", MaxReplyPreviewLength+20), VisibleTo: []uuid.UUID{sender}}
+
+	preview := msg.ReplyPreview()
+	assert.Equal(t, msg.ID, preview.ID)
+	assert.Equal(t, &sender, preview.SenderID)
+	assert.Equal(t, MaxReplyPreviewLength, TextLength(preview.BodyText))
+	assert.True(t, strings.HasSuffix(preview.BodyText, "…"))
+	assert.Nil(t, preview.VisibleTo)
+}
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, "short", TruncateText("short", 10))
+	assert.Equal(t, "hello…", TruncateText("hello world", 7), "trailing space before the ellipsis is dropped")
+	assert.Equal(t, "🇯🇵🇯🇵…", TruncateText("🇯🇵🇯🇵🇯🇵🇯🇵", 3), "flags aren't split")
+	assert.Equal(t, "", TruncateText("anything", 0))
+}
+
+// =============================================================================
+// Push payload Tests
+// =============================================================================
+
+func TestNewMessagePush_TagsByConversation(t *testing.T) {
+	group := &Conversation{ID: uuid.New(), Type: ConversationTypeGroup, Title: "Tea club"}
+	recipient := &User{NotificationSound: "kettle", MessagePreviews: true}
+
+	first := NewMessagePush(&Message{ID: uuid.New(), BodyText: "oolong?"}, group, "alice", recipient)
+	second := NewMessagePush(&Message{ID: uuid.New(), BodyText: "yes"}, group, "bob", recipient)
+
+	assert.Equal(t, PushTag(group.ID), first.Tag)
+	assert.Equal(t, first.Tag, second.Tag, "pushes from one conversation collapse")
+	assert.Equal(t, "kettle", first.Sound)
+	assert.Equal(t, "Tea club", first.Title)
+	assert.Equal(t, "alice: oolong?", first.Body)
+
+	other := NewMessagePush(&Message{ID: uuid.New(), BodyText: "hi"}, &Conversation{ID: uuid.New(), Type: ConversationTypeDM}, "alice", recipient)
+	assert.NotEqual(t, first.Tag, other.Tag)
+	assert.Equal(t, "alice", other.Title)
+	assert.Equal(t, "hi", other.Body)
+}
+
+func TestNewMessagePush_HonorsNoPreview(t *testing.T) {
+	dm := &Conversation{ID: uuid.New(), Type: ConversationTypeDM}
+	msg := &Message{ID: uuid.New(), BodyText: "the secret blend is earl grey"}
+
+	p := NewMessagePush(msg, dm, "alice", &User{MessagePreviews: false})
+	assert.Equal(t, PushBodyHidden, p.Body)
+	assert.NotContains(t, p.Body, "earl grey")
+	assert.Equal(t, PushTag(dm.ID), p.Tag, "hidden previews still collapse")
+
+	group := &Conversation{ID: uuid.New(), Type: ConversationTypeGroup, Title: "Tea club"}
+	p = NewMessagePush(msg, group, "alice", &User{MessagePreviews: false})
+	assert.Equal(t, PushBodyHidden, p.Body, "the sender isn't prefixed to a hidden body")
+}
+
+func TestNewMessagePush_PreviewAndSound(t *testing.T) {
+	dm := &Conversation{ID: uuid.New(), Type: ConversationTypeDM}
+
+	long := NewMessagePush(&Message{ID: uuid.New(), BodyText: strings.Repeat("a", 500)}, dm, "alice", &User{MessagePreviews: true})
+	assert.Equal(t, MaxPushPreviewLength, TextLength(long.Body))
+	assert.Equal(t, DefaultNotificationSound, long.Sound)
+
+	attachmentID := uuid.New()
+	silent := NewMessagePush(&Message{ID: uuid.New(), AttachmentID: &attachmentID}, dm, "alice", &User{NotificationSound: NotificationSoundNone, MessagePreviews: true})
+	assert.Equal(t, PushBodyAttachment, silent.Body)
+	assert.Empty(t, silent.Sound)
+
+	assert.NoError(t, ValidateNotificationSound("chime"))
+	assert.ErrorIs(t, ValidateNotificationSound("../../etc/passwd"), ErrInvalidSound)
+}
+
+// =============================================================================
+// Conversation insights Tests
+// =============================================================================
+
+func TestNewConversationInsights_ReflectsSeededCounters(t *testing.T) {
+	convID := uuid.New()
+	alice, bob, carol, dave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stats := &ConversationStats{
+		MessageCount:    42,
+		AttachmentCount: 3,
+		MemberMessageCounts: map[uuid.UUID]int64{
+			alice: 20, bob: 15, carol: 7, dave: 0,
+		},
+	}
+
+	insights := NewConversationInsights(convID, stats, 4, 5<<20, 9)
+
+	assert.Equal(t, convID, insights.ConversationID)
+	assert.Equal(t, int64(42), insights.MessageCount)
+	assert.Equal(t, int64(3), insights.AttachmentCount)
+	assert.Equal(t, int64(5<<20), insights.AttachmentBytes)
+	assert.Equal(t, 4, insights.MemberCount)
+	assert.Equal(t, 9, insights.ActiveDays)
+	assert.Equal(t, []SenderCount{
+		{UserID: alice, MessageCount: 20},
+		{UserID: bob, MessageCount: 15},
+		{UserID: carol, MessageCount: 7},
+	}, insights.TopSenders, "silent members aren't listed")
+}
+
+func TestTopSenders_CapsAndBreaksTies(t *testing.T) {
+	counts := make(map[uuid.UUID]int64)
+	for i := 0; i < 8; i++ {
+		counts[uuid.New()] = 10
+	}
+
+	top := TopSenders(counts, 5)
+	assert.Len(t, top, 5)
+	for i := 1; i < len(top); i++ {
+		assert.Less(t, top[i-1].UserID.String(), top[i].UserID.String(), "ties are ordered by user ID")
+	}
+	assert.Equal(t, top, TopSenders(counts, 5), "the order is stable")
+	assert.Empty(t, TopSenders(nil, 5))
+}
+
+func TestValidateSendAt(t *testing.T) {
+	now := time.Now()
+	assert.NoError(t, ValidateSendAt(now.Add(time.Minute), now))
+	assert.NoError(t, ValidateSendAt(now.Add(MaxScheduleAhead), now))
+	assert.ErrorIs(t, ValidateSendAt(now, now), ErrInvalidSendAt, "send_at must be in the future")
+	assert.ErrorIs(t, ValidateSendAt(now.Add(-time.Hour), now), ErrInvalidSendAt)
+	assert.ErrorIs(t, ValidateSendAt(now.Add(MaxScheduleAhead+time.Second), now), ErrInvalidSendAt, "no more than 30 days ahead")
+}
+
+func TestClassifyAttachment(t *testing.T) {
+	assert.Equal(t, AttachmentKindVoice, ClassifyAttachment("audio/ogg; codecs=opus"))
+	assert.Equal(t, AttachmentKindImage, ClassifyAttachment("Image/PNG"))
+	assert.Equal(t, AttachmentKindFile, ClassifyAttachment("video/mp4"))
+	assert.Equal(t, AttachmentKindFile, ClassifyAttachment("application/pdf"))
+}
+
+func TestValidateDuration(t *testing.T) {
+	ms := func(n int64) *int64 { return &n }
+	assert.NoError(t, ValidateDuration(AttachmentKindVoice, nil))
+	assert.NoError(t, ValidateDuration(AttachmentKindFile, nil))
+	assert.NoError(t, ValidateDuration(AttachmentKindVoice, ms(4200)))
+	assert.NoError(t, ValidateDuration(AttachmentKindVoice, ms(MaxVoiceDurationMs)))
+	assert.ErrorIs(t, ValidateDuration(AttachmentKindVoice, ms(0)), ErrInvalidDuration)
+	assert.ErrorIs(t, ValidateDuration(AttachmentKindVoice, ms(MaxVoiceDurationMs+1)), ErrInvalidDuration)
+	assert.ErrorIs(t, ValidateDuration(AttachmentKindImage, ms(1000)), ErrInvalidDuration)
+}
+
+func TestCheckMessageAttachment(t *testing.T) {
+	att := &Attachment{ConversationID: "c1", UploaderID: "u1", Status: AttachmentStatusReady}
+	assert.NoError(t, CheckMessageAttachment(att, "c1", "u1"))
+	assert.ErrorIs(t, CheckMessageAttachment(nil, "c1", "u1"), ErrAttachmentNotReady)
+	assert.ErrorIs(t, CheckMessageAttachment(att, "c2", "u1"), ErrAttachmentNotReady)
+	assert.ErrorIs(t, CheckMessageAttachment(att, "c1", "u2"), ErrAttachmentNotReady)
+
+	att.Status = AttachmentStatusUploading
+	assert.ErrorIs(t, CheckMessageAttachment(att, "c1", "u1"), ErrAttachmentNotReady)
+}
+
+func TestMessageAttachmentIDs(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	ids, err := MessageAttachmentIDs(&a, []uuid.UUID{b, a, c})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{a, b, c}, ids, "the single ID comes first and duplicates are dropped")
+
+	ids, err = MessageAttachmentIDs(nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	many := make([]uuid.UUID, MaxAttachmentsPerMessage+1)
+	for i := range many {
+		many[i] = uuid.New()
+	}
+	_, err = MessageAttachmentIDs(nil, many)
+	assert.ErrorIs(t, err, ErrTooManyAttachments)
+}
+
+func TestMessage_SetAttachments(t *testing.T) {
+	one := &Attachment{ID: uuid.New().String()}
+	two := &Attachment{ID: uuid.New().String()}
+
+	var m Message
+	m.SetAttachments([]*Attachment{one})
+	oneID := uuid.MustParse(one.ID)
+	assert.Equal(t, &oneID, m.AttachmentID)
+	assert.Same(t, one, m.Attachment, "a lone attachment fills the single fields too")
+	assert.Len(t, m.AttachmentIDs, 1)
+
+	m.SetAttachments([]*Attachment{one, two})
+	assert.Nil(t, m.AttachmentID)
+	assert.Nil(t, m.Attachment)
+	assert.Len(t, m.Attachments, 2)
+	assert.Equal(t, two.ID, m.AttachmentIDs[1].String())
+}
+
+func TestFirstURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/a?b=1", FirstURL("see https://example.com/a?b=1 and http://other.org"))
+	assert.Equal(t, "http://example.com/page", FirstURL("Read this: http://example.com/page."))
+	assert.Equal(t, "https://en.wikipedia.org/wiki/Tea_(meal)", FirstURL("(https://en.wikipedia.org/wiki/Tea_(meal))"))
+	assert.Equal(t, "https://example.com", FirstURL("(see https://example.com)"))
+	assert.Equal(t, "", FirstURL("no links, just example.com"))
+	assert.Equal(t, "", FirstURL("ftp://example.com/file"))
+}
+
+func TestParseMentions(t *testing.T) {
+	assert.Equal(t, []string{"alice", "bob_2"}, ParseMentions("@alice can you ask @bob_2? cc @alice"))
+	assert.Empty(t, ParseMentions("mail bob@example.com"), "emails aren't mentions")
+	assert.Empty(t, ParseMentions("@ab is too short, @9lives starts with a digit"))
+	assert.Equal(t, []string{"carol"}, ParseMentions("(@carol)"))
+	assert.Equal(t, []string{"alice", "bob_2"}, ParseMentions("@Alice and @BOB_2, then @alice again"),
+		"mentions match lowercased usernames and dedupe regardless of case")
+
+	var many strings.Builder
+	for i := 0; i < MaxMentionsPerMessage+5; i++ {
+		many.WriteString(" @user" + strings.Repeat("x", i))
+	}
+	assert.Len(t, ParseMentions(many.String()), MaxMentionsPerMessage)
+}
+
+func TestMessage_MentionRecipients(t *testing.T) {
+	sender, member, outsider, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	members := map[uuid.UUID]bool{sender: true, member: true, other: true}
+
+	msg := &Message{SenderID: &sender}
+	assert.Equal(t, []uuid.UUID{member, other}, msg.MentionRecipients([]uuid.UUID{sender, member, outsider, other}, members),
+		"the sender and non-members are skipped")
+
+	whisper := &Message{SenderID: &sender, VisibleTo: []uuid.UUID{sender, member}}
+	assert.Equal(t, []uuid.UUID{member}, whisper.MentionRecipients([]uuid.UUID{member, other}, members),
+		"whispers only notify their audience")
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxDraftLength is the maximum length of a saved draft, in characters
+const MaxDraftLength = 10000
+
+// Draft is a half-typed message a user has saved in a conversation, so it
+// follows them across devices
+type Draft struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Body           string    `json:"body"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CheckDraft validates a draft body. Whitespace is kept as typed, but a
+// blank draft should be deleted rather than saved.
+func CheckDraft(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return ErrEmptyText
+	}
+	return CheckTextLength(body, MaxDraftLength)
+}
@@ -12,18 +12,89 @@ var (
 	ErrTokenExpired       = errors.New("token has expired")
 	ErrTokenRevoked       = errors.New("token has been revoked")
 	ErrTokenInvalid       = errors.New("invalid token")
+	ErrSessionNotFound    = errors.New("session not found")
 
 	// Conversation errors
 	ErrConversationNotFound = errors.New("conversation not found")
 	ErrNotMember            = errors.New("user is not a member of this conversation")
+	ErrDMExists             = errors.New("a DM between these users already exists")
 	ErrAlreadyMember        = errors.New("user is already a member")
 	ErrCannotRemoveAdmin    = errors.New("cannot remove the last admin")
+	ErrInvalidRole          = errors.New("invalid member role")
+	ErrRoleEscalation       = errors.New("cannot grant a role higher than your own")
+	ErrMemberAddsDisabled   = errors.New("only admins can add members to this group")
+	ErrNotGroup             = errors.New("only group conversations support this")
+	ErrNotOwner             = errors.New("only the group owner can transfer ownership")
+	ErrInvalidTransfer      = errors.New("cannot transfer ownership to yourself")
+	ErrBanned               = errors.New("user is banned from this conversation")
+	ErrNotAdmin             = errors.New("only admins can do this")
+	ErrInvalidBan           = errors.New("cannot ban yourself or the group owner")
+	ErrInvalidAction        = errors.New("unknown conversation action")
+	ErrInvalidMuteDuration  = errors.New("mute duration must be between 0 and 365 days")
+	ErrInvalidMessageTTL    = errors.New("message lifetime must be between 0 and 90 days")
+	ErrReadOnly             = errors.New("only admins can post in this conversation")
+	ErrInvalidMemberCursor  = errors.New("invalid member cursor: pass next_before from the previous page as before")
+	ErrGroupTooSmall        = errors.New("group must have at least 2 members")
+	ErrGroupTooLarge        = errors.New("group has too many members")
 
 	// Message errors
-	ErrMessageNotFound = errors.New("message not found")
-	ErrEmptyMessage    = errors.New("message cannot be empty")
+	ErrMessageNotFound   = errors.New("message not found")
+	ErrEmptyMessage      = errors.New("message cannot be empty")
+	ErrEmptyBatch        = errors.New("no messages selected")
+	ErrBatchTooLarge     = errors.New("too many messages selected")
+	ErrSourceNotFound    = errors.New("one or more messages are not accessible")
+	ErrInvalidRecipients = errors.New("whisper recipients must be other members of the conversation")
+	ErrNotMessageSender  = errors.New("only the sender can edit this message")
+	ErrEditWindowClosed  = errors.New("message can no longer be edited")
+	ErrInvalidReply      = errors.New("can only reply to a message in the same conversation")
+
+	// Search errors
+	ErrInvalidSearchLanguage = errors.New("unsupported search language")
+	ErrSearchDisabled        = errors.New("search is unavailable while message encryption is enabled")
+	ErrSearchQueryTooShort   = errors.New("search query is too short")
+	ErrSearchQueryTooLong    = errors.New("search query is too long")
+	ErrInvalidSearchCursor   = errors.New("invalid search cursor: give before_rank, before_created_at (RFC 3339) and before_id together")
+	ErrInvalidSearchDate     = errors.New("after and before must be dates (YYYY-MM-DD) or RFC 3339 timestamps, with after earlier than before")
+
+	// Attachment errors
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+	ErrFileTypeMismatch   = errors.New("file content does not match its declared type")
+	ErrInvalidDuration    = errors.New("duration_ms must be positive, at most one hour, and only set for audio")
+	ErrAttachmentNotReady = errors.New("attachment not found or not ready")
+	ErrTooManyAttachments = errors.New("a message may have at most 10 attachments")
+	ErrQuotaExceeded      = errors.New("storage quota exceeded")
+	ErrAttachmentInUse    = errors.New("attachment is still attached to a message")
+	ErrImageTooLarge      = errors.New("image has too many pixels to process")
+
+	// Pin errors
+	ErrInvalidPinDuration = errors.New("pin duration must be between 0 and 30 days")
+	ErrTooManyPins        = errors.New("conversation has reached its pin limit")
+	ErrInvalidPinOrder    = errors.New("order must list every pinned message exactly once")
+	ErrCannotPinWhisper   = errors.New("whispers can't be pinned")
+
+	// Scheduled message errors
+	ErrInvalidSendAt            = errors.New("send_at must be in the future and within 30 days")
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	ErrTooManyScheduledMessages = errors.New("too many scheduled messages")
+
+	// Batch delete errors
+	ErrInvalidDeleteBatch = errors.New("give either 1 to 100 message_ids or a before timestamp")
+
+	// Draft errors
+	ErrDraftNotFound = errors.New("draft not found")
+
+	// Text validation errors
+	ErrEmptyText   = errors.New("text cannot be empty")
+	ErrTextTooLong = errors.New("text is too long")
+	ErrBlockedText = errors.New("text contains blocked words")
 
 	// Block errors
 	ErrUserBlocked = errors.New("user has blocked you")
 	ErrSelfBlock   = errors.New("cannot block yourself")
+
+	// Contact errors
+	ErrSelfContact = errors.New("cannot add yourself as a contact")
+
+	// Notification errors
+	ErrInvalidSound = errors.New("unknown notification sound")
 )
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the maximum length of a group title, in characters
+const MaxTitleLength = 100
+
+// FilterMode controls what happens to text containing a blocked term
+type FilterMode string
+
+const (
+	FilterModeReject FilterMode = "reject" // refuse the text
+	FilterModeMask   FilterMode = "mask"   // replace blocked terms with asterisks
+)
+
+// MessageFilter validates user-visible text such as group titles, nicknames
+// and announcements: it trims whitespace, enforces a length limit and applies
+// a configurable blocklist. A nil filter only trims and length-checks.
+type MessageFilter struct {
+	mode    FilterMode
+	blocked *regexp.Regexp // nil when the blocklist is empty
+}
+
+// NewMessageFilter creates a filter for the given blocklist. Terms match whole
+// words, case-insensitively. Unknown modes fall back to reject.
+func NewMessageFilter(mode FilterMode, blocklist []string) *MessageFilter {
+	if mode != FilterModeMask {
+		mode = FilterModeReject
+	}
+	f := &MessageFilter{mode: mode}
+
+	terms := make([]string, 0, len(blocklist))
+	for _, term := range blocklist {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, regexp.QuoteMeta(term))
+		}
+	}
+	if len(terms) > 0 {
+		f.blocked = regexp.MustCompile(`(?i)\b(?:` + strings.Join(terms, "|") + `)\b`)
+	}
+	return f
+}
+
+// Clean trims text and checks it against maxLen and the blocklist, returning
+// the text to store. Returns ErrEmptyText, ErrTextTooLong or ErrBlockedText.
+func (f *MessageFilter) Clean(text string, maxLen int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrEmptyText
+	}
+	if err := CheckTextLength(text, maxLen); err != nil {
+		return "", err
+	}
+
+	if f == nil || f.blocked == nil || !f.blocked.MatchString(text) {
+		return text, nil
+	}
+	if f.mode == FilterModeReject {
+		return "", ErrBlockedText
+	}
+	return f.blocked.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	}), nil
+}
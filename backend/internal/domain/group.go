@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxGroupMembers caps how many members a group may be created with,
+// unless configured otherwise
+const DefaultMaxGroupMembers = 100
+
+// MemberPreviewSize is how many members a fetched conversation comes with,
+// the earliest to join; the rest are paged through
+// GET /conversations/{id}/members
+const MemberPreviewSize = 20
+
+// MemberCursor marks where the previous page of members ended: members are
+// listed in (joined_at, user_id) order and continue after these values. Like
+// SearchCursor it holds values, so paging still works if that member has
+// since left.
+type MemberCursor struct {
+	JoinedAt time.Time
+	UserID   uuid.UUID
+}
+
+// NextMemberCursor returns the cursor for the page after members
+func NextMemberCursor(members []ConversationMember) MemberCursor {
+	last := members[len(members)-1]
+	return MemberCursor{JoinedAt: last.JoinedAt, UserID: last.UserID}
+}
+
+// String encodes the cursor for the members endpoint's before parameter
+func (c MemberCursor) String() string {
+	return c.JoinedAt.UTC().Format(time.RFC3339Nano) + "_" + c.UserID.String()
+}
+
+// ParseMemberCursor decodes a cursor made by MemberCursor.String. An empty
+// string returns nil for the first page.
+func ParseMemberCursor(s string) (*MemberCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	joinedAt, userID, ok := strings.Cut(s, "_")
+	if !ok {
+		return nil, ErrInvalidMemberCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, joinedAt)
+	if err != nil {
+		return nil, ErrInvalidMemberCursor
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrInvalidMemberCursor
+	}
+	return &MemberCursor{JoinedAt: t, UserID: id}, nil
+}
+
+// PageMembers trims members fetched with one extra row to limit, reporting
+// whether there were more
+func PageMembers(members []ConversationMember, limit int) ([]ConversationMember, bool) {
+	if len(members) > limit {
+		return members[:limit], true
+	}
+	return members, false
+}
+
+// GroupSettings are settings a group can be created with, saving follow-up
+// updates. Nil fields keep the defaults.
+type GroupSettings struct {
+	ReadOnly          *bool       `json:"read_only"`
+	DisappearingTTL   *int        `json:"disappearing_ttl"` // seconds, see ValidateMessageTTL
+	AllowMemberAdds   *bool       `json:"allow_member_adds"`
+	DefaultMemberRole *MemberRole `json:"default_member_role"`
+}
+
+// Apply validates s and sets it on a new group
+func (s GroupSettings) Apply(conv *Conversation) error {
+	if s.DisappearingTTL != nil {
+		if err := ValidateMessageTTL(*s.DisappearingTTL); err != nil {
+			return err
+		}
+		conv.MessageTTLSeconds = *s.DisappearingTTL
+	}
+	if s.DefaultMemberRole != nil {
+		if !s.DefaultMemberRole.IsValid() {
+			return ErrInvalidRole
+		}
+		conv.DefaultMemberRole = *s.DefaultMemberRole
+	}
+	if s.ReadOnly != nil {
+		conv.ReadOnly = *s.ReadOnly
+	}
+	if s.AllowMemberAdds != nil {
+		conv.AllowMemberAdds = *s.AllowMemberAdds
+	}
+	return nil
+}
+
+// GroupMembers builds a new group's member list. The creator comes first as
+// an admin; other members keep the role they were given, or the group's
+// default if none, and are listed once. A group needs at least one member
+// besides the creator and at most maxMembers in all.
+func GroupMembers(creatorID uuid.UUID, requested []ConversationMember, defaultRole MemberRole, maxMembers int) ([]ConversationMember, error) {
+	members := []ConversationMember{{UserID: creatorID, Role: MemberRoleAdmin}}
+	seen := map[uuid.UUID]bool{creatorID: true}
+	for _, m := range requested {
+		if seen[m.UserID] {
+			continue
+		}
+		seen[m.UserID] = true
+
+		if m.Role == "" {
+			m.Role = defaultRole
+		}
+		if !m.Role.IsValid() {
+			return nil, ErrInvalidRole
+		}
+		members = append(members, ConversationMember{UserID: m.UserID, Role: m.Role})
+	}
+
+	if len(members) < 2 {
+		return nil, ErrGroupTooSmall
+	}
+	if len(members) > maxMembers {
+		return nil, ErrGroupTooLarge
+	}
+	return members, nil
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// MaxInsightTopSenders is how many of a conversation's most active senders
+// its insights list
+const MaxInsightTopSenders = 5
+
+// ConversationInsights summarizes a conversation's size and activity for its admins
+type ConversationInsights struct {
+	ConversationID  uuid.UUID     `json:"conversation_id"`
+	MessageCount    int64         `json:"message_count"`
+	AttachmentCount int64         `json:"attachment_count"`
+	AttachmentBytes int64         `json:"attachment_bytes"`
+	MemberCount     int           `json:"member_count"`
+	ActiveDays      int           `json:"active_days"` // Distinct days with at least one message
+	TopSenders      []SenderCount `json:"top_senders"`
+}
+
+// SenderCount is how many messages a member has sent to a conversation
+type SenderCount struct {
+	UserID       uuid.UUID `json:"user_id"`
+	Username     string    `json:"username,omitempty"`
+	MessageCount int64     `json:"message_count"`
+}
+
+// NewConversationInsights combines a conversation's counters with the
+// figures computed alongside them. stats must include member counts.
+func NewConversationInsights(convID uuid.UUID, stats *ConversationStats, memberCount int, attachmentBytes int64, activeDays int) *ConversationInsights {
+	return &ConversationInsights{
+		ConversationID:  convID,
+		MessageCount:    stats.MessageCount,
+		AttachmentCount: stats.AttachmentCount,
+		AttachmentBytes: attachmentBytes,
+		MemberCount:     memberCount,
+		ActiveDays:      activeDays,
+		TopSenders:      TopSenders(stats.MemberMessageCounts, MaxInsightTopSenders),
+	}
+}
+
+// TopSenders returns the n members with the most messages, most first. Ties
+// are broken by user ID so the order is stable.
+func TopSenders(counts map[uuid.UUID]int64, n int) []SenderCount {
+	senders := make([]SenderCount, 0, len(counts))
+	for userID, count := range counts {
+		if count > 0 {
+			senders = append(senders, SenderCount{UserID: userID, MessageCount: count})
+		}
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		if senders[i].MessageCount != senders[j].MessageCount {
+			return senders[i].MessageCount > senders[j].MessageCount
+		}
+		return senders[i].UserID.String() < senders[j].UserID.String()
+	})
+	if len(senders) > n {
+		senders = senders[:n]
+	}
+	return senders
+}
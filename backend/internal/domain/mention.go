@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxMentionsPerMessage caps how many users one message can notify
+const MaxMentionsPerMessage = 20
+
+// mentionPattern matches @username with the rules usernames are registered
+// under. The @ must not follow a word character, so emails don't count.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z][a-zA-Z0-9_]{2,31})\b`)
+
+// ParseMentions returns the usernames @mentioned in text, lowercased as they
+// are stored, each once, in the order they first appear and at most
+// MaxMentionsPerMessage of them
+func ParseMentions(text string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		usernames = append(usernames, name)
+		if len(usernames) == MaxMentionsPerMessage {
+			break
+		}
+	}
+	return usernames
+}
+
+// MentionRecipients narrows the users mentioned in m to those who should be
+// notified: members of its conversation (per members) who can see it, other
+// than the sender
+func (m *Message) MentionRecipients(userIDs []uuid.UUID, members map[uuid.UUID]bool) []uuid.UUID {
+	var recipients []uuid.UUID
+	for _, id := range userIDs {
+		if !members[id] || !m.VisibleToUser(id) || (m.SenderID != nil && *m.SenderID == id) {
+			continue
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients
+}
+
+// Mention is a message that @mentioned the user, with the conversation it
+// was sent in
+type Mention struct {
+	Message           Message          `json:"message"`
+	ConversationType  ConversationType `json:"conversation_type"`
+	ConversationTitle string           `json:"conversation_title,omitempty"`
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxPinDuration caps how long a time-bound pin may last
+const MaxPinDuration = 30 * 24 * time.Hour
+
+// DefaultMaxPins is the per-conversation pin limit when none is configured
+const DefaultMaxPins = 50
+
+// PinnedMessage is a message pinned to the top of a conversation for everyone
+type PinnedMessage struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MessageID      uuid.UUID  `json:"message_id"`
+	PinnedBy       *uuid.UUID `json:"pinned_by,omitempty"` // nil if pinner deleted
+	PinnedAt       time.Time  `json:"pinned_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // nil pins never expire
+	Position       int        `json:"position"`             // manual order, lowest first
+
+	// Populated on fetch
+	Message *Message `json:"message,omitempty"`
+}
+
+// Expired reports whether a time-bound pin has lapsed
+func (p *PinnedMessage) Expired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// PinExpiry returns when a pin made at now for the given number of seconds
+// lapses. Zero seconds means the pin never expires.
+func PinExpiry(now time.Time, seconds int64) (*time.Time, error) {
+	if seconds == 0 {
+		return nil, nil
+	}
+	// Compared in seconds so a huge value can't overflow into a valid duration
+	if seconds < 0 || seconds > int64(MaxPinDuration/time.Second) {
+		return nil, ErrInvalidPinDuration
+	}
+	expiresAt := now.Add(time.Duration(seconds) * time.Second)
+	return &expiresAt, nil
+}
+
+// CanPin reports whether a member with the given role may pin or unpin
+// messages. Anyone can in a DM; only admins and moderators can in groups.
+func CanPin(convType ConversationType, role MemberRole) bool {
+	return convType != ConversationTypeGroup || role.CanModerateMessages()
+}
+
+// SplitExpiredPins partitions pins into those still active and those that
+// have lapsed at now
+func SplitExpiredPins(pins []PinnedMessage, now time.Time) (active, expired []PinnedMessage) {
+	for _, p := range pins {
+		if p.Expired(now) {
+			expired = append(expired, p)
+		} else {
+			active = append(active, p)
+		}
+	}
+	return active, expired
+}
+
+// CheckPinLimit returns ErrTooManyPins if pinning another message would take
+// a conversation with active unexpired pins past max. Re-pinning a message
+// that's already pinned (to change its expiry) is always allowed, and a max
+// of 0 disables the limit.
+func CheckPinLimit(active int, alreadyPinned bool, max int) error {
+	if max <= 0 || alreadyPinned || active < max {
+		return nil
+	}
+	return ErrTooManyPins
+}
+
+// ValidatePinOrder checks that ordered lists every currently pinned message
+// exactly once, so a reorder can't drop or invent pins
+func ValidatePinOrder(pinned, ordered []uuid.UUID) error {
+	if len(ordered) != len(pinned) {
+		return ErrInvalidPinOrder
+	}
+	want := make(map[uuid.UUID]bool, len(pinned))
+	for _, id := range pinned {
+		want[id] = true
+	}
+	for _, id := range ordered {
+		if !want[id] {
+			return ErrInvalidPinOrder
+		}
+		delete(want, id)
+	}
+	return nil
+}
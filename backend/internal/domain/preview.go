@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Preview text limits, in visible characters
+const (
+	MaxPreviewTitleLength       = 200
+	MaxPreviewDescriptionLength = 500
+)
+
+// LinkPreview is the card shown under a message for the first link in it,
+// built from the linked page's OpenGraph tags
+type LinkPreview struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	URL            string    `json:"url"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	ImageURL       string    `json:"image_url,omitempty"`
+	FetchedAt      time.Time `json:"fetched_at"`
+}
+
+// Empty reports whether the page gave nothing worth showing
+func (p *LinkPreview) Empty() bool {
+	return p.Title == "" && p.Description == ""
+}
+
+// Truncate trims the title and description to their limits
+func (p *LinkPreview) Truncate() {
+	p.Title = TruncateText(p.Title, MaxPreviewTitleLength)
+	p.Description = TruncateText(p.Description, MaxPreviewDescriptionLength)
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// FirstURL returns the first http(s) link in text, or "" if there is none.
+// Punctuation that usually ends a sentence rather than the link is dropped.
+func FirstURL(text string) string {
+	u := urlPattern.FindString(text)
+	for u != "" {
+		trimmed := strings.TrimRight(u, ".,;:!?'")
+		// Keep a closing parenthesis that matches one inside the link
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == u {
+			break
+		}
+		u = trimmed
+	}
+	if strings.HasSuffix(strings.ToLower(u), "://") {
+		return ""
+	}
+	return u
+}
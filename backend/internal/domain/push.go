@@ -0,0 +1,82 @@
+package domain
+
+import "github.com/google/uuid"
+
+// Notification sounds a user may pick for pushes. "none" delivers silently.
+const (
+	DefaultNotificationSound = "default"
+	NotificationSoundNone    = "none"
+)
+
+var notificationSounds = map[string]bool{
+	DefaultNotificationSound: true, NotificationSoundNone: true,
+	"chime": true, "bell": true, "pop": true, "kettle": true,
+}
+
+// ValidateNotificationSound returns ErrInvalidSound for a sound clients don't ship
+func ValidateNotificationSound(sound string) error {
+	if !notificationSounds[sound] {
+		return ErrInvalidSound
+	}
+	return nil
+}
+
+// MaxPushPreviewLength is how many characters of a message a push shows
+const MaxPushPreviewLength = 120
+
+// Push bodies used instead of message text
+const (
+	PushBodyHidden     = "New message"
+	PushBodyAttachment = "Sent an attachment"
+)
+
+// PushPayload is the notification sent to a user's devices for a new message
+type PushPayload struct {
+	Tag            string    `json:"tag"`             // Same per conversation, so its pushes collapse
+	Sound          string    `json:"sound,omitempty"` // Empty delivers silently
+	Title          string    `json:"title"`
+	Body           string    `json:"body"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+}
+
+// PushTag is the collapse tag for a conversation's pushes
+func PushTag(convID uuid.UUID) string {
+	return "conv:" + convID.String()
+}
+
+// NewMessagePush builds the push telling recipient about msg in conv.
+// Message text is only included, truncated, if the recipient has previews
+// on; otherwise the body is a generic line.
+func NewMessagePush(msg *Message, conv *Conversation, senderName string, recipient *User) PushPayload {
+	p := PushPayload{
+		Tag:            PushTag(conv.ID),
+		Title:          senderName,
+		ConversationID: conv.ID,
+		MessageID:      msg.ID,
+	}
+	if conv.Type == ConversationTypeGroup && conv.Title != "" {
+		p.Title = conv.Title
+	}
+
+	switch recipient.NotificationSound {
+	case NotificationSoundNone:
+	case "":
+		p.Sound = DefaultNotificationSound
+	default:
+		p.Sound = recipient.NotificationSound
+	}
+
+	switch {
+	case !recipient.MessagePreviews:
+		p.Body = PushBodyHidden
+	case msg.BodyText == "" && msg.AttachmentID != nil:
+		p.Body = PushBodyAttachment
+	default:
+		p.Body = TruncateText(msg.BodyText, MaxPushPreviewLength)
+	}
+	if p.Body != PushBodyHidden && conv.Type == ConversationTypeGroup {
+		p.Body = senderName + ": " + p.Body
+	}
+	return p
+}
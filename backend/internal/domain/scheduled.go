@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxScheduleAhead is how far in the future a message may be scheduled
+const MaxScheduleAhead = 30 * 24 * time.Hour
+
+// MaxPendingScheduledMessages caps how many messages one user may have
+// waiting to be sent, across all conversations
+const MaxPendingScheduledMessages = 100
+
+// ScheduledMessage is a message composed now and sent to its conversation
+// at SendAt
+type ScheduledMessage struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	SenderUsername string    `json:"sender_username,omitempty"`
+	BodyText       string    `json:"body_text"`
+	SendAt         time.Time `json:"send_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ValidateSendAt checks that sendAt is after now and no more than
+// MaxScheduleAhead away
+func ValidateSendAt(sendAt, now time.Time) error {
+	if !sendAt.After(now) || sendAt.Sub(now) > MaxScheduleAhead {
+		return ErrInvalidSendAt
+	}
+	return nil
+}
+
+// ToMessage returns the message to post when the schedule comes due at now
+func (s *ScheduledMessage) ToMessage(now time.Time) *Message {
+	senderID := s.SenderID
+	return &Message{
+		ID:             uuid.New(),
+		ConversationID: s.ConversationID,
+		SenderID:       &senderID,
+		BodyText:       s.BodyText,
+		CreatedAt:      now,
+	}
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSearchLanguage is the text-search config used when none is set
+const DefaultSearchLanguage = "english"
+
+// Search query limits. Lengths are in characters.
+const (
+	MinSearchQueryLength        = 2
+	DefaultMaxSearchQueryLength = 200
+	DefaultMaxSearchTerms       = 10
+)
+
+// searchLanguages are the Postgres text-search configs a conversation may use.
+// "simple" skips stemming and stop words, for languages without a config.
+var searchLanguages = map[string]bool{
+	"simple": true, "arabic": true, "danish": true, "dutch": true,
+	"english": true, "finnish": true, "french": true, "german": true,
+	"greek": true, "hungarian": true, "indonesian": true, "irish": true,
+	"italian": true, "lithuanian": true, "nepali": true, "norwegian": true,
+	"portuguese": true, "romanian": true, "russian": true, "spanish": true,
+	"swedish": true, "tamil": true, "turkish": true,
+}
+
+// ParseSearchLanguage normalizes a requested search language, returning
+// ErrInvalidSearchLanguage if Postgres has no matching text-search config.
+// An empty value selects the default.
+func ParseSearchLanguage(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return DefaultSearchLanguage, nil
+	}
+	if !searchLanguages[lang] {
+		return "", ErrInvalidSearchLanguage
+	}
+	return lang, nil
+}
+
+// CleanSearchQuery prepares user input for a full-text search: control
+// characters are stripped, whitespace collapsed, and terms past maxTerms
+// dropped. A query longer than maxLength is rejected outright with
+// ErrSearchQueryTooLong, and one left shorter than MinSearchQueryLength with
+// ErrSearchQueryTooShort. A limit of 0 disables it.
+func CleanSearchQuery(query string, maxLength, maxTerms int) (string, error) {
+	if maxLength > 0 && utf8.RuneCountInString(strings.TrimSpace(query)) > maxLength {
+		return "", ErrSearchQueryTooLong
+	}
+
+	terms := strings.Fields(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, query))
+	if maxTerms > 0 && len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+
+	cleaned := strings.Join(terms, " ")
+	if utf8.RuneCountInString(cleaned) < MinSearchQueryLength {
+		return "", ErrSearchQueryTooShort
+	}
+	return cleaned, nil
+}
+
+// SearchCursor marks where the previous page of search hits ended: results
+// continue after this rank, time and ID, in (rank, created_at, id) descending
+// order, so equally ranked hits come newest first. It holds
+// values rather than pointing at a row, so paging still works if the last
+// hit has since been deleted.
+type SearchCursor struct {
+	BeforeRank      float64
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+}
+
+// ParseSearchCursor reads a cursor from the before_rank, before_created_at
+// and before_id query values. All must be given together; if none are, it
+// returns nil for the first page.
+func ParseSearchCursor(rank, createdAt, id string) (*SearchCursor, error) {
+	if rank == "" && createdAt == "" && id == "" {
+		return nil, nil
+	}
+	if rank == "" || createdAt == "" || id == "" {
+		return nil, ErrInvalidSearchCursor
+	}
+	r, err := strconv.ParseFloat(rank, 64)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	msgID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	return &SearchCursor{BeforeRank: r, BeforeCreatedAt: t, BeforeID: msgID}, nil
+}
+
+// SearchFilter narrows search hits to one sender and/or a date range. After
+// is inclusive and Before exclusive; nil fields don't filter.
+type SearchFilter struct {
+	FromUserID *uuid.UUID
+	After      *time.Time
+	Before     *time.Time
+}
+
+// ParseSearchDate reads an after/before search bound, either an RFC 3339
+// timestamp or a plain date (meaning the start of that day, UTC). An empty
+// value returns nil.
+func ParseSearchDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, ErrInvalidSearchDate
+		}
+	}
+	return &t, nil
+}
+
+// Validate checks the date range isn't empty
+func (f SearchFilter) Validate() error {
+	if f.After != nil && f.Before != nil && !f.After.Before(*f.Before) {
+		return ErrInvalidSearchDate
+	}
+	return nil
+}
+
+// PageSearchHits trims hits fetched with one extra row to limit, reporting
+// whether there were more
+func PageSearchHits(hits []Message, limit int) ([]Message, bool) {
+	if len(hits) > limit {
+		return hits[:limit], true
+	}
+	return hits, false
+}
+
+// FilterAccessibleMessages drops search hits from conversations the user can
+// no longer read, preserving result order. accessible is the set of
+// conversations the user is currently a member of and not banned from.
+func FilterAccessibleMessages(messages []Message, accessible map[uuid.UUID]bool) []Message {
+	kept := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		if accessible[m.ConversationID] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// Markers ts_headline is asked to wrap matched terms in. They're control
+// characters so they can't be confused with markup in a message body.
+const (
+	SnippetStartSel = "\x02"
+	SnippetStopSel  = "\x03"
+)
+
+// RenderSnippet turns a raw search headline into HTML-safe text with each
+// match wrapped in <mark>, ready for a client to render
+func RenderSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	return strings.NewReplacer(SnippetStartSel, "<mark>", SnippetStopSel, "</mark>").Replace(escaped)
+}
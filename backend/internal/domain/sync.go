@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxFolderNameLength is the maximum length of a conversation folder name, in characters
+const MaxFolderNameLength = 64
+
+// CleanFolderName trims a folder name and checks its length. Folders are
+// private to their owner, so the blocklist doesn't apply.
+func CleanFolderName(name string) (string, error) {
+	var noBlocklist *MessageFilter
+	return noBlocklist.Clean(name, MaxFolderNameLength)
+}
+
+// MaxMuteDuration caps how long a time-bound mute may last; longer mutes
+// should be indefinite
+const MaxMuteDuration = 365 * 24 * time.Hour
+
+// MuteExpiry returns when a mute made at now for the given number of seconds
+// lifts. Zero seconds mutes indefinitely.
+func MuteExpiry(now time.Time, seconds int64) (*time.Time, error) {
+	if seconds == 0 {
+		return nil, nil
+	}
+	if seconds < 0 || seconds > int64(MaxMuteDuration/time.Second) {
+		return nil, ErrInvalidMuteDuration
+	}
+	until := now.Add(time.Duration(seconds) * time.Second)
+	return &until, nil
+}
+
+// ConversationMute is a user's mute on one conversation
+type ConversationMute struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty"` // nil means muted indefinitely
+}
+
+// ConversationFolder files one conversation into one of a user's folders
+type ConversationFolder struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Folder         string    `json:"folder"`
+}
+
+// SyncState is the per-user layout a reconnecting client restores: which
+// conversations are pinned, muted, archived and filed into folders
+type SyncState struct {
+	PinnedConversations   []uuid.UUID            `json:"pinned_conversations"`
+	MutedConversations    []ConversationMute     `json:"muted_conversations"`
+	ArchivedConversations []uuid.UUID            `json:"archived_conversations"`
+	Folders               map[string][]uuid.UUID `json:"folders"`
+	SyncedAt              time.Time              `json:"synced_at"`
+}
+
+// BuildSyncState assembles a SyncState. Mutes that have lapsed by now are
+// dropped, and folder assignments are grouped by folder name. Every
+// collection is non-nil so clients can replace their state wholesale.
+func BuildSyncState(pinned, archived []uuid.UUID, mutes []ConversationMute, folders []ConversationFolder, now time.Time) SyncState {
+	state := SyncState{
+		PinnedConversations:   make([]uuid.UUID, 0, len(pinned)),
+		MutedConversations:    make([]ConversationMute, 0, len(mutes)),
+		ArchivedConversations: make([]uuid.UUID, 0, len(archived)),
+		Folders:               make(map[string][]uuid.UUID),
+		SyncedAt:              now,
+	}
+
+	state.PinnedConversations = append(state.PinnedConversations, pinned...)
+	state.ArchivedConversations = append(state.ArchivedConversations, archived...)
+
+	for _, m := range mutes {
+		if m.MutedUntil != nil && !m.MutedUntil.After(now) {
+			continue
+		}
+		state.MutedConversations = append(state.MutedConversations, m)
+	}
+
+	for _, f := range folders {
+		state.Folders[f.Folder] = append(state.Folders[f.Folder], f.ConversationID)
+	}
+	for _, ids := range state.Folders {
+		sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	}
+
+	return state
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+)
+
+// MaxDisplayNameLength is the maximum length of a user's display name, in characters
+const MaxDisplayNameLength = 100
+
+// TextLength counts the characters a reader sees: grapheme clusters, so an
+// accented letter, a flag or a family emoji each count once however many
+// bytes or code points they're made of
+func TextLength(text string) int {
+	return uniseg.GraphemeClusterCount(text)
+}
+
+// CheckTextLength returns ErrTextTooLong if text shows more than maxLen characters
+func CheckTextLength(text string, maxLen int) error {
+	if TextLength(text) > maxLen {
+		return ErrTextTooLong
+	}
+	return nil
+}
+
+// TruncateText shortens text to at most maxLen characters, ending it with an
+// ellipsis when anything was cut. Grapheme clusters are never split.
+func TruncateText(text string, maxLen int) string {
+	if maxLen <= 0 {
+		return ""
+	}
+	if TextLength(text) <= maxLen {
+		return text
+	}
+
+	var b strings.Builder
+	g := uniseg.NewGraphemes(text)
+	for n := 0; n < maxLen-1 && g.Next(); n++ {
+		b.WriteString(g.Str())
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace) + "…"
+}
@@ -15,6 +15,9 @@ type User struct {
 	AvatarURL           string     `json:"avatar_url,omitempty"`
 	ShowOnlineStatus    bool       `json:"show_online_status"`
 	ReadReceiptsEnabled bool       `json:"read_receipts_enabled"`
+	AutoAcceptCalls     bool       `json:"auto_accept_calls"`  // auto-answer calls from contacts
+	NotificationSound   string     `json:"notification_sound"` // see NotificationSounds
+	MessagePreviews     bool       `json:"message_previews"`   // show message text in push notifications
 	LastSeenAt          *time.Time `json:"last_seen_at,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at"`
@@ -44,6 +47,41 @@ func (u *User) ToPublic() PublicUser {
 	return pub
 }
 
+// MaxPresenceQueryUsers caps how many users a single presence query may ask about
+const MaxPresenceQueryUsers = 100
+
+// Presence status values
+const (
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+	PresenceHidden  = "hidden" // user has opted out of sharing presence
+)
+
+// UserPresence is a user's online state as visible to others
+type UserPresence struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Status     string     `json:"status"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// Presence builds the user's visible presence given whether they are connected.
+// Users who hide their online status are reported as hidden with no last-seen.
+func (u *User) Presence(online bool) UserPresence {
+	p := UserPresence{UserID: u.ID, Status: PresenceHidden}
+	if !u.ShowOnlineStatus {
+		return p
+	}
+	p.Online = online
+	p.LastSeenAt = u.LastSeenAt
+	if online {
+		p.Status = PresenceOnline
+	} else {
+		p.Status = PresenceOffline
+	}
+	return p
+}
+
 // Credentials stores password hash separately from user
 type Credentials struct {
 	UserID       uuid.UUID `json:"-"`
@@ -60,8 +98,31 @@ type RefreshToken struct {
 	ExpiresAt time.Time  `json:"expires_at"`
 	CreatedAt time.Time  `json:"created_at"`
 	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"` // client that logged in
+	IP        string     `json:"ip,omitempty"`
 }
 
 func (rt *RefreshToken) IsValid() bool {
 	return rt.RevokedAt == nil && time.Now().Before(rt.ExpiresAt)
 }
+
+// MaxUserAgentLength caps the user agent stored with a session
+const MaxUserAgentLength = 512
+
+// SessionInfo describes the client a refresh token was issued to
+type SessionInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// CallAutoAccept is a callee's auto-answer setting as it applies to one caller
+type CallAutoAccept struct {
+	Enabled         bool // callee opted in to auto-answering
+	CallerIsContact bool // caller is in the callee's contacts
+}
+
+// Applies reports whether the callee's client should auto-answer the caller.
+// Auto-accept is contact-scoped: opting in never auto-answers strangers.
+func (a CallAutoAccept) Applies() bool {
+	return a.Enabled && a.CallerIsContact
+}
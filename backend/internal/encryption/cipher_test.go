@@ -0,0 +1,101 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCipher(t *testing.T, fill byte) *FieldCipher {
+	t.Helper()
+	c, err := NewFieldCipher(bytes.Repeat([]byte{fill}, KeySize))
+	require.NoError(t, err)
+	return c
+}
+
+func TestFieldCipher_RoundTrip(t *testing.T) {
+	c := newTestCipher(t, 1)
+
+	for _, plaintext := range []string{"hello", "meet at 6 🍵", strings.Repeat("x", 4096)} {
+		stored, err := c.Encrypt(plaintext)
+		require.NoError(t, err)
+
+		got, err := c.Decrypt(stored)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	}
+}
+
+func TestFieldCipher_DoesNotStorePlaintext(t *testing.T) {
+	c := newTestCipher(t, 1)
+	plaintext := "the launch code is tea"
+
+	stored, err := c.Encrypt(plaintext)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(stored, prefix))
+	assert.NotContains(t, stored, plaintext)
+	assert.NotContains(t, stored, "launch")
+
+	again, err := c.Encrypt(plaintext)
+	require.NoError(t, err)
+	assert.NotEqual(t, stored, again, "a fresh nonce means equal bodies don't look equal at rest")
+}
+
+func TestFieldCipher_EmptyStaysEmpty(t *testing.T) {
+	c := newTestCipher(t, 1)
+
+	stored, err := c.Encrypt("")
+	require.NoError(t, err)
+	assert.Equal(t, "", stored, "attachment-only messages have no body to seal")
+}
+
+func TestFieldCipher_ReadsLegacyPlaintext(t *testing.T) {
+	c := newTestCipher(t, 1)
+
+	got, err := c.Decrypt("written before encryption was enabled")
+	require.NoError(t, err)
+	assert.Equal(t, "written before encryption was enabled", got)
+}
+
+func TestFieldCipher_WrongKey(t *testing.T) {
+	stored, err := newTestCipher(t, 1).Encrypt("secret")
+	require.NoError(t, err)
+
+	_, err = newTestCipher(t, 2).Decrypt(stored)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = newTestCipher(t, 1).Decrypt(prefix + "not-base64!")
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestFieldCipher_NilPassesThrough(t *testing.T) {
+	var c *FieldCipher
+	assert.False(t, c.Enabled())
+
+	stored, err := c.Encrypt("plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", stored)
+
+	sealed, err := newTestCipher(t, 1).Encrypt("secret")
+	require.NoError(t, err)
+	_, err = c.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrNoKey, "ciphertext is never returned as if it were the body")
+}
+
+func TestParseKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{7}, KeySize)
+
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	assert.Equal(t, raw, key)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString(raw[:16]))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = ParseKey("not base64")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
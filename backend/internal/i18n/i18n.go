@@ -0,0 +1,132 @@
+// Package i18n renders the short, human-readable lines the server generates
+// itself (system messages such as "Alice joined the group") in a client's
+// language and time zone. User-written content is never translated.
+package i18n
+
+import (
+	"strings"
+	"time"
+)
+
+// Key identifies a system message template
+type Key string
+
+const (
+	KeyMemberJoined  Key = "member_joined"
+	KeyMemberLeft    Key = "member_left"
+	KeyMemberRemoved Key = "member_removed"
+	KeyRoomRenamed   Key = "room_renamed"
+	KeyReadOnlyOn    Key = "read_only_on"
+	KeyReadOnlyOff   Key = "read_only_off"
+	KeyThrottled     Key = "throttled"
+	KeyUnthrottled   Key = "unthrottled"
+)
+
+// DefaultLanguage is used when a client's locale isn't in the catalog
+const DefaultLanguage = "en"
+
+// catalog holds each language's templates. Placeholders are written {name}
+// and filled by Render. A key missing from a language falls back to English.
+var catalog = map[string]map[Key]string{
+	"en": {
+		KeyMemberJoined:  "{user} joined the group",
+		KeyMemberLeft:    "{user} left the group",
+		KeyMemberRemoved: "{user} was removed from the group",
+		KeyRoomRenamed:   "The group was renamed to “{title}”",
+		KeyReadOnlyOn:    "Only admins can send messages now",
+		KeyReadOnlyOff:   "Everyone can send messages again",
+		KeyThrottled:     "You're sending messages too fast. You can send again at {time}.",
+		KeyUnthrottled:   "You can send messages again",
+	},
+	"es": {
+		KeyMemberJoined:  "{user} se unió al grupo",
+		KeyMemberLeft:    "{user} salió del grupo",
+		KeyMemberRemoved: "{user} fue eliminado del grupo",
+		KeyRoomRenamed:   "El grupo ahora se llama «{title}»",
+		KeyReadOnlyOn:    "Ahora solo los administradores pueden enviar mensajes",
+		KeyReadOnlyOff:   "Todos pueden volver a enviar mensajes",
+		KeyThrottled:     "Estás enviando mensajes demasiado rápido. Podrás volver a enviar a las {time}.",
+		KeyUnthrottled:   "Ya puedes volver a enviar mensajes",
+	},
+	"fr": {
+		KeyMemberJoined:  "{user} a rejoint le groupe",
+		KeyMemberLeft:    "{user} a quitté le groupe",
+		KeyMemberRemoved: "{user} a été retiré du groupe",
+		KeyRoomRenamed:   "Le groupe s'appelle désormais « {title} »",
+		KeyReadOnlyOn:    "Seuls les administrateurs peuvent envoyer des messages",
+		KeyReadOnlyOff:   "Tout le monde peut de nouveau envoyer des messages",
+		KeyThrottled:     "Vous envoyez des messages trop vite. Vous pourrez de nouveau écrire à {time}.",
+		KeyUnthrottled:   "Vous pouvez de nouveau envoyer des messages",
+	},
+	"de": {
+		KeyMemberJoined:  "{user} ist der Gruppe beigetreten",
+		KeyMemberLeft:    "{user} hat die Gruppe verlassen",
+		KeyMemberRemoved: "{user} wurde aus der Gruppe entfernt",
+		KeyRoomRenamed:   "Die Gruppe heißt jetzt „{title}“",
+		KeyReadOnlyOn:    "Nur Admins können jetzt Nachrichten senden",
+		KeyReadOnlyOff:   "Alle können wieder Nachrichten senden",
+		KeyThrottled:     "Du sendest zu schnell. Du kannst um {time} wieder senden.",
+		KeyUnthrottled:   "Du kannst wieder Nachrichten senden",
+	},
+}
+
+// timeFormats are each language's clock format; others use 24-hour time
+var timeFormats = map[string]string{
+	"en": "3:04 PM",
+}
+
+// Match returns the catalog language for a locale tag such as "fr-CA" or
+// "pt_BR", falling back to DefaultLanguage
+func Match(locale string) string {
+	lang := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalog[lang]; ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// LoadLocation resolves an IANA time zone name, falling back to UTC for an
+// empty or unknown name
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Render fills key's template for lang with args, given as placeholder name
+// and value pairs
+func Render(lang string, key Key, args ...string) string {
+	tmpl, ok := catalog[lang][key]
+	if !ok {
+		tmpl = catalog[DefaultLanguage][key]
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+args[i]+"}", args[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+// FormatTime renders a clock time for lang in loc
+func FormatTime(lang string, t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout, ok := timeFormats[lang]
+	if !ok {
+		layout = "15:04"
+	}
+	return t.In(loc).Format(layout)
+}
@@ -0,0 +1,40 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	assert.Equal(t, "fr", Match("fr"))
+	assert.Equal(t, "fr", Match("fr-CA"))
+	assert.Equal(t, "de", Match("DE_at"))
+	assert.Equal(t, DefaultLanguage, Match("ja-JP"), "unsupported languages fall back")
+	assert.Equal(t, DefaultLanguage, Match(""))
+}
+
+func TestRender(t *testing.T) {
+	assert.Equal(t, "alice joined the group", Render("en", KeyMemberJoined, "user", "alice"))
+	assert.Equal(t, "alice a rejoint le groupe", Render("fr", KeyMemberJoined, "user", "alice"))
+	assert.Equal(t, "Only admins can send messages now", Render("xx", KeyReadOnlyOn), "unknown languages use English")
+}
+
+func TestRender_EveryLanguageCoversEveryKey(t *testing.T) {
+	for lang, templates := range catalog {
+		for key := range catalog[DefaultLanguage] {
+			assert.NotEmpty(t, templates[key], "%s is missing %s", lang, key)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	at := time.Date(2026, 3, 1, 17, 30, 0, 0, time.UTC)
+	paris := LoadLocation("Europe/Paris")
+
+	assert.Equal(t, "5:30 PM", FormatTime("en", at, time.UTC))
+	assert.Equal(t, "18:30", FormatTime("fr", at, paris))
+	assert.Equal(t, "17:30", FormatTime("de", at, nil))
+	assert.Equal(t, time.UTC, LoadLocation("Not/AZone"))
+}
@@ -0,0 +1,226 @@
+// Package linkpreview fetches web pages linked from messages and reads
+// their OpenGraph tags for preview cards.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/time/rate"
+)
+
+// Defaults used when a Fetcher is created with zero values
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultHostRate = 10 // fetches per minute to any one site
+)
+
+const (
+	maxPageBytes = 512 << 10 // OpenGraph tags live in <head>, well inside this
+	maxRedirects = 3
+	userAgent    = "TeaTimeBot/1.0 (+link preview)"
+)
+
+var (
+	ErrUnsupportedURL = errors.New("linkpreview: only http and https links are previewed")
+	ErrBlockedAddress = errors.New("linkpreview: host resolves to a private or loopback address")
+	ErrRateLimited    = errors.New("linkpreview: too many fetches for this host")
+	ErrNotHTML        = errors.New("linkpreview: page is not HTML")
+)
+
+// Fetcher builds link previews over HTTP. Connections to private, loopback
+// and link-local addresses are refused at dial time, so neither a link nor a
+// redirect can reach internal services.
+type Fetcher struct {
+	client *http.Client
+
+	hostRate  rate.Limit
+	hostBurst int
+	hostIdle  time.Duration // after this long unused a limiter is full again and can be forgotten
+	mu        sync.Mutex
+	hosts     map[string]*hostLimiter
+	lastSweep time.Time
+	now       func() time.Time
+
+	allowAddr func(net.IP) bool // swapped in tests, which serve from loopback
+}
+
+// hostLimiter is the fetch budget for one site and when it was last used
+type hostLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// NewFetcher creates a fetcher that gives up on a page after timeout and
+// fetches from any one site at most perHostPerMin times a minute
+func NewFetcher(timeout time.Duration, perHostPerMin int) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if perHostPerMin <= 0 {
+		perHostPerMin = DefaultHostRate
+	}
+
+	hostBurst := max(perHostPerMin/10, 1)
+	f := &Fetcher{
+		hostRate:  rate.Limit(float64(perHostPerMin) / 60.0),
+		hostBurst: hostBurst,
+		hostIdle:  time.Duration(hostBurst) * time.Minute / time.Duration(perHostPerMin),
+		hosts:     make(map[string]*hostLimiter),
+		now:       time.Now,
+		allowAddr: publicAddr,
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: f.checkDial}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // a proxy would dial on our behalf, past the guard
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("linkpreview: too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrUnsupportedURL
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// Fetch reads the OpenGraph title, description and image of the page at
+// rawURL. The returned preview only has URL and those fields set.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrUnsupportedURL
+	}
+	if !f.limiter(siteKey(u.Hostname())).Allow() {
+		return nil, ErrRateLimited
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("linkpreview: page returned %s", resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	tags := ParseOpenGraph(io.LimitReader(resp.Body, maxPageBytes))
+	preview := &domain.LinkPreview{
+		URL:         rawURL,
+		Title:       tags.Title,
+		Description: tags.Description,
+		ImageURL:    resolveImage(resp.Request.URL, tags.Image),
+	}
+	preview.Truncate()
+	return preview, nil
+}
+
+// siteKey returns the registrable domain of host (example.co.uk for
+// a.b.example.co.uk), so subdomains share one budget. IP addresses and
+// hosts without a known suffix are keyed as they are.
+func siteKey(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if site, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return site
+	}
+	return host
+}
+
+// limiter returns the rate limiter for a site, creating one if needed.
+// Limiters left unused long enough to have refilled are forgotten, since a
+// fresh one would behave the same.
+func (f *Fetcher) limiter(site string) *rate.Limiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if now.Sub(f.lastSweep) >= f.hostIdle {
+		for key, h := range f.hosts {
+			if now.Sub(h.lastUsed) >= f.hostIdle {
+				delete(f.hosts, key)
+			}
+		}
+		f.lastSweep = now
+	}
+
+	h, ok := f.hosts[site]
+	if !ok {
+		h = &hostLimiter{limiter: rate.NewLimiter(f.hostRate, f.hostBurst)}
+		f.hosts[site] = h
+	}
+	h.lastUsed = now
+	return h.limiter
+}
+
+// checkDial refuses connections to addresses allowAddr rejects. It runs
+// after DNS resolution, for every connection including redirects.
+func (f *Fetcher) checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !f.allowAddr(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// publicAddr reports whether ip is a globally routable unicast address
+func publicAddr(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), which
+// net.IP.IsPrivate doesn't cover
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// resolveImage makes an og:image reference absolute against the page URL,
+// dropping anything that isn't http(s)
+func resolveImage(page *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := page.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
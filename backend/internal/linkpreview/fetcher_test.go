@@ -0,0 +1,119 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><meta property="og:title" content="Teapots"><meta property="og:image" content="/pot.jpg"></head></html>`)
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		fmt.Fprint(w, "%PDF-1.7")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetcher_BlocksLoopback(t *testing.T) {
+	srv := newPageServer(t)
+	f := NewFetcher(time.Second, 60)
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/page")
+	assert.ErrorIs(t, err, ErrBlockedAddress, "test servers listen on loopback")
+
+	_, err = f.Fetch(context.Background(), "file:///etc/passwd")
+	assert.ErrorIs(t, err, ErrUnsupportedURL)
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	srv := newPageServer(t)
+	f := NewFetcher(time.Second, 60)
+	f.allowAddr = func(net.IP) bool { return true }
+
+	preview, err := f.Fetch(context.Background(), srv.URL+"/page")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/page", preview.URL)
+	assert.Equal(t, "Teapots", preview.Title)
+	assert.Equal(t, srv.URL+"/pot.jpg", preview.ImageURL, "relative images are made absolute")
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/file")
+	assert.ErrorIs(t, err, ErrNotHTML)
+}
+
+func TestFetcher_HostRateLimit(t *testing.T) {
+	srv := newPageServer(t)
+	f := NewFetcher(time.Second, 1)
+	f.allowAddr = func(net.IP) bool { return true }
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/page")
+	require.NoError(t, err)
+	_, err = f.Fetch(context.Background(), srv.URL+"/page")
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestSiteKey(t *testing.T) {
+	cases := map[string]string{
+		"example.com":     "example.com",
+		"a.b.Example.COM": "example.com",
+		"news.bbc.co.uk":  "bbc.co.uk",
+		"user.github.io":  "user.github.io",
+		"example.com.":    "example.com",
+		"203.0.113.7":     "203.0.113.7",
+		"2001:db8::1":     "2001:db8::1",
+		"localhost":       "localhost",
+		"co.uk":           "co.uk",
+	}
+	for host, want := range cases {
+		assert.Equal(t, want, siteKey(host), host)
+	}
+}
+
+func TestFetcher_SubdomainsShareABudget(t *testing.T) {
+	f := NewFetcher(time.Second, 1)
+
+	assert.True(t, f.limiter(siteKey("a.example.com")).Allow())
+	assert.False(t, f.limiter(siteKey("b.example.com")).Allow(), "a fresh subdomain doesn't get a fresh budget")
+	assert.True(t, f.limiter(siteKey("example.org")).Allow())
+}
+
+func TestFetcher_ForgetsIdleSites(t *testing.T) {
+	f := NewFetcher(time.Second, 10)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	f.limiter("example.com")
+	f.limiter("example.org")
+	assert.Len(t, f.hosts, 2)
+
+	now = now.Add(f.hostIdle / 2)
+	f.limiter("example.org")
+	now = now.Add(f.hostIdle/2 + time.Millisecond)
+	f.limiter("example.net")
+	assert.Len(t, f.hosts, 2, "a site idle long enough to refill is dropped")
+	assert.Contains(t, f.hosts, "example.org")
+	assert.Contains(t, f.hosts, "example.net")
+}
+
+func TestPublicAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "172.16.5.5", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1"} {
+		assert.False(t, publicAddr(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:4700::1111"} {
+		assert.True(t, publicAddr(net.ParseIP(addr)), addr)
+	}
+}
@@ -0,0 +1,104 @@
+package linkpreview
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Tags are the preview fields read from a page
+type Tags struct {
+	Title       string
+	Description string
+	Image       string // as written in the page; may be relative
+}
+
+// ParseOpenGraph reads og:title, og:description and og:image from an HTML
+// page, falling back to <title> and the description meta tag. It stops at
+// </head> (or <body>), since that's where the tags belong.
+func ParseOpenGraph(r io.Reader) Tags {
+	var og, fallback Tags
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return og.or(fallback)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.Body:
+				return og.or(fallback)
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				key, content := metaTag(tok)
+				switch key {
+				case "og:title":
+					og.Title = setOnce(og.Title, content)
+				case "og:description":
+					og.Description = setOnce(og.Description, content)
+				case "og:image", "og:image:url", "og:image:secure_url":
+					og.Image = setOnce(og.Image, content)
+				case "description":
+					fallback.Description = setOnce(fallback.Description, content)
+				}
+			}
+
+		case html.TextToken:
+			if inTitle {
+				fallback.Title += string(z.Text())
+			}
+
+		case html.EndTagToken:
+			switch z.Token().DataAtom {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				return og.or(fallback)
+			}
+		}
+	}
+}
+
+// or fills fields t left empty from other, cleaning up whitespace
+func (t Tags) or(other Tags) Tags {
+	pick := func(a, b string) string {
+		if a = strings.Join(strings.Fields(a), " "); a != "" {
+			return a
+		}
+		return strings.Join(strings.Fields(b), " ")
+	}
+	return Tags{
+		Title:       pick(t.Title, other.Title),
+		Description: pick(t.Description, other.Description),
+		Image:       strings.TrimSpace(pick(t.Image, other.Image)),
+	}
+}
+
+// metaTag returns a <meta> tag's property (or name), lowercased, and content
+func metaTag(tok html.Token) (key, content string) {
+	for _, a := range tok.Attr {
+		switch a.Key {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(a.Val))
+			}
+		case "content":
+			content = a.Val
+		}
+	}
+	return key, content
+}
+
+// setOnce keeps the first non-blank value seen for a tag
+func setOnce(current, value string) string {
+	if strings.TrimSpace(current) != "" {
+		return current
+	}
+	return value
+}
@@ -0,0 +1,31 @@
+package linkpreview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOpenGraph(t *testing.T) {
+	page := `<!doctype html><html><head>
+		<title>Fallback title</title>
+		<meta name="description" content="Fallback description">
+		<meta property="og:title" content="  Green tea,
+			explained ">
+		<meta property="og:image" content="/img/cup.png">
+		<meta property="og:image" content="/img/second.png">
+	</head><body><meta property="og:description" content="not in head"></body></html>`
+
+	tags := ParseOpenGraph(strings.NewReader(page))
+	assert.Equal(t, "Green tea, explained", tags.Title, "whitespace is collapsed")
+	assert.Equal(t, "Fallback description", tags.Description, "tags after <head> are ignored")
+	assert.Equal(t, "/img/cup.png", tags.Image, "the first image wins")
+}
+
+func TestParseOpenGraph_Fallbacks(t *testing.T) {
+	tags := ParseOpenGraph(strings.NewReader(`<html><head><title>Just a title</title></head></html>`))
+	assert.Equal(t, Tags{Title: "Just a title"}, tags)
+
+	assert.Equal(t, Tags{}, ParseOpenGraph(strings.NewReader("not html at all")))
+}
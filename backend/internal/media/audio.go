@@ -0,0 +1,50 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// ProbeDuration reads a clip's length in milliseconds from its first bytes.
+// Only WAV is supported, since it's the one common audio format whose
+// header records enough to tell; for anything else ok is false.
+func ProbeDuration(mimeType string, head []byte) (durationMs int64, ok bool) {
+	switch baseType(mimeType) {
+	case "audio/wav", "audio/wave", "audio/x-wav", "audio/vnd.wave":
+		return wavDuration(head)
+	}
+	return 0, false
+}
+
+// wavDuration walks a RIFF/WAVE header for the fmt chunk's byte rate and
+// the data chunk's size
+func wavDuration(head []byte) (int64, bool) {
+	if len(head) < 12 || !bytes.Equal(head[0:4], []byte("RIFF")) || !bytes.Equal(head[8:12], []byte("WAVE")) {
+		return 0, false
+	}
+
+	var byteRate uint32
+	for pos := 12; pos+8 <= len(head); {
+		id := head[pos : pos+4]
+		size := binary.LittleEndian.Uint32(head[pos+4 : pos+8])
+		body := pos + 8
+
+		switch {
+		case bytes.Equal(id, []byte("fmt ")):
+			if size < 12 || body+12 > len(head) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(head[body+8 : body+12])
+		case bytes.Equal(id, []byte("data")):
+			// Streaming writers leave the size unset
+			if byteRate == 0 || size == 0 || size == 0xFFFFFFFF {
+				return 0, false
+			}
+			return int64(size) * 1000 / int64(byteRate), true
+		}
+
+		// Chunks are padded to an even length
+		pos = body + int(size) + int(size&1)
+	}
+	return 0, false
+}
@@ -0,0 +1,51 @@
+package media
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wavHeader builds a PCM WAV header: 16kHz mono 16-bit is 32000 bytes/s
+func wavHeader(dataSize uint32, extra ...byte) []byte {
+	le := binary.LittleEndian
+	h := []byte("RIFF\x00\x00\x00\x00WAVE")
+	h = append(h, "fmt "...)
+	h = le.AppendUint32(h, 16)
+	h = le.AppendUint16(h, 1)     // PCM
+	h = le.AppendUint16(h, 1)     // channels
+	h = le.AppendUint32(h, 16000) // sample rate
+	h = le.AppendUint32(h, 32000) // byte rate
+	h = le.AppendUint16(h, 2)     // block align
+	h = le.AppendUint16(h, 16)    // bits per sample
+	if len(extra) > 0 {
+		h = append(h, "LIST"...)
+		h = le.AppendUint32(h, uint32(len(extra)))
+		h = append(h, extra...)
+		if len(extra)%2 == 1 {
+			h = append(h, 0)
+		}
+	}
+	h = append(h, "data"...)
+	return le.AppendUint32(h, dataSize)
+}
+
+func TestProbeDuration(t *testing.T) {
+	d, ok := ProbeDuration("audio/wav", wavHeader(48000))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1500), d)
+
+	d, ok = ProbeDuration("audio/x-wav", wavHeader(32000, 'a', 'b', 'c'))
+	assert.True(t, ok, "odd-sized chunks before data are skipped with their padding")
+	assert.Equal(t, int64(1000), d)
+
+	_, ok = ProbeDuration("audio/wav", wavHeader(0xFFFFFFFF))
+	assert.False(t, ok, "streamed WAVs have no data size")
+
+	_, ok = ProbeDuration("audio/wav", pngHead)
+	assert.False(t, ok)
+
+	_, ok = ProbeDuration("audio/ogg", wavHeader(48000))
+	assert.False(t, ok, "only WAV headers record a duration")
+}
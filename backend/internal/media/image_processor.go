@@ -0,0 +1,169 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// maxProcessBytes bounds how large an upload we'll download to process
+const maxProcessBytes = 20 * 1024 * 1024
+
+// imageQueueSize bounds how many uploads may wait for processing; beyond it
+// new uploads are left as uploaded rather than pile up
+const imageQueueSize = 256
+
+// ImageStorage reads and writes stored objects
+type ImageStorage interface {
+	GetObject(ctx context.Context, objectKey string, maxBytes int64) ([]byte, error)
+	PutObject(ctx context.Context, objectKey string, contentType string, data []byte) error
+}
+
+// ImageRecords records what processing did to an attachment
+type ImageRecords interface {
+	// SetAttachmentContent records the size and checksum of a rewritten object
+	SetAttachmentContent(ctx context.Context, id string, sizeBytes int64, sha256 string) error
+	SetThumbnailSizes(ctx context.Context, id string, sizes []int) error
+}
+
+// ImageProcessor straightens and thumbnails uploaded images in the
+// background, so decoding them never holds up an upload request. It's best
+// effort: clients show the original until (or unless) it's done.
+type ImageProcessor struct {
+	records        ImageRecords
+	storage        ImageStorage
+	autoOrient     bool
+	thumbnailSizes []int
+	queue          chan domain.Attachment
+	logger         *slog.Logger
+}
+
+// NewImageProcessor creates a processor that generates the default thumbnail
+// sizes; call Run to start it
+func NewImageProcessor(records ImageRecords, storage ImageStorage, logger *slog.Logger) *ImageProcessor {
+	return &ImageProcessor{
+		records:        records,
+		storage:        storage,
+		thumbnailSizes: DefaultThumbnailSizes,
+		queue:          make(chan domain.Attachment, imageQueueSize),
+		logger:         logger,
+	}
+}
+
+// SetAutoOrient sets whether JPEGs are rewritten with their EXIF orientation applied
+func (p *ImageProcessor) SetAutoOrient(enabled bool) {
+	p.autoOrient = enabled
+}
+
+// SetThumbnailSizes configures the thumbnail variants generated, by longest
+// edge in pixels. An empty list disables thumbnails.
+func (p *ImageProcessor) SetThumbnailSizes(sizes []int) {
+	p.thumbnailSizes = sizes
+}
+
+// Enqueue schedules a finished upload for processing if it's an image there's
+// work to do on. It never blocks: when the queue is full the upload is left
+// as it is.
+func (p *ImageProcessor) Enqueue(att *domain.Attachment) bool {
+	if p == nil || !p.wants(att) {
+		return false
+	}
+
+	select {
+	case p.queue <- *att:
+		return true
+	default:
+		p.logger.Warn("image processing queue full, skipping", "attachment_id", att.ID)
+		return false
+	}
+}
+
+// wants reports whether processing would do anything for the attachment
+func (p *ImageProcessor) wants(att *domain.Attachment) bool {
+	if att.SizeBytes > maxProcessBytes {
+		return false
+	}
+	orient := p.autoOrient && att.MimeType == "image/jpeg"
+	thumbnail := len(p.thumbnailSizes) > 0 && CanThumbnail(att.MimeType)
+	return orient || thumbnail
+}
+
+// Run processes queued uploads until ctx is cancelled
+func (p *ImageProcessor) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case att := <-p.queue:
+			p.Process(ctx, &att)
+		}
+	}
+}
+
+// Process straightens a sideways JPEG, recording its new size and checksum,
+// then stores a JPEG variant for each configured size and records which ones
+// exist. Failures are logged and leave the original in place.
+func (p *ImageProcessor) Process(ctx context.Context, att *domain.Attachment) {
+	data, err := p.storage.GetObject(ctx, att.ObjectKey, maxProcessBytes)
+	if err != nil {
+		p.logger.Warn("failed to read upload for processing", "error", err, "attachment_id", att.ID)
+		return
+	}
+
+	if p.autoOrient && att.MimeType == "image/jpeg" {
+		data = p.orient(ctx, att, data)
+	}
+	if len(p.thumbnailSizes) > 0 && CanThumbnail(att.MimeType) {
+		p.thumbnail(ctx, att, data)
+	}
+}
+
+// orient rewrites the stored JPEG upright, returning the data now stored
+func (p *ImageProcessor) orient(ctx context.Context, att *domain.Attachment, data []byte) []byte {
+	oriented, changed, err := AutoOrient(data)
+	if err != nil {
+		p.logger.Debug("auto-orient failed", "error", err, "attachment_id", att.ID)
+		return data
+	}
+	if !changed {
+		return data
+	}
+
+	if err := p.storage.PutObject(ctx, att.ObjectKey, att.MimeType, oriented); err != nil {
+		p.logger.Warn("failed to store oriented image", "error", err, "attachment_id", att.ID)
+		return data
+	}
+	// The stored object no longer matches what the client uploaded
+	sum := sha256.Sum256(oriented)
+	if err := p.records.SetAttachmentContent(ctx, att.ID, int64(len(oriented)), hex.EncodeToString(sum[:])); err != nil {
+		p.logger.Warn("failed to record oriented image", "error", err, "attachment_id", att.ID)
+	}
+	return oriented
+}
+
+// thumbnail stores the configured variants of an image and records them
+func (p *ImageProcessor) thumbnail(ctx context.Context, att *domain.Attachment, data []byte) {
+	thumbs, err := GenerateThumbnails(data, p.thumbnailSizes)
+	if err != nil {
+		p.logger.Debug("thumbnail generation failed", "error", err, "attachment_id", att.ID)
+		return
+	}
+	if len(thumbs) == 0 {
+		return
+	}
+
+	sizes := make([]int, 0, len(thumbs))
+	for _, thumb := range thumbs {
+		if err := p.storage.PutObject(ctx, ThumbnailKey(att.ObjectKey, thumb.Size), ThumbnailMimeType, thumb.Data); err != nil {
+			continue
+		}
+		sizes = append(sizes, thumb.Size)
+	}
+
+	if err := p.records.SetThumbnailSizes(ctx, att.ID, sizes); err != nil {
+		p.logger.Warn("failed to record thumbnails", "error", err, "attachment_id", att.ID)
+	}
+}
@@ -0,0 +1,79 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryObjects is an in-memory object store
+type memoryObjects map[string][]byte
+
+func (m memoryObjects) GetObject(_ context.Context, key string, _ int64) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m memoryObjects) PutObject(_ context.Context, key, _ string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+// recordedContent captures what the processor records about an attachment
+type recordedContent struct {
+	sizeBytes  int64
+	sha256     string
+	thumbnails []int
+}
+
+func (r *recordedContent) SetAttachmentContent(_ context.Context, _ string, sizeBytes int64, sha256 string) error {
+	r.sizeBytes, r.sha256 = sizeBytes, sha256
+	return nil
+}
+
+func (r *recordedContent) SetThumbnailSizes(_ context.Context, _ string, sizes []int) error {
+	r.thumbnails = sizes
+	return nil
+}
+
+func TestImageProcessor_OrientsAndThumbnails(t *testing.T) {
+	src := makeJPEG(t, 800, 400, OrientationRotate90CW)
+	objects := memoryObjects{"conv/a/photo.jpg": src}
+	records := &recordedContent{}
+	p := NewImageProcessor(records, objects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetAutoOrient(true)
+	p.SetThumbnailSizes([]int{100, 2000})
+
+	att := &domain.Attachment{ID: "a", ObjectKey: "conv/a/photo.jpg", MimeType: "image/jpeg", SizeBytes: int64(len(src))}
+	require.True(t, p.Enqueue(att))
+	p.Process(context.Background(), att)
+
+	// The stored object was rewritten upright, and its size and checksum recorded
+	stored := objects["conv/a/photo.jpg"]
+	assert.NotEqual(t, src, stored)
+	assert.Equal(t, OrientationNormal, ExifOrientation(stored))
+	sum := sha256.Sum256(stored)
+	assert.Equal(t, int64(len(stored)), records.sizeBytes)
+	assert.Equal(t, hex.EncodeToString(sum[:]), records.sha256)
+
+	// Only the variant smaller than the source is stored
+	assert.Equal(t, []int{100}, records.thumbnails)
+	assert.Contains(t, objects, ThumbnailKey("conv/a/photo.jpg", 100))
+}
+
+func TestImageProcessor_SkipsUploadsWithNothingToDo(t *testing.T) {
+	p := NewImageProcessor(&recordedContent{}, memoryObjects{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.False(t, p.Enqueue(&domain.Attachment{MimeType: "application/pdf", SizeBytes: 10}))
+	assert.False(t, p.Enqueue(&domain.Attachment{MimeType: "image/png", SizeBytes: maxProcessBytes + 1}))
+	assert.True(t, p.Enqueue(&domain.Attachment{MimeType: "image/png", SizeBytes: 10}))
+
+	var none *ImageProcessor
+	assert.False(t, none.Enqueue(&domain.Attachment{MimeType: "image/png", SizeBytes: 10}), "no processor configured")
+}
@@ -0,0 +1,96 @@
+package media
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// DefaultAllowedMimeTypes are the upload types accepted when none are
+// configured: images, audio, video, PDF and common documents. Entries ending
+// in "/" or "." match as prefixes.
+var DefaultAllowedMimeTypes = []string{
+	"image/", "video/", "audio/",
+	"application/pdf",
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument.",
+	"text/plain",
+}
+
+// SniffLen is how many leading bytes http.DetectContentType looks at
+const SniffLen = 512
+
+// unsniffableTypes are allowed types http.DetectContentType can't recognize,
+// so it reports them as application/octet-stream
+var unsniffableTypes = map[string]bool{
+	"application/msword": true, // OLE compound file
+	"audio/aac":          true,
+	"audio/flac":         true,
+	"audio/mp4":          true,
+	"audio/x-m4a":        true,
+	"image/heic":         true,
+	"image/heif":         true,
+	"video/quicktime":    true,
+	"video/x-matroska":   true,
+}
+
+// baseType lowercases a MIME type and drops any parameters
+func baseType(mimeType string) string {
+	if t, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return t
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// MimeTypeAllowed reports whether mimeType matches an entry in allowed
+func MimeTypeAllowed(mimeType string, allowed []string) bool {
+	t := baseType(mimeType)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if t == a || (strings.HasSuffix(a, "/") || strings.HasSuffix(a, ".")) && strings.HasPrefix(t, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckContent validates an upload's declared type against the allowlist
+// and against what its first bytes actually look like, so a renamed
+// executable can't pass as a PDF or image
+func CheckContent(declared string, head []byte, allowed []string) error {
+	if !MimeTypeAllowed(declared, allowed) {
+		return domain.ErrFileTypeNotAllowed
+	}
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	if !contentMatches(baseType(declared), baseType(http.DetectContentType(head))) {
+		return domain.ErrFileTypeMismatch
+	}
+	return nil
+}
+
+// contentMatches reports whether sniffed content is consistent with the
+// declared type. Sniffing only recognizes some formats and names containers
+// rather than codecs, so related types are accepted too.
+func contentMatches(declared, sniffed string) bool {
+	if declared == sniffed {
+		return true
+	}
+	family := func(t string) string { return t[:strings.IndexByte(t+"/", '/')] }
+
+	switch {
+	case sniffed == "application/octet-stream":
+		return unsniffableTypes[declared]
+	case family(declared) == family(sniffed) && (family(sniffed) == "image" || family(sniffed) == "audio" || family(sniffed) == "video"):
+		return true // e.g. image/jpg vs image/jpeg, audio/x-wav vs audio/wave
+	case sniffed == "video/mp4" || sniffed == "video/webm" || sniffed == "application/ogg":
+		// Audio-only files in a video or Ogg container
+		return family(declared) == "audio" || family(declared) == "video"
+	case sniffed == "application/zip":
+		return strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument.")
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package media
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+var (
+	pdfHead = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n")
+	pngHead = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	exeHead = append([]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00"), bytes.Repeat([]byte{0}, 48)...)
+	zipHead = []byte("PK\x03\x04\x14\x00\x06\x00\x08\x00\x00\x00!\x00")
+	mp4Head = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	movHead = []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00qt  \x00\x00\x00\x08wide")
+)
+
+func TestMimeTypeAllowed(t *testing.T) {
+	assert.True(t, MimeTypeAllowed("image/png", DefaultAllowedMimeTypes))
+	assert.True(t, MimeTypeAllowed("Text/Plain; charset=utf-8", DefaultAllowedMimeTypes), "parameters and case are ignored")
+	assert.True(t, MimeTypeAllowed("application/vnd.openxmlformats-officedocument.wordprocessingml.document", DefaultAllowedMimeTypes))
+	assert.False(t, MimeTypeAllowed("application/x-msdownload", DefaultAllowedMimeTypes))
+	assert.False(t, MimeTypeAllowed("application/pdfx", DefaultAllowedMimeTypes), "exact entries don't match as prefixes")
+	assert.False(t, MimeTypeAllowed("image/png", []string{"application/pdf"}))
+}
+
+func TestCheckContent(t *testing.T) {
+	assert.NoError(t, CheckContent("application/pdf", pdfHead, DefaultAllowedMimeTypes))
+	assert.NoError(t, CheckContent("image/png", pngHead, DefaultAllowedMimeTypes))
+	assert.NoError(t, CheckContent("text/plain", []byte("hello there"), DefaultAllowedMimeTypes))
+	assert.NoError(t, CheckContent("application/vnd.openxmlformats-officedocument.wordprocessingml.document", zipHead, DefaultAllowedMimeTypes), "docx files are zip archives")
+	assert.NoError(t, CheckContent("audio/mp4", mp4Head, DefaultAllowedMimeTypes), "audio in an MP4 container")
+	assert.NoError(t, CheckContent("video/quicktime", movHead, DefaultAllowedMimeTypes), "formats sniffing can't recognize are let through")
+
+	assert.ErrorIs(t, CheckContent("application/x-msdownload", exeHead, DefaultAllowedMimeTypes), domain.ErrFileTypeNotAllowed)
+	assert.ErrorIs(t, CheckContent("application/zip", zipHead, DefaultAllowedMimeTypes), domain.ErrFileTypeNotAllowed)
+}
+
+func TestCheckContent_SpoofedContentType(t *testing.T) {
+	// An executable uploaded with a harmless-looking Content-Type
+	for _, declared := range []string{"application/pdf", "image/png", "text/plain"} {
+		assert.ErrorIs(t, CheckContent(declared, exeHead, DefaultAllowedMimeTypes), domain.ErrFileTypeMismatch, declared)
+	}
+
+	assert.ErrorIs(t, CheckContent("image/png", pdfHead, DefaultAllowedMimeTypes), domain.ErrFileTypeMismatch, "a PDF isn't an image")
+	assert.ErrorIs(t, CheckContent("text/plain", []byte("<html><script>alert(1)</script>"), DefaultAllowedMimeTypes), domain.ErrFileTypeMismatch)
+	assert.ErrorIs(t, CheckContent("application/pdf", zipHead, DefaultAllowedMimeTypes), domain.ErrFileTypeMismatch)
+	assert.ErrorIs(t, CheckContent("application/msword", pngHead, DefaultAllowedMimeTypes), domain.ErrFileTypeMismatch, "unsniffable types still reject recognizable content")
+}
@@ -0,0 +1,98 @@
+package media
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// objectReaperBatch bounds how many deleted objects one sweep removes
+const objectReaperBatch = 100
+
+// DeletedObjectStore queues the stored objects of deleted attachments
+type DeletedObjectStore interface {
+	// PendingObjectDeletions returns up to limit objects waiting to be removed
+	PendingObjectDeletions(ctx context.Context, limit int) ([]domain.DeletedObject, error)
+	// ForgetObjectDeletions drops objects once they're gone from storage
+	ForgetObjectDeletions(ctx context.Context, objectKeys []string) error
+}
+
+// ObjectDeleter removes objects from storage
+type ObjectDeleter interface {
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// ObjectReaper periodically removes deleted attachments' objects and
+// thumbnails from storage
+type ObjectReaper struct {
+	store    DeletedObjectStore
+	storage  ObjectDeleter
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+// NewObjectReaper creates a reaper that runs every interval
+func NewObjectReaper(store DeletedObjectStore, storage ObjectDeleter, interval time.Duration, logger *slog.Logger) *ObjectReaper {
+	return &ObjectReaper{
+		store:    store,
+		storage:  storage,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run reaps until ctx is cancelled
+func (r *ObjectReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Reap(ctx)
+		}
+	}
+}
+
+// Reap removes a batch of deleted objects from storage. Objects that fail to
+// delete stay queued for the next run. Returns how many were removed.
+func (r *ObjectReaper) Reap(ctx context.Context) int {
+	pending, err := r.store.PendingObjectDeletions(ctx, objectReaperBatch)
+	if err != nil {
+		r.logger.Error("failed to list deleted objects", "error", err)
+		return 0
+	}
+
+	var done []string
+	for _, obj := range pending {
+		if err := r.deleteObject(ctx, obj); err != nil {
+			r.logger.Error("failed to delete stored object", "error", err, "object_key", obj.ObjectKey)
+			continue
+		}
+		done = append(done, obj.ObjectKey)
+	}
+	if len(done) == 0 {
+		return 0
+	}
+
+	if err := r.store.ForgetObjectDeletions(ctx, done); err != nil {
+		r.logger.Error("failed to forget deleted objects", "error", err)
+		return 0
+	}
+	r.logger.Info("removed deleted attachment objects", "count", len(done))
+	return len(done)
+}
+
+// deleteObject removes an object's thumbnails, then the object itself
+func (r *ObjectReaper) deleteObject(ctx context.Context, obj domain.DeletedObject) error {
+	for _, size := range obj.ThumbnailSizes {
+		if err := r.storage.DeleteObject(ctx, ThumbnailKey(obj.ObjectKey, size)); err != nil {
+			return err
+		}
+	}
+	return r.storage.DeleteObject(ctx, obj.ObjectKey)
+}
@@ -0,0 +1,91 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryDeletionStore mimics the storage_deletions table
+type memoryDeletionStore struct {
+	pending []domain.DeletedObject
+}
+
+func (s *memoryDeletionStore) PendingObjectDeletions(_ context.Context, limit int) ([]domain.DeletedObject, error) {
+	if len(s.pending) > limit {
+		return s.pending[:limit], nil
+	}
+	return s.pending, nil
+}
+
+func (s *memoryDeletionStore) ForgetObjectDeletions(_ context.Context, objectKeys []string) error {
+	var kept []domain.DeletedObject
+	for _, obj := range s.pending {
+		forget := false
+		for _, key := range objectKeys {
+			if obj.ObjectKey == key {
+				forget = true
+			}
+		}
+		if !forget {
+			kept = append(kept, obj)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+// recordingDeleter records deleted keys, failing for those in fail
+type recordingDeleter struct {
+	deleted []string
+	fail    map[string]bool
+}
+
+func (d *recordingDeleter) DeleteObject(_ context.Context, key string) error {
+	if d.fail[key] {
+		return errors.New("storage unavailable")
+	}
+	d.deleted = append(d.deleted, key)
+	return nil
+}
+
+func TestObjectReaper_RemovesObjectsAndThumbnails(t *testing.T) {
+	store := &memoryDeletionStore{pending: []domain.DeletedObject{
+		{ObjectKey: "conv/a/photo.jpg", ThumbnailSizes: []int{160, 480}},
+		{ObjectKey: "conv/a/notes.pdf"},
+	}}
+	deleter := &recordingDeleter{}
+	reaper := NewObjectReaper(store, deleter, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Equal(t, 2, reaper.Reap(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"conv/a/photo.jpg",
+		ThumbnailKey("conv/a/photo.jpg", 160),
+		ThumbnailKey("conv/a/photo.jpg", 480),
+		"conv/a/notes.pdf",
+	}, deleter.deleted)
+	assert.Empty(t, store.pending)
+}
+
+func TestObjectReaper_KeepsFailedDeletionsQueued(t *testing.T) {
+	store := &memoryDeletionStore{pending: []domain.DeletedObject{
+		{ObjectKey: "conv/a/photo.jpg", ThumbnailSizes: []int{160}},
+		{ObjectKey: "conv/a/notes.pdf"},
+	}}
+	deleter := &recordingDeleter{fail: map[string]bool{ThumbnailKey("conv/a/photo.jpg", 160): true}}
+	reaper := NewObjectReaper(store, deleter, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Equal(t, 1, reaper.Reap(context.Background()))
+	assert.Equal(t, []domain.DeletedObject{{ObjectKey: "conv/a/photo.jpg", ThumbnailSizes: []int{160}}}, store.pending)
+
+	// The next run retries once storage recovers
+	deleter.fail = nil
+	assert.Equal(t, 1, reaper.Reap(context.Background()))
+	assert.Empty(t, store.pending)
+}
@@ -0,0 +1,196 @@
+// Package media holds server-side processing for uploaded attachments.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// JPEG quality used when re-encoding a rotated image
+const orientJPEGQuality = 90
+
+// MaxImagePixels bounds the width×height of images we decode. A small file
+// can declare huge dimensions, and decoding allocates for all of them.
+const MaxImagePixels = 50_000_000
+
+// EXIF orientation values (TIFF tag 0x0112)
+const (
+	OrientationNormal      = 1
+	OrientationFlipH       = 2
+	OrientationRotate180   = 3
+	OrientationFlipV       = 4
+	OrientationTranspose   = 5
+	OrientationRotate90CW  = 6
+	OrientationTransverse  = 7
+	OrientationRotate90CCW = 8
+)
+
+const (
+	exifOrientationTag = 0x0112
+
+	// JPEG markers
+	markerSOI  = 0xD8
+	markerAPP1 = 0xE1
+	markerSOS  = 0xDA
+	markerEOI  = 0xD9
+	markerRST0 = 0xD0
+	markerTEM  = 0x01
+)
+
+// ExifOrientation returns the EXIF orientation of a JPEG, or OrientationNormal
+// if the data is not a JPEG or carries no orientation tag.
+func ExifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return OrientationNormal
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return OrientationNormal
+		}
+		marker := data[pos+1]
+		if marker == markerSOS || marker == markerEOI {
+			break
+		}
+		// Standalone markers (RSTn, TEM) carry no length
+		if (marker >= markerRST0 && marker <= markerRST0+7) || marker == markerTEM {
+			pos += 2
+			continue
+		}
+
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if segLen < 2 || pos+2+segLen > len(data) {
+			return OrientationNormal
+		}
+		segment := data[pos+4 : pos+2+segLen]
+		if marker == markerAPP1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseTIFFOrientation(segment[6:])
+		}
+		pos += 2 + segLen
+	}
+	return OrientationNormal
+}
+
+// parseTIFFOrientation reads the orientation tag from IFD0 of a TIFF block
+func parseTIFFOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return OrientationNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return OrientationNormal
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) == exifOrientationTag {
+			v := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if v >= OrientationNormal && v <= OrientationRotate90CCW {
+				return v
+			}
+			return OrientationNormal
+		}
+	}
+	return OrientationNormal
+}
+
+// CheckImagePixels reads an image's header and returns
+// domain.ErrImageTooLarge if decoding it would exceed MaxImagePixels
+func CheckImagePixels(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return domain.ErrImageTooLarge
+	}
+	return nil
+}
+
+// AutoOrient applies a JPEG's EXIF orientation to its pixels and re-encodes
+// it. The re-encoded image carries no EXIF, so clients can render it as-is.
+// It returns the original data and false when no rotation is needed.
+func AutoOrient(data []byte) ([]byte, bool, error) {
+	orientation := ExifOrientation(data)
+	if orientation == OrientationNormal {
+		return data, false, nil
+	}
+	if err := CheckImagePixels(data); err != nil {
+		return data, false, err
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, false, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Orient(img, orientation), &jpeg.Options{Quality: orientJPEGQuality}); err != nil {
+		return data, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// Orient returns img transformed so that it displays upright for the given
+// EXIF orientation.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= OrientationNormal || orientation > OrientationRotate90CCW {
+		return img
+	}
+
+	src := image.NewRGBA(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	minX, minY := src.Bounds().Min.X, src.Bounds().Min.Y
+
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= OrientationTranspose {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case OrientationFlipH:
+				dx, dy = w-1-x, y
+			case OrientationRotate180:
+				dx, dy = w-1-x, h-1-y
+			case OrientationFlipV:
+				dx, dy = x, h-1-y
+			case OrientationTranspose:
+				dx, dy = y, x
+			case OrientationRotate90CW:
+				dx, dy = h-1-y, x
+			case OrientationTransverse:
+				dx, dy = h-1-y, w-1-x
+			case OrientationRotate90CCW:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(minX+x, minY+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,133 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeJPEG encodes a w×h image whose left half is red and right half is blue,
+// optionally tagged with an EXIF orientation.
+func makeJPEG(t *testing.T, w, h, orientation int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// Little-endian TIFF with a single IFD0 entry for orientation
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // value padding + next IFD offset
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, markerAPP1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func isReddish(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > b*2
+}
+
+func isBluish(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return b > r*2
+}
+
+func TestExifOrientation_ReadsTag(t *testing.T) {
+	assert.Equal(t, OrientationRotate90CW, ExifOrientation(makeJPEG(t, 32, 16, OrientationRotate90CW)))
+	assert.Equal(t, OrientationNormal, ExifOrientation(makeJPEG(t, 32, 16, 0)))
+	assert.Equal(t, OrientationNormal, ExifOrientation([]byte("not a jpeg")))
+}
+
+func TestAutoOrient_RotatesAndStripsTag(t *testing.T) {
+	in := makeJPEG(t, 32, 16, OrientationRotate90CW)
+
+	out, changed, err := AutoOrient(in)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// Tag removed
+	assert.False(t, bytes.Contains(out, []byte("Exif\x00\x00")))
+	assert.Equal(t, OrientationNormal, ExifOrientation(out))
+
+	// Physically rotated 90° clockwise: landscape becomes portrait and the
+	// left (red) half ends up on top
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 16, img.Bounds().Dx())
+	assert.Equal(t, 32, img.Bounds().Dy())
+	assert.True(t, isReddish(img.At(8, 4)), "top should be red")
+	assert.True(t, isBluish(img.At(8, 28)), "bottom should be blue")
+}
+
+// withDeclaredSize rewrites a JPEG's frame header to claim w×h pixels,
+// leaving the (much smaller) encoded data alone
+func withDeclaredSize(t *testing.T, data []byte, w, h int) []byte {
+	t.Helper()
+	out := bytes.Clone(data)
+	sof := bytes.Index(out, []byte{0xFF, 0xC0})
+	require.NotEqual(t, -1, sof, "baseline JPEG frame header")
+	binary.BigEndian.PutUint16(out[sof+5:], uint16(h))
+	binary.BigEndian.PutUint16(out[sof+7:], uint16(w))
+	return out
+}
+
+func TestAutoOrient_RejectsImagesOverPixelCap(t *testing.T) {
+	in := withDeclaredSize(t, makeJPEG(t, 32, 16, OrientationRotate90CW), 60000, 60000)
+
+	out, changed, err := AutoOrient(in)
+	assert.ErrorIs(t, err, domain.ErrImageTooLarge)
+	assert.False(t, changed)
+	assert.Equal(t, in, out)
+}
+
+func TestAutoOrient_NoTagLeavesImageUntouched(t *testing.T) {
+	in := makeJPEG(t, 32, 16, 0)
+
+	out, changed, err := AutoOrient(in)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, in, out)
+}
+
+func TestOrient_AllOrientationsKeepPixelCount(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for o := OrientationNormal; o <= OrientationRotate90CCW; o++ {
+		b := Orient(src, o).Bounds()
+		assert.Equal(t, 8, b.Dx()*b.Dy(), "orientation %d", o)
+		if o >= OrientationTranspose {
+			assert.Equal(t, 2, b.Dx(), "orientation %d should swap dimensions", o)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Register decoders for the other formats we thumbnail
+	_ "image/gif"
+	_ "image/png"
+)
+
+// DefaultThumbnailSizes are the thumbnail variants generated for image
+// uploads, by longest edge in pixels: a grid tile, an inline preview and a
+// full-screen view.
+var DefaultThumbnailSizes = []int{96, 400, 1080}
+
+// JPEG quality used for thumbnails
+const thumbnailJPEGQuality = 80
+
+// ThumbnailMimeType is the content type every thumbnail is stored as
+const ThumbnailMimeType = "image/jpeg"
+
+// Thumbnail is one generated variant of an image
+type Thumbnail struct {
+	Size   int // longest edge the variant was asked for
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// ThumbnailKey returns the storage key for a variant of the object
+func ThumbnailKey(objectKey string, size int) string {
+	return fmt.Sprintf("%s.thumb_%d.jpg", objectKey, size)
+}
+
+// URLSigner issues time-limited download URLs for stored objects
+type URLSigner interface {
+	GeneratePresignedGetURL(ctx context.Context, objectKey string, expiry time.Duration) (string, error)
+}
+
+// ThumbnailURLs signs a download URL for each stored variant of the object,
+// keyed by size. Variants that can't be signed are left out, so clients fall
+// back to the original. Returns nil when there are no variants.
+func ThumbnailURLs(ctx context.Context, signer URLSigner, objectKey string, sizes []int, expiry time.Duration) map[string]string {
+	if len(sizes) == 0 {
+		return nil
+	}
+	urls := make(map[string]string, len(sizes))
+	for _, size := range sizes {
+		url, err := signer.GeneratePresignedGetURL(ctx, ThumbnailKey(objectKey, size), expiry)
+		if err != nil {
+			continue
+		}
+		urls[strconv.Itoa(size)] = url
+	}
+	return urls
+}
+
+// CanThumbnail reports whether GenerateThumbnails can decode the mime type
+func CanThumbnail(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// ParseThumbnailSizes parses a list of variant sizes, returning them sorted
+// and deduplicated. Every size must be a positive integer.
+func ParseThumbnailSizes(values []string) ([]int, error) {
+	seen := make(map[int]bool, len(values))
+	sizes := make([]int, 0, len(values))
+	for _, v := range values {
+		size, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid thumbnail size %q", v)
+		}
+		if !seen[size] {
+			seen[size] = true
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes, nil
+}
+
+// GenerateThumbnails decodes an image and produces a JPEG variant for each
+// size, scaled so its longest edge equals the size. Variants that would be as
+// large as or larger than the source are skipped, since the original serves
+// them better. EXIF orientation is applied so variants display upright.
+// Images over MaxImagePixels are refused before decoding.
+func GenerateThumbnails(data []byte, sizes []int) ([]Thumbnail, error) {
+	if err := CheckImagePixels(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img = Orient(img, ExifOrientation(data))
+
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	longest := max(srcW, srcH)
+
+	thumbs := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		if size <= 0 || size >= longest {
+			continue
+		}
+
+		w, h := size, size
+		if srcW >= srcH {
+			h = max(1, srcH*size/srcW)
+		} else {
+			w = max(1, srcW*size/srcH)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleDown(img, w, h), &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+			return nil, err
+		}
+		thumbs = append(thumbs, Thumbnail{Size: size, Width: w, Height: h, Data: buf.Bytes()})
+	}
+	return thumbs, nil
+}
+
+// scaleDown shrinks img to w×h by averaging the source pixels that fall in
+// each destination pixel. Transparent areas are composited onto white, since
+// thumbnails are JPEGs.
+func scaleDown(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Over)
+
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for dy := 0; dy < h; dy++ {
+		y0, y1 := dy*srcH/h, max((dy+1)*srcH/h, dy*srcH/h+1)
+		for dx := 0; dx < w; dx++ {
+			x0, x1 := dx*srcW/w, max((dx+1)*srcW/w, dx*srcW/w+1)
+
+			var r, g, bl, n uint32
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					px := row[x*4:]
+					r += uint32(px[0])
+					g += uint32(px[1])
+					bl += uint32(px[2])
+					n++
+				}
+			}
+			off := dy*dst.Stride + dx*4
+			dst.Pix[off] = uint8(r / n)
+			dst.Pix[off+1] = uint8(g / n)
+			dst.Pix[off+2] = uint8(bl / n)
+			dst.Pix[off+3] = 0xFF
+		}
+	}
+	return dst
+}
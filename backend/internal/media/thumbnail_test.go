@@ -0,0 +1,133 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateThumbnails_ProducesConfiguredVariants(t *testing.T) {
+	src := makeJPEG(t, 1200, 600, 0)
+
+	thumbs, err := GenerateThumbnails(src, []int{96, 400, 1080})
+	require.NoError(t, err)
+	require.Len(t, thumbs, 3)
+
+	for i, want := range []struct{ size, w, h int }{{96, 96, 48}, {400, 400, 200}, {1080, 1080, 540}} {
+		assert.Equal(t, want.size, thumbs[i].Size)
+		assert.Equal(t, want.w, thumbs[i].Width)
+		assert.Equal(t, want.h, thumbs[i].Height)
+
+		img, err := jpeg.Decode(bytes.NewReader(thumbs[i].Data))
+		require.NoError(t, err, "variants are stored as JPEG")
+		assert.Equal(t, want.w, img.Bounds().Dx())
+		assert.Equal(t, want.h, img.Bounds().Dy())
+	}
+
+	// Scaling keeps the picture: red stays left, blue stays right
+	img, err := jpeg.Decode(bytes.NewReader(thumbs[1].Data))
+	require.NoError(t, err)
+	assert.True(t, isReddish(img.At(50, 100)))
+	assert.False(t, isReddish(img.At(350, 100)))
+}
+
+func TestGenerateThumbnails_RefusesImagesOverPixelCap(t *testing.T) {
+	src := withDeclaredSize(t, makeJPEG(t, 64, 64, 0), 60000, 60000)
+
+	_, err := GenerateThumbnails(src, []int{96})
+	assert.ErrorIs(t, err, domain.ErrImageTooLarge)
+}
+
+func TestGenerateThumbnails_SkipsVariantsLargerThanSource(t *testing.T) {
+	src := makeJPEG(t, 300, 500, 0)
+
+	thumbs, err := GenerateThumbnails(src, []int{96, 400, 500, 1080})
+	require.NoError(t, err)
+	require.Len(t, thumbs, 2, "sizes at or above the source's longest edge are skipped")
+
+	assert.Equal(t, 96, thumbs[0].Size)
+	assert.Equal(t, 57, thumbs[0].Width, "portrait images scale by height")
+	assert.Equal(t, 96, thumbs[0].Height)
+	assert.Equal(t, 400, thumbs[1].Size)
+}
+
+func TestGenerateThumbnails_AppliesOrientation(t *testing.T) {
+	// Stored landscape, displayed portrait
+	src := makeJPEG(t, 800, 400, OrientationRotate90CW)
+
+	thumbs, err := GenerateThumbnails(src, []int{200})
+	require.NoError(t, err)
+	require.Len(t, thumbs, 1)
+	assert.Equal(t, 100, thumbs[0].Width)
+	assert.Equal(t, 200, thumbs[0].Height)
+}
+
+func TestGenerateThumbnails_PNGWithTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 0})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	thumbs, err := GenerateThumbnails(buf.Bytes(), []int{96})
+	require.NoError(t, err)
+	require.Len(t, thumbs, 1)
+
+	out, err := jpeg.Decode(bytes.NewReader(thumbs[0].Data))
+	require.NoError(t, err)
+	r, g, b, _ := out.At(48, 48).RGBA()
+	assert.True(t, r > 0xF000 && g > 0xF000 && b > 0xF000, "transparent areas become white")
+}
+
+func TestGenerateThumbnails_NotAnImage(t *testing.T) {
+	_, err := GenerateThumbnails([]byte("%PDF-1.7"), DefaultThumbnailSizes)
+	assert.Error(t, err)
+}
+
+func TestParseThumbnailSizes(t *testing.T) {
+	sizes, err := ParseThumbnailSizes([]string{"1080", " 96", "400", "96"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{96, 400, 1080}, sizes)
+
+	_, err = ParseThumbnailSizes([]string{"96", "big"})
+	assert.Error(t, err)
+	_, err = ParseThumbnailSizes([]string{"0"})
+	assert.Error(t, err)
+}
+
+func TestThumbnailKey(t *testing.T) {
+	assert.Equal(t, "conv/abc/def.jpg.thumb_400.jpg", ThumbnailKey("conv/abc/def.jpg", 400))
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) GeneratePresignedGetURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	if strings.Contains(key, "thumb_400") {
+		return "", errors.New("signing failed")
+	}
+	return "https://cdn.example/" + key, nil
+}
+
+func TestThumbnailURLs(t *testing.T) {
+	urls := ThumbnailURLs(context.Background(), fakeSigner{}, "conv/a/b.png", []int{96, 400, 1080}, time.Hour)
+	assert.Equal(t, map[string]string{
+		"96":   "https://cdn.example/conv/a/b.png.thumb_96.jpg",
+		"1080": "https://cdn.example/conv/a/b.png.thumb_1080.jpg",
+	}, urls, "variants that fail to sign are left out")
+
+	assert.Nil(t, ThumbnailURLs(context.Background(), fakeSigner{}, "conv/a/b.png", nil, time.Hour))
+}
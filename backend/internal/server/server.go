@@ -33,6 +33,7 @@ type Dependencies struct {
 	CallHandler    *api.CallHandler
 	UploadHandler  *api.UploadHandler
 	OAuthHandler   *api.OAuthHandlers
+	AdminHandler   *api.AdminHandler
 	WSHandler      *websocket.Handler
 	StaticDir      string
 	Logger         *slog.Logger
@@ -101,14 +102,23 @@ func registerRoutes(mux *http.ServeMux, cfg *config.Config, deps *Dependencies)
 	authMiddleware := auth.Middleware(deps.AuthService)
 
 	// =========================================================================
-	// OAuth routes (Google Sign-In)
+	// OAuth routes (one pair per configured provider, e.g. /auth/github)
 	// =========================================================================
 	if deps.OAuthHandler != nil {
-		mux.HandleFunc("GET /auth/google", deps.OAuthHandler.HandleGoogleAuth)
-		mux.HandleFunc("GET /auth/google/callback", deps.OAuthHandler.HandleGoogleCallback)
+		for _, provider := range deps.OAuthHandler.Providers() {
+			mux.HandleFunc("GET /auth/"+provider, deps.OAuthHandler.HandleAuth(provider))
+			mux.HandleFunc("GET /auth/"+provider+"/callback", deps.OAuthHandler.HandleCallback(provider))
+		}
 		mux.Handle("POST /auth/set-username", authMiddleware(http.HandlerFunc(deps.OAuthHandler.HandleSetUsername)))
 	}
 
+	// Change password, rate limited like login since it checks a password
+	mux.Handle("POST /auth/change-password", rateLimiter.Middleware(authMiddleware(http.HandlerFunc(deps.AuthHandler.ChangePassword))))
+
+	// Active sessions (refresh tokens)
+	mux.Handle("GET /auth/sessions", authMiddleware(http.HandlerFunc(deps.AuthHandler.ListSessions)))
+	mux.Handle("DELETE /auth/sessions/{id}", authMiddleware(http.HandlerFunc(deps.AuthHandler.RevokeSession)))
+
 	// Me endpoint
 	mux.Handle("GET /auth/me", authMiddleware(http.HandlerFunc(deps.AuthHandler.Me)))
 
@@ -117,24 +127,53 @@ func registerRoutes(mux *http.ServeMux, cfg *config.Config, deps *Dependencies)
 	// =========================================================================
 	mux.HandleFunc("GET /users/search", deps.UserHandler.Search) // public search
 	mux.HandleFunc("GET /users/{username}", deps.UserHandler.GetByUsername)
+	mux.Handle("GET /users/{username}/shared", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetSharedConversations)))
+	mux.Handle("GET /users/{username}/presence", authMiddleware(http.HandlerFunc(deps.UserHandler.GetPresence)))
+	mux.Handle("POST /users/{username}/messages", authMiddleware(http.HandlerFunc(deps.ConvHandler.SendDirectMessage)))
 	mux.Handle("GET /users/me", authMiddleware(http.HandlerFunc(deps.UserHandler.GetMe)))
 	mux.Handle("PUT /users/me", authMiddleware(http.HandlerFunc(deps.UserHandler.UpdateProfile)))
 	mux.Handle("PATCH /users/me/preferences", authMiddleware(http.HandlerFunc(deps.UserHandler.UpdatePreferences)))
 	mux.Handle("DELETE /users/me", authMiddleware(http.HandlerFunc(deps.UserHandler.DeleteAccount)))
+	mux.Handle("GET /users/me/contacts", authMiddleware(http.HandlerFunc(deps.UserHandler.ListContacts)))
+	mux.Handle("POST /users/me/contacts/{userId}", authMiddleware(http.HandlerFunc(deps.UserHandler.AddContact)))
+	mux.Handle("DELETE /users/me/contacts/{userId}", authMiddleware(http.HandlerFunc(deps.UserHandler.RemoveContact)))
+	mux.Handle("POST /presence/query", authMiddleware(http.HandlerFunc(deps.UserHandler.QueryPresence)))
 
 	// =========================================================================
 	// Conversation routes
 	// =========================================================================
 	mux.Handle("POST /conversations", authMiddleware(http.HandlerFunc(deps.ConvHandler.CreateConversation)))
 	mux.Handle("GET /conversations", authMiddleware(http.HandlerFunc(deps.ConvHandler.ListConversations)))
+	mux.Handle("GET /conversations/saved", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetSavedConversation)))
 	mux.Handle("GET /conversations/{id}", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetConversation)))
+	mux.Handle("GET /conversations/{id}/insights", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetConversationInsights)))
 	mux.Handle("PATCH /conversations/{id}", authMiddleware(http.HandlerFunc(deps.ConvHandler.UpdateConversation)))
+	mux.Handle("PATCH /conversations/{id}/settings", authMiddleware(http.HandlerFunc(deps.ConvHandler.UpdateMemberSettings)))
+	mux.Handle("PATCH /conversations/{id}/disappearing", authMiddleware(http.HandlerFunc(deps.ConvHandler.SetDisappearingMessages)))
+	mux.Handle("PUT /conversations/{id}/language", authMiddleware(http.HandlerFunc(deps.ConvHandler.UpdateSearchLanguage)))
+	mux.Handle("GET /conversations/{id}/members", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetMembers)))
 	mux.Handle("POST /conversations/{id}/members", authMiddleware(http.HandlerFunc(deps.ConvHandler.AddMember)))
 	mux.Handle("DELETE /conversations/{id}/members/{userId}", authMiddleware(http.HandlerFunc(deps.ConvHandler.RemoveMember)))
+	mux.Handle("PATCH /conversations/{id}/members/{userId}/role", authMiddleware(http.HandlerFunc(deps.ConvHandler.UpdateMemberRole)))
+	mux.Handle("PUT /conversations/{id}/members/{userId}/nickname", authMiddleware(http.HandlerFunc(deps.ConvHandler.SetMemberNickname)))
+	mux.Handle("POST /conversations/{id}/leave", authMiddleware(http.HandlerFunc(deps.ConvHandler.LeaveConversation)))
+	mux.Handle("POST /conversations/{id}/transfer", authMiddleware(http.HandlerFunc(deps.ConvHandler.TransferOwnership)))
+	mux.Handle("POST /conversations/{id}/bans/{userId}", authMiddleware(http.HandlerFunc(deps.ConvHandler.BanMember)))
+	mux.Handle("DELETE /conversations/{id}/bans/{userId}", authMiddleware(http.HandlerFunc(deps.ConvHandler.UnbanMember)))
 	mux.Handle("POST /conversations/{id}/archive", authMiddleware(http.HandlerFunc(deps.ConvHandler.ArchiveConversation)))
 	mux.Handle("POST /conversations/{id}/unarchive", authMiddleware(http.HandlerFunc(deps.ConvHandler.UnarchiveConversation)))
 	mux.Handle("POST /conversations/{id}/read", authMiddleware(http.HandlerFunc(deps.ConvHandler.MarkConversationRead)))
+	mux.Handle("GET /conversations/{id}/unread", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetUnreadCount)))
 	mux.Handle("POST /conversations/mark-all-read", authMiddleware(http.HandlerFunc(deps.ConvHandler.MarkAllConversationsRead)))
+	mux.Handle("POST /conversations/batch", authMiddleware(http.HandlerFunc(deps.ConvHandler.BatchConversations)))
+	mux.Handle("PUT /conversations/{id}/folder", authMiddleware(http.HandlerFunc(deps.ConvHandler.SetConversationFolder)))
+	mux.Handle("DELETE /conversations/{id}/folder", authMiddleware(http.HandlerFunc(deps.ConvHandler.ClearConversationFolder)))
+	mux.Handle("PUT /conversations/{id}/draft", authMiddleware(http.HandlerFunc(deps.ConvHandler.SaveDraft)))
+	mux.Handle("GET /conversations/{id}/draft", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetDraft)))
+	mux.Handle("DELETE /conversations/{id}/draft", authMiddleware(http.HandlerFunc(deps.ConvHandler.DeleteDraft)))
+	mux.Handle("POST /conversations/{id}/mute", authMiddleware(http.HandlerFunc(deps.ConvHandler.MuteConversation)))
+	mux.Handle("DELETE /conversations/{id}/mute", authMiddleware(http.HandlerFunc(deps.ConvHandler.UnmuteConversation)))
+	mux.Handle("GET /sync", authMiddleware(http.HandlerFunc(deps.ConvHandler.Sync)))
 
 	// =========================================================================
 	// Message routes
@@ -142,14 +181,30 @@ func registerRoutes(mux *http.ServeMux, cfg *config.Config, deps *Dependencies)
 	mux.Handle("GET /conversations/{id}/messages", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetMessages)))
 	mux.Handle("POST /conversations/{id}/messages", authMiddleware(http.HandlerFunc(deps.ConvHandler.SendMessage)))
 	mux.Handle("GET /conversations/{id}/messages/search", authMiddleware(http.HandlerFunc(deps.ConvHandler.SearchMessages)))
+	mux.Handle("POST /conversations/{id}/messages/schedule", authMiddleware(http.HandlerFunc(deps.ConvHandler.ScheduleMessage)))
+	mux.Handle("POST /conversations/{id}/messages/delete", authMiddleware(http.HandlerFunc(deps.ConvHandler.DeleteMessages)))
+	mux.Handle("GET /conversations/{id}/scheduled", authMiddleware(http.HandlerFunc(deps.ConvHandler.ListScheduledMessages)))
+	mux.Handle("DELETE /scheduled/{id}", authMiddleware(http.HandlerFunc(deps.ConvHandler.CancelScheduledMessage)))
+	mux.Handle("GET /conversations/{id}/pinned", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetPinnedMessages)))
+	mux.Handle("GET /conversations/{id}/pins", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetPinnedMessages)))
+	mux.Handle("PUT /conversations/{id}/pinned/order", authMiddleware(http.HandlerFunc(deps.ConvHandler.ReorderPins)))
+	mux.Handle("POST /conversations/{id}/messages/{messageId}/pin", authMiddleware(http.HandlerFunc(deps.ConvHandler.PinMessage)))
+	mux.Handle("DELETE /conversations/{id}/messages/{messageId}/pin", authMiddleware(http.HandlerFunc(deps.ConvHandler.UnpinMessage)))
 
 	// =========================================================================
 	// Starred messages routes
 	// =========================================================================
 	mux.Handle("GET /messages/starred", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetStarredMessages)))
+	mux.Handle("GET /messages/starred/search", authMiddleware(http.HandlerFunc(deps.ConvHandler.SearchStarredMessages)))
+
+	// Mentions
+	mux.Handle("GET /mentions", authMiddleware(http.HandlerFunc(deps.ConvHandler.GetMentions)))
 	mux.Handle("GET /messages/search", authMiddleware(http.HandlerFunc(deps.ConvHandler.SearchAllMessages)))
+	mux.Handle("POST /messages/forward", authMiddleware(http.HandlerFunc(deps.ConvHandler.ForwardMessages)))
 	mux.Handle("POST /messages/{id}/star", authMiddleware(http.HandlerFunc(deps.ConvHandler.StarMessage)))
 	mux.Handle("DELETE /messages/{id}/star", authMiddleware(http.HandlerFunc(deps.ConvHandler.UnstarMessage)))
+	mux.Handle("POST /messages/{id}/unread", authMiddleware(http.HandlerFunc(deps.ConvHandler.MarkUnreadFrom)))
+	mux.Handle("PATCH /messages/{id}", authMiddleware(http.HandlerFunc(deps.ConvHandler.EditMessage)))
 	mux.Handle("DELETE /messages/{id}", authMiddleware(http.HandlerFunc(deps.ConvHandler.DeleteMessage)))
 
 	// =========================================================================
@@ -164,6 +219,7 @@ func registerRoutes(mux *http.ServeMux, cfg *config.Config, deps *Dependencies)
 	if deps.CallHandler != nil {
 		mux.Handle("GET /calls", authMiddleware(http.HandlerFunc(deps.CallHandler.GetCallHistory)))
 		mux.Handle("GET /calls/missed/count", authMiddleware(http.HandlerFunc(deps.CallHandler.GetMissedCallCount)))
+		mux.Handle("GET /calls/active", authMiddleware(http.HandlerFunc(deps.CallHandler.GetActiveCalls)))
 		mux.Handle("GET /calls/{id}", authMiddleware(http.HandlerFunc(deps.CallHandler.GetCall)))
 		mux.Handle("POST /calls", authMiddleware(http.HandlerFunc(deps.CallHandler.CreateCall)))
 		mux.Handle("PATCH /calls/{id}", authMiddleware(http.HandlerFunc(deps.CallHandler.UpdateCall)))
@@ -175,6 +231,22 @@ func registerRoutes(mux *http.ServeMux, cfg *config.Config, deps *Dependencies)
 	mux.Handle("POST /uploads/init", authMiddleware(http.HandlerFunc(deps.UploadHandler.InitUpload)))
 	mux.Handle("POST /uploads/complete", authMiddleware(http.HandlerFunc(deps.UploadHandler.CompleteUpload)))
 	mux.Handle("GET /attachments/{id}/url", authMiddleware(http.HandlerFunc(deps.UploadHandler.GetAttachmentURL)))
+	mux.Handle("GET /attachments/{id}/download", authMiddleware(http.HandlerFunc(deps.UploadHandler.DownloadAttachment)))
+	mux.Handle("DELETE /attachments/{id}", authMiddleware(http.HandlerFunc(deps.UploadHandler.DeleteAttachment)))
+	mux.Handle("GET /users/me/storage", authMiddleware(http.HandlerFunc(deps.UploadHandler.GetStorageUsage)))
+
+	// =========================================================================
+	// Admin routes (operator introspection)
+	// =========================================================================
+	if deps.AdminHandler != nil {
+		adminMiddleware := auth.AdminMiddleware(cfg.AdminUserIDs)
+		mux.Handle("GET /admin/ws/stats", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.GetWSStats))))
+		mux.Handle("GET /admin/calls/stats", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.GetCallStats))))
+		mux.Handle("GET /admin/calls/{roomId}/stats", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.GetCallRoomStats))))
+		mux.Handle("POST /calls/{roomId}/record/start", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.StartCallRecording))))
+		mux.Handle("POST /calls/{roomId}/record/stop", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.StopCallRecording))))
+		mux.Handle("DELETE /admin/users/{id}/throttle", authMiddleware(adminMiddleware(http.HandlerFunc(deps.AdminHandler.ClearThrottle))))
+	}
 
 	// =========================================================================
 	// WebSocket route
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,11 +12,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// s3API is the subset of *s3.Client used for direct object operations
+type s3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
 // R2Storage handles Cloudflare R2 operations using AWS SDK v2
 type R2Storage struct {
-	client    *s3.Client
+	client    s3API
 	presigner *s3.PresignClient
 	bucket    string
+	retry     RetryPolicy
 }
 
 // NewR2Storage creates a new R2 storage client
@@ -29,11 +39,13 @@ func NewR2Storage(accountID, accessKeyID, secretAccessKey, bucket string) (*R2St
 	// Create AWS credentials
 	creds := credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")
 
-	// Create S3 client configured for R2
+	// Create S3 client configured for R2. Retries are handled by
+	// RetryPolicy so the SDK's own retryer is turned off.
 	client := s3.New(s3.Options{
 		Region:       "auto",
 		Credentials:  creds,
 		BaseEndpoint: aws.String(endpoint),
+		Retryer:      aws.NopRetryer{},
 	})
 
 	// Create presigner
@@ -43,9 +55,15 @@ func NewR2Storage(accountID, accessKeyID, secretAccessKey, bucket string) (*R2St
 		client:    client,
 		presigner: presigner,
 		bucket:    bucket,
+		retry:     DefaultRetryPolicy,
 	}, nil
 }
 
+// SetRetryPolicy overrides how object operations are retried
+func (r *R2Storage) SetRetryPolicy(policy RetryPolicy) {
+	r.retry = policy
+}
+
 // GeneratePresignedPutURL generates a presigned URL for uploading a file
 func (r *R2Storage) GeneratePresignedPutURL(ctx context.Context, objectKey string, contentType string, expiryDuration time.Duration) (string, error) {
 	input := &s3.PutObjectInput{
@@ -90,10 +108,92 @@ func (r *R2Storage) DeleteObject(ctx context.Context, objectKey string) error {
 		Key:    aws.String(objectKey),
 	}
 
-	_, err := r.client.DeleteObject(ctx, input)
+	err := r.retry.do(ctx, func(ctx context.Context) error {
+		_, err := r.client.DeleteObject(ctx, input)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete object: %w", err)
 	}
 
 	return nil
 }
+
+// GetObject downloads an object from R2, reading at most maxBytes
+func (r *R2Storage) GetObject(ctx context.Context, objectKey string, maxBytes int64) ([]byte, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(objectKey),
+	}
+
+	var data []byte
+	err := r.retry.do(ctx, func(ctx context.Context) error {
+		out, err := r.client.GetObject(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to get object: %w", err)
+		}
+		defer func() { _ = out.Body.Close() }()
+
+		data, err = io.ReadAll(io.LimitReader(out.Body, maxBytes+1))
+		if err != nil {
+			return fmt.Errorf("failed to read object: %w", err)
+		}
+		if int64(len(data)) > maxBytes {
+			return errPermanent{fmt.Errorf("object exceeds %d bytes", maxBytes)}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// GetObjectHead downloads the first n bytes of an object (fewer if it's shorter)
+func (r *R2Storage) GetObjectHead(ctx context.Context, objectKey string, n int64) ([]byte, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(objectKey),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
+	}
+
+	var data []byte
+	err := r.retry.do(ctx, func(ctx context.Context) error {
+		out, err := r.client.GetObject(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to get object: %w", err)
+		}
+		defer func() { _ = out.Body.Close() }()
+
+		data, err = io.ReadAll(io.LimitReader(out.Body, n))
+		if err != nil {
+			return fmt.Errorf("failed to read object: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// PutObject uploads an object to R2, replacing any existing one
+func (r *R2Storage) PutObject(ctx context.Context, objectKey string, contentType string, data []byte) error {
+	err := r.retry.do(ctx, func(ctx context.Context) error {
+		// Fresh body each attempt; a failed one may have consumed the reader
+		_, err := r.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(r.bucket),
+			Key:         aws.String(objectKey),
+			ContentType: aws.String(contentType),
+			Body:        bytes.NewReader(data),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusError stands in for the SDK's HTTP response errors
+type statusError struct{ code int }
+
+func (e statusError) Error() string       { return fmt.Sprintf("http %d", e.code) }
+func (e statusError) HTTPStatusCode() int { return e.code }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// flakyS3 fails each operation with the queued errors before succeeding
+type flakyS3 struct {
+	errs  []error
+	calls int
+	body  []byte
+}
+
+func (f *flakyS3) next() error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *flakyS3) GetObject(_ context.Context, _ *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := f.next(); err != nil {
+		return nil, err
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(f.body))}, nil
+}
+
+func (f *flakyS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := f.next(); err != nil {
+		// Drain the body the way a failed upload would
+		_, _ = io.Copy(io.Discard, in.Body)
+		return nil, err
+	}
+	f.body, _ = io.ReadAll(in.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *flakyS3) DeleteObject(_ context.Context, _ *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return &s3.DeleteObjectOutput{}, f.next()
+}
+
+func newTestR2(client s3API) *R2Storage {
+	return &R2Storage{
+		client: client,
+		bucket: "test",
+		retry:  RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	}
+}
+
+func TestR2Storage_RetriesTransientFailures(t *testing.T) {
+	ctx := context.Background()
+
+	client := &flakyS3{errs: []error{statusError{503}, timeoutError{}}}
+	r := newTestR2(client)
+	require.NoError(t, r.PutObject(ctx, "k", "text/plain", []byte("hello")))
+	assert.Equal(t, 3, client.calls)
+	assert.Equal(t, []byte("hello"), client.body, "each attempt gets the full body")
+
+	client = &flakyS3{errs: []error{statusError{500}}, body: []byte("data")}
+	r = newTestR2(client)
+	data, err := r.GetObject(ctx, "k", 100)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+	assert.Equal(t, 2, client.calls)
+
+	client = &flakyS3{errs: []error{statusError{429}}}
+	r = newTestR2(client)
+	require.NoError(t, r.DeleteObject(ctx, "k"))
+	assert.Equal(t, 2, client.calls)
+}
+
+func TestR2Storage_DoesNotRetryClientErrors(t *testing.T) {
+	client := &flakyS3{errs: []error{statusError{403}}}
+	r := newTestR2(client)
+
+	err := r.DeleteObject(context.Background(), "k")
+	require.Error(t, err)
+	var status statusError
+	assert.True(t, errors.As(err, &status))
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestR2Storage_DoesNotRetryOversizedObject(t *testing.T) {
+	client := &flakyS3{body: []byte("too large")}
+	r := newTestR2(client)
+
+	_, err := r.GetObject(context.Background(), "k", 4)
+	require.Error(t, err)
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestR2Storage_GivesUpAfterMaxAttempts(t *testing.T) {
+	client := &flakyS3{errs: []error{statusError{502}, statusError{502}, statusError{502}, statusError{502}}}
+	r := newTestR2(client)
+
+	err := r.DeleteObject(context.Background(), "k")
+	require.Error(t, err)
+	assert.Equal(t, 3, client.calls)
+}
+
+func TestR2Storage_StopsAtContextDeadline(t *testing.T) {
+	client := &flakyS3{errs: []error{statusError{503}, statusError{503}}}
+	r := newTestR2(client)
+	r.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := r.DeleteObject(ctx, "k")
+	require.Error(t, err)
+	assert.Equal(t, 1, client.calls, "a backoff past the deadline isn't waited out")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for i := 0; i < 20; i++ {
+		first := p.backoff(1)
+		assert.GreaterOrEqual(t, first, 50*time.Millisecond)
+		assert.LessOrEqual(t, first, 100*time.Millisecond)
+
+		capped := p.backoff(8)
+		assert.GreaterOrEqual(t, capped, 150*time.Millisecond)
+		assert.LessOrEqual(t, capped, 300*time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how R2 operations are retried on transient failures
+type RetryPolicy struct {
+	MaxAttempts int           // total tries including the first; 1 disables retries
+	BaseDelay   time.Duration // wait before the first retry, doubled each time after
+	MaxDelay    time.Duration // cap on any single wait
+}
+
+// DefaultRetryPolicy is used by NewR2Storage
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// errPermanent marks a failure that retrying won't fix
+type errPermanent struct{ err error }
+
+func (e errPermanent) Error() string { return e.err.Error() }
+func (e errPermanent) Unwrap() error { return e.err }
+
+// do runs op until it succeeds, fails with a non-retryable error, runs out of
+// attempts, or the next wait would overrun the context deadline. The last
+// error is returned.
+func (p RetryPolicy) do(ctx context.Context, op func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			wait := p.backoff(attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return err
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = op(ctx)
+		if err == nil || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff returns the wait before the given retry: exponential in the attempt
+// number, capped at MaxDelay, with the upper half jittered so concurrent
+// callers don't retry in lockstep
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(d-half)
+}
+
+// isRetryable reports whether err looks transient: a 5xx or 429 from R2, or a
+// network-level failure such as a timeout, reset connection or truncated
+// body. Other HTTP errors (4xx) mean the request itself is wrong and are
+// returned as-is.
+func isRetryable(err error) bool {
+	var perm errPermanent
+	if errors.As(err, &perm) {
+		return false
+	}
+
+	var status interface{ HTTPStatusCode() int }
+	if errors.As(err, &status) {
+		code := status.HTTPStatusCode()
+		return code >= 500 || code == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
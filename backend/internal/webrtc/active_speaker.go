@@ -0,0 +1,156 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// activeSpeakerInterval is how often participants' audio levels are compared
+	activeSpeakerInterval = 300 * time.Millisecond
+
+	// activeSpeakerHold is how many intervals in a row someone must be the
+	// loudest before they take over, so the indicator doesn't flicker
+	activeSpeakerHold = 2
+
+	// activeSpeakerMinEnergy ignores anything quieter than -60 dBov as silence
+	activeSpeakerMinEnergy = 127 - 60
+)
+
+// speakerDetector picks a room's dominant speaker from the audio levels
+// (RFC 6464) carried on its participants' RTP packets
+type speakerDetector struct {
+	mu      sync.Mutex
+	window  map[uuid.UUID]levelSum // levels seen this interval
+	evalAt  time.Time
+	current uuid.UUID
+
+	// Who is louder than current, and for how many intervals
+	challenger uuid.UUID
+	leads      int
+}
+
+type levelSum struct {
+	energy float64
+	n      int
+}
+
+func newSpeakerDetector() *speakerDetector {
+	return &speakerDetector{window: make(map[uuid.UUID]levelSum)}
+}
+
+// observe records one packet's level, in -dBov where 127 is silence
+func (d *speakerDetector) observe(userID uuid.UUID, level uint8) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.window[userID]
+	s.energy += float64(127 - min(level, 127))
+	s.n++
+	d.window[userID] = s
+}
+
+// elect compares everyone's average level once per interval and returns the
+// new dominant speaker if it changed
+func (d *speakerDetector) elect(now time.Time) (uuid.UUID, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Before(d.evalAt) {
+		return uuid.Nil, false
+	}
+	d.evalAt = now.Add(activeSpeakerInterval)
+
+	loudest, best := uuid.Nil, float64(activeSpeakerMinEnergy)
+	for id, s := range d.window {
+		if avg := s.energy / float64(s.n); avg > best {
+			loudest, best = id, avg
+		}
+	}
+	clear(d.window)
+
+	// Silence keeps the last speaker highlighted
+	if loudest == uuid.Nil || loudest == d.current {
+		d.challenger, d.leads = uuid.Nil, 0
+		return uuid.Nil, false
+	}
+	if loudest == d.challenger {
+		d.leads++
+	} else {
+		d.challenger, d.leads = loudest, 1
+	}
+	if d.leads < activeSpeakerHold {
+		return uuid.Nil, false
+	}
+
+	d.current = loudest
+	d.challenger, d.leads = uuid.Nil, 0
+	return loudest, true
+}
+
+// forget drops a participant who left, so they can be picked again on return
+func (d *speakerDetector) forget(userID uuid.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.window, userID)
+	if d.current == userID {
+		d.current = uuid.Nil
+	}
+	if d.challenger == userID {
+		d.challenger, d.leads = uuid.Nil, 0
+	}
+}
+
+// audioLevelExtension returns the negotiated RTP header extension ID carrying
+// audio levels for remoteTrack, or 0 if levels aren't being read
+func (p *SFUParticipant) audioLevelExtension(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) uint8 {
+	if !p.sfu.config.EnableAudioLevels || remoteTrack.Kind() != webrtc.RTPCodecTypeAudio || receiver == nil {
+		return 0
+	}
+	for _, ext := range receiver.GetParameters().HeaderExtensions {
+		if ext.URI == sdp.AudioLevelURI {
+			return uint8(ext.ID)
+		}
+	}
+	return 0
+}
+
+// observeAudioLevel feeds a packet's audio level to the room's speaker
+// detector and announces a new dominant speaker
+func (p *SFUParticipant) observeAudioLevel(ctx context.Context, packet *rtp.Packet, extID uint8) {
+	raw := packet.GetExtension(extID)
+	if raw == nil {
+		return
+	}
+	var level rtp.AudioLevelExtension
+	if err := level.Unmarshal(raw); err != nil {
+		return
+	}
+
+	p.room.speakers.observe(p.UserID, level.Level)
+	if speaker, changed := p.room.speakers.elect(time.Now()); changed {
+		p.sfu.publishActiveSpeaker(ctx, p.room.ID, speaker)
+		p.room.video.promote(speaker, p.sfu.config.MaxForwardedVideo, p.sfu.videoChanged(ctx, p.room))
+	}
+}
+
+// publishActiveSpeaker tells the room who is talking
+func (s *SFU) publishActiveSpeaker(ctx context.Context, roomID, userID uuid.UUID) {
+	payloadBytes, _ := json.Marshal(ActiveSpeakerPayload{RoomID: roomID, UserID: userID})
+	msg := &pubsub.Message{
+		Topic:   pubsub.Topics.Room(roomID.String()),
+		Type:    EventTypeCallActiveSpeaker,
+		Payload: payloadBytes,
+	}
+	if err := s.pubsub.Publish(ctx, msg.Topic, msg); err != nil {
+		s.logger.Error("failed to publish active speaker", "error", err, "room_id", roomID)
+	}
+}
@@ -0,0 +1,85 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// speakerClock feeds a detector one interval of audio levels at a time
+type speakerClock struct {
+	d   *speakerDetector
+	now time.Time
+}
+
+func (c *speakerClock) interval(levels map[uuid.UUID]uint8) (uuid.UUID, bool) {
+	for i := 0; i < 15; i++ {
+		for id, level := range levels {
+			c.d.observe(id, level)
+		}
+	}
+	speaker, changed := c.d.elect(c.now)
+	c.now = c.now.Add(activeSpeakerInterval)
+	return speaker, changed
+}
+
+func TestSpeakerDetector_DebouncesSpeakerChanges(t *testing.T) {
+	c := &speakerClock{d: newSpeakerDetector(), now: time.Now()}
+	alice, bob := uuid.New(), uuid.New()
+
+	// Alice must stay loudest for the hold before she's announced
+	_, changed := c.interval(map[uuid.UUID]uint8{alice: 20, bob: 100})
+	assert.False(t, changed)
+	speaker, changed := c.interval(map[uuid.UUID]uint8{alice: 20, bob: 100})
+	assert.True(t, changed)
+	assert.Equal(t, alice, speaker)
+
+	// A single loud burst from Bob doesn't take over
+	_, changed = c.interval(map[uuid.UUID]uint8{alice: 90, bob: 10})
+	assert.False(t, changed)
+	_, changed = c.interval(map[uuid.UUID]uint8{alice: 20, bob: 100})
+	assert.False(t, changed)
+
+	// Silence keeps Alice highlighted
+	_, changed = c.interval(map[uuid.UUID]uint8{alice: 127, bob: 127})
+	assert.False(t, changed)
+
+	// Bob speaking for longer does
+	_, changed = c.interval(map[uuid.UUID]uint8{alice: 90, bob: 10})
+	assert.False(t, changed)
+	speaker, changed = c.interval(map[uuid.UUID]uint8{alice: 90, bob: 10})
+	assert.True(t, changed)
+	assert.Equal(t, bob, speaker)
+}
+
+func TestSpeakerDetector_ElectsOncePerInterval(t *testing.T) {
+	d := newSpeakerDetector()
+	alice := uuid.New()
+	now := time.Now()
+
+	d.observe(alice, 20)
+	d.elect(now)
+	d.observe(alice, 20)
+	_, changed := d.elect(now.Add(activeSpeakerInterval / 2))
+	assert.False(t, changed, "too soon to compare again")
+	speaker, changed := d.elect(now.Add(activeSpeakerInterval))
+	assert.True(t, changed)
+	assert.Equal(t, alice, speaker)
+}
+
+func TestSpeakerDetector_ForgetsParticipantsWhoLeave(t *testing.T) {
+	c := &speakerClock{d: newSpeakerDetector(), now: time.Now()}
+	alice := uuid.New()
+
+	c.interval(map[uuid.UUID]uint8{alice: 20})
+	speaker, _ := c.interval(map[uuid.UUID]uint8{alice: 20})
+	assert.Equal(t, alice, speaker)
+
+	c.d.forget(alice)
+	c.interval(map[uuid.UUID]uint8{alice: 20})
+	speaker, changed := c.interval(map[uuid.UUID]uint8{alice: 20})
+	assert.True(t, changed, "a returning participant is announced again")
+	assert.Equal(t, alice, speaker)
+}
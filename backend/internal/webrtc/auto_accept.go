@@ -0,0 +1,65 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// AutoAcceptLookup reports callees' auto-answer settings for a caller
+type AutoAcceptLookup interface {
+	GetCallAutoAccept(ctx context.Context, callerID uuid.UUID, calleeIDs []uuid.UUID) (map[uuid.UUID]domain.CallAutoAccept, error)
+}
+
+// SetAutoAcceptLookup enables call.auto_accept hints for incoming calls
+func (h *CallHandler) SetAutoAcceptLookup(l AutoAcceptLookup) {
+	h.autoAccept = l
+}
+
+// SetAutoAcceptLookup enables call.auto_accept hints for incoming calls
+func (h *SFUHandler) SetAutoAcceptLookup(l AutoAcceptLookup) {
+	h.autoAccept = l
+}
+
+// sendAutoAcceptHints tells each callee who auto-answers calls from the
+// caller to do so for this call. It runs after call.incoming so clients
+// already know about the call when the hint arrives.
+func sendAutoAcceptHints(ctx context.Context, ps pubsub.PubSub, lookup AutoAcceptLookup, logger *slog.Logger, incoming CallIncomingPayload, calleeIDs []uuid.UUID) {
+	if lookup == nil || len(calleeIDs) == 0 {
+		return
+	}
+
+	settings, err := lookup.GetCallAutoAccept(ctx, incoming.CallerID, calleeIDs)
+	if err != nil {
+		logger.Error("failed to look up call auto-accept", "error", err, "call_id", incoming.CallID)
+		return
+	}
+
+	payloadBytes, err := json.Marshal(CallAutoAcceptPayload{
+		CallID:         incoming.CallID,
+		ConversationID: incoming.ConversationID,
+		CallerID:       incoming.CallerID,
+	})
+	if err != nil {
+		logger.Error("failed to marshal auto-accept payload", "error", err)
+		return
+	}
+
+	for _, calleeID := range calleeIDs {
+		if !settings[calleeID].Applies() {
+			continue
+		}
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.User(calleeID.String()),
+			Type:    EventTypeCallAutoAccept,
+			Payload: payloadBytes,
+		}
+		if err := ps.Publish(ctx, msg.Topic, msg); err != nil {
+			logger.Error("failed to publish auto-accept hint", "error", err, "user_id", calleeID)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAutoAcceptLookup returns fixed settings regardless of caller
+type fakeAutoAcceptLookup map[uuid.UUID]domain.CallAutoAccept
+
+func (f fakeAutoAcceptLookup) GetCallAutoAccept(_ context.Context, _ uuid.UUID, calleeIDs []uuid.UUID) (map[uuid.UUID]domain.CallAutoAccept, error) {
+	out := make(map[uuid.UUID]domain.CallAutoAccept, len(calleeIDs))
+	for _, id := range calleeIDs {
+		if a, ok := f[id]; ok {
+			out[id] = a
+		}
+	}
+	return out, nil
+}
+
+func TestSendAutoAcceptHints_OnlyForContacts(t *testing.T) {
+	handler, _, ps := newTestCallHandler(t)
+	ctx := context.Background()
+
+	callerID := uuid.New()
+	kiosk := uuid.New()    // opted in, caller is a contact
+	stranger := uuid.New() // opted in, caller is not a contact
+	friend := uuid.New()   // caller is a contact, but not opted in
+	unknown := uuid.New()  // no settings at all
+
+	handler.SetAutoAcceptLookup(fakeAutoAcceptLookup{
+		kiosk:    {Enabled: true, CallerIsContact: true},
+		stranger: {Enabled: true, CallerIsContact: false},
+		friend:   {Enabled: false, CallerIsContact: true},
+	})
+
+	var mu sync.Mutex
+	hinted := map[uuid.UUID]CallAutoAcceptPayload{}
+	for _, id := range []uuid.UUID{kiosk, stranger, friend, unknown} {
+		userID := id
+		sub, err := ps.Subscribe(ctx, pubsub.Topics.User(userID.String()), func(_ context.Context, msg *pubsub.Message) {
+			if msg.Type != EventTypeCallAutoAccept {
+				return
+			}
+			var p CallAutoAcceptPayload
+			_ = json.Unmarshal(msg.Payload, &p)
+			mu.Lock()
+			hinted[userID] = p
+			mu.Unlock()
+		})
+		require.NoError(t, err)
+		defer func() { _ = sub.Unsubscribe() }()
+	}
+
+	incoming := CallIncomingPayload{CallID: uuid.New(), ConversationID: uuid.New(), CallerID: callerID, CallerName: "alice"}
+	sendAutoAcceptHints(ctx, ps, handler.autoAccept, handler.logger, incoming, []uuid.UUID{kiosk, stranger, friend, unknown})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hinted) > 0
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let any stray hints land
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, hinted, 1, "only opted-in callees who have the caller as a contact are hinted")
+	require.Contains(t, hinted, kiosk)
+	assert.Equal(t, incoming.CallID, hinted[kiosk].CallID)
+	assert.Equal(t, incoming.ConversationID, hinted[kiosk].ConversationID)
+	assert.Equal(t, callerID, hinted[kiosk].CallerID)
+}
+
+func TestSendAutoAcceptHints_NoLookup(t *testing.T) {
+	handler, _, ps := newTestCallHandler(t)
+
+	assert.NotPanics(t, func() {
+		sendAutoAcceptHints(context.Background(), ps, handler.autoAccept, handler.logger, CallIncomingPayload{}, []uuid.UUID{uuid.New()})
+	}, "auto-accept stays off until a lookup is configured")
+}
+
+func TestCallAutoAccept_Applies(t *testing.T) {
+	assert.True(t, domain.CallAutoAccept{Enabled: true, CallerIsContact: true}.Applies())
+	assert.False(t, domain.CallAutoAccept{Enabled: true}.Applies(), "never auto-answers strangers")
+	assert.False(t, domain.CallAutoAccept{CallerIsContact: true}.Applies(), "opt-in")
+}
@@ -0,0 +1,122 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// CallLocator finds the live call a user is in, so a new call to them can be
+// flagged as call waiting instead of ringing over the one they're on
+type CallLocator interface {
+	// ActiveCallRoom returns a room other than except that the user is in
+	ActiveCallRoom(userID, except uuid.UUID) (uuid.UUID, bool)
+}
+
+// ActiveCallRoom returns a P2P room other than except that the user is in
+func (m *Manager) ActiveCallRoom(userID, except uuid.UUID) (uuid.UUID, bool) {
+	// Snapshot rooms first so room locks are never taken under the manager lock
+	m.mu.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for id, room := range m.rooms {
+		if id != except {
+			rooms = append(rooms, room)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, room := range rooms {
+		if room.HasParticipant(userID) {
+			return room.ID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// ActiveCallRoom returns an SFU room other than except that the user is in
+func (s *SFU) ActiveCallRoom(userID, except uuid.UUID) (uuid.UUID, bool) {
+	s.mu.RLock()
+	rooms := make([]*SFURoom, 0, len(s.rooms))
+	for id, room := range s.rooms {
+		if id != except {
+			rooms = append(rooms, room)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, room := range rooms {
+		if room.GetParticipant(userID) != nil {
+			return room.ID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// SetCallLocators enables busy/call-waiting signaling for incoming calls
+func (h *CallHandler) SetCallLocators(locators ...CallLocator) {
+	h.callLocators = locators
+}
+
+// SetCallLocators enables busy/call-waiting signaling for incoming calls
+func (h *SFUHandler) SetCallLocators(locators ...CallLocator) {
+	h.callLocators = locators
+}
+
+// busyElsewhere reports the call a callee is already in, if it isn't the
+// conversation being called
+func busyElsewhere(locators []CallLocator, userID, conversationID uuid.UUID) (uuid.UUID, bool) {
+	for _, l := range locators {
+		if roomID, ok := l.ActiveCallRoom(userID, conversationID); ok {
+			return roomID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// sendCallWaiting tells a callee who is on another call about the new one,
+// so their client can offer hold/switch rather than ring, and tells the
+// caller that callee is busy
+func sendCallWaiting(ctx context.Context, ps pubsub.PubSub, logger *slog.Logger, incoming CallIncomingPayload, calleeID, activeRoomID uuid.UUID) {
+	logger.Info("callee busy on another call",
+		"call_id", incoming.CallID,
+		"callee_id", calleeID,
+		"active_room_id", activeRoomID)
+
+	waitingBytes, err := json.Marshal(CallWaitingPayload{
+		CallIncomingPayload: incoming,
+		ActiveRoomID:        activeRoomID,
+	})
+	if err != nil {
+		logger.Error("failed to marshal call waiting payload", "error", err)
+		return
+	}
+	waiting := &pubsub.Message{
+		Topic:   pubsub.Topics.User(calleeID.String()),
+		Type:    EventTypeCallWaiting,
+		Payload: waitingBytes,
+	}
+	if err := ps.Publish(ctx, waiting.Topic, waiting); err != nil {
+		logger.Error("failed to publish call waiting", "error", err, "user_id", calleeID)
+	}
+
+	busyBytes, err := json.Marshal(CallBusyPayload{
+		CallID:         incoming.CallID,
+		ConversationID: incoming.ConversationID,
+		UserID:         calleeID,
+	})
+	if err != nil {
+		logger.Error("failed to marshal call busy payload", "error", err)
+		return
+	}
+	busy := &pubsub.Message{
+		Topic:   pubsub.Topics.User(incoming.CallerID.String()),
+		Type:    EventTypeCallBusy,
+		Payload: busyBytes,
+	}
+	if err := ps.Publish(ctx, busy.Topic, busy); err != nil {
+		logger.Error("failed to publish call busy", "error", err, "user_id", incoming.CallerID)
+	}
+}
@@ -0,0 +1,107 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_ActiveCallRoom(t *testing.T) {
+	_, mgr, _ := newTestCallHandler(t)
+	ctx := context.Background()
+	userID, roomA, roomB := uuid.New(), uuid.New(), uuid.New()
+
+	_, busy := mgr.ActiveCallRoom(userID, roomB)
+	assert.False(t, busy)
+
+	_, err := mgr.JoinCall(ctx, roomA, userID, "alice")
+	require.NoError(t, err)
+
+	roomID, busy := mgr.ActiveCallRoom(userID, roomB)
+	assert.True(t, busy)
+	assert.Equal(t, roomA, roomID)
+
+	_, busy = mgr.ActiveCallRoom(userID, roomA)
+	assert.False(t, busy, "being in the called conversation's room isn't busy")
+}
+
+func TestSFU_ActiveCallRoom(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+	sfuInst := NewSFU(&SFUConfig{}, ps, slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})))
+	userID, roomA, roomB := uuid.New(), uuid.New(), uuid.New()
+
+	sfuInst.GetOrCreateRoom(roomA).AddParticipant(&SFUParticipant{UserID: userID})
+	sfuInst.GetOrCreateRoom(roomB)
+
+	roomID, busy := sfuInst.ActiveCallRoom(userID, roomB)
+	assert.True(t, busy)
+	assert.Equal(t, roomA, roomID)
+
+	_, busy = sfuInst.ActiveCallRoom(uuid.New(), roomB)
+	assert.False(t, busy)
+}
+
+func TestCallWaiting_BusyCalleeGetsWaitingAndCallerGetsBusy(t *testing.T) {
+	handler, mgr, ps := newTestCallHandler(t)
+	handler.SetCallLocators(mgr)
+	ctx := context.Background()
+
+	callerID, calleeID := uuid.New(), uuid.New()
+	ongoingRoom, calledConv := uuid.New(), uuid.New()
+	_, err := mgr.JoinCall(ctx, ongoingRoom, calleeID, "bob")
+	require.NoError(t, err)
+
+	events := make(chan *pubsub.Message, 4)
+	for _, id := range []uuid.UUID{callerID, calleeID} {
+		sub, err := ps.Subscribe(ctx, pubsub.Topics.User(id.String()), func(_ context.Context, msg *pubsub.Message) {
+			if msg.Type == EventTypeCallWaiting || msg.Type == EventTypeCallBusy {
+				events <- msg
+			}
+		})
+		require.NoError(t, err)
+		defer func() { _ = sub.Unsubscribe() }()
+	}
+
+	activeRoomID, busy := busyElsewhere(handler.callLocators, calleeID, calledConv)
+	require.True(t, busy)
+	require.Equal(t, ongoingRoom, activeRoomID)
+
+	incoming := CallIncomingPayload{CallID: uuid.New(), ConversationID: calledConv, CallerID: callerID, CallerName: "alice", CallType: "video"}
+	sendCallWaiting(ctx, ps, handler.logger, incoming, calleeID, activeRoomID)
+
+	got := map[string]*pubsub.Message{}
+	for len(got) < 2 {
+		select {
+		case msg := <-events:
+			got[msg.Type] = msg
+		case <-time.After(time.Second):
+			t.Fatalf("expected call.waiting and call.busy, got %v", got)
+		}
+	}
+
+	var waiting CallWaitingPayload
+	require.NoError(t, json.Unmarshal(got[EventTypeCallWaiting].Payload, &waiting))
+	assert.Equal(t, pubsub.Topics.User(calleeID.String()), got[EventTypeCallWaiting].Topic)
+	assert.Equal(t, incoming.CallID, waiting.CallID)
+	assert.Equal(t, callerID, waiting.CallerID)
+	assert.Equal(t, ongoingRoom, waiting.ActiveRoomID)
+
+	var busyPayload CallBusyPayload
+	require.NoError(t, json.Unmarshal(got[EventTypeCallBusy].Payload, &busyPayload))
+	assert.Equal(t, pubsub.Topics.User(callerID.String()), got[EventTypeCallBusy].Topic)
+	assert.Equal(t, incoming.CallID, busyPayload.CallID)
+	assert.Equal(t, calleeID, busyPayload.UserID)
+
+	// An idle callee rings as usual
+	_, busy = busyElsewhere(handler.callLocators, uuid.New(), calledConv)
+	assert.False(t, busy)
+}
@@ -0,0 +1,156 @@
+package webrtc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/rtcp"
+)
+
+// ParticipantStats is a snapshot of how well media is reaching one
+// participant, taken from the RTCP receiver reports their client sends for
+// the tracks forwarded to them. The worst track is reported.
+type ParticipantStats struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Tracks      int       `json:"tracks"`           // forwarded tracks with reports
+	PacketLoss  float64   `json:"packet_loss"`      // fraction lost since the previous report, 0 to 1
+	PacketsLost int64     `json:"packets_lost"`     // cumulative, summed over tracks
+	JitterMs    float64   `json:"jitter_ms"`        // interarrival jitter
+	RTTMs       float64   `json:"rtt_ms,omitempty"` // only known once the client has seen a sender report
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// RoomStats is a snapshot of call quality across an SFU room
+type RoomStats struct {
+	RoomID       uuid.UUID          `json:"room_id"`
+	Participants []ParticipantStats `json:"participants"`
+	CollectedAt  time.Time          `json:"collected_at"`
+}
+
+// trackQuality is the latest receiver report for one forwarded track
+type trackQuality struct {
+	fractionLost uint8
+	totalLost    uint32
+	jitterMs     float64
+	rttMs        float64
+	at           time.Time
+}
+
+// callQuality collects receiver reports for the tracks forwarded to a
+// participant, keyed by SSRC. The zero value is ready to use.
+type callQuality struct {
+	mu     sync.Mutex
+	tracks map[uint32]trackQuality
+}
+
+// record stores the reception reports in pkt; clockRate converts jitter from
+// RTP timestamp units
+func (q *callQuality) record(pkt rtcp.Packet, clockRate uint32, now time.Time) {
+	rr, ok := pkt.(*rtcp.ReceiverReport)
+	if !ok || clockRate == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.tracks == nil {
+		q.tracks = make(map[uint32]trackQuality)
+	}
+	for _, report := range rr.Reports {
+		tq := trackQuality{
+			fractionLost: report.FractionLost,
+			totalLost:    report.TotalLost,
+			jitterMs:     float64(report.Jitter) / float64(clockRate) * 1000,
+			rttMs:        q.tracks[report.SSRC].rttMs,
+			at:           now,
+		}
+		if rtt, ok := roundTrip(report, now); ok {
+			tq.rttMs = rtt
+		}
+		q.tracks[report.SSRC] = tq
+	}
+}
+
+// forget drops a track that's no longer forwarded
+func (q *callQuality) forget(ssrc uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.tracks, ssrc)
+}
+
+// snapshot summarises the tracks' latest reports
+func (q *callQuality) snapshot() ParticipantStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var s ParticipantStats
+	for _, tq := range q.tracks {
+		s.Tracks++
+		s.PacketLoss = max(s.PacketLoss, float64(tq.fractionLost)/256)
+		s.PacketsLost += int64(tq.totalLost)
+		s.JitterMs = max(s.JitterMs, tq.jitterMs)
+		s.RTTMs = max(s.RTTMs, tq.rttMs)
+		if tq.at.After(s.UpdatedAt) {
+			s.UpdatedAt = tq.at
+		}
+	}
+	return s
+}
+
+// roundTrip works out the round-trip time from a reception report, per RFC
+// 3550 6.4.1: arrival time less the delay since the last sender report and
+// when that report was sent, all as the middle 32 bits of NTP time
+func roundTrip(report rtcp.ReceptionReport, now time.Time) (float64, bool) {
+	if report.LastSenderReport == 0 {
+		return 0, false
+	}
+	rtt := ntpMiddle(now) - report.LastSenderReport - report.Delay
+	if int32(rtt) < 0 {
+		return 0, false
+	}
+	return float64(rtt) / 65536 * 1000, true
+}
+
+// ntpMiddle returns the middle 32 bits of t's NTP timestamp: 16 bits of
+// seconds and 16 of fraction
+func ntpMiddle(t time.Time) uint32 {
+	const ntpEpochOffset = 2208988800 // seconds from 1900 to 1970
+	secs := uint64(t.Unix()) + ntpEpochOffset
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return uint32(secs<<16) | uint32(frac>>16)
+}
+
+// GetRoomStats returns a call-quality snapshot of an SFU room, or false if
+// there is no such room
+func (s *SFU) GetRoomStats(roomID uuid.UUID) (RoomStats, bool) {
+	room := s.GetRoom(roomID)
+	if room == nil {
+		return RoomStats{}, false
+	}
+
+	room.mu.RLock()
+	participants := make([]*SFUParticipant, 0, len(room.participants))
+	for _, p := range room.participants {
+		participants = append(participants, p)
+	}
+	room.mu.RUnlock()
+
+	stats := RoomStats{
+		RoomID:       roomID,
+		Participants: make([]ParticipantStats, 0, len(participants)),
+		CollectedAt:  time.Now(),
+	}
+	for _, p := range participants {
+		ps := p.quality.snapshot()
+		ps.UserID = p.UserID
+		ps.Username = p.Username
+		stats.Participants = append(stats.Participants, ps)
+	}
+	sort.Slice(stats.Participants, func(i, j int) bool {
+		return stats.Participants[i].Username < stats.Participants[j].Username
+	})
+	return stats, true
+}
@@ -0,0 +1,84 @@
+package webrtc
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallQuality_RecordsReceiverReports(t *testing.T) {
+	now := time.Now()
+	// Sender report went out 150ms ago and the client held it for 50ms
+	lsr := ntpMiddle(now.Add(-150 * time.Millisecond))
+	dlsr := uint32(65536 * 50 / 1000)
+
+	var q callQuality
+	q.record(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+		{SSRC: 1, FractionLost: 64, TotalLost: 10, Jitter: 480},
+		{SSRC: 2, FractionLost: 0, TotalLost: 3, Jitter: 90, LastSenderReport: lsr, Delay: dlsr},
+	}}, 48000, now)
+
+	s := q.snapshot()
+	assert.Equal(t, 2, s.Tracks)
+	assert.InDelta(t, 0.25, s.PacketLoss, 0.001, "worst track's loss is reported")
+	assert.Equal(t, int64(13), s.PacketsLost)
+	assert.InDelta(t, 10, s.JitterMs, 0.01, "480 units at 48kHz is 10ms")
+	assert.InDelta(t, 100, s.RTTMs, 1)
+	assert.Equal(t, now, s.UpdatedAt)
+}
+
+func TestCallQuality_KeepsRTTUntilNextSenderReport(t *testing.T) {
+	now := time.Now()
+	var q callQuality
+	q.record(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+		{SSRC: 1, LastSenderReport: ntpMiddle(now.Add(-40 * time.Millisecond))},
+	}}, 90000, now)
+	q.record(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1}}}, 90000, now.Add(time.Second))
+
+	assert.InDelta(t, 40, q.snapshot().RTTMs, 1)
+
+	q.forget(1)
+	assert.Zero(t, q.snapshot().Tracks)
+}
+
+func TestCallQuality_IgnoresOtherPackets(t *testing.T) {
+	var q callQuality
+	q.record(&rtcp.PictureLossIndication{MediaSSRC: 1}, 90000, time.Now())
+	q.record(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1}}}, 0, time.Now())
+
+	assert.Zero(t, q.snapshot().Tracks)
+}
+
+func TestSFU_GetRoomStats(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+	sfuInst := NewSFU(&SFUConfig{}, ps, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	_, ok := sfuInst.GetRoomStats(uuid.New())
+	assert.False(t, ok)
+
+	room := sfuInst.GetOrCreateRoom(uuid.New())
+	alice := &SFUParticipant{UserID: uuid.New(), Username: "alice"}
+	bob := &SFUParticipant{UserID: uuid.New(), Username: "bob"}
+	room.AddParticipant(bob)
+	room.AddParticipant(alice)
+	bob.quality.record(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+		{SSRC: 7, FractionLost: 128},
+	}}, 90000, time.Now())
+
+	stats, ok := sfuInst.GetRoomStats(room.ID)
+	require.True(t, ok)
+	assert.Equal(t, room.ID, stats.RoomID)
+	require.Len(t, stats.Participants, 2)
+	assert.Equal(t, "alice", stats.Participants[0].Username)
+	assert.Zero(t, stats.Participants[0].Tracks)
+	assert.Equal(t, bob.UserID, stats.Participants[1].UserID)
+	assert.InDelta(t, 0.5, stats.Participants[1].PacketLoss, 0.001)
+}
@@ -0,0 +1,44 @@
+package webrtc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CallEvictor removes a user from a conversation's live call, e.g. when they
+// are banned from the conversation
+type CallEvictor interface {
+	EvictFromCall(ctx context.Context, roomID, userID uuid.UUID)
+}
+
+// EvictFromCall removes a user from the conversation's P2P call as if they had
+// left. It is a no-op if they are not in the call.
+func (h *CallHandler) EvictFromCall(ctx context.Context, roomID, userID uuid.UUID) {
+	room := h.manager.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	for _, p := range room.GetParticipants() {
+		if p.UserID == userID {
+			h.logger.Info("evicting user from call", "room_id", roomID, "user_id", userID)
+			h.leaveRoom(ctx, roomID, &SignalingContext{UserID: p.UserID, Username: p.Username})
+			return
+		}
+	}
+}
+
+// EvictFromCall removes a user from the conversation's SFU call as if they had
+// left. It is a no-op if they are not in the call.
+func (h *SFUHandler) EvictFromCall(ctx context.Context, roomID, userID uuid.UUID) {
+	room := h.sfu.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	p := room.GetParticipant(userID)
+	if p == nil {
+		return
+	}
+	h.logger.Info("evicting user from SFU call", "room_id", roomID, "user_id", userID)
+	h.leaveSFURoom(ctx, roomID, &SignalingContext{UserID: p.UserID, Username: p.Username})
+}
@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallHandler_EvictFromCall_RemovesOnlyTarget(t *testing.T) {
+	handler, mgr, _ := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	banned, other := uuid.New(), uuid.New()
+	_, err := mgr.JoinCall(ctx, roomID, banned, "mallory")
+	require.NoError(t, err)
+	_, err = mgr.JoinCall(ctx, roomID, other, "alice")
+	require.NoError(t, err)
+
+	handler.EvictFromCall(ctx, roomID, banned)
+
+	room := mgr.GetRoom(roomID)
+	require.NotNil(t, room)
+	assert.False(t, room.HasParticipant(banned))
+	assert.True(t, room.HasParticipant(other))
+}
+
+func TestCallHandler_EvictFromCall_LastParticipantDeletesRoom(t *testing.T) {
+	handler, mgr, _ := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	banned := uuid.New()
+	_, err := mgr.JoinCall(ctx, roomID, banned, "mallory")
+	require.NoError(t, err)
+
+	handler.EvictFromCall(ctx, roomID, banned)
+
+	assert.Nil(t, mgr.GetRoom(roomID))
+}
+
+func TestCallHandler_EvictFromCall_NotInCall(t *testing.T) {
+	handler, mgr, _ := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	member := uuid.New()
+	_, err := mgr.JoinCall(ctx, roomID, member, "alice")
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		handler.EvictFromCall(ctx, roomID, uuid.New())
+		handler.EvictFromCall(ctx, uuid.New(), member)
+	})
+	assert.Equal(t, 1, mgr.GetRoom(roomID).ParticipantCount())
+}
+
+func TestSFUHandler_EvictFromCall_RemovesOnlyTarget(t *testing.T) {
+	handler, sfu, _, _ := newTestSFUHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	banned, other := uuid.New(), uuid.New()
+	addSFURoomParticipant(t, sfu, roomID, banned, "mallory")
+	addSFURoomParticipant(t, sfu, roomID, other, "alice")
+
+	handler.EvictFromCall(ctx, roomID, banned)
+
+	room := sfu.GetRoom(roomID)
+	require.NotNil(t, room)
+	assert.Nil(t, room.GetParticipant(banned))
+	assert.NotNil(t, room.GetParticipant(other))
+}
+
+func TestSFUHandler_EvictFromCall_LastParticipantDeletesRoom(t *testing.T) {
+	handler, sfu, _, _ := newTestSFUHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	banned := uuid.New()
+	addSFURoomParticipant(t, sfu, roomID, banned, "mallory")
+
+	handler.EvictFromCall(ctx, roomID, banned)
+
+	assert.Nil(t, sfu.GetRoom(roomID))
+}
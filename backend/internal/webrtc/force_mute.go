@@ -0,0 +1,94 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// ForceMutePayload is sent by a conversation admin muting someone in a group call
+type ForceMutePayload struct {
+	RoomID   string `json:"room_id"`
+	TargetID string `json:"target_id"`
+}
+
+// MutedByAdminPayload tells a participant an admin muted them, so their
+// client stops sending audio
+type MutedByAdminPayload struct {
+	RoomID string `json:"room_id"`
+	By     string `json:"by"`
+}
+
+// HandleForceMute processes call.force_mute. Only conversation admins in the
+// call may mute another participant.
+func (h *SFUHandler) HandleForceMute(ctx context.Context, sigCtx *SignalingContext, payload json.RawMessage) error {
+	var p ForceMutePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return &CallError{Code: "invalid_payload", Message: "Invalid force mute payload"}
+	}
+
+	roomID, err := uuid.Parse(p.RoomID)
+	if err != nil {
+		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
+	}
+	targetID, err := uuid.Parse(p.TargetID)
+	if err != nil || targetID == sigCtx.UserID {
+		return &CallError{Code: "invalid_target", Message: "Invalid target ID"}
+	}
+
+	room := h.sfu.GetRoom(roomID)
+	if room == nil {
+		return &CallError{Code: "room_not_found", Message: "Room not found"}
+	}
+	if room.GetParticipant(sigCtx.UserID) == nil {
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	if room.GetParticipant(targetID) == nil {
+		return &CallError{Code: "target_not_in_room", Message: "Target user not in call"}
+	}
+
+	role, err := h.convRepo.GetMemberRole(ctx, roomID, sigCtx.UserID)
+	if err != nil || role != domain.MemberRoleAdmin {
+		return &CallError{Code: "forbidden", Message: "Only admins can mute others"}
+	}
+
+	h.logger.Info("admin muted call participant", "room_id", roomID, "target", targetID, "by", sigCtx.UserID)
+	h.forceMute(ctx, room, targetID, sigCtx.UserID)
+	return nil
+}
+
+// forceMute tells the target to mute and everyone else that they are
+func (h *SFUHandler) forceMute(ctx context.Context, room *SFURoom, targetID, by uuid.UUID) {
+	mutedBytes, _ := json.Marshal(MutedByAdminPayload{
+		RoomID: room.ID.String(),
+		By:     by.String(),
+	})
+	muteBytes, _ := json.Marshal(map[string]interface{}{
+		"room_id": room.ID.String(),
+		"user_id": targetID.String(),
+		"kind":    "audio",
+		"muted":   true,
+	})
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+
+	if target, ok := room.participants[targetID]; ok {
+		target.setMuted("audio", true)
+	}
+	for _, participant := range room.participants {
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.User(participant.UserID.String()),
+			Type:    EventTypeCallMuteUpdate,
+			Payload: muteBytes,
+		}
+		if participant.UserID == targetID {
+			msg.Type = EventTypeCallMutedByAdmin
+			msg.Payload = mutedBytes
+		}
+		_ = h.pubsub.Publish(ctx, msg.Topic, msg)
+	}
+}
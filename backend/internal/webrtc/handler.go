@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/database"
@@ -13,11 +14,15 @@ import (
 
 // CallHandler processes WebRTC signaling messages from WebSocket
 type CallHandler struct {
-	manager  *Manager
-	convRepo *database.ConversationRepository
-	callRepo *database.CallRepository
-	pubsub   pubsub.PubSub
-	logger   *slog.Logger
+	manager    *Manager
+	convRepo   *database.ConversationRepository
+	callRepo   *database.CallRepository
+	pubsub     pubsub.PubSub
+	autoAccept AutoAcceptLookup
+	logger     *slog.Logger
+
+	// Where callees might already be on a call, for call waiting
+	callLocators []CallLocator
 }
 
 // NewCallHandler creates a new call handler
@@ -54,6 +59,9 @@ func (h *CallHandler) HandleJoin(ctx context.Context, sigCtx *SignalingContext,
 	if err != nil || !isMember {
 		return nil, &CallError{Code: "not_member", Message: "Not a member of this conversation"}
 	}
+	if banned, err := h.convRepo.IsBanned(ctx, roomID, sigCtx.UserID); err != nil || banned {
+		return nil, &CallError{Code: "banned", Message: "Banned from this conversation"}
+	}
 
 	// Join the call first - this is atomic
 	room, err := h.manager.JoinCall(ctx, roomID, sigCtx.UserID, sigCtx.Username)
@@ -104,6 +112,7 @@ func (h *CallHandler) HandleJoin(ctx context.Context, sigCtx *SignalingContext,
 
 			// Store call ID in room for later reference
 			room.SetCallID(callLog.ID)
+			h.manager.StartRinging(roomID, callLog.ID, sigCtx.UserID, recordMissedCall(h.callRepo, h.logger, callLog.ID))
 
 			// Notify other conversation members about incoming call
 			h.broadcastIncomingCall(ctx, roomID, callLog.ID, sigCtx, callType)
@@ -134,7 +143,7 @@ func (h *CallHandler) HandleJoin(ctx context.Context, sigCtx *SignalingContext,
 	// Return config with ICE servers and current participants
 	config := &CallConfigPayload{
 		RoomID:       roomID,
-		ICEServers:   h.manager.GetConfig().GetICEServers(),
+		ICEServers:   h.manager.ICEServers(ctx, sigCtx.UserID, roomID),
 		Participants: room.GetParticipants(),
 		IsInitiator:  isInitiator,
 	}
@@ -178,6 +187,7 @@ func (h *CallHandler) broadcastIncomingCall(ctx context.Context, conversationID,
 	payloadBytes, _ := json.Marshal(incomingPayload)
 
 	// Notify all members except the caller
+	var calleeIDs []uuid.UUID
 	for _, member := range conv.Members {
 		h.logger.Debug("checking member for notification",
 			"member_id", member.UserID,
@@ -187,6 +197,12 @@ func (h *CallHandler) broadcastIncomingCall(ctx context.Context, conversationID,
 		if member.UserID == caller.UserID {
 			continue
 		}
+		// Don't ring over a call the member is already on
+		if activeRoomID, busy := busyElsewhere(h.callLocators, member.UserID, conversationID); busy {
+			sendCallWaiting(ctx, h.pubsub, h.logger, incomingPayload, member.UserID, activeRoomID)
+			continue
+		}
+		calleeIDs = append(calleeIDs, member.UserID)
 
 		topic := pubsub.Topics.User(member.UserID.String())
 		h.logger.Info("sending call.incoming to user",
@@ -204,6 +220,8 @@ func (h *CallHandler) broadcastIncomingCall(ctx context.Context, conversationID,
 			h.logger.Info("successfully published call.incoming", "user_id", member.UserID)
 		}
 	}
+
+	sendAutoAcceptHints(ctx, h.pubsub, h.autoAccept, h.logger, incomingPayload, calleeIDs)
 }
 
 // HandleLeave processes a call.leave message
@@ -218,6 +236,12 @@ func (h *CallHandler) HandleLeave(ctx context.Context, sigCtx *SignalingContext,
 		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
 	}
 
+	h.leaveRoom(ctx, roomID, sigCtx)
+	return nil
+}
+
+// leaveRoom removes a participant from a P2P room, ending the call if it empties
+func (h *CallHandler) leaveRoom(ctx context.Context, roomID uuid.UUID, sigCtx *SignalingContext) {
 	// Get room before leaving to check if it will become empty
 	room := h.manager.GetRoom(roomID)
 	var callID uuid.UUID
@@ -232,8 +256,6 @@ func (h *CallHandler) HandleLeave(ctx context.Context, sigCtx *SignalingContext,
 		h.logger.Info("ending call in database", "call_id", callID)
 		_ = h.callRepo.EndCall(ctx, callID)
 	}
-
-	return nil
 }
 
 // HandleOffer relays an SDP offer to target participant
@@ -252,6 +274,9 @@ func (h *CallHandler) HandleOffer(ctx context.Context, sigCtx *SignalingContext,
 	if err != nil {
 		return &CallError{Code: "invalid_target", Message: "Invalid target ID"}
 	}
+	if targetID == sigCtx.UserID {
+		return &CallError{Code: "invalid_target", Message: "Cannot signal yourself"}
+	}
 
 	h.logger.Info("relaying offer", "from", sigCtx.UserID, "to", targetID, "room", roomID)
 
@@ -261,6 +286,14 @@ func (h *CallHandler) HandleOffer(ctx context.Context, sigCtx *SignalingContext,
 		return &CallError{Code: "no_call", Message: "No active call in this room"}
 	}
 
+	// SECURITY: Only participants may signal, or anyone who knew the room ID
+	// could push SDP or candidates at the people in it
+	if !room.HasParticipant(sigCtx.UserID) {
+		h.logger.Warn("call.offer from user not in room", "user_id", sigCtx.UserID, "room_id", roomID)
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	room.touch(sigCtx.UserID, time.Now())
+
 	// SECURITY: Verify target is actually in the room
 	if !room.HasParticipant(targetID) {
 		h.logger.Warn("call.offer target not found in room", "target_id", targetID, "room_id", roomID)
@@ -300,6 +333,9 @@ func (h *CallHandler) HandleAnswer(ctx context.Context, sigCtx *SignalingContext
 	if err != nil {
 		return &CallError{Code: "invalid_target", Message: "Invalid target ID"}
 	}
+	if targetID == sigCtx.UserID {
+		return &CallError{Code: "invalid_target", Message: "Cannot signal yourself"}
+	}
 
 	h.logger.Info("relaying answer", "from", sigCtx.UserID, "to", targetID, "room", roomID)
 
@@ -309,6 +345,14 @@ func (h *CallHandler) HandleAnswer(ctx context.Context, sigCtx *SignalingContext
 		return &CallError{Code: "no_call", Message: "No active call in this room"}
 	}
 
+	// SECURITY: Only participants may signal, or anyone who knew the room ID
+	// could push SDP or candidates at the people in it
+	if !room.HasParticipant(sigCtx.UserID) {
+		h.logger.Warn("call.answer from user not in room", "user_id", sigCtx.UserID, "room_id", roomID)
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	room.touch(sigCtx.UserID, time.Now())
+
 	// SECURITY: Verify target is actually in the room
 	if !room.HasParticipant(targetID) {
 		h.logger.Warn("call.answer target not found in room", "target_id", targetID, "room_id", roomID)
@@ -348,6 +392,9 @@ func (h *CallHandler) HandleICECandidate(ctx context.Context, sigCtx *SignalingC
 	if err != nil {
 		return &CallError{Code: "invalid_target", Message: "Invalid target ID"}
 	}
+	if targetID == sigCtx.UserID {
+		return &CallError{Code: "invalid_target", Message: "Cannot signal yourself"}
+	}
 
 	// Verify room exists
 	room := h.manager.GetRoom(roomID)
@@ -355,6 +402,14 @@ func (h *CallHandler) HandleICECandidate(ctx context.Context, sigCtx *SignalingC
 		return &CallError{Code: "no_call", Message: "No active call in this room"}
 	}
 
+	// SECURITY: Only participants may signal, or anyone who knew the room ID
+	// could push SDP or candidates at the people in it
+	if !room.HasParticipant(sigCtx.UserID) {
+		h.logger.Warn("call.candidate from user not in room", "user_id", sigCtx.UserID, "room_id", roomID)
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	room.touch(sigCtx.UserID, time.Now())
+
 	// SECURITY: Verify target is actually in the room
 	if !room.HasParticipant(targetID) {
 		h.logger.Warn("call.candidate target not found in room", "target_id", targetID, "room_id", roomID)
@@ -453,6 +508,7 @@ func (h *CallHandler) HandleReady(ctx context.Context, sigCtx *SignalingContext,
 	}
 
 	h.logger.Info("relaying call.ready", "from", sigCtx.UserID, "room_id", roomID)
+	room.touch(sigCtx.UserID, time.Now())
 
 	relayPayload := map[string]string{
 		"room_id": roomID.String(),
@@ -500,6 +556,10 @@ func (h *CallHandler) HandleMuteUpdate(ctx context.Context, sigCtx *SignalingCon
 	if room == nil {
 		return &CallError{Code: "no_call", Message: "No active call in this room"}
 	}
+	if !room.HasParticipant(sigCtx.UserID) {
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	room.touch(sigCtx.UserID, time.Now())
 
 	// Relay mute update to other participants in the P2P room
 	relayPayload := map[string]interface{}{
@@ -510,7 +570,8 @@ func (h *CallHandler) HandleMuteUpdate(ctx context.Context, sigCtx *SignalingCon
 	}
 	payloadBytes, _ := json.Marshal(relayPayload)
 
-	// Send to each participant except the sender
+	// Send to each participant except the sender, explicitly: the room's
+	// participant list includes them
 	for _, participant := range room.GetParticipants() {
 		if participant.UserID == sigCtx.UserID {
 			continue
@@ -526,6 +587,32 @@ func (h *CallHandler) HandleMuteUpdate(ctx context.Context, sigCtx *SignalingCon
 	return nil
 }
 
+// HandleHeartbeat processes a call.heartbeat message, marking the sender
+// active in their P2P call. Nothing is relayed.
+func (h *CallHandler) HandleHeartbeat(ctx context.Context, sigCtx *SignalingContext, payload json.RawMessage) error {
+	var p struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return &CallError{Code: "invalid_payload", Message: "Invalid heartbeat payload"}
+	}
+
+	roomID, err := uuid.Parse(p.RoomID)
+	if err != nil {
+		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
+	}
+
+	room := h.manager.GetRoom(roomID)
+	if room == nil {
+		return &CallError{Code: "no_call", Message: "No active call in this room"}
+	}
+	if !room.HasParticipant(sigCtx.UserID) {
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+	room.touch(sigCtx.UserID, time.Now())
+	return nil
+}
+
 // IsUserInRoom checks if a user is in a P2P room
 func (h *CallHandler) IsUserInRoom(roomID, userID uuid.UUID) bool {
 	room := h.manager.GetRoom(roomID)
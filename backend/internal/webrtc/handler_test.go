@@ -306,6 +306,53 @@ func TestCallHandler_HandleOffer_TargetNotInRoom(t *testing.T) {
 	assert.Equal(t, "target_not_found", callErr.Code)
 }
 
+// TestCallHandler_Signaling_SenderNotInRoom verifies an outsider who knows the
+// room ID can't relay offers, answers or candidates to someone in the call
+func TestCallHandler_Signaling_SenderNotInRoom(t *testing.T) {
+	handler, mgr, ps := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	aliceID := uuid.New()
+	bobID := uuid.New()
+	malloryID := uuid.New()
+
+	_, err := mgr.JoinCall(ctx, roomID, aliceID, "alice")
+	require.NoError(t, err)
+	_, err = mgr.JoinCall(ctx, roomID, bobID, "bob")
+	require.NoError(t, err)
+
+	received := make(chan *pubsub.Message, 3)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.User(bobID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	sigCtx := &SignalingContext{UserID: malloryID, Username: "mallory"}
+	offer, _ := json.Marshal(CallOfferPayload{RoomID: roomID.String(), TargetID: bobID.String(), SDP: "v=0..."})
+	answer, _ := json.Marshal(CallAnswerPayload{RoomID: roomID.String(), TargetID: bobID.String(), SDP: "v=0..."})
+	candidate, _ := json.Marshal(CallICECandidatePayload{RoomID: roomID.String(), TargetID: bobID.String(), Candidate: "candidate:..."})
+
+	for name, relay := range map[string]func() error{
+		"offer":     func() error { return handler.HandleOffer(ctx, sigCtx, offer) },
+		"answer":    func() error { return handler.HandleAnswer(ctx, sigCtx, answer) },
+		"candidate": func() error { return handler.HandleICECandidate(ctx, sigCtx, candidate) },
+	} {
+		err := relay()
+		require.Error(t, err, name)
+		callErr, ok := err.(*CallError)
+		require.True(t, ok, name)
+		assert.Equal(t, "not_in_call", callErr.Code, name)
+	}
+
+	select {
+	case msg := <-received:
+		t.Fatalf("Bob received %s from a non-participant", msg.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestCallHandler_HandleOffer_ValidRelay(t *testing.T) {
 	handler, mgr, ps := newTestCallHandler(t)
 	ctx := context.Background()
@@ -752,6 +799,68 @@ func TestCallHandler_HandleMuteUpdate_DoesNotRelayBackToSender(t *testing.T) {
 	}
 }
 
+func TestCallHandler_HandleMuteUpdate_SkipsSenderWithOthersPresent(t *testing.T) {
+	handler, mgr, ps := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	aliceID := uuid.New()
+	bobID := uuid.New()
+
+	_, _ = mgr.JoinCall(ctx, roomID, aliceID, "alice")
+	_, _ = mgr.JoinCall(ctx, roomID, bobID, "bob")
+
+	selfReceived := make(chan *pubsub.Message, 1)
+	selfSub, _ := ps.Subscribe(ctx, pubsub.Topics.User(aliceID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		selfReceived <- msg
+	})
+	defer func() { _ = selfSub.Unsubscribe() }()
+	bobReceived := make(chan *pubsub.Message, 1)
+	bobSub, _ := ps.Subscribe(ctx, pubsub.Topics.User(bobID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		bobReceived <- msg
+	})
+	defer func() { _ = bobSub.Unsubscribe() }()
+
+	sigCtx := &SignalingContext{UserID: aliceID, Username: "alice"}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"room_id": roomID.String(),
+		"kind":    "audio",
+		"muted":   true,
+	})
+	require.NoError(t, handler.HandleMuteUpdate(ctx, sigCtx, payload))
+
+	select {
+	case <-bobReceived:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Bob did not receive mute update")
+	}
+	select {
+	case <-selfReceived:
+		t.Fatal("Mute update should NOT be relayed back to the sender")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCallHandler_HandleMuteUpdate_SenderNotInRoom(t *testing.T) {
+	handler, mgr, _ := newTestCallHandler(t)
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	_, _ = mgr.JoinCall(ctx, roomID, uuid.New(), "alice")
+
+	sigCtx := &SignalingContext{UserID: uuid.New(), Username: "mallory"}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"room_id": roomID.String(),
+		"kind":    "audio",
+		"muted":   true,
+	})
+	err := handler.HandleMuteUpdate(ctx, sigCtx, payload)
+	require.Error(t, err)
+	callErr, ok := err.(*CallError)
+	require.True(t, ok)
+	assert.Equal(t, "not_in_call", callErr.Code)
+}
+
 // =============================================================================
 // HandleDeclined Tests
 // =============================================================================
@@ -887,11 +996,7 @@ func TestCallError_ImplementsError(t *testing.T) {
 // Edge Cases & Security Tests
 // =============================================================================
 
-func TestCallHandler_HandleOffer_SelfTarget(t *testing.T) {
-	// Sending an offer to yourself should return target_not_found
-	// because the room doesn't have you as a separate target.
-	// Actually, the room DOES have you as a participant, so this
-	// succeeds and relays to yourself — which is a potential bug.
+func TestCallHandler_SelfTargetedSignalingIsRejected(t *testing.T) {
 	handler, mgr, ps := newTestCallHandler(t)
 	ctx := context.Background()
 
@@ -900,30 +1005,37 @@ func TestCallHandler_HandleOffer_SelfTarget(t *testing.T) {
 
 	_, _ = mgr.JoinCall(ctx, roomID, aliceID, "alice")
 
-	selfReceived := make(chan *pubsub.Message, 1)
+	selfReceived := make(chan *pubsub.Message, 3)
 	sub, _ := ps.Subscribe(ctx, pubsub.Topics.User(aliceID.String()), func(ctx context.Context, msg *pubsub.Message) {
 		selfReceived <- msg
 	})
 	defer func() { _ = sub.Unsubscribe() }()
 
 	sigCtx := &SignalingContext{UserID: aliceID, Username: "alice"}
-	payload, _ := json.Marshal(CallOfferPayload{
-		RoomID:   roomID.String(),
-		TargetID: aliceID.String(), // Targeting self!
-		SDP:      "v=0...",
+	offer, _ := json.Marshal(CallOfferPayload{RoomID: roomID.String(), TargetID: aliceID.String(), SDP: "v=0..."})
+	answer, _ := json.Marshal(CallAnswerPayload{RoomID: roomID.String(), TargetID: aliceID.String(), SDP: "v=0..."})
+	candidate, _ := json.Marshal(CallICECandidatePayload{
+		RoomID:    roomID.String(),
+		TargetID:  aliceID.String(),
+		Candidate: map[string]interface{}{"candidate": "candidate:1 1 UDP 2130706431 192.168.1.1 5000 typ host"},
 	})
 
-	err := handler.HandleOffer(ctx, sigCtx, payload)
-	// NOTE: This test documents that self-targeting is allowed.
-	// A production system should probably reject it.
-	// If this passes, it means the code has no self-targeting guard.
-	assert.NoError(t, err, "self-targeting offer succeeds — possible security gap")
+	for name, send := range map[string]func() error{
+		"offer":     func() error { return handler.HandleOffer(ctx, sigCtx, offer) },
+		"answer":    func() error { return handler.HandleAnswer(ctx, sigCtx, answer) },
+		"candidate": func() error { return handler.HandleICECandidate(ctx, sigCtx, candidate) },
+	} {
+		err := send()
+		require.Error(t, err, name)
+		callErr, ok := err.(*CallError)
+		require.True(t, ok, "expected *CallError, got %T", err)
+		assert.Equal(t, "invalid_target", callErr.Code, name)
+	}
 
 	select {
 	case msg := <-selfReceived:
-		assert.Equal(t, EventTypeCallOffer, msg.Type, "self-targeted offer was relayed")
+		t.Fatalf("sender received their own %s", msg.Type)
 	case <-time.After(200 * time.Millisecond):
-		// If we get here, self-targeting was blocked (good!)
 	}
 }
 
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ICEServerProvider chooses the STUN/TURN servers sent to a user joining a
+// call, e.g. the ones nearest their region
+type ICEServerProvider interface {
+	ICEServersForCall(ctx context.Context, userID, roomID uuid.UUID) []ICEServer
+}
+
+// ICEServersForCall makes Config the default provider: the configured
+// servers, with TURN credentials issued to the user
+func (c *Config) ICEServersForCall(_ context.Context, userID, _ uuid.UUID) []ICEServer {
+	return c.ICEServersFor(userID)
+}
+
+// SetICEServerProvider overrides where the ICE servers in call.config and
+// sfu.config payloads come from. Nil restores the configured servers.
+func (m *Manager) SetICEServerProvider(provider ICEServerProvider) {
+	m.iceMu.Lock()
+	defer m.iceMu.Unlock()
+	m.iceProvider = provider
+}
+
+// ICEServers returns the ICE servers for a user joining the call in roomID
+func (m *Manager) ICEServers(ctx context.Context, userID, roomID uuid.UUID) []ICEServer {
+	m.iceMu.RLock()
+	provider := m.iceProvider
+	m.iceMu.RUnlock()
+
+	if provider == nil {
+		if m.config == nil {
+			return []ICEServer{}
+		}
+		provider = m.config
+	}
+	return provider.ICEServersForCall(ctx, userID, roomID)
+}
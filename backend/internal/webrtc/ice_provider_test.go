@@ -0,0 +1,49 @@
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// regionProvider hands out one TURN server and remembers who asked
+type regionProvider struct {
+	userID, roomID uuid.UUID
+}
+
+func (p *regionProvider) ICEServersForCall(_ context.Context, userID, roomID uuid.UUID) []ICEServer {
+	p.userID, p.roomID = userID, roomID
+	return []ICEServer{{URLs: []string{"turn:eu.turn.example.com:3478"}}}
+}
+
+func TestManager_ICEServers_Provider(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mgr := NewManager(&Config{STUNURLs: []string{"stun:stun.example.com:19302"}}, ps, logger)
+	ctx := context.Background()
+	userID, roomID := uuid.New(), uuid.New()
+
+	servers := mgr.ICEServers(ctx, userID, roomID)
+	require.Len(t, servers, 1)
+	assert.Equal(t, []string{"stun:stun.example.com:19302"}, servers[0].URLs, "defaults to the configured servers")
+
+	provider := &regionProvider{}
+	mgr.SetICEServerProvider(provider)
+	servers = mgr.ICEServers(ctx, userID, roomID)
+	require.Len(t, servers, 1)
+	assert.Equal(t, []string{"turn:eu.turn.example.com:3478"}, servers[0].URLs)
+	assert.Equal(t, userID, provider.userID)
+	assert.Equal(t, roomID, provider.roomID)
+
+	mgr.SetICEServerProvider(nil)
+	servers = mgr.ICEServers(ctx, userID, roomID)
+	require.Len(t, servers, 1)
+	assert.Equal(t, []string{"stun:stun.example.com:19302"}, servers[0].URLs)
+}
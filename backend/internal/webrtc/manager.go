@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/pubsub"
@@ -27,9 +28,17 @@ type Config struct {
 	TURNURLs     []string // e.g., ["turn:your-server:3478"]
 	TURNUsername string
 	TURNPassword string
+	RingTimeout  time.Duration // unanswered calls are marked missed after this, DefaultRingTimeout if unset
+	IdleTimeout  time.Duration // silent, disconnected participants are reaped after this, DefaultIdleTimeout if unset
+
+	// Shared secret for time-limited TURN credentials; the static
+	// username and password are used when it's empty
+	TURNSecret        string
+	TURNCredentialTTL time.Duration // DefaultTURNCredentialTTL if unset
 }
 
-// GetICEServers returns the ICE server configuration for clients
+// GetICEServers returns the ICE server configuration for clients with the
+// static TURN credentials. Use ICEServersFor to issue time-limited ones.
 func (c *Config) GetICEServers() []ICEServer {
 	servers := make([]ICEServer, 0, 2)
 
@@ -49,6 +58,7 @@ func (c *Config) GetICEServers() []ICEServer {
 }
 
 // GetPionICEServers returns ICE server configuration in Pion WebRTC format
+// with the static TURN credentials
 func (c *Config) GetPionICEServers() []pionwebrtc.ICEServer {
 	servers := make([]pionwebrtc.ICEServer, 0, 2)
 
@@ -72,6 +82,13 @@ func (c *Config) GetPionICEServers() []pionwebrtc.ICEServer {
 type Participant struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
+
+	// Group calls only
+	HandRaised bool `json:"hand_raised,omitempty"`
+	AudioMuted bool `json:"audio_muted,omitempty"`
+	VideoMuted bool `json:"video_muted,omitempty"`
+
+	lastActivity time.Time // joined or last signaled
 }
 
 // Room represents an active video call
@@ -110,8 +127,9 @@ func (r *Room) AddParticipant(userID uuid.UUID, username string) *Participant {
 	defer r.mu.Unlock()
 
 	p := &Participant{
-		UserID:   userID,
-		Username: username,
+		UserID:       userID,
+		Username:     username,
+		lastActivity: time.Now(),
 	}
 	r.Participants[userID] = p
 	return p
@@ -158,15 +176,26 @@ type Manager struct {
 	config *Config
 	pubsub pubsub.PubSub
 	logger *slog.Logger
+
+	// Ring timeouts for calls still waiting on an answer, by room
+	ringing map[uuid.UUID]*time.Timer
+	ringMu  sync.Mutex
+
+	// Picks joining users' ICE servers, the config's if unset
+	iceProvider ICEServerProvider
+	iceMu       sync.RWMutex
+
+	// Spares connected participants from the idle reaper
 }
 
 // NewManager creates a new WebRTC manager
 func NewManager(cfg *Config, ps pubsub.PubSub, logger *slog.Logger) *Manager {
 	return &Manager{
-		rooms:  make(map[uuid.UUID]*Room),
-		config: cfg,
-		pubsub: ps,
-		logger: logger,
+		rooms:   make(map[uuid.UUID]*Room),
+		config:  cfg,
+		pubsub:  ps,
+		logger:  logger,
+		ringing: make(map[uuid.UUID]*time.Timer),
 	}
 }
 
@@ -206,6 +235,9 @@ func (m *Manager) JoinCall(ctx context.Context, roomID, userID uuid.UUID, userna
 	existingParticipants := room.GetParticipants()
 
 	room.AddParticipant(userID, username)
+	if room.ParticipantCount() > 1 {
+		m.StopRinging(roomID)
+	}
 
 	// Notify other participants via pubsub (send to each user's topic)
 	event := CallParticipantEvent{
@@ -271,6 +303,7 @@ func (m *Manager) LeaveCall(ctx context.Context, roomID, userID uuid.UUID, usern
 
 	// Clean up empty rooms
 	if room.ParticipantCount() == 0 {
+		m.StopRinging(roomID)
 		m.DeleteRoom(roomID)
 		m.logger.Info("call ended (no participants)", "room_id", roomID)
 	}
@@ -22,6 +22,14 @@ const (
 	EventTypeCallReady      = "call.ready"       // Sent when participant is ready for offer
 	EventTypeCallMuteUpdate = "call.mute_update" // Sent when participant toggles mute/video
 	EventTypeCallMigration  = "call.migration"   // Sent when P2P call migrates to SFU
+	EventTypeCallAutoAccept = "call.auto_accept" // Sent to callees whose client should auto-answer
+	EventTypeCallWaiting    = "call.waiting"     // Sent instead of call.incoming to callees already on another call
+	EventTypeCallBusy       = "call.busy"        // Sent to the caller for each callee already on another call
+	EventTypeCallMissed     = "call.missed"      // Sent to the conversation when nobody answers in time
+	EventTypeCallHeartbeat  = "call.heartbeat"   // Sent periodically by P2P participants so the reaper knows they're still in the call
+
+	EventTypeCallAnswered          = "call.answered"           // Sent to those already in the call when a callee joins
+	EventTypeCallAnsweredElsewhere = "call.answered_elsewhere" // Sent to the callee's other connections so they stop ringing
 
 	// SFU Events
 	// Note: EventTypeSFUJoin exists for completeness but the frontend always sends
@@ -33,6 +41,18 @@ const (
 	EventTypeSFUCandidate  = "sfu.candidate"
 	EventTypeSFUTracks     = "sfu.tracks"
 	EventTypeSFUMuteUpdate = "sfu.mute_update"
+
+	EventTypeCallActiveSpeaker      = "call.active_speaker"      // Sent to the room when the dominant speaker changes
+	EventTypeCallScreenShareStarted = "call.screenshare_started" // Relayed to the other participants of an SFU call
+	EventTypeCallScreenShareStopped = "call.screenshare_stopped"
+	EventTypeCallRecordingStarted   = "call.recording_started" // Sent to the conversation when an admin starts recording an SFU call
+	EventTypeCallRecordingStopped   = "call.recording_stopped"
+	EventTypeCallVideoPaused        = "call.video_paused" // Sent to the conversation when a participant's video stops being forwarded in a large SFU call
+	EventTypeCallVideoResumed       = "call.video_resumed"
+	EventTypeCallRaiseHand          = "call.raise_hand" // Relayed to the other participants of an SFU call
+	EventTypeCallLowerHand          = "call.lower_hand"
+	EventTypeCallForceMute          = "call.force_mute"     // Sent by a conversation admin to mute someone in an SFU call
+	EventTypeCallMutedByAdmin       = "call.muted_by_admin" // Sent to the participant an admin muted
 )
 
 // CallJoinPayload is sent by client to join a call
@@ -101,12 +121,43 @@ type CallIncomingPayload struct {
 	IsGroup          bool      `json:"is_group"`
 }
 
+// CallAutoAcceptPayload follows call.incoming for a callee who auto-answers
+// calls from the caller; the client does the actual answering
+type CallAutoAcceptPayload struct {
+	CallID         uuid.UUID `json:"call_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CallerID       uuid.UUID `json:"caller_id"`
+}
+
+// CallWaitingPayload is sent to a callee already on another call. It carries
+// the incoming call plus the room they're in, so the client can offer to hold
+// or switch.
+type CallWaitingPayload struct {
+	CallIncomingPayload
+	ActiveRoomID uuid.UUID `json:"active_room_id"`
+}
+
+// CallBusyPayload tells the caller a callee is on another call
+type CallBusyPayload struct {
+	CallID         uuid.UUID `json:"call_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
 // CallAcceptedPayload is sent when someone accepts the call
 type CallAcceptedPayload struct {
 	CallID uuid.UUID `json:"call_id"`
 	UserID uuid.UUID `json:"user_id"`
 }
 
+// CallAnsweredPayload is sent as call.answered and call.answered_elsewhere
+// when a callee joins the call
+type CallAnsweredPayload struct {
+	RoomID   uuid.UUID `json:"room_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
 // CallDeclinedPayload is sent when someone declines the call
 type CallDeclinedPayload struct {
 	CallID uuid.UUID `json:"call_id"`
@@ -119,6 +170,19 @@ type CallCancelledPayload struct {
 	CallerID uuid.UUID `json:"caller_id"`
 }
 
+// CallMissedPayload is sent when a call times out unanswered
+type CallMissedPayload struct {
+	CallID         uuid.UUID `json:"call_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CallerID       uuid.UUID `json:"caller_id"`
+}
+
+// ActiveSpeakerPayload names who is currently talking in an SFU call
+type ActiveSpeakerPayload struct {
+	RoomID uuid.UUID `json:"room_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
 // CallEndedPayload is sent when call ends
 type CallEndedPayload struct {
 	CallID          uuid.UUID `json:"call_id"`
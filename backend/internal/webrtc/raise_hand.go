@@ -0,0 +1,71 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// HandPayload is sent by a participant raising or lowering their hand, and
+// relayed to the rest of the room with their user ID
+type HandPayload struct {
+	RoomID string `json:"room_id"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// HandleHand processes call.raise_hand and call.lower_hand, flagging the
+// participant so late joiners see it and telling the other participants
+func (h *SFUHandler) HandleHand(ctx context.Context, sigCtx *SignalingContext, payload json.RawMessage, raised bool) error {
+	var p HandPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return &CallError{Code: "invalid_payload", Message: "Invalid raise hand payload"}
+	}
+
+	roomID, err := uuid.Parse(p.RoomID)
+	if err != nil {
+		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
+	}
+
+	room := h.sfu.GetRoom(roomID)
+	if room == nil {
+		return &CallError{Code: "room_not_found", Message: "Room not found"}
+	}
+
+	participant := room.GetParticipant(sigCtx.UserID)
+	if participant == nil {
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+
+	// Nothing to tell anyone if the hand is already where they want it
+	if participant.handRaised.Swap(raised) == raised {
+		return nil
+	}
+
+	eventType := EventTypeCallLowerHand
+	if raised {
+		eventType = EventTypeCallRaiseHand
+	}
+	payloadBytes, _ := json.Marshal(HandPayload{
+		RoomID: roomID.String(),
+		UserID: sigCtx.UserID.String(),
+	})
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+
+	for _, other := range room.participants {
+		if other.UserID == sigCtx.UserID {
+			continue
+		}
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.User(other.UserID.String()),
+			Type:    eventType,
+			Payload: payloadBytes,
+		}
+		_ = h.pubsub.Publish(ctx, msg.Topic, msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/database"
+)
+
+// DefaultIdleTimeout is how long a P2P participant can go without signaling,
+// heartbeats included, before the reaper takes them out of the call
+const DefaultIdleTimeout = 5 * time.Minute
+
+// HeartbeatInterval is how often clients in a P2P call send call.heartbeat: a
+// settled call has no other signaling to keep it alive
+const HeartbeatInterval = time.Minute
+
+func (m *Manager) idleTimeout() time.Duration {
+	if m.config != nil && m.config.IdleTimeout > 0 {
+		return m.config.IdleTimeout
+	}
+	return DefaultIdleTimeout
+}
+
+func (r *Room) touch(userID uuid.UUID, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.Participants[userID]; ok {
+		p.lastActivity = now
+	}
+}
+
+// RunReaper takes idle participants out of P2P calls until ctx is done,
+// e.g. clients that crashed, or a tab that stayed connected after its call
+// UI went away. Only the call's own activity counts, not presence. ended
+// is called with the call log ID of each call the reaper empties.
+func (m *Manager) RunReaper(ctx context.Context, ended func(ctx context.Context, callID uuid.UUID)) {
+	ticker := time.NewTicker(m.idleTimeout() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, callID := range m.reapIdle(ctx, now) {
+				if ended != nil {
+					ended(ctx, callID)
+				}
+			}
+		}
+	}
+}
+
+// reapIdle removes participants idle since before the timeout, deleting the
+// rooms that leaves empty. It returns the call log IDs of those rooms.
+func (m *Manager) reapIdle(ctx context.Context, now time.Time) []uuid.UUID {
+	cutoff := now.Add(-m.idleTimeout())
+
+	m.mu.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.RUnlock()
+
+	var ended []uuid.UUID
+	for _, room := range rooms {
+		callID := room.GetCallID()
+		reaped := false
+		for _, p := range room.GetParticipants() {
+			if p.lastActivity.After(cutoff) {
+				continue
+			}
+			m.logger.Warn("reaping idle call participant", "room_id", room.ID, "user_id", p.UserID, "last_activity", p.lastActivity)
+			m.LeaveCall(ctx, room.ID, p.UserID, p.Username)
+			reaped = true
+		}
+		if reaped && m.GetRoom(room.ID) == nil && callID != uuid.Nil {
+			ended = append(ended, callID)
+		}
+	}
+	return ended
+}
+
+// EndCallLog returns a RunReaper callback that marks reaped calls ended
+func EndCallLog(callRepo *database.CallRepository, logger *slog.Logger) func(ctx context.Context, callID uuid.UUID) {
+	return func(ctx context.Context, callID uuid.UUID) {
+		if callRepo == nil {
+			return
+		}
+		logger.Info("ending reaped call in database", "call_id", callID)
+		if err := callRepo.EndCall(ctx, callID); err != nil {
+			logger.Error("failed to end reaped call", "error", err, "call_id", callID)
+		}
+	}
+}
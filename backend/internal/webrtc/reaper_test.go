@@ -0,0 +1,80 @@
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReapingManager(t *testing.T, timeout time.Duration) *Manager {
+	t.Helper()
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewManager(&Config{IdleTimeout: timeout}, ps, logger)
+}
+
+func TestManager_ReapIdle(t *testing.T) {
+	mgr := newReapingManager(t, time.Minute)
+	ctx := context.Background()
+	roomID, callID, alice, bob := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	room, err := mgr.JoinCall(ctx, roomID, alice, "alice")
+	require.NoError(t, err)
+	room.SetCallID(callID)
+	_, err = mgr.JoinCall(ctx, roomID, bob, "bob")
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.Empty(t, mgr.reapIdle(ctx, now), "nobody is idle yet")
+
+	// Bob keeps signaling, alice goes quiet
+	room.touch(bob, now.Add(90*time.Second))
+	assert.Empty(t, mgr.reapIdle(ctx, now.Add(2*time.Minute)))
+	assert.False(t, room.HasParticipant(alice))
+	assert.True(t, room.HasParticipant(bob))
+
+	ended := mgr.reapIdle(ctx, now.Add(5*time.Minute))
+	assert.Equal(t, []uuid.UUID{callID}, ended)
+	assert.Nil(t, mgr.GetRoom(roomID))
+}
+
+func TestCallHandler_HeartbeatKeepsParticipantInCall(t *testing.T) {
+	mgr := newReapingManager(t, time.Minute)
+	ctx := context.Background()
+	roomID, alice, mallory := uuid.New(), uuid.New(), uuid.New()
+	handler := NewCallHandler(mgr, nil, nil, pubsub.NewMemoryPubSub(), mgr.logger)
+
+	room, err := mgr.JoinCall(ctx, roomID, alice, "alice")
+	require.NoError(t, err)
+
+	// Alice joined long ago and has been in a settled call since
+	room.touch(alice, time.Now().Add(-10*time.Minute))
+	payload := []byte(`{"room_id":"` + roomID.String() + `"}`)
+	require.NoError(t, handler.HandleHeartbeat(ctx, &SignalingContext{UserID: alice, Username: "alice"}, payload))
+	now := time.Now()
+
+	var callErr *CallError
+	err = handler.HandleHeartbeat(ctx, &SignalingContext{UserID: mallory, Username: "mallory"}, payload)
+	require.ErrorAs(t, err, &callErr)
+	assert.Equal(t, "not_in_call", callErr.Code)
+
+	assert.Empty(t, mgr.reapIdle(ctx, now.Add(30*time.Second)))
+	assert.True(t, room.HasParticipant(alice))
+
+	// Once the heartbeats stop she is reaped, whether or not she's still connected
+	mgr.reapIdle(ctx, now.Add(2*time.Minute))
+	assert.Nil(t, mgr.GetRoom(roomID))
+}
+
+func TestManager_IdleTimeoutDefault(t *testing.T) {
+	assert.Equal(t, DefaultIdleTimeout, newReapingManager(t, 0).idleTimeout())
+	assert.Equal(t, time.Minute, newReapingManager(t, time.Minute).idleTimeout())
+}
@@ -0,0 +1,270 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+var (
+	ErrRecordingDisabled = errors.New("call recording is not enabled")
+	ErrAlreadyRecording  = errors.New("call is already being recorded")
+	ErrNotRecording      = errors.New("call is not being recorded")
+	ErrSFURoomNotFound   = errors.New("no active SFU call in this room")
+)
+
+// RecordingPayload is sent to the conversation when an SFU call starts or
+// stops being recorded, so everyone on it knows
+type RecordingPayload struct {
+	RoomID uuid.UUID `json:"room_id"`
+	CallID uuid.UUID `json:"call_id,omitempty"`
+	By     uuid.UUID `json:"by"`
+}
+
+// trackRecording is one track's file. Each has its own lock so a slow disk
+// write only holds up the track being written.
+type trackRecording struct {
+	mu     sync.Mutex
+	writer media.Writer
+}
+
+// roomRecorder writes each track of an SFU room to its own file: IVF for
+// VP8 video, OGG for Opus audio
+type roomRecorder struct {
+	dir    string
+	mu     sync.Mutex
+	tracks map[string]*trackRecording // trackKey -> file
+	closed bool
+}
+
+func newRoomRecorder(dir string) (*roomRecorder, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &roomRecorder{dir: dir, tracks: make(map[string]*trackRecording)}, nil
+}
+
+// attach opens a file for a sender's track. It reports whether a new file
+// was opened; a track already being recorded, or in a codec there's no
+// writer for, is skipped.
+func (r *roomRecorder) attach(senderID uuid.UUID, track *webrtc.TrackRemote) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := trackKey(senderID, track.ID())
+	if r.closed || r.tracks[key] != nil {
+		return false, nil
+	}
+
+	// Track IDs come from the client, so they stay out of the file name
+	base := filepath.Join(r.dir, fmt.Sprintf("%s-%s-%d", senderID, track.Kind(), len(r.tracks)+1))
+	codec := track.Codec()
+
+	var writer media.Writer
+	var err error
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		writer, err = ivfwriter.New(base+".ivf", ivfwriter.WithCodec(webrtc.MimeTypeVP8))
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		writer, err = oggwriter.New(base+".ogg", codec.ClockRate, codec.Channels)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.tracks[key] = &trackRecording{writer: writer}
+	return true, nil
+}
+
+// write tees a packet into the sender's track file, if it has one
+func (r *roomRecorder) write(senderID uuid.UUID, trackID string, packet *rtp.Packet) {
+	r.mu.Lock()
+	tr := r.tracks[trackKey(senderID, trackID)]
+	r.mu.Unlock()
+	if tr == nil {
+		return
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	// A packet the depacketizer can't parse only costs that frame
+	_ = tr.writer.WriteRTP(packet)
+}
+
+// close finalizes every file. Packets written afterwards are dropped.
+func (r *roomRecorder) close() error {
+	r.mu.Lock()
+	tracks := r.tracks
+	r.tracks = nil
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, tr := range tracks {
+		tr.mu.Lock()
+		errs = append(errs, tr.writer.Close())
+		tr.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Recording reports whether the room is being recorded
+func (r *SFURoom) Recording() bool {
+	return r.recorder.Load() != nil
+}
+
+// StartRecording starts recording every track in an SFU room, including ones
+// added later, to a new directory under the configured recording directory.
+// It returns the directory.
+func (s *SFU) StartRecording(roomID uuid.UUID) (string, error) {
+	if s.config == nil || s.config.RecordingDir == "" {
+		return "", ErrRecordingDisabled
+	}
+	room := s.GetRoom(roomID)
+	if room == nil {
+		return "", ErrSFURoomNotFound
+	}
+	if room.Recording() {
+		return "", ErrAlreadyRecording
+	}
+
+	dir := filepath.Join(s.config.RecordingDir, fmt.Sprintf("%s-%s", roomID, time.Now().UTC().Format("20060102T150405Z")))
+	rec, err := newRoomRecorder(dir)
+	if err != nil {
+		return "", fmt.Errorf("create recording directory: %w", err)
+	}
+	if !room.recorder.CompareAndSwap(nil, rec) {
+		_ = os.Remove(dir)
+		return "", ErrAlreadyRecording
+	}
+
+	// Tracks added from here on are attached by handleIncomingTrack
+	type videoTrack struct {
+		senderID uuid.UUID
+		trackID  string
+	}
+	var keyframes []videoTrack
+	room.mu.RLock()
+	for _, p := range room.participants {
+		p.mu.RLock()
+		for _, track := range p.remoteTracks {
+			opened, err := rec.attach(p.UserID, track)
+			if err != nil {
+				s.logger.Error("failed to start track recording", "error", err, "room_id", roomID, "user_id", p.UserID)
+				continue
+			}
+			if opened && track.Kind() == webrtc.RTPCodecTypeVideo {
+				keyframes = append(keyframes, videoTrack{p.UserID, track.ID()})
+			}
+		}
+		p.mu.RUnlock()
+	}
+	room.mu.RUnlock()
+
+	// Video files can only start at a keyframe, so ask for one now rather
+	// than wait for the next
+	for _, v := range keyframes {
+		s.requestKeyframe(v.senderID, v.trackID, roomID)
+	}
+
+	s.logger.Info("call recording started", "room_id", roomID, "dir", dir)
+	return dir, nil
+}
+
+// StopRecording stops recording an SFU room and finalizes its files,
+// returning the directory they're in
+func (s *SFU) StopRecording(roomID uuid.UUID) (string, error) {
+	room := s.GetRoom(roomID)
+	if room == nil {
+		return "", ErrSFURoomNotFound
+	}
+	rec := room.recorder.Swap(nil)
+	if rec == nil {
+		return "", ErrNotRecording
+	}
+
+	if err := rec.close(); err != nil {
+		s.logger.Error("failed to finalize call recording", "error", err, "room_id", roomID, "dir", rec.dir)
+	}
+	s.logger.Info("call recording stopped", "room_id", roomID, "dir", rec.dir)
+	return rec.dir, nil
+}
+
+// finishRecording finalizes a deleted room's recording, if it had one
+func (s *SFU) finishRecording(room *SFURoom) {
+	rec := room.recorder.Swap(nil)
+	if rec == nil {
+		return
+	}
+	if err := rec.close(); err != nil {
+		s.logger.Error("failed to finalize call recording", "error", err, "room_id", room.ID, "dir", rec.dir)
+	}
+	s.logger.Info("call recording finished with call", "room_id", room.ID, "dir", rec.dir)
+}
+
+// StartRecording starts recording the SFU call in roomID on behalf of an
+// admin, stores where on the call log and tells the conversation
+func (h *SFUHandler) StartRecording(ctx context.Context, roomID, by uuid.UUID) (string, error) {
+	dir, err := h.sfu.StartRecording(roomID)
+	if err != nil {
+		return "", err
+	}
+
+	var callID uuid.UUID
+	if room := h.sfu.GetRoom(roomID); room != nil {
+		callID = room.GetCallID()
+	}
+	if callID != uuid.Nil && h.callRepo != nil {
+		if err := h.callRepo.SetRecordingPath(ctx, callID, dir); err != nil {
+			h.logger.Error("failed to store recording path", "error", err, "call_id", callID)
+		}
+	}
+
+	h.publishRecording(ctx, EventTypeCallRecordingStarted, RecordingPayload{RoomID: roomID, CallID: callID, By: by})
+	return dir, nil
+}
+
+// StopRecording stops recording the SFU call in roomID and tells the
+// conversation
+func (h *SFUHandler) StopRecording(ctx context.Context, roomID, by uuid.UUID) (string, error) {
+	var callID uuid.UUID
+	if room := h.sfu.GetRoom(roomID); room != nil {
+		callID = room.GetCallID()
+	}
+
+	dir, err := h.sfu.StopRecording(roomID)
+	if err != nil {
+		return "", err
+	}
+
+	h.publishRecording(ctx, EventTypeCallRecordingStopped, RecordingPayload{RoomID: roomID, CallID: callID, By: by})
+	return dir, nil
+}
+
+func (h *SFUHandler) publishRecording(ctx context.Context, eventType string, payload RecordingPayload) {
+	payloadBytes, _ := json.Marshal(payload)
+	msg := &pubsub.Message{
+		Topic:   pubsub.Topics.Room(payload.RoomID.String()),
+		Type:    eventType,
+		Payload: payloadBytes,
+	}
+	if err := h.pubsub.Publish(ctx, msg.Topic, msg); err != nil {
+		h.logger.Error("failed to publish recording event", "error", err, "room_id", payload.RoomID)
+	}
+}
@@ -0,0 +1,132 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_StartRecording_Disabled(t *testing.T) {
+	_, sfu, _, _ := newTestSFUHandler(t)
+	roomID := uuid.New()
+	sfu.GetOrCreateRoom(roomID)
+
+	_, err := sfu.StartRecording(roomID)
+	assert.ErrorIs(t, err, ErrRecordingDisabled)
+}
+
+func TestSFU_Recording_Lifecycle(t *testing.T) {
+	_, sfu, _, _ := newTestSFUHandler(t)
+	sfu.config.RecordingDir = t.TempDir()
+
+	_, err := sfu.StartRecording(uuid.New())
+	assert.ErrorIs(t, err, ErrSFURoomNotFound)
+
+	roomID := uuid.New()
+	room := sfu.GetOrCreateRoom(roomID)
+
+	dir, err := sfu.StartRecording(roomID)
+	require.NoError(t, err)
+	assert.DirExists(t, dir)
+	assert.Equal(t, sfu.config.RecordingDir, filepath.Dir(dir))
+	assert.True(t, room.Recording())
+
+	_, err = sfu.StartRecording(roomID)
+	assert.ErrorIs(t, err, ErrAlreadyRecording)
+
+	stopped, err := sfu.StopRecording(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, dir, stopped)
+	assert.False(t, room.Recording())
+
+	_, err = sfu.StopRecording(roomID)
+	assert.ErrorIs(t, err, ErrNotRecording)
+}
+
+func TestSFU_DeleteRoom_FinalizesRecording(t *testing.T) {
+	_, sfu, _, _ := newTestSFUHandler(t)
+	sfu.config.RecordingDir = t.TempDir()
+	roomID := uuid.New()
+	room := sfu.GetOrCreateRoom(roomID)
+
+	_, err := sfu.StartRecording(roomID)
+	require.NoError(t, err)
+	rec := room.recorder.Load()
+
+	sfu.DeleteRoom(roomID)
+	assert.False(t, room.Recording())
+	assert.True(t, rec.closed)
+}
+
+func TestRoomRecorder_WritesTrackUntilClosed(t *testing.T) {
+	rec, err := newRoomRecorder(t.TempDir())
+	require.NoError(t, err)
+
+	senderID := uuid.New()
+	path := filepath.Join(rec.dir, "audio.ogg")
+	writer, err := oggwriter.New(path, 48000, 2)
+	require.NoError(t, err)
+	rec.tracks[trackKey(senderID, "audio")] = &trackRecording{writer: writer}
+
+	opus := &rtp.Packet{Header: rtp.Header{Timestamp: 960}, Payload: []byte{0xfc, 0xff, 0xfe}}
+	rec.write(senderID, "audio", opus)
+	rec.write(uuid.New(), "audio", opus) // not recorded
+	require.NoError(t, rec.close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	size := info.Size()
+	assert.Greater(t, size, int64(0))
+
+	rec.write(senderID, "audio", opus)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, size, info.Size(), "writes after close are dropped")
+}
+
+func TestSFUHandler_StartRecording_NotifiesConversation(t *testing.T) {
+	handler, sfu, _, ps := newTestSFUHandler(t)
+	sfu.config.RecordingDir = t.TempDir()
+	ctx := context.Background()
+
+	roomID := uuid.New()
+	adminID := uuid.New()
+	sfu.GetOrCreateRoom(roomID)
+
+	received := make(chan *pubsub.Message, 2)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.Room(roomID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	_, err = handler.StartRecording(ctx, roomID, adminID)
+	require.NoError(t, err)
+	_, err = handler.StopRecording(ctx, roomID, adminID)
+	require.NoError(t, err)
+
+	var types []string
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-received:
+			types = append(types, msg.Type)
+			var payload RecordingPayload
+			require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+			assert.Equal(t, roomID, payload.RoomID)
+			assert.Equal(t, adminID, payload.By)
+		case <-time.After(200 * time.Millisecond):
+			t.Fatal("conversation was not told about the recording")
+		}
+	}
+	assert.ElementsMatch(t, []string{EventTypeCallRecordingStarted, EventTypeCallRecordingStopped}, types)
+}
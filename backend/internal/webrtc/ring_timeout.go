@@ -0,0 +1,106 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/database"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// DefaultRingTimeout is how long a P2P call rings with only its initiator
+// before it is marked missed
+const DefaultRingTimeout = 45 * time.Second
+
+func (m *Manager) ringTimeout() time.Duration {
+	if m.config != nil && m.config.RingTimeout > 0 {
+		return m.config.RingTimeout
+	}
+	return DefaultRingTimeout
+}
+
+// StartRinging gives the call in roomID until the ring timeout for someone
+// else to join. If nobody does, missed runs to record the outcome, the
+// conversation is sent call.missed and the initiator is taken out of the
+// room. A second participant joining, or the room emptying, cancels it.
+func (m *Manager) StartRinging(roomID, callID, callerID uuid.UUID, missed func(ctx context.Context)) {
+	m.ringMu.Lock()
+	defer m.ringMu.Unlock()
+
+	if t, ok := m.ringing[roomID]; ok {
+		t.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(m.ringTimeout(), func() {
+		m.ringMu.Lock()
+		// Cancelled or replaced between firing and taking the lock
+		if m.ringing[roomID] != timer {
+			m.ringMu.Unlock()
+			return
+		}
+		delete(m.ringing, roomID)
+		m.ringMu.Unlock()
+
+		m.expireRinging(roomID, callID, callerID, missed)
+	})
+	m.ringing[roomID] = timer
+}
+
+// StopRinging cancels the ring timeout for roomID, if any
+func (m *Manager) StopRinging(roomID uuid.UUID) {
+	m.ringMu.Lock()
+	defer m.ringMu.Unlock()
+
+	if t, ok := m.ringing[roomID]; ok {
+		t.Stop()
+		delete(m.ringing, roomID)
+	}
+}
+
+func (m *Manager) expireRinging(roomID, callID, callerID uuid.UUID, missed func(ctx context.Context)) {
+	room := m.GetRoom(roomID)
+	if room == nil || room.GetCallID() != callID || room.ParticipantCount() > 1 {
+		return
+	}
+
+	m.logger.Info("call unanswered, marking missed", "room_id", roomID, "call_id", callID)
+	ctx := context.Background()
+	if missed != nil {
+		missed(ctx)
+	}
+
+	payloadBytes, _ := json.Marshal(CallMissedPayload{
+		CallID:         callID,
+		ConversationID: roomID,
+		CallerID:       callerID,
+	})
+	msg := &pubsub.Message{
+		Topic:   pubsub.Topics.Room(roomID.String()),
+		Type:    EventTypeCallMissed,
+		Payload: payloadBytes,
+	}
+	if err := m.pubsub.Publish(ctx, msg.Topic, msg); err != nil {
+		m.logger.Error("failed to publish call missed", "error", err, "room_id", roomID)
+	}
+
+	for _, p := range room.GetParticipants() {
+		m.LeaveCall(ctx, roomID, p.UserID, p.Username)
+	}
+}
+
+// recordMissedCall returns a StartRinging callback that marks the call log
+// missed
+func recordMissedCall(callRepo *database.CallRepository, logger *slog.Logger, callID uuid.UUID) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		if callRepo == nil {
+			return
+		}
+		if err := callRepo.UpdateCallStatus(ctx, callID, database.CallStatusMissed); err != nil {
+			logger.Error("failed to mark call missed", "error", err, "call_id", callID)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRingingManager(t *testing.T, timeout time.Duration) (*Manager, pubsub.PubSub) {
+	t.Helper()
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewManager(&Config{RingTimeout: timeout}, ps, logger), ps
+}
+
+func TestManager_UnansweredCallIsMarkedMissed(t *testing.T) {
+	mgr, ps := newRingingManager(t, 20*time.Millisecond)
+	ctx := context.Background()
+	roomID, callID, callerID := uuid.New(), uuid.New(), uuid.New()
+
+	missedEvents := make(chan *pubsub.Message, 1)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.Room(roomID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		missedEvents <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	room, err := mgr.JoinCall(ctx, roomID, callerID, "alice")
+	require.NoError(t, err)
+	room.SetCallID(callID)
+
+	var recorded atomic.Bool
+	mgr.StartRinging(roomID, callID, callerID, func(context.Context) { recorded.Store(true) })
+
+	select {
+	case msg := <-missedEvents:
+		assert.Equal(t, EventTypeCallMissed, msg.Type)
+		var p CallMissedPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, callID, p.CallID)
+		assert.Equal(t, callerID, p.CallerID)
+	case <-time.After(time.Second):
+		t.Fatal("expected call.missed on the conversation's topic")
+	}
+	assert.True(t, recorded.Load())
+	assert.Eventually(t, func() bool { return mgr.GetRoom(roomID) == nil }, time.Second, 5*time.Millisecond,
+		"the initiator is taken out of the room")
+}
+
+func TestManager_AnsweringCancelsRingTimeout(t *testing.T) {
+	mgr, _ := newRingingManager(t, 20*time.Millisecond)
+	ctx := context.Background()
+	roomID, callID, callerID := uuid.New(), uuid.New(), uuid.New()
+
+	room, err := mgr.JoinCall(ctx, roomID, callerID, "alice")
+	require.NoError(t, err)
+	room.SetCallID(callID)
+
+	var recorded atomic.Bool
+	mgr.StartRinging(roomID, callID, callerID, func(context.Context) { recorded.Store(true) })
+	_, err = mgr.JoinCall(ctx, roomID, uuid.New(), "bob")
+	require.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, recorded.Load())
+	require.NotNil(t, mgr.GetRoom(roomID))
+	assert.Equal(t, 2, mgr.GetRoom(roomID).ParticipantCount())
+}
@@ -0,0 +1,94 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+// Track sources reported in TrackInfo
+const (
+	TrackSourceCamera = "camera"
+	TrackSourceScreen = "screen"
+)
+
+// ScreenSharePayload is sent by a participant starting or stopping a screen
+// share, and relayed to the rest of the room with their user ID
+type ScreenSharePayload struct {
+	RoomID   string `json:"room_id"`
+	StreamID string `json:"stream_id"` // MediaStream carrying the screen tracks
+	UserID   string `json:"user_id,omitempty"`
+}
+
+// trackSource tells screen-share tracks from camera/mic ones. A stream the
+// participant announced with call.screenshare_started is a screen share, as
+// is any stream whose ID says so.
+func (p *SFUParticipant) trackSource(streamID string) string {
+	if p.screenStreams[streamID] || strings.Contains(strings.ToLower(streamID), TrackSourceScreen) {
+		return TrackSourceScreen
+	}
+	return TrackSourceCamera
+}
+
+// HandleScreenShare processes call.screenshare_started and
+// call.screenshare_stopped, recording which stream is the screen and telling
+// the other participants
+func (h *SFUHandler) HandleScreenShare(ctx context.Context, sigCtx *SignalingContext, payload json.RawMessage, started bool) error {
+	var p ScreenSharePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return &CallError{Code: "invalid_payload", Message: "Invalid screen share payload"}
+	}
+
+	roomID, err := uuid.Parse(p.RoomID)
+	if err != nil {
+		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
+	}
+
+	room := h.sfu.GetRoom(roomID)
+	if room == nil {
+		return &CallError{Code: "room_not_found", Message: "Room not found"}
+	}
+
+	participant := room.GetParticipant(sigCtx.UserID)
+	if participant == nil {
+		return &CallError{Code: "not_in_call", Message: "Not in this call"}
+	}
+
+	participant.mu.Lock()
+	if started && p.StreamID != "" {
+		participant.screenStreams[p.StreamID] = true
+	} else if !started {
+		delete(participant.screenStreams, p.StreamID)
+	}
+	participant.mu.Unlock()
+
+	eventType := EventTypeCallScreenShareStopped
+	if started {
+		eventType = EventTypeCallScreenShareStarted
+	}
+	payloadBytes, _ := json.Marshal(ScreenSharePayload{
+		RoomID:   roomID.String(),
+		StreamID: p.StreamID,
+		UserID:   sigCtx.UserID.String(),
+	})
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+
+	for _, other := range room.participants {
+		if other.UserID == sigCtx.UserID {
+			continue
+		}
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.User(other.UserID.String()),
+			Type:    eventType,
+			Payload: payloadBytes,
+		}
+		_ = h.pubsub.Publish(ctx, msg.Topic, msg)
+	}
+
+	return nil
+}
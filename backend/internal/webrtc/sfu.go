@@ -8,11 +8,13 @@ import (
 	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/observer/teatime/internal/pubsub"
 	"github.com/pion/rtcp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 )
 
@@ -26,7 +28,37 @@ type SFU struct {
 }
 
 type SFUConfig struct {
-	ICEServers []webrtc.ICEServer
+	ICEServers      []webrtc.ICEServer
+	ICEServersFor   func(userID uuid.UUID) []webrtc.ICEServer // if set, replaces ICEServers so each join gets fresh TURN credentials
+	MaxParticipants int                                       // per room, DefaultMaxSFUParticipants if unset
+
+	// Read audio levels from participants' RTP to announce the active speaker
+	EnableAudioLevels bool
+
+	// Where call recordings are written; recording is disabled if empty
+	RecordingDir string
+
+	// Accept simulcast video and forward each subscriber the layer their
+	// bandwidth allows; off, every track is forwarded as a single layer
+	EnableSimulcast bool
+
+	// Forward only this many participants' video per room, the most recent
+	// dominant speakers, pausing the rest; 0 forwards everyone's. Needs
+	// EnableAudioLevels to follow who is talking.
+	MaxForwardedVideo int
+}
+
+// DefaultMaxSFUParticipants caps a room so its forwarding loops stay bounded
+const DefaultMaxSFUParticipants = 12
+
+// ErrRoomFull is returned by JoinRoom when the room is at capacity
+var ErrRoomFull = errors.New("call is full")
+
+func (c *SFUConfig) maxParticipants() int {
+	if c != nil && c.MaxParticipants > 0 {
+		return c.MaxParticipants
+	}
+	return DefaultMaxSFUParticipants
 }
 
 type SFURoom struct {
@@ -34,7 +66,15 @@ type SFURoom struct {
 	ID           uuid.UUID
 	participants map[uuid.UUID]*SFUParticipant
 	callID       uuid.UUID
+	speakers     *speakerDetector
 	logger       *slog.Logger
+	sfu          *SFU
+
+	// Set while the room is being recorded
+	recorder atomic.Pointer[roomRecorder]
+
+	// Whose video is forwarded when MaxForwardedVideo caps it
+	video videoGate
 }
 
 type SFUParticipant struct {
@@ -67,11 +107,33 @@ type SFUParticipant struct {
 
 	// Track subscriptions (Receiver side) - to clean up on leave
 	subscriptions map[string]uuid.UUID // trackID -> senderID
+
+	// Stream IDs announced as screen shares
+	screenStreams map[string]bool
+
+	// Receiver reports for the tracks forwarded to this participant
+	quality callQuality
+
+	// Simulcast video this participant publishes, by track ID (under mu),
+	// and the layer each subscriber gets (under subscribersMu)
+	simulcast map[string]*simulcastTrack
+	layerSubs map[*webrtc.TrackLocalStaticRTP]*layerSubscription
+
+	// Set while the room's video cap keeps this participant's video back
+	videoPaused atomic.Bool
+
+	// Set while the participant has their hand up
+	handRaised atomic.Bool
+
+	// Last mute state the participant reported, for late joiners
+	audioMuted atomic.Bool
+	videoMuted atomic.Bool
 }
 
 type TrackInfo struct {
 	ID       string `json:"id"`
 	Kind     string `json:"kind"`
+	Source   string `json:"source"` // TrackSourceCamera or TrackSourceScreen
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 }
@@ -110,7 +172,9 @@ func (s *SFU) GetOrCreateRoom(roomID uuid.UUID) *SFURoom {
 	room := &SFURoom{
 		ID:           roomID,
 		participants: make(map[uuid.UUID]*SFUParticipant),
+		speakers:     newSpeakerDetector(),
 		logger:       s.logger.With("room_id", roomID),
+		sfu:          s,
 	}
 	s.rooms[roomID] = room
 	return room
@@ -124,8 +188,13 @@ func (s *SFU) GetRoom(roomID uuid.UUID) *SFURoom {
 
 func (s *SFU) DeleteRoom(roomID uuid.UUID) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	room := s.rooms[roomID]
 	delete(s.rooms, roomID)
+	s.mu.Unlock()
+
+	if room != nil {
+		s.finishRecording(room)
+	}
 }
 
 // requestKeyframe relays a PLI to the original sender of a track
@@ -177,6 +246,7 @@ func (r *SFURoom) GetTracks() []TrackInfo {
 			tracks = append(tracks, TrackInfo{
 				ID:       track.ID(), // The REAL WebRTC Track ID
 				Kind:     track.Kind().String(),
+				Source:   p.trackSource(track.StreamID()),
 				UserID:   p.UserID.String(),
 				Username: p.Username,
 			})
@@ -189,14 +259,29 @@ func (r *SFURoom) GetTracks() []TrackInfo {
 // JoinRoom adds a participant
 func (s *SFU) JoinRoom(ctx context.Context, roomID, userID uuid.UUID, username string) (*SFUParticipant, error) {
 	room := s.GetOrCreateRoom(roomID)
+	maxParticipants := s.config.maxParticipants()
+
+	// Checked again when adding; this avoids building a peer connection for nothing
+	if room.full(userID, maxParticipants) {
+		return nil, ErrRoomFull
+	}
 
 	// Create a dedicated context for this participant that survives the request
 	pCtx, pCancel := context.WithCancel(context.Background())
 
 	// Codec Enforcement (VP8/Opus)
 	m := &webrtc.MediaEngine{}
+	var videoFeedback []webrtc.RTCPFeedback
+	if s.config.EnableSimulcast {
+		// Subscribers' bandwidth estimates drive layer selection
+		videoFeedback = []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBGoogREMB}}
+		if err := registerSimulcastExtensions(m); err != nil {
+			pCancel()
+			return nil, err
+		}
+	}
 	if err := m.RegisterCodec(webrtc.RTPCodecParameters{
-		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, Channels: 0, SDPFmtpLine: "", RTCPFeedback: nil},
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, Channels: 0, SDPFmtpLine: "", RTCPFeedback: videoFeedback},
 		PayloadType:        96,
 	}, webrtc.RTPCodecTypeVideo); err != nil {
 		pCancel()
@@ -209,10 +294,19 @@ func (s *SFU) JoinRoom(ctx context.Context, roomID, userID uuid.UUID, username s
 		pCancel()
 		return nil, err
 	}
+	if s.config.EnableAudioLevels {
+		if err := m.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: sdp.AudioLevelURI}, webrtc.RTPCodecTypeAudio); err != nil {
+			pCancel()
+			return nil, err
+		}
+	}
 
 	api := webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(webrtc.SettingEngine{}))
 
 	config := webrtc.Configuration{ICEServers: s.config.ICEServers}
+	if s.config.ICEServersFor != nil {
+		config.ICEServers = s.config.ICEServersFor(userID)
+	}
 	pc, err := api.NewPeerConnection(config)
 	if err != nil {
 		pCancel()
@@ -240,6 +334,7 @@ func (s *SFU) JoinRoom(ctx context.Context, roomID, userID uuid.UUID, username s
 		remoteTracks:  make(map[string]*webrtc.TrackRemote),
 		subscribers:   make(map[string][]*webrtc.TrackLocalStaticRTP),
 		subscriptions: make(map[string]uuid.UUID),
+		screenStreams: make(map[string]bool),
 		room:          room,
 		sfu:           s,
 		logger:        room.logger.With("user_id", userID),
@@ -268,7 +363,18 @@ func (s *SFU) JoinRoom(ctx context.Context, roomID, userID uuid.UUID, username s
 		}
 	})
 
-	room.AddParticipant(participant)
+	if !room.addParticipantWithin(participant, maxParticipants) {
+		if closeErr := pc.Close(); closeErr != nil {
+			s.logger.Error("failed to close peer connection for full room", "error", closeErr)
+		}
+		pCancel()
+		return nil, ErrRoomFull
+	}
+	room.video.join(userID, s.config.MaxForwardedVideo, s.videoChanged(ctx, room))
+	if room.video.isPaused(userID) {
+		// Rejoined while held back; the gate saw no change to report
+		participant.videoPaused.Store(true)
+	}
 
 	// Subscribe to existing tracks
 	room.mu.RLock()
@@ -290,10 +396,38 @@ func (s *SFU) JoinRoom(ctx context.Context, roomID, userID uuid.UUID, username s
 }
 
 func (p *SFUParticipant) handleIncomingTrack(ctx context.Context, remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+	// Each simulcast layer arrives as its own track with the same ID. Only
+	// the first is announced and subscribed to; the rest are just forwarded.
+	var layer *simulcastLayer
+	if remoteTrack.RID() != "" && p.sfu.config.EnableSimulcast {
+		p.mu.Lock()
+		st, known := p.simulcast[remoteTrack.ID()]
+		if !known {
+			if p.simulcast == nil {
+				p.simulcast = make(map[string]*simulcastTrack)
+			}
+			st = &simulcastTrack{}
+			p.simulcast[remoteTrack.ID()] = st
+		}
+		layer = st.addLayer(remoteTrack)
+		p.mu.Unlock()
+
+		if known {
+			go p.forwardTrack(ctx, remoteTrack, 0, layer)
+			return
+		}
+	}
+
 	p.mu.Lock()
 	p.remoteTracks[remoteTrack.ID()] = remoteTrack
 	p.mu.Unlock()
 
+	if rec := p.room.recorder.Load(); rec != nil {
+		if _, err := rec.attach(p.UserID, remoteTrack); err != nil {
+			p.logger.Error("failed to start track recording", "error", err)
+		}
+	}
+
 	// Forward to others
 	p.room.mu.RLock()
 	for _, other := range p.room.participants {
@@ -305,7 +439,7 @@ func (p *SFUParticipant) handleIncomingTrack(ctx context.Context, remoteTrack *w
 	}
 	p.room.mu.RUnlock()
 
-	go p.forwardTrack(ctx, remoteTrack)
+	go p.forwardTrack(ctx, remoteTrack, p.audioLevelExtension(remoteTrack, receiver), layer)
 }
 
 // AddSubscriber adds a subscriber for a specific track
@@ -320,6 +454,7 @@ func (p *SFUParticipant) RemoveSubscriber(trackID string, sub *webrtc.TrackLocal
 	p.subscribersMu.Lock()
 	defer p.subscribersMu.Unlock()
 
+	delete(p.layerSubs, sub)
 	subs := p.subscribers[trackID]
 	for i, s := range subs {
 		if s == sub {
@@ -350,9 +485,31 @@ func (p *SFUParticipant) subscribeToTrack(ctx context.Context, senderID uuid.UUI
 	// Capture senderID for the goroutine closure
 	upstreamSenderID := senderID
 	upstreamTrackID := remoteTrack.ID()
+	clockRate := remoteTrack.Codec().ClockRate
+
+	p.room.mu.RLock()
+	sfuSender := p.room.participants[senderID]
+	p.room.mu.RUnlock()
 
-	// Read RTCP from receiver (needed for PLI)
+	// Simulcast tracks get a layer picked for this subscriber
+	var layers *layerSubscription
+	if sfuSender != nil {
+		layers = sfuSender.subscribeLayers(upstreamTrackID, localTrack)
+	}
+	switchLayer := func(rid string, changed bool) {
+		if changed {
+			p.sfu.requestLayerKeyframe(upstreamSenderID, upstreamTrackID, rid, p.room.ID)
+		}
+	}
+
+	// Read RTCP from receiver (needed for PLI, and receiver reports for call stats)
 	go func() {
+		var ssrc uint32
+		if params := sender.GetParameters(); len(params.Encodings) > 0 {
+			ssrc = uint32(params.Encodings[0].SSRC)
+		}
+		defer p.quality.forget(ssrc)
+
 		rtcpBuf := make([]byte, 1500)
 		for {
 			n, _, rtcpErr := sender.Read(rtcpBuf)
@@ -366,10 +523,26 @@ func (p *SFUParticipant) subscribeToTrack(ctx context.Context, senderID uuid.UUI
 			}
 
 			for _, pkt := range pkts {
-				switch pkt.(type) {
+				switch pkt := pkt.(type) {
 				case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
 					// Relay keyframe request to the specific sender
-					p.sfu.requestKeyframe(upstreamSenderID, upstreamTrackID, p.room.ID)
+					if layers != nil {
+						p.sfu.requestLayerKeyframe(upstreamSenderID, upstreamTrackID, layers.forwarded(), p.room.ID)
+					} else {
+						p.sfu.requestKeyframe(upstreamSenderID, upstreamTrackID, p.room.ID)
+					}
+				case *rtcp.ReceiverReport:
+					now := time.Now()
+					p.quality.record(pkt, clockRate, now)
+					if layers != nil {
+						for _, report := range pkt.Reports {
+							switchLayer(layers.onLoss(report.FractionLost, now))
+						}
+					}
+				case *rtcp.ReceiverEstimatedMaximumBitrate:
+					if layers != nil {
+						switchLayer(layers.onEstimate(float64(pkt.Bitrate), time.Now()))
+					}
 				}
 			}
 		}
@@ -384,16 +557,16 @@ func (p *SFUParticipant) subscribeToTrack(ctx context.Context, senderID uuid.UUI
 	p.mu.Unlock()
 
 	// Register with sender (sender uses bare trackID since its map is per-participant)
-	p.room.mu.RLock()
-	sfuSender := p.room.participants[senderID]
-	p.room.mu.RUnlock()
-
 	if sfuSender != nil {
 		sfuSender.AddSubscriber(remoteTrack.ID(), localTrack)
 	}
 
 	// Request Keyframe (PLI) immediately so new subscriber gets image
-	p.sendPLI(senderID, remoteTrack)
+	if layers != nil {
+		p.sfu.requestLayerKeyframe(senderID, upstreamTrackID, layers.forwarded(), p.room.ID)
+	} else {
+		p.sendPLI(senderID, remoteTrack)
+	}
 
 	if negotiate {
 		p.processNegotiation(ctx)
@@ -469,7 +642,11 @@ func (p *SFUParticipant) sendPLI(senderID uuid.UUID, track *webrtc.TrackRemote)
 	}
 }
 
-func (p *SFUParticipant) forwardTrack(ctx context.Context, remoteTrack *webrtc.TrackRemote) {
+func (p *SFUParticipant) forwardTrack(ctx context.Context, remoteTrack *webrtc.TrackRemote, audioLevelExt uint8, layer *simulcastLayer) {
+	// The primary layer stands in for a simulcast track: it alone is
+	// recorded, and sent to anyone without a layer picked
+	primary := layer == nil || layer.track.RID() == p.simulcastPrimary(remoteTrack.ID())
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -481,21 +658,51 @@ func (p *SFUParticipant) forwardTrack(ctx context.Context, remoteTrack *webrtc.T
 		if err != nil {
 			return
 		}
+		if audioLevelExt != 0 {
+			p.observeAudioLevel(ctx, rtp, audioLevelExt)
+		}
+		if rec := p.room.recorder.Load(); rec != nil && primary {
+			rec.write(p.UserID, remoteTrack.ID(), rtp)
+		}
+		if layer != nil {
+			layer.observe(rtp.MarshalSize(), time.Now())
+		}
+
+		// Video held back by the room's cap goes nowhere; audio always flows
+		if p.videoPaused.Load() && remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+			continue
+		}
 
 		// Optimized: Use internal subscribers map, no room lock needed
 		p.subscribersMu.RLock()
 		// Copy subscribers to avoid holding lock during write
 		targets := make([]*webrtc.TrackLocalStaticRTP, len(p.subscribers[remoteTrack.ID()]))
 		copy(targets, p.subscribers[remoteTrack.ID()])
+		var layerSubs []*layerSubscription
+		if layer != nil {
+			layerSubs = make([]*layerSubscription, len(targets))
+			for i, target := range targets {
+				layerSubs[i] = p.layerSubs[target]
+			}
+		}
 		p.subscribersMu.RUnlock()
 
 		// Write to targets
-		for _, target := range targets {
+		for i, target := range targets {
 			// FIX 4: Deep Copy the packet so SSRC rewriting doesn't race
 			packetCopy := *rtp
 			packetCopy.Header = rtp.Header   // Shallow copy header struct
 			packetCopy.Payload = rtp.Payload // Payload slice matches (safe to read shared)
 
+			// Subscribers to a simulcast track only get their layer
+			if layer != nil {
+				if sub := layerSubs[i]; sub == nil && !primary {
+					continue
+				} else if sub != nil && !sub.rewrite(layer.track.RID(), rtp, &packetCopy.Header) {
+					continue
+				}
+			}
+
 			// WriteRTP will modify the Header.SSRC of packetCopy, not the original rtp
 			if err := target.WriteRTP(&packetCopy); err != nil {
 				if errors.Is(err, io.ErrClosedPipe) {
@@ -676,6 +883,26 @@ func (r *SFURoom) AddParticipant(p *SFUParticipant) {
 	r.participants[p.UserID] = p
 }
 
+// full reports whether the room has no space for userID. Someone already in
+// the room, e.g. rejoining, always fits.
+func (r *SFURoom) full(userID uuid.UUID, max int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, in := r.participants[userID]
+	return !in && len(r.participants) >= max
+}
+
+// addParticipantWithin adds p unless the room is already full
+func (r *SFURoom) addParticipantWithin(p *SFUParticipant, max int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, in := r.participants[p.UserID]; !in && len(r.participants) >= max {
+		return false
+	}
+	r.participants[p.UserID] = p
+	return true
+}
+
 func (r *SFURoom) RemoveParticipant(u uuid.UUID) {
 	r.mu.Lock()
 	p, ok := r.participants[u]
@@ -683,6 +910,12 @@ func (r *SFURoom) RemoveParticipant(u uuid.UUID) {
 		delete(r.participants, u)
 	}
 	r.mu.Unlock()
+	if r.speakers != nil {
+		r.speakers.forget(u)
+	}
+	if ok && r.sfu != nil {
+		r.video.leave(u, r.sfu.config.MaxForwardedVideo, r.sfu.videoChanged(context.Background(), r))
+	}
 
 	if ok && p != nil {
 		if err := p.Close(); err != nil {
@@ -720,7 +953,23 @@ func (r *SFURoom) GetParticipantList() []Participant {
 	defer r.mu.RUnlock()
 	var list []Participant
 	for _, p := range r.participants {
-		list = append(list, Participant{UserID: p.UserID, Username: p.Username})
+		list = append(list, Participant{
+			UserID:     p.UserID,
+			Username:   p.Username,
+			HandRaised: p.handRaised.Load(),
+			AudioMuted: p.audioMuted.Load(),
+			VideoMuted: p.videoMuted.Load(),
+		})
 	}
 	return list
 }
+
+// setMuted records a mute update; kinds other than audio and video are ignored
+func (p *SFUParticipant) setMuted(kind string, muted bool) {
+	switch kind {
+	case "audio":
+		p.audioMuted.Store(muted)
+	case "video":
+		p.videoMuted.Store(muted)
+	}
+}
@@ -5,6 +5,7 @@ package webrtc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 
 	"github.com/google/uuid"
@@ -17,12 +18,16 @@ import (
 
 // SFUHandler processes signaling messages for group calls
 type SFUHandler struct {
-	sfu      *SFU
-	p2pMgr   *Manager // P2P manager for 1:1 calls
-	convRepo *database.ConversationRepository
-	callRepo *database.CallRepository
-	pubsub   pubsub.PubSub
-	logger   *slog.Logger
+	sfu        *SFU
+	p2pMgr     *Manager // P2P manager for 1:1 calls
+	convRepo   *database.ConversationRepository
+	callRepo   *database.CallRepository
+	pubsub     pubsub.PubSub
+	autoAccept AutoAcceptLookup
+	logger     *slog.Logger
+
+	// Where callees might already be on a call, for call waiting
+	callLocators []CallLocator
 }
 
 // NewSFUHandler creates a new SFU handler
@@ -71,6 +76,8 @@ type SFUConfigPayload struct {
 	Mode         string        `json:"mode"` // "sfu" or "p2p"
 	SDP          string        `json:"sdp,omitempty"`
 	IsInitiator  bool          `json:"is_initiator"`
+	Recording    bool          `json:"recording,omitempty"`    // the call is being recorded
+	PausedVideo  []uuid.UUID   `json:"paused_video,omitempty"` // participants whose video isn't forwarded
 }
 
 // SFUTracksPayload contains track information
@@ -97,6 +104,9 @@ func (h *SFUHandler) HandleGroupJoin(ctx context.Context, sigCtx *SignalingConte
 	if err != nil || !isMember {
 		return nil, &CallError{Code: "not_member", Message: "Not a member of this conversation"}
 	}
+	if banned, err := h.convRepo.IsBanned(ctx, roomID, sigCtx.UserID); err != nil || banned {
+		return nil, &CallError{Code: "banned", Message: "Banned from this conversation"}
+	}
 
 	// Get conversation to check if it's a group
 	conv, err := h.convRepo.GetByID(ctx, roomID)
@@ -165,6 +175,9 @@ func (h *SFUHandler) joinSFU(ctx context.Context, sigCtx *SignalingContext, room
 		"username", sigCtx.Username)
 
 	participant, err := h.sfu.JoinRoom(ctx, roomID, sigCtx.UserID, sigCtx.Username)
+	if errors.Is(err, ErrRoomFull) {
+		return nil, &CallError{Code: "room_full", Message: "This call is full"}
+	}
 	if err != nil {
 		return nil, &CallError{Code: "join_failed", Message: err.Error()}
 	}
@@ -257,7 +270,7 @@ func (h *SFUHandler) joinSFU(ctx context.Context, sigCtx *SignalingContext, room
 	h.sendTrackInfo(ctx, sigCtx.UserID, room)
 
 	// Return SFU config
-	iceServers := h.p2pMgr.GetConfig().GetICEServers()
+	iceServers := h.p2pMgr.ICEServers(ctx, sigCtx.UserID, roomID)
 	return &SFUConfigPayload{
 		RoomID:       roomID,
 		ICEServers:   iceServers,
@@ -265,6 +278,8 @@ func (h *SFUHandler) joinSFU(ctx context.Context, sigCtx *SignalingContext, room
 		Mode:         "sfu",
 		SDP:          offerSDP,
 		IsInitiator:  isInitiator, // Set based on whether they created the call
+		Recording:    room.Recording(),
+		PausedVideo:  room.PausedVideo(),
 	}, nil
 }
 
@@ -302,6 +317,7 @@ func (h *SFUHandler) joinP2P(ctx context.Context, sigCtx *SignalingContext, room
 		} else {
 			_ = h.callRepo.AddParticipant(ctx, callLog.ID, sigCtx.UserID)
 			room.SetCallID(callLog.ID)
+			h.p2pMgr.StartRinging(roomID, callLog.ID, sigCtx.UserID, recordMissedCall(h.callRepo, h.logger, callLog.ID))
 
 			// Broadcast incoming call to other members
 			h.broadcastIncomingCall(ctx, roomID, callLog.ID, sigCtx, ct)
@@ -313,7 +329,7 @@ func (h *SFUHandler) joinP2P(ctx context.Context, sigCtx *SignalingContext, room
 		}
 	}
 
-	iceServers := h.p2pMgr.GetConfig().GetICEServers()
+	iceServers := h.p2pMgr.ICEServers(ctx, sigCtx.UserID, roomID)
 	return &SFUConfigPayload{
 		RoomID:       roomID,
 		ICEServers:   iceServers,
@@ -427,6 +443,12 @@ func (h *SFUHandler) HandleSFULeave(ctx context.Context, sigCtx *SignalingContex
 		return &CallError{Code: "invalid_room", Message: "Invalid room ID"}
 	}
 
+	h.leaveSFURoom(ctx, roomID, sigCtx)
+	return nil
+}
+
+// leaveSFURoom removes a participant from an SFU room, ending the call if it empties
+func (h *SFUHandler) leaveSFURoom(ctx context.Context, roomID uuid.UUID, sigCtx *SignalingContext) {
 	room := h.sfu.GetRoom(roomID)
 	if room != nil {
 		// Capture call ID before removing participant
@@ -452,7 +474,6 @@ func (h *SFUHandler) HandleSFULeave(ctx context.Context, sigCtx *SignalingContex
 	}
 
 	h.logger.Info("user left SFU room", "room_id", roomID, "user_id", sigCtx.UserID)
-	return nil
 }
 
 func (h *SFUHandler) sendAnswerToParticipant(ctx context.Context, userID, roomID uuid.UUID, sdp string) {
@@ -565,6 +586,10 @@ func (h *SFUHandler) HandleSFUMuteUpdate(ctx context.Context, sigCtx *SignalingC
 		return &CallError{Code: "room_not_found", Message: "Room not found"}
 	}
 
+	if participant := room.GetParticipant(sigCtx.UserID); participant != nil {
+		participant.setMuted(p.Kind, p.Muted)
+	}
+
 	// Relay mute update to other participants in the SFU room
 	relayPayload := map[string]interface{}{
 		"room_id": roomID.String(),
@@ -630,11 +655,18 @@ func (h *SFUHandler) broadcastIncomingCall(ctx context.Context, conversationID,
 		return
 	}
 
+	var calleeIDs []uuid.UUID
 	for _, member := range members.Members {
 		// Don't send to caller
 		if member.UserID == caller.UserID {
 			continue
 		}
+		// Don't ring over a call the member is already on
+		if activeRoomID, busy := busyElsewhere(h.callLocators, member.UserID, conversationID); busy {
+			sendCallWaiting(ctx, h.pubsub, h.logger, incomingPayload, member.UserID, activeRoomID)
+			continue
+		}
+		calleeIDs = append(calleeIDs, member.UserID)
 
 		msg := &pubsub.Message{
 			Topic:   pubsub.Topics.User(member.UserID.String()),
@@ -646,4 +678,6 @@ func (h *SFUHandler) broadcastIncomingCall(ctx context.Context, conversationID,
 			h.logger.Error("failed to publish incoming call event", "error", err, "target_user", member.UserID)
 		}
 	}
+
+	sendAutoAcceptHints(ctx, h.pubsub, h.autoAccept, h.logger, incomingPayload, calleeIDs)
 }
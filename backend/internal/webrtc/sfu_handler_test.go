@@ -53,6 +53,7 @@ func addSFURoomParticipant(t *testing.T, sfu *SFU, roomID, userID uuid.UUID, use
 		remoteTracks:  make(map[string]*webrtc.TrackRemote),
 		subscribers:   make(map[string][]*webrtc.TrackLocalStaticRTP),
 		subscriptions: make(map[string]uuid.UUID),
+		screenStreams: make(map[string]bool),
 		room:          room,
 		sfu:           sfu,
 		logger:        slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
@@ -891,3 +892,232 @@ func TestSFUHandler_HandleSFUMuteUpdate_AloneInRoom(t *testing.T) {
 		// Correct — no mute update should be published when user is alone
 	}
 }
+
+func TestSFUHandler_JoinRejectedWhenRoomFull(t *testing.T) {
+	handler, sfu, _, _ := newTestSFUHandler(t)
+	sfu.config.MaxParticipants = 2
+	roomID, aliceID, bobID := uuid.New(), uuid.New(), uuid.New()
+
+	room := addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+	alice, bob := room.GetParticipant(aliceID), room.GetParticipant(bobID)
+
+	_, err := handler.joinSFU(context.Background(), &SignalingContext{UserID: uuid.New(), Username: "carol"}, roomID, "video")
+	require.Error(t, err)
+	callErr, ok := err.(*CallError)
+	require.True(t, ok, "expected *CallError, got %T", err)
+	assert.Equal(t, "room_full", callErr.Code)
+
+	assert.Equal(t, 2, room.ParticipantCount())
+	assert.Same(t, alice, room.GetParticipant(aliceID))
+	assert.Same(t, bob, room.GetParticipant(bobID))
+}
+
+func TestSFUHandler_HandleScreenShare_RelaysAndTagsStream(t *testing.T) {
+	handler, sfu, _, ps := newTestSFUHandler(t)
+	ctx := context.Background()
+	roomID, aliceID, bobID := uuid.New(), uuid.New(), uuid.New()
+
+	room := addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+
+	relayed := make(chan *pubsub.Message, 2)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.User(bobID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		relayed <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	alice := &SignalingContext{UserID: aliceID, Username: "alice"}
+	payload, _ := json.Marshal(ScreenSharePayload{RoomID: roomID.String(), StreamID: "{a1b2}"})
+	require.NoError(t, handler.HandleScreenShare(ctx, alice, payload, true))
+
+	select {
+	case msg := <-relayed:
+		assert.Equal(t, EventTypeCallScreenShareStarted, msg.Type)
+		var p ScreenSharePayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, aliceID.String(), p.UserID)
+		assert.Equal(t, "{a1b2}", p.StreamID)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected call.screenshare_started for bob")
+	}
+
+	p := room.GetParticipant(aliceID)
+	assert.Equal(t, TrackSourceScreen, p.trackSource("{a1b2}"))
+	assert.Equal(t, TrackSourceCamera, p.trackSource("{c3d4}"))
+	assert.Equal(t, TrackSourceScreen, p.trackSource("Screen-capture"), "stream IDs naming the screen need no announcement")
+
+	require.NoError(t, handler.HandleScreenShare(ctx, alice, payload, false))
+	assert.Equal(t, TrackSourceCamera, p.trackSource("{a1b2}"))
+
+	// Only participants can share
+	err = handler.HandleScreenShare(ctx, &SignalingContext{UserID: uuid.New()}, payload, true)
+	callErr, ok := err.(*CallError)
+	require.True(t, ok, "expected *CallError, got %T", err)
+	assert.Equal(t, "not_in_call", callErr.Code)
+}
+
+func TestSFUHandler_HandleHand_RelaysAndListsRaisedHands(t *testing.T) {
+	handler, sfu, _, ps := newTestSFUHandler(t)
+	ctx := context.Background()
+	roomID, aliceID, bobID := uuid.New(), uuid.New(), uuid.New()
+
+	room := addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+
+	relayed := make(chan *pubsub.Message, 4)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.User(bobID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		relayed <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	alice := &SignalingContext{UserID: aliceID, Username: "alice"}
+	payload, _ := json.Marshal(HandPayload{RoomID: roomID.String()})
+	require.NoError(t, handler.HandleHand(ctx, alice, payload, true))
+
+	select {
+	case msg := <-relayed:
+		assert.Equal(t, EventTypeCallRaiseHand, msg.Type)
+		var p HandPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, aliceID.String(), p.UserID)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected call.raise_hand for bob")
+	}
+
+	raised := map[uuid.UUID]bool{}
+	for _, p := range room.GetParticipantList() {
+		raised[p.UserID] = p.HandRaised
+	}
+	assert.Equal(t, map[uuid.UUID]bool{aliceID: true, bobID: false}, raised)
+
+	// Raising it again changes nothing
+	require.NoError(t, handler.HandleHand(ctx, alice, payload, true))
+	require.NoError(t, handler.HandleHand(ctx, alice, payload, false))
+	select {
+	case msg := <-relayed:
+		assert.Equal(t, EventTypeCallLowerHand, msg.Type)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected call.lower_hand for bob")
+	}
+	assert.False(t, room.GetParticipant(aliceID).handRaised.Load())
+
+	err = handler.HandleHand(ctx, &SignalingContext{UserID: uuid.New()}, payload, true)
+	callErr, ok := err.(*CallError)
+	require.True(t, ok, "expected *CallError, got %T", err)
+	assert.Equal(t, "not_in_call", callErr.Code)
+}
+
+func TestSFUHandler_HandleForceMute_RejectsBeforeRoleCheck(t *testing.T) {
+	handler, sfu, _, _ := newTestSFUHandler(t)
+	ctx := context.Background()
+	roomID, aliceID, bobID := uuid.New(), uuid.New(), uuid.New()
+
+	addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+
+	alice := &SignalingContext{UserID: aliceID, Username: "alice"}
+	cases := map[string]struct {
+		sigCtx  *SignalingContext
+		payload ForceMutePayload
+		code    string
+	}{
+		"self":             {alice, ForceMutePayload{RoomID: roomID.String(), TargetID: aliceID.String()}, "invalid_target"},
+		"no room":          {alice, ForceMutePayload{RoomID: uuid.NewString(), TargetID: bobID.String()}, "room_not_found"},
+		"sender outside":   {&SignalingContext{UserID: uuid.New()}, ForceMutePayload{RoomID: roomID.String(), TargetID: bobID.String()}, "not_in_call"},
+		"target outside":   {alice, ForceMutePayload{RoomID: roomID.String(), TargetID: uuid.NewString()}, "target_not_in_room"},
+		"malformed room":   {alice, ForceMutePayload{RoomID: "nope", TargetID: bobID.String()}, "invalid_room"},
+		"malformed target": {alice, ForceMutePayload{RoomID: roomID.String(), TargetID: "nope"}, "invalid_target"},
+	}
+	for name, tc := range cases {
+		payload, _ := json.Marshal(tc.payload)
+		err := handler.HandleForceMute(ctx, tc.sigCtx, payload)
+		callErr, ok := err.(*CallError)
+		require.True(t, ok, "%s: expected *CallError, got %T", name, err)
+		assert.Equal(t, tc.code, callErr.Code, name)
+	}
+}
+
+func TestSFUHandler_ForceMute_NotifiesTargetAndRoom(t *testing.T) {
+	handler, sfu, _, ps := newTestSFUHandler(t)
+	ctx := context.Background()
+	roomID, aliceID, bobID, carolID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	room := addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+	addSFURoomParticipant(t, sfu, roomID, carolID, "carol")
+
+	received := map[uuid.UUID]chan *pubsub.Message{}
+	for _, id := range []uuid.UUID{aliceID, bobID, carolID} {
+		ch := make(chan *pubsub.Message, 1)
+		received[id] = ch
+		sub, err := ps.Subscribe(ctx, pubsub.Topics.User(id.String()), func(ctx context.Context, msg *pubsub.Message) {
+			ch <- msg
+		})
+		require.NoError(t, err)
+		defer func() { _ = sub.Unsubscribe() }()
+	}
+
+	handler.forceMute(ctx, room, bobID, aliceID)
+
+	next := func(id uuid.UUID) *pubsub.Message {
+		select {
+		case msg := <-received[id]:
+			return msg
+		case <-time.After(200 * time.Millisecond):
+			t.Fatalf("nothing published to %s", id)
+			return nil
+		}
+	}
+
+	msg := next(bobID)
+	assert.Equal(t, EventTypeCallMutedByAdmin, msg.Type)
+	var muted MutedByAdminPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &muted))
+	assert.Equal(t, aliceID.String(), muted.By)
+
+	for _, id := range []uuid.UUID{aliceID, carolID} {
+		msg := next(id)
+		assert.Equal(t, EventTypeCallMuteUpdate, msg.Type)
+		var update map[string]interface{}
+		require.NoError(t, json.Unmarshal(msg.Payload, &update))
+		assert.Equal(t, bobID.String(), update["user_id"])
+		assert.Equal(t, "audio", update["kind"])
+		assert.Equal(t, true, update["muted"])
+	}
+	assert.True(t, room.GetParticipant(bobID).audioMuted.Load())
+}
+
+func TestSFUHandler_LateJoinerSeesMuteState(t *testing.T) {
+	handler, sfu, _, _ := newTestSFUHandler(t)
+	ctx := context.Background()
+	roomID, aliceID, bobID, carolID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	room := addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+
+	mute := func(userID uuid.UUID, kind string, muted bool) {
+		payload, _ := json.Marshal(map[string]interface{}{"room_id": roomID.String(), "kind": kind, "muted": muted})
+		require.NoError(t, handler.HandleSFUMuteUpdate(ctx, &SignalingContext{UserID: userID}, payload))
+	}
+	mute(aliceID, "audio", true)
+	mute(bobID, "video", true)
+	mute(bobID, "audio", true)
+	mute(bobID, "audio", false)
+
+	addSFURoomParticipant(t, sfu, roomID, carolID, "carol")
+
+	byID := map[uuid.UUID]Participant{}
+	for _, p := range room.GetParticipantList() {
+		byID[p.UserID] = p
+	}
+	require.Len(t, byID, 3)
+	assert.True(t, byID[aliceID].AudioMuted)
+	assert.False(t, byID[aliceID].VideoMuted)
+	assert.False(t, byID[bobID].AudioMuted)
+	assert.True(t, byID[bobID].VideoMuted)
+	assert.False(t, byID[carolID].AudioMuted)
+	assert.False(t, byID[carolID].VideoMuted)
+}
@@ -0,0 +1,305 @@
+package webrtc
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// simulcastLayerStale is how long a layer can go without packets before
+	// it's no longer picked; senders pause layers when their uplink suffers
+	simulcastLayerStale = 2 * time.Second
+
+	// simulcastRateWindow is how often each layer's bitrate is measured
+	simulcastRateWindow = time.Second
+
+	// switchTimestampGap separates the last frame of the old layer from the
+	// first of the new one: one frame at 30fps on the 90kHz video clock
+	switchTimestampGap = 3000
+
+	// Loss thresholds, as fractions of 256 like RTCP reports them
+	lossStepDown = 26 // ~10%: fall back below the current layer
+	lossStepUp   = 5  // ~2%: let the estimate grow again
+
+	// headroom keeps the chosen layer a little under the estimate
+	headroom = 0.9
+)
+
+// registerSimulcastExtensions lets the media engine tell simulcast layers
+// apart by RID
+func registerSimulcastExtensions(m *webrtc.MediaEngine) error {
+	for _, uri := range []string{sdp.SDESMidURI, sdp.SDESRTPStreamIDURI} {
+		if err := m.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: uri}, webrtc.RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulcastLayer is one encoding of a simulcast video track
+type simulcastLayer struct {
+	track *webrtc.TrackRemote
+
+	rate       atomic.Uint64 // bits/s over the last window
+	lastPacket atomic.Int64  // unix nanos
+
+	// Only touched by the layer's forwarding goroutine
+	windowBytes uint64
+	windowStart time.Time
+}
+
+// observe counts a forwarded packet towards the layer's bitrate
+func (l *simulcastLayer) observe(size int, now time.Time) {
+	l.lastPacket.Store(now.UnixNano())
+	if l.windowStart.IsZero() {
+		l.windowStart = now
+	}
+	l.windowBytes += uint64(size)
+	if elapsed := now.Sub(l.windowStart); elapsed >= simulcastRateWindow {
+		l.rate.Store(uint64(float64(l.windowBytes*8) / elapsed.Seconds()))
+		l.windowBytes = 0
+		l.windowStart = now
+	}
+}
+
+// simulcastTrack is the set of layers a participant publishes for one video
+// track. The first layer to arrive stands in for the track elsewhere: it is
+// what remoteTracks holds and what gets recorded.
+type simulcastTrack struct {
+	mu      sync.RWMutex
+	layers  map[string]*simulcastLayer // by RID
+	primary string
+}
+
+func (t *simulcastTrack) addLayer(track *webrtc.TrackRemote) *simulcastLayer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.layers == nil {
+		t.layers = make(map[string]*simulcastLayer)
+		t.primary = track.RID()
+	}
+	layer := &simulcastLayer{track: track}
+	t.layers[track.RID()] = layer
+	return layer
+}
+
+func (t *simulcastTrack) layer(rid string) *simulcastLayer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.layers[rid]
+}
+
+// pick chooses the best layer that fits in budget bits/s, or the lowest if
+// none does. With no budget yet the best layer is picked. Layers that have
+// gone quiet are passed over; if all have, fallback is kept.
+func (t *simulcastTrack) pick(budget float64, fallback string, now time.Time) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	type candidate struct {
+		rid  string
+		rate uint64
+	}
+	var live []candidate
+	for rid, l := range t.layers {
+		if now.Sub(time.Unix(0, l.lastPacket.Load())) < simulcastLayerStale {
+			live = append(live, candidate{rid, l.rate.Load()})
+		}
+	}
+	if len(live) == 0 {
+		if fallback == "" {
+			return t.primary
+		}
+		return fallback
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].rate < live[j].rate })
+
+	if budget <= 0 {
+		return live[len(live)-1].rid
+	}
+	best := live[0].rid
+	for _, c := range live[1:] {
+		if float64(c.rate) <= budget*headroom {
+			best = c.rid
+		}
+	}
+	return best
+}
+
+// layerSubscription is which layer of a simulcast track one subscriber is
+// sent. Switches wait for a keyframe on the new layer, and sequence numbers
+// and timestamps are rewritten so the subscriber sees one continuous stream.
+type layerSubscription struct {
+	mu      sync.Mutex
+	track   *simulcastTrack
+	current string  // layer being forwarded, "" until the first keyframe
+	target  string  // layer to switch to at its next keyframe
+	budget  float64 // bits/s the subscriber can take, 0 if unknown
+
+	started   bool
+	seqOffset uint16
+	tsOffset  uint32
+	lastSeq   uint16
+	lastTS    uint32
+}
+
+func newLayerSubscription(track *simulcastTrack) *layerSubscription {
+	return &layerSubscription{track: track, target: track.pick(0, "", time.Now())}
+}
+
+// forwarded is the layer to ask for keyframes on: the one being switched to,
+// if a switch is pending
+func (s *layerSubscription) forwarded() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+// rewrite reports whether a packet from layer rid goes to this subscriber,
+// setting header's sequence number and timestamp if so
+func (s *layerSubscription) rewrite(rid string, packet *rtp.Packet, header *rtp.Header) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rid != s.current {
+		if rid != s.target || !isVP8Keyframe(packet.Payload) {
+			return false
+		}
+		s.current = rid
+		if s.started {
+			s.seqOffset = s.lastSeq + 1 - packet.SequenceNumber
+			s.tsOffset = s.lastTS + switchTimestampGap - packet.Timestamp
+		}
+	}
+
+	header.SequenceNumber = packet.SequenceNumber + s.seqOffset
+	header.Timestamp = packet.Timestamp + s.tsOffset
+	s.lastSeq = header.SequenceNumber
+	s.lastTS = header.Timestamp
+	s.started = true
+	return true
+}
+
+// onEstimate takes a REMB bitrate estimate from the subscriber. It returns
+// the new target layer if that changed.
+func (s *layerSubscription) onEstimate(bitrate float64, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget = bitrate
+	return s.retarget(now)
+}
+
+// onLoss adjusts the budget from the loss in a receiver report, for clients
+// that don't send REMB: heavy loss caps it below the current layer, and
+// light loss lets it creep back up
+func (s *layerSubscription) onLoss(fractionLost uint8, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case fractionLost >= lossStepDown:
+		if l := s.track.layer(s.current); l != nil {
+			if capped := float64(l.rate.Load()) * 0.85; s.budget == 0 || capped < s.budget {
+				s.budget = capped
+			}
+		}
+	case fractionLost <= lossStepUp && s.budget > 0:
+		s.budget *= 1.08
+	}
+	return s.retarget(now)
+}
+
+func (s *layerSubscription) retarget(now time.Time) (string, bool) {
+	target := s.track.pick(s.budget, s.target, now)
+	if target == s.target {
+		return "", false
+	}
+	s.target = target
+	return target, true
+}
+
+// isVP8Keyframe reports whether an RTP payload starts a VP8 keyframe
+func isVP8Keyframe(payload []byte) bool {
+	var vp8 codecs.VP8Packet
+	if _, err := vp8.Unmarshal(payload); err != nil || len(vp8.Payload) == 0 {
+		return false
+	}
+	// First partition of the frame, with the inverse keyframe bit clear
+	return vp8.S == 1 && vp8.PID == 0 && vp8.Payload[0]&0x01 == 0
+}
+
+// simulcastLayer returns the layer of a simulcast track p publishes, or nil
+func (p *SFUParticipant) simulcastLayer(trackID, rid string) *simulcastLayer {
+	p.mu.RLock()
+	st := p.simulcast[trackID]
+	p.mu.RUnlock()
+	if st == nil {
+		return nil
+	}
+	return st.layer(rid)
+}
+
+// subscribeLayers registers a subscriber to one of p's simulcast tracks for
+// layer selection. It returns nil if the track isn't simulcast.
+func (p *SFUParticipant) subscribeLayers(trackID string, local *webrtc.TrackLocalStaticRTP) *layerSubscription {
+	p.mu.RLock()
+	st := p.simulcast[trackID]
+	p.mu.RUnlock()
+	if st == nil {
+		return nil
+	}
+
+	sub := newLayerSubscription(st)
+	p.subscribersMu.Lock()
+	if p.layerSubs == nil {
+		p.layerSubs = make(map[*webrtc.TrackLocalStaticRTP]*layerSubscription)
+	}
+	p.layerSubs[local] = sub
+	p.subscribersMu.Unlock()
+	return sub
+}
+
+// requestLayerKeyframe relays a PLI to one layer of a sender's simulcast
+// track, or to the track itself if it isn't simulcast
+func (s *SFU) requestLayerKeyframe(senderID uuid.UUID, trackID, rid string, roomID uuid.UUID) {
+	room := s.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	sender := room.GetParticipant(senderID)
+	if sender == nil {
+		return
+	}
+
+	layer := sender.simulcastLayer(trackID, rid)
+	if layer == nil {
+		s.requestKeyframe(senderID, trackID, roomID)
+		return
+	}
+	_ = sender.pc.WriteRTCP([]rtcp.Packet{
+		&rtcp.PictureLossIndication{MediaSSRC: uint32(layer.track.SSRC())},
+	})
+}
+
+// simulcastPrimary returns the RID of the layer standing in for one of p's
+// simulcast tracks
+func (p *SFUParticipant) simulcastPrimary(trackID string) string {
+	p.mu.RLock()
+	st := p.simulcast[trackID]
+	p.mu.RUnlock()
+	if st == nil {
+		return ""
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.primary
+}
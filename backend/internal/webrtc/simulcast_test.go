@@ -0,0 +1,120 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	vp8Keyframe = []byte{0x10, 0x00, 0x9d, 0x01}
+	vp8Delta    = []byte{0x10, 0x01, 0x9d, 0x01}
+)
+
+// newTestSimulcastTrack builds a track whose layers have been sending at the
+// given bitrates
+func newTestSimulcastTrack(now time.Time, rates map[string]uint64) *simulcastTrack {
+	st := &simulcastTrack{layers: make(map[string]*simulcastLayer), primary: "q"}
+	for rid, rate := range rates {
+		l := &simulcastLayer{}
+		l.rate.Store(rate)
+		l.lastPacket.Store(now.UnixNano())
+		st.layers[rid] = l
+	}
+	return st
+}
+
+func TestSimulcastLayer_MeasuresBitrate(t *testing.T) {
+	var l simulcastLayer
+	start := time.Now()
+	for i := 0; i <= 10; i++ {
+		l.observe(1250, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+	// 11 packets of 10kbit over one second
+	assert.Equal(t, uint64(110_000), l.rate.Load())
+}
+
+func TestSimulcastTrack_Pick(t *testing.T) {
+	now := time.Now()
+	st := newTestSimulcastTrack(now, map[string]uint64{"q": 150_000, "h": 500_000, "f": 1_500_000})
+
+	assert.Equal(t, "f", st.pick(0, "", now), "no estimate yet gets the best layer")
+	assert.Equal(t, "f", st.pick(2_000_000, "", now))
+	assert.Equal(t, "h", st.pick(1_000_000, "", now))
+	assert.Equal(t, "q", st.pick(100_000, "", now), "the lowest layer when nothing fits")
+
+	st.layers["f"].lastPacket.Store(now.Add(-5 * time.Second).UnixNano())
+	assert.Equal(t, "h", st.pick(0, "", now), "paused layers are passed over")
+
+	later := now.Add(time.Minute)
+	assert.Equal(t, "h", st.pick(0, "h", later), "keeps the fallback when every layer is quiet")
+	assert.Equal(t, "q", st.pick(0, "", later), "or the primary layer without one")
+}
+
+func TestLayerSubscription_SwitchesAtKeyframe(t *testing.T) {
+	now := time.Now()
+	st := newTestSimulcastTrack(now, map[string]uint64{"q": 150_000, "f": 1_500_000})
+	sub := &layerSubscription{track: st, target: "q"}
+
+	var h rtp.Header
+	assert.False(t, sub.rewrite("q", &rtp.Packet{Header: rtp.Header{SequenceNumber: 10}, Payload: vp8Delta}, &h),
+		"nothing is sent before a keyframe")
+	require.True(t, sub.rewrite("q", &rtp.Packet{Header: rtp.Header{SequenceNumber: 11, Timestamp: 9000}, Payload: vp8Keyframe}, &h))
+	assert.Equal(t, uint16(11), h.SequenceNumber)
+	assert.False(t, sub.rewrite("f", &rtp.Packet{Header: rtp.Header{SequenceNumber: 500}, Payload: vp8Keyframe}, &h),
+		"other layers are dropped")
+
+	target, changed := sub.onEstimate(3_000_000, now)
+	require.True(t, changed)
+	assert.Equal(t, "f", target)
+
+	require.True(t, sub.rewrite("q", &rtp.Packet{Header: rtp.Header{SequenceNumber: 12, Timestamp: 12000}, Payload: vp8Delta}, &h),
+		"the old layer carries on until the new one has a keyframe")
+	assert.False(t, sub.rewrite("f", &rtp.Packet{Header: rtp.Header{SequenceNumber: 600, Timestamp: 50000}, Payload: vp8Delta}, &h))
+
+	require.True(t, sub.rewrite("f", &rtp.Packet{Header: rtp.Header{SequenceNumber: 601, Timestamp: 53000}, Payload: vp8Keyframe}, &h))
+	assert.Equal(t, uint16(13), h.SequenceNumber, "sequence numbers carry on from the old layer")
+	assert.Equal(t, uint32(12000+switchTimestampGap), h.Timestamp)
+	assert.False(t, sub.rewrite("q", &rtp.Packet{Header: rtp.Header{SequenceNumber: 13}, Payload: vp8Delta}, &h))
+
+	require.True(t, sub.rewrite("f", &rtp.Packet{Header: rtp.Header{SequenceNumber: 602, Timestamp: 56000}, Payload: vp8Delta}, &h))
+	assert.Equal(t, uint16(14), h.SequenceNumber)
+	assert.Equal(t, uint32(15000+switchTimestampGap), h.Timestamp)
+}
+
+func TestLayerSubscription_LossStepsDown(t *testing.T) {
+	now := time.Now()
+	st := newTestSimulcastTrack(now, map[string]uint64{"q": 150_000, "h": 500_000, "f": 1_500_000})
+	sub := &layerSubscription{track: st, current: "f", target: "f"}
+
+	_, changed := sub.onLoss(0, now)
+	assert.False(t, changed, "no estimate and no loss keeps the best layer")
+
+	target, changed := sub.onLoss(64, now) // 25%
+	require.True(t, changed)
+	assert.Equal(t, "h", target)
+
+	sub.current = "h"
+	target, _ = sub.onLoss(64, now)
+	assert.Equal(t, "q", target)
+
+	// Light loss lets the budget grow back
+	sub.current = "q"
+	for i := 0; i < 30; i++ {
+		target, changed = sub.onLoss(0, now)
+		if changed {
+			break
+		}
+	}
+	assert.Equal(t, "h", target)
+}
+
+func TestIsVP8Keyframe(t *testing.T) {
+	assert.True(t, isVP8Keyframe(vp8Keyframe))
+	assert.False(t, isVP8Keyframe(vp8Delta))
+	assert.False(t, isVP8Keyframe([]byte{0x00, 0x00, 0x9d, 0x01}), "not the start of a frame")
+	assert.False(t, isVP8Keyframe(nil))
+}
@@ -0,0 +1,80 @@
+package webrtc
+
+import "github.com/google/uuid"
+
+// CallStats summarises live call state for operator introspection
+type CallStats struct {
+	Rooms        int `json:"rooms"`
+	Participants int `json:"participants"`
+}
+
+// Stats returns the number of active P2P rooms and their participants
+func (m *Manager) Stats() CallStats {
+	// Snapshot rooms first so room locks are never taken under the manager lock
+	m.mu.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.RUnlock()
+
+	stats := CallStats{Rooms: len(rooms)}
+	for _, room := range rooms {
+		stats.Participants += room.ParticipantCount()
+	}
+	return stats
+}
+
+// Stats returns the number of active SFU rooms and their participants
+func (s *SFU) Stats() CallStats {
+	s.mu.RLock()
+	rooms := make([]*SFURoom, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	s.mu.RUnlock()
+
+	stats := CallStats{Rooms: len(rooms)}
+	for _, room := range rooms {
+		stats.Participants += room.ParticipantCount()
+	}
+	return stats
+}
+
+// ParticipantCounter reports live participant counts for call rooms, keyed by
+// conversation ID
+type ParticipantCounter interface {
+	ParticipantCounts(roomIDs []uuid.UUID) map[uuid.UUID]int
+}
+
+// ParticipantCounts returns the live participant count for each of roomIDs
+// that has an active P2P room. Rooms without participants are omitted.
+func (m *Manager) ParticipantCounts(roomIDs []uuid.UUID) map[uuid.UUID]int {
+	counts := make(map[uuid.UUID]int, len(roomIDs))
+	for _, roomID := range roomIDs {
+		room := m.GetRoom(roomID)
+		if room == nil {
+			continue
+		}
+		if n := room.ParticipantCount(); n > 0 {
+			counts[roomID] = n
+		}
+	}
+	return counts
+}
+
+// ParticipantCounts returns the live participant count for each of roomIDs
+// that has an active SFU room. Rooms without participants are omitted.
+func (s *SFU) ParticipantCounts(roomIDs []uuid.UUID) map[uuid.UUID]int {
+	counts := make(map[uuid.UUID]int, len(roomIDs))
+	for _, roomID := range roomIDs {
+		room := s.GetRoom(roomID)
+		if room == nil {
+			continue
+		}
+		if n := room.ParticipantCount(); n > 0 {
+			counts[roomID] = n
+		}
+	}
+	return counts
+}
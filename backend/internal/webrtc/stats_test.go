@@ -0,0 +1,96 @@
+package webrtc
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+)
+
+func TestManager_Stats_CountsRoomsAndParticipants(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mgr := NewManager(&Config{}, ps, logger)
+
+	if stats := mgr.Stats(); stats.Rooms != 0 || stats.Participants != 0 {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+
+	room1 := mgr.GetOrCreateRoom(uuid.New())
+	room1.AddParticipant(uuid.New(), "alice")
+	room1.AddParticipant(uuid.New(), "bob")
+	room2 := mgr.GetOrCreateRoom(uuid.New())
+	room2.AddParticipant(uuid.New(), "carol")
+
+	stats := mgr.Stats()
+	if stats.Rooms != 2 {
+		t.Errorf("got %d rooms, want 2", stats.Rooms)
+	}
+	if stats.Participants != 3 {
+		t.Errorf("got %d participants, want 3", stats.Participants)
+	}
+}
+
+func TestSFU_Stats_CountsRoomsAndParticipants(t *testing.T) {
+	_, sfu, _, _ := newTestSFUHandler(t)
+
+	roomID := uuid.New()
+	addSFURoomParticipant(t, sfu, roomID, uuid.New(), "alice")
+	addSFURoomParticipant(t, sfu, roomID, uuid.New(), "bob")
+
+	stats := sfu.Stats()
+	if stats.Rooms != 1 {
+		t.Errorf("got %d rooms, want 1", stats.Rooms)
+	}
+	if stats.Participants != 2 {
+		t.Errorf("got %d participants, want 2", stats.Participants)
+	}
+}
+
+func TestManager_ParticipantCounts_OnlyActiveRooms(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mgr := NewManager(&Config{}, ps, logger)
+
+	activeConv := uuid.New()
+	idleConv := uuid.New()
+	emptyConv := uuid.New()
+
+	room := mgr.GetOrCreateRoom(activeConv)
+	room.AddParticipant(uuid.New(), "alice")
+	room.AddParticipant(uuid.New(), "bob")
+	mgr.GetOrCreateRoom(emptyConv)
+
+	counts := mgr.ParticipantCounts([]uuid.UUID{activeConv, idleConv, emptyConv})
+	if counts[activeConv] != 2 {
+		t.Errorf("got %d participants for active conversation, want 2", counts[activeConv])
+	}
+	if _, ok := counts[idleConv]; ok {
+		t.Error("conversation without a call should not be reported")
+	}
+	if _, ok := counts[emptyConv]; ok {
+		t.Error("conversation with an empty room should not be reported")
+	}
+}
+
+func TestSFU_ParticipantCounts_OnlyActiveRooms(t *testing.T) {
+	_, sfu, _, _ := newTestSFUHandler(t)
+
+	activeConv := uuid.New()
+	idleConv := uuid.New()
+	addSFURoomParticipant(t, sfu, activeConv, uuid.New(), "alice")
+
+	counts := sfu.ParticipantCounts([]uuid.UUID{activeConv, idleConv})
+	if counts[activeConv] != 1 {
+		t.Errorf("got %d participants for active conversation, want 1", counts[activeConv])
+	}
+	if _, ok := counts[idleConv]; ok {
+		t.Error("conversation without a call should not be reported")
+	}
+}
@@ -0,0 +1,86 @@
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	pionwebrtc "github.com/pion/webrtc/v3"
+)
+
+// DefaultTURNCredentialTTL is how long a generated TURN credential is valid.
+// It only has to outlast allocation and refresh requests, so a call may run
+// past it.
+const DefaultTURNCredentialTTL = 12 * time.Hour
+
+func (c *Config) turnCredentialTTL() time.Duration {
+	if c.TURNCredentialTTL > 0 {
+		return c.TURNCredentialTTL
+	}
+	return DefaultTURNCredentialTTL
+}
+
+// turnCredentials returns the TURN username and password for userID. With a
+// shared secret they are the time-limited credentials of the TURN REST API
+// (username "<expiry>:<userID>", password base64(HMAC-SHA1(secret, username)))
+// that coturn's use-auth-secret checks; otherwise the static pair is used.
+// ok is false when TURN isn't configured.
+func (c *Config) turnCredentials(userID uuid.UUID, now time.Time) (username, password string, ok bool) {
+	if len(c.TURNURLs) == 0 {
+		return "", "", false
+	}
+
+	if c.TURNSecret == "" {
+		return c.TURNUsername, c.TURNPassword, c.TURNUsername != ""
+	}
+
+	expiry := now.Add(c.turnCredentialTTL()).Unix()
+	username = strconv.FormatInt(expiry, 10) + ":" + userID.String()
+	mac := hmac.New(sha1.New, []byte(c.TURNSecret))
+	mac.Write([]byte(username))
+	return username, base64.StdEncoding.EncodeToString(mac.Sum(nil)), true
+}
+
+// ICEServersFor returns the ICE server configuration for userID's client,
+// with TURN credentials issued to them
+func (c *Config) ICEServersFor(userID uuid.UUID) []ICEServer {
+	servers := make([]ICEServer, 0, 2)
+
+	if len(c.STUNURLs) > 0 {
+		servers = append(servers, ICEServer{URLs: c.STUNURLs})
+	}
+
+	if username, password, ok := c.turnCredentials(userID, time.Now()); ok {
+		servers = append(servers, ICEServer{
+			URLs:       c.TURNURLs,
+			Username:   username,
+			Credential: password,
+		})
+	}
+
+	return servers
+}
+
+// PionICEServersFor returns the ICE server configuration in Pion WebRTC
+// format for the server's side of userID's peer connection
+func (c *Config) PionICEServersFor(userID uuid.UUID) []pionwebrtc.ICEServer {
+	servers := make([]pionwebrtc.ICEServer, 0, 2)
+
+	if len(c.STUNURLs) > 0 {
+		servers = append(servers, pionwebrtc.ICEServer{URLs: c.STUNURLs})
+	}
+
+	if username, password, ok := c.turnCredentials(userID, time.Now()); ok {
+		servers = append(servers, pionwebrtc.ICEServer{
+			URLs:           c.TURNURLs,
+			Username:       username,
+			Credential:     password,
+			CredentialType: pionwebrtc.ICECredentialTypePassword,
+		})
+	}
+
+	return servers
+}
@@ -0,0 +1,83 @@
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_TURNCredentials_HMAC(t *testing.T) {
+	cfg := Config{
+		TURNURLs:          []string{"turn:turn.example.com:3478"},
+		TURNUsername:      "static",
+		TURNPassword:      "static-pass",
+		TURNSecret:        "s3cret",
+		TURNCredentialTTL: time.Hour,
+	}
+	userID := uuid.New()
+	now := time.Unix(1_700_000_000, 0)
+
+	username, password, ok := cfg.turnCredentials(userID, now)
+	require.True(t, ok)
+	assert.Equal(t, strconv.FormatInt(now.Add(time.Hour).Unix(), 10)+":"+userID.String(), username)
+
+	mac := hmac.New(sha1.New, []byte("s3cret"))
+	mac.Write([]byte(username))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), password)
+}
+
+func TestConfig_TURNCredentials_DefaultTTL(t *testing.T) {
+	cfg := Config{TURNURLs: []string{"turn:turn.example.com:3478"}, TURNSecret: "s3cret"}
+	now := time.Unix(1_700_000_000, 0)
+
+	username, _, ok := cfg.turnCredentials(uuid.New(), now)
+	require.True(t, ok)
+	expiry, _, _ := strings.Cut(username, ":")
+	assert.Equal(t, strconv.FormatInt(now.Add(DefaultTURNCredentialTTL).Unix(), 10), expiry)
+}
+
+func TestConfig_ICEServersFor(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("static fallback", func(t *testing.T) {
+		cfg := Config{
+			STUNURLs:     []string{"stun:stun.example.com:19302"},
+			TURNURLs:     []string{"turn:turn.example.com:3478"},
+			TURNUsername: "user",
+			TURNPassword: "pass",
+		}
+		servers := cfg.ICEServersFor(userID)
+		require.Len(t, servers, 2)
+		assert.Equal(t, "user", servers[1].Username)
+		assert.Equal(t, "pass", servers[1].Credential)
+
+		pion := cfg.PionICEServersFor(userID)
+		require.Len(t, pion, 2)
+		assert.Equal(t, "pass", pion[1].Credential)
+	})
+
+	t.Run("per-user credentials", func(t *testing.T) {
+		cfg := Config{TURNURLs: []string{"turn:turn.example.com:3478"}, TURNSecret: "s3cret"}
+		servers := cfg.ICEServersFor(userID)
+		require.Len(t, servers, 1)
+		assert.True(t, strings.HasSuffix(servers[0].Username, ":"+userID.String()))
+		assert.NotEmpty(t, servers[0].Credential)
+
+		other := cfg.ICEServersFor(uuid.New())
+		assert.NotEqual(t, servers[0].Credential, other[0].Credential)
+	})
+
+	t.Run("no TURN urls", func(t *testing.T) {
+		cfg := Config{STUNURLs: []string{"stun:stun.example.com:19302"}, TURNSecret: "s3cret"}
+		assert.Len(t, cfg.ICEServersFor(userID), 1)
+		assert.Len(t, cfg.PionICEServersFor(userID), 1)
+	})
+}
@@ -0,0 +1,165 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// videoGate picks whose video is forwarded in a room capped by
+// MaxForwardedVideo: the most recent dominant speakers, then whoever joined
+// first. Everyone else's video is paused; audio always flows.
+type videoGate struct {
+	mu     sync.Mutex
+	order  []uuid.UUID // most recently dominant first, then by join
+	paused map[uuid.UUID]bool
+}
+
+// videoChange is called for each participant whose video is paused or resumed
+type videoChange func(userID uuid.UUID, paused bool)
+
+// update applies mutate to the order and reports who crossed the cap
+func (g *videoGate) update(max int, mutate func(), change videoChange) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	mutate()
+	if g.paused == nil {
+		g.paused = make(map[uuid.UUID]bool)
+	}
+	for i, id := range g.order {
+		pause := max > 0 && i >= max
+		if pause == g.paused[id] {
+			continue
+		}
+		if pause {
+			g.paused[id] = true
+		} else {
+			delete(g.paused, id)
+		}
+		change(id, pause)
+	}
+}
+
+// join adds a participant at the back. Rejoining keeps their place.
+func (g *videoGate) join(userID uuid.UUID, max int, change videoChange) {
+	g.update(max, func() {
+		if !slices.Contains(g.order, userID) {
+			g.order = append(g.order, userID)
+		}
+	}, change)
+}
+
+// leave drops a participant, letting the next in line take their place
+func (g *videoGate) leave(userID uuid.UUID, max int, change videoChange) {
+	g.update(max, func() {
+		g.order = slices.DeleteFunc(g.order, func(id uuid.UUID) bool { return id == userID })
+		delete(g.paused, userID)
+	}, change)
+}
+
+// promote moves a new dominant speaker to the front
+func (g *videoGate) promote(userID uuid.UUID, max int, change videoChange) {
+	g.update(max, func() {
+		i := slices.Index(g.order, userID)
+		if i < 0 {
+			return
+		}
+		g.order = slices.Insert(slices.Delete(g.order, i, i+1), 0, userID)
+	}, change)
+}
+
+func (g *videoGate) isPaused(userID uuid.UUID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused[userID]
+}
+
+// pausedVideo lists whose video isn't being forwarded
+func (g *videoGate) pausedVideo() []uuid.UUID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(g.paused))
+	for _, id := range g.order {
+		if g.paused[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// VideoPausedPayload is sent to the conversation when a participant's video
+// stops or starts being forwarded, so clients can show a placeholder
+type VideoPausedPayload struct {
+	RoomID uuid.UUID `json:"room_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// PausedVideo lists the participants whose video isn't being forwarded
+func (r *SFURoom) PausedVideo() []uuid.UUID {
+	return r.video.pausedVideo()
+}
+
+// videoChanged returns the callback that flips a participant's forwarding
+// and tells the conversation
+func (s *SFU) videoChanged(ctx context.Context, room *SFURoom) videoChange {
+	return func(userID uuid.UUID, paused bool) {
+		if p := room.GetParticipant(userID); p != nil {
+			p.videoPaused.Store(paused)
+			if !paused {
+				// Subscribers need a keyframe to pick the video back up
+				s.requestVideoKeyframes(p)
+			}
+		}
+
+		eventType := EventTypeCallVideoResumed
+		if paused {
+			eventType = EventTypeCallVideoPaused
+		}
+		payloadBytes, _ := json.Marshal(VideoPausedPayload{RoomID: room.ID, UserID: userID})
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.Room(room.ID.String()),
+			Type:    eventType,
+			Payload: payloadBytes,
+		}
+		if err := s.pubsub.Publish(ctx, msg.Topic, msg); err != nil {
+			s.logger.Error("failed to publish video pause", "error", err, "room_id", room.ID)
+		}
+	}
+}
+
+// requestVideoKeyframes asks a participant for keyframes on all their video,
+// every simulcast layer included
+func (s *SFU) requestVideoKeyframes(p *SFUParticipant) {
+	if p.pc == nil {
+		return
+	}
+
+	var pkts []rtcp.Packet
+	p.mu.RLock()
+	for id, track := range p.remoteTracks {
+		if track.Kind() != webrtc.RTPCodecTypeVideo {
+			continue
+		}
+		if st := p.simulcast[id]; st != nil {
+			st.mu.RLock()
+			for _, l := range st.layers {
+				pkts = append(pkts, &rtcp.PictureLossIndication{MediaSSRC: uint32(l.track.SSRC())})
+			}
+			st.mu.RUnlock()
+			continue
+		}
+		pkts = append(pkts, &rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())})
+	}
+	p.mu.RUnlock()
+
+	if len(pkts) > 0 {
+		_ = p.pc.WriteRTCP(pkts)
+	}
+}
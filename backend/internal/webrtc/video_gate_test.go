@@ -0,0 +1,99 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordChanges collects a gate's pause/resume callbacks
+func recordChanges(changes map[uuid.UUID]bool) videoChange {
+	return func(userID uuid.UUID, paused bool) { changes[userID] = paused }
+}
+
+func TestVideoGate_CapsForwardedVideo(t *testing.T) {
+	var g videoGate
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	changes := map[uuid.UUID]bool{}
+
+	g.join(a, 2, recordChanges(changes))
+	g.join(b, 2, recordChanges(changes))
+	assert.Empty(t, changes, "under the cap nobody is paused")
+
+	g.join(c, 2, recordChanges(changes))
+	assert.Equal(t, map[uuid.UUID]bool{c: true}, changes, "the newcomer waits for a slot")
+
+	clear(changes)
+	g.promote(c, 2, recordChanges(changes))
+	assert.Equal(t, map[uuid.UUID]bool{c: false, b: true}, changes, "a new speaker bumps the least recent")
+	assert.Equal(t, []uuid.UUID{b}, g.pausedVideo())
+
+	clear(changes)
+	g.join(b, 2, recordChanges(changes))
+	assert.Empty(t, changes, "rejoining keeps your place")
+	assert.True(t, g.isPaused(b))
+
+	clear(changes)
+	g.leave(a, 2, recordChanges(changes))
+	assert.Equal(t, map[uuid.UUID]bool{b: false}, changes, "a free slot goes to the next in line")
+	assert.Empty(t, g.pausedVideo())
+}
+
+func TestVideoGate_Uncapped(t *testing.T) {
+	var g videoGate
+	changes := map[uuid.UUID]bool{}
+	for i := 0; i < 20; i++ {
+		g.join(uuid.New(), 0, recordChanges(changes))
+	}
+	assert.Empty(t, changes)
+}
+
+func TestSFU_VideoGate_PausesAndAnnounces(t *testing.T) {
+	_, sfu, _, ps := newTestSFUHandler(t)
+	sfu.config.MaxForwardedVideo = 1
+	ctx := context.Background()
+
+	roomID, aliceID, bobID := uuid.New(), uuid.New(), uuid.New()
+	addSFURoomParticipant(t, sfu, roomID, aliceID, "alice")
+	room := addSFURoomParticipant(t, sfu, roomID, bobID, "bob")
+	alice, bob := room.GetParticipant(aliceID), room.GetParticipant(bobID)
+
+	received := make(chan *pubsub.Message, 4)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.Room(room.ID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	room.video.join(alice.UserID, 1, sfu.videoChanged(ctx, room))
+	room.video.join(bob.UserID, 1, sfu.videoChanged(ctx, room))
+	assert.False(t, alice.videoPaused.Load())
+	assert.True(t, bob.videoPaused.Load())
+	assert.Equal(t, []uuid.UUID{bob.UserID}, room.PausedVideo())
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, EventTypeCallVideoPaused, msg.Type)
+		var payload VideoPausedPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+		assert.Equal(t, bob.UserID, payload.UserID)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("conversation was not told bob's video is paused")
+	}
+
+	// Alice leaving frees the slot for Bob
+	room.RemoveParticipant(alice.UserID)
+	assert.False(t, bob.videoPaused.Load())
+	select {
+	case msg := <-received:
+		assert.Equal(t, EventTypeCallVideoResumed, msg.Type)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("conversation was not told bob's video resumed")
+	}
+}
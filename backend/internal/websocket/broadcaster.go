@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
 	"github.com/observer/teatime/internal/pubsub"
 )
 
@@ -20,8 +21,63 @@ type RoomBroadcaster interface {
 	// BroadcastRoomUpdated notifies room members that the conversation was updated
 	BroadcastRoomUpdated(ctx context.Context, convID uuid.UUID, title string, updatedBy uuid.UUID) error
 
-	// BroadcastMessageDeleted notifies room members that a message was deleted
-	BroadcastMessageDeleted(ctx context.Context, messageID, convID, deletedBy uuid.UUID) error
+	// BroadcastReadOnlyChanged notifies room members that read-only mode was switched
+	BroadcastReadOnlyChanged(ctx context.Context, convID uuid.UUID, readOnly bool, updatedBy uuid.UUID) error
+
+	// BroadcastMessageTTLChanged notifies room members that the disappearing-message
+	// lifetime changed
+	BroadcastMessageTTLChanged(ctx context.Context, convID uuid.UUID, ttlSeconds int, updatedBy uuid.UUID) error
+
+	// BroadcastOwnerChanged notifies room members that group ownership was transferred
+	BroadcastOwnerChanged(ctx context.Context, convID, previousOwner, newOwner uuid.UUID) error
+
+	// BroadcastMemberRoleChanged notifies room members that a member's role was
+	// changed; changedBy is uuid.Nil when they were promoted automatically
+	BroadcastMemberRoleChanged(ctx context.Context, convID, userID uuid.UUID, role string, changedBy uuid.UUID) error
+
+	// BroadcastMessageDeleted notifies everyone who could see a message that it
+	// was deleted; deletedBy is uuid.Nil when a disappearing message expired
+	BroadcastMessageDeleted(ctx context.Context, msg *domain.Message, deletedBy uuid.UUID) error
+
+	// BroadcastMessagesDeleted notifies room members that a batch of messages
+	// was deleted in one go; whispers in it are only named to their audience
+	BroadcastMessagesDeleted(ctx context.Context, convID uuid.UUID, msgs []domain.Message, deletedBy uuid.UUID) error
+
+	// BroadcastMessageEdited notifies everyone who can see a message of its
+	// new body; whisper edits only reach the whisper's audience
+	BroadcastMessageEdited(ctx context.Context, msg *domain.Message, editedBy uuid.UUID) error
+
+	// BroadcastNewMessage delivers a message posted outside a WebSocket
+	// message.send, such as a scheduled message, to room members
+	BroadcastNewMessage(ctx context.Context, msg *domain.Message, senderUsername string) error
+
+	// BroadcastMessageBatch delivers several new messages to room members in one event
+	BroadcastMessageBatch(ctx context.Context, convID uuid.UUID, messages []domain.Message, senderUsername string) error
+
+	// BroadcastLinkPreview delivers the preview card fetched for a link in a message
+	BroadcastLinkPreview(ctx context.Context, preview *domain.LinkPreview) error
+
+	// BroadcastMessagePinned notifies room members that a message was pinned
+	BroadcastMessagePinned(ctx context.Context, pin *domain.PinnedMessage, pinnedBy uuid.UUID) error
+
+	// BroadcastMessageUnpinned notifies room members that a pin was removed;
+	// unpinnedBy is nil when the pin expired
+	BroadcastMessageUnpinned(ctx context.Context, convID, messageID uuid.UUID, unpinnedBy *uuid.UUID) error
+
+	// BroadcastPinsReordered notifies room members of the new pin order
+	BroadcastPinsReordered(ctx context.Context, convID uuid.UUID, messageIDs []uuid.UUID, reorderedBy uuid.UUID) error
+
+	// NotifyConversationRead tells the user's own connections that their read
+	// state for a conversation changed
+	NotifyConversationRead(ctx context.Context, userID uuid.UUID, state *domain.UnreadState) error
+
+	// NotifyMentioned tells each of userIDs, on their own topic, that msg
+	// mentioned them
+	NotifyMentioned(ctx context.Context, userIDs []uuid.UUID, msg *domain.Message, senderUsername string) error
+
+	// NotifyMessageStarred tells the user's own connections that they starred
+	// (convID set) or unstarred (convID nil) a message
+	NotifyMessageStarred(ctx context.Context, userID, messageID uuid.UUID, convID *uuid.UUID, starred bool) error
 }
 
 // PubSubBroadcaster implements RoomBroadcaster using the PubSub system
@@ -64,13 +120,290 @@ func (b *PubSubBroadcaster) BroadcastRoomUpdated(ctx context.Context, convID uui
 	return b.broadcast(ctx, convID, EventTypeRoomUpdated, payload)
 }
 
-func (b *PubSubBroadcaster) BroadcastMessageDeleted(ctx context.Context, messageID, convID, deletedBy uuid.UUID) error {
+func (b *PubSubBroadcaster) BroadcastReadOnlyChanged(ctx context.Context, convID uuid.UUID, readOnly bool, updatedBy uuid.UUID) error {
+	payload := RoomReadOnlyPayload{
+		ConversationID: convID,
+		ReadOnly:       readOnly,
+		UpdatedBy:      updatedBy,
+	}
+	return b.broadcast(ctx, convID, EventTypeRoomReadOnly, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessageTTLChanged(ctx context.Context, convID uuid.UUID, ttlSeconds int, updatedBy uuid.UUID) error {
+	payload := RoomTTLUpdatedPayload{
+		ConversationID: convID,
+		TTLSeconds:     ttlSeconds,
+		UpdatedBy:      updatedBy,
+	}
+	return b.broadcast(ctx, convID, EventTypeRoomTTLUpdated, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastOwnerChanged(ctx context.Context, convID, previousOwner, newOwner uuid.UUID) error {
+	payload := OwnerChangedPayload{
+		ConversationID: convID,
+		PreviousOwner:  previousOwner,
+		NewOwner:       newOwner,
+	}
+	return b.broadcast(ctx, convID, EventTypeOwnerChanged, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMemberRoleChanged(ctx context.Context, convID, userID uuid.UUID, role string, changedBy uuid.UUID) error {
+	payload := MemberRoleChangedPayload{
+		ConversationID: convID,
+		UserID:         userID,
+		Role:           role,
+		ChangedBy:      changedBy,
+	}
+	return b.broadcast(ctx, convID, EventTypeMemberRoleChanged, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessageDeleted(ctx context.Context, msg *domain.Message, deletedBy uuid.UUID) error {
 	payload := MessageDeletedPayload{
+		MessageID:      msg.ID,
+		ConversationID: msg.ConversationID,
+		DeletedBy:      deletedBy,
+	}
+	return b.broadcastToAudience(ctx, msg, EventTypeMessageDeleted, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessagesDeleted(ctx context.Context, convID uuid.UUID, msgs []domain.Message, deletedBy uuid.UUID) error {
+	var public []uuid.UUID
+	whispers := make(map[uuid.UUID][]uuid.UUID) // audience member -> whisper IDs
+	var audience []uuid.UUID
+	for i := range msgs {
+		if !msgs[i].IsWhisper() {
+			public = append(public, msgs[i].ID)
+			continue
+		}
+		for _, userID := range msgs[i].VisibleTo {
+			if _, ok := whispers[userID]; !ok {
+				audience = append(audience, userID)
+			}
+			whispers[userID] = append(whispers[userID], msgs[i].ID)
+		}
+	}
+
+	if len(public) > 0 {
+		payload := MessagesDeletedPayload{
+			ConversationID: convID,
+			MessageIDs:     public,
+			DeletedBy:      deletedBy,
+		}
+		if err := b.broadcast(ctx, convID, EventTypeMessagesDeleted, payload); err != nil {
+			return err
+		}
+	}
+	for _, userID := range audience {
+		payloadBytes, err := json.Marshal(MessagesDeletedPayload{
+			ConversationID: convID,
+			MessageIDs:     whispers[userID],
+			DeletedBy:      deletedBy,
+		})
+		if err != nil {
+			return err
+		}
+		out := &pubsub.Message{
+			Topic:   pubsub.Topics.User(userID.String()),
+			Type:    EventTypeMessagesDeleted,
+			Payload: payloadBytes,
+		}
+		if err := b.ps.Publish(ctx, out.Topic, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *PubSubBroadcaster) BroadcastMessageEdited(ctx context.Context, msg *domain.Message, editedBy uuid.UUID) error {
+	payload := MessageEditedPayload{
+		MessageID:      msg.ID,
+		ConversationID: msg.ConversationID,
+		BodyText:       msg.BodyText,
+		EditedBy:       editedBy,
+	}
+	if msg.EditedAt != nil {
+		payload.EditedAt = *msg.EditedAt
+	}
+	return b.broadcastToAudience(ctx, msg, EventTypeMessageEdited, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastNewMessage(ctx context.Context, msg *domain.Message, senderUsername string) error {
+	var senderID uuid.UUID
+	if msg.SenderID != nil {
+		senderID = *msg.SenderID
+	}
+	payload := MessageNewPayload{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderID:       senderID,
+		SenderUsername: senderUsername,
+		BodyText:       msg.BodyText,
+		AttachmentID:   msg.AttachmentID,
+		AttachmentIDs:  msg.AttachmentIDs,
+		ReplyToID:      msg.ReplyToID,
+		CreatedAt:      msg.CreatedAt,
+		ExpiresAt:      msg.ExpiresAt,
+	}
+	return b.broadcast(ctx, msg.ConversationID, EventTypeMessageNew, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessageBatch(ctx context.Context, convID uuid.UUID, messages []domain.Message, senderUsername string) error {
+	payload := MessageBatchPayload{
+		ConversationID: convID,
+		Messages:       make([]MessageNewPayload, 0, len(messages)),
+	}
+	for _, m := range messages {
+		var senderID uuid.UUID
+		if m.SenderID != nil {
+			senderID = *m.SenderID
+		}
+		payload.Messages = append(payload.Messages, MessageNewPayload{
+			ID:             m.ID,
+			ConversationID: m.ConversationID,
+			SenderID:       senderID,
+			SenderUsername: senderUsername,
+			BodyText:       m.BodyText,
+			AttachmentID:   m.AttachmentID,
+			AttachmentIDs:  m.AttachmentIDs,
+			ForwardedFrom:  m.ForwardedFrom,
+			CreatedAt:      m.CreatedAt,
+			ExpiresAt:      m.ExpiresAt,
+		})
+	}
+	return b.broadcast(ctx, convID, EventTypeMessageBatch, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastLinkPreview(ctx context.Context, preview *domain.LinkPreview) error {
+	payload := MessagePreviewPayload{
+		MessageID:      preview.MessageID,
+		ConversationID: preview.ConversationID,
+		URL:            preview.URL,
+		Title:          preview.Title,
+		Description:    preview.Description,
+		ImageURL:       preview.ImageURL,
+	}
+	return b.broadcast(ctx, preview.ConversationID, EventTypeMessagePreview, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessagePinned(ctx context.Context, pin *domain.PinnedMessage, pinnedBy uuid.UUID) error {
+	payload := MessagePinnedPayload{
+		ConversationID: pin.ConversationID,
+		MessageID:      pin.MessageID,
+		PinnedBy:       pinnedBy,
+		ExpiresAt:      pin.ExpiresAt,
+	}
+	return b.broadcast(ctx, pin.ConversationID, EventTypeMessagePinned, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastMessageUnpinned(ctx context.Context, convID, messageID uuid.UUID, unpinnedBy *uuid.UUID) error {
+	payload := MessageUnpinnedPayload{
+		ConversationID: convID,
 		MessageID:      messageID,
+		UnpinnedBy:     unpinnedBy,
+		Expired:        unpinnedBy == nil,
+	}
+	return b.broadcast(ctx, convID, EventTypeMessageUnpinned, payload)
+}
+
+func (b *PubSubBroadcaster) BroadcastPinsReordered(ctx context.Context, convID uuid.UUID, messageIDs []uuid.UUID, reorderedBy uuid.UUID) error {
+	payload := PinsReorderedPayload{
 		ConversationID: convID,
-		DeletedBy:      deletedBy,
+		MessageIDs:     messageIDs,
+		ReorderedBy:    reorderedBy,
+	}
+	return b.broadcast(ctx, convID, EventTypePinsReordered, payload)
+}
+
+func (b *PubSubBroadcaster) NotifyConversationRead(ctx context.Context, userID uuid.UUID, state *domain.UnreadState) error {
+	payload := ConversationReadPayload{
+		ConversationID:    state.ConversationID,
+		UnreadCount:       state.UnreadCount,
+		LastReadAt:        state.LastReadAt,
+		LastReadMessageID: state.LastReadMessageID,
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	msg := &pubsub.Message{
+		Topic:   pubsub.Topics.User(userID.String()),
+		Type:    EventTypeConversationRead,
+		Payload: payloadBytes,
+	}
+
+	return b.ps.Publish(ctx, msg.Topic, msg)
+}
+
+func (b *PubSubBroadcaster) NotifyMessageStarred(ctx context.Context, userID, messageID uuid.UUID, convID *uuid.UUID, starred bool) error {
+	payloadBytes, err := json.Marshal(MessageStarPayload{MessageID: messageID, ConversationID: convID})
+	if err != nil {
+		return err
+	}
+
+	eventType := EventTypeMessageUnstarred
+	if starred {
+		eventType = EventTypeMessageStarred
+	}
+	msg := &pubsub.Message{
+		Topic:   pubsub.Topics.User(userID.String()),
+		Type:    eventType,
+		Payload: payloadBytes,
+	}
+
+	return b.ps.Publish(ctx, msg.Topic, msg)
+}
+
+func (b *PubSubBroadcaster) NotifyMentioned(ctx context.Context, userIDs []uuid.UUID, msg *domain.Message, senderUsername string) error {
+	payload := MentionPayload{
+		MessageID:      msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderUsername: senderUsername,
+		BodyText:       domain.TruncateText(msg.BodyText, domain.MaxReplyPreviewLength),
+		CreatedAt:      msg.CreatedAt,
+	}
+	if msg.SenderID != nil {
+		payload.SenderID = *msg.SenderID
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		out := &pubsub.Message{
+			Topic:   pubsub.Topics.User(userID.String()),
+			Type:    EventTypeMention,
+			Payload: payloadBytes,
+		}
+		if err := b.ps.Publish(ctx, out.Topic, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// broadcastToAudience sends an event about msg to its room, or only to the
+// whisper's audience when msg is a whisper
+func (b *PubSubBroadcaster) broadcastToAudience(ctx context.Context, msg *domain.Message, eventType string, payload interface{}) error {
+	if !msg.IsWhisper() {
+		return b.broadcast(ctx, msg.ConversationID, eventType, payload)
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for _, userID := range msg.VisibleTo {
+		out := &pubsub.Message{
+			Topic:   pubsub.Topics.User(userID.String()),
+			Type:    eventType,
+			Payload: payloadBytes,
+		}
+		if err := b.ps.Publish(ctx, out.Topic, out); err != nil {
+			return err
+		}
 	}
-	return b.broadcast(ctx, convID, EventTypeMessageDeleted, payload)
+	return nil
 }
 
 func (b *PubSubBroadcaster) broadcast(ctx context.Context, convID uuid.UUID, eventType string, payload interface{}) error {
@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectTopic records every event published to a topic
+func collectTopic(t *testing.T, ps pubsub.PubSub, topic string) <-chan *pubsub.Message {
+	t.Helper()
+	ch := make(chan *pubsub.Message, 8)
+	sub, err := ps.Subscribe(context.Background(), topic, func(_ context.Context, msg *pubsub.Message) {
+		ch <- msg
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Unsubscribe() })
+	return ch
+}
+
+func TestPubSubBroadcaster_MessageEdited_GoesToRoom(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	sender, convID := uuid.New(), uuid.New()
+	editedAt := time.Now().UTC().Truncate(time.Second)
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	msg := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &sender, BodyText: "fixed typo", EditedAt: &editedAt}
+	require.NoError(t, b.BroadcastMessageEdited(context.Background(), msg, sender))
+
+	select {
+	case out := <-room:
+		assert.Equal(t, EventTypeMessageEdited, out.Type)
+		var p MessageEditedPayload
+		require.NoError(t, json.Unmarshal(out.Payload, &p))
+		assert.Equal(t, msg.ID, p.MessageID)
+		assert.Equal(t, "fixed typo", p.BodyText)
+		assert.Equal(t, sender, p.EditedBy)
+		assert.True(t, editedAt.Equal(p.EditedAt))
+	case <-time.After(time.Second):
+		t.Fatal("expected message.edited on the room topic")
+	}
+}
+
+func TestPubSubBroadcaster_MessageEdited_WhisperOnlyReachesAudience(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	sender, recipient, bystander, convID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+	toSender := collectTopic(t, ps, pubsub.Topics.User(sender.String()))
+	toRecipient := collectTopic(t, ps, pubsub.Topics.User(recipient.String()))
+	toBystander := collectTopic(t, ps, pubsub.Topics.User(bystander.String()))
+
+	msg := &domain.Message{ID: uuid.New(), ConversationID: convID, SenderID: &sender, BodyText: "psst", VisibleTo: []uuid.UUID{sender, recipient}}
+	require.NoError(t, b.BroadcastMessageEdited(context.Background(), msg, sender))
+
+	for _, ch := range []<-chan *pubsub.Message{toSender, toRecipient} {
+		select {
+		case out := <-ch:
+			assert.Equal(t, EventTypeMessageEdited, out.Type)
+		case <-time.After(time.Second):
+			t.Fatal("expected message.edited for the whisper audience")
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond) // let any stray events land
+	assert.Empty(t, room, "whisper edits don't go to the whole room")
+	assert.Empty(t, toBystander)
+}
+
+func TestPubSubBroadcaster_MessagesDeleted_WhispersOnlyReachAudience(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	admin, recipient, bystander, convID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+	toRecipient := collectTopic(t, ps, pubsub.Topics.User(recipient.String()))
+	toBystander := collectTopic(t, ps, pubsub.Topics.User(bystander.String()))
+
+	public := domain.Message{ID: uuid.New(), ConversationID: convID}
+	whisper := domain.Message{ID: uuid.New(), ConversationID: convID, VisibleTo: []uuid.UUID{admin, recipient}}
+	require.NoError(t, b.BroadcastMessagesDeleted(context.Background(), convID, []domain.Message{public, whisper}, admin))
+
+	for _, tc := range []struct {
+		ch  <-chan *pubsub.Message
+		ids []uuid.UUID
+	}{{room, []uuid.UUID{public.ID}}, {toRecipient, []uuid.UUID{whisper.ID}}} {
+		select {
+		case out := <-tc.ch:
+			assert.Equal(t, EventTypeMessagesDeleted, out.Type)
+			var p MessagesDeletedPayload
+			require.NoError(t, json.Unmarshal(out.Payload, &p))
+			assert.Equal(t, tc.ids, p.MessageIDs)
+		case <-time.After(time.Second):
+			t.Fatal("expected messages.deleted")
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond) // let any stray events land
+	assert.Empty(t, room, "the whisper isn't named to the whole room")
+	assert.Empty(t, toBystander)
+}
+
+func TestPubSubBroadcaster_ReadOnlyChanged(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	admin, convID := uuid.New(), uuid.New()
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	require.NoError(t, b.BroadcastReadOnlyChanged(context.Background(), convID, true, admin))
+
+	select {
+	case out := <-room:
+		assert.Equal(t, EventTypeRoomReadOnly, out.Type)
+		var p RoomReadOnlyPayload
+		require.NoError(t, json.Unmarshal(out.Payload, &p))
+		assert.Equal(t, convID, p.ConversationID)
+		assert.True(t, p.ReadOnly)
+		assert.Equal(t, admin, p.UpdatedBy)
+	case <-time.After(time.Second):
+		t.Fatal("expected room.read_only on the room topic")
+	}
+}
+
+func TestPubSubBroadcaster_MessageTTLChanged(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	admin, convID := uuid.New(), uuid.New()
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	require.NoError(t, b.BroadcastMessageTTLChanged(context.Background(), convID, 3600, admin))
+
+	select {
+	case out := <-room:
+		assert.Equal(t, EventTypeRoomTTLUpdated, out.Type)
+		var p RoomTTLUpdatedPayload
+		require.NoError(t, json.Unmarshal(out.Payload, &p))
+		assert.Equal(t, convID, p.ConversationID)
+		assert.Equal(t, 3600, p.TTLSeconds)
+		assert.Equal(t, admin, p.UpdatedBy)
+	case <-time.After(time.Second):
+		t.Fatal("expected room.ttl_updated on the room topic")
+	}
+}
+
+func TestPubSubBroadcaster_MemberRoleChanged(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	b := NewPubSubBroadcaster(ps)
+
+	admin, member, convID := uuid.New(), uuid.New(), uuid.New()
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	require.NoError(t, b.BroadcastMemberRoleChanged(context.Background(), convID, member, "moderator", admin))
+
+	select {
+	case out := <-room:
+		assert.Equal(t, EventTypeMemberRoleChanged, out.Type)
+		var p MemberRoleChangedPayload
+		require.NoError(t, json.Unmarshal(out.Payload, &p))
+		assert.Equal(t, convID, p.ConversationID)
+		assert.Equal(t, member, p.UserID)
+		assert.Equal(t, "moderator", p.Role)
+		assert.Equal(t, admin, p.ChangedBy)
+	case <-time.After(time.Second):
+		t.Fatal("expected member.role_changed on the room topic")
+	}
+}
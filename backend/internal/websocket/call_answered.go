@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/observer/teatime/internal/webrtc"
+)
+
+// notifyCallAnswered runs when client joins a call someone else started. The
+// user's other connections get call.answered_elsewhere so they stop ringing,
+// and everyone already in the call (the caller, for a 1:1) gets call.answered.
+func (h *Hub) notifyCallAnswered(client *Client, roomID uuid.UUID, participants []webrtc.Participant) {
+	userID := client.UserID()
+	payload := webrtc.CallAnsweredPayload{RoomID: roomID, UserID: userID, Username: client.Username()}
+
+	// Other connections are found locally, pubsub can't exclude one of them
+	h.mu.RLock()
+	others := make([]*Client, 0, len(h.clients[userID]))
+	for c := range h.clients[userID] {
+		if c != client {
+			others = append(others, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(others) > 0 {
+		msg, err := NewMessage(webrtc.EventTypeCallAnsweredElsewhere, payload)
+		if err != nil {
+			h.logger.Error("failed to build call.answered_elsewhere", "error", err)
+		} else {
+			for _, c := range others {
+				_ = c.Send(msg)
+			}
+		}
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		h.logger.Error("failed to marshal call answered payload", "error", err)
+		return
+	}
+	for _, p := range participants {
+		if p.UserID == userID {
+			continue
+		}
+		msg := &pubsub.Message{
+			Topic:   pubsub.Topics.User(p.UserID.String()),
+			Type:    webrtc.EventTypeCallAnswered,
+			Payload: payloadBytes,
+		}
+		if err := h.pubsub.Publish(client.Context(), msg.Topic, msg); err != nil {
+			h.logger.Error("failed to publish call.answered", "error", err, "user_id", p.UserID)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/observer/teatime/internal/webrtc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_NotifyCallAnswered_StopsRingingOnOtherConnections(t *testing.T) {
+	hub, phone := newTestAckHub(t)
+	userID := phone.UserID()
+	laptop := &Client{hub: hub, send: make(chan []byte, 4), rooms: make(map[uuid.UUID]bool), logger: phone.logger}
+	laptop.SetUser(userID, "alice")
+	hub.clients[userID] = map[*Client]bool{phone: true, laptop: true}
+
+	roomID, callerID := uuid.New(), uuid.New()
+	answered := make(chan *pubsub.Message, 1)
+	sub, err := hub.pubsub.Subscribe(context.Background(), pubsub.Topics.User(callerID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		answered <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	hub.notifyCallAnswered(phone, roomID, []webrtc.Participant{
+		{UserID: callerID, Username: "bob"},
+		{UserID: userID, Username: "alice"},
+	})
+
+	msg := receiveMessage(t, laptop)
+	assert.Equal(t, webrtc.EventTypeCallAnsweredElsewhere, msg.Type)
+	var p webrtc.CallAnsweredPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &p))
+	assert.Equal(t, roomID, p.RoomID)
+	assert.Equal(t, userID, p.UserID)
+	assert.Empty(t, phone.send, "the answering connection isn't told to stop ringing")
+
+	select {
+	case msg := <-answered:
+		assert.Equal(t, webrtc.EventTypeCallAnswered, msg.Type)
+		var p webrtc.CallAnsweredPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, userID, p.UserID)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected call.answered on the caller's topic")
+	}
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCallReconnectGrace is how long a dropped connection keeps its call
+// participation before the hub leaves the call on the user's behalf.
+const DefaultCallReconnectGrace = 10 * time.Second
+
+// graceKey identifies one user's participation in one room. A uuid.Nil room
+// stands for user-wide cleanup that isn't tied to a single conversation.
+type graceKey struct {
+	userID uuid.UUID
+	roomID uuid.UUID
+}
+
+// callGrace defers call cleanup after a disconnect so that a brief network
+// blip doesn't end the user's call. Reconnecting and rejoining the room
+// within the window cancels the pending leave.
+type callGrace struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending map[graceKey]*time.Timer
+}
+
+func newCallGrace(window time.Duration) *callGrace {
+	return &callGrace{
+		window:  window,
+		pending: make(map[graceKey]*time.Timer),
+	}
+}
+
+// Schedule runs leave once the window expires unless Cancel is called first.
+// With no window the leave runs immediately. Scheduling the same user and
+// room again replaces the earlier leave.
+func (g *callGrace) Schedule(userID, roomID uuid.UUID, leave func()) {
+	if g.window <= 0 {
+		leave()
+		return
+	}
+
+	key := graceKey{userID: userID, roomID: roomID}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if t, ok := g.pending[key]; ok {
+		t.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(g.window, func() {
+		g.mu.Lock()
+		// A rejoin may have cancelled this leave, or a later disconnect
+		// replaced it, between the timer firing and taking the lock
+		if g.pending[key] != timer {
+			g.mu.Unlock()
+			return
+		}
+		delete(g.pending, key)
+		g.mu.Unlock()
+
+		leave()
+	})
+	g.pending[key] = timer
+}
+
+// Cancel drops a pending leave. It reports whether one was pending.
+func (g *callGrace) Cancel(userID, roomID uuid.UUID) bool {
+	key := graceKey{userID: userID, roomID: roomID}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.pending[key]
+	if !ok {
+		return false
+	}
+	t.Stop()
+	delete(g.pending, key)
+	return true
+}
+
+// Pending reports whether a leave is scheduled for the user and room
+func (g *callGrace) Pending(userID, roomID uuid.UUID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.pending[graceKey{userID: userID, roomID: roomID}]
+	return ok
+}
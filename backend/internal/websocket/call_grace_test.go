@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallGrace_ReconnectWithinWindowPreservesParticipation(t *testing.T) {
+	g := newCallGrace(50 * time.Millisecond)
+	userID, roomID := uuid.New(), uuid.New()
+
+	var left atomic.Bool
+	g.Schedule(userID, roomID, func() { left.Store(true) })
+	assert.True(t, g.Pending(userID, roomID))
+
+	assert.True(t, g.Cancel(userID, roomID), "rejoining cancels the pending leave")
+	time.Sleep(100 * time.Millisecond)
+
+	assert.False(t, left.Load(), "user stays in the call")
+	assert.False(t, g.Pending(userID, roomID))
+}
+
+func TestCallGrace_LeavesAfterWindow(t *testing.T) {
+	g := newCallGrace(20 * time.Millisecond)
+	userID, roomID := uuid.New(), uuid.New()
+
+	var left atomic.Bool
+	g.Schedule(userID, roomID, func() { left.Store(true) })
+
+	assert.Eventually(t, left.Load, time.Second, 5*time.Millisecond)
+	assert.False(t, g.Pending(userID, roomID))
+	assert.False(t, g.Cancel(userID, roomID), "nothing left to cancel")
+}
+
+func TestCallGrace_ZeroWindowLeavesImmediately(t *testing.T) {
+	g := newCallGrace(0)
+
+	left := false
+	g.Schedule(uuid.New(), uuid.New(), func() { left = true })
+	assert.True(t, left)
+}
+
+func TestCallGrace_RescheduleReplacesEarlierLeave(t *testing.T) {
+	g := newCallGrace(20 * time.Millisecond)
+	userID, roomID := uuid.New(), uuid.New()
+
+	var leaves atomic.Int32
+	g.Schedule(userID, roomID, func() { leaves.Add(1) })
+	g.Schedule(userID, roomID, func() { leaves.Add(1) })
+
+	time.Sleep(80 * time.Millisecond)
+	assert.Equal(t, int32(1), leaves.Load(), "two quick drops leave the call once")
+}
+
+func TestHub_DisconnectDefersCallLeaveUntilRejoin(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	hub.SetCallReconnectGrace(time.Minute)
+
+	convID := uuid.New()
+	client.JoinRoom(convID)
+	hub.rooms[convID] = map[*Client]bool{client: true}
+	hub.clients[client.UserID()] = map[*Client]bool{client: true}
+
+	hub.handleUnregister(client)
+	assert.True(t, hub.callGrace.Pending(client.UserID(), convID), "leave waits out the grace window")
+
+	hub.resumeCalls(client.UserID(), convID)
+	assert.False(t, hub.callGrace.Pending(client.UserID(), convID), "rejoining the room keeps the call")
+}
@@ -3,12 +3,15 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/observer/teatime/internal/i18n"
 	"github.com/observer/teatime/internal/pubsub"
 )
 
@@ -16,12 +19,6 @@ const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period (must be less than pongWait)
-	pingPeriod = (pongWait * 9) / 10
-
 	// Maximum message size allowed from peer (64KB for attachment metadata)
 	maxMessageSize = 65536
 )
@@ -33,29 +30,72 @@ type Client struct {
 	send     chan []byte
 	userID   uuid.UUID
 	username string
+	lang     string              // i18n catalog language for server-rendered text
+	location *time.Location      // time zone for server-rendered timestamps
 	rooms    map[uuid.UUID]bool  // conversation IDs this client is subscribed to
 	userSub  pubsub.Subscription // subscription for user-specific events
 	mu       sync.RWMutex
 	logger   *slog.Logger
+	ctx      context.Context // connection-scoped, cancelled on unregister
 	cancel   context.CancelFunc
+	admitted bool // holds an admission slot until it authenticates
+
+	// Heartbeat: ping every pingEvery, drop the connection after pongWait of silence
+	pingEvery time.Duration
+	pongWait  time.Duration
+
+	limiter *eventLimiter // nil if unlimited
+
+	// Frames at least this big are compressed, if the connection negotiated it
+	compressMinSize int
+
+	// Highest room event sequence sent on this connection, see replayBuffer
+	lastSeq atomic.Uint64
 }
 
 // NewClient creates a new client
 func NewClient(hub *Hub, conn *websocket.Conn, logger *slog.Logger) *Client {
 	return &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		rooms:  make(map[uuid.UUID]bool),
-		logger: logger,
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan []byte, 256),
+		rooms:     make(map[uuid.UUID]bool),
+		logger:    logger,
+		pingEvery: hub.pingEvery,
+		pongWait:  hub.pongWait,
+		limiter:   newEventLimiter(hub.rateMessages, hub.rateSignals, hub.rateWindow),
+
+		compressMinSize: hub.compressMinSize,
 	}
 }
 
-// SetCancelFunc sets the context cancel function for cleanup
-func (c *Client) SetCancelFunc(cancel context.CancelFunc) {
+// closeWith sends a close frame with the given code and reason, then closes
+// the connection, which ends ReadPump
+func (c *Client) closeWith(code int, reason string) {
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	_ = c.conn.Close()
+}
+
+// SetContext sets the connection-scoped context and its cancel function.
+// The context is cancelled when the client unregisters, aborting any
+// in-flight DB or pubsub work started on the client's behalf.
+func (c *Client) SetContext(ctx context.Context, cancel context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
 	c.cancel = cancel
 }
 
+// Context returns the connection-scoped context
+func (c *Client) Context() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // SetUser sets the authenticated user info
 func (c *Client) SetUser(userID uuid.UUID, username string) {
 	c.mu.Lock()
@@ -64,6 +104,24 @@ func (c *Client) SetUser(userID uuid.UUID, username string) {
 	c.username = username
 }
 
+// SetLocale sets the language and time zone used for server-rendered text
+func (c *Client) SetLocale(lang string, location *time.Location) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lang = lang
+	c.location = location
+}
+
+// Locale returns the client's language and time zone
+func (c *Client) Locale() (string, *time.Location) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lang == "" {
+		return i18n.DefaultLanguage, time.UTC
+	}
+	return c.lang, c.location
+}
+
 // UserID returns the client's user ID
 func (c *Client) UserID() uuid.UUID {
 	c.mu.RLock()
@@ -78,6 +136,11 @@ func (c *Client) Username() string {
 	return c.username
 }
 
+// LastSeq returns the highest room event sequence sent on this connection
+func (c *Client) LastSeq() uint64 {
+	return c.lastSeq.Load()
+}
+
 // IsAuthenticated returns true if the client has authenticated
 func (c *Client) IsAuthenticated() bool {
 	c.mu.RLock()
@@ -125,9 +188,9 @@ func (c *Client) ReadPump(ctx context.Context) {
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 	c.conn.SetPongHandler(func(string) error {
-		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 		return nil
 	})
 
@@ -136,9 +199,14 @@ func (c *Client) ReadPump(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			_, message, err := c.conn.ReadMessage()
+			message, err := c.readFrame()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				if errors.Is(err, errMessageTooBig) {
+					c.logger.Warn("websocket message too big", "user_id", c.UserID())
+					c.closeWith(websocket.CloseMessageTooBig, "message too big")
+				} else if isHeartbeatTimeout(err) {
+					c.logger.Info("websocket heartbeat timed out", "user_id", c.UserID())
+				} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					c.logger.Warn("websocket read error", "error", err, "user_id", c.userID)
 				}
 				return
@@ -159,7 +227,7 @@ func (c *Client) ReadPump(ctx context.Context) {
 
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Client) WritePump(ctx context.Context) {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.pingEvery)
 	defer func() {
 		ticker.Stop()
 		_ = c.conn.Close()
@@ -177,20 +245,20 @@ func (c *Client) WritePump(ctx context.Context) {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			_, _ = w.Write(message)
-
-			// Add queued messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				_, _ = w.Write([]byte{'\n'})
-				_, _ = w.Write(<-c.send)
+			// Add queued messages to the current websocket message. The
+			// frame is built up front so its size decides compression;
+			// message may be shared with other clients, so copy before
+			// appending.
+			frame := message
+			if n := len(c.send); n > 0 {
+				frame = append([]byte(nil), message...)
+				for i := 0; i < n; i++ {
+					frame = append(frame, '\n')
+					frame = append(frame, <-c.send...)
+				}
 			}
 
-			if err := w.Close(); err != nil {
+			if err := c.writeFrame(frame); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -211,6 +279,9 @@ func (c *Client) Send(msg *Message) error {
 
 	select {
 	case c.send <- data:
+		if msg.Seq > c.lastSeq.Load() {
+			c.lastSeq.Store(msg.Seq)
+		}
 	default:
 		// Buffer full, drop message
 		c.logger.Warn("client send buffer full, dropping message", "user_id", c.userID)
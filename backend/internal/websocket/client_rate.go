@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/observer/teatime/internal/webrtc"
+	"golang.org/x/time/rate"
+)
+
+// Default per-connection rate limits, each a token bucket refilled evenly
+// over the window. Call signaling gets its own, larger bucket because trickle
+// ICE sends a burst of candidates whenever a call is set up.
+const (
+	DefaultClientRateMessages = 20
+	DefaultClientRateSignals  = 100
+	DefaultClientRateWindow   = 10 * time.Second
+)
+
+// chatEvents share the chat bucket
+var chatEvents = map[string]bool{
+	EventTypeMessageSend: true,
+	EventTypeTypingStart: true,
+	EventTypeTypingStop:  true,
+}
+
+// signalingEvents share the call signaling bucket
+var signalingEvents = map[string]bool{
+	webrtc.EventTypeCallJoin:               true,
+	webrtc.EventTypeCallLeave:              true,
+	webrtc.EventTypeCallOffer:              true,
+	webrtc.EventTypeCallAnswer:             true,
+	webrtc.EventTypeCallICECandidate:       true,
+	webrtc.EventTypeCallDeclined:           true,
+	webrtc.EventTypeCallReady:              true,
+	webrtc.EventTypeCallMuteUpdate:         true,
+	webrtc.EventTypeCallHeartbeat:          true,
+	webrtc.EventTypeSFUJoin:                true,
+	webrtc.EventTypeSFUOffer:               true,
+	webrtc.EventTypeSFUAnswer:              true,
+	webrtc.EventTypeSFUCandidate:           true,
+	webrtc.EventTypeSFULeave:               true,
+	webrtc.EventTypeCallScreenShareStarted: true,
+	webrtc.EventTypeCallScreenShareStopped: true,
+	webrtc.EventTypeCallRaiseHand:          true,
+	webrtc.EventTypeCallLowerHand:          true,
+	webrtc.EventTypeCallForceMute:          true,
+}
+
+// eventLimiter throttles the events one connection sends. Unlike the flood
+// guard it never mutes: an event over the limit is just dropped.
+type eventLimiter struct {
+	chat   *rate.Limiter
+	signal *rate.Limiter
+}
+
+// newEventLimiter allows up to chatMax chat events and signalMax signaling
+// events per window; a max of 0 leaves that kind unlimited
+func newEventLimiter(chatMax, signalMax int, window time.Duration) *eventLimiter {
+	return &eventLimiter{
+		chat:   newBucket(chatMax, window),
+		signal: newBucket(signalMax, window),
+	}
+}
+
+func newBucket(max int, window time.Duration) *rate.Limiter {
+	if max <= 0 || window <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(float64(max)/window.Seconds()), max)
+}
+
+// Allow reports whether an event of this type sent at now is within limits
+func (l *eventLimiter) Allow(eventType string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	var bucket *rate.Limiter
+	switch {
+	case chatEvents[eventType]:
+		bucket = l.chat
+	case signalingEvents[eventType]:
+		bucket = l.signal
+	}
+	return bucket == nil || bucket.AllowN(now, 1)
+}
+
+// SetClientRateLimits configures per-connection throttling: each connection
+// may send maxMessages chat messages and typing events, and maxSignals call
+// signaling events, per window. Events over the limit are answered with a
+// rate_limited error and dropped. Applies to connections opened afterwards.
+func (h *Hub) SetClientRateLimits(maxMessages, maxSignals int, window time.Duration) {
+	h.rateMessages = maxMessages
+	h.rateSignals = maxSignals
+	h.rateWindow = window
+}
+
+// allowEvent checks the client's rate limit for an incoming event, telling
+// the client when it's dropped
+func (h *Hub) allowEvent(client *Client, eventType string) bool {
+	if client.limiter.Allow(eventType, time.Now()) {
+		return true
+	}
+	client.sendError("rate_limited", "Too many events, slow down")
+	return false
+}
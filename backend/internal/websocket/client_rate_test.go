@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/observer/teatime/internal/webrtc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLimiter_Burst(t *testing.T) {
+	l := newEventLimiter(20, 100, 10*time.Second)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		require.True(t, l.Allow(EventTypeMessageSend, now), "send %d is within the burst", i+1)
+	}
+	assert.False(t, l.Allow(EventTypeMessageSend, now), "the 21st send in the same instant is dropped")
+	assert.False(t, l.Allow(EventTypeTypingStart, now), "typing shares the chat bucket")
+
+	assert.True(t, l.Allow(webrtc.EventTypeCallICECandidate, now), "signaling has its own bucket")
+	assert.True(t, l.Allow(EventTypeAuth, now), "unlisted events aren't limited")
+
+	assert.True(t, l.Allow(EventTypeMessageSend, now.Add(500*time.Millisecond)), "a token refills every half second")
+}
+
+func TestEventLimiter_Sustained(t *testing.T) {
+	l := newEventLimiter(20, 100, 10*time.Second)
+	now := time.Now()
+
+	// Drain the burst, then send steadily under the refill rate of 2/s
+	for i := 0; i < 20; i++ {
+		l.Allow(EventTypeMessageSend, now)
+	}
+	for i := 1; i <= 100; i++ {
+		require.True(t, l.Allow(EventTypeMessageSend, now.Add(time.Duration(i)*600*time.Millisecond)), "steady send %d", i)
+	}
+
+	// Steady sending under the rate let the bucket refill. Sending at twice
+	// the rate for 10s then gets the full burst plus 2/s through, no more.
+	start := now.Add(61 * time.Second)
+	allowed := 0
+	for i := 0; i < 40; i++ {
+		if l.Allow(EventTypeMessageSend, start.Add(time.Duration(i)*250*time.Millisecond)) {
+			allowed++
+		}
+	}
+	assert.InDelta(t, 20+20, allowed, 2)
+}
+
+func TestEventLimiter_ZeroDisables(t *testing.T) {
+	l := newEventLimiter(0, 0, 10*time.Second)
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		require.True(t, l.Allow(EventTypeMessageSend, now))
+		require.True(t, l.Allow(webrtc.EventTypeSFUCandidate, now))
+	}
+
+	var unlimited *eventLimiter
+	assert.True(t, unlimited.Allow(EventTypeMessageSend, now))
+}
+
+func TestHub_HandleMessage_DropsRateLimitedEvents(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	client.limiter = newEventLimiter(2, 2, time.Minute)
+
+	// Typing with no payload is ignored silently, so only limit errors show up
+	for i := 0; i < 3; i++ {
+		hub.HandleMessage(client, &Message{Type: EventTypeTypingStart})
+	}
+
+	require.Len(t, client.send, 1, "only the over-limit event gets a reply")
+	var env Message
+	require.NoError(t, json.Unmarshal(<-client.send, &env))
+	assert.Equal(t, EventTypeError, env.Type)
+	var p ErrorPayload
+	require.NoError(t, json.Unmarshal(env.Payload, &p))
+	assert.Equal(t, "rate_limited", p.Code)
+}
@@ -1,11 +1,14 @@
 package websocket
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -222,3 +225,61 @@ func TestClient_SendError(t *testing.T) {
 		t.Fatal("error message was not queued")
 	}
 }
+
+// =============================================================================
+// Context Tests
+// =============================================================================
+
+func TestClient_Context_DefaultsToBackground(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := &Client{
+		send:   make(chan []byte, 256),
+		rooms:  make(map[uuid.UUID]bool),
+		logger: logger,
+	}
+
+	require.NotNil(t, client.Context())
+	assert.NoError(t, client.Context().Err())
+}
+
+func TestHub_Unregister_CancelsClientContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+	client := &Client{
+		hub:    hub,
+		send:   make(chan []byte, 256),
+		rooms:  make(map[uuid.UUID]bool),
+		logger: logger,
+	}
+
+	ctx, cancel := context.WithCancel(hub.Context())
+	client.SetContext(ctx, cancel)
+
+	// Simulate in-flight handler work waiting on the client context
+	done := make(chan error, 1)
+	go func() {
+		<-client.Context().Done()
+		done <- client.Context().Err()
+	}()
+
+	hub.handleUnregister(client)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("in-flight work was not cancelled on disconnect")
+	}
+}
+
+func TestHub_Context_DerivedFromRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	go hub.Run(runCtx)
+	require.Eventually(t, func() bool { return hub.Context() == runCtx }, time.Second, 10*time.Millisecond)
+
+	stop()
+	assert.ErrorIs(t, hub.Context().Err(), context.Canceled)
+}
@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"compress/flate"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Defaults for permessage-deflate compression
+const (
+	DefaultCompressionLevel   = flate.BestSpeed
+	DefaultCompressionMinSize = 512 // bytes; smaller frames cost more to deflate than they save
+)
+
+// errMessageTooBig is returned by readFrame for a message that inflates past
+// maxMessageSize
+var errMessageTooBig = errors.New("websocket: message too big")
+
+// SetCompression configures permessage-deflate: when enabled it is offered
+// to connecting clients, and frames of at least minSize bytes are sent
+// compressed at level (flate.HuffmanOnly to flate.BestCompression). Invalid
+// values keep the defaults.
+func (h *Hub) SetCompression(enabled bool, level, minSize int) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = DefaultCompressionLevel
+	}
+	if minSize < 0 {
+		minSize = DefaultCompressionMinSize
+	}
+	h.compress = enabled
+	h.compressLevel = level
+	h.compressMinSize = minSize
+}
+
+// upgrade upgrades an HTTP request to a WebSocket connection, negotiating
+// compression if it's enabled and the client supports it
+func (h *Hub) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	u := upgrader
+	u.EnableCompression = h.compress
+	conn, err := u.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.compress {
+		_ = conn.SetCompressionLevel(h.compressLevel)
+	}
+	return conn, nil
+}
+
+// readFrame reads the next message, capping its size after decompression.
+// The connection's read limit only counts bytes on the wire, so a small
+// compressed frame could otherwise inflate without bound.
+func (c *Client) readFrame() ([]byte, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMessageSize {
+		return nil, errMessageTooBig
+	}
+	return data, nil
+}
+
+// writeFrame sends data as one text frame, compressed if compression was
+// negotiated and the frame is big enough to be worth it
+func (c *Client) writeFrame(data []byte) error {
+	c.conn.EnableWriteCompression(len(data) >= c.compressMinSize)
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
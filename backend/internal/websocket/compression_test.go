@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingConn counts the bytes read off the wire
+type countingConn struct {
+	net.Conn
+	n *atomic.Int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// dialPump starts a server whose clients are sent frames through the write
+// pump, and dials it offering compression. It returns the connection, the
+// client's send channel and a count of bytes received.
+func dialPump(t *testing.T, hub *Hub) (*websocket.Conn, chan []byte, *atomic.Int64) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	sends := make(chan chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := hub.upgrade(w, r)
+		if err != nil {
+			return
+		}
+		client := NewClient(hub, conn, slog.Default())
+		sends <- client.send
+		client.WritePump(ctx)
+	}))
+	t.Cleanup(srv.Close)
+
+	received := &atomic.Int64{}
+	dialer := websocket.Dialer{
+		EnableCompression: true,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			return countingConn{Conn: conn, n: received}, err
+		},
+	}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if hub.compress {
+		assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+	} else {
+		assert.Empty(t, resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	return conn, <-sends, received
+}
+
+// roundTrip sends frame through the pump and returns what the peer read
+// along with how many bytes crossed the wire for it
+func roundTrip(t *testing.T, conn *websocket.Conn, send chan []byte, received *atomic.Int64, frame []byte) ([]byte, int64) {
+	t.Helper()
+	before := received.Load()
+	send <- frame
+	_, got, err := conn.ReadMessage()
+	require.NoError(t, err)
+	return got, received.Load() - before
+}
+
+func TestWritePump_CompressesLargeFrames(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), slog.Default())
+	hub.SetCompression(true, DefaultCompressionLevel, 256)
+	conn, send, received := dialPump(t, hub)
+
+	large := []byte(`{"type":"message.batch","payload":"` + strings.Repeat("hello teatime ", 2000) + `"}`)
+	got, wire := roundTrip(t, conn, send, received, large)
+	assert.Equal(t, large, got)
+	assert.Less(t, wire, int64(len(large)/4), "a repetitive frame shrinks on the wire")
+
+	small := []byte(`{"type":"typing","payload":{"x":"` + strings.Repeat("a", 100) + `"}}`)
+	got, wire = roundTrip(t, conn, send, received, small)
+	assert.Equal(t, small, got)
+	assert.GreaterOrEqual(t, wire, int64(len(small)), "frames under the threshold go uncompressed")
+}
+
+func TestWritePump_CompressionDisabled(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), slog.Default())
+	hub.SetCompression(false, DefaultCompressionLevel, 0)
+	conn, send, received := dialPump(t, hub)
+
+	large := []byte(strings.Repeat("x", 4096))
+	got, wire := roundTrip(t, conn, send, received, large)
+	assert.Equal(t, large, got)
+	assert.GreaterOrEqual(t, wire, int64(len(large)))
+}
+
+func TestHub_SetCompressionKeepsDefaultsForInvalidValues(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), slog.Default())
+
+	hub.SetCompression(true, 42, -1)
+	assert.Equal(t, DefaultCompressionLevel, hub.compressLevel)
+	assert.Equal(t, DefaultCompressionMinSize, hub.compressMinSize)
+}
+
+func TestReadPump_ClosesOnMessageInflatingPastLimit(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), slog.Default())
+	hub.SetCompression(true, DefaultCompressionLevel, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(NewHandler(hub, slog.Default()))
+	defer srv.Close()
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A megabyte of one byte deflates to well under the wire limit
+	conn.EnableWriteCompression(true)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("a", 1<<20))))
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err = conn.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "got %v", err)
+}
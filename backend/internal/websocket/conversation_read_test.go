@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubBroadcaster_NotifyConversationRead_TargetsUser(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+	b := NewPubSubBroadcaster(ps)
+	ctx := context.Background()
+
+	userID, convID, lastReadID := uuid.New(), uuid.New(), uuid.New()
+	readAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	got := make(chan *pubsub.Message, 1)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.User(userID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		got <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	roomMsgs := make(chan *pubsub.Message, 1)
+	roomSub, err := ps.Subscribe(ctx, pubsub.Topics.Room(convID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		roomMsgs <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = roomSub.Unsubscribe() }()
+
+	state := &domain.UnreadState{ConversationID: convID, UnreadCount: 4, LastReadAt: &readAt, LastReadMessageID: &lastReadID}
+	require.NoError(t, b.NotifyConversationRead(ctx, userID, state))
+
+	select {
+	case msg := <-got:
+		assert.Equal(t, EventTypeConversationRead, msg.Type)
+		var p ConversationReadPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, convID, p.ConversationID)
+		assert.Equal(t, 4, p.UnreadCount)
+		require.NotNil(t, p.LastReadAt)
+		assert.True(t, readAt.Equal(*p.LastReadAt))
+		assert.Equal(t, &lastReadID, p.LastReadMessageID)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected conversation.read on the user's topic")
+	}
+
+	select {
+	case <-roomMsgs:
+		t.Fatal("read state is private to the user, not broadcast to the room")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPubSubBroadcaster_NotifyMessageStarred_TargetsUser(t *testing.T) {
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+	b := NewPubSubBroadcaster(ps)
+	ctx := context.Background()
+
+	userID, convID, messageID := uuid.New(), uuid.New(), uuid.New()
+
+	got := make(chan *pubsub.Message, 2)
+	sub, err := ps.Subscribe(ctx, pubsub.Topics.User(userID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		got <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = sub.Unsubscribe() }()
+
+	roomMsgs := make(chan *pubsub.Message, 2)
+	roomSub, err := ps.Subscribe(ctx, pubsub.Topics.Room(convID.String()), func(ctx context.Context, msg *pubsub.Message) {
+		roomMsgs <- msg
+	})
+	require.NoError(t, err)
+	defer func() { _ = roomSub.Unsubscribe() }()
+
+	receive := func(want string) MessageStarPayload {
+		t.Helper()
+		select {
+		case msg := <-got:
+			assert.Equal(t, want, msg.Type)
+			var p MessageStarPayload
+			require.NoError(t, json.Unmarshal(msg.Payload, &p))
+			return p
+		case <-time.After(200 * time.Millisecond):
+			t.Fatalf("expected %s on the user's topic", want)
+			return MessageStarPayload{}
+		}
+	}
+
+	require.NoError(t, b.NotifyMessageStarred(ctx, userID, messageID, &convID, true))
+	starred := receive(EventTypeMessageStarred)
+	assert.Equal(t, messageID, starred.MessageID)
+	assert.Equal(t, &convID, starred.ConversationID)
+
+	require.NoError(t, b.NotifyMessageStarred(ctx, userID, messageID, nil, false))
+	unstarred := receive(EventTypeMessageUnstarred)
+	assert.Equal(t, messageID, unstarred.MessageID)
+	assert.Nil(t, unstarred.ConversationID)
+
+	select {
+	case <-roomMsgs:
+		t.Fatal("stars are personal, not broadcast to the room")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default flood thresholds: more than 30 messages in 10 seconds, summed across
+// all conversations, mutes the sender for a minute.
+const (
+	DefaultFloodMaxMessages = 30
+	DefaultFloodWindow      = 10 * time.Second
+	DefaultFloodCooldown    = time.Minute
+)
+
+// FloodChecker counts message sends against the cross-conversation flood
+// limit. The Hub implements it, so sends over REST share one budget with
+// sends over the socket.
+type FloodChecker interface {
+	// CheckFlood records a send and reports whether it may proceed
+	CheckFlood(userID uuid.UUID) bool
+}
+
+// floodState is one user's recent send times and any active mute
+type floodState struct {
+	sends      []time.Time
+	mutedUntil time.Time
+}
+
+// floodGuard detects users sending sustained bursts of messages and mutes
+// them for a cooldown. Unlike the per-request rate limiter it counts sends
+// across every conversation, so spreading a flood over rooms doesn't help.
+type floodGuard struct {
+	mu          sync.Mutex
+	maxMessages int
+	window      time.Duration
+	cooldown    time.Duration
+	users       map[uuid.UUID]*floodState
+	now         func() time.Time
+}
+
+func newFloodGuard(maxMessages int, window, cooldown time.Duration) *floodGuard {
+	return &floodGuard{
+		maxMessages: maxMessages,
+		window:      window,
+		cooldown:    cooldown,
+		users:       make(map[uuid.UUID]*floodState),
+		now:         time.Now,
+	}
+}
+
+// Allow records a send attempt. It returns false while the user is muted;
+// justMuted is true on the send that crossed the threshold.
+func (g *floodGuard) Allow(userID uuid.UUID) (allowed, justMuted bool, mutedUntil time.Time) {
+	if g.maxMessages <= 0 {
+		return true, false, time.Time{}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	st, ok := g.users[userID]
+	if !ok {
+		st = &floodState{}
+		g.users[userID] = st
+	}
+
+	if now.Before(st.mutedUntil) {
+		return false, false, st.mutedUntil
+	}
+
+	// Drop sends that fell out of the window
+	cutoff := now.Add(-g.window)
+	kept := st.sends[:0]
+	for _, t := range st.sends {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	st.sends = append(kept, now)
+
+	if len(st.sends) > g.maxMessages {
+		st.mutedUntil = now.Add(g.cooldown)
+		st.sends = nil
+		return false, true, st.mutedUntil
+	}
+	return true, false, time.Time{}
+}
+
+// MutedUntil returns when the user's mute lifts, or the zero time if not muted
+func (g *floodGuard) MutedUntil(userID uuid.UUID) time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if st, ok := g.users[userID]; ok && g.now().Before(st.mutedUntil) {
+		return st.mutedUntil
+	}
+	return time.Time{}
+}
+
+// Clear lifts any mute and forgets the user's send history.
+// Returns false if the user wasn't muted.
+func (g *floodGuard) Clear(userID uuid.UUID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	st, ok := g.users[userID]
+	if !ok {
+		return false
+	}
+	wasMuted := g.now().Before(st.mutedUntil)
+	delete(g.users, userID)
+	return wasMuted
+}
+
+// Cleanup forgets users with no recent sends and no active mute
+func (g *floodGuard) Cleanup() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	cutoff := now.Add(-g.window)
+	for id, st := range g.users {
+		if now.Before(st.mutedUntil) {
+			continue
+		}
+		if len(st.sends) == 0 || !st.sends[len(st.sends)-1].After(cutoff) {
+			delete(g.users, id)
+		}
+	}
+}
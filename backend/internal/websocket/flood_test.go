@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a controllable time source for flood tests
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestFloodGuard(max int, window, cooldown time.Duration) (*floodGuard, *fakeClock) {
+	clock := &fakeClock{t: time.Now()}
+	g := newFloodGuard(max, window, cooldown)
+	g.now = clock.Now
+	return g, clock
+}
+
+// =============================================================================
+// Flood Guard Tests
+// =============================================================================
+
+func TestFloodGuard_CrossingThresholdMutes(t *testing.T) {
+	g, _ := newTestFloodGuard(3, 10*time.Second, time.Minute)
+	userID := uuid.New()
+
+	for i := 0; i < 3; i++ {
+		allowed, _, _ := g.Allow(userID)
+		require.True(t, allowed, "send %d should be under the threshold", i+1)
+	}
+
+	allowed, justMuted, until := g.Allow(userID)
+	assert.False(t, allowed)
+	assert.True(t, justMuted)
+	assert.False(t, until.IsZero())
+
+	// Still muted; only the crossing send reports justMuted
+	allowed, justMuted, _ = g.Allow(userID)
+	assert.False(t, allowed)
+	assert.False(t, justMuted)
+}
+
+func TestFloodGuard_CooldownLiftsMute(t *testing.T) {
+	g, clock := newTestFloodGuard(2, 10*time.Second, time.Minute)
+	userID := uuid.New()
+
+	g.Allow(userID)
+	g.Allow(userID)
+	allowed, _, _ := g.Allow(userID)
+	require.False(t, allowed)
+
+	clock.Advance(59 * time.Second)
+	allowed, _, _ = g.Allow(userID)
+	assert.False(t, allowed, "mute should hold until the cooldown ends")
+
+	clock.Advance(2 * time.Second)
+	allowed, _, _ = g.Allow(userID)
+	assert.True(t, allowed, "mute should lift after the cooldown")
+	assert.True(t, g.MutedUntil(userID).IsZero())
+}
+
+func TestFloodGuard_SlowSendsNeverMute(t *testing.T) {
+	g, clock := newTestFloodGuard(2, 10*time.Second, time.Minute)
+	userID := uuid.New()
+
+	for i := 0; i < 10; i++ {
+		allowed, _, _ := g.Allow(userID)
+		require.True(t, allowed)
+		clock.Advance(6 * time.Second)
+	}
+}
+
+func TestFloodGuard_UsersCountedIndependently(t *testing.T) {
+	g, _ := newTestFloodGuard(1, 10*time.Second, time.Minute)
+	alice, bob := uuid.New(), uuid.New()
+
+	g.Allow(alice)
+	allowed, _, _ := g.Allow(alice)
+	require.False(t, allowed)
+
+	allowed, _, _ = g.Allow(bob)
+	assert.True(t, allowed)
+}
+
+func TestFloodGuard_ClearLiftsMute(t *testing.T) {
+	g, _ := newTestFloodGuard(1, 10*time.Second, time.Minute)
+	userID := uuid.New()
+
+	g.Allow(userID)
+	g.Allow(userID)
+	require.False(t, g.MutedUntil(userID).IsZero())
+
+	assert.True(t, g.Clear(userID))
+	allowed, _, _ := g.Allow(userID)
+	assert.True(t, allowed)
+	assert.False(t, g.Clear(uuid.New()), "clearing an unmuted user reports false")
+}
+
+func TestFloodGuard_ZeroDisables(t *testing.T) {
+	g, _ := newTestFloodGuard(0, 10*time.Second, time.Minute)
+	userID := uuid.New()
+
+	for i := 0; i < 100; i++ {
+		allowed, _, _ := g.Allow(userID)
+		require.True(t, allowed)
+	}
+}
+
+// =============================================================================
+// Hub Flood Tests
+// =============================================================================
+
+func TestHub_CheckFlood_EmitsThrottledEvent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	hub := NewHub(nil, nil, nil, nil, ps, logger)
+	hub.SetFloodLimits(1, 10*time.Second, time.Minute)
+
+	userID := uuid.New()
+	events := make(chan *pubsub.Message, 4)
+	_, err := ps.Subscribe(hub.Context(), pubsub.Topics.User(userID.String()), func(_ context.Context, msg *pubsub.Message) {
+		events <- msg
+	})
+	require.NoError(t, err)
+
+	assert.True(t, hub.CheckFlood(userID))
+	assert.False(t, hub.CheckFlood(userID))
+
+	select {
+	case msg := <-events:
+		assert.Equal(t, EventTypeUserThrottled, msg.Type)
+		var p UserThrottledPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.True(t, p.Throttled)
+		assert.NotNil(t, p.Until)
+	case <-time.After(time.Second):
+		t.Fatal("expected user.throttled event")
+	}
+
+	assert.True(t, hub.ClearThrottle(userID, uuid.New()))
+	assert.True(t, hub.CheckFlood(userID))
+}
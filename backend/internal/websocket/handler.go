@@ -2,8 +2,10 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -33,21 +35,52 @@ func NewHandler(hub *Hub, logger *slog.Logger) *Handler {
 
 // ServeHTTP upgrades HTTP to WebSocket and handles the connection
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.hub.upgrade(w, r)
 	if err != nil {
 		h.logger.Error("websocket upgrade failed", "error", err)
 		return
 	}
 
+	// During a reconnect storm, turn the connection away with a retry hint
+	// rather than letting it queue up behind the auths already in flight
+	if !h.hub.admission.TryAcquire() {
+		h.shed(conn)
+		return
+	}
+
 	client := NewClient(h.hub, conn, h.logger)
+	client.admitted = true
 	h.hub.Register(client)
 
-	// Use a dedicated context for the WebSocket connection lifecycle
+	// Use a dedicated context for the WebSocket connection lifecycle, derived
+	// from the hub's run context so hub shutdown also cancels client work.
 	// The request context gets cancelled when ServeHTTP returns after upgrade
-	ctx, cancel := context.WithCancel(context.Background())
-	client.SetCancelFunc(cancel)
+	ctx, cancel := context.WithCancel(h.hub.Context())
+	client.SetContext(ctx, cancel)
+
+	// Don't let a socket that never authenticates hold its admission slot
+	if deadline := h.hub.startAuthDeadline(client); deadline != nil {
+		defer deadline.Stop()
+	}
 
 	// Start client goroutines
 	go client.WritePump(ctx)
 	client.ReadPump(ctx) // Block here until client disconnects
 }
+
+// shed sends server.busy with a jittered retry delay and closes the connection
+func (h *Handler) shed(conn *websocket.Conn) {
+	defer conn.Close()
+
+	retryAfter := h.hub.admission.RetryAfter()
+	msg, _ := NewMessage(EventTypeServerBusy, ServerBusyPayload{RetryAfter: retryAfter.Seconds()})
+	data, _ := json.Marshal(msg)
+
+	deadline := time.Now().Add(writeWait)
+	_ = conn.SetWriteDeadline(deadline)
+	_ = conn.WriteMessage(websocket.TextMessage, data)
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "server busy"), deadline)
+
+	h.logger.Debug("shed websocket connection", "retry_after", retryAfter)
+}
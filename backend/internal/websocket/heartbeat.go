@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Defaults for the connection heartbeat
+const (
+	DefaultPingInterval = 30 * time.Second
+	DefaultPongWait     = 60 * time.Second
+)
+
+// SetHeartbeat configures liveness checks: every connection is pinged each
+// pingInterval and dropped if nothing, pong included, arrives within
+// pongWait. A ping interval that isn't shorter than pongWait is reduced so a
+// healthy peer always has a chance to answer.
+func (h *Hub) SetHeartbeat(pingInterval, pongWait time.Duration) {
+	if pongWait <= 0 {
+		pongWait = DefaultPongWait
+	}
+	if pingInterval <= 0 || pingInterval >= pongWait {
+		pingInterval = pongWait * 9 / 10
+	}
+	h.pingEvery = pingInterval
+	h.pongWait = pongWait
+}
+
+// isHeartbeatTimeout reports whether a read failed because the peer stopped
+// answering pings
+func isHeartbeatTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_HeartbeatDropsSilentConnections(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+	hub.SetHeartbeat(30*time.Millisecond, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(NewHandler(hub, logger))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// gorilla answers pings while reading, so this peer stays alive
+	alive, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer alive.Close()
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 1 }, time.Second, 5*time.Millisecond)
+
+	// A half-open peer never reads, so never pongs
+	silent, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer silent.Close()
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 2 }, time.Second, 5*time.Millisecond)
+
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 1 }, time.Second, 10*time.Millisecond,
+		"the silent connection is unregistered once pongWait passes")
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, hub.admission.Pending(), "a peer answering pings outlives several pong windows")
+}
+
+func TestHub_SetHeartbeatKeepsPingsInsidePongWait(t *testing.T) {
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), slog.Default())
+
+	hub.SetHeartbeat(10*time.Second, 20*time.Second)
+	assert.Equal(t, 10*time.Second, hub.pingEvery)
+	assert.Equal(t, 20*time.Second, hub.pongWait)
+
+	hub.SetHeartbeat(time.Minute, 20*time.Second)
+	assert.Equal(t, 18*time.Second, hub.pingEvery, "pings must land before the read deadline")
+
+	hub.SetHeartbeat(0, 0)
+	assert.Equal(t, DefaultPongWait, hub.pongWait)
+	assert.Less(t, hub.pingEvery, hub.pongWait)
+}
@@ -3,18 +3,26 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/observer/teatime/internal/auth"
 	"github.com/observer/teatime/internal/database"
 	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/i18n"
+	"github.com/observer/teatime/internal/media"
 	"github.com/observer/teatime/internal/pubsub"
 	"github.com/observer/teatime/internal/webrtc"
 )
 
+// thumbnailURLExpiry is how long signed thumbnail URLs in broadcasts stay valid
+const thumbnailURLExpiry = time.Hour
+
 // Hub maintains the set of active clients and broadcasts messages
 type Hub struct {
 	// Registered clients by user ID (one user can have multiple connections)
@@ -37,6 +45,9 @@ type Hub struct {
 	convRepo       *database.ConversationRepository
 	userRepo       *database.UserRepository
 	attachmentRepo *database.AttachmentRepository
+	urlSigner      media.URLSigner
+	linkPreviews   *LinkPreviewWorker
+	mentions       *Mentions
 	pubsub         pubsub.PubSub
 	callHandler    *webrtc.CallHandler
 	sfuHandler     *webrtc.SFUHandler
@@ -44,23 +55,89 @@ type Hub struct {
 
 	// PubSub subscriptions for room-level events
 	roomSubs map[uuid.UUID]pubsub.Subscription
+
+	// Run context; client contexts are derived from it
+	ctx context.Context
+
+	// Delivered messages awaiting a message.ack, per user
+	pending *pendingQueue
+
+	// Cross-conversation flood detection for message sends
+	flood *floodGuard
+
+	// Deferred call cleanup for connections that may come back
+	callGrace *callGrace
+
+	// Load shedding for connections that haven't authenticated yet, and how
+	// long they get to do so
+	admission   *admissionGate
+	authTimeout time.Duration
+
+	// The conversation each user is currently typing in
+	typing *typingTargets
+
+	// Last seen tracking, written on final disconnect and every lastSeenEvery
+	lastSeen      LastSeenStore
+	lastSeenEvery time.Duration
+
+	// Connection heartbeat handed to each new client, see SetHeartbeat
+	pingEvery time.Duration
+	pongWait  time.Duration
+
+	// Per-connection event limits, see SetClientRateLimits
+	rateMessages int
+	rateSignals  int
+	rateWindow   time.Duration
+
+	// Longest message body accepted, see SetMessageLimit
+	maxMessageLen int
+
+	// permessage-deflate settings, see SetCompression
+	compress        bool
+	compressLevel   int
+	compressMinSize int
+
+	// Recent room events for clients that reconnect, nil if disabled
+	replay *replayBuffer
 }
 
 // NewHub creates a new Hub
 func NewHub(authService *auth.Service, convRepo *database.ConversationRepository, userRepo *database.UserRepository, attachmentRepo *database.AttachmentRepository, ps pubsub.PubSub, logger *slog.Logger) *Hub {
-	return &Hub{
-		clients:        make(map[uuid.UUID]map[*Client]bool),
-		rooms:          make(map[uuid.UUID]map[*Client]bool),
-		register:       make(chan *Client),
-		unregister:     make(chan *Client),
-		authService:    authService,
-		convRepo:       convRepo,
-		userRepo:       userRepo,
-		attachmentRepo: attachmentRepo,
-		pubsub:         ps,
-		roomSubs:       make(map[uuid.UUID]pubsub.Subscription),
-		logger:         logger,
-	}
+	h := &Hub{
+		clients:         make(map[uuid.UUID]map[*Client]bool),
+		rooms:           make(map[uuid.UUID]map[*Client]bool),
+		register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		authService:     authService,
+		convRepo:        convRepo,
+		userRepo:        userRepo,
+		attachmentRepo:  attachmentRepo,
+		pubsub:          ps,
+		roomSubs:        make(map[uuid.UUID]pubsub.Subscription),
+		logger:          logger,
+		ctx:             context.Background(),
+		pending:         newPendingQueue(),
+		flood:           newFloodGuard(DefaultFloodMaxMessages, DefaultFloodWindow, DefaultFloodCooldown),
+		callGrace:       newCallGrace(DefaultCallReconnectGrace),
+		admission:       newAdmissionGate(DefaultMaxPendingAuths, DefaultStormRetryAfter),
+		authTimeout:     DefaultAuthTimeout,
+		typing:          newTypingTargets(),
+		lastSeenEvery:   DefaultLastSeenHeartbeat,
+		pingEvery:       DefaultPingInterval,
+		pongWait:        DefaultPongWait,
+		rateMessages:    DefaultClientRateMessages,
+		rateSignals:     DefaultClientRateSignals,
+		rateWindow:      DefaultClientRateWindow,
+		maxMessageLen:   domain.DefaultMaxMessageLength,
+		compress:        true,
+		compressLevel:   DefaultCompressionLevel,
+		compressMinSize: DefaultCompressionMinSize,
+		replay:          newReplayBuffer(DefaultReplayBufferSize, DefaultReplayMaxAge),
+	}
+	if userRepo != nil {
+		h.lastSeen = userRepo
+	}
+	return h
 }
 
 // SetCallHandler sets the WebRTC call handler for processing call events
@@ -73,12 +150,99 @@ func (h *Hub) SetSFUHandler(sh *webrtc.SFUHandler) {
 	h.sfuHandler = sh
 }
 
+// SetAttachmentURLSigner lets new-message broadcasts carry signed thumbnail
+// URLs for image attachments
+func (h *Hub) SetAttachmentURLSigner(signer media.URLSigner) {
+	h.urlSigner = signer
+}
+
+// SetLinkPreviews queues links in sent messages for preview cards; nil
+// leaves messages without them
+func (h *Hub) SetLinkPreviews(w *LinkPreviewWorker) {
+	h.linkPreviews = w
+}
+
+// SetMentions records @mentions in sent messages and notifies those
+// mentioned; nil leaves mentions as plain text
+func (h *Hub) SetMentions(m *Mentions) {
+	h.mentions = m
+}
+
+// SetMessageLimit sets the longest message body accepted, in bytes; 0 keeps
+// the default
+func (h *Hub) SetMessageLimit(max int) {
+	if max > 0 {
+		h.maxMessageLen = max
+	}
+}
+
+// SetFloodLimits configures flood detection: sending more than maxMessages
+// within window mutes the user for cooldown. A maxMessages of 0 disables it.
+func (h *Hub) SetFloodLimits(maxMessages int, window, cooldown time.Duration) {
+	h.flood = newFloodGuard(maxMessages, window, cooldown)
+}
+
+// SetCallReconnectGrace configures how long a disconnected user stays in
+// their calls before being removed. A window of 0 leaves calls immediately.
+func (h *Hub) SetCallReconnectGrace(window time.Duration) {
+	h.callGrace = newCallGrace(window)
+}
+
+// SetAdmissionLimit configures reconnect-storm shedding: while maxPending
+// connections are still authenticating, new ones are told to retry after
+// retryAfter plus jitter. A maxPending of 0 disables shedding.
+func (h *Hub) SetAdmissionLimit(maxPending int, retryAfter time.Duration) {
+	h.admission = newAdmissionGate(maxPending, retryAfter)
+}
+
+// SetAuthTimeout sets how long a new connection has to authenticate before
+// it's closed and its admission slot released. 0 disables the deadline.
+func (h *Hub) SetAuthTimeout(timeout time.Duration) {
+	h.authTimeout = timeout
+}
+
+// startAuthDeadline closes the client if it hasn't authenticated within the
+// auth timeout. Stop the returned timer once the connection ends; it's nil
+// when there's no deadline.
+func (h *Hub) startAuthDeadline(client *Client) *time.Timer {
+	if h.authTimeout <= 0 {
+		return nil
+	}
+	return time.AfterFunc(h.authTimeout, func() {
+		if client.UserID() != uuid.Nil {
+			return
+		}
+		h.logger.Debug("closing connection that never authenticated", "remote_addr", client.conn.RemoteAddr())
+		h.finishAdmission(client)
+		client.closeWith(websocket.ClosePolicyViolation, "authentication timeout")
+	})
+}
+
 // Run starts the hub's main loop
 func (h *Hub) Run(ctx context.Context) {
+	h.mu.Lock()
+	h.ctx = ctx
+	h.mu.Unlock()
+
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+
+	var heartbeat <-chan time.Time
+	if h.lastSeenEvery > 0 {
+		ticker := time.NewTicker(h.lastSeenEvery)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-cleanup.C:
+			h.flood.Cleanup()
+			h.pending.Prune()
+		case <-heartbeat:
+			go h.touchLastSeen(h.GetOnlineUserIDs()...)
 		case client := <-h.register:
 			h.handleRegister(client)
 		case client := <-h.unregister:
@@ -87,6 +251,13 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
+// Context returns the hub's run context
+func (h *Hub) Context() context.Context {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ctx
+}
+
 // Register adds a client to the hub
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -105,9 +276,28 @@ func (h *Hub) handleRegister(client *Client) {
 	h.logger.Debug("client connected", "remote_addr", client.conn.RemoteAddr())
 }
 
+// finishAdmission releases the client's admission slot once it has
+// authenticated or disconnected, whichever happens first
+func (h *Hub) finishAdmission(client *Client) {
+	client.mu.Lock()
+	admitted := client.admitted
+	client.admitted = false
+	client.mu.Unlock()
+
+	if admitted {
+		h.admission.Release()
+	}
+}
+
 func (h *Hub) handleUnregister(client *Client) {
-	// Cleanup user subscription
+	// A connection that never authenticated frees its admission slot
+	h.finishAdmission(client)
+
+	// Cancel in-flight work and cleanup user subscription
 	client.mu.Lock()
+	if client.cancel != nil {
+		client.cancel()
+	}
 	if client.userSub != nil {
 		_ = client.userSub.Unsubscribe()
 		client.userSub = nil
@@ -118,6 +308,8 @@ func (h *Hub) handleUnregister(client *Client) {
 
 	userID := client.UserID()
 	username := client.Username()
+	var typingIn uuid.UUID
+	var wasTyping, wentOffline bool
 	if userID != uuid.Nil {
 		// Remove from user's client set
 		if clients, ok := h.clients[userID]; ok {
@@ -126,13 +318,16 @@ func (h *Hub) handleUnregister(client *Client) {
 				delete(clients, client)
 				if len(clients) == 0 {
 					delete(h.clients, userID)
-					// User is now offline - could broadcast presence here
+					wentOffline = true
+					typingIn, wasTyping = h.typing.Clear(userID)
 
 					// Clean up WebRTC participation for this user (Ghost User fix)
 					// This handles unexpected disconnects when the last client for a user disconnects.
+					// It waits out the reconnect grace so a blip doesn't drop the call.
 					if h.callHandler != nil {
-						// Assuming HandleDisconnect takes userID and username
-						h.callHandler.HandleDisconnect(context.Background(), userID, username)
+						h.callGrace.Schedule(userID, uuid.Nil, func() {
+							h.callHandler.HandleDisconnect(h.Context(), userID, username)
+						})
 					}
 					// SFU cleanup should also be handled if possible,
 					// but SFU interactions are often room-based and handled by room leave logic.
@@ -162,9 +357,21 @@ func (h *Hub) handleUnregister(client *Client) {
 
 	h.mu.Unlock()
 
-	// Clean up call participation for this user (they might be in active calls)
+	// Don't leave a user who went offline mid-sentence typing forever
+	if wasTyping {
+		h.broadcastTyping(typingIn, client, false)
+	}
+	if wentOffline {
+		go func() {
+			h.touchLastSeen(userID)
+			h.broadcastPresence(userID, username)
+		}()
+	}
+
+	// Clean up call participation for this user (they might be in active calls).
+	// The leave is deferred by the reconnect grace and cancelled if the user
+	// rejoins the room in time.
 	if userID != uuid.Nil {
-		ctx := context.Background()
 		sigCtx := &webrtc.SignalingContext{
 			UserID:   userID,
 			Username: username,
@@ -173,21 +380,26 @@ func (h *Hub) handleUnregister(client *Client) {
 		for _, roomID := range roomsForCallCleanup {
 			leavePayload := json.RawMessage(`{"room_id":"` + roomID.String() + `"}`)
 
-			// Clean up SFU participation
-			if h.sfuHandler != nil {
-				_ = h.sfuHandler.HandleSFULeave(ctx, sigCtx, leavePayload)
-			}
+			h.callGrace.Schedule(userID, roomID, func() {
+				// Client context is already cancelled; cleanup runs under the hub context
+				ctx := h.Context()
 
-			// Clean up P2P participation
-			if h.callHandler != nil {
-				_ = h.callHandler.HandleLeave(ctx, sigCtx, leavePayload)
-			}
+				// Clean up SFU participation
+				if h.sfuHandler != nil {
+					_ = h.sfuHandler.HandleSFULeave(ctx, sigCtx, leavePayload)
+				}
+
+				// Clean up P2P participation
+				if h.callHandler != nil {
+					_ = h.callHandler.HandleLeave(ctx, sigCtx, leavePayload)
+				}
+			})
 		}
 	}
 
 	// Unsubscribe from empty rooms (outside lock)
 	for _, roomID := range roomsToCheck {
-		h.unsubscribeFromRoom(roomID)
+		h.releaseRoom(roomID)
 	}
 
 	close(client.send)
@@ -196,6 +408,10 @@ func (h *Hub) handleUnregister(client *Client) {
 
 // HandleMessage processes incoming WebSocket messages
 func (h *Hub) HandleMessage(client *Client, msg *Message) {
+	if !h.allowEvent(client, msg.Type) {
+		return
+	}
+
 	switch msg.Type {
 	case EventTypeAuth:
 		h.handleAuth(client, msg.Payload)
@@ -211,6 +427,12 @@ func (h *Hub) HandleMessage(client *Client, msg *Message) {
 		h.handleTyping(client, msg.Payload, false)
 	case EventTypeReceiptRead:
 		h.handleReceiptRead(client, msg.Payload)
+	case EventTypeReceiptReadUpTo:
+		h.handleReceiptReadUpTo(client, msg.Payload)
+	case EventTypeMessageAck:
+		h.handleMessageAck(client, msg.Payload)
+	case EventTypeResume:
+		h.handleResume(client, msg.Payload)
 	// WebRTC call events
 	case webrtc.EventTypeCallJoin:
 		h.handleCallJoin(client, msg.Payload)
@@ -228,6 +450,8 @@ func (h *Hub) HandleMessage(client *Client, msg *Message) {
 		h.handleCallReady(client, msg.Payload)
 	case webrtc.EventTypeCallMuteUpdate:
 		h.handleCallMuteUpdate(client, msg.Payload)
+	case webrtc.EventTypeCallHeartbeat:
+		h.handleCallHeartbeat(client, msg.Payload)
 	// SFU group call events
 	case webrtc.EventTypeSFUJoin:
 		h.handleSFUJoin(client, msg.Payload)
@@ -239,6 +463,16 @@ func (h *Hub) HandleMessage(client *Client, msg *Message) {
 		h.handleSFUCandidate(client, msg.Payload)
 	case webrtc.EventTypeSFULeave:
 		h.handleSFULeave(client, msg.Payload)
+	case webrtc.EventTypeCallScreenShareStarted:
+		h.handleScreenShare(client, msg.Payload, true)
+	case webrtc.EventTypeCallScreenShareStopped:
+		h.handleScreenShare(client, msg.Payload, false)
+	case webrtc.EventTypeCallRaiseHand:
+		h.handleHand(client, msg.Payload, true)
+	case webrtc.EventTypeCallLowerHand:
+		h.handleHand(client, msg.Payload, false)
+	case webrtc.EventTypeCallForceMute:
+		h.handleForceMute(client, msg.Payload)
 	default:
 		client.sendError("unknown_event", "Unknown event type: "+msg.Type)
 	}
@@ -260,15 +494,22 @@ func (h *Hub) handleAuth(client *Client, payload json.RawMessage) {
 
 	// Set user info on client
 	client.SetUser(claims.UserID, claims.Username)
+	client.SetLocale(i18n.Match(p.Locale), i18n.LoadLocation(p.Timezone))
+	h.finishAdmission(client)
 
 	// Register client to user's connection set
 	h.mu.Lock()
+	cameOnline := len(h.clients[claims.UserID]) == 0
 	if h.clients[claims.UserID] == nil {
 		h.clients[claims.UserID] = make(map[*Client]bool)
 	}
 	h.clients[claims.UserID][client] = true
 	h.mu.Unlock()
 
+	if cameOnline {
+		go h.broadcastPresence(claims.UserID, claims.Username)
+	}
+
 	// Send success
 	msg, _ := NewMessage(EventTypeAuthSuccess, AuthSuccessPayload{
 		UserID:   claims.UserID,
@@ -278,7 +519,8 @@ func (h *Hub) handleAuth(client *Client, payload json.RawMessage) {
 
 	h.logger.Info("client authenticated", "user_id", claims.UserID, "username", claims.Username)
 
-	// Subscribe user to their personal event channel
+	// Subscribe user to their personal event channel. Unacked messages wait
+	// for a resume, which says what the client already has.
 	h.subscribeUserToEvents(client, claims.UserID)
 }
 
@@ -301,7 +543,7 @@ func (h *Hub) handleRoomJoin(client *Client, payload json.RawMessage) {
 	}
 
 	// Check if user is a member
-	ctx := context.Background()
+	ctx := client.Context()
 	userID := client.UserID()
 	isMember, err := h.convRepo.IsMember(ctx, convID, userID)
 	if err != nil || !isMember {
@@ -322,6 +564,9 @@ func (h *Hub) handleRoomJoin(client *Client, payload json.RawMessage) {
 	// Ensure we're subscribed to room events via PubSub
 	h.subscribeToRoom(convID)
 
+	// A reconnect within the grace window keeps the user in the call
+	h.resumeCalls(userID, convID)
+
 	// Mark all undelivered messages in this conversation as delivered
 	deliveredMsgIDs, err := h.convRepo.MarkConversationMessagesDelivered(ctx, convID, userID)
 	if err != nil {
@@ -341,6 +586,15 @@ func (h *Hub) handleRoomJoin(client *Client, payload json.RawMessage) {
 	h.logger.Debug("client joined room", "user_id", userID, "room_id", convID)
 }
 
+// resumeCalls cancels call cleanup pending from an earlier disconnect of
+// this user, now that they're back in the room
+func (h *Hub) resumeCalls(userID, roomID uuid.UUID) {
+	resumed := h.callGrace.Cancel(userID, roomID)
+	if h.callGrace.Cancel(userID, uuid.Nil) || resumed {
+		h.logger.Debug("call participation preserved across reconnect", "user_id", userID, "room_id", roomID)
+	}
+}
+
 func (h *Hub) handleRoomLeave(client *Client, payload json.RawMessage) {
 	var p RoomLeavePayload
 	if err := json.Unmarshal(payload, &p); err != nil {
@@ -382,23 +636,48 @@ func (h *Hub) handleMessageSend(client *Client, payload json.RawMessage) {
 		return
 	}
 
-	if p.BodyText == "" && p.AttachmentID == "" {
+	if p.BodyText == "" && p.AttachmentID == "" && len(p.AttachmentIDs) == 0 {
 		client.sendError("empty_message", "Message cannot be empty")
 		return
 	}
 
-	if len(p.BodyText) > 10000 {
-		client.sendError("message_too_long", "Message exceeds 10000 characters")
+	if len(p.BodyText) > h.maxMessageLen {
+		client.sendError("message_too_long", fmt.Sprintf("Message exceeds %d characters", h.maxMessageLen))
 		return
 	}
 
-	// Check membership
-	ctx := context.Background()
-	isMember, err := h.convRepo.IsMember(ctx, convID, client.UserID())
-	if err != nil || !isMember {
+	if !h.CheckFlood(client.UserID()) {
+		client.sendError("throttled", "Sending too fast, try again later")
+		return
+	}
+
+	// Check membership, and that the sender may post if the conversation is read-only
+	ctx := client.Context()
+	role, readOnly, err := h.convRepo.GetPostingRole(ctx, convID, client.UserID())
+	if err != nil {
 		client.sendError("not_member", "Not a member of this conversation")
 		return
 	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		client.sendError("read_only", err.Error())
+		return
+	}
+
+	// A block in either direction closes an existing DM
+	partnerID, err := h.convRepo.GetDMPartner(ctx, convID, client.UserID())
+	blocked := false
+	if err == nil && partnerID != uuid.Nil {
+		blocked, err = h.convRepo.IsBlocked(ctx, client.UserID(), partnerID)
+	}
+	if err != nil {
+		h.logger.Error("failed to check block", "error", err)
+		client.sendError("send_failed", "Failed to send message")
+		return
+	}
+	if blocked {
+		client.sendError("blocked", domain.ErrUserBlocked.Error())
+		return
+	}
 
 	// Create message
 	userID := client.UserID()
@@ -410,14 +689,65 @@ func (h *Hub) handleMessageSend(client *Client, payload json.RawMessage) {
 		CreatedAt:      time.Now(),
 	}
 
-	// Add attachment if provided
+	// A whisper is stored and delivered for its recipients and the sender only
+	if len(p.RecipientIDs) > 0 {
+		audience, err := h.whisperAudience(ctx, convID, userID, p.RecipientIDs)
+		if err != nil {
+			client.sendError("invalid_recipients", domain.ErrInvalidRecipients.Error())
+			return
+		}
+		msg.VisibleTo = audience
+	}
+
+	// Add attachments if provided; each must be the sender's own finished upload here
+	var attachments []*domain.Attachment
+	rawAttachmentIDs := p.AttachmentIDs
 	if p.AttachmentID != "" {
-		attachmentUUID, err := uuid.Parse(p.AttachmentID)
+		rawAttachmentIDs = append([]string{p.AttachmentID}, rawAttachmentIDs...)
+	}
+	if len(rawAttachmentIDs) > 0 {
+		ids := make([]uuid.UUID, len(rawAttachmentIDs))
+		for i, raw := range rawAttachmentIDs {
+			if ids[i], err = uuid.Parse(raw); err != nil {
+				client.sendError("invalid_attachment", "Invalid attachment ID")
+				return
+			}
+		}
+		ids, err = domain.MessageAttachmentIDs(nil, ids)
+		if err != nil {
+			client.sendError("invalid_attachment", err.Error())
+			return
+		}
+		for _, id := range ids {
+			att, _ := h.attachmentRepo.GetAttachmentByID(ctx, id.String())
+			if err := domain.CheckMessageAttachment(att, convID.String(), userID.String()); err != nil {
+				client.sendError("invalid_attachment", err.Error())
+				return
+			}
+			attachments = append(attachments, att)
+		}
+		msg.SetAttachments(attachments)
+	}
+
+	var replyTarget *domain.Message
+	if p.ReplyToID != "" {
+		replyToID, err := uuid.Parse(p.ReplyToID)
+		if err != nil {
+			client.sendError("invalid_reply", "Invalid reply_to_id")
+			return
+		}
+		replyTarget, err = h.convRepo.GetMessageByID(ctx, replyToID)
+		if err == nil {
+			err = domain.CheckReplyTarget(replyTarget, convID, userID)
+		}
 		if err != nil {
-			client.sendError("invalid_attachment", "Invalid attachment ID")
+			if !errors.Is(err, domain.ErrMessageNotFound) && !errors.Is(err, domain.ErrInvalidReply) {
+				h.logger.Error("failed to get reply target", "error", err)
+			}
+			client.sendError("invalid_reply", domain.ErrInvalidReply.Error())
 			return
 		}
-		msg.AttachmentID = &attachmentUUID
+		msg.ReplyToID = &replyToID
 	}
 
 	// Save to database
@@ -427,19 +757,20 @@ func (h *Hub) handleMessageSend(client *Client, payload json.RawMessage) {
 		return
 	}
 
-	// Fetch attachment details if present
+	// The draft has been sent
+	if err := h.convRepo.DeleteDraft(ctx, convID, userID); err != nil {
+		h.logger.Error("failed to clear draft", "error", err)
+	}
+
+	// Describe the attachments for clients; a lone one also goes in the
+	// single field older clients read
+	var attachmentPayloads []AttachmentPayload
+	for _, att := range attachments {
+		attachmentPayloads = append(attachmentPayloads, h.attachmentPayload(ctx, att))
+	}
 	var attachmentPayload *AttachmentPayload
-	if msg.AttachmentID != nil {
-		attachment, err := h.attachmentRepo.GetAttachmentByID(ctx, msg.AttachmentID.String())
-		if err == nil {
-			attachmentID, _ := uuid.Parse(attachment.ID)
-			attachmentPayload = &AttachmentPayload{
-				ID:        attachmentID,
-				Filename:  attachment.Filename,
-				MimeType:  attachment.MimeType,
-				SizeBytes: attachment.SizeBytes,
-			}
-		}
+	if len(attachmentPayloads) == 1 {
+		attachmentPayload = &attachmentPayloads[0]
 	}
 
 	// Broadcast to room
@@ -451,11 +782,117 @@ func (h *Hub) handleMessageSend(client *Client, payload json.RawMessage) {
 		BodyText:       msg.BodyText,
 		AttachmentID:   msg.AttachmentID,
 		Attachment:     attachmentPayload,
+		AttachmentIDs:  msg.AttachmentIDs,
+		Attachments:    attachmentPayloads,
+		VisibleTo:      msg.VisibleTo,
+		ReplyToID:      msg.ReplyToID,
 		CreatedAt:      msg.CreatedAt,
+		ExpiresAt:      msg.ExpiresAt,
 		TempID:         p.TempID,
 	}
+	if replyTarget != nil {
+		broadcastPayload.ReplyTo = replyPreview(msg, replyTarget)
+	}
 
+	// Ack the sending connection first so it can settle its optimistic
+	// bubble before the room broadcast arrives
+	h.acknowledgeSend(client, msg, p.TempID)
+	h.mentions.Record(ctx, msg, client.Username())
+	if msg.IsWhisper() {
+		h.deliverWhisper(broadcastPayload)
+		return
+	}
 	h.BroadcastToRoom(convID, EventTypeMessageNew, broadcastPayload)
+	h.linkPreviews.Enqueue(msg)
+}
+
+// replyPreview quotes target for a reply, or returns nil if anyone the reply
+// is delivered to couldn't see target, as with a public reply to a whisper
+func replyPreview(reply, target *domain.Message) *ReplyPreview {
+	if target.IsWhisper() {
+		if !reply.IsWhisper() {
+			return nil
+		}
+		for _, id := range reply.VisibleTo {
+			if !target.VisibleToUser(id) {
+				return nil
+			}
+		}
+	}
+
+	quoted := target.ReplyPreview()
+	return &ReplyPreview{
+		ID:        quoted.ID,
+		SenderID:  quoted.SenderID,
+		BodyText:  quoted.BodyText,
+		CreatedAt: quoted.CreatedAt,
+	}
+}
+
+// attachmentPayload describes an attachment for a message event, with
+// signed thumbnail URLs when storage is configured
+func (h *Hub) attachmentPayload(ctx context.Context, att *domain.Attachment) AttachmentPayload {
+	id, _ := uuid.Parse(att.ID)
+	payload := AttachmentPayload{
+		ID:         id,
+		Filename:   att.Filename,
+		MimeType:   att.MimeType,
+		SizeBytes:  att.SizeBytes,
+		Kind:       string(att.Kind),
+		DurationMs: att.DurationMs,
+	}
+	if h.urlSigner != nil {
+		payload.Thumbnails = media.ThumbnailURLs(ctx, h.urlSigner, att.ObjectKey, att.ThumbnailSizes, thumbnailURLExpiry)
+	}
+	return payload
+}
+
+// whisperAudience parses and validates whisper recipients, returning the
+// visibility list for the message
+func (h *Hub) whisperAudience(ctx context.Context, convID, senderID uuid.UUID, rawIDs []string) ([]uuid.UUID, error) {
+	recipientIDs := make([]uuid.UUID, 0, len(rawIDs))
+	for _, raw := range rawIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, domain.ErrInvalidRecipients
+		}
+		recipientIDs = append(recipientIDs, id)
+	}
+	if len(recipientIDs) > domain.MaxWhisperRecipients {
+		return nil, domain.ErrInvalidRecipients
+	}
+
+	members, err := h.convRepo.GetMembersIn(ctx, convID, recipientIDs)
+	if err != nil {
+		return nil, err
+	}
+	return domain.WhisperAudience(senderID, recipientIDs, members)
+}
+
+// deliverWhisper sends a whisper to each member of its audience on their
+// own topic. It never touches the room topic, so other members' connections
+// don't see it. Recipients queue it for acking as their topic delivers it.
+func (h *Hub) deliverWhisper(payload MessageNewPayload) {
+	for _, userID := range payload.VisibleTo {
+		h.BroadcastToUser(userID, EventTypeMessageNew, payload)
+	}
+}
+
+// acknowledgeSend tells the connection that sent a message it was persisted.
+// Only that connection gets the ack; the sender's other devices see the
+// message through the room broadcast like everyone else.
+func (h *Hub) acknowledgeSend(client *Client, msg *domain.Message, tempID string) {
+	ack, err := NewMessage(EventTypeMessageSent, MessageSentPayload{
+		TempID:         tempID,
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		CreatedAt:      msg.CreatedAt,
+	})
+	if err != nil {
+		h.logger.Error("failed to build send ack", "error", err)
+		return
+	}
+	_ = client.Send(ack)
 }
 
 func (h *Hub) handleTyping(client *Client, payload json.RawMessage, isTyping bool) {
@@ -473,17 +910,43 @@ func (h *Hub) handleTyping(client *Client, payload json.RawMessage, isTyping boo
 		return
 	}
 
-	// Broadcast typing indicator to other room members
+	// A user types in one conversation at a time: starting in another
+	// implicitly stops the indicator they left behind
+	if isTyping {
+		if previous, switched := h.typing.Start(client.UserID(), convID); switched {
+			h.broadcastTyping(previous, client, false)
+		}
+	} else {
+		h.typing.Stop(client.UserID(), convID)
+	}
+
+	h.broadcastTyping(convID, client, isTyping)
+}
+
+// broadcastTyping sends the client's typing state to other room members.
+// Typing from someone in the conversation's call is flagged so call
+// participants can show the speaking indicator instead, while members
+// outside the call still see it.
+func (h *Hub) broadcastTyping(convID uuid.UUID, client *Client, isTyping bool) {
 	broadcastPayload := TypingBroadcastPayload{
 		ConversationID: convID,
 		UserID:         client.UserID(),
 		Username:       client.Username(),
 		IsTyping:       isTyping,
+		InCall:         h.isInCall(convID, client.UserID()),
 	}
 
 	h.BroadcastToRoomExcept(convID, client, EventTypeTyping, broadcastPayload)
 }
 
+// isInCall reports whether the user is in the conversation's P2P or SFU call
+func (h *Hub) isInCall(convID, userID uuid.UUID) bool {
+	if h.sfuHandler != nil && h.sfuHandler.IsUserInSFURoom(convID, userID) {
+		return true
+	}
+	return h.callHandler != nil && h.callHandler.IsUserInRoom(convID, userID)
+}
+
 func (h *Hub) handleReceiptRead(client *Client, payload json.RawMessage) {
 	if !client.IsAuthenticated() {
 		return
@@ -501,7 +964,7 @@ func (h *Hub) handleReceiptRead(client *Client, payload json.RawMessage) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := client.Context()
 	userID := client.UserID()
 
 	// Get the message to find its conversation
@@ -540,6 +1003,106 @@ func (h *Hub) handleReceiptRead(client *Client, payload json.RawMessage) {
 	h.BroadcastToRoom(msg.ConversationID, EventTypeReceiptUpdate, broadcastPayload)
 }
 
+// handleReceiptReadUpTo marks every unread message up to and including the
+// given one as read in a single update, then emits one batched receipt.
+func (h *Hub) handleReceiptReadUpTo(client *Client, payload json.RawMessage) {
+	if !client.IsAuthenticated() {
+		client.sendError("not_authenticated", "Must authenticate first")
+		return
+	}
+
+	var p ReceiptReadUpToPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		client.sendError("invalid_payload", "Invalid receipt payload")
+		return
+	}
+
+	messageID, err := uuid.Parse(p.MessageID)
+	if err != nil {
+		client.sendError("invalid_message", "Invalid message ID")
+		return
+	}
+
+	ctx := client.Context()
+	userID := client.UserID()
+
+	// Get the message to find its conversation
+	msg, err := h.convRepo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		h.logger.Error("failed to get message for receipt", "error", err)
+		return
+	}
+
+	isMember, err := h.convRepo.IsMember(ctx, msg.ConversationID, userID)
+	if err != nil || !isMember {
+		return
+	}
+
+	readIDs, err := h.convRepo.MarkMessagesReadUpTo(ctx, msg.ConversationID, userID, messageID)
+	if err != nil {
+		h.logger.Error("failed to mark messages read up to", "error", err)
+		return
+	}
+
+	h.broadcastReadBatch(msg.ConversationID, userID, readIDs)
+}
+
+// broadcastReadBatch emits a single read receipt covering all given messages
+func (h *Hub) broadcastReadBatch(convID, userID uuid.UUID, messageIDs []uuid.UUID) {
+	if len(messageIDs) == 0 {
+		return
+	}
+
+	h.BroadcastToRoom(convID, EventTypeReceiptUpdate, ReceiptBatchUpdatePayload{
+		ConversationID: convID,
+		MessageIDs:     messageIDs,
+		UserID:         userID,
+		Status:         "read",
+		Timestamp:      time.Now(),
+	})
+}
+
+func (h *Hub) handleMessageAck(client *Client, payload json.RawMessage) {
+	if !client.IsAuthenticated() {
+		return
+	}
+
+	var p MessageAckPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		client.sendError("invalid_payload", "Invalid message ack payload")
+		return
+	}
+
+	messageID, err := uuid.Parse(p.MessageID)
+	if err != nil {
+		client.sendError("invalid_message", "Invalid message ID")
+		return
+	}
+
+	userID := client.UserID()
+	convID, ok := h.pending.Ack(userID, messageID)
+	if !ok {
+		return // Already acked or never queued for this user
+	}
+
+	// Record delivery now that the app has confirmed receipt
+	if h.convRepo == nil {
+		return
+	}
+	if err := h.convRepo.MarkMessageDelivered(client.Context(), messageID, userID); err != nil {
+		h.logger.Error("failed to mark message as delivered", "error", err)
+		return
+	}
+
+	h.BroadcastToRoom(convID, EventTypeReceiptUpdate, ReceiptUpdatePayload{
+		MessageID:      messageID,
+		ConversationID: convID,
+		UserID:         userID,
+		Status:         "delivered",
+		Timestamp:      time.Now(),
+	})
+}
+
 // ============================================================================
 // WebRTC Call Handlers
 // ============================================================================
@@ -557,7 +1120,7 @@ func (h *Hub) handleCallJoin(client *Client, payload json.RawMessage) {
 
 	// Route through SFU handler if available — it auto-delegates to P2P for 1:1 calls
 	if h.sfuHandler != nil {
-		config, err := h.sfuHandler.HandleGroupJoin(context.Background(), sigCtx, payload)
+		config, err := h.sfuHandler.HandleGroupJoin(client.Context(), sigCtx, payload)
 		if err != nil {
 			if callErr, ok := err.(*webrtc.CallError); ok {
 				client.sendError(callErr.Code, callErr.Message)
@@ -568,6 +1131,9 @@ func (h *Hub) handleCallJoin(client *Client, payload json.RawMessage) {
 		}
 		msg, _ := NewMessage(webrtc.EventTypeCallConfig, config)
 		_ = client.Send(msg)
+		if !config.IsInitiator {
+			h.notifyCallAnswered(client, config.RoomID, config.Participants)
+		}
 		return
 	}
 
@@ -577,7 +1143,7 @@ func (h *Hub) handleCallJoin(client *Client, payload json.RawMessage) {
 		return
 	}
 
-	config, err := h.callHandler.HandleJoin(context.Background(), sigCtx, payload)
+	config, err := h.callHandler.HandleJoin(client.Context(), sigCtx, payload)
 	if err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
@@ -589,6 +1155,9 @@ func (h *Hub) handleCallJoin(client *Client, payload json.RawMessage) {
 
 	msg, _ := NewMessage(webrtc.EventTypeCallConfig, config)
 	_ = client.Send(msg)
+	if !config.IsInitiator {
+		h.notifyCallAnswered(client, config.RoomID, config.Participants)
+	}
 }
 
 func (h *Hub) handleCallLeave(client *Client, payload json.RawMessage) {
@@ -601,7 +1170,7 @@ func (h *Hub) handleCallLeave(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.callHandler.HandleLeave(context.Background(), sigCtx, payload)
+	_ = h.callHandler.HandleLeave(client.Context(), sigCtx, payload)
 }
 
 func (h *Hub) handleCallOffer(client *Client, payload json.RawMessage) {
@@ -620,7 +1189,7 @@ func (h *Hub) handleCallOffer(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	if err := h.callHandler.HandleOffer(context.Background(), sigCtx, payload); err != nil {
+	if err := h.callHandler.HandleOffer(client.Context(), sigCtx, payload); err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
 		}
@@ -643,7 +1212,7 @@ func (h *Hub) handleCallAnswer(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	if err := h.callHandler.HandleAnswer(context.Background(), sigCtx, payload); err != nil {
+	if err := h.callHandler.HandleAnswer(client.Context(), sigCtx, payload); err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
 		}
@@ -660,7 +1229,7 @@ func (h *Hub) handleCallICECandidate(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.callHandler.HandleICECandidate(context.Background(), sigCtx, payload)
+	_ = h.callHandler.HandleICECandidate(client.Context(), sigCtx, payload)
 }
 
 func (h *Hub) handleCallDeclined(client *Client, payload json.RawMessage) {
@@ -673,7 +1242,7 @@ func (h *Hub) handleCallDeclined(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.callHandler.HandleDeclined(context.Background(), sigCtx, payload)
+	_ = h.callHandler.HandleDeclined(client.Context(), sigCtx, payload)
 }
 
 func (h *Hub) handleCallReady(client *Client, payload json.RawMessage) {
@@ -686,7 +1255,20 @@ func (h *Hub) handleCallReady(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.callHandler.HandleReady(context.Background(), sigCtx, payload)
+	_ = h.callHandler.HandleReady(client.Context(), sigCtx, payload)
+}
+
+func (h *Hub) handleCallHeartbeat(client *Client, payload json.RawMessage) {
+	if !client.IsAuthenticated() || h.callHandler == nil {
+		return
+	}
+
+	sigCtx := &webrtc.SignalingContext{
+		UserID:   client.UserID(),
+		Username: client.Username(),
+	}
+
+	_ = h.callHandler.HandleHeartbeat(client.Context(), sigCtx, payload)
 }
 
 func (h *Hub) handleCallMuteUpdate(client *Client, payload json.RawMessage) {
@@ -714,9 +1296,9 @@ func (h *Hub) handleCallMuteUpdate(client *Client, payload json.RawMessage) {
 
 	// Try SFU room first, then P2P
 	if h.sfuHandler != nil && h.sfuHandler.IsUserInSFURoom(roomID, client.UserID()) {
-		_ = h.sfuHandler.HandleSFUMuteUpdate(context.Background(), sigCtx, payload)
+		_ = h.sfuHandler.HandleSFUMuteUpdate(client.Context(), sigCtx, payload)
 	} else if h.callHandler != nil {
-		_ = h.callHandler.HandleMuteUpdate(context.Background(), sigCtx, payload)
+		_ = h.callHandler.HandleMuteUpdate(client.Context(), sigCtx, payload)
 	}
 }
 
@@ -736,7 +1318,7 @@ func (h *Hub) handleSFUJoin(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	config, err := h.sfuHandler.HandleGroupJoin(context.Background(), sigCtx, payload)
+	config, err := h.sfuHandler.HandleGroupJoin(client.Context(), sigCtx, payload)
 	if err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
@@ -753,6 +1335,9 @@ func (h *Hub) handleSFUJoin(client *Client, payload json.RawMessage) {
 		Payload: responseBytes,
 	}
 	_ = client.Send(msg)
+	if !config.IsInitiator {
+		h.notifyCallAnswered(client, config.RoomID, config.Participants)
+	}
 }
 
 func (h *Hub) handleSFUOffer(client *Client, payload json.RawMessage) {
@@ -765,7 +1350,7 @@ func (h *Hub) handleSFUOffer(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	if err := h.sfuHandler.HandleSFUOffer(context.Background(), sigCtx, payload); err != nil {
+	if err := h.sfuHandler.HandleSFUOffer(client.Context(), sigCtx, payload); err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
 		}
@@ -788,7 +1373,7 @@ func (h *Hub) handleSFUAnswer(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	if err := h.sfuHandler.HandleSFUAnswer(context.Background(), sigCtx, payload); err != nil {
+	if err := h.sfuHandler.HandleSFUAnswer(client.Context(), sigCtx, payload); err != nil {
 		if callErr, ok := err.(*webrtc.CallError); ok {
 			client.sendError(callErr.Code, callErr.Message)
 		}
@@ -805,7 +1390,7 @@ func (h *Hub) handleSFUCandidate(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.sfuHandler.HandleSFUCandidate(context.Background(), sigCtx, payload)
+	_ = h.sfuHandler.HandleSFUCandidate(client.Context(), sigCtx, payload)
 }
 
 func (h *Hub) handleSFULeave(client *Client, payload json.RawMessage) {
@@ -818,7 +1403,58 @@ func (h *Hub) handleSFULeave(client *Client, payload json.RawMessage) {
 		Username: client.Username(),
 	}
 
-	_ = h.sfuHandler.HandleSFULeave(context.Background(), sigCtx, payload)
+	_ = h.sfuHandler.HandleSFULeave(client.Context(), sigCtx, payload)
+}
+
+func (h *Hub) handleScreenShare(client *Client, payload json.RawMessage, started bool) {
+	if !client.IsAuthenticated() || h.sfuHandler == nil {
+		return
+	}
+
+	sigCtx := &webrtc.SignalingContext{
+		UserID:   client.UserID(),
+		Username: client.Username(),
+	}
+
+	if err := h.sfuHandler.HandleScreenShare(client.Context(), sigCtx, payload, started); err != nil {
+		if callErr, ok := err.(*webrtc.CallError); ok {
+			client.sendError(callErr.Code, callErr.Message)
+		}
+	}
+}
+
+func (h *Hub) handleHand(client *Client, payload json.RawMessage, raised bool) {
+	if !client.IsAuthenticated() || h.sfuHandler == nil {
+		return
+	}
+
+	sigCtx := &webrtc.SignalingContext{
+		UserID:   client.UserID(),
+		Username: client.Username(),
+	}
+
+	if err := h.sfuHandler.HandleHand(client.Context(), sigCtx, payload, raised); err != nil {
+		if callErr, ok := err.(*webrtc.CallError); ok {
+			client.sendError(callErr.Code, callErr.Message)
+		}
+	}
+}
+
+func (h *Hub) handleForceMute(client *Client, payload json.RawMessage) {
+	if !client.IsAuthenticated() || h.sfuHandler == nil {
+		return
+	}
+
+	sigCtx := &webrtc.SignalingContext{
+		UserID:   client.UserID(),
+		Username: client.Username(),
+	}
+
+	if err := h.sfuHandler.HandleForceMute(client.Context(), sigCtx, payload); err != nil {
+		if callErr, ok := err.(*webrtc.CallError); ok {
+			client.sendError(callErr.Code, callErr.Message)
+		}
+	}
 }
 
 // BroadcastToRoom sends a message to all clients in a room via PubSub
@@ -835,7 +1471,7 @@ func (h *Hub) BroadcastToRoom(roomID uuid.UUID, eventType string, payload interf
 		Payload: payloadBytes,
 	}
 
-	if err := h.pubsub.Publish(context.Background(), msg.Topic, msg); err != nil {
+	if err := h.pubsub.Publish(h.Context(), msg.Topic, msg); err != nil {
 		h.logger.Error("failed to publish to room", "room_id", roomID, "error", err)
 	}
 }
@@ -869,6 +1505,35 @@ func (h *Hub) BroadcastToRoomExcept(roomID uuid.UUID, except *Client, eventType
 	}
 }
 
+// CheckFlood records a send and reports whether it may proceed. The send that
+// crosses the flood threshold mutes the user and notifies all their devices.
+func (h *Hub) CheckFlood(userID uuid.UUID) bool {
+	allowed, justMuted, until := h.flood.Allow(userID)
+	if justMuted {
+		h.logger.Warn("audit: user auto-muted for flooding", "audit", true, "user_id", userID, "until", until)
+		h.BroadcastToUser(userID, EventTypeUserThrottled, UserThrottledPayload{
+			UserID:    userID,
+			Throttled: true,
+			Until:     &until,
+			Reason:    "flood",
+		})
+	}
+	return allowed
+}
+
+// ClearThrottle lifts a flood mute early. Returns false if the user wasn't muted.
+func (h *Hub) ClearThrottle(userID, clearedBy uuid.UUID) bool {
+	if !h.flood.Clear(userID) {
+		return false
+	}
+	h.logger.Warn("audit: flood mute cleared", "audit", true, "user_id", userID, "cleared_by", clearedBy)
+	h.BroadcastToUser(userID, EventTypeUserThrottled, UserThrottledPayload{
+		UserID:    userID,
+		Throttled: false,
+	})
+	return true
+}
+
 // BroadcastToUser sends to all connections of a specific user
 func (h *Hub) BroadcastToUser(userID uuid.UUID, eventType string, payload interface{}) {
 	payloadBytes, err := json.Marshal(payload)
@@ -883,7 +1548,7 @@ func (h *Hub) BroadcastToUser(userID uuid.UUID, eventType string, payload interf
 		Payload: payloadBytes,
 	}
 
-	if err := h.pubsub.Publish(context.Background(), msg.Topic, msg); err != nil {
+	if err := h.pubsub.Publish(h.Context(), msg.Topic, msg); err != nil {
 		h.logger.Error("failed to publish to user", "user_id", userID, "error", err)
 	}
 }
@@ -898,7 +1563,7 @@ func (h *Hub) subscribeToRoom(roomID uuid.UUID) {
 	}
 
 	topic := pubsub.Topics.Room(roomID.String())
-	sub, err := h.pubsub.Subscribe(context.Background(), topic, func(ctx context.Context, msg *pubsub.Message) {
+	sub, err := h.pubsub.Subscribe(h.ctx, topic, func(ctx context.Context, msg *pubsub.Message) {
 		h.deliverToRoom(roomID, msg)
 	})
 	if err != nil {
@@ -907,6 +1572,7 @@ func (h *Hub) subscribeToRoom(roomID uuid.UUID) {
 	}
 
 	h.roomSubs[roomID] = sub
+	h.replay.Open(roomID)
 }
 
 // unsubscribeFromRoom removes PubSub subscription when no local clients remain
@@ -922,11 +1588,20 @@ func (h *Hub) unsubscribeFromRoom(roomID uuid.UUID) {
 	if sub, ok := h.roomSubs[roomID]; ok {
 		_ = sub.Unsubscribe()
 		delete(h.roomSubs, roomID)
+		h.replay.Close(roomID)
 	}
 }
 
-// deliverToRoom delivers a PubSub message to all local clients in a room
+// deliverToRoom delivers a PubSub message to all local clients in a room,
+// numbering and buffering it for replay even if none are connected
 func (h *Hub) deliverToRoom(roomID uuid.UUID, psMsg *pubsub.Message) {
+	msg := &Message{
+		Type:      psMsg.Type,
+		Payload:   psMsg.Payload,
+		Timestamp: time.Now(),
+	}
+	h.replay.Record(roomID, msg)
+
 	h.mu.RLock()
 	room, ok := h.rooms[roomID]
 	if !ok {
@@ -940,14 +1615,54 @@ func (h *Hub) deliverToRoom(roomID uuid.UUID, psMsg *pubsub.Message) {
 	}
 	h.mu.RUnlock()
 
-	msg := &Message{
-		Type:      psMsg.Type,
-		Payload:   psMsg.Payload,
-		Timestamp: time.Now(),
+	// New messages stay queued for each recipient until the app acks them
+	var newMsg *MessageNewPayload
+	if psMsg.Type == EventTypeMessageNew {
+		var p MessageNewPayload
+		if err := json.Unmarshal(psMsg.Payload, &p); err == nil {
+			newMsg = &p
+		}
 	}
 
 	for _, client := range clients {
-		_ = client.Send(msg)
+		if newMsg != nil {
+			if userID := client.UserID(); userID != newMsg.SenderID {
+				h.pending.Add(userID, newMsg.ID, newMsg.ConversationID, msg)
+			}
+		}
+		_ = client.Send(client.localize(msg))
+	}
+
+	// Every instance sees member_left, so each drops the departed member's
+	// connections from the room once they've been told
+	if psMsg.Type == EventTypeMemberLeft {
+		var p MemberLeftPayload
+		if err := json.Unmarshal(psMsg.Payload, &p); err == nil {
+			h.removeUserFromRoom(roomID, p.UserID)
+		}
+	}
+}
+
+// removeUserFromRoom stops a user's local connections receiving a room's
+// events, e.g. after they were removed or banned from it
+func (h *Hub) removeUserFromRoom(roomID, userID uuid.UUID) {
+	var removed []*Client
+	h.mu.Lock()
+	if room, ok := h.rooms[roomID]; ok {
+		for client := range room {
+			if client.UserID() == userID {
+				delete(room, client)
+				removed = append(removed, client)
+			}
+		}
+		if len(room) == 0 {
+			delete(h.rooms, roomID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, client := range removed {
+		client.LeaveRoom(roomID)
 	}
 }
 
@@ -956,14 +1671,22 @@ func (h *Hub) subscribeUserToEvents(client *Client, userID uuid.UUID) {
 	topic := pubsub.Topics.User(userID.String())
 	h.logger.Info("subscribing user to events", "user_id", userID, "topic", topic)
 
-	sub, err := h.pubsub.Subscribe(context.Background(), topic, func(ctx context.Context, msg *pubsub.Message) {
+	sub, err := h.pubsub.Subscribe(h.Context(), topic, func(ctx context.Context, msg *pubsub.Message) {
 		h.logger.Info("received pubsub message for user", "user_id", userID, "type", msg.Type, "topic", msg.Topic)
 		wsMsg := &Message{
 			Type:      msg.Type,
 			Payload:   msg.Payload,
 			Timestamp: time.Now(),
 		}
-		_ = client.Send(wsMsg)
+		// Whispers arrive here rather than through the room, and stay queued
+		// until acked just like room messages
+		if msg.Type == EventTypeMessageNew {
+			var p MessageNewPayload
+			if err := json.Unmarshal(msg.Payload, &p); err == nil && p.SenderID != userID {
+				h.pending.Add(userID, p.ID, p.ConversationID, wsMsg)
+			}
+		}
+		_ = client.Send(client.localize(wsMsg))
 		h.logger.Info("sent message to client", "user_id", userID, "type", msg.Type)
 	})
 	if err != nil {
@@ -0,0 +1,34 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLastSeenHeartbeat is how often connected users' last seen time is refreshed
+const DefaultLastSeenHeartbeat = 5 * time.Minute
+
+// LastSeenStore records when users were last connected
+type LastSeenStore interface {
+	// TouchLastSeen sets each user's last seen time to now
+	TouchLastSeen(ctx context.Context, userIDs ...uuid.UUID) error
+}
+
+// SetLastSeenHeartbeat sets how often connected users' last seen time is
+// refreshed, so a crash can't leave it far behind. 0 only records it when a
+// user's last connection closes.
+func (h *Hub) SetLastSeenHeartbeat(interval time.Duration) {
+	h.lastSeenEvery = interval
+}
+
+// touchLastSeen records the users as seen now
+func (h *Hub) touchLastSeen(userIDs ...uuid.UUID) {
+	if h.lastSeen == nil || len(userIDs) == 0 {
+		return
+	}
+	if err := h.lastSeen.TouchLastSeen(h.Context(), userIDs...); err != nil {
+		h.logger.Error("failed to update last seen", "users", len(userIDs), "error", err)
+	}
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryLastSeen records every touch
+type memoryLastSeen struct {
+	mu      sync.Mutex
+	touches [][]uuid.UUID
+}
+
+func (s *memoryLastSeen) TouchLastSeen(_ context.Context, userIDs ...uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touches = append(s.touches, userIDs)
+	return nil
+}
+
+func (s *memoryLastSeen) Touches() [][]uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]uuid.UUID(nil), s.touches...)
+}
+
+func TestHub_LastSeenTouchedOnlyWhenLastConnectionCloses(t *testing.T) {
+	hub, first := newTestAckHub(t)
+	store := &memoryLastSeen{}
+	hub.lastSeen = store
+
+	userID := first.UserID()
+	second := &Client{hub: hub, send: make(chan []byte, 1), rooms: make(map[uuid.UUID]bool), logger: first.logger}
+	second.SetUser(userID, "alice")
+	hub.clients[userID] = map[*Client]bool{first: true, second: true}
+
+	hub.handleUnregister(first)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, store.Touches(), "another connection is still open")
+
+	hub.handleUnregister(second)
+	assert.Eventually(t, func() bool { return len(store.Touches()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []uuid.UUID{userID}, store.Touches()[0])
+}
+
+func TestHub_LastSeenHeartbeatTouchesOnlineUsers(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	store := &memoryLastSeen{}
+	hub.lastSeen = store
+	hub.SetLastSeenHeartbeat(10 * time.Millisecond)
+	hub.clients[client.UserID()] = map[*Client]bool{client: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	assert.Eventually(t, func() bool { return len(store.Touches()) >= 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []uuid.UUID{client.UserID()}, store.Touches()[0])
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// LinkPreviewFetcher builds the preview card for a URL. It's an interface
+// so previews can be turned off or faked in tests.
+type LinkPreviewFetcher interface {
+	Fetch(ctx context.Context, url string) (*domain.LinkPreview, error)
+}
+
+// LinkPreviewStore saves fetched previews
+type LinkPreviewStore interface {
+	SaveLinkPreview(ctx context.Context, preview *domain.LinkPreview) error
+}
+
+// linkPreviewQueueSize bounds how many messages may wait for a preview;
+// beyond it new links go without one rather than pile up
+const linkPreviewQueueSize = 256
+
+// LinkPreviewWorker fetches previews for links in new messages in the
+// background, stores them and broadcasts message.preview to the room
+type LinkPreviewWorker struct {
+	store       LinkPreviewStore
+	fetcher     LinkPreviewFetcher
+	broadcaster RoomBroadcaster
+	queue       chan domain.LinkPreview
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewLinkPreviewWorker creates a worker; call Run to start it
+func NewLinkPreviewWorker(store LinkPreviewStore, fetcher LinkPreviewFetcher, broadcaster RoomBroadcaster, logger *slog.Logger) *LinkPreviewWorker {
+	return &LinkPreviewWorker{
+		store:       store,
+		fetcher:     fetcher,
+		broadcaster: broadcaster,
+		queue:       make(chan domain.LinkPreview, linkPreviewQueueSize),
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Enqueue schedules a preview for the first link in msg, if it has one.
+// Whispers are skipped, since the preview event goes to the whole room.
+// It never blocks: when the queue is full the link is left without a preview.
+func (w *LinkPreviewWorker) Enqueue(msg *domain.Message) bool {
+	if w == nil || msg.IsWhisper() {
+		return false
+	}
+	url := domain.FirstURL(msg.BodyText)
+	if url == "" {
+		return false
+	}
+
+	select {
+	case w.queue <- domain.LinkPreview{MessageID: msg.ID, ConversationID: msg.ConversationID, URL: url}:
+		return true
+	default:
+		w.logger.Warn("link preview queue full, skipping", "message_id", msg.ID)
+		return false
+	}
+}
+
+// Run processes queued links until ctx is cancelled
+func (w *LinkPreviewWorker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-w.queue:
+			w.Process(ctx, job)
+		}
+	}
+}
+
+// Process fetches, stores and broadcasts one preview. Pages that can't be
+// fetched or have nothing to show are skipped quietly.
+func (w *LinkPreviewWorker) Process(ctx context.Context, job domain.LinkPreview) bool {
+	preview, err := w.fetcher.Fetch(ctx, job.URL)
+	if err != nil {
+		w.logger.Debug("link preview fetch failed", "error", err, "message_id", job.MessageID)
+		return false
+	}
+	if preview == nil || preview.Empty() {
+		return false
+	}
+	preview.MessageID = job.MessageID
+	preview.ConversationID = job.ConversationID
+	preview.URL = job.URL
+	preview.FetchedAt = w.now()
+	preview.Truncate()
+
+	if err := w.store.SaveLinkPreview(ctx, preview); err != nil {
+		if !errors.Is(err, domain.ErrMessageNotFound) {
+			w.logger.Error("failed to save link preview", "error", err, "message_id", job.MessageID)
+		}
+		return false
+	}
+	if err := w.broadcaster.BroadcastLinkPreview(ctx, preview); err != nil {
+		w.logger.Error("failed to broadcast link preview", "error", err, "message_id", job.MessageID)
+	}
+	return true
+}
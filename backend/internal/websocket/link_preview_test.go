@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubFetcher serves canned previews by URL
+type stubFetcher map[string]*domain.LinkPreview
+
+func (f stubFetcher) Fetch(_ context.Context, url string) (*domain.LinkPreview, error) {
+	if p, ok := f[url]; ok {
+		out := *p
+		return &out, nil
+	}
+	return nil, errors.New("not found")
+}
+
+type memoryPreviewStore struct {
+	mu    sync.Mutex
+	saved []domain.LinkPreview
+}
+
+func (s *memoryPreviewStore) SaveLinkPreview(_ context.Context, p *domain.LinkPreview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, *p)
+	return nil
+}
+
+func newTestPreviewWorker(t *testing.T, fetcher LinkPreviewFetcher) (*LinkPreviewWorker, *memoryPreviewStore, pubsub.PubSub) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	store := &memoryPreviewStore{}
+	return NewLinkPreviewWorker(store, fetcher, NewPubSubBroadcaster(ps), logger), store, ps
+}
+
+func TestLinkPreviewWorker_StoresAndBroadcasts(t *testing.T) {
+	worker, store, ps := newTestPreviewWorker(t, stubFetcher{
+		"https://tea.example/green": {Title: "Green tea", Description: "All about it", ImageURL: "https://tea.example/cup.png"},
+	})
+	convID := uuid.New()
+	events := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	msg := &domain.Message{ID: uuid.New(), ConversationID: convID, BodyText: "look: https://tea.example/green!"}
+	require.True(t, worker.Enqueue(msg))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx)
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, EventTypeMessagePreview, ev.Type)
+		var p MessagePreviewPayload
+		require.NoError(t, json.Unmarshal(ev.Payload, &p))
+		assert.Equal(t, msg.ID, p.MessageID)
+		assert.Equal(t, "https://tea.example/green", p.URL)
+		assert.Equal(t, "Green tea", p.Title)
+		assert.Equal(t, "https://tea.example/cup.png", p.ImageURL)
+	case <-time.After(time.Second):
+		t.Fatal("expected message.preview")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, msg.ID, store.saved[0].MessageID)
+	assert.False(t, store.saved[0].FetchedAt.IsZero())
+}
+
+func TestLinkPreviewWorker_Skips(t *testing.T) {
+	worker, store, _ := newTestPreviewWorker(t, stubFetcher{
+		"https://tea.example/empty": {},
+	})
+	convID, alice := uuid.New(), uuid.New()
+
+	assert.False(t, worker.Enqueue(&domain.Message{ID: uuid.New(), ConversationID: convID, BodyText: "no links"}))
+	assert.False(t, worker.Enqueue(&domain.Message{ID: uuid.New(), ConversationID: convID, BodyText: "https://tea.example/x", VisibleTo: []uuid.UUID{alice}}),
+		"whisper previews would reach the whole room")
+
+	var nilWorker *LinkPreviewWorker
+	assert.False(t, nilWorker.Enqueue(&domain.Message{BodyText: "https://tea.example/x"}), "previews may be disabled")
+
+	ctx := context.Background()
+	assert.False(t, worker.Process(ctx, domain.LinkPreview{MessageID: uuid.New(), ConversationID: convID, URL: "https://tea.example/empty"}), "pages without a title or description")
+	assert.False(t, worker.Process(ctx, domain.LinkPreview{MessageID: uuid.New(), ConversationID: convID, URL: "https://tea.example/missing"}), "fetch failures")
+	assert.Empty(t, store.saved)
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// MentionUserLookup resolves mentioned usernames
+type MentionUserLookup interface {
+	GetByUsernames(ctx context.Context, usernames []string) ([]domain.User, error)
+}
+
+// MentionStore checks membership and records mentions
+type MentionStore interface {
+	GetMembersIn(ctx context.Context, convID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	CreateMentions(ctx context.Context, msg *domain.Message, userIDs []uuid.UUID) error
+}
+
+// Mentions records the @mentions in new messages and notifies the users
+// mentioned on their own topic, so they hear about it outside the room
+type Mentions struct {
+	users       MentionUserLookup
+	store       MentionStore
+	broadcaster RoomBroadcaster
+	logger      *slog.Logger
+}
+
+// NewMentions creates a mention recorder
+func NewMentions(users MentionUserLookup, store MentionStore, broadcaster RoomBroadcaster, logger *slog.Logger) *Mentions {
+	return &Mentions{
+		users:       users,
+		store:       store,
+		broadcaster: broadcaster,
+		logger:      logger,
+	}
+}
+
+// Record stores and announces the mentions in a message that's just been
+// saved, returning who was notified. Unknown usernames, non-members, the
+// sender and, for whispers, anyone outside the audience are ignored. Errors
+// are logged rather than returned: the message itself has already been sent.
+func (m *Mentions) Record(ctx context.Context, msg *domain.Message, senderUsername string) []uuid.UUID {
+	if m == nil {
+		return nil
+	}
+	usernames := domain.ParseMentions(msg.BodyText)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := m.users.GetByUsernames(ctx, usernames)
+	if err != nil {
+		m.logger.Error("failed to resolve mentions", "error", err, "message_id", msg.ID)
+		return nil
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	members, err := m.store.GetMembersIn(ctx, msg.ConversationID, ids)
+	if err != nil {
+		m.logger.Error("failed to check mentioned members", "error", err, "message_id", msg.ID)
+		return nil
+	}
+
+	recipients := msg.MentionRecipients(ids, members)
+	if len(recipients) == 0 {
+		return nil
+	}
+	if err := m.store.CreateMentions(ctx, msg, recipients); err != nil {
+		m.logger.Error("failed to save mentions", "error", err, "message_id", msg.ID)
+		return nil
+	}
+	if err := m.broadcaster.NotifyMentioned(ctx, recipients, msg, senderUsername); err != nil {
+		m.logger.Error("failed to notify mentions", "error", err, "message_id", msg.ID)
+	}
+	return recipients
+}
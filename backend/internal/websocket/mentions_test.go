@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubMentionUsers map[string]uuid.UUID
+
+func (u stubMentionUsers) GetByUsernames(_ context.Context, usernames []string) ([]domain.User, error) {
+	var users []domain.User
+	for _, name := range usernames {
+		if id, ok := u[name]; ok {
+			users = append(users, domain.User{ID: id, Username: name})
+		}
+	}
+	return users, nil
+}
+
+type memoryMentionStore struct {
+	members map[uuid.UUID]bool
+	saved   []uuid.UUID
+}
+
+func (s *memoryMentionStore) GetMembersIn(_ context.Context, _ uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	in := make(map[uuid.UUID]bool)
+	for _, id := range userIDs {
+		if s.members[id] {
+			in[id] = true
+		}
+	}
+	return in, nil
+}
+
+func (s *memoryMentionStore) CreateMentions(_ context.Context, _ *domain.Message, userIDs []uuid.UUID) error {
+	s.saved = append(s.saved, userIDs...)
+	return nil
+}
+
+func TestMentions_Record(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+
+	sender, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	users := stubMentionUsers{"alice": sender, "bob": bob, "carol": carol}
+	store := &memoryMentionStore{members: map[uuid.UUID]bool{sender: true, bob: true}}
+	mentions := NewMentions(users, store, NewPubSubBroadcaster(ps), logger)
+
+	bobEvents := collectTopic(t, ps, pubsub.Topics.User(bob.String()))
+	carolEvents := collectTopic(t, ps, pubsub.Topics.User(carol.String()))
+
+	msg := &domain.Message{ID: uuid.New(), ConversationID: uuid.New(), SenderID: &sender, BodyText: "@bob @carol @alice @nobody lunch?", CreatedAt: time.Now()}
+	notified := mentions.Record(context.Background(), msg, "alice")
+	assert.Equal(t, []uuid.UUID{bob}, notified, "non-members, unknown names and the sender are ignored")
+	assert.Equal(t, []uuid.UUID{bob}, store.saved)
+
+	select {
+	case ev := <-bobEvents:
+		assert.Equal(t, EventTypeMention, ev.Type)
+		var p MentionPayload
+		require.NoError(t, json.Unmarshal(ev.Payload, &p))
+		assert.Equal(t, msg.ID, p.MessageID)
+		assert.Equal(t, msg.ConversationID, p.ConversationID)
+		assert.Equal(t, sender, p.SenderID)
+		assert.Equal(t, "alice", p.SenderUsername)
+	case <-time.After(time.Second):
+		t.Fatal("expected a mention event on bob's topic")
+	}
+
+	select {
+	case <-carolEvents:
+		t.Fatal("carol isn't a member and shouldn't be notified")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var disabled *Mentions
+	assert.Nil(t, disabled.Record(context.Background(), msg, "alice"))
+}
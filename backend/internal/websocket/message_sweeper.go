@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// ExpiredMessageStore removes disappearing messages that have outlived their TTL
+type ExpiredMessageStore interface {
+	// DeleteExpiredMessages deletes every message that expired at or before now and returns them
+	DeleteExpiredMessages(ctx context.Context, now time.Time) ([]domain.Message, error)
+}
+
+// MessageSweeper periodically deletes expired disappearing messages and tells
+// the affected rooms
+type MessageSweeper struct {
+	store       ExpiredMessageStore
+	broadcaster RoomBroadcaster
+	interval    time.Duration
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewMessageSweeper creates a sweeper that runs every interval
+func NewMessageSweeper(store ExpiredMessageStore, broadcaster RoomBroadcaster, interval time.Duration, logger *slog.Logger) *MessageSweeper {
+	return &MessageSweeper{
+		store:       store,
+		broadcaster: broadcaster,
+		interval:    interval,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run sweeps until ctx is cancelled
+func (s *MessageSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep deletes every expired message and broadcasts message.deleted for
+// each, with no deleting user. Whisper deletions only reach their audience.
+// Returns how many messages were removed.
+func (s *MessageSweeper) Sweep(ctx context.Context) int {
+	expired, err := s.store.DeleteExpiredMessages(ctx, s.now())
+	if err != nil {
+		s.logger.Error("failed to sweep expired messages", "error", err)
+		return 0
+	}
+
+	for i := range expired {
+		msg := &expired[i]
+		if err := s.broadcaster.BroadcastMessageDeleted(ctx, msg, uuid.Nil); err != nil {
+			s.logger.Error("failed to broadcast expired message", "error", err, "message_id", msg.ID)
+		}
+	}
+	if len(expired) > 0 {
+		s.logger.Info("deleted expired messages", "count", len(expired))
+	}
+	return len(expired)
+}
@@ -0,0 +1,119 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryMessageStore mimics expiry on the messages table
+type memoryMessageStore struct {
+	mu       sync.Mutex
+	messages []domain.Message
+}
+
+func (s *memoryMessageStore) DeleteExpiredMessages(_ context.Context, now time.Time) ([]domain.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept, expired []domain.Message
+	for _, m := range s.messages {
+		if m.Expired(now) {
+			expired = append(expired, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	s.messages = kept
+	return expired, nil
+}
+
+func (s *memoryMessageStore) list() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(s.messages))
+	for _, m := range s.messages {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestMessageSweeper_DeletesExpiredMessages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	now := time.Now()
+	past, future := now.Add(-time.Second), now.Add(time.Hour)
+	convID := uuid.New()
+	expiredMsg, laterMsg, permanentMsg := uuid.New(), uuid.New(), uuid.New()
+
+	store := &memoryMessageStore{messages: []domain.Message{
+		{ID: expiredMsg, ConversationID: convID, ExpiresAt: &past},
+		{ID: laterMsg, ConversationID: convID, ExpiresAt: &future},
+		{ID: permanentMsg, ConversationID: convID},
+	}}
+	events := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	sweeper := NewMessageSweeper(store, NewPubSubBroadcaster(ps), time.Minute, logger)
+	sweeper.now = func() time.Time { return now }
+
+	assert.Equal(t, 1, sweeper.Sweep(context.Background()))
+	assert.ElementsMatch(t, []uuid.UUID{laterMsg, permanentMsg}, store.list())
+
+	select {
+	case msg := <-events:
+		assert.Equal(t, EventTypeMessageDeleted, msg.Type)
+		var p MessageDeletedPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, expiredMsg, p.MessageID)
+		assert.Equal(t, convID, p.ConversationID)
+		assert.Equal(t, uuid.Nil, p.DeletedBy, "expiry has no deleting user")
+	case <-time.After(time.Second):
+		t.Fatal("expected message.deleted for the expired message")
+	}
+
+	sweeper.now = func() time.Time { return future }
+	assert.Equal(t, 1, sweeper.Sweep(context.Background()))
+	assert.Equal(t, []uuid.UUID{permanentMsg}, store.list(), "messages without expiry are never swept")
+}
+
+func TestMessageSweeper_ExpiredWhisperOnlyReachesAudience(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	now := time.Now()
+	past := now.Add(-time.Second)
+	sender, recipient, bystander, convID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &memoryMessageStore{messages: []domain.Message{
+		{ID: uuid.New(), ConversationID: convID, SenderID: &sender, VisibleTo: []uuid.UUID{sender, recipient}, ExpiresAt: &past},
+	}}
+	room := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+	toRecipient := collectTopic(t, ps, pubsub.Topics.User(recipient.String()))
+	toBystander := collectTopic(t, ps, pubsub.Topics.User(bystander.String()))
+
+	sweeper := NewMessageSweeper(store, NewPubSubBroadcaster(ps), time.Minute, logger)
+	sweeper.now = func() time.Time { return now }
+	assert.Equal(t, 1, sweeper.Sweep(context.Background()))
+
+	select {
+	case msg := <-toRecipient:
+		assert.Equal(t, EventTypeMessageDeleted, msg.Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected message.deleted for the whisper audience")
+	}
+
+	time.Sleep(50 * time.Millisecond) // let any stray events land
+	assert.Empty(t, room, "expired whispers don't go to the whole room")
+	assert.Empty(t, toBystander)
+}
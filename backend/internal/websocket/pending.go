@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxPendingPerUser bounds the unacknowledged queue so a client that never
+// acks cannot grow server memory without limit. Oldest entries are evicted first.
+const maxPendingPerUser = 500
+
+// pendingMaxAge is how long an unacked message waits for redelivery. Past
+// that a reconnecting client is better off refetching history.
+const pendingMaxAge = 10 * time.Minute
+
+// pendingEntry is a message pushed to a user that has not been acked yet
+type pendingEntry struct {
+	messageID      uuid.UUID
+	conversationID uuid.UUID
+	msg            *Message
+	at             time.Time
+}
+
+// pendingQueue tracks message.new events delivered to a user's devices that
+// the app has not yet confirmed rendering via message.ack. Entries are
+// retired on ack, when a resume cursor shows the client has them, or when
+// they age out; a resume redelivers the rest.
+type pendingQueue struct {
+	mu     sync.Mutex
+	byUser map[uuid.UUID][]pendingEntry
+	now    func() time.Time
+}
+
+func newPendingQueue() *pendingQueue {
+	return &pendingQueue{
+		byUser: make(map[uuid.UUID][]pendingEntry),
+		now:    time.Now,
+	}
+}
+
+// Add queues a message for a user until it is acked (no-op if already queued)
+func (q *pendingQueue) Add(userID, messageID, conversationID uuid.UUID, msg *Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := q.byUser[userID]
+	for _, e := range entries {
+		if e.messageID == messageID {
+			return
+		}
+	}
+
+	now := q.now()
+	entries = append(q.fresh(entries, now), pendingEntry{messageID: messageID, conversationID: conversationID, msg: msg, at: now})
+	if len(entries) > maxPendingPerUser {
+		entries = entries[len(entries)-maxPendingPerUser:]
+	}
+	q.byUser[userID] = entries
+}
+
+// Ack removes a message from the user's queue and returns its conversation.
+// Returns false if the message wasn't queued.
+func (q *pendingQueue) Ack(userID, messageID uuid.UUID) (uuid.UUID, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := q.byUser[userID]
+	for i, e := range entries {
+		if e.messageID == messageID {
+			entries = append(entries[:i], entries[i+1:]...)
+			if len(entries) == 0 {
+				delete(q.byUser, userID)
+			} else {
+				q.byUser[userID] = entries
+			}
+			return e.conversationID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// Resume returns the user's unacked messages a resuming client hasn't seen.
+// Those numbered at or before lastSeq reached the client before it dropped
+// and are retired.
+func (q *pendingQueue) Resume(userID uuid.UUID, lastSeq uint64) []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var kept []pendingEntry
+	var msgs []*Message
+	for _, e := range q.fresh(q.byUser[userID], q.now()) {
+		if e.msg.Seq != 0 && e.msg.Seq <= lastSeq {
+			continue
+		}
+		kept = append(kept, e)
+		msgs = append(msgs, e.msg)
+	}
+	q.set(userID, kept)
+	return msgs
+}
+
+// Prune drops expired entries for every user, freeing the queues of users
+// who never came back
+func (q *pendingQueue) Prune() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for userID, entries := range q.byUser {
+		q.set(userID, q.fresh(entries, now))
+	}
+}
+
+// fresh drops the entries older than pendingMaxAge; entries are oldest first
+func (q *pendingQueue) fresh(entries []pendingEntry, now time.Time) []pendingEntry {
+	cutoff := now.Add(-pendingMaxAge)
+	n := 0
+	for n < len(entries) && entries[n].at.Before(cutoff) {
+		n++
+	}
+	return entries[n:]
+}
+
+// set stores a user's entries, forgetting the user once none are left
+func (q *pendingQueue) set(userID uuid.UUID, entries []pendingEntry) {
+	if len(entries) == 0 {
+		delete(q.byUser, userID)
+		return
+	}
+	q.byUser[userID] = entries
+}
+
+// Len returns the number of unacked messages for a user
+func (q *pendingQueue) Len(userID uuid.UUID) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.byUser[userID])
+}
@@ -0,0 +1,251 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Pending Queue Tests
+// =============================================================================
+
+func TestPendingQueue_AckRemovesEntry(t *testing.T) {
+	q := newPendingQueue()
+	userID, msgID, convID := uuid.New(), uuid.New(), uuid.New()
+
+	q.Add(userID, msgID, convID, &Message{Type: EventTypeMessageNew})
+	require.Equal(t, 1, q.Len(userID))
+
+	gotConv, ok := q.Ack(userID, msgID)
+	assert.True(t, ok)
+	assert.Equal(t, convID, gotConv)
+	assert.Equal(t, 0, q.Len(userID))
+	assert.Empty(t, q.Resume(userID, 0))
+}
+
+func TestPendingQueue_UnackedKeptForRedelivery(t *testing.T) {
+	q := newPendingQueue()
+	userID, convID := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+
+	q.Add(userID, first, convID, &Message{Type: "first"})
+	q.Add(userID, second, convID, &Message{Type: "second"})
+
+	_, ok := q.Ack(userID, first)
+	require.True(t, ok)
+
+	pending := q.Resume(userID, 0)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "second", pending[0].Type)
+}
+
+func TestPendingQueue_AckUnknownMessage(t *testing.T) {
+	q := newPendingQueue()
+
+	_, ok := q.Ack(uuid.New(), uuid.New())
+	assert.False(t, ok)
+}
+
+func TestPendingQueue_AddIsIdempotent(t *testing.T) {
+	q := newPendingQueue()
+	userID, msgID, convID := uuid.New(), uuid.New(), uuid.New()
+
+	q.Add(userID, msgID, convID, &Message{})
+	q.Add(userID, msgID, convID, &Message{})
+
+	assert.Equal(t, 1, q.Len(userID))
+}
+
+func TestPendingQueue_BoundedPerUser(t *testing.T) {
+	q := newPendingQueue()
+	userID, convID := uuid.New(), uuid.New()
+
+	firstID := uuid.New()
+	q.Add(userID, firstID, convID, &Message{})
+	for i := 0; i < maxPendingPerUser; i++ {
+		q.Add(userID, uuid.New(), convID, &Message{})
+	}
+
+	assert.Equal(t, maxPendingPerUser, q.Len(userID))
+	_, ok := q.Ack(userID, firstID)
+	assert.False(t, ok, "oldest entry should have been evicted")
+}
+
+func TestPendingQueue_ExpiredEntriesDropped(t *testing.T) {
+	now := time.Now()
+	q := newPendingQueue()
+	q.now = func() time.Time { return now }
+	stale, fresh := uuid.New(), uuid.New()
+	convID := uuid.New()
+
+	q.Add(stale, uuid.New(), convID, &Message{})
+	now = now.Add(pendingMaxAge / 2)
+	q.Add(fresh, uuid.New(), convID, &Message{})
+	now = now.Add(pendingMaxAge/2 + time.Second)
+
+	q.Prune()
+	assert.Equal(t, 0, q.Len(stale))
+	assert.NotContains(t, q.byUser, stale, "users left with nothing are forgotten")
+	assert.Equal(t, 1, q.Len(fresh))
+}
+
+func TestPendingQueue_ResumeRetiresWhatTheClientHas(t *testing.T) {
+	q := newPendingQueue()
+	userID, convID := uuid.New(), uuid.New()
+	seen := &Message{Type: "seen", Seq: 4}
+	missed := &Message{Type: "missed", Seq: 5}
+	unnumbered := &Message{Type: "unnumbered"}
+
+	q.Add(userID, uuid.New(), convID, seen)
+	q.Add(userID, uuid.New(), convID, missed)
+	q.Add(userID, uuid.New(), convID, unnumbered)
+
+	assert.Equal(t, []*Message{missed, unnumbered}, q.Resume(userID, 4))
+	assert.Equal(t, 2, q.Len(userID), "redelivered messages stay queued until acked")
+}
+
+// =============================================================================
+// Hub Ack Tests
+// =============================================================================
+
+func newTestAckHub(t *testing.T) (*Hub, *Client) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+	client := &Client{
+		hub:    hub,
+		send:   make(chan []byte, 256),
+		rooms:  make(map[uuid.UUID]bool),
+		logger: logger,
+	}
+	client.SetUser(uuid.New(), "alice")
+	return hub, client
+}
+
+func TestHub_DeliverToRoom_QueuesNewMessagesForRecipients(t *testing.T) {
+	hub, recipient := newTestAckHub(t)
+	convID, msgID := uuid.New(), uuid.New()
+
+	hub.rooms[convID] = map[*Client]bool{recipient: true}
+
+	payload, _ := json.Marshal(MessageNewPayload{ID: msgID, ConversationID: convID, SenderID: uuid.New()})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMessageNew, Payload: payload})
+
+	assert.Equal(t, 1, hub.pending.Len(recipient.UserID()))
+}
+
+func TestHub_DeliverToRoom_DoesNotQueueForSender(t *testing.T) {
+	hub, sender := newTestAckHub(t)
+	convID := uuid.New()
+
+	hub.rooms[convID] = map[*Client]bool{sender: true}
+
+	payload, _ := json.Marshal(MessageNewPayload{ID: uuid.New(), ConversationID: convID, SenderID: sender.UserID()})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMessageNew, Payload: payload})
+
+	assert.Equal(t, 0, hub.pending.Len(sender.UserID()))
+}
+
+func TestHub_DeliverToRoom_MemberLeftRemovesTheirConnections(t *testing.T) {
+	hub, banned := newTestAckHub(t)
+	other := &Client{hub: hub, send: make(chan []byte, 256), rooms: make(map[uuid.UUID]bool), logger: banned.logger}
+	other.SetUser(uuid.New(), "bob")
+	convID := uuid.New()
+
+	hub.rooms[convID] = map[*Client]bool{banned: true, other: true}
+	banned.JoinRoom(convID)
+
+	payload, _ := json.Marshal(MemberLeftPayload{ConversationID: convID, UserID: banned.UserID(), RemovedBy: other.UserID()})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMemberLeft, Payload: payload})
+	assert.Len(t, banned.send, 1, "the departing member is told before being removed")
+	assert.False(t, banned.IsInRoom(convID))
+
+	payload, _ = json.Marshal(MessageNewPayload{ID: uuid.New(), ConversationID: convID, SenderID: other.UserID()})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMessageNew, Payload: payload})
+	assert.Len(t, banned.send, 1, "nothing more reaches them")
+	assert.Len(t, other.send, 2)
+}
+
+func TestHub_HandleMessageAck_RemovesQueuedEntry(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	msgID := uuid.New()
+
+	hub.pending.Add(client.UserID(), msgID, uuid.New(), &Message{Type: EventTypeMessageNew})
+
+	payload, _ := json.Marshal(MessageAckPayload{MessageID: msgID.String()})
+	hub.handleMessageAck(client, payload)
+
+	assert.Equal(t, 0, hub.pending.Len(client.UserID()))
+}
+
+func TestHub_HandleMessageAck_OtherMessagesRemainQueued(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	acked, unacked := uuid.New(), uuid.New()
+
+	hub.pending.Add(client.UserID(), acked, uuid.New(), &Message{})
+	hub.pending.Add(client.UserID(), unacked, uuid.New(), &Message{})
+
+	payload, _ := json.Marshal(MessageAckPayload{MessageID: acked.String()})
+	hub.handleMessageAck(client, payload)
+
+	assert.Equal(t, 1, hub.pending.Len(client.UserID()))
+}
+
+func TestHub_HandleMessageAck_InvalidID(t *testing.T) {
+	hub, client := newTestAckHub(t)
+
+	hub.handleMessageAck(client, json.RawMessage(`{"message_id":"not-a-uuid"}`))
+
+	select {
+	case data := <-client.send:
+		assert.Contains(t, string(data), "invalid_message")
+	default:
+		t.Fatal("expected error for invalid message ID")
+	}
+}
+
+func TestHub_ResumeRedeliversUnackedMessagesOnce(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	convID := uuid.New()
+	hub.replay.Open(convID)
+	hub.rooms[convID] = map[*Client]bool{client: true}
+	client.JoinRoom(convID)
+
+	// Delivered but never acked, then the client drops
+	payload, _ := json.Marshal(MessageNewPayload{ID: uuid.New(), ConversationID: convID, SenderID: uuid.New()})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMessageNew, Payload: payload})
+	delivered := receiveMessage(t, client)
+	require.Equal(t, 1, hub.pending.Len(client.UserID()))
+
+	// Nothing is pushed until the client resumes
+	select {
+	case data := <-client.send:
+		t.Fatalf("unexpected %s", data)
+	default:
+	}
+
+	resume, _ := json.Marshal(ResumePayload{LastSeq: delivered.Seq - 1})
+	hub.handleResume(client, resume)
+
+	redelivered := receiveMessage(t, client)
+	assert.Equal(t, EventTypeMessageNew, redelivered.Type)
+	assert.Equal(t, delivered.Seq, redelivered.Seq)
+	done := receiveMessage(t, client)
+	require.Equal(t, EventTypeResumed, done.Type)
+	var resumed ResumedPayload
+	require.NoError(t, json.Unmarshal(done.Payload, &resumed))
+	assert.Equal(t, 1, resumed.Replayed, "the room replay and the pending queue hold the same message")
+
+	// Resuming past it retires it
+	resume, _ = json.Marshal(ResumePayload{LastSeq: delivered.Seq})
+	hub.handleResume(client, resume)
+	assert.Equal(t, 0, hub.pending.Len(client.UserID()))
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/observer/teatime/internal/domain"
+)
+
+// ExpiredPinStore removes lapsed time-bound pins
+type ExpiredPinStore interface {
+	// UnpinExpired deletes every pin that lapsed at or before now and returns them
+	UnpinExpired(ctx context.Context, now time.Time) ([]domain.PinnedMessage, error)
+}
+
+// PinSweeper periodically unpins expired messages and tells the affected rooms
+type PinSweeper struct {
+	store       ExpiredPinStore
+	broadcaster RoomBroadcaster
+	interval    time.Duration
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewPinSweeper creates a sweeper that runs every interval
+func NewPinSweeper(store ExpiredPinStore, broadcaster RoomBroadcaster, interval time.Duration, logger *slog.Logger) *PinSweeper {
+	return &PinSweeper{
+		store:       store,
+		broadcaster: broadcaster,
+		interval:    interval,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run sweeps until ctx is cancelled
+func (s *PinSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep unpins everything that has expired and broadcasts message.unpinned
+// for each. Returns how many pins were removed.
+func (s *PinSweeper) Sweep(ctx context.Context) int {
+	expired, err := s.store.UnpinExpired(ctx, s.now())
+	if err != nil {
+		s.logger.Error("failed to sweep expired pins", "error", err)
+		return 0
+	}
+
+	for _, pin := range expired {
+		if err := s.broadcaster.BroadcastMessageUnpinned(ctx, pin.ConversationID, pin.MessageID, nil); err != nil {
+			s.logger.Error("failed to broadcast expired pin", "error", err, "message_id", pin.MessageID)
+		}
+	}
+	if len(expired) > 0 {
+		s.logger.Info("unpinned expired messages", "count", len(expired))
+	}
+	return len(expired)
+}
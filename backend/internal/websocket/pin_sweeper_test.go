@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPinStore mimics the pinned_messages table
+type memoryPinStore struct {
+	mu   sync.Mutex
+	pins []domain.PinnedMessage
+}
+
+func (s *memoryPinStore) UnpinExpired(_ context.Context, now time.Time) ([]domain.PinnedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active, expired := domain.SplitExpiredPins(s.pins, now)
+	s.pins = active
+	return expired, nil
+}
+
+func (s *memoryPinStore) list() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(s.pins))
+	for _, p := range s.pins {
+		ids = append(ids, p.MessageID)
+	}
+	return ids
+}
+
+func TestPinSweeper_SweepsExpiredPins(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	convID := uuid.New()
+	expiredMsg, laterMsg, permanentMsg := uuid.New(), uuid.New(), uuid.New()
+
+	store := &memoryPinStore{pins: []domain.PinnedMessage{
+		{ConversationID: convID, MessageID: expiredMsg, PinnedAt: now.Add(-time.Hour), ExpiresAt: &past},
+		{ConversationID: convID, MessageID: laterMsg, PinnedAt: now, ExpiresAt: &future},
+		{ConversationID: convID, MessageID: permanentMsg, PinnedAt: now},
+	}}
+
+	events := make(chan *pubsub.Message, 4)
+	_, err := ps.Subscribe(context.Background(), pubsub.Topics.Room(convID.String()), func(_ context.Context, msg *pubsub.Message) {
+		events <- msg
+	})
+	require.NoError(t, err)
+
+	sweeper := NewPinSweeper(store, NewPubSubBroadcaster(ps), time.Minute, logger)
+	sweeper.now = func() time.Time { return now }
+
+	assert.Equal(t, 1, sweeper.Sweep(context.Background()))
+	assert.ElementsMatch(t, []uuid.UUID{laterMsg, permanentMsg}, store.list(), "expired pin removed from the list")
+
+	select {
+	case msg := <-events:
+		assert.Equal(t, EventTypeMessageUnpinned, msg.Type)
+		var p MessageUnpinnedPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, expiredMsg, p.MessageID)
+		assert.Equal(t, convID, p.ConversationID)
+		assert.True(t, p.Expired)
+		assert.Nil(t, p.UnpinnedBy)
+	case <-time.After(time.Second):
+		t.Fatal("expected message.unpinned for the expired pin")
+	}
+
+	// Nothing left to sweep until the next pin lapses
+	assert.Equal(t, 0, sweeper.Sweep(context.Background()))
+
+	sweeper.now = func() time.Time { return future }
+	assert.Equal(t, 1, sweeper.Sweep(context.Background()))
+	assert.Equal(t, []uuid.UUID{permanentMsg}, store.list(), "pins without expiry are never swept")
+}
@@ -0,0 +1,76 @@
+package websocket
+
+import "github.com/google/uuid"
+
+// PresenceProvider reports which users currently hold a live connection.
+// API handlers use it to answer presence queries without touching the hub directly.
+type PresenceProvider interface {
+	// IsOnline reports whether the user has at least one authenticated connection
+	IsOnline(userID uuid.UUID) bool
+
+	// OnlineStatus reports connection state for each of the given users
+	OnlineStatus(userIDs []uuid.UUID) map[uuid.UUID]bool
+}
+
+// IsOnline reports whether the user has at least one authenticated connection
+func (h *Hub) IsOnline(userID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
+
+// OnlineStatus reports connection state for each of the given users
+func (h *Hub) OnlineStatus(userIDs []uuid.UUID) map[uuid.UUID]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		status[id] = len(h.clients[id]) > 0
+	}
+	return status
+}
+
+// broadcastPresence tells every conversation the user belongs to whether
+// they're online. It runs after a user's first connection authenticates or
+// their last one closes; the state sent is read when publishing, so quick
+// reconnects can't leave members with a stale status. Users who hide their
+// online status are skipped.
+func (h *Hub) broadcastPresence(userID uuid.UUID, username string) {
+	if h.userRepo == nil || h.convRepo == nil {
+		return
+	}
+	ctx := h.Context()
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		h.logger.Error("presence: get user failed", "user_id", userID, "error", err)
+		return
+	}
+	if !user.ShowOnlineStatus {
+		return
+	}
+
+	convs, err := h.convRepo.GetUserConversations(ctx, userID)
+	if err != nil {
+		h.logger.Error("presence: get conversations failed", "user_id", userID, "error", err)
+		return
+	}
+	convIDs := make([]uuid.UUID, len(convs))
+	for i, c := range convs {
+		convIDs[i] = c.ID
+	}
+	h.publishPresence(userID, username, convIDs)
+}
+
+// publishPresence sends the user's current online state to each conversation
+func (h *Hub) publishPresence(userID uuid.UUID, username string, convIDs []uuid.UUID) {
+	payload := PresencePayload{
+		UserID:   userID,
+		Username: username,
+		Online:   h.IsOnline(userID),
+	}
+	for _, convID := range convIDs {
+		h.BroadcastToRoom(convID, EventTypePresence, payload)
+	}
+}
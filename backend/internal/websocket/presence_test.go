@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_OnlineStatus_MixedUsers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+
+	onlineID, offlineID := uuid.New(), uuid.New()
+	hub.clients[onlineID] = map[*Client]bool{{}: true}
+
+	status := hub.OnlineStatus([]uuid.UUID{onlineID, offlineID})
+	assert.True(t, status[onlineID])
+	assert.False(t, status[offlineID])
+	assert.Len(t, status, 2)
+
+	assert.True(t, hub.IsOnline(onlineID))
+	assert.False(t, hub.IsOnline(offlineID))
+}
+
+func TestHub_PublishPresence_ReachesEveryConversation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	hub := NewHub(nil, nil, nil, nil, ps, logger)
+
+	userID, convA, convB := uuid.New(), uuid.New(), uuid.New()
+	roomA := collectTopic(t, ps, pubsub.Topics.Room(convA.String()))
+	roomB := collectTopic(t, ps, pubsub.Topics.Room(convB.String()))
+
+	receive := func(room <-chan *pubsub.Message) PresencePayload {
+		t.Helper()
+		select {
+		case msg := <-room:
+			require.Equal(t, EventTypePresence, msg.Type)
+			var p PresencePayload
+			require.NoError(t, json.Unmarshal(msg.Payload, &p))
+			return p
+		case <-time.After(time.Second):
+			t.Fatal("expected a presence event")
+			return PresencePayload{}
+		}
+	}
+
+	// First connection
+	hub.clients[userID] = map[*Client]bool{{}: true}
+	hub.publishPresence(userID, "alice", []uuid.UUID{convA, convB})
+	for _, room := range []<-chan *pubsub.Message{roomA, roomB} {
+		p := receive(room)
+		assert.Equal(t, userID, p.UserID)
+		assert.Equal(t, "alice", p.Username)
+		assert.True(t, p.Online)
+	}
+
+	// Last connection closed
+	delete(hub.clients, userID)
+	hub.publishPresence(userID, "alice", []uuid.UUID{convA, convB})
+	assert.False(t, receive(roomA).Online)
+	assert.False(t, receive(roomB).Online)
+}
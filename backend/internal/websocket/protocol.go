@@ -9,27 +9,49 @@ import (
 
 // Event types for client -> server
 const (
-	EventTypeAuth        = "auth"
-	EventTypeRoomJoin    = "room.join"
-	EventTypeRoomLeave   = "room.leave"
-	EventTypeMessageSend = "message.send"
-	EventTypeTypingStart = "typing.start"
-	EventTypeTypingStop  = "typing.stop"
-	EventTypeReceiptRead = "receipt.read"
+	EventTypeAuth            = "auth"
+	EventTypeRoomJoin        = "room.join"
+	EventTypeRoomLeave       = "room.leave"
+	EventTypeMessageSend     = "message.send"
+	EventTypeTypingStart     = "typing.start"
+	EventTypeTypingStop      = "typing.stop"
+	EventTypeReceiptRead     = "receipt.read"
+	EventTypeReceiptReadUpTo = "receipt.read_up_to"
+	EventTypeMessageAck      = "message.ack"
+	EventTypeResume          = "resume"
 )
 
 // Event types for server -> client
 const (
-	EventTypeError          = "error"
-	EventTypeAuthSuccess    = "auth.success"
-	EventTypeMessageNew     = "message.new"
-	EventTypeMessageDeleted = "message.deleted"
-	EventTypeTyping         = "typing"
-	EventTypeReceiptUpdate  = "receipt.updated"
-	EventTypeMemberJoined   = "room.member_joined"
-	EventTypeMemberLeft     = "room.member_left"
-	EventTypeRoomUpdated    = "room.updated"
-	EventTypePresence       = "presence"
+	EventTypeError             = "error"
+	EventTypeAuthSuccess       = "auth.success"
+	EventTypeMessageNew        = "message.new"
+	EventTypeMessageSent       = "message.sent"
+	EventTypeMessageDeleted    = "message.deleted"
+	EventTypeMessagesDeleted   = "messages.deleted"
+	EventTypeMessageEdited     = "message.edited"
+	EventTypeMessageBatch      = "message.batch"
+	EventTypeMessagePinned     = "message.pinned"
+	EventTypeMessageUnpinned   = "message.unpinned"
+	EventTypeMessagePreview    = "message.preview"
+	EventTypePinsReordered     = "pinned.reordered"
+	EventTypeConversationRead  = "conversation.read"
+	EventTypeMessageStarred    = "message.starred"
+	EventTypeMessageUnstarred  = "message.unstarred"
+	EventTypeMention           = "mention"
+	EventTypeTyping            = "typing"
+	EventTypeReceiptUpdate     = "receipt.updated"
+	EventTypeMemberJoined      = "room.member_joined"
+	EventTypeMemberLeft        = "room.member_left"
+	EventTypeRoomUpdated       = "room.updated"
+	EventTypeOwnerChanged      = "room.owner_changed"
+	EventTypeMemberRoleChanged = "member.role_changed"
+	EventTypeRoomReadOnly      = "room.read_only"
+	EventTypeRoomTTLUpdated    = "room.ttl_updated"
+	EventTypePresence          = "presence"
+	EventTypeUserThrottled     = "user.throttled"
+	EventTypeServerBusy        = "server.busy"
+	EventTypeResumed           = "resumed"
 )
 
 // Message is the base WebSocket message envelope
@@ -37,6 +59,8 @@ type Message struct {
 	Type      string          `json:"type"`
 	Payload   json.RawMessage `json:"payload,omitempty"`
 	Timestamp time.Time       `json:"timestamp,omitempty"`
+	Text      string          `json:"text,omitempty"` // system event line in the client's locale
+	Seq       uint64          `json:"seq,omitempty"`  // room events only, for resume; local to one server
 }
 
 // NewMessage creates a message with the current timestamp
@@ -58,7 +82,9 @@ func NewMessage(eventType string, payload interface{}) (*Message, error) {
 
 // AuthPayload for authenticating the WebSocket connection
 type AuthPayload struct {
-	Token string `json:"token"` // JWT access token
+	Token    string `json:"token"`              // JWT access token
+	Locale   string `json:"locale,omitempty"`   // BCP 47 tag, e.g. "fr-CA"; defaults to English
+	Timezone string `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Paris"; defaults to UTC
 }
 
 // RoomJoinPayload for joining a conversation room
@@ -73,10 +99,13 @@ type RoomLeavePayload struct {
 
 // MessageSendPayload for sending a message via WebSocket
 type MessageSendPayload struct {
-	ConversationID string `json:"conversation_id"`
-	BodyText       string `json:"body_text"`
-	AttachmentID   string `json:"attachment_id,omitempty"`
-	TempID         string `json:"temp_id,omitempty"` // Client-side temp ID for optimistic UI
+	ConversationID string   `json:"conversation_id"`
+	BodyText       string   `json:"body_text"`
+	AttachmentID   string   `json:"attachment_id,omitempty"`
+	AttachmentIDs  []string `json:"attachment_ids,omitempty"` // Several attachments, in display order
+	TempID         string   `json:"temp_id,omitempty"`        // Client-side temp ID for optimistic UI
+	RecipientIDs   []string `json:"recipient_ids,omitempty"`  // Whisper to these members only
+	ReplyToID      string   `json:"reply_to_id,omitempty"`    // Message in the same conversation being replied to
 }
 
 // TypingPayload for typing indicators
@@ -89,10 +118,34 @@ type ReceiptReadPayload struct {
 	MessageID string `json:"message_id"`
 }
 
+// ReceiptReadUpToPayload marks every message up to and including MessageID as read
+type ReceiptReadUpToPayload struct {
+	MessageID string `json:"message_id"`
+}
+
+// MessageAckPayload confirms the app rendered a delivered message
+type MessageAckPayload struct {
+	MessageID string `json:"message_id"`
+}
+
+// ResumePayload asks for the room events missed while disconnected, and the
+// messages not yet acked. Send it after rejoining rooms, with the highest seq
+// received before the drop.
+type ResumePayload struct {
+	LastSeq uint64 `json:"last_seq"`
+}
+
 // ============================================================================
 // Server -> Client Payloads
 // ============================================================================
 
+// ResumedPayload follows the events replayed for a resume
+type ResumedPayload struct {
+	LastSeq  uint64      `json:"last_seq"`       // resume from here next time
+	Replayed int         `json:"replayed"`       // events sent before this one
+	Gaps     []uuid.UUID `json:"gaps,omitempty"` // rooms where events may be missing; refetch their history
+}
+
 // ErrorPayload for error responses
 type ErrorPayload struct {
 	Code    string `json:"code"`
@@ -107,23 +160,59 @@ type AuthSuccessPayload struct {
 
 // MessageNewPayload broadcasts a new message to room members
 type MessageNewPayload struct {
-	ID             uuid.UUID          `json:"id"`
-	ConversationID uuid.UUID          `json:"conversation_id"`
-	SenderID       uuid.UUID          `json:"sender_id"`
-	SenderUsername string             `json:"sender_username"`
-	BodyText       string             `json:"body_text"`
-	AttachmentID   *uuid.UUID         `json:"attachment_id,omitempty"`
-	Attachment     *AttachmentPayload `json:"attachment,omitempty"`
-	CreatedAt      time.Time          `json:"created_at"`
-	TempID         string             `json:"temp_id,omitempty"` // Echo back for sender
+	ID             uuid.UUID           `json:"id"`
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	SenderID       uuid.UUID           `json:"sender_id"`
+	SenderUsername string              `json:"sender_username"`
+	BodyText       string              `json:"body_text"`
+	AttachmentID   *uuid.UUID          `json:"attachment_id,omitempty"` // Set when there's exactly one attachment
+	Attachment     *AttachmentPayload  `json:"attachment,omitempty"`
+	AttachmentIDs  []uuid.UUID         `json:"attachment_ids,omitempty"`
+	Attachments    []AttachmentPayload `json:"attachments,omitempty"`
+	ForwardedFrom  *uuid.UUID          `json:"forwarded_from,omitempty"`
+	VisibleTo      []uuid.UUID         `json:"visible_to,omitempty"` // Set on whispers
+	ReplyToID      *uuid.UUID          `json:"reply_to_id,omitempty"`
+	ReplyTo        *ReplyPreview       `json:"reply_to,omitempty"` // Quote of ReplyToID, if every recipient can see it
+	CreatedAt      time.Time           `json:"created_at"`
+	ExpiresAt      *time.Time          `json:"expires_at,omitempty"` // Set on disappearing messages
+	TempID         string              `json:"temp_id,omitempty"`    // Echo back for sender
+}
+
+// ReplyPreview quotes the message a new message replies to
+type ReplyPreview struct {
+	ID        uuid.UUID  `json:"id"`
+	SenderID  *uuid.UUID `json:"sender_id,omitempty"`
+	BodyText  string     `json:"body_text"` // Truncated to domain.MaxReplyPreviewLength
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MessageSentPayload acknowledges to the sending connection that its
+// message.send was persisted, keyed by the client's temp ID
+type MessageSentPayload struct {
+	TempID         string    `json:"temp_id,omitempty"`
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MessageBatchPayload delivers several new messages at once (e.g. a forwarded selection)
+type MessageBatchPayload struct {
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	Messages       []MessageNewPayload `json:"messages"`
 }
 
 // AttachmentPayload contains attachment details
 type AttachmentPayload struct {
-	ID        uuid.UUID `json:"id"`
-	Filename  string    `json:"filename"`
-	MimeType  string    `json:"mime_type"`
-	SizeBytes int64     `json:"size_bytes"`
+	ID         uuid.UUID `json:"id"`
+	Filename   string    `json:"filename"`
+	MimeType   string    `json:"mime_type"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Kind       string    `json:"kind"`                  // "voice", "image" or "file"
+	DurationMs *int64    `json:"duration_ms,omitempty"` // voice clips only
+
+	// Thumbnails maps each image variant's size (longest edge, in pixels)
+	// to a download URL. Empty for non-images or without storage.
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
 }
 
 // TypingBroadcastPayload broadcasts typing status
@@ -132,6 +221,21 @@ type TypingBroadcastPayload struct {
 	UserID         uuid.UUID `json:"user_id"`
 	Username       string    `json:"username"`
 	IsTyping       bool      `json:"is_typing"`
+	InCall         bool      `json:"in_call,omitempty"` // sender is in the conversation's call
+}
+
+// UserThrottledPayload tells a user they've been muted for flooding, or that the mute was lifted
+type UserThrottledPayload struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Throttled bool       `json:"throttled"`
+	Until     *time.Time `json:"until,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// ServerBusyPayload tells a client the server is shedding connections and
+// when to try again. The delay is jittered per client to spread reconnects.
+type ServerBusyPayload struct {
+	RetryAfter float64 `json:"retry_after"` // seconds
 }
 
 // PresencePayload for online/offline status
@@ -165,11 +269,117 @@ type RoomUpdatedPayload struct {
 	UpdatedBy      uuid.UUID `json:"updated_by"`
 }
 
+// RoomReadOnlyPayload broadcasts when a group's read-only (announcement) mode
+// is switched; while on, only admins may post
+type RoomReadOnlyPayload struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	ReadOnly       bool      `json:"read_only"`
+	UpdatedBy      uuid.UUID `json:"updated_by"`
+}
+
+// RoomTTLUpdatedPayload broadcasts when a conversation's disappearing-message
+// lifetime changes; 0 means disappearing messages were turned off
+type RoomTTLUpdatedPayload struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	TTLSeconds     int       `json:"ttl_seconds"`
+	UpdatedBy      uuid.UUID `json:"updated_by"`
+}
+
+// OwnerChangedPayload broadcasts when group ownership is transferred
+type OwnerChangedPayload struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	PreviousOwner  uuid.UUID `json:"previous_owner"`
+	NewOwner       uuid.UUID `json:"new_owner"`
+}
+
+// MemberRoleChangedPayload broadcasts when an admin changes a member's role
+type MemberRoleChangedPayload struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Role           string    `json:"role"`
+	ChangedBy      uuid.UUID `json:"changed_by"`
+}
+
+// MessagePinnedPayload broadcasts when a message is pinned
+type MessagePinnedPayload struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MessageID      uuid.UUID  `json:"message_id"`
+	PinnedBy       uuid.UUID  `json:"pinned_by"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// MessageUnpinnedPayload broadcasts when a pin is removed or expires
+type MessageUnpinnedPayload struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MessageID      uuid.UUID  `json:"message_id"`
+	UnpinnedBy     *uuid.UUID `json:"unpinned_by,omitempty"` // nil when the pin expired
+	Expired        bool       `json:"expired,omitempty"`
+}
+
+// PinsReorderedPayload broadcasts the new manual order of a conversation's pins
+type PinsReorderedPayload struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	MessageIDs     []uuid.UUID `json:"message_ids"`
+	ReorderedBy    uuid.UUID   `json:"reordered_by"`
+}
+
+// ConversationReadPayload tells a user's own connections that their read
+// cursor for a conversation moved, so every device shows the same badge
+type ConversationReadPayload struct {
+	ConversationID    uuid.UUID  `json:"conversation_id"`
+	UnreadCount       int        `json:"unread_count"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
+}
+
+// MessageStarPayload tells a user's own connections that they starred or
+// unstarred a message on another device
+type MessageStarPayload struct {
+	MessageID      uuid.UUID  `json:"message_id"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"` // Set when starring
+}
+
 // MessageDeletedPayload broadcasts when a message is deleted
 type MessageDeletedPayload struct {
 	MessageID      uuid.UUID `json:"message_id"`
 	ConversationID uuid.UUID `json:"conversation_id"`
-	DeletedBy      uuid.UUID `json:"deleted_by"`
+	DeletedBy      uuid.UUID `json:"deleted_by"` // nil UUID if the message expired
+}
+
+// MessagesDeletedPayload broadcasts a batch of messages deleted at once
+type MessagesDeletedPayload struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	MessageIDs     []uuid.UUID `json:"message_ids"`
+	DeletedBy      uuid.UUID   `json:"deleted_by"`
+}
+
+// MessageEditedPayload broadcasts a message's new body after an edit
+type MessageEditedPayload struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	BodyText       string    `json:"body_text"`
+	EditedBy       uuid.UUID `json:"edited_by"`
+	EditedAt       time.Time `json:"edited_at"`
+}
+
+// MessagePreviewPayload carries the link preview card fetched for a message
+type MessagePreviewPayload struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	URL            string    `json:"url"`
+	Title          string    `json:"title,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	ImageURL       string    `json:"image_url,omitempty"`
+}
+
+// MentionPayload tells a user they were @mentioned, wherever they are
+type MentionPayload struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	SenderUsername string    `json:"sender_username"`
+	BodyText       string    `json:"body_text"` // Truncated to domain.MaxReplyPreviewLength
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 // ReceiptUpdatePayload broadcasts when message receipts are updated
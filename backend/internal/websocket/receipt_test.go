@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_BroadcastReadBatch_EmitsSingleEventForSpan(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	hub := NewHub(nil, nil, nil, nil, ps, logger)
+
+	convID, readerID := uuid.New(), uuid.New()
+	span := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+
+	events := make(chan *pubsub.Message, 8)
+	_, err := ps.Subscribe(context.Background(), pubsub.Topics.Room(convID.String()), func(_ context.Context, msg *pubsub.Message) {
+		events <- msg
+	})
+	require.NoError(t, err)
+
+	hub.broadcastReadBatch(convID, readerID, span)
+
+	select {
+	case msg := <-events:
+		assert.Equal(t, EventTypeReceiptUpdate, msg.Type)
+		var p ReceiptBatchUpdatePayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, "read", p.Status)
+		assert.Equal(t, readerID, p.UserID)
+		assert.Equal(t, span, p.MessageIDs)
+	case <-time.After(time.Second):
+		t.Fatal("expected a batched receipt")
+	}
+
+	select {
+	case <-events:
+		t.Fatal("read-up-to should emit exactly one broadcast")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_BroadcastReadBatch_NothingNewlyRead(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	hub := NewHub(nil, nil, nil, nil, ps, logger)
+
+	convID := uuid.New()
+	events := make(chan *pubsub.Message, 1)
+	_, err := ps.Subscribe(context.Background(), pubsub.Topics.Room(convID.String()), func(_ context.Context, msg *pubsub.Message) {
+		events <- msg
+	})
+	require.NoError(t, err)
+
+	hub.broadcastReadBatch(convID, uuid.New(), nil)
+
+	select {
+	case <-events:
+		t.Fatal("no broadcast expected when nothing was marked")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_HandleReceiptReadUpTo_InvalidID(t *testing.T) {
+	hub, client := newTestAckHub(t)
+
+	hub.handleReceiptReadUpTo(client, json.RawMessage(`{"message_id":"nope"}`))
+
+	select {
+	case data := <-client.send:
+		assert.Contains(t, string(data), "invalid_message")
+	default:
+		t.Fatal("expected error for invalid message ID")
+	}
+}
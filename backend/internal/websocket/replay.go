@@ -0,0 +1,208 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults for the missed-event replay buffer
+const (
+	DefaultReplayBufferSize = 200 // events kept per conversation
+	DefaultReplayMaxAge     = 2 * time.Minute
+)
+
+// replayEvent is a room event kept for clients that reconnect
+type replayEvent struct {
+	msg *Message
+	at  time.Time
+}
+
+// replayRoom holds a conversation's recent events, oldest first. Events up to
+// and including dropped are no longer known: evicted, or sent before the hub
+// was listening to the room.
+type replayRoom struct {
+	events  []replayEvent
+	dropped uint64
+}
+
+// replayBuffer numbers room events as they're delivered and keeps the recent
+// ones per conversation, bounded by count and age, so a client that drops
+// and reconnects can be sent what it missed. Sequence numbers are local to
+// this hub.
+type replayBuffer struct {
+	mu     sync.Mutex
+	seq    uint64
+	size   int
+	maxAge time.Duration
+	rooms  map[uuid.UUID]*replayRoom
+	now    func() time.Time
+}
+
+func newReplayBuffer(size int, maxAge time.Duration) *replayBuffer {
+	if size <= 0 {
+		return nil
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultReplayMaxAge
+	}
+	return &replayBuffer{
+		size:   size,
+		maxAge: maxAge,
+		rooms:  make(map[uuid.UUID]*replayRoom),
+		now:    time.Now,
+	}
+}
+
+// SetReplayBuffer configures missed-event replay: the last size events of
+// each conversation, up to maxAge old, are kept for clients that resume. A
+// size of 0 disables replay.
+func (h *Hub) SetReplayBuffer(size int, maxAge time.Duration) {
+	h.replay = newReplayBuffer(size, maxAge)
+}
+
+// Open starts buffering a room, marking everything before now as unknown
+func (b *replayBuffer) Open(roomID uuid.UUID) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.rooms[roomID]; !ok {
+		b.rooms[roomID] = &replayRoom{dropped: b.seq}
+	}
+}
+
+// Close stops buffering a room and forgets its events
+func (b *replayBuffer) Close(roomID uuid.UUID) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.rooms, roomID)
+}
+
+// Record numbers msg with the next sequence and keeps it for roomID
+func (b *replayBuffer) Record(roomID uuid.UUID, msg *Message) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	room, ok := b.rooms[roomID]
+	if !ok {
+		room = &replayRoom{dropped: b.seq}
+		b.rooms[roomID] = room
+	}
+	b.seq++
+	msg.Seq = b.seq
+	room.events = append(room.events, replayEvent{msg: msg, at: b.now()})
+	b.trim(room)
+}
+
+// trim evicts a room's events beyond the size limit or older than maxAge
+func (b *replayBuffer) trim(room *replayRoom) {
+	cutoff := b.now().Add(-b.maxAge)
+	n := 0
+	for n < len(room.events) && (len(room.events)-n > b.size || room.events[n].at.Before(cutoff)) {
+		n++
+	}
+	if n > 0 {
+		room.dropped = room.events[n-1].msg.Seq
+		room.events = append(room.events[:0], room.events[n:]...)
+	}
+}
+
+// Since returns the events of the given rooms numbered after seq, in order,
+// and the rooms where some of those events are no longer known
+func (b *replayBuffer) Since(roomIDs []uuid.UUID, seq uint64) ([]*Message, []uuid.UUID) {
+	if b == nil {
+		return nil, roomIDs
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []*Message
+	var gaps []uuid.UUID
+	for _, roomID := range roomIDs {
+		room, ok := b.rooms[roomID]
+		if ok {
+			b.trim(room)
+		}
+		// A seq from the future was issued by another hub or before a restart
+		if !ok || seq < room.dropped || seq > b.seq {
+			gaps = append(gaps, roomID)
+		}
+		if !ok {
+			continue
+		}
+		for _, e := range room.events {
+			if e.msg.Seq > seq {
+				events = append(events, e.msg)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, gaps
+}
+
+// handleResume replays the room events a reconnecting client missed, and the
+// messages it never acked. It is sent after rejoining rooms, with the last
+// seq the client saw; rooms whose gap can't be filled are listed so the
+// client can refetch them.
+func (h *Hub) handleResume(client *Client, payload json.RawMessage) {
+	if client.UserID() == uuid.Nil {
+		client.sendError("unauthorized", "Not authenticated")
+		return
+	}
+
+	var p ResumePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		client.sendError("invalid_payload", "Invalid resume payload")
+		return
+	}
+
+	events, gaps := h.replay.Since(client.GetRooms(), p.LastSeq)
+
+	// Redeliver messages a previous connection received but never acked,
+	// unless the room replay already covers them
+	replayed := make(map[*Message]bool, len(events))
+	for _, msg := range events {
+		replayed[msg] = true
+	}
+	for _, msg := range h.pending.Resume(client.UserID(), p.LastSeq) {
+		if !replayed[msg] {
+			events = append(events, msg)
+		}
+	}
+
+	for _, msg := range events {
+		_ = client.Send(client.localize(msg))
+	}
+
+	resumed, err := NewMessage(EventTypeResumed, ResumedPayload{
+		LastSeq:  max(client.LastSeq(), p.LastSeq),
+		Replayed: len(events),
+		Gaps:     gaps,
+	})
+	if err != nil {
+		return
+	}
+	_ = client.Send(resumed)
+}
+
+// releaseRoom drops the hub's subscription to a room with no local clients
+// left. With replay on, the subscription outlives them by the replay window
+// so events keep being buffered for clients about to reconnect.
+func (h *Hub) releaseRoom(roomID uuid.UUID) {
+	if h.replay == nil {
+		h.unsubscribeFromRoom(roomID)
+		return
+	}
+	time.AfterFunc(h.replay.maxAge, func() { h.unsubscribeFromRoom(roomID) })
+}
@@ -0,0 +1,151 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordN(b *replayBuffer, roomID uuid.UUID, n int) []*Message {
+	msgs := make([]*Message, n)
+	for i := range msgs {
+		msgs[i] = &Message{Type: EventTypeReceiptUpdate}
+		b.Record(roomID, msgs[i])
+	}
+	return msgs
+}
+
+func TestReplayBuffer_ReplaysEventsAfterSeq(t *testing.T) {
+	b := newReplayBuffer(10, time.Minute)
+	roomA, roomB := uuid.New(), uuid.New()
+	b.Open(roomA)
+	b.Open(roomB)
+
+	a := recordN(b, roomA, 2)
+	bb := recordN(b, roomB, 1)
+	a = append(a, recordN(b, roomA, 1)...)
+
+	events, gaps := b.Since([]uuid.UUID{roomA, roomB}, a[0].Seq)
+	assert.Empty(t, gaps)
+	assert.Equal(t, []*Message{a[1], bb[0], a[2]}, events, "rooms are merged in sequence order")
+
+	events, gaps = b.Since([]uuid.UUID{roomA}, a[2].Seq)
+	assert.Empty(t, events)
+	assert.Empty(t, gaps)
+}
+
+func TestReplayBuffer_BoundedByCount(t *testing.T) {
+	b := newReplayBuffer(3, time.Minute)
+	roomID := uuid.New()
+	b.Open(roomID)
+	msgs := recordN(b, roomID, 5)
+
+	events, gaps := b.Since([]uuid.UUID{roomID}, msgs[1].Seq)
+	assert.Equal(t, msgs[2:], events)
+	assert.Empty(t, gaps, "everything after seq is still held")
+
+	events, gaps = b.Since([]uuid.UUID{roomID}, msgs[0].Seq)
+	assert.Equal(t, msgs[2:], events)
+	assert.Equal(t, []uuid.UUID{roomID}, gaps, "the second event was evicted")
+}
+
+func TestReplayBuffer_BoundedByAge(t *testing.T) {
+	now := time.Now()
+	b := newReplayBuffer(10, time.Minute)
+	b.now = func() time.Time { return now }
+	roomID := uuid.New()
+	b.Open(roomID)
+
+	old := recordN(b, roomID, 1)
+	now = now.Add(45 * time.Second)
+	recent := recordN(b, roomID, 1)
+	now = now.Add(30 * time.Second)
+
+	events, gaps := b.Since([]uuid.UUID{roomID}, 0)
+	assert.Equal(t, recent, events)
+	assert.Equal(t, []uuid.UUID{roomID}, gaps)
+
+	_, gaps = b.Since([]uuid.UUID{roomID}, old[0].Seq)
+	assert.Empty(t, gaps)
+}
+
+func TestReplayBuffer_UnknownHistoryIsAGap(t *testing.T) {
+	b := newReplayBuffer(10, time.Minute)
+	other, joined := uuid.New(), uuid.New()
+	b.Open(other)
+	recordN(b, other, 3)
+	b.Open(joined)
+
+	_, gaps := b.Since([]uuid.UUID{joined}, 1)
+	assert.Equal(t, []uuid.UUID{joined}, gaps, "events before the hub listened to the room are unknown")
+
+	_, gaps = b.Since([]uuid.UUID{joined}, 3)
+	assert.Empty(t, gaps)
+
+	_, gaps = b.Since([]uuid.UUID{joined}, 99)
+	assert.Equal(t, []uuid.UUID{joined}, gaps, "a seq from another server can't be trusted")
+
+	_, gaps = b.Since([]uuid.UUID{uuid.New()}, 3)
+	assert.Len(t, gaps, 1)
+}
+
+func TestReplayBuffer_Disabled(t *testing.T) {
+	b := newReplayBuffer(0, time.Minute)
+	assert.Nil(t, b)
+
+	roomID := uuid.New()
+	msg := &Message{}
+	b.Record(roomID, msg)
+	assert.Zero(t, msg.Seq)
+
+	events, gaps := b.Since([]uuid.UUID{roomID}, 0)
+	assert.Empty(t, events)
+	assert.Equal(t, []uuid.UUID{roomID}, gaps)
+}
+
+func TestHub_ResumeReplaysMissedRoomEvents(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	convID := uuid.New()
+	hub.replay.Open(convID)
+
+	payload, _ := json.Marshal(MemberJoinedPayload{ConversationID: convID, UserID: uuid.New(), Username: "bob"})
+	deliver := func() { hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMemberJoined, Payload: payload}) }
+
+	// Seen before the drop
+	hub.rooms[convID] = map[*Client]bool{client: true}
+	client.JoinRoom(convID)
+	deliver()
+	seen := receiveMessage(t, client)
+	require.NotZero(t, seen.Seq)
+	assert.Equal(t, seen.Seq, client.LastSeq())
+
+	// Missed while offline
+	delete(hub.rooms, convID)
+	deliver()
+	deliver()
+
+	// Reconnected and rejoined
+	_, reconnected := newTestAckHub(t)
+	reconnected.SetUser(client.UserID(), "alice")
+	reconnected.hub = hub
+	reconnected.JoinRoom(convID)
+	hub.rooms[convID] = map[*Client]bool{reconnected: true}
+
+	resume, _ := json.Marshal(ResumePayload{LastSeq: seen.Seq})
+	hub.handleResume(reconnected, resume)
+
+	first, second := receiveMessage(t, reconnected), receiveMessage(t, reconnected)
+	assert.Equal(t, []uint64{seen.Seq + 1, seen.Seq + 2}, []uint64{first.Seq, second.Seq})
+	assert.Equal(t, "bob joined the group", first.Text, "replayed events are localized")
+
+	done := receiveMessage(t, reconnected)
+	require.Equal(t, EventTypeResumed, done.Type)
+	var resumed ResumedPayload
+	require.NoError(t, json.Unmarshal(done.Payload, &resumed))
+	assert.Equal(t, ResumedPayload{LastSeq: seen.Seq + 2, Replayed: 2}, resumed)
+}
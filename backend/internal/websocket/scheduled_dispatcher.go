@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+)
+
+// ScheduledMessageStore hands due scheduled messages to the dispatcher and
+// posts them
+type ScheduledMessageStore interface {
+	// ClaimDueScheduledMessages leases every scheduled message due at or before now and returns them
+	ClaimDueScheduledMessages(ctx context.Context, now time.Time) ([]domain.ScheduledMessage, error)
+	// SendScheduledMessage posts msg and removes scheduledID in one go
+	SendScheduledMessage(ctx context.Context, scheduledID uuid.UUID, msg *domain.Message) error
+	CancelScheduledMessage(ctx context.Context, id, senderID uuid.UUID) error
+	GetPostingRole(ctx context.Context, convID, userID uuid.UUID) (domain.MemberRole, bool, error)
+	GetDMPartner(ctx context.Context, convID, userID uuid.UUID) (uuid.UUID, error)
+	IsBlocked(ctx context.Context, user1, user2 uuid.UUID) (bool, error)
+}
+
+// ScheduledDispatcher periodically posts scheduled messages that have come
+// due and broadcasts them as message.new
+type ScheduledDispatcher struct {
+	store       ScheduledMessageStore
+	broadcaster RoomBroadcaster
+	interval    time.Duration
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewScheduledDispatcher creates a dispatcher that polls every interval
+func NewScheduledDispatcher(store ScheduledMessageStore, broadcaster RoomBroadcaster, interval time.Duration, logger *slog.Logger) *ScheduledDispatcher {
+	return &ScheduledDispatcher{
+		store:       store,
+		broadcaster: broadcaster,
+		interval:    interval,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run dispatches until ctx is cancelled
+func (d *ScheduledDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Dispatch(ctx)
+		}
+	}
+}
+
+// Dispatch posts every due scheduled message and returns how many were sent.
+// Messages whose sender can no longer post to the conversation (they left,
+// it went read-only, or a DM partner blocked them) are dropped. One that fails
+// to post stays scheduled and is retried. Scheduled sends don't count against
+// the flood limit: the sender isn't flooding when they come due.
+func (d *ScheduledDispatcher) Dispatch(ctx context.Context) int {
+	now := d.now()
+	due, err := d.store.ClaimDueScheduledMessages(ctx, now)
+	if err != nil {
+		d.logger.Error("failed to claim scheduled messages", "error", err)
+		return 0
+	}
+
+	sent := 0
+	for i := range due {
+		s := &due[i]
+		if err := d.canSend(ctx, s); err != nil {
+			d.logger.Warn("dropping scheduled message", "error", err, "scheduled_id", s.ID, "conversation_id", s.ConversationID)
+			d.drop(ctx, s)
+			continue
+		}
+
+		msg := s.ToMessage(now)
+		if err := d.store.SendScheduledMessage(ctx, s.ID, msg); err != nil {
+			if !errors.Is(err, domain.ErrScheduledMessageNotFound) {
+				d.logger.Error("failed to send scheduled message", "error", err, "scheduled_id", s.ID)
+			}
+			continue
+		}
+		sent++
+		if err := d.broadcaster.BroadcastNewMessage(ctx, msg, s.SenderUsername); err != nil {
+			d.logger.Error("failed to broadcast scheduled message", "error", err, "message_id", msg.ID)
+		}
+	}
+	if sent > 0 {
+		d.logger.Info("sent scheduled messages", "count", sent)
+	}
+	return sent
+}
+
+// drop removes a scheduled message that won't be sent
+func (d *ScheduledDispatcher) drop(ctx context.Context, s *domain.ScheduledMessage) {
+	err := d.store.CancelScheduledMessage(ctx, s.ID, s.SenderID)
+	if err != nil && !errors.Is(err, domain.ErrScheduledMessageNotFound) {
+		d.logger.Error("failed to drop scheduled message", "error", err, "scheduled_id", s.ID)
+	}
+}
+
+// canSend rechecks at send time the rules applied when the message was scheduled
+func (d *ScheduledDispatcher) canSend(ctx context.Context, s *domain.ScheduledMessage) error {
+	role, readOnly, err := d.store.GetPostingRole(ctx, s.ConversationID, s.SenderID)
+	if err != nil {
+		return err
+	}
+	if err := domain.CanPost(role, readOnly); err != nil {
+		return err
+	}
+
+	partnerID, err := d.store.GetDMPartner(ctx, s.ConversationID, s.SenderID)
+	if err != nil || partnerID == uuid.Nil {
+		return err
+	}
+	blocked, err := d.store.IsBlocked(ctx, s.SenderID, partnerID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return domain.ErrUserBlocked
+	}
+	return nil
+}
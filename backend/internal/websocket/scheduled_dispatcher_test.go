@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryScheduleStore mimics the scheduled_messages table and the checks
+// made before posting
+type memoryScheduleStore struct {
+	mu        sync.Mutex
+	scheduled []domain.ScheduledMessage
+	claimed   map[uuid.UUID]bool
+	members   map[uuid.UUID]bool // sender IDs still in the conversation
+	sent      []domain.Message
+	failSends int // SendScheduledMessage fails this many times
+}
+
+func (s *memoryScheduleStore) ClaimDueScheduledMessages(_ context.Context, now time.Time) ([]domain.ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed == nil {
+		s.claimed = make(map[uuid.UUID]bool)
+	}
+	var due []domain.ScheduledMessage
+	for _, m := range s.scheduled {
+		if !m.SendAt.After(now) && !s.claimed[m.ID] {
+			s.claimed[m.ID] = true
+			due = append(due, m)
+		}
+	}
+	return due, nil
+}
+
+// expireClaims lets every claimed message be claimed again, as when leases run out
+func (s *memoryScheduleStore) expireClaims() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed = nil
+}
+
+func (s *memoryScheduleStore) remove(id uuid.UUID) bool {
+	for i, m := range s.scheduled {
+		if m.ID == id {
+			s.scheduled = append(s.scheduled[:i], s.scheduled[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryScheduleStore) SendScheduledMessage(_ context.Context, scheduledID uuid.UUID, msg *domain.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failSends > 0 {
+		s.failSends--
+		return errors.New("insert failed")
+	}
+	if !s.remove(scheduledID) {
+		return domain.ErrScheduledMessageNotFound
+	}
+	s.sent = append(s.sent, *msg)
+	return nil
+}
+
+func (s *memoryScheduleStore) CancelScheduledMessage(_ context.Context, id, _ uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.remove(id) {
+		return domain.ErrScheduledMessageNotFound
+	}
+	return nil
+}
+
+func (s *memoryScheduleStore) GetPostingRole(_ context.Context, _, userID uuid.UUID) (domain.MemberRole, bool, error) {
+	if !s.members[userID] {
+		return "", false, domain.ErrNotMember
+	}
+	return domain.MemberRoleMember, false, nil
+}
+
+func (s *memoryScheduleStore) GetDMPartner(context.Context, uuid.UUID, uuid.UUID) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+
+func (s *memoryScheduleStore) IsBlocked(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func TestScheduledDispatcher_SendsDueMessages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	now := time.Now()
+	convID, sender, leaver := uuid.New(), uuid.New(), uuid.New()
+	store := &memoryScheduleStore{
+		scheduled: []domain.ScheduledMessage{
+			{ID: uuid.New(), ConversationID: convID, SenderID: sender, SenderUsername: "alice", BodyText: "due", SendAt: now.Add(-time.Second)},
+			{ID: uuid.New(), ConversationID: convID, SenderID: leaver, SenderUsername: "bob", BodyText: "left", SendAt: now.Add(-time.Second)},
+			{ID: uuid.New(), ConversationID: convID, SenderID: sender, SenderUsername: "alice", BodyText: "later", SendAt: now.Add(time.Hour)},
+		},
+		members: map[uuid.UUID]bool{sender: true},
+	}
+	events := collectTopic(t, ps, pubsub.Topics.Room(convID.String()))
+
+	dispatcher := NewScheduledDispatcher(store, NewPubSubBroadcaster(ps), time.Minute, logger)
+	dispatcher.now = func() time.Time { return now }
+
+	assert.Equal(t, 1, dispatcher.Dispatch(context.Background()), "a sender who left the conversation is dropped")
+	require.Len(t, store.sent, 1)
+	assert.Equal(t, "due", store.sent[0].BodyText)
+	assert.Len(t, store.scheduled, 1, "sent and dropped messages are removed; ones not yet due stay scheduled")
+
+	select {
+	case msg := <-events:
+		assert.Equal(t, EventTypeMessageNew, msg.Type)
+		var p MessageNewPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		assert.Equal(t, store.sent[0].ID, p.ID)
+		assert.Equal(t, sender, p.SenderID)
+		assert.Equal(t, "alice", p.SenderUsername)
+		assert.Equal(t, "due", p.BodyText)
+	case <-time.After(time.Second):
+		t.Fatal("expected message.new for the scheduled message")
+	}
+
+	assert.Equal(t, 0, dispatcher.Dispatch(context.Background()), "claimed messages are sent once")
+}
+
+func TestScheduledDispatcher_FailedSendStaysScheduled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ps := pubsub.NewMemoryPubSub()
+	defer func() { _ = ps.Close() }()
+
+	now := time.Now()
+	convID, sender := uuid.New(), uuid.New()
+	store := &memoryScheduleStore{
+		scheduled: []domain.ScheduledMessage{
+			{ID: uuid.New(), ConversationID: convID, SenderID: sender, SenderUsername: "alice", BodyText: "retry me", SendAt: now.Add(-time.Second)},
+		},
+		members:   map[uuid.UUID]bool{sender: true},
+		failSends: 1,
+	}
+
+	dispatcher := NewScheduledDispatcher(store, NewPubSubBroadcaster(ps), time.Minute, logger)
+	dispatcher.now = func() time.Time { return now }
+
+	assert.Equal(t, 0, dispatcher.Dispatch(context.Background()))
+	assert.Len(t, store.scheduled, 1, "a failed post doesn't lose the message")
+	assert.Equal(t, 0, dispatcher.Dispatch(context.Background()), "it isn't reclaimed while leased")
+
+	store.expireClaims()
+	assert.Equal(t, 1, dispatcher.Dispatch(context.Background()), "it's retried once the lease runs out")
+	require.Len(t, store.sent, 1)
+	assert.Equal(t, "retry me", store.sent[0].BodyText)
+	assert.Empty(t, store.scheduled)
+}
@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_AcknowledgeSend_TargetsSendingConnection(t *testing.T) {
+	hub, sender := newTestAckHub(t)
+
+	// The sender's phone, and another member of the room
+	otherDevice := &Client{hub: hub, send: make(chan []byte, 8), rooms: make(map[uuid.UUID]bool), logger: hub.logger}
+	otherDevice.SetUser(sender.UserID(), sender.Username())
+	peer := &Client{hub: hub, send: make(chan []byte, 8), rooms: make(map[uuid.UUID]bool), logger: hub.logger}
+	peer.SetUser(uuid.New(), "bob")
+
+	convID := uuid.New()
+	hub.rooms[convID] = map[*Client]bool{sender: true, otherDevice: true, peer: true}
+	hub.clients[sender.UserID()] = map[*Client]bool{sender: true, otherDevice: true}
+
+	userID := sender.UserID()
+	msg := &domain.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       &userID,
+		BodyText:       "hello",
+		CreatedAt:      time.Now().UTC().Truncate(time.Millisecond),
+	}
+	hub.acknowledgeSend(sender, msg, "tmp-42")
+
+	select {
+	case data := <-sender.send:
+		var env Message
+		require.NoError(t, json.Unmarshal(data, &env))
+		assert.Equal(t, EventTypeMessageSent, env.Type)
+
+		var ack MessageSentPayload
+		require.NoError(t, json.Unmarshal(env.Payload, &ack))
+		assert.Equal(t, "tmp-42", ack.TempID, "keyed to the client's optimistic bubble")
+		assert.Equal(t, msg.ID, ack.ID, "carries the server-assigned ID")
+		assert.Equal(t, convID, ack.ConversationID)
+		assert.True(t, msg.CreatedAt.Equal(ack.CreatedAt), "carries the server timestamp")
+	default:
+		t.Fatal("expected message.sent on the sending connection")
+	}
+
+	assert.Empty(t, otherDevice.send, "the sender's other devices rely on the room broadcast")
+	assert.Empty(t, peer.send, "the ack is not broadcast")
+}
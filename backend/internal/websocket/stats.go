@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// UserConnectionCount is the number of live connections held by one user
+type UserConnectionCount struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Connections int       `json:"connections"`
+}
+
+// RoomSubscriptionCount is the number of connections subscribed to one room
+type RoomSubscriptionCount struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Subscribers    int       `json:"subscribers"`
+}
+
+// HubStats is a point-in-time snapshot of hub state for debugging
+type HubStats struct {
+	Connections       int                     `json:"connections"`        // authenticated connections
+	Users             int                     `json:"users"`              // distinct connected users
+	TopUsers          []UserConnectionCount   `json:"top_users"`          // users with the most connections
+	Rooms             int                     `json:"rooms"`              // rooms with at least one local subscriber
+	RoomSubscriptions int                     `json:"room_subscriptions"` // total client-room subscriptions
+	PubSubRooms       int                     `json:"pubsub_rooms"`       // rooms with an active pubsub subscription
+	TopRooms          []RoomSubscriptionCount `json:"top_rooms"`          // rooms with the most subscribers
+}
+
+// Stats snapshots the hub's connections and room subscriptions, returning at
+// most topN entries in the per-user and per-room breakdowns.
+func (h *Hub) Stats(topN int) HubStats {
+	h.mu.RLock()
+	stats := HubStats{
+		Users:       len(h.clients),
+		Rooms:       len(h.rooms),
+		PubSubRooms: len(h.roomSubs),
+		TopUsers:    make([]UserConnectionCount, 0, len(h.clients)),
+		TopRooms:    make([]RoomSubscriptionCount, 0, len(h.rooms)),
+	}
+	for userID, clients := range h.clients {
+		stats.Connections += len(clients)
+		stats.TopUsers = append(stats.TopUsers, UserConnectionCount{UserID: userID, Connections: len(clients)})
+	}
+	for roomID, clients := range h.rooms {
+		stats.RoomSubscriptions += len(clients)
+		stats.TopRooms = append(stats.TopRooms, RoomSubscriptionCount{ConversationID: roomID, Subscribers: len(clients)})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats.TopUsers, func(i, j int) bool {
+		return stats.TopUsers[i].Connections > stats.TopUsers[j].Connections
+	})
+	sort.Slice(stats.TopRooms, func(i, j int) bool {
+		return stats.TopRooms[i].Subscribers > stats.TopRooms[j].Subscribers
+	})
+	if topN >= 0 && len(stats.TopUsers) > topN {
+		stats.TopUsers = stats.TopUsers[:topN]
+	}
+	if topN >= 0 && len(stats.TopRooms) > topN {
+		stats.TopRooms = stats.TopRooms[:topN]
+	}
+	return stats
+}
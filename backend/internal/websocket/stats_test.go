@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_Stats_ReflectsClientsAndRooms(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+
+	alice, bob := uuid.New(), uuid.New()
+	aliceWeb, alicePhone, bobWeb := &Client{}, &Client{}, &Client{}
+	hub.clients[alice] = map[*Client]bool{aliceWeb: true, alicePhone: true}
+	hub.clients[bob] = map[*Client]bool{bobWeb: true}
+
+	busy, quiet := uuid.New(), uuid.New()
+	hub.rooms[busy] = map[*Client]bool{aliceWeb: true, alicePhone: true, bobWeb: true}
+	hub.rooms[quiet] = map[*Client]bool{bobWeb: true}
+
+	stats := hub.Stats(10)
+	assert.Equal(t, 3, stats.Connections)
+	assert.Equal(t, 2, stats.Users)
+	assert.Equal(t, 2, stats.Rooms)
+	assert.Equal(t, 4, stats.RoomSubscriptions)
+
+	require.Len(t, stats.TopUsers, 2)
+	assert.Equal(t, alice, stats.TopUsers[0].UserID)
+	assert.Equal(t, 2, stats.TopUsers[0].Connections)
+
+	require.Len(t, stats.TopRooms, 2)
+	assert.Equal(t, busy, stats.TopRooms[0].ConversationID)
+	assert.Equal(t, 3, stats.TopRooms[0].Subscribers)
+}
+
+func TestHub_Stats_TopNLimitsBreakdown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+
+	for i := 0; i < 5; i++ {
+		hub.clients[uuid.New()] = map[*Client]bool{{}: true}
+	}
+
+	stats := hub.Stats(2)
+	assert.Equal(t, 5, stats.Users)
+	assert.Len(t, stats.TopUsers, 2)
+}
+
+func TestHub_Stats_Empty(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+
+	stats := hub.Stats(10)
+	assert.Zero(t, stats.Connections)
+	assert.Zero(t, stats.Rooms)
+	assert.Empty(t, stats.TopUsers)
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Default reconnect-storm limits: with more than 200 connections still
+// waiting to authenticate, new upgrades are told to come back in 5-10s.
+// A connection that hasn't authenticated 10s after upgrading is closed, so
+// idle sockets can't hold on to their slot.
+const (
+	DefaultMaxPendingAuths = 200
+	DefaultStormRetryAfter = 5 * time.Second
+	DefaultAuthTimeout     = 10 * time.Second
+)
+
+// admissionGate sheds new connections while too many earlier ones are still
+// authenticating, which is what a mass reconnect after a restart looks like.
+// Shed clients get a jittered retry hint so the herd spreads out instead of
+// coming back in lockstep.
+type admissionGate struct {
+	mu         sync.Mutex
+	maxPending int
+	pending    int
+	retryAfter time.Duration
+	jitter     func(time.Duration) time.Duration
+}
+
+func newAdmissionGate(maxPending int, retryAfter time.Duration) *admissionGate {
+	return &admissionGate{
+		maxPending: maxPending,
+		retryAfter: retryAfter,
+		jitter: func(d time.Duration) time.Duration {
+			if d <= 0 {
+				return 0
+			}
+			return rand.N(d)
+		},
+	}
+}
+
+// TryAcquire admits a connection that has yet to authenticate. It returns
+// false when the pending limit is reached. A maxPending of 0 admits everyone.
+func (g *admissionGate) TryAcquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.maxPending > 0 && g.pending >= g.maxPending {
+		return false
+	}
+	g.pending++
+	return true
+}
+
+// Release marks an admitted connection as done authenticating, whether it
+// succeeded or went away
+func (g *admissionGate) Release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.pending > 0 {
+		g.pending--
+	}
+}
+
+// Pending returns how many admitted connections are still authenticating
+func (g *admissionGate) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pending
+}
+
+// RetryAfter returns how long a shed client should wait: the base delay plus
+// up to the same again of random jitter
+func (g *admissionGate) RetryAfter() time.Duration {
+	return g.retryAfter + g.jitter(g.retryAfter)
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ShedsUpgradesAbovePendingAuthThreshold(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+	hub.SetAdmissionLimit(1, 2*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(NewHandler(hub, logger))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// The first connection is admitted and sits waiting to authenticate
+	first, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 1 }, time.Second, 5*time.Millisecond)
+
+	// The next one is over the threshold and gets a retry hint
+	second, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer second.Close()
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+
+	_, data, err := second.ReadMessage()
+	require.NoError(t, err)
+	var env Message
+	require.NoError(t, json.Unmarshal(data, &env))
+	assert.Equal(t, EventTypeServerBusy, env.Type)
+
+	var busy ServerBusyPayload
+	require.NoError(t, json.Unmarshal(env.Payload, &busy))
+	assert.GreaterOrEqual(t, busy.RetryAfter, 2.0, "at least the base delay")
+	assert.Less(t, busy.RetryAfter, 4.0, "jitter adds at most the base again")
+
+	_, _, err = second.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "closed with try-again-later, got %v", err)
+	assert.Equal(t, 1, hub.admission.Pending(), "shed connections don't take a slot")
+}
+
+func TestAdmissionGate_ReleaseFreesSlot(t *testing.T) {
+	g := newAdmissionGate(2, time.Second)
+
+	assert.True(t, g.TryAcquire())
+	assert.True(t, g.TryAcquire())
+	assert.False(t, g.TryAcquire(), "over the threshold")
+
+	g.Release()
+	assert.True(t, g.TryAcquire(), "an authenticated connection makes room")
+}
+
+func TestAdmissionGate_ZeroDisables(t *testing.T) {
+	g := newAdmissionGate(0, time.Second)
+	for i := 0; i < 1000; i++ {
+		assert.True(t, g.TryAcquire())
+	}
+}
+
+func TestHub_FinishAdmissionReleasesOnce(t *testing.T) {
+	hub, client := newTestAckHub(t)
+	hub.SetAdmissionLimit(1, time.Second)
+
+	require.True(t, hub.admission.TryAcquire())
+	client.admitted = true
+
+	hub.finishAdmission(client) // authenticated
+	hub.finishAdmission(client) // later disconnect
+	assert.Equal(t, 0, hub.admission.Pending())
+	assert.True(t, hub.admission.TryAcquire(), "the slot was released exactly once")
+	assert.False(t, hub.admission.TryAcquire())
+}
+
+func TestHandler_ClosesIdleUnauthenticatedConnectionAndFreesSlot(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := NewHub(nil, nil, nil, nil, pubsub.NewMemoryPubSub(), logger)
+	hub.SetAdmissionLimit(1, time.Second)
+	hub.SetAuthTimeout(100 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(NewHandler(hub, logger))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// Connect and never authenticate
+	idle, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer idle.Close()
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 1 }, time.Second, 5*time.Millisecond)
+
+	_ = idle.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = idle.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "closed for not authenticating, got %v", err)
+	assert.Equal(t, 0, hub.admission.Pending(), "the idle connection's slot is released")
+
+	// The freed slot admits the next connection instead of shedding it
+	next, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer next.Close()
+	require.Eventually(t, func() bool { return hub.admission.Pending() == 1 }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/observer/teatime/internal/i18n"
+)
+
+// systemText renders the human-readable line for a system event in lang and
+// loc, or "" if the event isn't one the server describes itself
+func systemText(eventType string, payload json.RawMessage, lang string, loc *time.Location) string {
+	switch eventType {
+	case EventTypeMemberJoined:
+		var p MemberJoinedPayload
+		if json.Unmarshal(payload, &p) != nil {
+			return ""
+		}
+		return i18n.Render(lang, i18n.KeyMemberJoined, "user", p.Username)
+
+	case EventTypeMemberLeft:
+		var p MemberLeftPayload
+		if json.Unmarshal(payload, &p) != nil {
+			return ""
+		}
+		if p.RemovedBy != p.UserID {
+			return i18n.Render(lang, i18n.KeyMemberRemoved, "user", p.Username)
+		}
+		return i18n.Render(lang, i18n.KeyMemberLeft, "user", p.Username)
+
+	case EventTypeRoomUpdated:
+		var p RoomUpdatedPayload
+		if json.Unmarshal(payload, &p) != nil || p.Title == "" {
+			return ""
+		}
+		return i18n.Render(lang, i18n.KeyRoomRenamed, "title", p.Title)
+
+	case EventTypeRoomReadOnly:
+		var p RoomReadOnlyPayload
+		if json.Unmarshal(payload, &p) != nil {
+			return ""
+		}
+		if p.ReadOnly {
+			return i18n.Render(lang, i18n.KeyReadOnlyOn)
+		}
+		return i18n.Render(lang, i18n.KeyReadOnlyOff)
+
+	case EventTypeUserThrottled:
+		var p UserThrottledPayload
+		if json.Unmarshal(payload, &p) != nil {
+			return ""
+		}
+		if !p.Throttled {
+			return i18n.Render(lang, i18n.KeyUnthrottled)
+		}
+		if p.Until == nil {
+			return ""
+		}
+		return i18n.Render(lang, i18n.KeyThrottled, "time", i18n.FormatTime(lang, *p.Until, loc))
+	}
+	return ""
+}
+
+// localize returns msg with its system text rendered for this client. Other
+// messages are returned unchanged; msg itself is never modified since it's
+// shared between every client in a room.
+func (c *Client) localize(msg *Message) *Message {
+	lang, loc := c.Locale()
+	text := systemText(msg.Type, msg.Payload, lang, loc)
+	if text == "" {
+		return msg
+	}
+	localized := *msg
+	localized.Text = text
+	return &localized
+}
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/i18n"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveMessage(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return msg
+	default:
+		t.Fatal("expected a message")
+		return Message{}
+	}
+}
+
+func TestHub_DeliverToRoom_RendersSystemTextInClientLocale(t *testing.T) {
+	hub, french := newTestAckHub(t)
+	french.SetLocale(i18n.Match("fr-CA"), i18n.LoadLocation("Europe/Paris"))
+	_, english := newTestAckHub(t)
+	convID := uuid.New()
+
+	hub.rooms[convID] = map[*Client]bool{french: true, english: true}
+
+	payload, _ := json.Marshal(MemberJoinedPayload{ConversationID: convID, UserID: uuid.New(), Username: "bob"})
+	hub.deliverToRoom(convID, &pubsub.Message{Type: EventTypeMemberJoined, Payload: payload})
+
+	assert.Equal(t, "bob a rejoint le groupe", receiveMessage(t, french).Text)
+	assert.Equal(t, "bob joined the group", receiveMessage(t, english).Text)
+}
+
+func TestSystemText(t *testing.T) {
+	userID := uuid.New()
+	until := time.Date(2026, 3, 1, 17, 30, 0, 0, time.UTC)
+	paris := i18n.LoadLocation("Europe/Paris")
+
+	left, _ := json.Marshal(MemberLeftPayload{UserID: userID, Username: "bob", RemovedBy: userID})
+	removed, _ := json.Marshal(MemberLeftPayload{UserID: userID, Username: "bob", RemovedBy: uuid.New()})
+	throttled, _ := json.Marshal(UserThrottledPayload{UserID: userID, Throttled: true, Until: &until})
+	message, _ := json.Marshal(MessageNewPayload{BodyText: "hello"})
+
+	assert.Equal(t, "bob hat die Gruppe verlassen", systemText(EventTypeMemberLeft, left, "de", time.UTC))
+	assert.Equal(t, "bob was removed from the group", systemText(EventTypeMemberLeft, removed, "en", time.UTC))
+	assert.Contains(t, systemText(EventTypeUserThrottled, throttled, "fr", paris), "18:30", "times are in the client's zone")
+	assert.Contains(t, systemText(EventTypeUserThrottled, throttled, "en", time.UTC), "5:30 PM")
+	assert.Empty(t, systemText(EventTypeMessageNew, message, "fr", paris), "user content isn't rendered")
+}
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// typingTargets remembers the one conversation each user is typing in, so
+// starting to type somewhere else can stop the indicator left behind. Clients
+// don't always send typing.stop when switching rooms, and without this a user
+// could appear to be typing in several conversations at once.
+type typingTargets struct {
+	mu      sync.Mutex
+	targets map[uuid.UUID]uuid.UUID
+}
+
+func newTypingTargets() *typingTargets {
+	return &typingTargets{targets: make(map[uuid.UUID]uuid.UUID)}
+}
+
+// Start makes convID the user's typing target. If they were typing in a
+// different conversation, that one is returned so its indicator can be stopped.
+func (t *typingTargets) Start(userID, convID uuid.UUID) (previous uuid.UUID, switched bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.targets[userID]
+	t.targets[userID] = convID
+	return previous, ok && previous != convID
+}
+
+// Stop clears the user's typing target if it is convID. A stale stop for a
+// conversation they've since moved on from leaves the current target alone.
+func (t *typingTargets) Stop(userID, convID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.targets[userID] == convID {
+		delete(t.targets, userID)
+	}
+}
+
+// Clear forgets the user's typing target, returning it if there was one
+func (t *typingTargets) Clear(userID uuid.UUID) (uuid.UUID, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	convID, ok := t.targets[userID]
+	delete(t.targets, userID)
+	return convID, ok
+}
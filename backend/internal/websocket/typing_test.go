@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/observer/teatime/internal/webrtc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readTyping returns the next typing event queued for client
+func readTyping(t *testing.T, client *Client) TypingBroadcastPayload {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		require.Equal(t, EventTypeTyping, msg.Type)
+		var p TypingBroadcastPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("expected a typing event")
+	}
+	return TypingBroadcastPayload{}
+}
+
+func TestHub_HandleTyping_FlagsTypingFromCallParticipants(t *testing.T) {
+	hub, typist := newTestAckHub(t)
+	watcher := &Client{hub: hub, send: make(chan []byte, 8), rooms: make(map[uuid.UUID]bool), logger: hub.logger}
+	watcher.SetUser(uuid.New(), "bob")
+
+	ps := pubsub.NewMemoryPubSub()
+	t.Cleanup(func() { _ = ps.Close() })
+	mgr := webrtc.NewManager(&webrtc.Config{}, ps, hub.logger)
+	hub.SetCallHandler(webrtc.NewCallHandler(mgr, nil, nil, ps, hub.logger))
+
+	convID := uuid.New()
+	hub.rooms[convID] = map[*Client]bool{typist: true, watcher: true}
+	payload, _ := json.Marshal(TypingPayload{ConversationID: convID.String()})
+
+	// Not in the call: plain chat typing
+	hub.handleTyping(typist, payload, true)
+	p := readTyping(t, watcher)
+	assert.Equal(t, typist.UserID(), p.UserID)
+	assert.True(t, p.IsTyping)
+	assert.False(t, p.InCall)
+
+	// In the conversation's call: still delivered, but flagged
+	_, err := mgr.JoinCall(context.Background(), convID, typist.UserID(), typist.Username())
+	require.NoError(t, err)
+
+	hub.handleTyping(typist, payload, true)
+	p = readTyping(t, watcher)
+	assert.True(t, p.IsTyping)
+	assert.True(t, p.InCall)
+
+	// A call in another conversation doesn't count
+	otherConv := uuid.New()
+	hub.rooms[otherConv] = map[*Client]bool{typist: true, watcher: true}
+	otherPayload, _ := json.Marshal(TypingPayload{ConversationID: otherConv.String()})
+	hub.handleTyping(typist, otherPayload, true)
+	p = readTyping(t, watcher)
+	require.Equal(t, convID, p.ConversationID, "switching conversations stops the old indicator first")
+	assert.False(t, p.IsTyping)
+	p = readTyping(t, watcher)
+	assert.Equal(t, otherConv, p.ConversationID)
+	assert.False(t, p.InCall)
+}
+
+func TestHub_HandleTyping_TypingInAnotherConversationStopsTheFirst(t *testing.T) {
+	hub, typist := newTestAckHub(t)
+	watcher := &Client{hub: hub, send: make(chan []byte, 8), rooms: make(map[uuid.UUID]bool), logger: hub.logger}
+	watcher.SetUser(uuid.New(), "bob")
+
+	convA, convB := uuid.New(), uuid.New()
+	hub.rooms[convA] = map[*Client]bool{typist: true, watcher: true}
+	hub.rooms[convB] = map[*Client]bool{typist: true, watcher: true}
+	payloadA, _ := json.Marshal(TypingPayload{ConversationID: convA.String()})
+	payloadB, _ := json.Marshal(TypingPayload{ConversationID: convB.String()})
+
+	hub.handleTyping(typist, payloadA, true)
+	p := readTyping(t, watcher)
+	assert.Equal(t, convA, p.ConversationID)
+	assert.True(t, p.IsTyping)
+
+	// Starting in B clears A without the client sending typing.stop
+	hub.handleTyping(typist, payloadB, true)
+	p = readTyping(t, watcher)
+	assert.Equal(t, convA, p.ConversationID)
+	assert.Equal(t, typist.UserID(), p.UserID)
+	assert.False(t, p.IsTyping)
+	p = readTyping(t, watcher)
+	assert.Equal(t, convB, p.ConversationID)
+	assert.True(t, p.IsTyping)
+
+	// Repeated starts in the same conversation don't emit stops
+	hub.handleTyping(typist, payloadB, true)
+	p = readTyping(t, watcher)
+	assert.Equal(t, convB, p.ConversationID)
+	assert.True(t, p.IsTyping)
+
+	// A late stop for A doesn't clear the target, so B stays current
+	hub.handleTyping(typist, payloadA, false)
+	assert.False(t, readTyping(t, watcher).IsTyping)
+	hub.handleTyping(typist, payloadA, true)
+	p = readTyping(t, watcher)
+	assert.Equal(t, convB, p.ConversationID, "B is still stopped on the switch back to A")
+	assert.False(t, p.IsTyping)
+	assert.Equal(t, convA, readTyping(t, watcher).ConversationID)
+}
+
+func TestTypingTargets(t *testing.T) {
+	targets := newTypingTargets()
+	user, convA, convB := uuid.New(), uuid.New(), uuid.New()
+
+	_, switched := targets.Start(user, convA)
+	assert.False(t, switched)
+	_, switched = targets.Start(user, convA)
+	assert.False(t, switched)
+
+	previous, switched := targets.Start(user, convB)
+	assert.True(t, switched)
+	assert.Equal(t, convA, previous)
+
+	targets.Stop(user, convA)
+	current, ok := targets.Clear(user)
+	assert.True(t, ok, "a stale stop leaves the current target")
+	assert.Equal(t, convB, current)
+
+	_, ok = targets.Clear(user)
+	assert.False(t, ok)
+}
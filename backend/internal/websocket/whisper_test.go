@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/observer/teatime/internal/domain"
+	"github.com/observer/teatime/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_DeliverWhisper_OnlyReachesAudience(t *testing.T) {
+	hub, _ := newTestAckHub(t)
+	ctx := context.Background()
+
+	sender, recipient, bystander := uuid.New(), uuid.New(), uuid.New()
+	convID := uuid.New()
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	subscribe := func(topic string) {
+		sub, err := hub.pubsub.Subscribe(ctx, topic, func(_ context.Context, msg *pubsub.Message) {
+			if msg.Type != EventTypeMessageNew {
+				return
+			}
+			mu.Lock()
+			received[topic]++
+			mu.Unlock()
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sub.Unsubscribe() })
+	}
+	senderTopic := pubsub.Topics.User(sender.String())
+	recipientTopic := pubsub.Topics.User(recipient.String())
+	bystanderTopic := pubsub.Topics.User(bystander.String())
+	roomTopic := pubsub.Topics.Room(convID.String())
+	for _, topic := range []string{senderTopic, recipientTopic, bystanderTopic, roomTopic} {
+		subscribe(topic)
+	}
+
+	hub.deliverWhisper(MessageNewPayload{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       sender,
+		BodyText:       "psst",
+		VisibleTo:      []uuid.UUID{sender, recipient},
+		CreatedAt:      time.Now(),
+	})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[senderTopic] == 1 && received[recipientTopic] == 1
+	}, time.Second, 5*time.Millisecond, "sender and recipient both get the whisper")
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Zero(t, received[bystanderTopic], "non-recipients don't receive it")
+	assert.Zero(t, received[roomTopic], "whispers never go to the room topic")
+}
+
+func TestHub_DeliverWhisper_QueuedForRecipientsUntilAcked(t *testing.T) {
+	hub, recipient := newTestAckHub(t)
+	sender := &Client{hub: hub, send: make(chan []byte, 256), rooms: make(map[uuid.UUID]bool), logger: recipient.logger}
+	sender.SetUser(uuid.New(), "bob")
+	hub.subscribeUserToEvents(recipient, recipient.UserID())
+	hub.subscribeUserToEvents(sender, sender.UserID())
+
+	hub.deliverWhisper(MessageNewPayload{
+		ID:             uuid.New(),
+		ConversationID: uuid.New(),
+		SenderID:       sender.UserID(),
+		BodyText:       "psst",
+		VisibleTo:      []uuid.UUID{sender.UserID(), recipient.UserID()},
+		CreatedAt:      time.Now(),
+	})
+
+	require.Eventually(t, func() bool {
+		return len(recipient.send) == 1 && len(sender.send) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, hub.pending.Len(recipient.UserID()), "redelivered on resume if the recipient drops before acking")
+	assert.Equal(t, 0, hub.pending.Len(sender.UserID()))
+}
+
+func TestReplyPreview_WithholdsWhisperFromWiderAudience(t *testing.T) {
+	sender, recipient, bystander := uuid.New(), uuid.New(), uuid.New()
+	whisper := &domain.Message{ID: uuid.New(), SenderID: &sender, BodyText: "psst", VisibleTo: []uuid.UUID{sender, recipient}}
+
+	public := &domain.Message{SenderID: &recipient}
+	assert.Nil(t, replyPreview(public, whisper), "a public reply mustn't quote a whisper")
+
+	privateReply := &domain.Message{SenderID: &recipient, VisibleTo: []uuid.UUID{recipient, sender}}
+	preview := replyPreview(privateReply, whisper)
+	require.NotNil(t, preview)
+	assert.Equal(t, "psst", preview.BodyText)
+
+	widerReply := &domain.Message{SenderID: &recipient, VisibleTo: []uuid.UUID{recipient, bystander}}
+	assert.Nil(t, replyPreview(widerReply, whisper))
+
+	plain := &domain.Message{ID: uuid.New(), SenderID: &sender, BodyText: "hi"}
+	assert.Equal(t, plain.ID, replyPreview(public, plain).ID)
+}
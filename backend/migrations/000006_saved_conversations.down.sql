@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_conversations_saved_for;
+ALTER TABLE conversations
+DROP COLUMN IF EXISTS saved_for;
@@ -0,0 +1,6 @@
+-- Add per-user "Saved Messages" self-conversation
+ALTER TABLE conversations
+ADD COLUMN IF NOT EXISTS saved_for UUID REFERENCES users(id) ON DELETE CASCADE;
+
+-- At most one saved conversation per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_saved_for ON conversations(saved_for) WHERE saved_for IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE conversations
+DROP COLUMN IF EXISTS default_member_role,
+DROP COLUMN IF EXISTS allow_member_adds;
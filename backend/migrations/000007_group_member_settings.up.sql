@@ -0,0 +1,4 @@
+-- Add per-group settings for invited members
+ALTER TABLE conversations
+ADD COLUMN IF NOT EXISTS default_member_role member_role NOT NULL DEFAULT 'member',
+ADD COLUMN IF NOT EXISTS allow_member_adds BOOLEAN NOT NULL DEFAULT true;
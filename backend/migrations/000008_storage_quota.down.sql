@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS attachments_storage_update ON attachments;
+DROP FUNCTION IF EXISTS attachments_storage_trigger();
+ALTER TABLE users
+DROP COLUMN IF EXISTS storage_used_bytes;
@@ -0,0 +1,36 @@
+-- Track per-user attachment storage for quota enforcement
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS storage_used_bytes BIGINT NOT NULL DEFAULT 0;
+
+-- Backfill from existing attachments
+UPDATE users u
+SET storage_used_bytes = COALESCE((
+    SELECT SUM(a.size_bytes) FROM attachments a
+    WHERE a.uploader_id = u.id AND a.status != 'error'
+), 0);
+
+-- Keep the counter in sync: reserve on insert, release on delete or failed upload
+CREATE OR REPLACE FUNCTION attachments_storage_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET storage_used_bytes = storage_used_bytes + NEW.size_bytes
+        WHERE id = NEW.uploader_id;
+    ELSIF TG_OP = 'DELETE' THEN
+        IF OLD.status != 'error' THEN
+            UPDATE users SET storage_used_bytes = GREATEST(storage_used_bytes - OLD.size_bytes, 0)
+            WHERE id = OLD.uploader_id;
+        END IF;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF NEW.status = 'error' AND OLD.status != 'error' THEN
+            UPDATE users SET storage_used_bytes = GREATEST(storage_used_bytes - OLD.size_bytes, 0)
+            WHERE id = OLD.uploader_id;
+        END IF;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_storage_update ON attachments;
+CREATE TRIGGER attachments_storage_update
+    AFTER INSERT OR DELETE OR UPDATE OF status ON attachments
+    FOR EACH ROW EXECUTE FUNCTION attachments_storage_trigger();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS messages_stats_update ON messages;
+DROP FUNCTION IF EXISTS messages_stats_trigger();
+DROP TABLE IF EXISTS conversation_member_stats;
+DROP TABLE IF EXISTS conversation_stats;
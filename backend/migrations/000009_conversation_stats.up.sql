@@ -0,0 +1,76 @@
+-- Add incrementally maintained per-conversation message counters
+CREATE TABLE IF NOT EXISTS conversation_stats (
+    conversation_id UUID PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    attachment_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS conversation_member_stats (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+-- Backfill from existing messages
+INSERT INTO conversation_stats (conversation_id, message_count, attachment_count)
+SELECT conversation_id, COUNT(*), COUNT(attachment_id)
+FROM messages
+GROUP BY conversation_id
+ON CONFLICT (conversation_id) DO UPDATE
+SET message_count = EXCLUDED.message_count, attachment_count = EXCLUDED.attachment_count;
+
+INSERT INTO conversation_member_stats (conversation_id, user_id, message_count)
+SELECT conversation_id, sender_id, COUNT(*)
+FROM messages
+WHERE sender_id IS NOT NULL
+GROUP BY conversation_id, sender_id
+ON CONFLICT (conversation_id, user_id) DO UPDATE
+SET message_count = EXCLUDED.message_count;
+
+-- Increment on insert, decrement on delete. Deletes only UPDATE existing rows so
+-- cascading conversation deletes don't recreate stats for a vanished conversation.
+CREATE OR REPLACE FUNCTION messages_stats_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO conversation_stats (conversation_id, message_count, attachment_count)
+        VALUES (NEW.conversation_id, 1, CASE WHEN NEW.attachment_id IS NULL THEN 0 ELSE 1 END)
+        ON CONFLICT (conversation_id) DO UPDATE
+        SET message_count = conversation_stats.message_count + 1,
+            attachment_count = conversation_stats.attachment_count + EXCLUDED.attachment_count;
+
+        IF NEW.sender_id IS NOT NULL THEN
+            INSERT INTO conversation_member_stats (conversation_id, user_id, message_count)
+            VALUES (NEW.conversation_id, NEW.sender_id, 1)
+            ON CONFLICT (conversation_id, user_id) DO UPDATE
+            SET message_count = conversation_member_stats.message_count + 1;
+        END IF;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE conversation_stats
+        SET message_count = GREATEST(message_count - 1, 0),
+            attachment_count = GREATEST(attachment_count - CASE WHEN OLD.attachment_id IS NULL THEN 0 ELSE 1 END, 0)
+        WHERE conversation_id = OLD.conversation_id;
+
+        IF OLD.sender_id IS NOT NULL THEN
+            UPDATE conversation_member_stats
+            SET message_count = GREATEST(message_count - 1, 0)
+            WHERE conversation_id = OLD.conversation_id AND user_id = OLD.sender_id;
+        END IF;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Attachment removed (ON DELETE SET NULL) or attached after the fact
+        IF OLD.attachment_id IS NOT NULL AND NEW.attachment_id IS NULL THEN
+            UPDATE conversation_stats SET attachment_count = GREATEST(attachment_count - 1, 0)
+            WHERE conversation_id = NEW.conversation_id;
+        ELSIF OLD.attachment_id IS NULL AND NEW.attachment_id IS NOT NULL THEN
+            UPDATE conversation_stats SET attachment_count = attachment_count + 1
+            WHERE conversation_id = NEW.conversation_id;
+        END IF;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS messages_stats_update ON messages;
+CREATE TRIGGER messages_stats_update
+    AFTER INSERT OR DELETE OR UPDATE OF attachment_id ON messages
+    FOR EACH ROW EXECUTE FUNCTION messages_stats_trigger();
@@ -0,0 +1,3 @@
+-- Add a reference to the original message for forwarded copies
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS forwarded_from UUID REFERENCES messages(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pinned_messages_expires_at;
+DROP TABLE IF EXISTS pinned_messages;
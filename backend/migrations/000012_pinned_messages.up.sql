@@ -0,0 +1,12 @@
+-- Add shared pinned messages, optionally time-bound
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    pinned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    pinned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    PRIMARY KEY (conversation_id, message_id)
+);
+
+-- Lets the expiry sweeper find lapsed pins without a full scan
+CREATE INDEX IF NOT EXISTS idx_pinned_messages_expires_at ON pinned_messages(expires_at) WHERE expires_at IS NOT NULL;
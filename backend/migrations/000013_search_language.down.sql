@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION messages_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := to_tsvector('english', COALESCE(NEW.body_text, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE messages m SET search_vector = to_tsvector('english', COALESCE(m.body_text, ''))
+FROM conversations c
+WHERE c.id = m.conversation_id AND c.search_language <> 'english'::regconfig;
+
+ALTER TABLE conversations
+DROP COLUMN IF EXISTS search_language;
@@ -0,0 +1,14 @@
+-- Add a per-conversation text-search config so non-English chats stem correctly
+ALTER TABLE conversations
+ADD COLUMN IF NOT EXISTS search_language REGCONFIG NOT NULL DEFAULT 'english';
+
+-- Index each message with its conversation's config
+CREATE OR REPLACE FUNCTION messages_search_vector_update() RETURNS TRIGGER AS $$
+DECLARE
+    cfg REGCONFIG;
+BEGIN
+    SELECT search_language INTO cfg FROM conversations WHERE id = NEW.conversation_id;
+    NEW.search_vector := to_tsvector(COALESCE(cfg, 'english'::regconfig), COALESCE(NEW.body_text, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
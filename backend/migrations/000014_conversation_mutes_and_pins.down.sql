@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pinned_conversations;
+DROP TABLE IF EXISTS conversation_mute_settings;
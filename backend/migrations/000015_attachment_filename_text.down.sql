@@ -0,0 +1 @@
+ALTER TABLE attachments ALTER COLUMN filename TYPE VARCHAR(255);
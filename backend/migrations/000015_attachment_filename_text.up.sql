@@ -0,0 +1,2 @@
+-- Add room for encrypted attachment filenames, which outgrow VARCHAR(255)
+ALTER TABLE attachments ALTER COLUMN filename TYPE TEXT;
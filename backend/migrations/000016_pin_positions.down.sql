@@ -0,0 +1 @@
+ALTER TABLE pinned_messages DROP COLUMN IF EXISTS position;
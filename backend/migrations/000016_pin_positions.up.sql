@@ -0,0 +1,10 @@
+-- Add manual ordering for pinned messages; existing pins keep newest-first
+ALTER TABLE pinned_messages ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE pinned_messages pm SET position = ranked.rn
+FROM (
+    SELECT conversation_id, message_id,
+           ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY pinned_at DESC) AS rn
+    FROM pinned_messages
+) ranked
+WHERE pm.conversation_id = ranked.conversation_id AND pm.message_id = ranked.message_id;
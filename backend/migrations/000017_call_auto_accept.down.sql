@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_contacts;
+ALTER TABLE users DROP COLUMN IF EXISTS auto_accept_calls;
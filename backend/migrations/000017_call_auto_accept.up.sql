@@ -0,0 +1,10 @@
+-- Add contacts and an opt-in setting to auto-answer calls from them
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auto_accept_calls BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS user_contacts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    contact_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, contact_id),
+    CHECK (user_id <> contact_id)
+);
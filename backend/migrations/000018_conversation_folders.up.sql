@@ -0,0 +1,10 @@
+-- Add per-user conversation folders; a conversation sits in at most one folder per user
+CREATE TABLE IF NOT EXISTS conversation_folders (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    folder VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, conversation_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_folders_user ON conversation_folders(user_id, folder);
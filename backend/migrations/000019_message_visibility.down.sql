@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_visible_to;
+ALTER TABLE messages DROP COLUMN IF EXISTS visible_to;
@@ -0,0 +1,4 @@
+-- Add whisper visibility: when set, only these users (sender included) can see the message
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS visible_to UUID[];
+
+CREATE INDEX IF NOT EXISTS idx_messages_visible_to ON messages USING GIN (visible_to) WHERE visible_to IS NOT NULL;
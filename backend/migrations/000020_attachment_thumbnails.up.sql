@@ -0,0 +1,2 @@
+-- Add the thumbnail variants generated for an image attachment, by longest edge in pixels
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumbnail_sizes INTEGER[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_message_edits_message;
+DROP TABLE IF EXISTS message_edits;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,2 @@
+ALTER TABLE conversations
+DROP COLUMN IF EXISTS read_only;
@@ -0,0 +1,3 @@
+-- Announcement mode: when set, only admins may post in the conversation
+ALTER TABLE conversations
+ADD COLUMN IF NOT EXISTS read_only BOOLEAN NOT NULL DEFAULT false;
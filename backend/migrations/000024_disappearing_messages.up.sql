@@ -0,0 +1,5 @@
+-- Disappearing messages: a per-conversation lifetime stamped onto each new message
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS message_ttl_seconds INTEGER;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS message_previews;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_sound;
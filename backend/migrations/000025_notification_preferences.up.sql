@@ -0,0 +1,3 @@
+-- Per-user push notification sound and whether pushes show message text
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_sound TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS message_previews BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,3 @@
+-- Postgres can't drop an enum value, so demote moderators and leave the type as is
+UPDATE conversation_members SET role = 'member' WHERE role = 'moderator';
+UPDATE conversations SET default_member_role = 'member' WHERE default_member_role = 'moderator';
@@ -0,0 +1,2 @@
+-- Moderators may delete other members' messages but can't rename or kick
+ALTER TYPE member_role ADD VALUE IF NOT EXISTS 'moderator';
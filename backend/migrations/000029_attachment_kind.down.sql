@@ -0,0 +1,2 @@
+ALTER TABLE attachments DROP COLUMN IF EXISTS duration_ms;
+ALTER TABLE attachments DROP COLUMN IF EXISTS kind;
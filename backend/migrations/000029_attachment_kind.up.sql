@@ -0,0 +1,7 @@
+-- Classify attachments so clients know how to render them, and store clip
+-- length for voice notes
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'file';
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS duration_ms BIGINT;
+
+UPDATE attachments SET kind = 'voice' WHERE mime_type LIKE 'audio/%';
+UPDATE attachments SET kind = 'image' WHERE mime_type LIKE 'image/%';
@@ -0,0 +1,7 @@
+-- Order a message's attachments, and move single attachments into the join
+-- table so it lists every message's files
+ALTER TABLE message_attachments ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+INSERT INTO message_attachments (message_id, attachment_id, position)
+SELECT id, attachment_id, 0 FROM messages WHERE attachment_id IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Preview card for the first link in a message, fetched in the background.
+-- url, title, description and image_url go through the field cipher like
+-- message bodies.
+CREATE TABLE IF NOT EXISTS message_link_previews (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
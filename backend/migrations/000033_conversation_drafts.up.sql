@@ -0,0 +1,8 @@
+-- Unsent message drafts, one per user per conversation, synced across devices
+CREATE TABLE IF NOT EXISTS conversation_drafts (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, user_id)
+);
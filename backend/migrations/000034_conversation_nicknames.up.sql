@@ -0,0 +1,9 @@
+-- Nicknames shown in place of a member's display name within one conversation
+CREATE TABLE IF NOT EXISTS conversation_nicknames (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nickname TEXT NOT NULL,
+    set_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, target_user_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE call_logs DROP COLUMN IF EXISTS recording_path;
@@ -0,0 +1,2 @@
+-- Where an SFU call's recording was written, if it was recorded
+ALTER TABLE call_logs ADD COLUMN IF NOT EXISTS recording_path TEXT;
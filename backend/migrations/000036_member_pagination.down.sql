@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_conversation_members_joined;
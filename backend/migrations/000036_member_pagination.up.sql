@@ -0,0 +1,2 @@
+-- Keyset pagination of a conversation's members, in join order
+CREATE INDEX IF NOT EXISTS idx_conversation_members_joined ON conversation_members(conversation_id, joined_at, user_id);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS attachments_queue_deletion ON attachments;
+DROP FUNCTION IF EXISTS attachments_queue_deletion();
+DROP TABLE IF EXISTS storage_deletions;
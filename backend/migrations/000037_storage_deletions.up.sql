@@ -0,0 +1,22 @@
+-- Stored objects of deleted attachments, removed from R2 by the object reaper.
+-- A trigger queues them so every way an attachment row goes (message delete,
+-- expiry, cascades) frees its storage.
+CREATE TABLE IF NOT EXISTS storage_deletions (
+    object_key TEXT PRIMARY KEY,
+    thumbnail_sizes INTEGER[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION attachments_queue_deletion() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO storage_deletions (object_key, thumbnail_sizes)
+    VALUES (OLD.object_key, OLD.thumbnail_sizes)
+    ON CONFLICT (object_key) DO NOTHING;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_queue_deletion ON attachments;
+CREATE TRIGGER attachments_queue_deletion
+    AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION attachments_queue_deletion();
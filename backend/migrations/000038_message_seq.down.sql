@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_conversation_created_seq;
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
+DROP SEQUENCE IF EXISTS messages_seq;
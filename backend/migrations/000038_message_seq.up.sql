@@ -0,0 +1,16 @@
+-- Insertion order of messages. Ties on created_at (a forwarded batch is sent
+-- in one instant) are broken by seq, so batches keep their order.
+CREATE SEQUENCE IF NOT EXISTS messages_seq;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+UPDATE messages m
+SET seq = o.n
+FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS n FROM messages) o
+WHERE o.id = m.id AND m.seq IS NULL;
+
+SELECT setval('messages_seq', COALESCE((SELECT MAX(seq) FROM messages), 0) + 1, false);
+ALTER TABLE messages ALTER COLUMN seq SET DEFAULT nextval('messages_seq');
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+ALTER SEQUENCE messages_seq OWNED BY messages.seq;
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_created_seq ON messages(conversation_id, created_at DESC, seq DESC);
@@ -0,0 +1 @@
+-- Nothing to undo: whisper pins removed by the up migration aren't restored
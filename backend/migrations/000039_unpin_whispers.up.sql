@@ -0,0 +1,4 @@
+-- Whispers can't be pinned: pins are shown to the whole conversation
+DELETE FROM pinned_messages pm
+USING messages m
+WHERE m.id = pm.message_id AND m.visible_to IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE users ALTER COLUMN display_name TYPE VARCHAR(100);
+ALTER TABLE conversations ALTER COLUMN title TYPE VARCHAR(100);
@@ -0,0 +1,4 @@
+-- Title and display name limits count visible characters, which can take
+-- more than VARCHAR(100) code points; the length is checked in the app
+ALTER TABLE conversations ALTER COLUMN title TYPE TEXT;
+ALTER TABLE users ALTER COLUMN display_name TYPE TEXT;
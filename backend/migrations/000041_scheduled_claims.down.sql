@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduled_messages_sender;
+ALTER TABLE scheduled_messages DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,5 @@
+-- Due messages are leased to a dispatcher rather than deleted on claim, so
+-- one whose post fails is retried once the lease runs out
+ALTER TABLE scheduled_messages ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sender ON scheduled_messages(sender_id);
@@ -71,7 +71,7 @@ export default function ChatWindow({
 
   const getConversationSubtitle = () => {
     if (conversation.type === 'group') {
-      const count = conversation.members?.length || 0;
+      const count = conversation.member_count || conversation.members?.length || 0;
       return `${count} member${count !== 1 ? 's' : ''}`;
     }
     return 'Online'; // Could show actual online status later
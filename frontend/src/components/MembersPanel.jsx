@@ -27,13 +27,22 @@ export default function MembersPanel({
     setLoading(true)
     setError("")
     try {
-      // The conversation object should already have members from the API
-      if (conversation.members) {
-        setMembers(conversation.members)
+      // The conversation object may already have all its members from the API;
+      // a fetched one only comes with a preview
+      const preview = conversation.members
+      if (preview && preview.length >= (conversation.member_count || 0)) {
+        setMembers(preview)
       } else {
-        // Fetch conversation details if members not included
-        const data = await api.request(`/conversations/${conversation.id}`)
-        setMembers(data.members || [])
+        // Page through the member list in join order
+        const all = []
+        let before = null
+        for (;;) {
+          const data = await api.getMembers(conversation.id, before)
+          all.push(...(data.members || []))
+          if (!data.has_more || !data.next_before) break
+          before = data.next_before
+        }
+        setMembers(all)
       }
     } catch (err) {
       setError("Failed to load members")
@@ -41,7 +50,7 @@ export default function MembersPanel({
     } finally {
       setLoading(false)
     }
-  }, [conversation.id, conversation.members])
+  }, [conversation.id, conversation.members, conversation.member_count])
 
   const searchUsers = useCallback(async () => {
     setSearching(true)
@@ -144,7 +144,7 @@ export default function Sidebar({
               const name = getConversationName(conv);
               const avatar = getConversationAvatar(conv);
               const isActive = currentConversation?.id === conv.id;
-              const memberCount = conv.members?.length || 0;
+              const memberCount = conv.member_count || conv.members?.length || 0;
 
               return (
                 <button
@@ -660,6 +660,17 @@ export function useWebRTC(userId) {
       handleDeviceSwitch();
   }, [localStream]);
 
+  // Keep our P2P call alive on the server: once a call settles there is no
+  // other signaling, and the server reaps participants that go quiet
+  React.useEffect(() => {
+    if (!isInCall || !callRoomId || callMode !== 'p2p') return;
+
+    const interval = setInterval(() => {
+      wsService.send('call.heartbeat', { room_id: callRoomId });
+    }, 60000);
+    return () => clearInterval(interval);
+  }, [isInCall, callRoomId, callMode]);
+
   // Handle incoming call config (after joining)
   React.useEffect(() => {
     const handleCallConfig = async (payload) => {
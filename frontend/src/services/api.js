@@ -177,6 +177,15 @@ class ApiService {
     });
   }
 
+  // before is the previous page's next_before; members come in join order
+  async getMembers(conversationId, before = null, limit = 100) {
+    let url = `/conversations/${conversationId}/members?limit=${limit}`;
+    if (before) {
+      url += `&before=${encodeURIComponent(before)}`;
+    }
+    return this.request(url);
+  }
+
   async getMessages(conversationId, before = null, limit = 50) {
     let url = `/conversations/${conversationId}/messages?limit=${limit}`;
     if (before) {